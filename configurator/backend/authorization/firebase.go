@@ -19,6 +19,7 @@ type Provider interface {
 	VerifyAccessToken(token string) (string, error)
 	IsAdmin(userID string) (bool, error)
 	GenerateUserAccessToken(userID string) (string, error)
+	GetUserEmail(userID string) (string, error)
 
 	UsersExist() (bool, error)
 	Type() string
@@ -132,6 +133,16 @@ func (fp *FirebaseProvider) GenerateUserAccessToken(userID string) (string, erro
 	return fp.authClient.CustomToken(fp.ctx, user.UID)
 }
 
+//GetUserEmail returns email of the Firebase user
+func (fp *FirebaseProvider) GetUserEmail(userID string) (string, error) {
+	authUserInfo, err := fp.authClient.GetUser(fp.ctx, userID)
+	if err != nil {
+		return "", fmt.Errorf("Failed to get authorization data for user_id [%s]: %v", userID, err)
+	}
+
+	return authUserInfo.Email, nil
+}
+
 //UsersExist returns always true
 func (fp *FirebaseProvider) UsersExist() (bool, error) {
 	return true, nil
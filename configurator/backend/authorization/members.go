@@ -0,0 +1,428 @@
+package authorization
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jitsucom/jitsu/configurator/storages"
+	entime "github.com/jitsucom/jitsu/configurator/time"
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	ProjectMembersCollection = "project_members"
+	UserProjectsCollection   = "user_projects"
+	InvitationsCollection    = "invitations"
+
+	//invitationTTL is a period during which an invitation can be accepted
+	invitationTTL = 7 * 24 * time.Hour
+)
+
+var (
+	ErrProjectAccessDenied    = errors.New("User does not have access to the project")
+	ErrPermissionDenied       = errors.New("User role does not have permission for this action")
+	ErrInvalidRole            = errors.New("Unknown role. Supported: owner, admin, editor, viewer")
+	ErrInvitationNotFound     = errors.New("Invitation wasn't found")
+	ErrInvitationAccepted     = errors.New("Invitation has been already accepted")
+	ErrInvitationEmail        = errors.New("Invitation was sent to another email")
+	ErrInvitationExpired      = errors.New("Invitation has expired")
+	ErrMemberNotFound         = errors.New("Project member wasn't found")
+	ErrLastOwner              = errors.New("Project must have at least one owner")
+	ErrProjectIDNotConfigured = errors.New("User doesn't have own project. Please provide project_id")
+)
+
+//GetProjectRole returns user role in the project
+//users are owners of their own project (from UserInfo) until the project has explicit members
+func (s *Service) GetProjectRole(userID, projectID string) (Role, error) {
+	members, err := s.getProjectMembers(projectID)
+	if err != nil {
+		return "", err
+	}
+
+	if member, ok := members.Members[userID]; ok {
+		return member.Role, nil
+	}
+
+	//the first invitation saves the owner as an explicit member: removed members don't have access
+	if len(members.Members) > 0 {
+		return "", ErrProjectAccessDenied
+	}
+
+	ownProjectID, err := s.GetProjectID(userID)
+	if err == nil && ownProjectID == projectID {
+		return OwnerRole, nil
+	}
+
+	return "", ErrProjectAccessDenied
+}
+
+//GetUserProjects returns all projects with roles which are available to the user (including own project)
+func (s *Service) GetUserProjects(userID string) (map[string]Role, error) {
+	userProjects, err := s.getUserProjects(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string]Role{}
+	for projectID, role := range userProjects.Projects {
+		result[projectID] = role
+	}
+
+	ownProjectID, err := s.GetProjectID(userID)
+	if err == nil {
+		role, err := s.GetProjectRole(userID, ownProjectID)
+		if err == nil {
+			result[ownProjectID] = role
+		} else if err != ErrProjectAccessDenied {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+//GetProjectMembers returns all project members
+//returns only the requester as an owner if the project hasn't had any invitations yet
+func (s *Service) GetProjectMembers(projectID, requesterID string) ([]*ProjectMember, error) {
+	members, err := s.getProjectMembers(projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(members.Members) == 0 {
+		return []*ProjectMember{{UserID: requesterID, Role: OwnerRole}}, nil
+	}
+
+	result := make([]*ProjectMember, 0, len(members.Members))
+	for _, member := range members.Members {
+		result = append(result, member)
+	}
+
+	return result, nil
+}
+
+//CreateInvitation creates and saves an invitation of the email to the project with the role
+func (s *Service) CreateInvitation(projectID, inviterID, email string, role Role) (*Invitation, error) {
+	if !role.IsValid() {
+		return nil, ErrInvalidRole
+	}
+
+	unlock, err := s.lockProject(projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	if err := s.checkCanManage(projectID, inviterID, role); err != nil {
+		return nil, err
+	}
+
+	//the first invitation makes implicit owner an explicit project member
+	if err := s.ensureMember(projectID, inviterID); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	invitation := &Invitation{
+		ID:        "invitation-" + uuid.NewV4().String(),
+		ProjectID: projectID,
+		Email:     strings.ToLower(strings.TrimSpace(email)),
+		Role:      role,
+		InvitedBy: inviterID,
+		CreatedAt: entime.AsISOString(now),
+		ExpiresAt: entime.AsISOString(now.Add(invitationTTL)),
+	}
+
+	if err := s.configurationsStorage.Store(InvitationsCollection, invitation.ID, invitation); err != nil {
+		return nil, fmt.Errorf("Error saving invitation: %v", err)
+	}
+
+	return invitation, nil
+}
+
+//AcceptInvitation adds the user to the invitation project with the invitation role and returns the project ID
+func (s *Service) AcceptInvitation(invitationID, userID string) (string, error) {
+	invitation, err := s.getInvitation(invitationID)
+	if err != nil {
+		return "", err
+	}
+
+	unlock, err := s.lockProject(invitation.ProjectID)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	//re-read under the lock: the invitation might have been accepted concurrently
+	invitation, err = s.getInvitation(invitationID)
+	if err != nil {
+		return "", err
+	}
+
+	if invitation.AcceptedBy != "" {
+		return "", ErrInvitationAccepted
+	}
+
+	if invitation.IsExpired(time.Now().UTC()) {
+		return "", ErrInvitationExpired
+	}
+
+	email, err := s.authProvider.GetUserEmail(userID)
+	if err != nil {
+		return "", fmt.Errorf("Error getting user [%s] email: %v", userID, err)
+	}
+	if strings.ToLower(strings.TrimSpace(email)) != invitation.Email {
+		return "", ErrInvitationEmail
+	}
+
+	if err := s.saveMember(invitation.ProjectID, &ProjectMember{UserID: userID, Email: invitation.Email, Role: invitation.Role}); err != nil {
+		return "", err
+	}
+
+	invitation.AcceptedBy = userID
+	invitation.AcceptedAt = entime.AsISOString(time.Now().UTC())
+	if err := s.configurationsStorage.Store(InvitationsCollection, invitation.ID, invitation); err != nil {
+		return "", fmt.Errorf("Error saving invitation: %v", err)
+	}
+
+	return invitation.ProjectID, nil
+}
+
+//UpdateMemberRole changes the member role
+func (s *Service) UpdateMemberRole(projectID, actorID, userID string, role Role) error {
+	if !role.IsValid() {
+		return ErrInvalidRole
+	}
+
+	unlock, err := s.lockProject(projectID)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	members, err := s.getProjectMembers(projectID)
+	if err != nil {
+		return err
+	}
+
+	member, ok := members.Members[userID]
+	if !ok {
+		return ErrMemberNotFound
+	}
+
+	if err := s.checkCanManage(projectID, actorID, member.Role); err != nil {
+		return err
+	}
+	if err := s.checkCanManage(projectID, actorID, role); err != nil {
+		return err
+	}
+
+	if member.Role == OwnerRole && role != OwnerRole && countOwners(members) == 1 {
+		return ErrLastOwner
+	}
+
+	member.Role = role
+	return s.saveMember(projectID, member)
+}
+
+//RemoveMember removes the user from the project
+func (s *Service) RemoveMember(projectID, actorID, userID string) error {
+	unlock, err := s.lockProject(projectID)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	members, err := s.getProjectMembers(projectID)
+	if err != nil {
+		return err
+	}
+
+	member, ok := members.Members[userID]
+	if !ok {
+		return ErrMemberNotFound
+	}
+
+	if err := s.checkCanManage(projectID, actorID, member.Role); err != nil {
+		return err
+	}
+
+	if member.Role == OwnerRole && countOwners(members) == 1 {
+		return ErrLastOwner
+	}
+
+	delete(members.Members, userID)
+	if err := s.configurationsStorage.Store(ProjectMembersCollection, projectID, members); err != nil {
+		return fmt.Errorf("Error saving project [%s] members: %v", projectID, err)
+	}
+
+	return s.updateUserProjects(userID, func(userProjects *UserProjects) {
+		delete(userProjects.Projects, projectID)
+	})
+}
+
+//checkCanManage returns err if the actor can't manage members with the role
+func (s *Service) checkCanManage(projectID, actorID string, role Role) error {
+	actorRole, err := s.GetProjectRole(actorID, projectID)
+	if err != nil {
+		return err
+	}
+
+	if !actorRole.CanManage(role) {
+		return ErrPermissionDenied
+	}
+
+	return nil
+}
+
+//ensureMember saves implicit owner as an explicit project member
+func (s *Service) ensureMember(projectID, userID string) error {
+	members, err := s.getProjectMembers(projectID)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := members.Members[userID]; ok {
+		return nil
+	}
+
+	role, err := s.GetProjectRole(userID, projectID)
+	if err != nil {
+		return err
+	}
+
+	return s.saveMember(projectID, &ProjectMember{UserID: userID, Role: role})
+}
+
+//saveMember saves member into project members and project into user projects
+//must be called under the project lock
+func (s *Service) saveMember(projectID string, member *ProjectMember) error {
+	members, err := s.getProjectMembers(projectID)
+	if err != nil {
+		return err
+	}
+
+	members.Members[member.UserID] = member
+	if err := s.configurationsStorage.Store(ProjectMembersCollection, projectID, members); err != nil {
+		return fmt.Errorf("Error saving project [%s] members: %v", projectID, err)
+	}
+
+	return s.updateUserProjects(member.UserID, func(userProjects *UserProjects) {
+		userProjects.Projects[projectID] = member.Role
+	})
+}
+
+//updateUserProjects applies the update to user projects under the user lock
+//user projects are changed from different projects concurrently
+func (s *Service) updateUserProjects(userID string, update func(userProjects *UserProjects)) error {
+	unlock, err := s.configurationsStorage.Lock(UserProjectsCollection + "#" + userID)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	userProjects, err := s.getUserProjects(userID)
+	if err != nil {
+		return err
+	}
+
+	update(userProjects)
+	if err := s.configurationsStorage.Store(UserProjectsCollection, userID, userProjects); err != nil {
+		return fmt.Errorf("Error saving user [%s] projects: %v", userID, err)
+	}
+
+	return nil
+}
+
+//lockProject locks project members and invitations updates on all configurator nodes
+//the project lock is always acquired before a user lock
+func (s *Service) lockProject(projectID string) (func(), error) {
+	return s.configurationsStorage.Lock(ProjectMembersCollection + "#" + projectID)
+}
+
+func (s *Service) getInvitation(invitationID string) (*Invitation, error) {
+	b, err := s.configurationsStorage.Get(InvitationsCollection, invitationID)
+	if err != nil {
+		if err == storages.ErrConfigurationNotFound {
+			return nil, ErrInvitationNotFound
+		}
+
+		return nil, err
+	}
+
+	invitation := &Invitation{}
+	if err := json.Unmarshal(b, invitation); err != nil {
+		return nil, fmt.Errorf("Error parsing invitation [%s]: %v", invitationID, err)
+	}
+
+	return invitation, nil
+}
+
+func (s *Service) getProjectMembers(projectID string) (*ProjectMembers, error) {
+	members := &ProjectMembers{}
+	b, err := s.configurationsStorage.Get(ProjectMembersCollection, projectID)
+	if err != nil && err != storages.ErrConfigurationNotFound {
+		return nil, fmt.Errorf("Error getting project [%s] members: %v", projectID, err)
+	}
+
+	if err == nil {
+		if err := json.Unmarshal(b, members); err != nil {
+			return nil, fmt.Errorf("Error parsing project [%s] members: %v", projectID, err)
+		}
+	}
+
+	if members.Members == nil {
+		members.Members = map[string]*ProjectMember{}
+	}
+
+	return members, nil
+}
+
+func (s *Service) getUserProjects(userID string) (*UserProjects, error) {
+	userProjects := &UserProjects{}
+	b, err := s.configurationsStorage.Get(UserProjectsCollection, userID)
+	if err != nil && err != storages.ErrConfigurationNotFound {
+		return nil, fmt.Errorf("Error getting user [%s] projects: %v", userID, err)
+	}
+
+	if err == nil {
+		if err := json.Unmarshal(b, userProjects); err != nil {
+			return nil, fmt.Errorf("Error parsing user [%s] projects: %v", userID, err)
+		}
+	}
+
+	if userProjects.Projects == nil {
+		userProjects.Projects = map[string]Role{}
+	}
+
+	return userProjects, nil
+}
+
+func countOwners(members *ProjectMembers) int {
+	owners := 0
+	for _, member := range members.Members {
+		if member.Role == OwnerRole {
+			owners++
+		}
+	}
+
+	return owners
+}
+
+//IsExpired returns true if the invitation can't be accepted anymore
+//invitations without expires_at (created before the TTL was introduced) expire invitationTTL after creation
+func (i *Invitation) IsExpired(now time.Time) bool {
+	expiresAt, err := entime.ParseISOString(i.ExpiresAt)
+	if err != nil {
+		createdAt, err := entime.ParseISOString(i.CreatedAt)
+		if err != nil {
+			return true
+		}
+		expiresAt = createdAt.Add(invitationTTL)
+	}
+
+	return !now.Before(expiresAt)
+}
@@ -0,0 +1,272 @@
+package authorization
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jitsucom/jitsu/configurator/storages"
+	entime "github.com/jitsucom/jitsu/configurator/time"
+	"github.com/stretchr/testify/require"
+)
+
+const testProjectID = "project1"
+
+//newTestService returns Service where 'owner' user has own testProjectID and admin, editor, viewer are its members
+func newTestService(t *testing.T) (*Service, *storages.Mock) {
+	storage := storages.NewMock()
+	require.NoError(t, storage.Store(UsersInfoCollection, "owner", &UserInfo{Project: Project{ID: testProjectID}}))
+
+	emails := map[string]string{"owner": "owner@example.com", "stranger": "stranger@example.com"}
+	for i := 0; i < 10; i++ {
+		emails[fmt.Sprintf("user%d", i)] = fmt.Sprintf("user%d@example.com", i)
+	}
+	service := NewServiceWithProvider(&ProviderMock{Emails: emails}, storage)
+
+	for _, role := range []Role{AdminRole, EditorRole, ViewerRole} {
+		userID := string(role)
+		emails[userID] = userID + "@example.com"
+		addMember(t, service, userID, role)
+	}
+
+	return service, storage
+}
+
+func addMember(t *testing.T, service *Service, userID string, role Role) {
+	invitation, err := service.CreateInvitation(testProjectID, "owner", userID+"@example.com", role)
+	require.NoError(t, err)
+
+	projectID, err := service.AcceptInvitation(invitation.ID, userID)
+	require.NoError(t, err)
+	require.Equal(t, testProjectID, projectID)
+}
+
+func TestGetProjectRole(t *testing.T) {
+	storage := storages.NewMock()
+	require.NoError(t, storage.Store(UsersInfoCollection, "owner", &UserInfo{Project: Project{ID: testProjectID}}))
+	service := NewServiceWithProvider(&ProviderMock{Emails: map[string]string{"owner": "owner@example.com", "viewer": "viewer@example.com"}}, storage)
+
+	role, err := service.GetProjectRole("owner", testProjectID)
+	require.NoError(t, err)
+	require.Equal(t, OwnerRole, role, "user is an implicit owner of own project")
+
+	_, err = service.GetProjectRole("viewer", testProjectID)
+	require.Equal(t, ErrProjectAccessDenied, err)
+
+	addMember(t, service, "viewer", ViewerRole)
+
+	role, err = service.GetProjectRole("viewer", testProjectID)
+	require.NoError(t, err)
+	require.Equal(t, ViewerRole, role)
+
+	members, err := service.GetProjectMembers(testProjectID, "owner")
+	require.NoError(t, err)
+	require.Len(t, members, 2, "the first invitation must save the owner as an explicit member")
+
+	projects, err := service.GetUserProjects("viewer")
+	require.NoError(t, err)
+	require.Equal(t, map[string]Role{testProjectID: ViewerRole}, projects)
+}
+
+func TestInvitations(t *testing.T) {
+	tests := []struct {
+		name       string
+		inviterID  string
+		role       Role
+		acceptorID string
+		createErr  error
+		acceptErr  error
+	}{
+		{"Owner invites owner", "owner", OwnerRole, "user0", nil, nil},
+		{"Admin invites admin", "admin", AdminRole, "user0", nil, nil},
+		{"Admin can't invite owner", "admin", OwnerRole, "user0", ErrPermissionDenied, nil},
+		{"Editor can't invite", "editor", ViewerRole, "user0", ErrPermissionDenied, nil},
+		{"Viewer can't invite", "viewer", ViewerRole, "user0", ErrPermissionDenied, nil},
+		{"Stranger can't invite", "stranger", ViewerRole, "user0", ErrProjectAccessDenied, nil},
+		{"Unknown role", "owner", Role("superuser"), "user0", ErrInvalidRole, nil},
+		{"Accepted by another user", "owner", EditorRole, "user1", nil, ErrInvitationEmail},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, _ := newTestService(t)
+
+			invitation, err := service.CreateInvitation(testProjectID, tt.inviterID, " User0@Example.com ", tt.role)
+			if tt.createErr != nil {
+				require.Equal(t, tt.createErr, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, "user0@example.com", invitation.Email)
+
+			_, err = service.AcceptInvitation(invitation.ID, tt.acceptorID)
+			if tt.acceptErr != nil {
+				require.Equal(t, tt.acceptErr, err)
+				return
+			}
+			require.NoError(t, err)
+
+			role, err := service.GetProjectRole(tt.acceptorID, testProjectID)
+			require.NoError(t, err)
+			require.Equal(t, tt.role, role)
+
+			_, err = service.AcceptInvitation(invitation.ID, tt.acceptorID)
+			require.Equal(t, ErrInvitationAccepted, err)
+		})
+	}
+}
+
+func TestInvitationExpiration(t *testing.T) {
+	now := time.Now().UTC()
+	tests := []struct {
+		name        string
+		createdAt   time.Time
+		expiresAt   string
+		expectedErr error
+	}{
+		{"Not expired", now, entime.AsISOString(now.Add(time.Hour)), nil},
+		{"Expired", now.Add(-2 * time.Hour), entime.AsISOString(now.Add(-time.Hour)), ErrInvitationExpired},
+		{"Without expires_at not expired", now.Add(-time.Hour), "", nil},
+		{"Without expires_at expired", now.Add(-invitationTTL - time.Hour), "", ErrInvitationExpired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, storage := newTestService(t)
+
+			invitation, err := service.CreateInvitation(testProjectID, "owner", "user0@example.com", ViewerRole)
+			require.NoError(t, err)
+			require.NotEmpty(t, invitation.ExpiresAt)
+
+			invitation.CreatedAt = entime.AsISOString(tt.createdAt)
+			invitation.ExpiresAt = tt.expiresAt
+			require.NoError(t, storage.Store(InvitationsCollection, invitation.ID, invitation))
+
+			_, err = service.AcceptInvitation(invitation.ID, "user0")
+			require.Equal(t, tt.expectedErr, err)
+		})
+	}
+
+	service, _ := newTestService(t)
+	_, err := service.AcceptInvitation("invitation-unknown", "user0")
+	require.Equal(t, ErrInvitationNotFound, err)
+}
+
+func TestUpdateMemberRole(t *testing.T) {
+	tests := []struct {
+		name        string
+		actorID     string
+		userID      string
+		role        Role
+		expectedErr error
+	}{
+		{"Owner promotes viewer", "owner", "viewer", AdminRole, nil},
+		{"Admin promotes viewer", "admin", "viewer", EditorRole, nil},
+		{"Admin can't promote to owner", "admin", "viewer", OwnerRole, ErrPermissionDenied},
+		{"Admin can't demote owner", "admin", "owner", ViewerRole, ErrPermissionDenied},
+		{"Editor can't change roles", "editor", "viewer", EditorRole, ErrPermissionDenied},
+		{"Last owner can't be demoted", "owner", "owner", AdminRole, ErrLastOwner},
+		{"Unknown member", "owner", "stranger", ViewerRole, ErrMemberNotFound},
+		{"Unknown role", "owner", "viewer", Role(""), ErrInvalidRole},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, _ := newTestService(t)
+
+			err := service.UpdateMemberRole(testProjectID, tt.actorID, tt.userID, tt.role)
+			if tt.expectedErr != nil {
+				require.Equal(t, tt.expectedErr, err)
+				return
+			}
+			require.NoError(t, err)
+
+			role, err := service.GetProjectRole(tt.userID, testProjectID)
+			require.NoError(t, err)
+			require.Equal(t, tt.role, role)
+
+			projects, err := service.GetUserProjects(tt.userID)
+			require.NoError(t, err)
+			require.Equal(t, tt.role, projects[testProjectID])
+		})
+	}
+}
+
+func TestRemoveMember(t *testing.T) {
+	tests := []struct {
+		name        string
+		actorID     string
+		userID      string
+		expectedErr error
+	}{
+		{"Owner removes admin", "owner", "admin", nil},
+		{"Admin removes viewer", "admin", "viewer", nil},
+		{"Admin can't remove owner", "admin", "owner", ErrPermissionDenied},
+		{"Viewer can't remove editor", "viewer", "editor", ErrPermissionDenied},
+		{"Last owner can't be removed", "owner", "owner", ErrLastOwner},
+		{"Unknown member", "owner", "stranger", ErrMemberNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, _ := newTestService(t)
+
+			err := service.RemoveMember(testProjectID, tt.actorID, tt.userID)
+			if tt.expectedErr != nil {
+				require.Equal(t, tt.expectedErr, err)
+				return
+			}
+			require.NoError(t, err)
+
+			_, err = service.GetProjectRole(tt.userID, testProjectID)
+			require.Equal(t, ErrProjectAccessDenied, err)
+
+			projects, err := service.GetUserProjects(tt.userID)
+			require.NoError(t, err)
+			require.NotContains(t, projects, testProjectID)
+		})
+	}
+}
+
+//slowStorage makes read-modify-write windows wider: data is read before the delay
+type slowStorage struct {
+	*storages.Mock
+}
+
+func (ss *slowStorage) Get(collection string, id string) ([]byte, error) {
+	b, err := ss.Mock.Get(collection, id)
+	time.Sleep(5 * time.Millisecond)
+	return b, err
+}
+
+//TestConcurrentMembershipUpdates checks that concurrent invitations accepting doesn't lose members
+func TestConcurrentMembershipUpdates(t *testing.T) {
+	service, storage := newTestService(t)
+	service.configurationsStorage = &slowStorage{Mock: storage}
+
+	var invitations []*Invitation
+	for i := 0; i < 10; i++ {
+		invitation, err := service.CreateInvitation(testProjectID, "owner", fmt.Sprintf("user%d@example.com", i), ViewerRole)
+		require.NoError(t, err)
+		invitations = append(invitations, invitation)
+	}
+
+	wg := &sync.WaitGroup{}
+	errs := make(chan error, len(invitations))
+	for i, invitation := range invitations {
+		wg.Add(1)
+		go func(userID, invitationID string) {
+			defer wg.Done()
+			_, err := service.AcceptInvitation(invitationID, userID)
+			errs <- err
+		}(fmt.Sprintf("user%d", i), invitation.ID)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	members, err := service.GetProjectMembers(testProjectID, "owner")
+	require.NoError(t, err)
+	//owner, admin, editor, viewer and 10 users
+	require.Len(t, members, 14)
+}
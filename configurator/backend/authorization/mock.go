@@ -0,0 +1,86 @@
+package authorization
+
+import "errors"
+
+var errNotSupportedByMock = errors.New("Not supported by the mock")
+
+//ProviderMock is a Provider with predefined users (is used in tests)
+type ProviderMock struct {
+	//map[token]userID
+	Tokens map[string]string
+	//map[userID]email
+	Emails map[string]string
+}
+
+//VerifyAccessToken is a mock func
+func (pm *ProviderMock) VerifyAccessToken(token string) (string, error) {
+	userID, ok := pm.Tokens[token]
+	if !ok {
+		return "", ErrUnknownToken
+	}
+
+	return userID, nil
+}
+
+//GetUserEmail is a mock func
+func (pm *ProviderMock) GetUserEmail(userID string) (string, error) {
+	email, ok := pm.Emails[userID]
+	if !ok {
+		return "", ErrUserNotFound
+	}
+
+	return email, nil
+}
+
+//IsAdmin is a mock func
+func (pm *ProviderMock) IsAdmin(userID string) (bool, error) { return false, nil }
+
+//GenerateUserAccessToken is a mock func
+func (pm *ProviderMock) GenerateUserAccessToken(userID string) (string, error) {
+	return "", errNotSupportedByMock
+}
+
+//UsersExist is a mock func
+func (pm *ProviderMock) UsersExist() (bool, error) { return len(pm.Emails) > 0, nil }
+
+//Type is a mock func
+func (pm *ProviderMock) Type() string { return RedisType }
+
+//GetUserByID is a mock func
+func (pm *ProviderMock) GetUserByID(userID string) (*User, error) { return nil, errNotSupportedByMock }
+
+//GetUserByEmail is a mock func
+func (pm *ProviderMock) GetUserByEmail(email string) (*User, error) { return nil, errNotSupportedByMock }
+
+//SaveUser is a mock func
+func (pm *ProviderMock) SaveUser(user *User) error { return errNotSupportedByMock }
+
+//CreateTokens is a mock func
+func (pm *ProviderMock) CreateTokens(userID string) (*TokenDetails, error) {
+	return nil, errNotSupportedByMock
+}
+
+//DeleteToken is a mock func
+func (pm *ProviderMock) DeleteToken(token string) error { return errNotSupportedByMock }
+
+//DeleteAllTokens is a mock func
+func (pm *ProviderMock) DeleteAllTokens(userID string) error { return errNotSupportedByMock }
+
+//SavePasswordResetID is a mock func
+func (pm *ProviderMock) SavePasswordResetID(resetID, userID string) error { return errNotSupportedByMock }
+
+//DeletePasswordResetID is a mock func
+func (pm *ProviderMock) DeletePasswordResetID(resetID string) error { return errNotSupportedByMock }
+
+//GetUserByResetID is a mock func
+func (pm *ProviderMock) GetUserByResetID(resetID string) (*User, error) {
+	return nil, errNotSupportedByMock
+}
+
+//RefreshTokens is a mock func
+func (pm *ProviderMock) RefreshTokens(refreshToken string) (*TokenDetails, error) {
+	return nil, errNotSupportedByMock
+}
+
+//Close is a mock func
+func (pm *ProviderMock) Close() error { return nil }
@@ -28,3 +28,35 @@ type Project struct {
 	ID   string `json:"_id"`
 	Name string `json:"_name"`
 }
+
+//ProjectMembers is a storage entity with all project members
+type ProjectMembers struct {
+	//map[userID]*ProjectMember
+	Members map[string]*ProjectMember `firestore:"members" json:"members"`
+}
+
+//ProjectMember is a user with a role in a project
+type ProjectMember struct {
+	UserID string `firestore:"user_id" json:"user_id"`
+	Email  string `firestore:"email" json:"email,omitempty"`
+	Role   Role   `firestore:"role" json:"role"`
+}
+
+//UserProjects is a storage entity with all projects which are available to a user (except own project from UserInfo)
+type UserProjects struct {
+	//map[projectID]Role
+	Projects map[string]Role `firestore:"projects" json:"projects"`
+}
+
+//Invitation is a storage entity of a pending (or accepted) invitation to a project
+type Invitation struct {
+	ID         string `firestore:"id" json:"id"`
+	ProjectID  string `firestore:"project_id" json:"project_id"`
+	Email      string `firestore:"email" json:"email"`
+	Role       Role   `firestore:"role" json:"role"`
+	InvitedBy  string `firestore:"invited_by" json:"invited_by"`
+	CreatedAt  string `firestore:"created_at" json:"created_at"`
+	ExpiresAt  string `firestore:"expires_at" json:"expires_at,omitempty"`
+	AcceptedBy string `firestore:"accepted_by" json:"accepted_by,omitempty"`
+	AcceptedAt string `firestore:"accepted_at" json:"accepted_at,omitempty"`
+}
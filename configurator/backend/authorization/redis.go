@@ -77,6 +77,16 @@ func (rp *RedisProvider) UsersExist() (bool, error) {
 	return exists, nil
 }
 
+//GetUserEmail returns email of the user
+func (rp *RedisProvider) GetUserEmail(userID string) (string, error) {
+	user, err := rp.GetUserByID(userID)
+	if err != nil {
+		return "", err
+	}
+
+	return user.Email, nil
+}
+
 //GetUserByID returns User by user ID
 func (rp *RedisProvider) GetUserByID(userID string) (*User, error) {
 	conn := rp.pool.Get()
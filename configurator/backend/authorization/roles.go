@@ -0,0 +1,61 @@
+package authorization
+
+//Role is a user role in a project
+type Role string
+
+//Permission is an action which might be allowed for a Role
+type Permission string
+
+const (
+	OwnerRole  Role = "owner"
+	AdminRole  Role = "admin"
+	EditorRole Role = "editor"
+	ViewerRole Role = "viewer"
+
+	//ReadPermission allows reading project configurations (destinations, sources, api keys, etc.)
+	ReadPermission Permission = "read"
+	//WritePermission allows changing project configurations
+	WritePermission Permission = "write"
+	//ManageMembersPermission allows inviting users to the project, changing their roles and removing them
+	ManageMembersPermission Permission = "manage_members"
+)
+
+var rolePermissions = map[Role]map[Permission]bool{
+	OwnerRole:  {ReadPermission: true, WritePermission: true, ManageMembersPermission: true},
+	AdminRole:  {ReadPermission: true, WritePermission: true, ManageMembersPermission: true},
+	EditorRole: {ReadPermission: true, WritePermission: true},
+	ViewerRole: {ReadPermission: true},
+}
+
+//roleLevels is used for checking which roles might be assigned by a member
+var roleLevels = map[Role]int{
+	OwnerRole:  4,
+	AdminRole:  3,
+	EditorRole: 2,
+	ViewerRole: 1,
+}
+
+//IsValid returns true if the role is known
+func (r Role) IsValid() bool {
+	_, ok := rolePermissions[r]
+	return ok
+}
+
+//Has returns true if the role has the permission
+func (r Role) Has(permission Permission) bool {
+	return rolePermissions[r][permission]
+}
+
+//CanManage returns true if a member with the role can assign (or change, or remove) another role
+//owners can manage all roles, admins can manage all roles except owners
+func (r Role) CanManage(other Role) bool {
+	if !r.Has(ManageMembersPermission) {
+		return false
+	}
+
+	if r == OwnerRole {
+		return true
+	}
+
+	return roleLevels[r] >= roleLevels[other] && other != OwnerRole
+}
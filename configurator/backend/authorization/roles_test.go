@@ -0,0 +1,64 @@
+package authorization
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRolePermissions(t *testing.T) {
+	tests := []struct {
+		name       string
+		role       Role
+		permission Permission
+		expected   bool
+	}{
+		{"Owner reads", OwnerRole, ReadPermission, true},
+		{"Owner writes", OwnerRole, WritePermission, true},
+		{"Owner manages members", OwnerRole, ManageMembersPermission, true},
+		{"Admin writes", AdminRole, WritePermission, true},
+		{"Admin manages members", AdminRole, ManageMembersPermission, true},
+		{"Editor writes", EditorRole, WritePermission, true},
+		{"Editor doesn't manage members", EditorRole, ManageMembersPermission, false},
+		{"Viewer reads", ViewerRole, ReadPermission, true},
+		{"Viewer doesn't write", ViewerRole, WritePermission, false},
+		{"Viewer doesn't manage members", ViewerRole, ManageMembersPermission, false},
+		{"Unknown role doesn't read", Role("guest"), ReadPermission, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, tt.role.Has(tt.permission))
+		})
+	}
+}
+
+func TestRoleCanManage(t *testing.T) {
+	tests := []struct {
+		name     string
+		role     Role
+		other    Role
+		expected bool
+	}{
+		{"Owner manages owner", OwnerRole, OwnerRole, true},
+		{"Owner manages viewer", OwnerRole, ViewerRole, true},
+		{"Admin doesn't manage owner", AdminRole, OwnerRole, false},
+		{"Admin manages admin", AdminRole, AdminRole, true},
+		{"Admin manages editor", AdminRole, EditorRole, true},
+		{"Editor doesn't manage viewer", EditorRole, ViewerRole, false},
+		{"Viewer doesn't manage viewer", ViewerRole, ViewerRole, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, tt.role.CanManage(tt.other))
+		})
+	}
+}
+
+func TestRoleIsValid(t *testing.T) {
+	for _, role := range []Role{OwnerRole, AdminRole, EditorRole, ViewerRole} {
+		require.True(t, role.IsValid(), string(role))
+	}
+
+	require.False(t, Role("").IsValid())
+	require.False(t, Role("superuser").IsValid())
+}
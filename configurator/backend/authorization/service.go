@@ -85,7 +85,12 @@ func NewService(ctx context.Context, vp *viper.Viper, storage storages.Configura
 		return nil, errors.New("Unknown 'auth' section type. Supported: firebase, redis")
 	}
 
-	return &Service{authProvider: authProvider, configurationsStorage: storage}, nil
+	return NewServiceWithProvider(authProvider, storage), nil
+}
+
+//NewServiceWithProvider returns Service with the configured auth provider
+func NewServiceWithProvider(authProvider Provider, storage storages.ConfigurationsStorage) *Service {
+	return &Service{authProvider: authProvider, configurationsStorage: storage}
 }
 
 //Authenticate verify access token and return user id
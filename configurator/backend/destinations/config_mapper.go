@@ -37,6 +37,12 @@ func MapConfig(destinationID string, destination *entities.Destination, defaultS
 		config, err = mapAmplitude(destination)
 	case enstorages.HubSpotType:
 		config, err = mapHubSpot(destination)
+	case enstorages.MixpanelType:
+		config, err = mapMixpanel(destination)
+	case enstorages.PostHogType:
+		config, err = mapPostHog(destination)
+	case enstorages.SegmentType:
+		config, err = mapSegment(destination)
 	case enstorages.DbtCloudType:
 		config, err = mapDbtCloud(destination)
 	case enstorages.MySQLType:
@@ -392,8 +398,12 @@ func mapFacebook(fbDestination *entities.Destination) (*enstorages.DestinationCo
 		Type: enstorages.FacebookType,
 		Mode: fbFormData.Mode,
 		Facebook: &enadapters.FacebookConversionAPIConfig{
-			PixelID:     fbFormData.PixelID,
-			AccessToken: fbFormData.AccessToken,
+			PixelID:       fbFormData.PixelID,
+			AccessToken:   fbFormData.AccessToken,
+			ActionSource:  fbFormData.ActionSource,
+			TestEventCode: fbFormData.TestEventCode,
+			EventNames:    fbFormData.EventNames,
+			CustomData:    fbFormData.CustomData,
 		},
 		DataLayout: &enstorages.DataLayout{
 			TableNameTemplate: fbFormData.TableName,
@@ -422,15 +432,37 @@ func mapWebhook(whDestination *entities.Destination) (*enstorages.DestinationCon
 		headers[strings.TrimSpace(nameValue[0])] = strings.TrimSpace(nameValue[1])
 	}
 
+	webHookConfig := &enadapters.WebHookConfig{
+		URL:     whFormData.URL,
+		Method:  whFormData.Method,
+		Body:    whFormData.Body,
+		Headers: headers,
+	}
+	if whFormData.SignatureSecret != "" {
+		webHookConfig.Signature = &enadapters.WebHookSignatureConfig{Secret: whFormData.SignatureSecret}
+	}
+	if whFormData.OAuth2CredentialID != "" {
+		webHookConfig.OAuth2 = &enadapters.WebHookOAuth2Config{CredentialID: whFormData.OAuth2CredentialID}
+	} else if whFormData.OAuth2TokenURL != "" {
+		webHookConfig.OAuth2 = &enadapters.WebHookOAuth2Config{
+			TokenURL:     whFormData.OAuth2TokenURL,
+			ClientID:     whFormData.OAuth2ClientID,
+			ClientSecret: whFormData.OAuth2ClientSecret,
+			Scopes:       whFormData.OAuth2Scopes,
+		}
+	}
+	if whFormData.TLSClientCert != "" {
+		webHookConfig.TLS = &enadapters.WebHookTLSConfig{
+			ClientCert: whFormData.TLSClientCert,
+			ClientKey:  whFormData.TLSClientKey,
+			ServerCA:   whFormData.TLSServerCA,
+		}
+	}
+
 	return &enstorages.DestinationConfig{
-		Type: enstorages.WebHookType,
-		Mode: whFormData.Mode,
-		WebHook: &enadapters.WebHookConfig{
-			URL:     whFormData.URL,
-			Method:  whFormData.Method,
-			Body:    whFormData.Body,
-			Headers: headers,
-		},
+		Type:    enstorages.WebHookType,
+		Mode:    whFormData.Mode,
+		WebHook: webHookConfig,
 		DataLayout: &enstorages.DataLayout{
 			TableNameTemplate: whFormData.TableName,
 		},
@@ -461,6 +493,83 @@ func mapAmplitude(aDestination *entities.Destination) (*enstorages.DestinationCo
 	}, nil
 }
 
+func mapMixpanel(mDestination *entities.Destination) (*enstorages.DestinationConfig, error) {
+	b, err := json.Marshal(mDestination.Data)
+	if err != nil {
+		return nil, fmt.Errorf("Error marshaling mixpanel config destination: %v", err)
+	}
+
+	mFormData := &entities.MixpanelFormData{}
+	err = json.Unmarshal(b, mFormData)
+	if err != nil {
+		return nil, fmt.Errorf("Error unmarshaling mixpanel form data: %v", err)
+	}
+
+	return &enstorages.DestinationConfig{
+		Type: enstorages.MixpanelType,
+		Mode: mFormData.Mode,
+		Mixpanel: &enadapters.MixpanelConfig{
+			ProjectToken: mFormData.ProjectToken,
+			APISecret:    mFormData.APISecret,
+			ProjectID:    mFormData.ProjectID,
+			BaseURL:      mFormData.BaseURL,
+		},
+		DataLayout: &enstorages.DataLayout{
+			TableNameTemplate: mFormData.TableName,
+		},
+	}, nil
+}
+
+func mapPostHog(phDestination *entities.Destination) (*enstorages.DestinationConfig, error) {
+	b, err := json.Marshal(phDestination.Data)
+	if err != nil {
+		return nil, fmt.Errorf("Error marshaling posthog config destination: %v", err)
+	}
+
+	phFormData := &entities.PostHogFormData{}
+	err = json.Unmarshal(b, phFormData)
+	if err != nil {
+		return nil, fmt.Errorf("Error unmarshaling posthog form data: %v", err)
+	}
+
+	return &enstorages.DestinationConfig{
+		Type: enstorages.PostHogType,
+		Mode: phFormData.Mode,
+		PostHog: &enadapters.PostHogConfig{
+			APIKey:  phFormData.APIKey,
+			BaseURL: phFormData.BaseURL,
+		},
+		DataLayout: &enstorages.DataLayout{
+			TableNameTemplate: phFormData.TableName,
+		},
+	}, nil
+}
+
+func mapSegment(sDestination *entities.Destination) (*enstorages.DestinationConfig, error) {
+	b, err := json.Marshal(sDestination.Data)
+	if err != nil {
+		return nil, fmt.Errorf("Error marshaling segment config destination: %v", err)
+	}
+
+	sFormData := &entities.SegmentFormData{}
+	err = json.Unmarshal(b, sFormData)
+	if err != nil {
+		return nil, fmt.Errorf("Error unmarshaling segment form data: %v", err)
+	}
+
+	return &enstorages.DestinationConfig{
+		Type: enstorages.SegmentType,
+		Mode: sFormData.Mode,
+		Segment: &enadapters.SegmentConfig{
+			WriteKey: sFormData.WriteKey,
+			Endpoint: sFormData.Endpoint,
+		},
+		DataLayout: &enstorages.DataLayout{
+			TableNameTemplate: sFormData.TableName,
+		},
+	}, nil
+}
+
 func mapHubSpot(hDestination *entities.Destination) (*enstorages.DestinationConfig, error) {
 	b, err := json.Marshal(hDestination.Data)
 	if err != nil {
@@ -477,8 +586,10 @@ func mapHubSpot(hDestination *entities.Destination) (*enstorages.DestinationConf
 		Type: enstorages.HubSpotType,
 		Mode: hFormData.Mode,
 		HubSpot: &enadapters.HubSpotConfig{
-			APIKey: hFormData.APIKey,
-			HubID:  hFormData.HubID,
+			AccessToken:  hFormData.AccessToken,
+			CredentialID: hFormData.CredentialID,
+			APIKey:       hFormData.APIKey,
+			HubID:        hFormData.HubID,
 		},
 		DataLayout: &enstorages.DataLayout{
 			TableNameTemplate: hFormData.TableName,
@@ -37,6 +37,7 @@ func (sc *SMTPConfiguration) Validate() error {
 type Service struct {
 	smtp               *SMTPConfiguration
 	resetPasswordEmail *template.Template
+	invitationEmail    *template.Template
 }
 
 func NewService(smtp *SMTPConfiguration) (*Service, error) {
@@ -49,7 +50,12 @@ func NewService(smtp *SMTPConfiguration) (*Service, error) {
 		return nil, fmt.Errorf("Error parsing reset password email template: %v", err)
 	}
 
-	return &Service{smtp: smtp, resetPasswordEmail: t}, nil
+	invitation, err := template.New("invitation_email").Parse(invitationTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing invitation email template: %v", err)
+	}
+
+	return &Service{smtp: smtp, resetPasswordEmail: t, invitationEmail: invitation}, nil
 }
 
 func (s *Service) IsConfigured() bool {
@@ -85,8 +91,41 @@ func (s *Service) SendResetPassword(email, link string) error {
 		return err
 	}
 
+	return s.send(m, email, body.String())
+}
+
+//SendInvitation sends an email with a link for accepting an invitation to a project
+func (s *Service) SendInvitation(email, role, link string) error {
+	if s.smtp == nil {
+		return ErrSMTPNotConfigured
+	}
+
+	m := gomail.NewMessage()
+	m.SetHeader("From", "support@jitsu.com")
+	m.SetHeader("To", email)
+	m.SetHeader("Subject", "You have been invited to a project in Jitsu - an open-source data collection platform")
+
+	var body bytes.Buffer
+	err := s.invitationEmail.Execute(&body, struct {
+		Email string
+		Role  string
+		Link  string
+	}{
+		Email: email,
+		Role:  role,
+		Link:  link,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return s.send(m, email, body.String())
+}
+
+func (s *Service) send(m *gomail.Message, email, body string) error {
 	// Set E-Mail body. You can set plain text or html with text/html
-	m.SetBody("text/html", body.String())
+	m.SetBody("text/html", body)
 
 	// Settings for SMTP server
 	d := gomail.NewDialer(s.smtp.Host, s.smtp.Port, s.smtp.User, s.smtp.Password)
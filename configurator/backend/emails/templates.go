@@ -10,3 +10,15 @@ const resetPasswordTemplate = `<!DOCTYPE html>
 	<p>Your Jitsu - an open-source data collection platform team</p>
 </body>
 </html>`
+
+const invitationTemplate = `<!DOCTYPE html>
+<html>
+<body>
+    <p>Hello,</p>
+	<p>You have been invited to join a project in Jitsu - an open-source data collection platform as {{.Role}}.</p>
+	<p>Follow this <a href='{{.Link}}'>link</a> to accept the invitation for your {{.Email}} account.</p>
+	<p>If you weren't expecting this invitation, you can ignore this email.</p>
+	<p>Thanks,</p>
+	<p>Your Jitsu - an open-source data collection platform team</p>
+</body>
+</html>`
@@ -113,8 +113,12 @@ type FacebookFormData struct {
 	Mode      string `firestore:"mode" json:"mode"`
 	TableName string `firestore:"tableName" json:"tableName"`
 
-	PixelID     string `firestore:"fbPixelID" json:"fbPixelID"`
-	AccessToken string `firestore:"fbAccessToken" json:"fbAccessToken"`
+	PixelID       string            `firestore:"fbPixelID" json:"fbPixelID"`
+	AccessToken   string            `firestore:"fbAccessToken" json:"fbAccessToken"`
+	ActionSource  string            `firestore:"fbActionSource" json:"fbActionSource,omitempty"`
+	TestEventCode string            `firestore:"fbTestEventCode" json:"fbTestEventCode,omitempty"`
+	EventNames    map[string]string `firestore:"fbEventNames" json:"fbEventNames,omitempty"`
+	CustomData    map[string]string `firestore:"fbCustomData" json:"fbCustomData,omitempty"`
 }
 
 //WebhookFormData entity is stored in main storage (Firebase/Redis)
@@ -126,6 +130,16 @@ type WebhookFormData struct {
 	Method  string   `firestore:"method" json:"method"`
 	Body    string   `firestore:"body" json:"body"`
 	Headers []string `firestore:"headers" json:"headers"`
+
+	SignatureSecret    string   `firestore:"signatureSecret" json:"signatureSecret,omitempty"`
+	OAuth2TokenURL     string   `firestore:"oauth2TokenURL" json:"oauth2TokenURL,omitempty"`
+	OAuth2ClientID     string   `firestore:"oauth2ClientID" json:"oauth2ClientID,omitempty"`
+	OAuth2ClientSecret string   `firestore:"oauth2ClientSecret" json:"oauth2ClientSecret,omitempty"`
+	OAuth2Scopes       []string `firestore:"oauth2Scopes" json:"oauth2Scopes,omitempty"`
+	OAuth2CredentialID string   `firestore:"oauth2CredentialId" json:"oauth2CredentialId,omitempty"`
+	TLSClientCert      string   `firestore:"tlsClientCert" json:"tlsClientCert,omitempty"`
+	TLSClientKey       string   `firestore:"tlsClientKey" json:"tlsClientKey,omitempty"`
+	TLSServerCA        string   `firestore:"tlsServerCA" json:"tlsServerCA,omitempty"`
 }
 
 //AmplitudeFormData entity is stored in main storage (Firebase/Redis)
@@ -141,8 +155,39 @@ type HubSpotFormData struct {
 	Mode      string `firestore:"mode" json:"mode"`
 	TableName string `firestore:"tableName" json:"tableName"`
 
-	APIKey string `firestore:"apiKey" json:"apiKey"`
-	HubID  string `firestore:"hubID" json:"hubID"`
+	AccessToken  string `firestore:"accessToken" json:"accessToken,omitempty"`
+	CredentialID string `firestore:"credentialId" json:"credentialId,omitempty"`
+	APIKey       string `firestore:"apiKey" json:"apiKey"`
+	HubID        string `firestore:"hubID" json:"hubID"`
+}
+
+//MixpanelFormData entity is stored in main storage (Firebase/Redis)
+type MixpanelFormData struct {
+	Mode      string `firestore:"mode" json:"mode"`
+	TableName string `firestore:"tableName" json:"tableName"`
+
+	ProjectToken string `firestore:"projectToken" json:"projectToken"`
+	APISecret    string `firestore:"apiSecret" json:"apiSecret"`
+	ProjectID    string `firestore:"projectID" json:"projectID,omitempty"`
+	BaseURL      string `firestore:"baseURL" json:"baseURL,omitempty"`
+}
+
+//PostHogFormData entity is stored in main storage (Firebase/Redis)
+type PostHogFormData struct {
+	Mode      string `firestore:"mode" json:"mode"`
+	TableName string `firestore:"tableName" json:"tableName"`
+
+	APIKey  string `firestore:"apiKey" json:"apiKey"`
+	BaseURL string `firestore:"baseURL" json:"baseURL,omitempty"`
+}
+
+//SegmentFormData entity is stored in main storage (Firebase/Redis)
+type SegmentFormData struct {
+	Mode      string `firestore:"mode" json:"mode"`
+	TableName string `firestore:"tableName" json:"tableName"`
+
+	WriteKey string `firestore:"writeKey" json:"writeKey"`
+	Endpoint string `firestore:"endpoint" json:"endpoint,omitempty"`
 }
 
 //DbtCloudFormData entity is stored in main storage (Firebase/Redis)
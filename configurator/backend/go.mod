@@ -17,6 +17,7 @@ require (
 	github.com/prometheus/common v0.15.0 // indirect
 	github.com/satori/go.uuid v1.2.0
 	github.com/spf13/viper v1.8.1
+	github.com/stretchr/testify v1.7.0
 	golang.org/x/crypto v0.0.0-20201016220609-9e8e0b390897
 	google.golang.org/api v0.56.0
 	google.golang.org/grpc v1.40.0
@@ -50,29 +50,18 @@ type APIKeyCreationRequest struct {
 	ProjectID string `json:"projectID"`
 }
 
+//CreateDefaultAPIKeyHandler creates default API key for the project
+//project is taken from the context: it has been checked by the authenticator (from body projectID field)
 func (akh *APIKeysHandler) CreateDefaultAPIKeyHandler(c *gin.Context) {
-	body := DbCreationRequestBody{}
-	if err := c.BindJSON(&body); err != nil {
-		c.JSON(http.StatusBadRequest, enmiddleware.ErrResponse("Failed to parse request body", err))
-		return
-	}
-	if body.ProjectID == "" {
-		c.JSON(http.StatusBadRequest, enmiddleware.ErrResponse(ErrProjectIDRequired.Error(), nil))
-		return
-	}
-	userProjectID := c.GetString(middleware.ProjectIDKey)
-	if userProjectID == "" {
+	projectID := c.GetString(middleware.ProjectIDKey)
+	if projectID == "" {
 		logging.SystemError(ErrProjectIDNotFoundInContext)
 		c.JSON(http.StatusUnauthorized, enmiddleware.ErrResponse("Project authorization error", ErrProjectIDNotFoundInContext))
 		return
 	}
 
-	if userProjectID != body.ProjectID {
-		c.JSON(http.StatusUnauthorized, enmiddleware.ErrResponse("User does not have access to project "+body.ProjectID, nil))
-		return
-	}
-	if err := akh.configurationsService.CreateDefaultAPIKey(body.ProjectID); err != nil {
-		c.JSON(http.StatusUnauthorized, enmiddleware.ErrResponse("Failed to create key for project "+body.ProjectID, err))
+	if err := akh.configurationsService.CreateDefaultAPIKey(projectID, c.GetString(middleware.UserIDKey)); err != nil {
+		c.JSON(http.StatusUnauthorized, enmiddleware.ErrResponse("Failed to create key for project "+projectID, err))
 		return
 	}
 	c.JSON(http.StatusOK, enmiddleware.OKResponse())
@@ -142,7 +142,7 @@ func (ah *AuthorizationHandler) OnboardedSignUp(c *gin.Context) {
 	}
 
 	//store telemetry settings
-	err = ah.configService.SaveTelemetry(map[string]bool{telemetryUsageKey: req.UsageOptout})
+	err = ah.configService.SaveTelemetry(map[string]bool{telemetryUsageKey: req.UsageOptout}, td.AccessToken.UserID)
 	if err != nil {
 		logging.Errorf("Error saving telemetry configuration [%v] to storage: %v", req.UsageOptout, err)
 	}
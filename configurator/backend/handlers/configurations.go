@@ -10,8 +10,17 @@ import (
 	"github.com/jitsucom/jitsu/configurator/storages"
 	mdlwr "github.com/jitsucom/jitsu/server/middleware"
 	"net/http"
+	"strconv"
 )
 
+type RevisionsResponse struct {
+	Revisions []*storages.Revision `json:"revisions"`
+}
+
+type RevisionDiffResponse struct {
+	Changes []*storages.Change `json:"changes"`
+}
+
 type ConfigurationHandler struct {
 	configurationsService *storages.ConfigurationsService
 	configStorage         storages.ConfigurationsStorage
@@ -68,7 +77,7 @@ func (ch *ConfigurationHandler) StoreUserInfo(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, mdlwr.ErrResponse(bodyExtractionErrorMessage, nil))
 		return
 	}
-	err = ch.configurationsService.StoreConfig(authorization.UsersInfoCollection, userID, data)
+	err = ch.configurationsService.StoreConfig(authorization.UsersInfoCollection, userID, data, userID)
 	if err != nil {
 		configStoreErrorMessage := fmt.Sprintf("Failed to save user info [%s]: %v", userID, err)
 		c.JSON(http.StatusBadRequest, mdlwr.ErrResponse(configStoreErrorMessage, nil))
@@ -98,7 +107,7 @@ func (ch *ConfigurationHandler) saveConfig(c *gin.Context, collection string, id
 		c.JSON(http.StatusBadRequest, mdlwr.ErrResponse(bodyExtractionErrorMessage, nil))
 		return
 	}
-	err = ch.configurationsService.StoreConfig(collection, id, data)
+	err = ch.configurationsService.StoreConfig(collection, id, data, c.GetString(middleware.UserIDKey))
 	if err != nil {
 		configStoreErrorMessage := fmt.Sprintf("Failed to save collection [%s], id=[%s]: %v", collection, id, err)
 		c.JSON(http.StatusBadRequest, mdlwr.ErrResponse(configStoreErrorMessage, nil))
@@ -107,6 +116,108 @@ func (ch *ConfigurationHandler) saveConfig(c *gin.Context, collection string, id
 	c.JSON(http.StatusOK, mdlwr.OKResponse())
 }
 
+//GetRevisions returns all revisions of the configuration (collection + id query parameter)
+func (ch *ConfigurationHandler) GetRevisions(c *gin.Context) {
+	configID := c.Query("id")
+	if configID == "" {
+		c.JSON(http.StatusBadRequest, mdlwr.ErrResponse("Required query parameter [id] is empty", nil))
+		return
+	}
+
+	revisions, err := ch.configurationsService.GetRevisions(c.Param("collection"), configID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, mdlwr.ErrResponse("Failed to get configuration revisions", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, RevisionsResponse{Revisions: revisions})
+}
+
+//GetRevision returns the configuration revision with payload
+func (ch *ConfigurationHandler) GetRevision(c *gin.Context) {
+	configID, version, ok := revisionParams(c)
+	if !ok {
+		return
+	}
+
+	revision, err := ch.configurationsService.GetRevision(c.Param("collection"), configID, version)
+	if err != nil {
+		writeRevisionError(c, "Failed to get configuration revision", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, revision)
+}
+
+//DiffRevision returns changes between the revision and the revision from compare_to query parameter
+//or the current configuration state if compare_to isn't provided
+func (ch *ConfigurationHandler) DiffRevision(c *gin.Context) {
+	configID, version, ok := revisionParams(c)
+	if !ok {
+		return
+	}
+
+	var compareTo int64
+	if compareToStr := c.Query("compare_to"); compareToStr != "" {
+		var err error
+		compareTo, err = strconv.ParseInt(compareToStr, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, mdlwr.ErrResponse("Query parameter [compare_to] must be a revision version number", err))
+			return
+		}
+	}
+
+	changes, err := ch.configurationsService.DiffRevisions(c.Param("collection"), configID, version, compareTo)
+	if err != nil {
+		writeRevisionError(c, "Failed to build configuration revisions diff", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, RevisionDiffResponse{Changes: changes})
+}
+
+//RestoreRevision stores the revision payload as the current configuration state (as a new revision)
+func (ch *ConfigurationHandler) RestoreRevision(c *gin.Context) {
+	configID, version, ok := revisionParams(c)
+	if !ok {
+		return
+	}
+
+	revision, err := ch.configurationsService.RestoreRevision(c.Param("collection"), configID, version, c.GetString(middleware.UserIDKey))
+	if err != nil {
+		writeRevisionError(c, "Failed to restore configuration revision", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, revision)
+}
+
+//revisionParams returns id query parameter and version path parameter or writes bad request response
+func revisionParams(c *gin.Context) (string, int64, bool) {
+	configID := c.Query("id")
+	if configID == "" {
+		c.JSON(http.StatusBadRequest, mdlwr.ErrResponse("Required query parameter [id] is empty", nil))
+		return "", 0, false
+	}
+
+	version, err := strconv.ParseInt(c.Param("version"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, mdlwr.ErrResponse("Path parameter [version] must be a revision version number", err))
+		return "", 0, false
+	}
+
+	return configID, version, true
+}
+
+func writeRevisionError(c *gin.Context, msg string, err error) {
+	if err == storages.ErrRevisionNotFound {
+		c.JSON(http.StatusNotFound, mdlwr.ErrResponse(err.Error(), nil))
+		return
+	}
+
+	c.JSON(http.StatusInternalServerError, mdlwr.ErrResponse(msg, err))
+}
+
 func writeResponse(c *gin.Context, config []byte) {
 	c.Header("Content-Type", jsonContentType)
 	c.Writer.WriteHeader(http.StatusOK)
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jitsucom/jitsu/configurator/storages"
+	"github.com/jitsucom/jitsu/server/logging"
+	jmiddleware "github.com/jitsucom/jitsu/server/middleware"
+	jssl "github.com/jitsucom/jitsu/server/ssl"
+)
+
+const customDomainsGettingErrMsg = "Custom domains getting error"
+
+//CustomDomainsHandler serves custom domains of all projects to Jitsu Server (for issuing SSL certificates)
+type CustomDomainsHandler struct {
+	configurationsService *storages.ConfigurationsService
+}
+
+//NewCustomDomainsHandler returns configured CustomDomainsHandler instance
+func NewCustomDomainsHandler(configurationsService *storages.ConfigurationsService) *CustomDomainsHandler {
+	return &CustomDomainsHandler{
+		configurationsService: configurationsService,
+	}
+}
+
+//GetHandler returns sorted unique custom domain names of all projects
+func (cdh *CustomDomainsHandler) GetHandler(c *gin.Context) {
+	begin := time.Now()
+	customDomainsByProject, err := cdh.configurationsService.GetCustomDomains()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, jmiddleware.ErrResponse(customDomainsGettingErrMsg, err))
+		return
+	}
+
+	domainsSet := map[string]bool{}
+	for _, customDomains := range customDomainsByProject {
+		for _, domain := range customDomains.Domains {
+			if domain != nil && domain.Name != "" {
+				domainsSet[domain.Name] = true
+			}
+		}
+	}
+
+	domains := make([]string, 0, len(domainsSet))
+	for domain := range domainsSet {
+		domains = append(domains, domain)
+	}
+	sort.Strings(domains)
+
+	logging.Debugf("Custom domains response in [%.2f] seconds", time.Now().Sub(begin).Seconds())
+	c.JSON(http.StatusOK, &jssl.DomainsPayload{Domains: domains})
+}
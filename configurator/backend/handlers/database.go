@@ -26,26 +26,17 @@ func NewDatabaseHandler(configurationsStorage *storages.ConfigurationsService) *
 	return &DatabaseHandler{storage: configurationsStorage}
 }
 
+//PostHandler creates default database for the project
+//project is taken from the context: it has been checked by the authenticator (from body projectID field)
 func (eh *DatabaseHandler) PostHandler(c *gin.Context) {
-	body := DbCreationRequestBody{}
-	if err := c.BindJSON(&body); err != nil {
-		c.JSON(http.StatusBadRequest, enmiddleware.ErrResponse("Invalid input JSON", err))
-		return
-	}
-	projectID := body.ProjectID
-	userProjectID := c.GetString(middleware.ProjectIDKey)
-	if userProjectID == "" {
+	projectID := c.GetString(middleware.ProjectIDKey)
+	if projectID == "" {
 		logging.SystemError(ErrProjectIDNotFoundInContext)
 		c.JSON(http.StatusUnauthorized, enmiddleware.ErrResponse("Project authorization error", ErrProjectIDNotFoundInContext))
 		return
 	}
 
-	if userProjectID != projectID {
-		c.JSON(http.StatusUnauthorized, enmiddleware.ErrResponse("User does not have access to project "+projectID, nil))
-		return
-	}
-
-	database, err := eh.storage.CreateDefaultDestination(projectID)
+	database, err := eh.storage.CreateDefaultDestination(projectID, c.GetString(middleware.UserIDKey))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, enmiddleware.ErrResponse("Failed to create a database for project "+projectID, err))
 		return
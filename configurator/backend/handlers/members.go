@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/jitsucom/jitsu/configurator/authorization"
+	"github.com/jitsucom/jitsu/configurator/emails"
+	"github.com/jitsucom/jitsu/configurator/middleware"
+	"github.com/jitsucom/jitsu/server/logging"
+	jmiddleware "github.com/jitsucom/jitsu/server/middleware"
+	"net/http"
+	"strings"
+)
+
+type InvitationRequest struct {
+	Email    string             `json:"email"`
+	Role     authorization.Role `json:"role"`
+	Callback string             `json:"callback"`
+}
+
+func (ir *InvitationRequest) Validate() error {
+	if ir.Email == "" {
+		return errors.New("email is required field")
+	}
+
+	if ir.Role == "" {
+		return errors.New("role is required field")
+	}
+
+	return nil
+}
+
+type RoleRequest struct {
+	Role authorization.Role `json:"role"`
+}
+
+type InvitationResponse struct {
+	Invitation *authorization.Invitation `json:"invitation"`
+	//Link is returned when the invitation email wasn't sent (e.g. SMTP isn't configured)
+	Link string `json:"link,omitempty"`
+}
+
+type ProjectMembersResponse struct {
+	Members []*authorization.ProjectMember `json:"members"`
+}
+
+type UserProjectsResponse struct {
+	Projects map[string]authorization.Role `json:"projects"`
+}
+
+type AcceptInvitationResponse struct {
+	ProjectID string `json:"project_id"`
+}
+
+//ProjectMembersHandler handles project members and invitations requests
+type ProjectMembersHandler struct {
+	authService  *authorization.Service
+	emailService *emails.Service
+}
+
+func NewProjectMembersHandler(authService *authorization.Service, emailService *emails.Service) *ProjectMembersHandler {
+	return &ProjectMembersHandler{authService: authService, emailService: emailService}
+}
+
+//GetUserProjectsHandler returns all projects with roles which are available to the current user
+func (pmh *ProjectMembersHandler) GetUserProjectsHandler(c *gin.Context) {
+	userID := c.GetString(middleware.UserIDKey)
+
+	projects, err := pmh.authService.GetUserProjects(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, jmiddleware.ErrResponse("Error getting user projects", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, UserProjectsResponse{Projects: projects})
+}
+
+//GetMembersHandler returns all project members
+func (pmh *ProjectMembersHandler) GetMembersHandler(c *gin.Context) {
+	projectID := c.GetString(middleware.ProjectIDKey)
+	userID := c.GetString(middleware.UserIDKey)
+
+	members, err := pmh.authService.GetProjectMembers(projectID, userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, jmiddleware.ErrResponse("Error getting project members", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, ProjectMembersResponse{Members: members})
+}
+
+//InviteHandler creates an invitation and sends it by email (if SMTP is configured)
+//otherwise returns the invitation link
+func (pmh *ProjectMembersHandler) InviteHandler(c *gin.Context) {
+	req := &InvitationRequest{}
+	if err := c.BindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, jmiddleware.ErrResponse("Invalid input JSON", err))
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, jmiddleware.ErrResponse("Invalid input data", err))
+		return
+	}
+
+	projectID := c.GetString(middleware.ProjectIDKey)
+	userID := c.GetString(middleware.UserIDKey)
+
+	invitation, err := pmh.authService.CreateInvitation(projectID, userID, req.Email, req.Role)
+	if err != nil {
+		writeMembersError(c, "Error creating invitation", err)
+		return
+	}
+
+	link := strings.ReplaceAll(req.Callback, "{{token}}", invitation.ID)
+	if !pmh.emailService.IsConfigured() || req.Callback == "" {
+		c.JSON(http.StatusOK, InvitationResponse{Invitation: invitation, Link: link})
+		return
+	}
+
+	if err := pmh.emailService.SendInvitation(invitation.Email, string(invitation.Role), link); err != nil {
+		logging.Errorf("Error sending invitation [%s] email: %v", invitation.ID, err)
+		c.JSON(http.StatusInternalServerError, jmiddleware.ErrResponse("Error sending email message", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, InvitationResponse{Invitation: invitation})
+}
+
+//AcceptInvitationHandler adds the current user to the invitation project
+func (pmh *ProjectMembersHandler) AcceptInvitationHandler(c *gin.Context) {
+	userID := c.GetString(middleware.UserIDKey)
+	invitationID := c.Param("invitationID")
+
+	projectID, err := pmh.authService.AcceptInvitation(invitationID, userID)
+	if err != nil {
+		if err == authorization.ErrInvitationNotFound || err == authorization.ErrInvitationAccepted || err == authorization.ErrInvitationExpired {
+			c.JSON(http.StatusBadRequest, jmiddleware.ErrResponse(err.Error(), nil))
+			return
+		}
+
+		if err == authorization.ErrInvitationEmail {
+			c.JSON(http.StatusForbidden, jmiddleware.ErrResponse(err.Error(), nil))
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, jmiddleware.ErrResponse("Error accepting invitation", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, AcceptInvitationResponse{ProjectID: projectID})
+}
+
+//UpdateMemberRoleHandler changes the project member role
+func (pmh *ProjectMembersHandler) UpdateMemberRoleHandler(c *gin.Context) {
+	req := &RoleRequest{}
+	if err := c.BindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, jmiddleware.ErrResponse("Invalid input JSON", err))
+		return
+	}
+
+	projectID := c.GetString(middleware.ProjectIDKey)
+	userID := c.GetString(middleware.UserIDKey)
+
+	if err := pmh.authService.UpdateMemberRole(projectID, userID, c.Param("userID"), req.Role); err != nil {
+		writeMembersError(c, "Error updating member role", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, jmiddleware.OKResponse())
+}
+
+//RemoveMemberHandler removes the member from the project
+func (pmh *ProjectMembersHandler) RemoveMemberHandler(c *gin.Context) {
+	projectID := c.GetString(middleware.ProjectIDKey)
+	userID := c.GetString(middleware.UserIDKey)
+
+	if err := pmh.authService.RemoveMember(projectID, userID, c.Param("userID")); err != nil {
+		writeMembersError(c, "Error removing member", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, jmiddleware.OKResponse())
+}
+
+func writeMembersError(c *gin.Context, msg string, err error) {
+	switch err {
+	case authorization.ErrPermissionDenied, authorization.ErrProjectAccessDenied:
+		c.JSON(http.StatusForbidden, jmiddleware.ErrResponse(err.Error(), nil))
+	case authorization.ErrInvalidRole, authorization.ErrMemberNotFound, authorization.ErrLastOwner:
+		c.JSON(http.StatusBadRequest, jmiddleware.ErrResponse(err.Error(), nil))
+	default:
+		c.JSON(http.StatusInternalServerError, jmiddleware.ErrResponse(msg, err))
+	}
+}
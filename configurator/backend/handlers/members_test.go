@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jitsucom/jitsu/configurator/authorization"
+	"github.com/jitsucom/jitsu/configurator/emails"
+	"github.com/jitsucom/jitsu/configurator/middleware"
+	"github.com/jitsucom/jitsu/configurator/storages"
+	entime "github.com/jitsucom/jitsu/configurator/time"
+	"github.com/stretchr/testify/require"
+)
+
+const testUserHeader = "X-Test-User"
+
+//newTestMembersRouter returns router with members API of project1 where 'owner' is the owner and 'editor' is an editor
+//users are taken from testUserHeader (authorization is checked by middleware in production)
+func newTestMembersRouter(t *testing.T) (*gin.Engine, *authorization.Service, *storages.Mock) {
+	storage := storages.NewMock()
+	require.NoError(t, storage.Store(authorization.UsersInfoCollection, "owner", &authorization.UserInfo{Project: authorization.Project{ID: "project1"}}))
+
+	provider := &authorization.ProviderMock{Emails: map[string]string{"owner": "owner@example.com", "editor": "editor@example.com", "viewer": "viewer@example.com"}}
+	authService := authorization.NewServiceWithProvider(provider, storage)
+
+	invitation, err := authService.CreateInvitation("project1", "owner", "editor@example.com", authorization.EditorRole)
+	require.NoError(t, err)
+	_, err = authService.AcceptInvitation(invitation.ID, "editor")
+	require.NoError(t, err)
+
+	emailService, err := emails.NewService(nil)
+	require.NoError(t, err)
+	handler := NewProjectMembersHandler(authService, emailService)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set(middleware.UserIDKey, c.GetHeader(testUserHeader))
+		c.Set(middleware.ProjectIDKey, "project1")
+	})
+	router.GET("/members", handler.GetMembersHandler)
+	router.PUT("/members/:userID", handler.UpdateMemberRoleHandler)
+	router.DELETE("/members/:userID", handler.RemoveMemberHandler)
+	router.POST("/invitations", handler.InviteHandler)
+	router.POST("/invitations/:invitationID/accept", handler.AcceptInvitationHandler)
+
+	return router, authService, storage
+}
+
+func doTestRequest(router *gin.Engine, method, url, userID, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, url, strings.NewReader(body))
+	req.Header.Set(testUserHeader, userID)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestProjectMembersHandlers(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		url          string
+		userID       string
+		body         string
+		expectedCode int
+	}{
+		{"Get members", http.MethodGet, "/members", "editor", "", http.StatusOK},
+		{"Invite", http.MethodPost, "/invitations", "owner", `{"email":"viewer@example.com","role":"viewer"}`, http.StatusOK},
+		{"Invite with invalid JSON", http.MethodPost, "/invitations", "owner", `{`, http.StatusBadRequest},
+		{"Invite without email", http.MethodPost, "/invitations", "owner", `{"role":"viewer"}`, http.StatusBadRequest},
+		{"Invite with unknown role", http.MethodPost, "/invitations", "owner", `{"email":"viewer@example.com","role":"superuser"}`, http.StatusBadRequest},
+		{"Editor can't invite", http.MethodPost, "/invitations", "editor", `{"email":"viewer@example.com","role":"viewer"}`, http.StatusForbidden},
+		{"Update role", http.MethodPut, "/members/editor", "owner", `{"role":"admin"}`, http.StatusOK},
+		{"Editor can't update role", http.MethodPut, "/members/editor", "editor", `{"role":"admin"}`, http.StatusForbidden},
+		{"Last owner can't be demoted", http.MethodPut, "/members/owner", "owner", `{"role":"viewer"}`, http.StatusBadRequest},
+		{"Remove member", http.MethodDelete, "/members/editor", "owner", "", http.StatusOK},
+		{"Remove unknown member", http.MethodDelete, "/members/viewer", "owner", "", http.StatusBadRequest},
+		{"Editor can't remove owner", http.MethodDelete, "/members/owner", "editor", "", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, _, _ := newTestMembersRouter(t)
+
+			w := doTestRequest(router, tt.method, tt.url, tt.userID, tt.body)
+			require.Equal(t, tt.expectedCode, w.Code, w.Body.String())
+		})
+	}
+}
+
+func TestInviteHandlerReturnsLink(t *testing.T) {
+	router, _, _ := newTestMembersRouter(t)
+
+	w := doTestRequest(router, http.MethodPost, "/invitations", "owner", `{"email":"viewer@example.com","role":"viewer","callback":"https://app.example.com/accept/{{token}}"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	resp := &InvitationResponse{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), resp))
+	require.Equal(t, authorization.ViewerRole, resp.Invitation.Role)
+	require.Equal(t, "https://app.example.com/accept/"+resp.Invitation.ID, resp.Link, "link must be returned when SMTP isn't configured")
+}
+
+func TestAcceptInvitationHandler(t *testing.T) {
+	tests := []struct {
+		name         string
+		userID       string
+		expired      bool
+		unknown      bool
+		expectedCode int
+	}{
+		{"Accept", "viewer", false, false, http.StatusOK},
+		{"Accept by another user", "editor", false, false, http.StatusForbidden},
+		{"Accept expired", "viewer", true, false, http.StatusBadRequest},
+		{"Accept unknown", "viewer", false, true, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, authService, storage := newTestMembersRouter(t)
+
+			invitation, err := authService.CreateInvitation("project1", "owner", "viewer@example.com", authorization.ViewerRole)
+			require.NoError(t, err)
+			if tt.expired {
+				invitation.ExpiresAt = entime.AsISOString(time.Now().UTC().Add(-time.Minute))
+				require.NoError(t, storage.Store(authorization.InvitationsCollection, invitation.ID, invitation))
+			}
+			invitationID := invitation.ID
+			if tt.unknown {
+				invitationID = "invitation-unknown"
+			}
+
+			w := doTestRequest(router, http.MethodPost, "/invitations/"+invitationID+"/accept", tt.userID, "")
+			require.Equal(t, tt.expectedCode, w.Code, w.Body.String())
+			if tt.expectedCode != http.StatusOK {
+				return
+			}
+
+			resp := &AcceptInvitationResponse{}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), resp))
+			require.Equal(t, "project1", resp.ProjectID)
+
+			w = doTestRequest(router, http.MethodPost, "/invitations/"+invitationID+"/accept", tt.userID, "")
+			require.Equal(t, http.StatusBadRequest, w.Code, "invitation can be accepted only once")
+		})
+	}
+}
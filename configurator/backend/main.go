@@ -145,7 +145,11 @@ func main() {
 		logging.Fatalf("Error creating configurations storage: %v", err)
 	}
 
-	configurationsService := storages.NewConfigurationsService(configurationsStorage, defaultPostgres)
+	revisionsRetention := &storages.RevisionsRetention{
+		MaxCount: viper.GetInt("server.revisions.max_count"),
+		MaxAge:   time.Duration(viper.GetInt("server.revisions.max_age_days")) * 24 * time.Hour,
+	}
+	configurationsService := storages.NewConfigurationsService(configurationsStorage, defaultPostgres, revisionsRetention)
 	if err != nil {
 		logging.Fatalf("Error creating configurations service: %v", err)
 	}
@@ -264,47 +268,66 @@ func SetupRouter(jitsuService *jitsu.Service, configurationsStorage storages.Con
 		"/proxy/api/v1/events/cache": jitsu.NewEventsCacheDecorator(configurationsService).Decorate,
 		"/proxy/api/v1/statistics":   jitsu.NewStatisticsDecorator().Decorate,
 	})
-	router.Any("/proxy/*path", authenticatorMiddleware.ClientProjectAuth(proxyHandler.Handler))
+	router.Any("/proxy/*path", authenticatorMiddleware.ProjectAccessByMethod(proxyHandler.Handler))
 
 	apiV1 := router.Group("/api/v1")
 	{
-		apiV1.POST("/notify", authenticatorMiddleware.ClientProjectAuth(handlers.NotifyHandler))
-		apiV1.POST("/database", authenticatorMiddleware.ClientProjectAuth(handlers.NewDatabaseHandler(configurationsService).PostHandler))
-		apiV1.POST("/apikeys/default", authenticatorMiddleware.ClientProjectAuth(apiKeysHandler.CreateDefaultAPIKeyHandler))
+		apiV1.POST("/notify", authenticatorMiddleware.ProjectAccess(handlers.NotifyHandler, authorization.ReadPermission))
+		apiV1.POST("/database", authenticatorMiddleware.ProjectAccessByBody(handlers.NewDatabaseHandler(configurationsService).PostHandler, authorization.WritePermission))
+		apiV1.POST("/apikeys/default", authenticatorMiddleware.ProjectAccessByBody(apiKeysHandler.CreateDefaultAPIKeyHandler, authorization.WritePermission))
 
 		apiV1.GET("/apikeys", middleware.ServerAuth(middleware.IfModifiedSince(apiKeysHandler.GetHandler, configurationsService.GetAPIKeysLastUpdated), serverToken))
 
-		apiV1.GET("/jitsu/configuration", authenticatorMiddleware.ClientProjectAuth(handlers.NewConfigurationHandler(configurationsService).Handler))
+		apiV1.GET("/jitsu/configuration", authenticatorMiddleware.ProjectAccess(handlers.NewConfigurationHandler(configurationsService).Handler, authorization.ReadPermission))
 
 		if sslUpdateExecutor != nil {
-			apiV1.POST("/ssl", authenticatorMiddleware.ClientProjectAuth(handlers.NewCustomDomainHandler(sslUpdateExecutor).PerProjectHandler))
+			apiV1.POST("/ssl", authenticatorMiddleware.ProjectAccess(handlers.NewCustomDomainHandler(sslUpdateExecutor).PerProjectHandler, authorization.WritePermission))
 			apiV1.POST("/ssl/all", middleware.ServerAuth(handlers.NewCustomDomainHandler(sslUpdateExecutor).AllHandler, serverToken))
 		}
 
 		destinationsHandler := handlers.NewDestinationsHandler(configurationsService, defaultS3, jitsuService)
 		apiV1.GET("/destinations", middleware.ServerAuth(middleware.IfModifiedSince(destinationsHandler.GetHandler, configurationsService.GetDestinationsLastUpdated), serverToken))
-		apiV1.POST("/destinations/test", authenticatorMiddleware.ClientProjectAuth(destinationsHandler.TestHandler))
+		apiV1.POST("/destinations/test", authenticatorMiddleware.ProjectAccess(destinationsHandler.TestHandler, authorization.WritePermission))
 
 		sourcesHandler := handlers.NewSourcesHandler(configurationsService, jitsuService)
 		apiV1.GET("/sources", middleware.ServerAuth(middleware.IfModifiedSince(sourcesHandler.GetHandler, configurationsService.GetSourcesLastUpdated), serverToken))
-		apiV1.POST("/sources/test", authenticatorMiddleware.ClientProjectAuth(sourcesHandler.TestHandler))
+		apiV1.POST("/sources/test", authenticatorMiddleware.ProjectAccess(sourcesHandler.TestHandler, authorization.WritePermission))
 
 		telemetryHandler := handlers.NewTelemetryHandler(configurationsService)
 		apiV1.GET("/telemetry", middleware.ServerAuth(telemetryHandler.GetHandler, serverToken))
 
 		apiV1.GET("/become", authenticatorMiddleware.ClientAuth(handlers.NewBecomeUserHandler(authService).Handler))
 
-		apiV1.GET("/configurations/:collection", authenticatorMiddleware.ClientProjectAuth(enConfigurationsHandler.GetConfig))
-		apiV1.POST("/configurations/:collection", authenticatorMiddleware.ClientProjectAuth(enConfigurationsHandler.StoreConfig))
+		apiV1.GET("/configurations/:collection", authenticatorMiddleware.ConfigurationAccess(enConfigurationsHandler.GetConfig))
+		apiV1.POST("/configurations/:collection", authenticatorMiddleware.ConfigurationAccess(enConfigurationsHandler.StoreConfig))
+		apiV1.GET("/configurations/:collection/revisions", authenticatorMiddleware.ConfigurationAccess(enConfigurationsHandler.GetRevisions))
+		apiV1.GET("/configurations/:collection/revisions/:version", authenticatorMiddleware.ConfigurationAccess(enConfigurationsHandler.GetRevision))
+		apiV1.GET("/configurations/:collection/revisions/:version/diff", authenticatorMiddleware.ConfigurationAccess(enConfigurationsHandler.DiffRevision))
+		apiV1.POST("/configurations/:collection/revisions/:version/restore", authenticatorMiddleware.ConfigurationAccess(enConfigurationsHandler.RestoreRevision))
 
 		apiV1.GET("/system/configuration", handlers.NewSystemHandler(authService, configurationsService, emailService.IsConfigured(), viper.GetBool("server.self_hosted"), *dockerHubID).GetHandler)
 		apiV1.GET("/system/version", func(c *gin.Context) {
 			c.JSON(http.StatusOK, Version{tag, builtAt})
 		})
 
+		customDomainsHandler := handlers.NewCustomDomainsHandler(configurationsService)
+		apiV1.GET("/custom_domains", middleware.ServerAuth(middleware.IfModifiedSince(customDomainsHandler.GetHandler, configurationsService.GetCustomDomainsLastUpdated), serverToken))
+
 		geoDataResolversHandler := handlers.NewGeoDataResolversHandler(configurationsService)
 		apiV1.GET("/geo_data_resolvers", middleware.ServerAuth(middleware.IfModifiedSince(geoDataResolversHandler.GetHandler, configurationsService.GetGeoDataResolversLastUpdated), serverToken))
 
+		//project members (roles and invitations)
+		membersHandler := handlers.NewProjectMembersHandler(authService, emailService)
+		apiV1.GET("/projects", authenticatorMiddleware.ClientAuth(membersHandler.GetUserProjectsHandler))
+		projectsAPIGroup := apiV1.Group("/projects/:projectID")
+		{
+			projectsAPIGroup.GET("/members", authenticatorMiddleware.ProjectAccess(membersHandler.GetMembersHandler, authorization.ReadPermission))
+			projectsAPIGroup.PUT("/members/:userID", authenticatorMiddleware.ProjectAccess(membersHandler.UpdateMemberRoleHandler, authorization.ManageMembersPermission))
+			projectsAPIGroup.DELETE("/members/:userID", authenticatorMiddleware.ProjectAccess(membersHandler.RemoveMemberHandler, authorization.ManageMembersPermission))
+			projectsAPIGroup.POST("/invitations", authenticatorMiddleware.ProjectAccess(membersHandler.InviteHandler, authorization.ManageMembersPermission))
+		}
+		apiV1.POST("/invitations/:invitationID/accept", authenticatorMiddleware.ClientAuth(membersHandler.AcceptInvitationHandler))
+
 		usersAPIGroup := apiV1.Group("/users")
 		{
 			usersAPIGroup.GET("/info", authenticatorMiddleware.ClientAuth(enConfigurationsHandler.GetUserInfo))
@@ -1,20 +1,36 @@
 package middleware
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/jitsucom/jitsu/configurator/authorization"
 	"github.com/jitsucom/jitsu/server/logging"
 	"github.com/jitsucom/jitsu/server/middleware"
+	"io/ioutil"
 	"net/http"
 )
 
 const (
 	ProjectIDKey     = "_project_id"
 	UserIDKey        = "_user_id"
+	RoleKey          = "_role"
 	TokenKey         = "_token"
 	ClientAuthHeader = "X-Client-Auth"
 )
 
+//globalConfigurationCollections are configurations which aren't stored per project
+var globalConfigurationCollections = map[string]bool{"telemetry": true}
+
+//membershipCollections are managed only with project members API (role checks)
+//they aren't available via generic configurations API
+var membershipCollections = map[string]bool{
+	authorization.ProjectMembersCollection: true,
+	authorization.UserProjectsCollection:   true,
+	authorization.InvitationsCollection:    true,
+}
+
 type Authenticator struct {
 	service *authorization.Service
 }
@@ -23,7 +39,53 @@ func NewAuthenticator(service *authorization.Service) *Authenticator {
 	return &Authenticator{service: service}
 }
 
-func (a *Authenticator) ClientProjectAuth(main gin.HandlerFunc) gin.HandlerFunc {
+//ProjectAccess authenticates user and checks that user role in the requested project has the permission
+//project is taken from 'projectID' path parameter or 'project_id' query parameter or user's own project
+func (a *Authenticator) ProjectAccess(main gin.HandlerFunc, permission authorization.Permission) gin.HandlerFunc {
+	return a.projectAccess(main, func(c *gin.Context) authorization.Permission { return permission }, func(c *gin.Context) string {
+		if projectID := c.Param("projectID"); projectID != "" {
+			return projectID
+		}
+
+		return c.Query("project_id")
+	})
+}
+
+//ProjectAccessByBody is the same as ProjectAccess but project is taken from 'project_id' query parameter
+//or 'project_id' ('projectID') field of JSON body. Handlers must use project from the context (ProjectIDKey)
+func (a *Authenticator) ProjectAccessByBody(main gin.HandlerFunc, permission authorization.Permission) gin.HandlerFunc {
+	return a.projectAccess(main, func(c *gin.Context) authorization.Permission { return permission }, projectIDFromQueryOrBody)
+}
+
+//ProjectAccessByMethod is the same as ProjectAccess but requires authorization.ReadPermission for reading HTTP methods
+//and authorization.WritePermission for others
+func (a *Authenticator) ProjectAccessByMethod(main gin.HandlerFunc) gin.HandlerFunc {
+	return a.projectAccess(main, permissionByMethod, projectIDFromQueryOrBody)
+}
+
+//ConfigurationAccess is the same as ProjectAccessByMethod but project is taken from 'id' query parameter
+//(configurations are stored per project). Global configurations (e.g. telemetry) are checked against user's own project
+//Membership collections (project members, user projects, invitations) are forbidden
+func (a *Authenticator) ConfigurationAccess(main gin.HandlerFunc) gin.HandlerFunc {
+	access := a.projectAccess(main, permissionByMethod, func(c *gin.Context) string {
+		if globalConfigurationCollections[c.Param("collection")] {
+			return ""
+		}
+
+		return c.Query("id")
+	})
+
+	return func(c *gin.Context) {
+		if collection := c.Param("collection"); membershipCollections[collection] {
+			c.JSON(http.StatusForbidden, middleware.ErrResponse(fmt.Sprintf("Collection [%s] is available only via project members API", collection), nil))
+			return
+		}
+
+		access(c)
+	}
+}
+
+func (a *Authenticator) projectAccess(main gin.HandlerFunc, permissionFunc func(c *gin.Context) authorization.Permission, projectIDFunc func(c *gin.Context) string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		token := c.GetHeader(ClientAuthHeader)
 		userID, err := a.service.Authenticate(token)
@@ -33,15 +95,37 @@ func (a *Authenticator) ClientProjectAuth(main gin.HandlerFunc) gin.HandlerFunc
 			return
 		}
 
-		projectID, err := a.service.GetProjectID(userID)
+		projectID := projectIDFunc(c)
+		if projectID == "" {
+			projectID, err = a.service.GetProjectID(userID)
+			if err != nil {
+				logging.Errorf("Project id error in token %s: %v", token, err)
+				c.JSON(http.StatusUnauthorized, middleware.ErrResponse("Authorization error", authorization.ErrProjectIDNotConfigured))
+				return
+			}
+		}
+
+		role, err := a.service.GetProjectRole(userID, projectID)
 		if err != nil {
-			logging.SystemErrorf("Project id error in token %s: %v", token, err)
+			if err == authorization.ErrProjectAccessDenied {
+				c.JSON(http.StatusForbidden, middleware.ErrResponse("User does not have access to project "+projectID, nil))
+				return
+			}
+
+			logging.SystemErrorf("Error getting user [%s] role in project [%s]: %v", userID, projectID, err)
 			c.JSON(http.StatusUnauthorized, middleware.ErrResponse("Authorization error", err))
 			return
 		}
 
+		permission := permissionFunc(c)
+		if !role.Has(permission) {
+			c.JSON(http.StatusForbidden, middleware.ErrResponse(fmt.Sprintf("User role [%s] does not have [%s] permission in project %s", role, permission, projectID), nil))
+			return
+		}
+
 		c.Set(ProjectIDKey, projectID)
 		c.Set(UserIDKey, userID)
+		c.Set(RoleKey, role)
 		c.Set(TokenKey, token)
 
 		main(c)
@@ -64,3 +148,41 @@ func (a *Authenticator) ClientAuth(main gin.HandlerFunc) gin.HandlerFunc {
 		main(c)
 	}
 }
+
+func permissionByMethod(c *gin.Context) authorization.Permission {
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return authorization.ReadPermission
+	default:
+		return authorization.WritePermission
+	}
+}
+
+//projectIDFromQueryOrBody returns 'project_id' query parameter or 'project_id' ('projectID') field from JSON body
+//keeps request body readable
+func projectIDFromQueryOrBody(c *gin.Context) string {
+	if projectID := c.Query("project_id"); projectID != "" {
+		return projectID
+	}
+
+	if c.Request.Body == nil {
+		return ""
+	}
+
+	contents, _ := ioutil.ReadAll(c.Request.Body)
+	c.Request.Body = ioutil.NopCloser(bytes.NewReader(contents))
+
+	body := &struct {
+		ProjectID          string `json:"project_id"`
+		CamelCaseProjectID string `json:"projectID"`
+	}{}
+	if err := json.Unmarshal(contents, body); err != nil {
+		return ""
+	}
+
+	if body.ProjectID != "" {
+		return body.ProjectID
+	}
+
+	return body.CamelCaseProjectID
+}
@@ -0,0 +1,150 @@
+package middleware
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jitsucom/jitsu/configurator/authorization"
+	"github.com/jitsucom/jitsu/configurator/storages"
+	"github.com/stretchr/testify/require"
+)
+
+type testResponse struct {
+	ProjectID string `json:"project_id"`
+	UserID    string `json:"user_id"`
+	Body      string `json:"body"`
+}
+
+func testHandler(c *gin.Context) {
+	body, _ := ioutil.ReadAll(c.Request.Body)
+	c.JSON(http.StatusOK, testResponse{ProjectID: c.GetString(ProjectIDKey), UserID: c.GetString(UserIDKey), Body: string(body)})
+}
+
+//newTestRouter returns router with Authenticator where 'owner' has own project1 and project2,
+//'editor' and 'viewer' are members of project1
+func newTestRouter(t *testing.T) *gin.Engine {
+	storage := storages.NewMock()
+	require.NoError(t, storage.Store(authorization.UsersInfoCollection, "owner", &authorization.UserInfo{Project: authorization.Project{ID: "project1"}}))
+	require.NoError(t, storage.Store(authorization.UsersInfoCollection, "owner2", &authorization.UserInfo{Project: authorization.Project{ID: "project2"}}))
+
+	provider := &authorization.ProviderMock{
+		Tokens: map[string]string{"owner-token": "owner", "editor-token": "editor", "viewer-token": "viewer", "owner2-token": "owner2", "stranger-token": "stranger"},
+		Emails: map[string]string{"owner": "owner@example.com", "editor": "editor@example.com", "viewer": "viewer@example.com"},
+	}
+	service := authorization.NewServiceWithProvider(provider, storage)
+
+	for userID, role := range map[string]authorization.Role{"editor": authorization.EditorRole, "viewer": authorization.ViewerRole} {
+		invitation, err := service.CreateInvitation("project1", "owner", userID+"@example.com", role)
+		require.NoError(t, err)
+		_, err = service.AcceptInvitation(invitation.ID, userID)
+		require.NoError(t, err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	authenticator := NewAuthenticator(service)
+	router.GET("/projects/:projectID/members", authenticator.ProjectAccess(testHandler, authorization.ReadPermission))
+	router.POST("/projects/:projectID/invitations", authenticator.ProjectAccess(testHandler, authorization.ManageMembersPermission))
+	router.POST("/database", authenticator.ProjectAccessByBody(testHandler, authorization.WritePermission))
+	router.Any("/apikeys", authenticator.ProjectAccessByMethod(testHandler))
+	router.Any("/configurations/:collection", authenticator.ConfigurationAccess(testHandler))
+	router.GET("/projects", authenticator.ClientAuth(testHandler))
+
+	return router
+}
+
+func TestProjectAccess(t *testing.T) {
+	router := newTestRouter(t)
+
+	tests := []struct {
+		name              string
+		method            string
+		url               string
+		body              string
+		token             string
+		expectedCode      int
+		expectedProjectID string
+	}{
+		{"Unknown token", http.MethodGet, "/projects/project1/members", "", "unknown", http.StatusUnauthorized, ""},
+		{"Owner reads own project", http.MethodGet, "/projects/project1/members", "", "owner-token", http.StatusOK, "project1"},
+		{"Viewer reads project", http.MethodGet, "/projects/project1/members", "", "viewer-token", http.StatusOK, "project1"},
+		{"Stranger can't read project", http.MethodGet, "/projects/project1/members", "", "stranger-token", http.StatusForbidden, ""},
+		{"Owner can't read another project", http.MethodGet, "/projects/project2/members", "", "owner-token", http.StatusForbidden, ""},
+		{"Owner invites", http.MethodPost, "/projects/project1/invitations", "{}", "owner-token", http.StatusOK, "project1"},
+		{"Editor can't invite", http.MethodPost, "/projects/project1/invitations", "{}", "editor-token", http.StatusForbidden, ""},
+
+		{"Project from body", http.MethodPost, "/database", `{"projectID":"project1"}`, "editor-token", http.StatusOK, "project1"},
+		{"Project from snake case body field", http.MethodPost, "/database", `{"project_id":"project1"}`, "editor-token", http.StatusOK, "project1"},
+		{"Project from query overrides body", http.MethodPost, "/database?project_id=project2", `{"projectID":"project1"}`, "editor-token", http.StatusForbidden, ""},
+		{"Viewer can't write project from body", http.MethodPost, "/database", `{"projectID":"project1"}`, "viewer-token", http.StatusForbidden, ""},
+		{"Owner can't write another project from body", http.MethodPost, "/database", `{"projectID":"project2"}`, "owner-token", http.StatusForbidden, ""},
+		{"Own project without body project", http.MethodPost, "/database", `{}`, "owner-token", http.StatusOK, "project1"},
+		{"User without own project", http.MethodPost, "/database", `{}`, "viewer-token", http.StatusUnauthorized, ""},
+
+		{"Viewer reads by method", http.MethodGet, "/apikeys?project_id=project1", "", "viewer-token", http.StatusOK, "project1"},
+		{"Viewer can't write by method", http.MethodPost, "/apikeys?project_id=project1", "{}", "viewer-token", http.StatusForbidden, ""},
+		{"Editor writes by method", http.MethodPost, "/apikeys?project_id=project1", "{}", "editor-token", http.StatusOK, "project1"},
+
+		{"Editor reads configuration", http.MethodGet, "/configurations/destinations?id=project1", "", "editor-token", http.StatusOK, "project1"},
+		{"Viewer can't write configuration", http.MethodPost, "/configurations/destinations?id=project1", "{}", "viewer-token", http.StatusForbidden, ""},
+		{"Global configuration is checked against own project", http.MethodPost, "/configurations/telemetry?id=global", "{}", "owner-token", http.StatusOK, "project1"},
+		{"Project members collection is forbidden", http.MethodGet, "/configurations/project_members?id=project1", "", "owner-token", http.StatusForbidden, ""},
+		{"Invitations collection is forbidden", http.MethodPost, "/configurations/invitations?id=project1", "{}", "owner-token", http.StatusForbidden, ""},
+		{"User projects collection is forbidden", http.MethodGet, "/configurations/user_projects?id=owner", "", "owner-token", http.StatusForbidden, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.url, strings.NewReader(tt.body))
+			req.Header.Set(ClientAuthHeader, tt.token)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			require.Equal(t, tt.expectedCode, w.Code, w.Body.String())
+			if tt.expectedCode != http.StatusOK {
+				return
+			}
+
+			resp := &testResponse{}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), resp))
+			require.Equal(t, tt.expectedProjectID, resp.ProjectID)
+			require.Equal(t, tt.body, resp.Body, "request body must be readable by handlers")
+		})
+	}
+}
+
+func TestClientAuth(t *testing.T) {
+	router := newTestRouter(t)
+
+	tests := []struct {
+		name           string
+		token          string
+		expectedCode   int
+		expectedUserID string
+	}{
+		{"Known token", "viewer-token", http.StatusOK, "viewer"},
+		{"Unknown token", "unknown", http.StatusUnauthorized, ""},
+		{"Empty token", "", http.StatusUnauthorized, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/projects", nil)
+			req.Header.Set(ClientAuthHeader, tt.token)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			require.Equal(t, tt.expectedCode, w.Code)
+			if tt.expectedCode != http.StatusOK {
+				return
+			}
+
+			resp := &testResponse{}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), resp))
+			require.Equal(t, tt.expectedUserID, resp.UserID)
+		})
+	}
+}
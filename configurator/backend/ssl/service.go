@@ -169,7 +169,7 @@ func NewCertificateService(sshClient *ssh.ClientWrapper, enHosts []string, confi
 }
 
 func (s *CertificateService) UpdateCustomDomains(projectID string, domains *entities.CustomDomains) error {
-	return s.configurationsService.UpdateCustomDomain(projectID, domains)
+	return s.configurationsService.UpdateCustomDomain(projectID, domains, storages.SystemAuthor)
 }
 
 func (s *CertificateService) LoadCustomDomains() (map[string]*entities.CustomDomains, error) {
@@ -3,13 +3,24 @@ package storages
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/jitsucom/jitsu/server/logging"
 	"github.com/jitsucom/jitsu/server/meta"
 	"github.com/spf13/viper"
 	"time"
 )
 
-var ErrConfigurationNotFound = errors.New("Configuration wasn't found")
+var (
+	ErrConfigurationNotFound = errors.New("Configuration wasn't found")
+	ErrLockTimeout           = errors.New("Timeout waiting for configurations lock")
+)
+
+const (
+	//lockTTL is a max lock holding time: the lock is released automatically if a node dies while holding it
+	lockTTL           = 30 * time.Second
+	lockWaitTimeout   = 10 * time.Second
+	lockRetryInterval = 100 * time.Millisecond
+)
 
 //ConfigurationsStorage - Collection here is used as a type of configuration - like destinations, api_keys, custom_domains, etc.
 type ConfigurationsStorage interface {
@@ -26,6 +37,18 @@ type ConfigurationsStorage interface {
 	UpdateCollectionLastUpdated(collection string) error
 	//Store saves entity and also must update _lastUpdated field of the collection
 	Store(collection string, id string, entity interface{}) error
+	//SaveRevision assigns the next version number (per collection and id) to the revision, saves it and returns the version
+	SaveRevision(revision *Revision) (int64, error)
+	//GetRevisions returns all revisions of a configuration sorted by version (the latest first)
+	GetRevisions(collection string, id string) ([]*Revision, error)
+	//GetRevision returns a single revision of a configuration
+	//If revision is not found, must return ErrRevisionNotFound
+	GetRevision(collection string, id string, version int64) (*Revision, error)
+	//DeleteRevisions deletes revisions of a configuration by versions (versions of next revisions aren't reused)
+	DeleteRevisions(collection string, id string, versions []int64) error
+	//Lock acquires a cluster-wide lock with the name (waits while it is held by other requests or configurator nodes)
+	//and returns a function for releasing it. If the lock isn't acquired in time, must return ErrLockTimeout
+	Lock(name string) (func(), error)
 	//Close frees all the resources used by the storage (close connections etc.)
 	Close() error
 }
@@ -56,3 +79,22 @@ func NewConfigurationsStorage(ctx context.Context, vp *viper.Viper) (Configurati
 		return nil, errors.New("Unknown 'storage' section type. Supported: firebase, redis")
 	}
 }
+
+//waitLock calls tryLock until the lock is acquired or lockWaitTimeout is over
+func waitLock(name string, tryLock func() (bool, error)) error {
+	deadline := time.Now().Add(lockWaitTimeout)
+	for {
+		acquired, err := tryLock()
+		if err != nil {
+			return fmt.Errorf("Error acquiring lock [%s]: %v", name, err)
+		}
+		if acquired {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return ErrLockTimeout
+		}
+		time.Sleep(lockRetryInterval)
+	}
+}
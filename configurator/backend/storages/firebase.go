@@ -8,14 +8,30 @@ import (
 	firebase "firebase.google.com/go/v4"
 	"fmt"
 	entime "github.com/jitsucom/jitsu/configurator/time"
+	"github.com/jitsucom/jitsu/server/logging"
+	uuid "github.com/satori/go.uuid"
 	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"strconv"
 	"time"
 )
 
-const defaultDocID = "default"
+const (
+	defaultDocID = "default"
+
+	//config_revisions/{collection#id} {version} - the last revision version of configuration
+	//config_revisions/{collection#id}/revisions/{version} - revision documents
+	revisionsCollection      = "config_revisions"
+	revisionsSubCollection   = "revisions"
+	revisionLastVersionField = "version"
+
+	//config_locks/{name} {token, expires_at} - cluster-wide locks
+	locksCollection    = "config_locks"
+	lockTokenField     = "token"
+	lockExpiresAtField = "expires_at"
+)
 
 type Firebase struct {
 	ctx    context.Context
@@ -112,6 +128,151 @@ func (fb *Firebase) Store(collection string, documentID string, entity interface
 	return err
 }
 
+//SaveRevision increments configuration version and saves revision in a transaction
+func (fb *Firebase) SaveRevision(revision *Revision) (int64, error) {
+	configRef := fb.client.Collection(revisionsCollection).Doc(revision.Collection + "#" + revision.ID)
+	err := fb.client.RunTransaction(fb.ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		var lastVersion int64
+		doc, err := tx.Get(configRef)
+		if err != nil && status.Code(err) != codes.NotFound {
+			return err
+		}
+		if err == nil {
+			if value, err := doc.DataAt(revisionLastVersionField); err == nil {
+				lastVersion, _ = value.(int64)
+			}
+		}
+
+		revision.Version = lastVersion + 1
+		if err := tx.Set(configRef, map[string]interface{}{revisionLastVersionField: revision.Version}); err != nil {
+			return err
+		}
+
+		return tx.Set(configRef.Collection(revisionsSubCollection).Doc(strconv.FormatInt(revision.Version, 10)), revision)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("Error storing revision of [%s], id=[%s]: %v", revision.Collection, revision.ID, err)
+	}
+
+	return revision.Version, nil
+}
+
+//GetRevisions returns all configuration revisions sorted by version desc
+func (fb *Firebase) GetRevisions(collection string, id string) ([]*Revision, error) {
+	iter := fb.client.Collection(revisionsCollection).Doc(collection+"#"+id).Collection(revisionsSubCollection).
+		OrderBy("version", firestore.Desc).Documents(fb.ctx)
+	revisions := []*Revision{}
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get revisions from firestore: %v", err)
+		}
+
+		revision := &Revision{}
+		if err := doc.DataTo(revision); err != nil {
+			return nil, fmt.Errorf("Error parsing revision [%s] of [%s], id=[%s]: %v", doc.Ref.ID, collection, id, err)
+		}
+		revisions = append(revisions, revision)
+	}
+
+	return revisions, nil
+}
+
+//GetRevision returns configuration revision by version or ErrRevisionNotFound
+func (fb *Firebase) GetRevision(collection string, id string, version int64) (*Revision, error) {
+	doc, err := fb.client.Collection(revisionsCollection).Doc(collection + "#" + id).Collection(revisionsSubCollection).
+		Doc(strconv.FormatInt(version, 10)).Get(fb.ctx)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return nil, ErrRevisionNotFound
+		}
+		return nil, err
+	}
+
+	revision := &Revision{}
+	if err := doc.DataTo(revision); err != nil {
+		return nil, fmt.Errorf("Error parsing revision [%d] of [%s], id=[%s]: %v", version, collection, id, err)
+	}
+
+	return revision, nil
+}
+
+//DeleteRevisions deletes configuration revisions by versions in a batch
+func (fb *Firebase) DeleteRevisions(collection string, id string, versions []int64) error {
+	if len(versions) == 0 {
+		return nil
+	}
+
+	revisionsRef := fb.client.Collection(revisionsCollection).Doc(collection + "#" + id).Collection(revisionsSubCollection)
+	batch := fb.client.Batch()
+	for _, version := range versions {
+		batch.Delete(revisionsRef.Doc(strconv.FormatInt(version, 10)))
+	}
+
+	if _, err := batch.Commit(fb.ctx); err != nil {
+		return fmt.Errorf("Error deleting revisions of [%s], id=[%s]: %v", collection, id, err)
+	}
+
+	return nil
+}
+
+//Lock acquires a cluster-wide lock in a transaction (if it doesn't exist or has expired) and returns a function for releasing it
+func (fb *Firebase) Lock(name string) (func(), error) {
+	lockRef := fb.client.Collection(locksCollection).Doc(name)
+	token := uuid.NewV4().String()
+
+	err := waitLock(name, func() (bool, error) {
+		acquired := false
+		err := fb.client.RunTransaction(fb.ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+			acquired = false
+			doc, err := tx.Get(lockRef)
+			if err != nil && status.Code(err) != codes.NotFound {
+				return err
+			}
+			if err == nil {
+				if value, err := doc.DataAt(lockExpiresAtField); err == nil {
+					if expiresAt, ok := value.(time.Time); ok && expiresAt.After(time.Now()) {
+						return nil
+					}
+				}
+			}
+
+			acquired = true
+			return tx.Set(lockRef, map[string]interface{}{lockTokenField: token, lockExpiresAtField: time.Now().Add(lockTTL)})
+		})
+
+		return acquired, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return func() {
+		err := fb.client.RunTransaction(fb.ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+			doc, err := tx.Get(lockRef)
+			if err != nil {
+				if status.Code(err) == codes.NotFound {
+					return nil
+				}
+				return err
+			}
+
+			//the lock might have expired and been acquired by another node
+			if value, err := doc.DataAt(lockTokenField); err != nil || value != token {
+				return nil
+			}
+
+			return tx.Delete(lockRef)
+		})
+		if err != nil {
+			logging.Errorf("Error releasing lock [%s]: %v", name, err)
+		}
+	}, nil
+}
+
 func (fb *Firebase) Close() error {
 	if err := fb.client.Close(); err != nil {
 		return err
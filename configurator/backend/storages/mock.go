@@ -0,0 +1,173 @@
+package storages
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+)
+
+//Mock is an in-memory ConfigurationsStorage (is used in tests)
+type Mock struct {
+	mutex       *sync.Mutex
+	collections map[string]map[string][]byte
+	revisions   map[string][]*Revision
+	versions    map[string]int64
+	locks       map[string]bool
+}
+
+func NewMock() *Mock {
+	return &Mock{
+		mutex:       &sync.Mutex{},
+		collections: map[string]map[string][]byte{},
+		revisions:   map[string][]*Revision{},
+		versions:    map[string]int64{},
+		locks:       map[string]bool{},
+	}
+}
+
+//Get is a mock func
+func (m *Mock) Get(collection string, id string) ([]byte, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	b, ok := m.collections[collection][id]
+	if !ok {
+		return nil, ErrConfigurationNotFound
+	}
+
+	return b, nil
+}
+
+//GetAllGroupedByID is a mock func
+func (m *Mock) GetAllGroupedByID(collection string) ([]byte, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	configs := map[string]json.RawMessage{}
+	for id, b := range m.collections[collection] {
+		configs[id] = b
+	}
+
+	return json.Marshal(configs)
+}
+
+//GetCollectionLastUpdated is a mock func
+func (m *Mock) GetCollectionLastUpdated(collection string) (*time.Time, error) {
+	return &time.Time{}, nil
+}
+
+//UpdateCollectionLastUpdated is a mock func
+func (m *Mock) UpdateCollectionLastUpdated(collection string) error {
+	return nil
+}
+
+//Store is a mock func
+func (m *Mock) Store(collection string, id string, entity interface{}) error {
+	b, err := json.Marshal(entity)
+	if err != nil {
+		return err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, ok := m.collections[collection]; !ok {
+		m.collections[collection] = map[string][]byte{}
+	}
+	m.collections[collection][id] = b
+
+	return nil
+}
+
+//SaveRevision is a mock func
+func (m *Mock) SaveRevision(revision *Revision) (int64, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	key := revision.Collection + "#" + revision.ID
+	m.versions[key]++
+	revision.Version = m.versions[key]
+	m.revisions[key] = append(m.revisions[key], revision)
+
+	return revision.Version, nil
+}
+
+//GetRevisions is a mock func
+func (m *Mock) GetRevisions(collection string, id string) ([]*Revision, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	revisions := append([]*Revision{}, m.revisions[collection+"#"+id]...)
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i].Version > revisions[j].Version
+	})
+
+	return revisions, nil
+}
+
+//GetRevision is a mock func
+func (m *Mock) GetRevision(collection string, id string, version int64) (*Revision, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for _, revision := range m.revisions[collection+"#"+id] {
+		if revision.Version == version {
+			return revision, nil
+		}
+	}
+
+	return nil, ErrRevisionNotFound
+}
+
+//DeleteRevisions is a mock func
+func (m *Mock) DeleteRevisions(collection string, id string, versions []int64) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	toDelete := map[int64]bool{}
+	for _, version := range versions {
+		toDelete[version] = true
+	}
+
+	key := collection + "#" + id
+	revisions := make([]*Revision, 0, len(m.revisions[key]))
+	for _, revision := range m.revisions[key] {
+		if !toDelete[revision.Version] {
+			revisions = append(revisions, revision)
+		}
+	}
+	m.revisions[key] = revisions
+
+	return nil
+}
+
+//Lock is a mock func (in-process lock)
+func (m *Mock) Lock(name string) (func(), error) {
+	err := waitLock(name, func() (bool, error) {
+		m.mutex.Lock()
+		defer m.mutex.Unlock()
+
+		if m.locks[name] {
+			return false, nil
+		}
+
+		m.locks[name] = true
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return func() {
+		m.mutex.Lock()
+		defer m.mutex.Unlock()
+
+		delete(m.locks, name)
+	}, nil
+}
+
+//Close is a mock func
+func (m *Mock) Close() error {
+	return nil
+}
@@ -7,12 +7,31 @@ import (
 	entime "github.com/jitsucom/jitsu/configurator/time"
 	"github.com/jitsucom/jitsu/server/logging"
 	"github.com/jitsucom/jitsu/server/meta"
+	uuid "github.com/satori/go.uuid"
+	"sort"
 	"time"
 )
 
 //TODO change to config#meta someday
 const lastUpdatedPerCollection = "configs#meta#last_updated"
 
+//configs#revisions#versions [collection#id] - hashtable with the last revision version per configuration
+//configs#revisions#collection#id [version] {revision JSON} - hashtable with all revisions of configuration
+const (
+	revisionVersionsKey = "configs#revisions#versions"
+	revisionsPrefix     = "configs#revisions#"
+)
+
+//configs#locks#name {token} - cluster-wide lock with TTL
+const locksPrefix = "configs#locks#"
+
+//unlockScript deletes the lock only if it is still held by the token (it might have expired and been acquired by another node)
+var unlockScript = redis.NewScript(1, `
+if redis.call('get', KEYS[1]) == ARGV[1] then
+  return redis.call('del', KEYS[1])
+end
+return 0`)
+
 type Redis struct {
 	pool *meta.RedisPool
 }
@@ -132,6 +151,131 @@ func (r *Redis) Store(collection string, key string, entity interface{}) error {
 	return nil
 }
 
+//SaveRevision increments configuration version and saves revision
+func (r *Redis) SaveRevision(revision *Revision) (int64, error) {
+	connection := r.pool.Get()
+	defer connection.Close()
+
+	version, err := redis.Int64(connection.Do("hincrby", revisionVersionsKey, revision.Collection+"#"+revision.ID, 1))
+	if err != nil {
+		return 0, fmt.Errorf("Error incrementing revision version of [%s], id=[%s]: %v", revision.Collection, revision.ID, err)
+	}
+	revision.Version = version
+
+	serialized, err := json.Marshal(revision)
+	if err != nil {
+		return 0, fmt.Errorf("Error serializing revision of [%s], id=[%s]: %v", revision.Collection, revision.ID, err)
+	}
+
+	if _, err := connection.Do("hset", toRevisionsKey(revision.Collection, revision.ID), version, serialized); err != nil {
+		return 0, fmt.Errorf("Error storing revision of [%s], id=[%s]: %v", revision.Collection, revision.ID, err)
+	}
+
+	return version, nil
+}
+
+//GetRevisions returns all configuration revisions sorted by version desc
+func (r *Redis) GetRevisions(collection string, id string) ([]*Revision, error) {
+	connection := r.pool.Get()
+	defer connection.Close()
+
+	revisionsByVersion, err := redis.StringMap(connection.Do("hgetall", toRevisionsKey(collection, id)))
+	if err != nil && err != redis.ErrNil {
+		return nil, err
+	}
+
+	revisions := make([]*Revision, 0, len(revisionsByVersion))
+	for version, serialized := range revisionsByVersion {
+		revision := &Revision{}
+		if err := json.Unmarshal([]byte(serialized), revision); err != nil {
+			return nil, fmt.Errorf("Error parsing revision [%s] of [%s], id=[%s]: %v", version, collection, id, err)
+		}
+		revisions = append(revisions, revision)
+	}
+
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i].Version > revisions[j].Version
+	})
+
+	return revisions, nil
+}
+
+//GetRevision returns configuration revision by version or ErrRevisionNotFound
+func (r *Redis) GetRevision(collection string, id string, version int64) (*Revision, error) {
+	connection := r.pool.Get()
+	defer connection.Close()
+
+	serialized, err := redis.Bytes(connection.Do("hget", toRevisionsKey(collection, id), version))
+	if err != nil {
+		if err == redis.ErrNil {
+			return nil, ErrRevisionNotFound
+		}
+
+		return nil, err
+	}
+
+	revision := &Revision{}
+	if err := json.Unmarshal(serialized, revision); err != nil {
+		return nil, fmt.Errorf("Error parsing revision [%d] of [%s], id=[%s]: %v", version, collection, id, err)
+	}
+
+	return revision, nil
+}
+
+//DeleteRevisions deletes configuration revisions by versions
+func (r *Redis) DeleteRevisions(collection string, id string, versions []int64) error {
+	if len(versions) == 0 {
+		return nil
+	}
+
+	connection := r.pool.Get()
+	defer connection.Close()
+
+	args := redis.Args{}.Add(toRevisionsKey(collection, id)).AddFlat(versions)
+	if _, err := connection.Do("hdel", args...); err != nil {
+		return fmt.Errorf("Error deleting revisions of [%s], id=[%s]: %v", collection, id, err)
+	}
+
+	return nil
+}
+
+//Lock acquires a cluster-wide lock with SET NX and returns a function for releasing it
+func (r *Redis) Lock(name string) (func(), error) {
+	key := locksPrefix + name
+	token := uuid.NewV4().String()
+
+	err := waitLock(name, func() (bool, error) {
+		connection := r.pool.Get()
+		defer connection.Close()
+
+		if _, err := redis.String(connection.Do("set", key, token, "nx", "px", lockTTL.Milliseconds())); err != nil {
+			if err == redis.ErrNil {
+				return false, nil
+			}
+
+			return false, err
+		}
+
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return func() {
+		connection := r.pool.Get()
+		defer connection.Close()
+
+		if _, err := unlockScript.Do(connection, key, token); err != nil {
+			logging.Errorf("Error releasing lock [%s]: %v", name, err)
+		}
+	}, nil
+}
+
+func toRevisionsKey(collection, id string) string {
+	return revisionsPrefix + collection + "#" + id
+}
+
 func toStringMap(value interface{}) (map[string]interface{}, error) {
 	marshal, err := json.Marshal(value)
 	if err != nil {
@@ -0,0 +1,207 @@
+package storages
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	AddOperation     = "add"
+	RemoveOperation  = "remove"
+	ReplaceOperation = "replace"
+)
+
+var ErrRevisionNotFound = errors.New("Configuration revision wasn't found")
+
+//Revision is a versioned snapshot of a configuration (collection + id) which is saved on every StoreConfig call
+type Revision struct {
+	Version      int64     `firestore:"version" json:"version"`
+	Collection   string    `firestore:"collection" json:"collection"`
+	ID           string    `firestore:"id" json:"id"`
+	Author       string    `firestore:"author" json:"author"`
+	CreatedAt    string    `firestore:"created_at" json:"created_at"`
+	RestoredFrom int64     `firestore:"restored_from" json:"restored_from,omitempty"`
+	Diff         []*Change `firestore:"diff" json:"diff"`
+	//Payload is a serialized JSON configuration
+	Payload string `firestore:"payload" json:"payload,omitempty"`
+}
+
+//RevisionsRetention is a policy of keeping configuration revisions: the last MaxCount revisions
+//which aren't older than MaxAge are kept (zero values mean unlimited). The latest revision is always kept
+type RevisionsRetention struct {
+	MaxCount int
+	MaxAge   time.Duration
+}
+
+//expiredVersions returns versions of revisions (sorted by version desc) which must be deleted according to the policy
+//revisions with unparsable creation time aren't considered as expired by age
+func (rr *RevisionsRetention) expiredVersions(revisions []*Revision, now time.Time) []int64 {
+	if rr == nil {
+		return nil
+	}
+
+	var expired []int64
+	for i, revision := range revisions {
+		if i == 0 {
+			continue
+		}
+
+		if rr.MaxCount > 0 && i >= rr.MaxCount {
+			expired = append(expired, revision.Version)
+			continue
+		}
+
+		if rr.MaxAge > 0 {
+			createdAt, err := time.Parse(LastUpdatedLayout, revision.CreatedAt)
+			if err == nil && now.Sub(createdAt) > rr.MaxAge {
+				expired = append(expired, revision.Version)
+			}
+		}
+	}
+
+	return expired
+}
+
+//Change is a JSON diff element
+//Path is a JSON pointer (e.g. /destinations/0/_formData/pghost)
+type Change struct {
+	Operation string      `firestore:"op" json:"op"`
+	Path      string      `firestore:"path" json:"path"`
+	OldValue  interface{} `firestore:"old_value" json:"old_value,omitempty"`
+	NewValue  interface{} `firestore:"new_value" json:"new_value,omitempty"`
+}
+
+//toJSONObject returns JSON representation (maps, slices and primitives) of the entity without system fields
+func toJSONObject(entity interface{}) (interface{}, error) {
+	var b []byte
+	switch e := entity.(type) {
+	case []byte:
+		b = e
+	case string:
+		b = []byte(e)
+	default:
+		var err error
+		b, err = json.Marshal(entity)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(b) == 0 {
+		return nil, nil
+	}
+
+	var object interface{}
+	if err := json.Unmarshal(b, &object); err != nil {
+		return nil, err
+	}
+
+	if m, ok := object.(map[string]interface{}); ok {
+		delete(m, lastUpdatedField)
+	}
+
+	return object, nil
+}
+
+//DiffJSON returns changes between two JSON payloads (serialized JSON or any serializable entities)
+func DiffJSON(before, after interface{}) ([]*Change, error) {
+	beforeObject, err := toJSONObject(before)
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing original JSON: %v", err)
+	}
+
+	afterObject, err := toJSONObject(after)
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing changed JSON: %v", err)
+	}
+
+	changes := []*Change{}
+	diff("", beforeObject, afterObject, &changes)
+	return changes, nil
+}
+
+func diff(path string, before, after interface{}, changes *[]*Change) {
+	if before == nil && after == nil {
+		return
+	}
+
+	if before == nil {
+		*changes = append(*changes, &Change{Operation: AddOperation, Path: pathOrRoot(path), NewValue: after})
+		return
+	}
+
+	if after == nil {
+		*changes = append(*changes, &Change{Operation: RemoveOperation, Path: pathOrRoot(path), OldValue: before})
+		return
+	}
+
+	switch b := before.(type) {
+	case map[string]interface{}:
+		a, ok := after.(map[string]interface{})
+		if !ok {
+			break
+		}
+
+		keys := map[string]bool{}
+		for k := range b {
+			keys[k] = true
+		}
+		for k := range a {
+			keys[k] = true
+		}
+		sortedKeys := make([]string, 0, len(keys))
+		for k := range keys {
+			sortedKeys = append(sortedKeys, k)
+		}
+		sort.Strings(sortedKeys)
+
+		for _, k := range sortedKeys {
+			diff(path+"/"+escapePointer(k), b[k], a[k], changes)
+		}
+		return
+	case []interface{}:
+		a, ok := after.([]interface{})
+		if !ok {
+			break
+		}
+
+		max := len(b)
+		if len(a) > max {
+			max = len(a)
+		}
+		for i := 0; i < max; i++ {
+			var bv, av interface{}
+			if i < len(b) {
+				bv = b[i]
+			}
+			if i < len(a) {
+				av = a[i]
+			}
+			diff(path+"/"+strconv.Itoa(i), bv, av, changes)
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(before, after) {
+		*changes = append(*changes, &Change{Operation: ReplaceOperation, Path: pathOrRoot(path), OldValue: before, NewValue: after})
+	}
+}
+
+//escapePointer escapes JSON pointer reference token (RFC 6901)
+func escapePointer(key string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
+}
+
+func pathOrRoot(path string) string {
+	if path == "" {
+		return "/"
+	}
+
+	return path
+}
@@ -0,0 +1,153 @@
+package storages
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		before   interface{}
+		after    interface{}
+		expected []*Change
+	}{
+		{
+			"New configuration",
+			nil,
+			`{"name":"pg"}`,
+			[]*Change{{Operation: AddOperation, Path: "/", NewValue: map[string]interface{}{"name": "pg"}}},
+		},
+		{
+			"Removed configuration",
+			[]byte(`{"name":"pg"}`),
+			nil,
+			[]*Change{{Operation: RemoveOperation, Path: "/", OldValue: map[string]interface{}{"name": "pg"}}},
+		},
+		{
+			"Equal configurations with different last updated",
+			`{"name":"pg","_lastUpdated":"2021-01-01T00:00:00.000Z"}`,
+			`{"name":"pg","_lastUpdated":"2021-01-02T00:00:00.000Z"}`,
+			[]*Change{},
+		},
+		{
+			"Added, removed and replaced fields",
+			`{"host":"localhost","port":5432,"ssl":true}`,
+			`{"host":"db.example.com","port":5432,"user":"admin"}`,
+			[]*Change{
+				{Operation: ReplaceOperation, Path: "/host", OldValue: "localhost", NewValue: "db.example.com"},
+				{Operation: RemoveOperation, Path: "/ssl", OldValue: true},
+				{Operation: AddOperation, Path: "/user", NewValue: "admin"},
+			},
+		},
+		{
+			"Nested objects and arrays",
+			`{"destinations":[{"_formData":{"pghost":"a"}},{"_id":"second"}]}`,
+			`{"destinations":[{"_formData":{"pghost":"b"}}]}`,
+			[]*Change{
+				{Operation: ReplaceOperation, Path: "/destinations/0/_formData/pghost", OldValue: "a", NewValue: "b"},
+				{Operation: RemoveOperation, Path: "/destinations/1", OldValue: map[string]interface{}{"_id": "second"}},
+			},
+		},
+		{
+			"Type change",
+			`{"keys":["a"]}`,
+			`{"keys":"a"}`,
+			[]*Change{{Operation: ReplaceOperation, Path: "/keys", OldValue: []interface{}{"a"}, NewValue: "a"}},
+		},
+		{
+			"Escaped path",
+			`{"a/b":{"c~d":1}}`,
+			`{"a/b":{"c~d":2}}`,
+			[]*Change{{Operation: ReplaceOperation, Path: "/a~1b/c~0d", OldValue: float64(1), NewValue: float64(2)}},
+		},
+		{
+			"Serializable entities",
+			map[string]interface{}{"disabled": map[string]bool{"usage": false}},
+			struct {
+				Disabled map[string]bool `json:"disabled"`
+			}{Disabled: map[string]bool{"usage": true}},
+			[]*Change{{Operation: ReplaceOperation, Path: "/disabled/usage", OldValue: false, NewValue: true}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes, err := DiffJSON(tt.before, tt.after)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, changes)
+		})
+	}
+
+	_, err := DiffJSON(`{"name":`, `{}`)
+	require.Error(t, err, "malformed JSON")
+}
+
+func TestRevisionsRetentionExpiredVersions(t *testing.T) {
+	now := time.Date(2021, 12, 31, 0, 0, 0, 0, time.UTC)
+	revisions := []*Revision{
+		{Version: 5, CreatedAt: now.Add(-40 * 24 * time.Hour).Format(LastUpdatedLayout)},
+		{Version: 4, CreatedAt: now.Add(-time.Hour).Format(LastUpdatedLayout)},
+		{Version: 3, CreatedAt: "malformed"},
+		{Version: 2, CreatedAt: now.Add(-31 * 24 * time.Hour).Format(LastUpdatedLayout)},
+		{Version: 1, CreatedAt: now.Add(-60 * 24 * time.Hour).Format(LastUpdatedLayout)},
+	}
+
+	tests := []struct {
+		name      string
+		retention *RevisionsRetention
+		expected  []int64
+	}{
+		{
+			"Without retention",
+			nil,
+			nil,
+		},
+		{
+			"Unlimited",
+			&RevisionsRetention{},
+			nil,
+		},
+		{
+			"Max count",
+			&RevisionsRetention{MaxCount: 3},
+			[]int64{2, 1},
+		},
+		{
+			"Max age keeps the latest revision",
+			&RevisionsRetention{MaxAge: 30 * 24 * time.Hour},
+			[]int64{2, 1},
+		},
+		{
+			"Max count and max age",
+			&RevisionsRetention{MaxCount: 2, MaxAge: 30 * time.Minute},
+			[]int64{4, 3, 2, 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, tt.retention.expiredVersions(revisions, now))
+		})
+	}
+}
+
+func TestStoreConfigRevisionsRetention(t *testing.T) {
+	storage := NewMock()
+	service := NewConfigurationsService(storage, nil, &RevisionsRetention{MaxCount: 2})
+
+	for i := 1; i <= 4; i++ {
+		revision, err := service.storeConfig(destinationsCollection, "project1", map[string]interface{}{"version": i}, "user1", 0)
+		require.NoError(t, err)
+		require.Equal(t, int64(i), revision.Version, "versions mustn't be reused after deletion")
+	}
+
+	revisions, err := service.GetRevisions(destinationsCollection, "project1")
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+	require.Equal(t, int64(4), revisions[0].Version)
+	require.Equal(t, int64(3), revisions[1].Version)
+
+	_, err = service.GetRevision(destinationsCollection, "project1", 1)
+	require.Equal(t, ErrRevisionNotFound, err)
+}
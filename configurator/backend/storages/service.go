@@ -8,6 +8,7 @@ import (
 	"github.com/jitsucom/jitsu/configurator/destinations"
 	"github.com/jitsucom/jitsu/configurator/entities"
 	"github.com/jitsucom/jitsu/configurator/random"
+	"github.com/jitsucom/jitsu/server/logging"
 	"github.com/jitsucom/jitsu/server/telemetry"
 	"time"
 )
@@ -25,6 +26,9 @@ const (
 	telemetryGlobalID   = "global_configuration"
 
 	LastUpdatedLayout = "2006-01-02T15:04:05.000Z"
+
+	//SystemAuthor is an author of configuration revisions which aren't made by users (e.g. certificates updates)
+	SystemAuthor = "system"
 )
 
 //collectionsDependencies is used for updating last_updated field in db. It leads Jitsu Server to reload configuration with new changes
@@ -35,14 +39,17 @@ var collectionsDependencies = map[string]string{
 type ConfigurationsService struct {
 	storage            ConfigurationsStorage
 	defaultDestination *destinations.Postgres
+	revisionsRetention *RevisionsRetention
 }
 
-func NewConfigurationsService(storage ConfigurationsStorage, defaultDestination *destinations.Postgres) *ConfigurationsService {
-	return &ConfigurationsService{storage: storage, defaultDestination: defaultDestination}
+//NewConfigurationsService returns configured ConfigurationsService
+//old configuration revisions are deleted according to revisionsRetention (nil means keep all revisions)
+func NewConfigurationsService(storage ConfigurationsStorage, defaultDestination *destinations.Postgres, revisionsRetention *RevisionsRetention) *ConfigurationsService {
+	return &ConfigurationsService{storage: storage, defaultDestination: defaultDestination, revisionsRetention: revisionsRetention}
 }
 
 //CreateDefaultDestination Creates default destination in case no other destinations exist for the project
-func (cs *ConfigurationsService) CreateDefaultDestination(projectID string) (*entities.Database, error) {
+func (cs *ConfigurationsService) CreateDefaultDestination(projectID, author string) (*entities.Database, error) {
 	if cs.defaultDestination == nil {
 		return nil, errors.New("Default destination postgres isn't configured")
 	}
@@ -56,7 +63,7 @@ func (cs *ConfigurationsService) CreateDefaultDestination(projectID string) (*en
 				return nil, fmt.Errorf("Error creating database: [%s]: %v", projectID, err)
 			}
 
-			err = cs.storage.Store(defaultDatabaseCredentialsCollection, projectID, database)
+			_, err = cs.storeConfig(defaultDatabaseCredentialsCollection, projectID, database, author, 0)
 			if err != nil {
 				return nil, err
 			}
@@ -232,7 +239,7 @@ func (cs *ConfigurationsService) GetGeoDataResolverByProjectID(projectID string)
 }
 
 //CreateDefaultAPIKey returns generated default key per project only in case if no other API key exists
-func (cs *ConfigurationsService) CreateDefaultAPIKey(projectID string) error {
+func (cs *ConfigurationsService) CreateDefaultAPIKey(projectID, author string) error {
 	keys, err := cs.GetAPIKeysByProjectID(projectID)
 	if err != nil {
 		return err
@@ -247,7 +254,7 @@ func (cs *ConfigurationsService) CreateDefaultAPIKey(projectID string) error {
 		}
 	}
 	apiKeyRecord := cs.generateDefaultAPIToken(projectID)
-	err = cs.storage.Store(apiKeysCollection, projectID, apiKeyRecord)
+	_, err = cs.storeConfig(apiKeysCollection, projectID, apiKeyRecord, author, 0)
 	if err != nil {
 		return fmt.Errorf("Failed to store default key for project=[%s]: %v", projectID, err)
 	}
@@ -255,8 +262,8 @@ func (cs *ConfigurationsService) CreateDefaultAPIKey(projectID string) error {
 }
 
 //SaveTelemetry saves telemetry configuration
-func (cs *ConfigurationsService) SaveTelemetry(disabledConfiguration map[string]bool) error {
-	err := cs.storage.Store(telemetryCollection, telemetryGlobalID, telemetry.Configuration{Disabled: disabledConfiguration})
+func (cs *ConfigurationsService) SaveTelemetry(disabledConfiguration map[string]bool, author string) error {
+	_, err := cs.storeConfig(telemetryCollection, telemetryGlobalID, telemetry.Configuration{Disabled: disabledConfiguration}, author, 0)
 	if err != nil {
 		return fmt.Errorf("Failed to store telemetry settings:: %v", err)
 	}
@@ -288,19 +295,137 @@ func (cs *ConfigurationsService) GetParsedTelemetry() (*telemetry.Configuration,
 	return telemetryConfig, nil
 }
 
-//StoreConfig stores configuration in db and update last update field in dependencies
-func (cs *ConfigurationsService) StoreConfig(collection string, key string, entity interface{}) error {
+//StoreConfig stores configuration in db, update last update field in dependencies
+//and saves a new configuration revision with the author and the diff with the previous state
+func (cs *ConfigurationsService) StoreConfig(collection string, key string, entity interface{}, author string) error {
+	_, err := cs.storeConfig(collection, key, entity, author, 0)
+	return err
+}
+
+//GetRevisions returns all configuration revisions (the latest first) without payloads
+func (cs *ConfigurationsService) GetRevisions(collection string, key string) ([]*Revision, error) {
+	revisions, err := cs.storage.GetRevisions(collection, key)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get revisions of [%s], id=[%s]: %v", collection, key, err)
+	}
+
+	for _, revision := range revisions {
+		revision.Payload = ""
+	}
+
+	return revisions, nil
+}
+
+//GetRevision returns configuration revision with payload
+func (cs *ConfigurationsService) GetRevision(collection string, key string, version int64) (*Revision, error) {
+	return cs.storage.GetRevision(collection, key, version)
+}
+
+//DiffRevisions returns changes between two configuration revisions
+//if compareTo is 0, the revision is compared with the current configuration state
+func (cs *ConfigurationsService) DiffRevisions(collection string, key string, version, compareTo int64) ([]*Change, error) {
+	revision, err := cs.storage.GetRevision(collection, key, version)
+	if err != nil {
+		return nil, err
+	}
+
+	var target []byte
+	if compareTo == 0 {
+		target, err = cs.storage.Get(collection, key)
+		if err != nil && err != ErrConfigurationNotFound {
+			return nil, fmt.Errorf("Failed to get current configuration of [%s], id=[%s]: %v", collection, key, err)
+		}
+	} else {
+		compareToRevision, err := cs.storage.GetRevision(collection, key, compareTo)
+		if err != nil {
+			return nil, err
+		}
+		target = []byte(compareToRevision.Payload)
+	}
+
+	return DiffJSON(revision.Payload, target)
+}
+
+//RestoreRevision stores configuration payload from the revision as the current state
+//returns the new revision
+func (cs *ConfigurationsService) RestoreRevision(collection string, key string, version int64, author string) (*Revision, error) {
+	revision, err := cs.storage.GetRevision(collection, key, version)
+	if err != nil {
+		return nil, err
+	}
+
+	var entity interface{}
+	if err := json.Unmarshal([]byte(revision.Payload), &entity); err != nil {
+		return nil, fmt.Errorf("Failed to parse revision [%d] payload of [%s], id=[%s]: %v", version, collection, key, err)
+	}
+
+	return cs.storeConfig(collection, key, entity, author, version)
+}
+
+//storeConfig stores configuration, updates last update field in dependencies and saves a new revision
+//the configuration is already stored if the revision can't be saved, so revision errors are only logged
+func (cs *ConfigurationsService) storeConfig(collection string, key string, entity interface{}, author string, restoredFrom int64) (*Revision, error) {
+	previous, err := cs.storage.Get(collection, key)
+	if err != nil && err != ErrConfigurationNotFound {
+		return nil, fmt.Errorf("Failed to get current configuration: %v", err)
+	}
+
 	if err := cs.storage.Store(collection, key, entity); err != nil {
-		return err
+		return nil, err
 	}
 
 	if dependency, ok := collectionsDependencies[collection]; ok {
 		if err := cs.storage.UpdateCollectionLastUpdated(dependency); err != nil {
-			return err
+			return nil, err
 		}
 	}
 
-	return nil
+	revision := &Revision{
+		Collection:   collection,
+		ID:           key,
+		Author:       author,
+		CreatedAt:    time.Now().UTC().Format(LastUpdatedLayout),
+		RestoredFrom: restoredFrom,
+	}
+	if err := cs.saveRevision(revision, previous, entity); err != nil {
+		logging.SystemErrorf("Configuration [%s], id=[%s] has been stored but its revision wasn't saved: %v", collection, key, err)
+	} else if err := cs.deleteExpiredRevisions(collection, key); err != nil {
+		logging.Errorf("Error deleting expired revisions of configuration [%s], id=[%s]: %v", collection, key, err)
+	}
+
+	return revision, nil
+}
+
+//deleteExpiredRevisions deletes configuration revisions which aren't kept by the retention policy
+func (cs *ConfigurationsService) deleteExpiredRevisions(collection string, key string) error {
+	if cs.revisionsRetention == nil {
+		return nil
+	}
+
+	revisions, err := cs.storage.GetRevisions(collection, key)
+	if err != nil {
+		return err
+	}
+
+	return cs.storage.DeleteRevisions(collection, key, cs.revisionsRetention.expiredVersions(revisions, time.Now().UTC()))
+}
+
+//saveRevision fills the revision payload and the diff with the previous state and saves it
+func (cs *ConfigurationsService) saveRevision(revision *Revision, previous []byte, entity interface{}) error {
+	payload, err := json.Marshal(entity)
+	if err != nil {
+		return fmt.Errorf("Failed to serialize configuration revision: %v", err)
+	}
+
+	changes, err := DiffJSON(previous, payload)
+	if err != nil {
+		return fmt.Errorf("Failed to build configuration diff: %v", err)
+	}
+
+	revision.Diff = changes
+	revision.Payload = string(payload)
+	_, err = cs.storage.SaveRevision(revision)
+	return err
 }
 
 func (cs *ConfigurationsService) generateDefaultAPIToken(projectID string) entities.APIKeys {
@@ -313,6 +438,11 @@ func (cs *ConfigurationsService) generateDefaultAPIToken(projectID string) entit
 	}
 }
 
+//GetCustomDomainsLastUpdated returns custom domains last updated
+func (cs *ConfigurationsService) GetCustomDomainsLastUpdated() (*time.Time, error) {
+	return cs.storage.GetCollectionLastUpdated(customDomainsCollection)
+}
+
 func (cs *ConfigurationsService) GetCustomDomains() (map[string]*entities.CustomDomains, error) {
 	customDomains := make(map[string]*entities.CustomDomains)
 	data, err := cs.storage.GetAllGroupedByID(customDomainsCollection)
@@ -339,8 +469,9 @@ func (cs *ConfigurationsService) GetCustomDomainsByProjectID(projectID string) (
 	return domains, nil
 }
 
-func (cs *ConfigurationsService) UpdateCustomDomain(projectID string, customDomains *entities.CustomDomains) error {
-	return cs.storage.Store(customDomainsCollection, projectID, customDomains)
+func (cs *ConfigurationsService) UpdateCustomDomain(projectID string, customDomains *entities.CustomDomains, author string) error {
+	_, err := cs.storeConfig(customDomainsCollection, projectID, customDomains, author, 0)
+	return err
 }
 
 func (cs *ConfigurationsService) Close() (multiErr error) {
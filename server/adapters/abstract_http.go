@@ -1,6 +1,9 @@
 package adapters
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 //AbstractHTTP is an Abstract HTTP adapter for keeping default funcs
 type AbstractHTTP struct {
@@ -41,6 +44,20 @@ func (a *AbstractHTTP) BulkUpdate(table *Table, objects []map[string]interface{}
 	return fmt.Errorf("%s doesn't support BulkUpdate() func", a.Type())
 }
 
+func (a *AbstractHTTP) Delete(table *Table, deleteConditions *DeleteConditions) error {
+	return fmt.Errorf("%s doesn't support Delete() func", a.Type())
+}
+
+//GetTableNames isn't supported
+func (a *AbstractHTTP) GetTableNames() ([]string, error) {
+	return nil, fmt.Errorf("%s doesn't support GetTableNames() func", a.Type())
+}
+
+//DeleteOlderThan isn't supported
+func (a *AbstractHTTP) DeleteOlderThan(table *Table, field string, before time.Time, dryRun bool) (int64, error) {
+	return 0, fmt.Errorf("%s doesn't support DeleteOlderThan() func", a.Type())
+}
+
 //Type returns adapter type. Should be overridden in every implementation
 func (a *AbstractHTTP) Type() string {
 	return "AbstractHTTP"
@@ -50,6 +67,11 @@ func (a *AbstractHTTP) Truncate(tableName string) error {
 	return nil
 }
 
+//Status returns underlying HTTPAdapter status
+func (a *AbstractHTTP) Status() *HTTPAdapterStatus {
+	return a.httpAdapter.Status()
+}
+
 //Close closes underlying HTTPAdapter
 func (a *AbstractHTTP) Close() error {
 	return a.httpAdapter.Close()
@@ -8,11 +8,12 @@ import (
 	"github.com/jitsucom/jitsu/server/logging"
 	"io"
 	"regexp"
+	"time"
 )
 
 var ErrTableNotExist = errors.New("table doesn't exist")
 
-var notExistRegexp = regexp.MustCompile(`(?i)(not|doesn't)\sexist`)
+var notExistRegexp = regexp.MustCompile(`(?i)(not|doesn't)\sexist|no\ssuch\stable|invalid\sobject\sname`)
 
 //SQLAdapter is a manager for DWH tables
 type SQLAdapter interface {
@@ -22,7 +23,21 @@ type SQLAdapter interface {
 	PatchTableSchema(schemaToAdd *Table) error
 	BulkInsert(table *Table, objects []map[string]interface{}) error
 	BulkUpdate(table *Table, objects []map[string]interface{}, deleteConditions *DeleteConditions) error
+	Delete(table *Table, deleteConditions *DeleteConditions) error
 	Truncate(tableName string) error
+	//GetTableNames returns names of all tables in the configured schema (database, dataset)
+	GetTableNames() ([]string, error)
+	//DeleteOlderThan deletes rows which field value is before the time and returns the number of deleted rows
+	//if dryRun is true - rows are only counted
+	DeleteOlderThan(table *Table, field string, before time.Time, dryRun bool) (int64, error)
+}
+
+//Querier is a SQL adapter which supports running SELECT queries (e.g. for syncing query results to other destinations)
+type Querier interface {
+	//Select executes a single SELECT statement (read-only if the database supports it) and passes result rows
+	//as column name -> value maps to consume one by one. The whole result isn't loaded into memory
+	//returns ErrNotSelectQuery or ErrMultipleStatements if the query isn't a single SELECT statement
+	Select(query string, consume func(row map[string]interface{}) error) error
 }
 
 //Adapter is an adapter for all destinations
@@ -50,6 +65,126 @@ func (sp *SqlParams) commonTruncate(tableName, statement string) error {
 	return nil
 }
 
+//commonGetTableNames returns values of the first column of the query result
+func (sp *SqlParams) commonGetTableNames(query string, values ...interface{}) ([]string, error) {
+	rows, err := sp.dataSource.QueryContext(sp.ctx, query, values...)
+	if err != nil {
+		return nil, fmt.Errorf("Error querying table names: %v", err)
+	}
+	defer rows.Close()
+
+	var tableNames []string
+	for rows.Next() {
+		var tableName string
+		if err := rows.Scan(&tableName); err != nil {
+			return nil, fmt.Errorf("Error scanning table name: %v", err)
+		}
+		tableNames = append(tableNames, tableName)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("Error reading table names: %v", err)
+	}
+
+	return tableNames, nil
+}
+
+//commonSelect checks that query is a single SELECT statement, executes it (in a read-only transaction if readOnlyTx is true)
+//and passes rows as column name -> value maps to consume one by one. []byte values are converted to strings
+func (sp *SqlParams) commonSelect(query string, readOnlyTx bool, consume func(row map[string]interface{}) error) error {
+	if err := CheckSelectQuery(query); err != nil {
+		return err
+	}
+
+	sp.queryLogger.LogQuery(query)
+
+	var rows *sql.Rows
+	if readOnlyTx {
+		tx, err := sp.dataSource.BeginTx(sp.ctx, &sql.TxOptions{ReadOnly: true})
+		if err != nil {
+			return fmt.Errorf("Error starting read-only transaction: %v", err)
+		}
+		//nothing to commit
+		defer tx.Rollback()
+
+		rows, err = tx.QueryContext(sp.ctx, query)
+		if err != nil {
+			return fmt.Errorf("Error executing query: %v", err)
+		}
+	} else {
+		var err error
+		rows, err = sp.dataSource.QueryContext(sp.ctx, query)
+		if err != nil {
+			return fmt.Errorf("Error executing query: %v", err)
+		}
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return fmt.Errorf("Error getting result columns: %v", err)
+	}
+
+	values := make([]interface{}, len(columns))
+	pointers := make([]interface{}, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(pointers...); err != nil {
+			return fmt.Errorf("Error scanning row: %v", err)
+		}
+
+		row := make(map[string]interface{}, len(columns))
+		for i, column := range columns {
+			if b, ok := values[i].([]byte); ok {
+				row[column] = string(b)
+			} else {
+				row[column] = values[i]
+			}
+		}
+		if err := consume(row); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("Error reading rows: %v", err)
+	}
+
+	return nil
+}
+
+//commonDeleteOlderThan executes countQuery if dryRun is true and returns the count
+//otherwise executes deleteQuery and returns the number of affected rows
+func (sp *SqlParams) commonDeleteOlderThan(tableName, countQuery, deleteQuery string, values []interface{}, dryRun bool) (int64, error) {
+	if dryRun {
+		sp.queryLogger.LogQueryWithValues(countQuery, values)
+
+		var count int64
+		if err := sp.dataSource.QueryRowContext(sp.ctx, countQuery, values...).Scan(&count); err != nil {
+			return 0, mapError(fmt.Errorf("Error counting rows in table %s using query: %s: %v", tableName, countQuery, err))
+		}
+
+		return count, nil
+	}
+
+	sp.queryLogger.LogQueryWithValues(deleteQuery, values)
+
+	result, err := sp.dataSource.ExecContext(sp.ctx, deleteQuery, values...)
+	if err != nil {
+		return 0, mapError(fmt.Errorf("Error deleting rows from table %s using query: %s: %v", tableName, deleteQuery, err))
+	}
+
+	return result.RowsAffected()
+}
+
+//olderThanConditions returns condition field < before
+func olderThanConditions(field string, before time.Time) *DeleteConditions {
+	return &DeleteConditions{
+		JoinCondition: "AND",
+		Conditions:    []DeleteCondition{{Field: field, Clause: "<", Value: before}},
+	}
+}
+
 func mapError(err error) error {
 	if notExistRegexp.MatchString(err.Error()) {
 		return ErrTableNotExist
@@ -10,7 +10,10 @@ import (
 )
 
 const (
-	amplitudeAPIURL = "https://api.amplitude.com/2/httpapi"
+	amplitudeAPIURL      = "https://api.amplitude.com/2/httpapi"
+	amplitudeBatchAPIURL = "https://api2.amplitude.com/batch"
+	//amplitudeMaxBatchEvents is a Batch Event Upload API limit
+	amplitudeMaxBatchEvents = 2000
 )
 
 //AmplitudeRequest is a dto for sending requests to Amplitude
@@ -20,9 +23,12 @@ type AmplitudeRequest struct {
 }
 
 //AmplitudeResponse is a dto for receiving response from Amplitude
+//events_with_* fields contain indexes of invalid events by field name (on 400 Bad Request)
 type AmplitudeResponse struct {
-	Code  int    `json:"code"`
-	Error string `json:"error"`
+	Code                    int              `json:"code"`
+	Error                   string           `json:"error"`
+	EventsWithInvalidFields map[string][]int `json:"events_with_invalid_fields,omitempty"`
+	EventsWithMissingFields map[string][]int `json:"events_with_missing_fields,omitempty"`
 }
 
 //AmplitudeRequestFactory is a factory for building Amplitude HTTP requests from input events
@@ -57,6 +63,70 @@ func (arf *AmplitudeRequestFactory) Create(object map[string]interface{}) (*Requ
 	}, nil
 }
 
+//BatchKey returns the same key for all requests: all Amplitude requests can be sent in one batch
+func (arf *AmplitudeRequestFactory) BatchKey(req *Request) string {
+	return req.URL
+}
+
+//CreateBatch returns Batch Event Upload API request with events from all requests
+func (arf *AmplitudeRequestFactory) CreateBatch(requests []*Request) (*Request, error) {
+	batch := AmplitudeRequest{APIKey: arf.apiKey}
+	for _, r := range requests {
+		req := &AmplitudeRequest{}
+		if err := json.Unmarshal(r.Body, req); err != nil {
+			return nil, fmt.Errorf("Error unmarshalling amplitude request: %v", err)
+		}
+		batch.Events = append(batch.Events, req.Events...)
+	}
+
+	b, err := json.Marshal(batch)
+	if err != nil {
+		return nil, fmt.Errorf("Error marshalling amplitude batch request: %v", err)
+	}
+	return &Request{
+		URL:     amplitudeBatchAPIURL,
+		Method:  http.MethodPost,
+		Body:    b,
+		Headers: map[string]string{"Content-Type": "application/json"},
+	}, nil
+}
+
+//MaxBatchEvents returns Amplitude Batch Event Upload API limit
+func (arf *AmplitudeRequestFactory) MaxBatchEvents() int {
+	return amplitudeMaxBatchEvents
+}
+
+//BatchItemErrors returns errors of events with invalid or missing fields from 400 Bad Request response
+//Amplitude rejects the whole batch in this case, so other events are retried
+func (arf *AmplitudeRequestFactory) BatchItemErrors(statusCode int, responseBody []byte, batchSize int) map[int]error {
+	itemErrors := map[int]error{}
+	if statusCode != http.StatusBadRequest {
+		return itemErrors
+	}
+
+	response := &AmplitudeResponse{}
+	if err := json.Unmarshal(responseBody, response); err != nil {
+		return itemErrors
+	}
+
+	for field, indexes := range response.EventsWithInvalidFields {
+		for _, i := range indexes {
+			if i >= 0 && i < batchSize {
+				itemErrors[i] = fmt.Errorf("Amplitude event has invalid field [%s]: %s", field, response.Error)
+			}
+		}
+	}
+	for field, indexes := range response.EventsWithMissingFields {
+		for _, i := range indexes {
+			if i >= 0 && i < batchSize {
+				itemErrors[i] = fmt.Errorf("Amplitude event doesn't have required field [%s]: %s", field, response.Error)
+			}
+		}
+	}
+
+	return itemErrors
+}
+
 func (arf *AmplitudeRequestFactory) Close() {
 }
 
@@ -2,10 +2,13 @@ package adapters
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/jitsucom/jitsu/server/uuid"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/jitsucom/jitsu/server/logging"
 	"github.com/jitsucom/jitsu/server/typing"
@@ -27,6 +30,12 @@ const (
 	deleteBeforeBulkMergeUsing     = `DELETE FROM "%s"."%s" using "%s"."%s" where %s`
 	deleteBeforeBulkMergeCondition = `"%s"."%s".%s = "%s"."%s".%s`
 	redshiftBulkMergeInsert        = `INSERT INTO "%s"."%s" (%s) select %s from "%s"."%s"`
+	redshiftMergeStatement         = `MERGE INTO "%s"."%s" USING "%s"."%s" AS staging ON %s WHEN MATCHED THEN UPDATE SET %s WHEN NOT MATCHED THEN INSERT (%s) VALUES (%s)`
+	redshiftMergeCondition         = `"%s"."%s".%s = staging.%s`
+	createStagingTableTemplate     = `CREATE TABLE "%s"."%s" (LIKE "%s"."%s")`
+
+	vacuumRedshiftTemplate  = `VACUUM %s "%s"."%s"`
+	analyzeRedshiftTemplate = `ANALYZE "%s"."%s"`
 
 	primaryKeyFieldsRedshiftQuery = `select kcu.column_name as key_column
 									 from information_schema.table_constraints tco
@@ -38,8 +47,86 @@ const (
                                      order by kcu.ordinal_position`
 
 	redshiftValuesLimit = 32767 // this is a limitation of parameters one can pass as query values. If more parameters are passed, error is returned
+
+	//RedshiftMergeDeleteInsert deletes rows with the same primary keys and inserts all rows from the staging table
+	RedshiftMergeDeleteInsert = "delete_insert"
+	//RedshiftMergeStatement uses Redshift MERGE statement
+	RedshiftMergeStatement = "merge"
+
+	redshiftVacuumNone = "NONE"
 )
 
+var (
+	redshiftDistStyles   = map[string]bool{"AUTO": true, "EVEN": true, "KEY": true, "ALL": true}
+	redshiftVacuumModes  = map[string]bool{"FULL": true, "SORT ONLY": true, "DELETE ONLY": true, "REINDEX": true, redshiftVacuumNone: true}
+	defaultRedshiftMerge = RedshiftMergeDeleteInsert
+)
+
+//RedshiftConfig dto for Redshift specific tables and loading configuration
+type RedshiftConfig struct {
+	//SortKey and DistKey are applied to tables created by Jitsu
+	SortKey   []string `mapstructure:"sort_key" json:"sort_key,omitempty" yaml:"sort_key,omitempty"`
+	DistKey   string   `mapstructure:"dist_key" json:"dist_key,omitempty" yaml:"dist_key,omitempty"`
+	DistStyle string   `mapstructure:"dist_style" json:"dist_style,omitempty" yaml:"dist_style,omitempty"`
+	//MergeStrategy is used for tables with primary keys: delete_insert or merge
+	MergeStrategy string                     `mapstructure:"merge_strategy" json:"merge_strategy,omitempty" yaml:"merge_strategy,omitempty"`
+	Maintenance   *RedshiftMaintenanceConfig `mapstructure:"maintenance" json:"maintenance,omitempty" yaml:"maintenance,omitempty"`
+}
+
+//RedshiftMaintenanceConfig dto for scheduled VACUUM and ANALYZE of tables managed by Jitsu
+type RedshiftMaintenanceConfig struct {
+	//Schedule in standard cron format
+	Schedule string `mapstructure:"schedule" json:"schedule,omitempty" yaml:"schedule,omitempty"`
+	//Vacuum is VACUUM mode: FULL, SORT ONLY, DELETE ONLY, REINDEX or NONE
+	Vacuum      string `mapstructure:"vacuum" json:"vacuum,omitempty" yaml:"vacuum,omitempty"`
+	SkipAnalyze bool   `mapstructure:"skip_analyze" json:"skip_analyze,omitempty" yaml:"skip_analyze,omitempty"`
+}
+
+//Validate returns err if invalid and sets default values
+func (rc *RedshiftConfig) Validate() error {
+	if rc == nil {
+		return nil
+	}
+
+	rc.DistStyle = strings.ToUpper(rc.DistStyle)
+	if rc.DistStyle == "" && rc.DistKey != "" {
+		rc.DistStyle = "KEY"
+	}
+	if rc.DistStyle != "" && !redshiftDistStyles[rc.DistStyle] {
+		return fmt.Errorf("Unknown Redshift dist_style: %s. Supported: AUTO, EVEN, KEY, ALL", rc.DistStyle)
+	}
+	if rc.DistStyle == "KEY" && rc.DistKey == "" {
+		return errors.New("Redshift dist_key is required with KEY dist_style")
+	}
+	if rc.DistStyle != "KEY" && rc.DistKey != "" {
+		return fmt.Errorf("Redshift dist_key can be used only with KEY dist_style")
+	}
+
+	switch rc.MergeStrategy {
+	case "":
+		rc.MergeStrategy = defaultRedshiftMerge
+	case RedshiftMergeDeleteInsert, RedshiftMergeStatement:
+	default:
+		return fmt.Errorf("Unknown Redshift merge_strategy: %s. Supported: %s, %s", rc.MergeStrategy, RedshiftMergeDeleteInsert, RedshiftMergeStatement)
+	}
+
+	if rc.Maintenance != nil {
+		if rc.Maintenance.Schedule == "" {
+			return errors.New("Redshift maintenance.schedule is required parameter")
+		}
+		//VACUUM is run only if it is configured explicitly: Redshift runs automatic VACUUM DELETE in the background
+		rc.Maintenance.Vacuum = strings.ToUpper(rc.Maintenance.Vacuum)
+		if rc.Maintenance.Vacuum == "" {
+			rc.Maintenance.Vacuum = redshiftVacuumNone
+		}
+		if !redshiftVacuumModes[rc.Maintenance.Vacuum] {
+			return fmt.Errorf("Unknown Redshift maintenance.vacuum: %s. Supported: FULL, SORT ONLY, DELETE ONLY, REINDEX, NONE", rc.Maintenance.Vacuum)
+		}
+	}
+
+	return nil
+}
+
 var (
 	SchemaToRedshift = map[typing.DataType]string{
 		typing.STRING:    "character varying(65535)",
@@ -56,18 +143,23 @@ type AwsRedshift struct {
 	//Aws Redshift uses Postgres fork under the hood
 	dataSourceProxy *Postgres
 	s3Config        *S3Config
+	config          *RedshiftConfig
 }
 
 //NewAwsRedshift returns configured AwsRedshift adapter instance
-func NewAwsRedshift(ctx context.Context, dsConfig *DataSourceConfig, s3Config *S3Config,
+//redshiftConfig is optional
+func NewAwsRedshift(ctx context.Context, dsConfig *DataSourceConfig, s3Config *S3Config, redshiftConfig *RedshiftConfig,
 	queryLogger *logging.QueryLogger, sqlTypes typing.SQLTypes) (*AwsRedshift, error) {
+	if redshiftConfig == nil {
+		redshiftConfig = &RedshiftConfig{MergeStrategy: defaultRedshiftMerge}
+	}
 
 	postgres, err := NewPostgresUnderRedshift(ctx, dsConfig, queryLogger, reformatMappings(sqlTypes, SchemaToRedshift))
 	if err != nil {
 		return nil, err
 	}
 
-	return &AwsRedshift{dataSourceProxy: postgres, s3Config: s3Config}, nil
+	return &AwsRedshift{dataSourceProxy: postgres, s3Config: s3Config, config: redshiftConfig}, nil
 }
 
 func (AwsRedshift) Type() string {
@@ -91,19 +183,60 @@ func (ar *AwsRedshift) Copy(fileKey, tableName string) error {
 		return err
 	}
 
+	if err := ar.copyInTransaction(wrappedTx, fileKey, tableName); err != nil {
+		wrappedTx.Rollback()
+		return err
+	}
+
+	return wrappedTx.DirectCommit()
+}
+
+//CopyMerge transfers data from s3 into a staging table with the same structure as the table
+//and merges it into the table by primary keys. All statements are executed in one transaction:
+//the table isn't changed if any of them fails. Objects in the file must be deduplicated by primary keys
+func (ar *AwsRedshift) CopyMerge(fileKey string, table *Table) error {
+	wrappedTx, err := ar.OpenTx()
+	if err != nil {
+		return err
+	}
+
+	stagingTable, err := ar.createStagingTableInTransaction(wrappedTx, table)
+	if err != nil {
+		wrappedTx.Rollback()
+		return err
+	}
+
+	if err := ar.copyInTransaction(wrappedTx, fileKey, stagingTable.Name); err != nil {
+		wrappedTx.Rollback()
+		return fmt.Errorf("Error copying into staging table [%s]: %v", stagingTable.Name, err)
+	}
+
+	if err := ar.mergeFromStagingInTransaction(wrappedTx, table, stagingTable); err != nil {
+		wrappedTx.Rollback()
+		return err
+	}
+
+	if err := ar.dataSourceProxy.dropTableInTransaction(wrappedTx, stagingTable); err != nil {
+		wrappedTx.Rollback()
+		return err
+	}
+
+	return wrappedTx.DirectCommit()
+}
+
+//copyInTransaction runs COPY from s3 file into the table
+func (ar *AwsRedshift) copyInTransaction(wrappedTx *Transaction, fileKey, tableName string) error {
 	//add folder prefix if configured
 	if ar.s3Config.Folder != "" {
 		fileKey = ar.s3Config.Folder + "/" + fileKey
 	}
 
 	statement := fmt.Sprintf(copyTemplate, ar.dataSourceProxy.config.Schema, tableName, ar.s3Config.Bucket, fileKey, ar.s3Config.AccessKeyID, ar.s3Config.SecretKey, ar.s3Config.Region)
-	_, err = wrappedTx.tx.ExecContext(ar.dataSourceProxy.ctx, statement)
-	if err != nil {
-		wrappedTx.Rollback()
+	if _, err := wrappedTx.tx.ExecContext(ar.dataSourceProxy.ctx, statement); err != nil {
 		return checkErr(err)
 	}
 
-	return wrappedTx.DirectCommit()
+	return nil
 }
 
 //CreateDbSchema create database schema instance if doesn't exist
@@ -200,13 +333,14 @@ func (ar *AwsRedshift) GetTableSchema(tableName string) (*Table, error) {
 }
 
 //CreateTable create database table with name,columns provided in Table representation
+//applies configured DISTSTYLE, DISTKEY and SORTKEY
 func (ar *AwsRedshift) CreateTable(tableSchema *Table) error {
 	wrappedTx, err := ar.OpenTx()
 	if err != nil {
 		return err
 	}
 
-	err = ar.dataSourceProxy.createTableInTransaction(wrappedTx, tableSchema)
+	err = ar.createTableInTransaction(wrappedTx, tableSchema)
 	if err != nil {
 		wrappedTx.Rollback()
 		return err
@@ -215,6 +349,45 @@ func (ar *AwsRedshift) CreateTable(tableSchema *Table) error {
 	return wrappedTx.DirectCommit()
 }
 
+//Maintain runs VACUUM (if configured) and ANALYZE for tables. Tables which don't exist in the schema are skipped
+//VACUUM can't be run inside a transaction
+func (ar *AwsRedshift) Maintain(tableNames []string) {
+	if ar.config.Maintenance == nil {
+		return
+	}
+
+	schemaTables, err := ar.GetTableNames()
+	if err != nil {
+		logging.Errorf("Error getting tables for Redshift maintenance: %v", err)
+		return
+	}
+	existingTables := make(map[string]bool, len(schemaTables))
+	for _, tableName := range schemaTables {
+		existingTables[strings.ToLower(tableName)] = true
+	}
+
+	for _, tableName := range tableNames {
+		if !existingTables[strings.ToLower(tableName)] {
+			continue
+		}
+
+		var statements []string
+		if ar.config.Maintenance.Vacuum != redshiftVacuumNone {
+			statements = append(statements, fmt.Sprintf(vacuumRedshiftTemplate, ar.config.Maintenance.Vacuum, ar.dataSourceProxy.config.Schema, tableName))
+		}
+		if !ar.config.Maintenance.SkipAnalyze {
+			statements = append(statements, fmt.Sprintf(analyzeRedshiftTemplate, ar.dataSourceProxy.config.Schema, tableName))
+		}
+
+		for _, statement := range statements {
+			ar.dataSourceProxy.queryLogger.LogQuery(statement)
+			if _, err := ar.dataSourceProxy.dataSource.ExecContext(ar.dataSourceProxy.ctx, statement); err != nil {
+				logging.Errorf("Error running Redshift maintenance [%s]: %v", statement, checkErr(err))
+			}
+		}
+	}
+}
+
 //Update one record in Redshift
 func (ar *AwsRedshift) Update(table *Table, object map[string]interface{}, whereKey string, whereValue interface{}) error {
 	columns := make([]string, len(object), len(object))
@@ -355,6 +528,32 @@ func (ar *AwsRedshift) BulkUpdate(table *Table, objects []map[string]interface{}
 	return wrappedTx.DirectCommit()
 }
 
+//Delete deletes objects with deleteConditions in transaction uses underlying postgres datasource
+func (ar *AwsRedshift) Delete(table *Table, deleteConditions *DeleteConditions) error {
+	return ar.dataSourceProxy.Delete(table, deleteConditions)
+}
+
+//GetTableNames returns names of all tables in the schema uses underlying postgres datasource
+func (ar *AwsRedshift) GetTableNames() ([]string, error) {
+	sqlParams := SqlParams{
+		dataSource:  ar.dataSourceProxy.dataSource,
+		queryLogger: ar.dataSourceProxy.queryLogger,
+		ctx:         ar.dataSourceProxy.ctx,
+	}
+	return sqlParams.commonGetTableNames(tableNamesQuery, ar.dataSourceProxy.config.Schema)
+}
+
+//Select executes SELECT query in a read-only transaction and passes result rows to consume
+//uses underlying postgres datasource
+func (ar *AwsRedshift) Select(query string, consume func(row map[string]interface{}) error) error {
+	return ar.dataSourceProxy.Select(query, consume)
+}
+
+//DeleteOlderThan deletes (or counts if dryRun) rows which field value is before the time uses underlying postgres datasource
+func (ar *AwsRedshift) DeleteOlderThan(table *Table, field string, before time.Time, dryRun bool) (int64, error) {
+	return ar.dataSourceProxy.DeleteOlderThan(table, field, before, dryRun)
+}
+
 //Truncate deletes all records in tableName table
 func (ar *AwsRedshift) Truncate(tableName string) error {
 	return ar.dataSourceProxy.Truncate(tableName)
@@ -386,24 +585,80 @@ func (ar *AwsRedshift) bulkStoreInTransaction(wrappedTx *Transaction, table *Tab
 	return nil
 }
 
-//bulkMergeInTransaction uses temporary table and insert from select statement
+//bulkMergeInTransaction inserts objects into a staging table with the same structure as the table
+//and merges it into the table by primary keys
 func (ar *AwsRedshift) bulkMergeInTransaction(wrappedTx *Transaction, table *Table, objects []map[string]interface{}) error {
-	tmpTable := &Table{
-		Name:           fmt.Sprintf("jitsu_tmp_%s", uuid.NewLettersNumbers()[:5]),
-		Columns:        table.Columns,
-		PKFields:       map[string]bool{},
-		DeletePkFields: false,
-		Version:        0,
+	stagingTable, err := ar.createStagingTableInTransaction(wrappedTx, table)
+	if err != nil {
+		return err
 	}
 
-	err := ar.dataSourceProxy.createTableInTransaction(wrappedTx, tmpTable)
+	err = ar.dataSourceProxy.bulkInsertInTransaction(wrappedTx, stagingTable, objects, redshiftValuesLimit)
 	if err != nil {
-		return fmt.Errorf("Error creating temporary table: %v", err)
+		return fmt.Errorf("Error inserting in staging table [%s]: %v", stagingTable.Name, err)
 	}
 
-	err = ar.dataSourceProxy.bulkInsertInTransaction(wrappedTx, tmpTable, objects, redshiftValuesLimit)
-	if err != nil {
-		return fmt.Errorf("Error inserting in temporary table [%s]: %v", tmpTable.Name, err)
+	if err := ar.mergeFromStagingInTransaction(wrappedTx, table, stagingTable); err != nil {
+		return err
+	}
+
+	//delete staging table
+	return ar.dataSourceProxy.dropTableInTransaction(wrappedTx, stagingTable)
+}
+
+//createStagingTableInTransaction creates a table with the same columns, types, sort and dist keys as the table
+//staging table is created in the transaction: it is dropped on rollback
+func (ar *AwsRedshift) createStagingTableInTransaction(wrappedTx *Transaction, table *Table) (*Table, error) {
+	stagingTable := &Table{
+		Name:     fmt.Sprintf("jitsu_tmp_%s", uuid.NewLettersNumbers()[:5]),
+		Columns:  table.Columns,
+		PKFields: map[string]bool{},
+	}
+
+	query := fmt.Sprintf(createStagingTableTemplate, ar.dataSourceProxy.config.Schema, stagingTable.Name, ar.dataSourceProxy.config.Schema, table.Name)
+	ar.dataSourceProxy.queryLogger.LogDDL(query)
+	if _, err := wrappedTx.tx.ExecContext(ar.dataSourceProxy.ctx, query); err != nil {
+		return nil, fmt.Errorf("Error creating staging table [%s]: %v", stagingTable.Name, checkErr(err))
+	}
+
+	return stagingTable, nil
+}
+
+//mergeFromStagingInTransaction merges rows from the staging table into the table by primary keys with configured strategy:
+//delete_insert: deletes rows with the same primary keys and inserts all staging rows
+//merge: runs MERGE statement
+func (ar *AwsRedshift) mergeFromStagingInTransaction(wrappedTx *Transaction, table, stagingTable *Table) error {
+	schema := ar.dataSourceProxy.config.Schema
+	columnNames := make([]string, 0, len(stagingTable.Columns))
+	for columnName := range stagingTable.Columns {
+		columnNames = append(columnNames, columnName)
+	}
+	sort.Strings(columnNames)
+
+	var quotedColumnNames []string
+	for _, columnName := range columnNames {
+		quotedColumnNames = append(quotedColumnNames, fmt.Sprintf(`"%s"`, columnName))
+	}
+	quotedHeader := strings.Join(quotedColumnNames, ", ")
+
+	if ar.config.MergeStrategy == RedshiftMergeStatement {
+		var conditions, updateSet, stagingValues []string
+		for _, pkColumn := range table.GetPKFields() {
+			conditions = append(conditions, fmt.Sprintf(redshiftMergeCondition, schema, table.Name, pkColumn, pkColumn))
+		}
+		for _, quotedColumnName := range quotedColumnNames {
+			updateSet = append(updateSet, fmt.Sprintf("%s = staging.%s", quotedColumnName, quotedColumnName))
+			stagingValues = append(stagingValues, "staging."+quotedColumnName)
+		}
+
+		mergeStatement := fmt.Sprintf(redshiftMergeStatement, schema, table.Name, schema, stagingTable.Name, strings.Join(conditions, " AND "),
+			strings.Join(updateSet, ", "), quotedHeader, strings.Join(stagingValues, ", "))
+		ar.dataSourceProxy.queryLogger.LogQuery(mergeStatement)
+		if _, err := wrappedTx.tx.ExecContext(ar.dataSourceProxy.ctx, mergeStatement); err != nil {
+			return fmt.Errorf("Error merging rows: %v", checkErr(err))
+		}
+
+		return nil
 	}
 
 	//delete duplicates from table
@@ -412,33 +667,79 @@ func (ar *AwsRedshift) bulkMergeInTransaction(wrappedTx *Transaction, table *Tab
 		if i > 0 {
 			deleteCondition += " AND "
 		}
-		deleteCondition += fmt.Sprintf(deleteBeforeBulkMergeCondition, ar.dataSourceProxy.config.Schema, table.Name, pkColumn, ar.dataSourceProxy.config.Schema, tmpTable.Name, pkColumn)
+		deleteCondition += fmt.Sprintf(deleteBeforeBulkMergeCondition, schema, table.Name, pkColumn, schema, stagingTable.Name, pkColumn)
 	}
-	deleteStatement := fmt.Sprintf(deleteBeforeBulkMergeUsing, ar.dataSourceProxy.config.Schema, table.Name, ar.dataSourceProxy.config.Schema, tmpTable.Name, deleteCondition)
+	deleteStatement := fmt.Sprintf(deleteBeforeBulkMergeUsing, schema, table.Name, schema, stagingTable.Name, deleteCondition)
 
 	ar.dataSourceProxy.queryLogger.LogQuery(deleteStatement)
-	_, err = wrappedTx.tx.ExecContext(ar.dataSourceProxy.ctx, deleteStatement)
-	if err != nil {
-		err = checkErr(err)
-		return fmt.Errorf("Error deleting duplicated rows: %v", err)
+	if _, err := wrappedTx.tx.ExecContext(ar.dataSourceProxy.ctx, deleteStatement); err != nil {
+		return fmt.Errorf("Error deleting duplicated rows: %v", checkErr(err))
 	}
 
 	//insert from select
-	var quotedColumnNames []string
-	for columnName := range tmpTable.Columns {
-		quotedColumnNames = append(quotedColumnNames, fmt.Sprintf(`"%s"`, columnName))
-	}
-	quotedHeader := strings.Join(quotedColumnNames, ", ")
-	insertFromSelectStatement := fmt.Sprintf(redshiftBulkMergeInsert, ar.dataSourceProxy.config.Schema, table.Name, quotedHeader, quotedHeader, ar.dataSourceProxy.config.Schema, tmpTable.Name)
+	insertFromSelectStatement := fmt.Sprintf(redshiftBulkMergeInsert, schema, table.Name, quotedHeader, quotedHeader, schema, stagingTable.Name)
 	ar.dataSourceProxy.queryLogger.LogQuery(insertFromSelectStatement)
-	_, err = wrappedTx.tx.ExecContext(ar.dataSourceProxy.ctx, insertFromSelectStatement)
-	if err != nil {
+	if _, err := wrappedTx.tx.ExecContext(ar.dataSourceProxy.ctx, insertFromSelectStatement); err != nil {
+		return fmt.Errorf("Error merging rows: %v", checkErr(err))
+	}
+
+	return nil
+}
+
+//createTableInTransaction creates table with postgres columns DDL and Redshift table attributes
+func (ar *AwsRedshift) createTableInTransaction(wrappedTx *Transaction, table *Table) error {
+	var columnsDDL []string
+	pkFields := table.GetPKFieldsMap()
+	for columnName, column := range table.Columns {
+		columnsDDL = append(columnsDDL, ar.dataSourceProxy.columnDDL(columnName, column, pkFields))
+	}
+
+	//sorting columns asc
+	sort.Strings(columnsDDL)
+	query := fmt.Sprintf(createTableTemplate, ar.dataSourceProxy.config.Schema, table.Name, strings.Join(columnsDDL, ", ")) + ar.tableAttributes(table)
+	ar.dataSourceProxy.queryLogger.LogDDL(query)
+
+	if _, err := wrappedTx.tx.ExecContext(ar.dataSourceProxy.ctx, query); err != nil {
 		err = checkErr(err)
-		return fmt.Errorf("Error merging rows: %v", err)
+		return fmt.Errorf("Error creating [%s] table with statement [%s]: %v", table.Name, query, err)
+	}
+
+	return ar.dataSourceProxy.createPrimaryKeyInTransaction(wrappedTx, table)
+}
+
+//tableAttributes returns DISTSTYLE, DISTKEY and SORTKEY clauses
+//key columns which don't exist in the table are skipped
+func (ar *AwsRedshift) tableAttributes(table *Table) string {
+	var attributes []string
+	if ar.config.DistStyle != "" {
+		if ar.config.DistStyle == "KEY" {
+			if _, ok := table.Columns[ar.config.DistKey]; ok {
+				attributes = append(attributes, fmt.Sprintf(`DISTSTYLE KEY DISTKEY("%s")`, ar.config.DistKey))
+			} else {
+				logging.Warnf("Redshift dist_key [%s] column doesn't exist in table [%s]. DISTKEY is skipped", ar.config.DistKey, table.Name)
+			}
+		} else {
+			attributes = append(attributes, "DISTSTYLE "+ar.config.DistStyle)
+		}
+	}
+
+	var sortKey []string
+	for _, column := range ar.config.SortKey {
+		if _, ok := table.Columns[column]; ok {
+			sortKey = append(sortKey, fmt.Sprintf(`"%s"`, column))
+		} else {
+			logging.Warnf("Redshift sort_key [%s] column doesn't exist in table [%s] and is skipped", column, table.Name)
+		}
+	}
+	if len(sortKey) > 0 {
+		attributes = append(attributes, fmt.Sprintf("COMPOUND SORTKEY(%s)", strings.Join(sortKey, ", ")))
+	}
+
+	if len(attributes) == 0 {
+		return ""
 	}
 
-	//delete tmp table
-	return ar.dataSourceProxy.dropTableInTransaction(wrappedTx, tmpTable)
+	return " " + strings.Join(attributes, " ")
 }
 
 func (ar *AwsRedshift) deleteWithConditions(wrappedTx *Transaction, table *Table, deleteConditions *DeleteConditions) error {
@@ -22,7 +22,7 @@ func TestRedshiftBulkInsert(t *testing.T) {
 		return
 	}
 
-	redshift, err := NewAwsRedshift(context.Background(), dsConfig, nil, &logging.QueryLogger{}, typing.SQLTypes{})
+	redshift, err := NewAwsRedshift(context.Background(), dsConfig, nil, nil, &logging.QueryLogger{}, typing.SQLTypes{})
 	require.NoError(t, err)
 	defer redshift.Close()
 
@@ -61,7 +61,7 @@ func TestRedshiftBulkUpdate(t *testing.T) {
 		return
 	}
 
-	redshift, err := NewAwsRedshift(context.Background(), dsConfig, nil, &logging.QueryLogger{}, typing.SQLTypes{})
+	redshift, err := NewAwsRedshift(context.Background(), dsConfig, nil, nil, &logging.QueryLogger{}, typing.SQLTypes{})
 	require.NoError(t, err)
 	defer redshift.Close()
 
@@ -101,6 +101,80 @@ func TestRedshiftBulkUpdate(t *testing.T) {
 	require.Equal(t, count, 5)
 }
 
+func TestRedshiftTableAttributes(t *testing.T) {
+	table := &Table{
+		Name:    "events",
+		Columns: Columns{"_timestamp": typing.SQLColumn{Type: "timestamp"}, "user_id": typing.SQLColumn{Type: "text"}},
+	}
+	tests := []struct {
+		name        string
+		config      *RedshiftConfig
+		expected    string
+		expectedErr string
+	}{
+		{
+			"empty config",
+			&RedshiftConfig{},
+			"",
+			"",
+		},
+		{
+			"dist key without style",
+			&RedshiftConfig{DistKey: "user_id", SortKey: []string{"_timestamp"}},
+			` DISTSTYLE KEY DISTKEY("user_id") COMPOUND SORTKEY("_timestamp")`,
+			"",
+		},
+		{
+			"missing columns are skipped",
+			&RedshiftConfig{DistStyle: "key", DistKey: "unknown", SortKey: []string{"unknown", "user_id"}},
+			` COMPOUND SORTKEY("user_id")`,
+			"",
+		},
+		{
+			"dist style all",
+			&RedshiftConfig{DistStyle: "all"},
+			" DISTSTYLE ALL",
+			"",
+		},
+		{
+			"dist key with even style",
+			&RedshiftConfig{DistStyle: "EVEN", DistKey: "user_id"},
+			"",
+			"Redshift dist_key can be used only with KEY dist_style",
+		},
+		{
+			"unknown merge strategy",
+			&RedshiftConfig{MergeStrategy: "upsert"},
+			"",
+			"Unknown Redshift merge_strategy: upsert. Supported: delete_insert, merge",
+		},
+		{
+			"maintenance without schedule",
+			&RedshiftConfig{Maintenance: &RedshiftMaintenanceConfig{}},
+			"",
+			"Redshift maintenance.schedule is required parameter",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.expectedErr != "" {
+				require.EqualError(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, RedshiftMergeDeleteInsert, tt.config.MergeStrategy)
+
+			ar := &AwsRedshift{config: tt.config}
+			require.Equal(t, tt.expected, ar.tableAttributes(table))
+		})
+	}
+
+	maintenance := &RedshiftConfig{Maintenance: &RedshiftMaintenanceConfig{Schedule: "0 3 * * *"}}
+	require.NoError(t, maintenance.Validate())
+	require.Equal(t, redshiftVacuumNone, maintenance.Maintenance.Vacuum, "VACUUM must be configured explicitly")
+}
+
 func readRedshiftConfig(t *testing.T) (*DataSourceConfig, bool) {
 	sfConfigJSON := os.Getenv(testRedshiftConfigVar)
 	if sfConfigJSON == "" {
@@ -0,0 +1,118 @@
+package adapters
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	azureBlobURLTemplate = "https://%s.blob.core.windows.net/%s/%s"
+	azureBlobAPIVersion  = "2020-04-08"
+)
+
+//AzureBlobConfig is a dto for Azure Blob Storage container config deserialization
+type AzureBlobConfig struct {
+	AccountName string `mapstructure:"account_name" json:"account_name,omitempty" yaml:"account_name,omitempty"`
+	Container   string `mapstructure:"container" json:"container,omitempty" yaml:"container,omitempty"`
+	Folder      string `mapstructure:"folder" json:"folder,omitempty" yaml:"folder,omitempty"`
+	//SASToken is a Shared Access Signature token of the container with read, write and delete permissions
+	SASToken string `mapstructure:"sas_token" json:"sas_token,omitempty" yaml:"sas_token,omitempty"`
+}
+
+//Validate returns err if invalid
+func (abc *AzureBlobConfig) Validate() error {
+	if abc == nil {
+		return errors.New("Azure Blob config is required")
+	}
+	if abc.AccountName == "" {
+		return errors.New("Azure Blob account_name is required parameter")
+	}
+	if abc.Container == "" {
+		return errors.New("Azure Blob container is required parameter")
+	}
+	if abc.SASToken == "" {
+		return errors.New("Azure Blob sas_token is required parameter")
+	}
+
+	abc.SASToken = strings.TrimPrefix(abc.SASToken, "?")
+	return nil
+}
+
+//ObjectURL returns URL of the object in the container (without SAS token)
+func (abc *AzureBlobConfig) ObjectURL(fileName string) string {
+	if abc.Folder != "" {
+		fileName = abc.Folder + "/" + fileName
+	}
+
+	return fmt.Sprintf(azureBlobURLTemplate, abc.AccountName, abc.Container, (&url.URL{Path: fileName}).EscapedPath())
+}
+
+//AzureBlob is an Azure Blob Storage adapter for uploading/deleting files with REST API
+//it is used as a stage of Azure Synapse destination
+type AzureBlob struct {
+	config *AzureBlobConfig
+	client *http.Client
+}
+
+//NewAzureBlob returns configured AzureBlob adapter
+func NewAzureBlob(config *AzureBlobConfig) (*AzureBlob, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &AzureBlob{config: config, client: &http.Client{Timeout: 10 * time.Minute}}, nil
+}
+
+//UploadBytes creates named block blob with fileBytes content
+func (ab *AzureBlob) UploadBytes(fileName string, fileBytes []byte) error {
+	headers := map[string]string{"x-ms-blob-type": "BlockBlob", "Content-Type": "text/csv"}
+	if err := ab.doRequest(http.MethodPut, fileName, fileBytes, headers); err != nil {
+		return fmt.Errorf("Error uploading file [%s] to Azure Blob container [%s]: %v", fileName, ab.config.Container, err)
+	}
+
+	return nil
+}
+
+//DeleteObject deletes blob by key
+func (ab *AzureBlob) DeleteObject(key string) error {
+	if err := ab.doRequest(http.MethodDelete, key, nil, nil); err != nil {
+		return fmt.Errorf("Error deleting file [%s] from Azure Blob container [%s]: %v", key, ab.config.Container, err)
+	}
+
+	return nil
+}
+
+//Close returns nil
+func (ab *AzureBlob) Close() error {
+	return nil
+}
+
+func (ab *AzureBlob) doRequest(method, fileName string, body []byte, headers map[string]string) error {
+	req, err := http.NewRequest(method, ab.config.ObjectURL(fileName)+"?"+ab.config.SASToken, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("x-ms-version", azureBlobAPIVersion)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := ab.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		responseBody, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("HTTP code = %d, response: %s", resp.StatusCode, string(responseBody))
+	}
+
+	return nil
+}
@@ -8,16 +8,21 @@ import (
 	"github.com/hashicorp/go-multierror"
 	"net/http"
 	"strings"
+	"time"
 
 	"cloud.google.com/go/bigquery"
 	"github.com/jitsucom/jitsu/server/logging"
 	"github.com/jitsucom/jitsu/server/typing"
 	"google.golang.org/api/googleapi"
+	"google.golang.org/api/iterator"
 )
 
 const (
 	deleteBigQueryTemplate   = "DELETE FROM `%s.%s.%s` WHERE %s"
 	truncateBigQueryTemplate = "TRUNCATE TABLE `%s.%s.%s`"
+	//olderThan templates use @before named parameter
+	countOlderThanBigQueryTemplate  = "SELECT count(*) FROM `%s.%s.%s` WHERE `%s` < @before"
+	deleteOlderThanBigQueryTemplate = "DELETE FROM `%s.%s.%s` WHERE `%s` < @before"
 
 	rowsLimitPerInsertOperation = 500
 )
@@ -41,6 +46,8 @@ type BigQuery struct {
 	config      *GoogleConfig
 	queryLogger *logging.QueryLogger
 	sqlTypes    typing.SQLTypes
+	//writer is used in stream mode if Storage Write API is enabled
+	writer *BigQueryWriter
 }
 
 //NewBigQuery return configured BigQuery adapter instance
@@ -58,7 +65,17 @@ func NewBigQuery(ctx context.Context, config *GoogleConfig, queryLogger *logging
 		return nil, fmt.Errorf("Error creating BigQuery client: %v", err)
 	}
 
-	return &BigQuery{ctx: ctx, client: client, config: config, queryLogger: queryLogger, sqlTypes: reformatMappings(sqlTypes, SchemaToBigQueryString)}, nil
+	bq := &BigQuery{ctx: ctx, client: client, config: config, queryLogger: queryLogger, sqlTypes: reformatMappings(sqlTypes, SchemaToBigQueryString)}
+	if config.streamMode && config.StorageWriteAPI != nil && config.StorageWriteAPI.Enabled {
+		writer, err := NewBigQueryWriter(ctx, config, queryLogger)
+		if err != nil {
+			client.Close()
+			return nil, err
+		}
+		bq.writer = writer
+	}
+
+	return bq, nil
 }
 
 //Copy transfers data from google cloud storage file to google BigQuery table as one batch
@@ -93,27 +110,177 @@ func (bq *BigQuery) Test() error {
 
 //Insert provided object in BigQuery in stream mode
 func (bq *BigQuery) Insert(eventContext *EventContext) error {
-	inserter := bq.client.Dataset(bq.config.Dataset).Table(eventContext.Table.Name).Inserter()
-	bq.logQuery(fmt.Sprintf("Inserting values to table %s: ", eventContext.Table.Name), eventContext.ProcessedEvent, false)
-	err := inserter.Put(bq.ctx, &BQItem{values: eventContext.ProcessedEvent})
-	if err != nil {
-		putMultiError, ok := err.(bigquery.PutMultiError)
-		if !ok {
-			return err
+	return bq.InsertBatch([]*EventContext{eventContext})[0]
+}
+
+//InsertBatch inserts objects in BigQuery in stream mode with one request per table
+//uses Storage Write API if configured. Rows rejected by the write stream (e.g. the stream doesn't know new columns yet)
+//are inserted with legacy streaming inserts
+//returns errors per event (in the same order)
+func (bq *BigQuery) InsertBatch(eventContexts []*EventContext) []error {
+	errs := make([]error, len(eventContexts))
+	legacyEvents := eventContexts
+	var legacyIndexes []int
+	if bq.writer != nil {
+		legacyEvents = nil
+		for i, err := range bq.writer.InsertBatch(eventContexts) {
+			if err == ErrWriteStreamRejectedRow {
+				legacyEvents = append(legacyEvents, eventContexts[i])
+				legacyIndexes = append(legacyIndexes, i)
+			} else {
+				errs[i] = err
+			}
 		}
+	} else {
+		for i := range eventContexts {
+			legacyIndexes = append(legacyIndexes, i)
+		}
+	}
 
-		//parse bigquery multi error
+	tableNames, tableIndexes := groupByTable(legacyEvents)
+	for _, tableName := range tableNames {
+		indexes := tableIndexes[tableName]
+		tableEvents := make([]*EventContext, len(indexes))
+		for j, i := range indexes {
+			tableEvents[j] = legacyEvents[i]
+		}
+
+		for j, err := range bq.insertLegacy(tableName, tableEvents) {
+			errs[legacyIndexes[indexes[j]]] = err
+		}
+	}
+
+	return errs
+}
+
+//insertLegacy inserts objects into the table with one legacy streaming insert request
+//invalid rows are skipped and don't fail other rows. Returns errors per event (in the same order)
+func (bq *BigQuery) insertLegacy(tableName string, eventContexts []*EventContext) []error {
+	errs := make([]error, len(eventContexts))
+	items := make([]*BQItem, len(eventContexts))
+	for i, eventContext := range eventContexts {
+		bq.logQuery(fmt.Sprintf("Inserting values to table %s: ", tableName), eventContext.ProcessedEvent, false)
+		items[i] = &BQItem{values: ReformatBigQueryObject(eventContext.Table, eventContext.ProcessedEvent)}
+	}
+
+	inserter := bq.client.Dataset(bq.config.Dataset).Table(tableName).Inserter()
+	inserter.SkipInvalidRows = true
+	err := inserter.Put(bq.ctx, items)
+	if err == nil {
+		return errs
+	}
+
+	putMultiError, ok := err.(bigquery.PutMultiError)
+	if !ok {
+		for i := range errs {
+			errs[i] = err
+		}
+		return errs
+	}
+
+	//parse bigquery multi error
+	for _, rowErr := range putMultiError {
 		var multiErr error
-		for _, errUnit := range putMultiError {
+		for _, errUnit := range rowErr.Errors {
 			multiErr = multierror.Append(multiErr, errors.New(errUnit.Error()))
 		}
+		if rowErr.RowIndex >= 0 && rowErr.RowIndex < len(errs) {
+			errs[rowErr.RowIndex] = multiErr
+		}
+	}
 
-		return multiErr
+	return errs
+}
+
+//WriteBatchSize returns max number of events which are inserted with one InsertBatch call
+//batches are used only with Storage Write API, returns 0 otherwise
+func (bq *BigQuery) WriteBatchSize() int {
+	if bq.writer == nil {
+		return 0
+	}
+
+	return bq.config.StorageWriteAPI.BatchSize
+}
+
+//groupByTable returns table names in the order of events and events indexes per table
+func groupByTable(eventContexts []*EventContext) ([]string, map[string][]int) {
+	var tableNames []string
+	tableIndexes := map[string][]int{}
+	for i, eventContext := range eventContexts {
+		tableName := eventContext.Table.Name
+		if _, ok := tableIndexes[tableName]; !ok {
+			tableNames = append(tableNames, tableName)
+		}
+		tableIndexes[tableName] = append(tableIndexes[tableName], i)
+	}
+
+	return tableNames, tableIndexes
+}
+
+//Select executes SELECT query (standard SQL) and passes result rows to consume one by one (the iterator loads result pages)
+//BigQuery doesn't have read-only transactions: the statement type is checked with a dry run before the execution
+func (bq *BigQuery) Select(query string, consume func(row map[string]interface{}) error) error {
+	if err := CheckSelectQuery(query); err != nil {
+		return err
+	}
+
+	bq.queryLogger.LogQuery(query)
+
+	dryRun := bq.client.Query(query)
+	dryRun.DryRun = true
+	job, err := dryRun.Run(bq.ctx)
+	if err != nil {
+		return fmt.Errorf("Error validating query: %v", err)
+	}
+	if status := job.LastStatus(); status != nil && status.Statistics != nil {
+		if queryStatistics, ok := status.Statistics.Details.(*bigquery.QueryStatistics); ok && queryStatistics.StatementType != "SELECT" {
+			return ErrNotSelectQuery
+		}
+	}
+
+	it, err := bq.client.Query(query).Read(bq.ctx)
+	if err != nil {
+		return fmt.Errorf("Error executing query: %v", err)
+	}
+
+	for {
+		row := map[string]bigquery.Value{}
+		err := it.Next(&row)
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("Error reading rows: %v", err)
+		}
+
+		if err := consume(bigQueryValue(row).(map[string]interface{})); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
+//bigQueryValue converts BigQuery records and repeated values into plain maps and slices
+func bigQueryValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]bigquery.Value:
+		object := make(map[string]interface{}, len(v))
+		for name, fieldValue := range v {
+			object[name] = bigQueryValue(fieldValue)
+		}
+		return object
+	case []bigquery.Value:
+		array := make([]interface{}, len(v))
+		for i, element := range v {
+			array[i] = bigQueryValue(element)
+		}
+		return array
+	default:
+		return v
+	}
+}
+
 //GetTableSchema return google BigQuery table (name,columns) representation wrapped in Table struct
 func (bq *BigQuery) GetTableSchema(tableName string) (*Table, error) {
 	table := &Table{Name: tableName, Columns: Columns{}}
@@ -130,7 +297,12 @@ func (bq *BigQuery) GetTableSchema(tableName string) (*Table, error) {
 	}
 
 	for _, field := range meta.Schema {
-		table.Columns[field.Name] = typing.SQLColumn{Type: string(field.Type)}
+		if field.Type == bigquery.RecordFieldType || field.Repeated {
+			nestedType := nestedTypeFromBigQueryField(field)
+			table.Columns[field.Name] = typing.SQLColumn{Type: nestedType.String(), Nested: nestedType}
+		} else {
+			table.Columns[field.Name] = typing.SQLColumn{Type: string(field.Type)}
+		}
 	}
 
 	return table, nil
@@ -152,12 +324,7 @@ func (bq *BigQuery) CreateTable(table *Table) error {
 
 	bqSchema := bigquery.Schema{}
 	for columnName, column := range table.Columns {
-		bigQueryType := bigquery.FieldType(strings.ToUpper(column.DDLType()))
-		sqlType, ok := bq.sqlTypes[columnName]
-		if ok {
-			bigQueryType = bigquery.FieldType(strings.ToUpper(sqlType.DDLType()))
-		}
-		bqSchema = append(bqSchema, &bigquery.FieldSchema{Name: columnName, Type: bigQueryType})
+		bqSchema = append(bqSchema, bq.columnFieldSchema(columnName, column))
 	}
 	bq.logQuery("Creating table for schema: ", bqSchema, true)
 	if err := bqTable.Create(bq.ctx, &bigquery.TableMetadata{Name: table.Name, Schema: bqSchema}); err != nil {
@@ -185,7 +352,21 @@ func (bq *BigQuery) CreateDataset(dataset string) error {
 	return nil
 }
 
+//columnFieldSchema returns BigQuery field schema of the column (RECORD or REPEATED field for nested columns)
+func (bq *BigQuery) columnFieldSchema(columnName string, column typing.SQLColumn) *bigquery.FieldSchema {
+	if sqlType, ok := bq.sqlTypes[columnName]; ok {
+		return &bigquery.FieldSchema{Name: columnName, Type: bigquery.FieldType(strings.ToUpper(sqlType.DDLType()))}
+	}
+
+	if column.Nested != nil {
+		return bigQueryFieldSchema(columnName, column.Nested)
+	}
+
+	return &bigquery.FieldSchema{Name: columnName, Type: bigquery.FieldType(strings.ToUpper(column.DDLType()))}
+}
+
 //PatchTableSchema adds Table columns to google BigQuery table
+//new fields of nested records are added into existing RECORD columns
 func (bq *BigQuery) PatchTableSchema(patchSchema *Table) error {
 	bqTable := bq.client.Dataset(bq.config.Dataset).Table(patchSchema.Name)
 	metadata, err := bqTable.Metadata(bq.ctx)
@@ -194,12 +375,22 @@ func (bq *BigQuery) PatchTableSchema(patchSchema *Table) error {
 	}
 
 	for columnName, column := range patchSchema.Columns {
-		bigQueryType := bigquery.FieldType(strings.ToUpper(column.DDLType()))
-		sqlType, ok := bq.sqlTypes[columnName]
-		if ok {
-			bigQueryType = bigquery.FieldType(strings.ToUpper(sqlType.DDLType()))
+		fieldSchema := bq.columnFieldSchema(columnName, column)
+
+		var existingField *bigquery.FieldSchema
+		for _, field := range metadata.Schema {
+			if field.Name == columnName {
+				existingField = field
+				break
+			}
+		}
+
+		if existingField != nil {
+			//new fields of the nested record
+			mergeBigQueryFieldSchema(existingField, fieldSchema)
+		} else {
+			metadata.Schema = append(metadata.Schema, fieldSchema)
 		}
-		metadata.Schema = append(metadata.Schema, &bigquery.FieldSchema{Name: columnName, Type: bigQueryType})
 	}
 	updateReq := bigquery.TableMetadataToUpdate{Schema: metadata.Schema}
 	bq.logQuery("Patch update request: ", updateReq, true)
@@ -228,6 +419,74 @@ func (bq *BigQuery) DeleteWithConditions(tableName string, deleteConditions *Del
 	return err
 }
 
+//Delete runs DeleteWithConditions
+func (bq *BigQuery) Delete(table *Table, deleteConditions *DeleteConditions) error {
+	return bq.DeleteWithConditions(table.Name, deleteConditions)
+}
+
+//GetTableNames returns names of all tables in the dataset
+func (bq *BigQuery) GetTableNames() ([]string, error) {
+	var tableNames []string
+	tables := bq.client.Dataset(bq.config.Dataset).Tables(bq.ctx)
+	for {
+		table, err := tables.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Error listing tables of BigQuery dataset %s: %v", bq.config.Dataset, err)
+		}
+
+		tableNames = append(tableNames, table.TableID)
+	}
+
+	return tableNames, nil
+}
+
+//DeleteOlderThan deletes (or counts if dryRun) rows which field value is before the time
+//The same limitations as in DeleteWithConditions are applied to recently streamed rows
+func (bq *BigQuery) DeleteOlderThan(table *Table, field string, before time.Time, dryRun bool) (int64, error) {
+	template := deleteOlderThanBigQueryTemplate
+	if dryRun {
+		template = countOlderThanBigQueryTemplate
+	}
+	query := bq.client.Query(fmt.Sprintf(template, bq.config.Project, bq.config.Dataset, table.Name, field))
+	query.Parameters = []bigquery.QueryParameter{{Name: "before", Value: before}}
+	bq.queryLogger.LogQueryWithValues(query.Q, []interface{}{before})
+
+	job, err := query.Run(bq.ctx)
+	if err != nil {
+		return 0, mapError(fmt.Errorf("Error running query in table %s: %v", table.Name, err))
+	}
+
+	if dryRun {
+		it, err := job.Read(bq.ctx)
+		if err != nil {
+			return 0, mapError(fmt.Errorf("Error counting rows in table %s: %v", table.Name, err))
+		}
+		var row []bigquery.Value
+		if err := it.Next(&row); err != nil {
+			return 0, fmt.Errorf("Error reading rows count of table %s: %v", table.Name, err)
+		}
+		count, _ := row[0].(int64)
+		return count, nil
+	}
+
+	status, err := job.Wait(bq.ctx)
+	if err != nil {
+		return 0, fmt.Errorf("Error waiting for delete job in table %s: %v", table.Name, err)
+	}
+	if err := status.Err(); err != nil {
+		return 0, mapError(fmt.Errorf("Error deleting rows from table %s: %v", table.Name, err))
+	}
+
+	if statistics, ok := status.Statistics.Details.(*bigquery.QueryStatistics); ok {
+		return statistics.NumDMLAffectedRows, nil
+	}
+
+	return 0, nil
+}
+
 //BulkInsert streams data into BQ using stream API
 //1 insert = max 500 rows
 func (bq *BigQuery) BulkInsert(table *Table, objects []map[string]interface{}) error {
@@ -245,7 +504,7 @@ func (bq *BigQuery) BulkInsert(table *Table, objects []map[string]interface{}) e
 			items = make([]*BQItem, 0, rowsLimitPerInsertOperation)
 		}
 
-		items = append(items, &BQItem{values: object})
+		items = append(items, &BQItem{values: ReformatBigQueryObject(table, object)})
 	}
 
 	if len(items) > 0 {
@@ -312,7 +571,7 @@ func (bq *BigQuery) toDeleteQuery(conditions *DeleteConditions) string {
 		queryConditions = append(queryConditions, conditionString)
 	}
 
-	return strings.Join(queryConditions, conditions.JoinCondition)
+	return strings.Join(queryConditions, " "+conditions.JoinCondition+" ")
 }
 
 func (bq *BigQuery) logQuery(messageTemplate string, entity interface{}, ddl bool) {
@@ -329,6 +588,12 @@ func (bq *BigQuery) logQuery(messageTemplate string, entity interface{}, ddl boo
 }
 
 func (bq *BigQuery) Close() error {
+	if bq.writer != nil {
+		if err := bq.writer.Close(); err != nil {
+			logging.Errorf("Error closing BigQuery Storage Write API writer: %v", err)
+		}
+	}
+
 	return bq.client.Close()
 }
 
@@ -0,0 +1,214 @@
+package adapters
+
+import (
+	"fmt"
+	"sort"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/jitsucom/jitsu/server/schema"
+	"github.com/jitsucom/jitsu/server/typing"
+)
+
+//BigQueryNestedColumn maps structure of nested objects and arrays into RECORD and REPEATED column
+//BigQuery doesn't support arrays of arrays and records without fields: they are written as STRING (JSON)
+//nested field names are reformatted like top level columns names
+func BigQueryNestedColumn(nestedType *typing.NestedType) typing.SQLColumn {
+	normalized := normalizeBigQueryNestedType(nestedType)
+	if normalized == nil || (normalized.Type != typing.OBJECT && normalized.Type != typing.ARRAY) {
+		return typing.SQLColumn{Type: SchemaToBigQueryString[typing.STRING]}
+	}
+
+	return typing.SQLColumn{Type: normalized.String(), Nested: normalized}
+}
+
+//normalizeBigQueryNestedType returns nested type which can be created in BigQuery or nil if it doesn't have fields
+func normalizeBigQueryNestedType(nestedType *typing.NestedType) *typing.NestedType {
+	if nestedType == nil {
+		return nil
+	}
+
+	switch nestedType.Type {
+	case typing.OBJECT:
+		normalized := &typing.NestedType{Type: typing.OBJECT, Fields: map[string]*typing.NestedType{}}
+		for name, fieldType := range nestedType.Fields {
+			fieldName := schema.Reformat(name)
+			normalized.Fields[fieldName] = normalizeBigQueryNestedType(typing.MergeNestedTypes(normalized.Fields[fieldName], fieldType))
+			if normalized.Fields[fieldName] == nil {
+				delete(normalized.Fields, fieldName)
+			}
+		}
+		if len(normalized.Fields) == 0 {
+			return nil
+		}
+		return normalized
+	case typing.ARRAY:
+		elem := normalizeBigQueryNestedType(nestedType.Elem)
+		if elem == nil || elem.Type == typing.ARRAY {
+			elem = &typing.NestedType{Type: typing.STRING}
+		}
+		return &typing.NestedType{Type: typing.ARRAY, Elem: elem}
+	case typing.BOOL, typing.INT64, typing.FLOAT64, typing.TIMESTAMP:
+		return nestedType
+	default:
+		return &typing.NestedType{Type: typing.STRING}
+	}
+}
+
+//bigQueryFieldSchema returns BigQuery field schema of normalized nested type
+func bigQueryFieldSchema(name string, nestedType *typing.NestedType) *bigquery.FieldSchema {
+	switch nestedType.Type {
+	case typing.OBJECT:
+		names := make([]string, 0, len(nestedType.Fields))
+		for fieldName := range nestedType.Fields {
+			names = append(names, fieldName)
+		}
+		sort.Strings(names)
+
+		fieldSchema := &bigquery.FieldSchema{Name: name, Type: bigquery.RecordFieldType}
+		for _, fieldName := range names {
+			fieldSchema.Schema = append(fieldSchema.Schema, bigQueryFieldSchema(fieldName, nestedType.Fields[fieldName]))
+		}
+		return fieldSchema
+	case typing.ARRAY:
+		fieldSchema := bigQueryFieldSchema(name, nestedType.Elem)
+		fieldSchema.Repeated = true
+		return fieldSchema
+	default:
+		return &bigquery.FieldSchema{Name: name, Type: bigquery.FieldType(SchemaToBigQueryString[nestedType.Type])}
+	}
+}
+
+//nestedTypeFromBigQueryField returns nested type of RECORD or REPEATED field
+//types which aren't created by Jitsu (e.g. NUMERIC) are considered as STRING
+func nestedTypeFromBigQueryField(fieldSchema *bigquery.FieldSchema) *typing.NestedType {
+	var nestedType *typing.NestedType
+	switch fieldSchema.Type {
+	case bigquery.RecordFieldType:
+		nestedType = &typing.NestedType{Type: typing.OBJECT, Fields: map[string]*typing.NestedType{}}
+		for _, field := range fieldSchema.Schema {
+			nestedType.Fields[field.Name] = nestedTypeFromBigQueryField(field)
+		}
+	case bigquery.IntegerFieldType:
+		nestedType = &typing.NestedType{Type: typing.INT64}
+	case bigquery.FloatFieldType:
+		nestedType = &typing.NestedType{Type: typing.FLOAT64}
+	case bigquery.BooleanFieldType:
+		nestedType = &typing.NestedType{Type: typing.BOOL}
+	case bigquery.TimestampFieldType:
+		nestedType = &typing.NestedType{Type: typing.TIMESTAMP}
+	default:
+		nestedType = &typing.NestedType{Type: typing.STRING}
+	}
+
+	if fieldSchema.Repeated {
+		return &typing.NestedType{Type: typing.ARRAY, Elem: nestedType}
+	}
+
+	return nestedType
+}
+
+//mergeBigQueryFieldSchema adds fields of another RECORD which don't exist in current RECORD (recursively)
+//types of existing fields aren't changed
+func mergeBigQueryFieldSchema(current, another *bigquery.FieldSchema) {
+	if current.Type != bigquery.RecordFieldType || another.Type != bigquery.RecordFieldType {
+		return
+	}
+
+	for _, anotherField := range another.Schema {
+		var currentField *bigquery.FieldSchema
+		for _, field := range current.Schema {
+			if field.Name == anotherField.Name {
+				currentField = field
+				break
+			}
+		}
+
+		if currentField == nil {
+			current.Schema = append(current.Schema, anotherField)
+		} else {
+			mergeBigQueryFieldSchema(currentField, anotherField)
+		}
+	}
+}
+
+//ReformatBigQueryObject returns a copy of the object with values of RECORD and REPEATED columns reformatted
+//according to the column structure (e.g. nested field names are reformatted, values of STRING fields are JSON strings)
+//nested objects and arrays of other columns are JSON strings
+func ReformatBigQueryObject(table *Table, object map[string]interface{}) map[string]interface{} {
+	reformatted := make(map[string]interface{}, len(object))
+	for name, value := range object {
+		if column, ok := table.Columns[name]; ok && column.Nested != nil {
+			reformatted[name] = reformatBigQueryNestedValue(column.Nested, value)
+		} else {
+			reformatted[name] = nestedToJSON(value)
+		}
+	}
+
+	return reformatted
+}
+
+//reformatBigQueryNestedValue returns the value according to the nested type
+//values which don't match the type (e.g. object instead of array) are returned as is
+func reformatBigQueryNestedValue(nestedType *typing.NestedType, value interface{}) interface{} {
+	switch nestedType.Type {
+	case typing.OBJECT:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return value
+		}
+
+		reformatted := map[string]interface{}{}
+		for name, fieldValue := range object {
+			if fieldValue == nil {
+				continue
+			}
+
+			fieldName := schema.Reformat(name)
+			fieldType, ok := nestedType.Fields[fieldName]
+			if !ok {
+				//objects without fields don't have RECORD fields
+				if fieldObject, isObject := fieldValue.(map[string]interface{}); isObject && len(fieldObject) == 0 {
+					continue
+				}
+				reformatted[fieldName] = fieldValue
+				continue
+			}
+
+			reformatted[fieldName] = reformatBigQueryNestedValue(fieldType, fieldValue)
+		}
+
+		if len(reformatted) == 0 {
+			return nil
+		}
+		return reformatted
+	case typing.ARRAY:
+		array, ok := value.([]interface{})
+		if !ok {
+			return value
+		}
+
+		reformatted := make([]interface{}, 0, len(array))
+		for _, element := range array {
+			if element == nil {
+				continue
+			}
+			if reformattedElement := reformatBigQueryNestedValue(nestedType.Elem, element); reformattedElement != nil {
+				reformatted = append(reformatted, reformattedElement)
+			}
+		}
+		return reformatted
+	case typing.STRING:
+		switch v := value.(type) {
+		case string:
+			return v
+		case map[string]interface{}, []interface{}:
+			return nestedToJSON(v)
+		default:
+			return fmt.Sprint(typing.ReformatValue(v))
+		}
+	case typing.TIMESTAMP:
+		return typing.ReformatTimeValue(value)
+	default:
+		return typing.ReformatValue(value)
+	}
+}
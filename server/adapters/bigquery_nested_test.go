@@ -0,0 +1,109 @@
+package adapters
+
+import (
+	"encoding/json"
+	"testing"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/jitsucom/jitsu/server/typing"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBigQueryNestedColumn(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    interface{}
+		expected string
+	}{
+		{
+			"object",
+			map[string]interface{}{"Email": "a", "amount": json.Number("1.5"), "address": map[string]interface{}{"city": "b"}},
+			"STRUCT<address STRUCT<city STRING>, amount FLOAT64, email STRING>",
+		},
+		{
+			"array of objects",
+			[]interface{}{map[string]interface{}{"sku": "a"}, map[string]interface{}{"price": 1}},
+			"ARRAY<STRUCT<price INT64, sku STRING>>",
+		},
+		{
+			"array of arrays",
+			[]interface{}{[]interface{}{1}},
+			"ARRAY<STRING>",
+		},
+		{
+			"empty array",
+			[]interface{}{},
+			"ARRAY<STRING>",
+		},
+		{
+			"empty objects are skipped",
+			map[string]interface{}{"id": 1, "empty": map[string]interface{}{}},
+			"STRUCT<id INT64>",
+		},
+		{
+			"empty object",
+			map[string]interface{}{},
+			"STRING",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nestedType, err := typing.NestedTypeFromValue(tt.input)
+			require.NoError(t, err)
+
+			column := BigQueryNestedColumn(nestedType)
+			require.Equal(t, tt.expected, column.Type)
+			if column.Nested != nil {
+				require.Equal(t, column.Nested, nestedTypeFromBigQueryField(bigQueryFieldSchema("column", column.Nested)), "BigQuery field schema must be read back as the same type")
+			}
+		})
+	}
+}
+
+func TestMergeBigQueryFieldSchema(t *testing.T) {
+	current := bigQueryFieldSchema("user", &typing.NestedType{Type: typing.OBJECT, Fields: map[string]*typing.NestedType{
+		"email":   {Type: typing.STRING},
+		"address": {Type: typing.OBJECT, Fields: map[string]*typing.NestedType{"city": {Type: typing.STRING}}},
+	}})
+	another := bigQueryFieldSchema("user", &typing.NestedType{Type: typing.OBJECT, Fields: map[string]*typing.NestedType{
+		"email":   {Type: typing.INT64},
+		"name":    {Type: typing.STRING},
+		"address": {Type: typing.OBJECT, Fields: map[string]*typing.NestedType{"zip": {Type: typing.INT64}}},
+	}})
+
+	mergeBigQueryFieldSchema(current, another)
+
+	require.Equal(t, &bigquery.FieldSchema{Name: "user", Type: bigquery.RecordFieldType, Schema: bigquery.Schema{
+		{Name: "address", Type: bigquery.RecordFieldType, Schema: bigquery.Schema{
+			{Name: "city", Type: bigquery.StringFieldType},
+			{Name: "zip", Type: bigquery.IntegerFieldType},
+		}},
+		{Name: "email", Type: bigquery.StringFieldType},
+		{Name: "name", Type: bigquery.StringFieldType},
+	}}, current)
+}
+
+func TestReformatBigQueryObject(t *testing.T) {
+	table := &Table{Name: "events", Columns: Columns{
+		"user": BigQueryNestedColumn(&typing.NestedType{Type: typing.OBJECT, Fields: map[string]*typing.NestedType{
+			"email": {Type: typing.STRING},
+			"tags":  {Type: typing.ARRAY, Elem: &typing.NestedType{Type: typing.STRING}},
+		}}),
+		"items": BigQueryNestedColumn(&typing.NestedType{Type: typing.ARRAY, Elem: &typing.NestedType{Type: typing.INT64}}),
+	}}
+	object := map[string]interface{}{
+		"id":      "1",
+		"user":    map[string]interface{}{"Email": "a@b.com", "tags": []interface{}{"a", json.Number("1"), []interface{}{"b"}}, "empty": nil},
+		"items":   []interface{}{json.Number("1"), nil, json.Number("2")},
+		"payload": map[string]interface{}{"key": "value"},
+	}
+
+	reformatted := ReformatBigQueryObject(table, object)
+	require.Equal(t, map[string]interface{}{
+		"id":      "1",
+		"user":    map[string]interface{}{"email": "a@b.com", "tags": []interface{}{"a", "1", `["b"]`}},
+		"items":   []interface{}{int64(1), int64(2)},
+		"payload": `{"key":"value"}`,
+	}, reformatted)
+	require.Equal(t, map[string]interface{}{"Email": "a@b.com", "tags": []interface{}{"a", json.Number("1"), []interface{}{"b"}}, "empty": nil}, object["user"], "input object mustn't be changed")
+}
@@ -0,0 +1,735 @@
+package adapters
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/appstatus"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/safego"
+	"github.com/jitsucom/jitsu/server/timestamp"
+	"github.com/jitsucom/jitsu/server/typing"
+	"google.golang.org/api/option"
+	gtransport "google.golang.org/api/transport/grpc"
+	storagepb "google.golang.org/genproto/googleapis/cloud/bigquery/storage/v1beta2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+const (
+	//CommittedWriteStream rows are visible right after append
+	CommittedWriteStream = "committed"
+	//PendingWriteStream rows are visible after the stream is finalized and committed
+	PendingWriteStream = "pending"
+
+	defaultWriteStreamCommitRows        = 10000
+	defaultWriteStreamCommitIntervalSec = 10
+	defaultWriteStreamBatchSize         = 500
+
+	storageWriteAPIEndpoint = "bigquerystorage.googleapis.com:443"
+	storageWriteAPIScope    = "https://www.googleapis.com/auth/bigquery.insertdata"
+
+	writeStreamMessageName   = "jitsu_row"
+	writeStreamAppendRetries = 3
+)
+
+//ErrWriteStreamRejectedRow is returned when the Storage Write API rejects rows (e.g. the stream doesn't know new columns yet)
+//rows aren't written and can be inserted in another way
+var ErrWriteStreamRejectedRow = errors.New("row has been rejected by BigQuery write stream")
+
+//ErrWriteStreamNotAppended is returned when rows haven't been appended to the write stream (e.g. connection errors)
+//rows can be appended again
+var ErrWriteStreamNotAppended = errors.New("rows haven't been appended to BigQuery write stream")
+
+//BigQueryWriter writes rows into BigQuery tables with Storage Write API (one write stream per table)
+//Rows of the same table are appended with one AppendRows request with the explicit offset: the number of rows which have been
+//appended to the stream in the order StreamingWorker dequeues events. A retried append after a lost response is deduplicated
+//by BigQuery (ALREADY_EXISTS). Rows which haven't been appended are put back into the events queue by StreamingWorker
+type BigQueryWriter struct {
+	ctx         context.Context
+	config      *GoogleConfig
+	conn        *grpc.ClientConn
+	client      storagepb.BigQueryWriteClient
+	queryLogger *logging.QueryLogger
+
+	//mutex guards only streams and closed. Appends are guarded by the stream mutex
+	mutex *sync.Mutex
+	//streams is a table name - write stream
+	streams map[string]*writeStream
+	closed  bool
+}
+
+//writeStream is an opened Storage Write API stream with the rows schema
+type writeStream struct {
+	mutex        *sync.Mutex
+	name         string
+	tableName    string
+	appendRows   storagepb.BigQueryWrite_AppendRowsClient
+	schema       *writeStreamSchema
+	nextOffset   int64
+	createdAt    time.Time
+	schemaIsSent bool
+	//closed stream is finalized (or can't be used) and will be replaced with a new one
+	closed bool
+}
+
+//writeStreamSchema is a proto descriptor of rows built from the table columns
+type writeStreamSchema struct {
+	descriptor        *descriptorpb.DescriptorProto
+	messageDescriptor protoreflect.MessageDescriptor
+	//signature is sorted columns names with types, it is used for detecting schema changes
+	signature string
+}
+
+//NewBigQueryWriter returns configured BigQueryWriter and runs goroutine for committing pending streams
+func NewBigQueryWriter(ctx context.Context, config *GoogleConfig, queryLogger *logging.QueryLogger) (*BigQueryWriter, error) {
+	var conn *grpc.ClientConn
+	var err error
+	if config.StorageWriteAPI.Endpoint != "" {
+		//local emulator
+		conn, err = grpc.DialContext(ctx, config.StorageWriteAPI.Endpoint, grpc.WithInsecure())
+	} else {
+		opts := []option.ClientOption{option.WithEndpoint(storageWriteAPIEndpoint), option.WithScopes(storageWriteAPIScope)}
+		if config.credentials != nil {
+			opts = append(opts, config.credentials)
+		}
+		conn, err = gtransport.Dial(ctx, opts...)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Error creating BigQuery Storage Write API connection: %v", err)
+	}
+
+	bqw := &BigQueryWriter{
+		ctx:         ctx,
+		config:      config,
+		conn:        conn,
+		client:      storagepb.NewBigQueryWriteClient(conn),
+		queryLogger: queryLogger,
+		mutex:       &sync.Mutex{},
+		streams:     map[string]*writeStream{},
+	}
+
+	if config.StorageWriteAPI.StreamType == PendingWriteStream {
+		bqw.startCommitter()
+	}
+
+	return bqw, nil
+}
+
+//Insert appends the row into the table write stream
+func (bqw *BigQueryWriter) Insert(eventContext *EventContext) error {
+	return bqw.InsertBatch([]*EventContext{eventContext})[0]
+}
+
+//InsertBatch appends rows into the tables write streams with one AppendRows request per table
+//returns errors per event (in the same order). Rows rejected by the write stream have ErrWriteStreamRejectedRow error
+func (bqw *BigQueryWriter) InsertBatch(eventContexts []*EventContext) []error {
+	errs := make([]error, len(eventContexts))
+	tableNames, tableIndexes := groupByTable(eventContexts)
+	for _, tableName := range tableNames {
+		indexes := tableIndexes[tableName]
+		tableEvents := make([]*EventContext, len(indexes))
+		for j, i := range indexes {
+			tableEvents[j] = eventContexts[i]
+		}
+
+		err := bqw.insertTable(tableEvents)
+		for _, i := range indexes {
+			errs[i] = err
+		}
+	}
+
+	return errs
+}
+
+//Close finalizes (and commits pending) all streams and closes the connection
+func (bqw *BigQueryWriter) Close() error {
+	bqw.closeStreams()
+
+	return bqw.conn.Close()
+}
+
+//closeStreams marks the writer closed, finalizes (and commits pending) all streams
+func (bqw *BigQueryWriter) closeStreams() {
+	bqw.mutex.Lock()
+	defer bqw.mutex.Unlock()
+
+	bqw.closed = true
+
+	for _, stream := range bqw.streams {
+		stream.mutex.Lock()
+		if !stream.closed {
+			if err := bqw.closeStream(stream); err != nil {
+				logging.Errorf("Error closing BigQuery write stream [%s]: %v", stream.name, err)
+			}
+		}
+		stream.mutex.Unlock()
+	}
+}
+
+//insertTable appends rows of the same table with one request
+//the last event table is used as the stream schema because the table schema is only extended during the batch
+func (bqw *BigQueryWriter) insertTable(eventContexts []*EventContext) error {
+	table := eventContexts[len(eventContexts)-1].Table
+	schema, err := newWriteStreamSchema(table)
+	if err != nil {
+		return err
+	}
+
+	rows := make([][]byte, len(eventContexts))
+	for i, eventContext := range eventContexts {
+		rows[i], err = schema.marshal(ReformatBigQueryObject(table, eventContext.ProcessedEvent))
+		if err != nil {
+			return err
+		}
+	}
+
+	for {
+		stream, err := bqw.getStream(table.Name, schema)
+		if err != nil {
+			return err
+		}
+
+		stream.mutex.Lock()
+		//the stream has been committed after it was got
+		if stream.closed {
+			stream.mutex.Unlock()
+			continue
+		}
+
+		bqw.queryLogger.LogQuery(fmt.Sprintf("Appending %d rows to BigQuery write stream %s offset %d", len(rows), stream.name, stream.nextOffset))
+		err = bqw.appendRows(stream, rows)
+		if err == nil && bqw.config.StorageWriteAPI.StreamType == PendingWriteStream && stream.nextOffset >= int64(bqw.config.StorageWriteAPI.CommitRows) {
+			//rows have been appended and the events are consumed: the commit error can't be retried
+			if commitErr := bqw.closeStream(stream); commitErr != nil {
+				logging.SystemErrorf("Error committing BigQuery write stream [%s]: %v", stream.name, commitErr)
+			}
+		}
+		stream.mutex.Unlock()
+
+		return err
+	}
+}
+
+//getStream returns opened table stream or creates a new one if there is no stream or the table schema
+//(provided by TableHelper) was changed
+func (bqw *BigQueryWriter) getStream(tableName string, schema *writeStreamSchema) (*writeStream, error) {
+	bqw.mutex.Lock()
+	defer bqw.mutex.Unlock()
+
+	if bqw.closed {
+		return nil, errors.New("BigQuery writer is closed")
+	}
+
+	if stream, ok := bqw.streams[tableName]; ok {
+		stream.mutex.Lock()
+		if !stream.closed && stream.schema.signature != schema.signature {
+			//table schema has been changed: new stream will be created with the current table schema
+			if err := bqw.closeStream(stream); err != nil {
+				logging.Errorf("Error closing BigQuery write stream [%s] on schema change: %v", stream.name, err)
+			}
+		}
+		closed := stream.closed
+		stream.mutex.Unlock()
+
+		if !closed {
+			return stream, nil
+		}
+	}
+
+	stream, err := bqw.createStream(tableName, schema)
+	if err != nil {
+		return nil, err
+	}
+	bqw.streams[tableName] = stream
+
+	return stream, nil
+}
+
+//appendRows appends rows with the stream current offset (the stream mutex must be held)
+//the offset is advanced only if BigQuery has rows (appended or already exist)
+//if the append can't be confirmed, the stream is finalized and the row count shows if rows were written.
+//Rows which haven't been written have ErrWriteStreamNotAppended (or ErrWriteStreamRejectedRow) error
+func (bqw *BigQueryWriter) appendRows(stream *writeStream, rows [][]byte) error {
+	offset := stream.nextOffset
+
+	var appendErr error
+	for attempt := 0; attempt < writeStreamAppendRetries; attempt++ {
+		appendErr = bqw.sendRows(stream, offset, rows)
+		if appendErr == nil {
+			stream.nextOffset += int64(len(rows))
+			return nil
+		}
+
+		if appendErr == ErrWriteStreamRejectedRow {
+			break
+		}
+
+		//reopen connection and retry with the same offset
+		stream.appendRows.CloseSend()
+		if err := bqw.openAppendRows(stream); err != nil {
+			appendErr = err
+			break
+		}
+	}
+
+	//the stream can't be used anymore: finalize it and check if rows were written
+	stream.closed = true
+	stream.appendRows.CloseSend()
+	rowCount, err := bqw.finalizeStream(stream)
+	if err != nil {
+		//rows are considered as not written: they are appended again into a new stream
+		logging.SystemErrorf("%v", err)
+	} else {
+		if rowCount >= offset+int64(len(rows)) {
+			//rows were written, the last response was lost
+			stream.nextOffset = rowCount
+			appendErr = nil
+		}
+
+		if err := bqw.commitStream(stream); err != nil {
+			logging.SystemErrorf("%v", err)
+		}
+	}
+
+	if appendErr == nil || appendErr == ErrWriteStreamRejectedRow {
+		return appendErr
+	}
+
+	return fmt.Errorf("%w [%s]: %v", ErrWriteStreamNotAppended, stream.name, appendErr)
+}
+
+//sendRows sends AppendRows request and waits for the response
+func (bqw *BigQueryWriter) sendRows(stream *writeStream, offset int64, rows [][]byte) error {
+	protoData := &storagepb.AppendRowsRequest_ProtoData{
+		Rows: &storagepb.ProtoRows{SerializedRows: rows},
+	}
+	//writer schema must be sent in the first request of the connection
+	if !stream.schemaIsSent {
+		protoData.WriterSchema = &storagepb.ProtoSchema{ProtoDescriptor: stream.schema.descriptor}
+	}
+
+	request := &storagepb.AppendRowsRequest{
+		WriteStream: stream.name,
+		Offset:      wrapperspb.Int64(offset),
+		Rows:        &storagepb.AppendRowsRequest_ProtoRows{ProtoRows: protoData},
+	}
+	if err := stream.appendRows.Send(request); err != nil {
+		return err
+	}
+	stream.schemaIsSent = true
+
+	response, err := stream.appendRows.Recv()
+	if err != nil {
+		return err
+	}
+
+	if rowError := response.GetError(); rowError != nil {
+		switch codes.Code(rowError.GetCode()) {
+		case codes.AlreadyExists:
+			//rows with this offset have been already written (e.g. on retry after lost response or on restart)
+			return nil
+		case codes.InvalidArgument:
+			logging.Warnf("BigQuery write stream [%s] rejected %d rows: %s", stream.name, len(rows), rowError.GetMessage())
+			return ErrWriteStreamRejectedRow
+		default:
+			return fmt.Errorf("append error: %s", rowError.GetMessage())
+		}
+	}
+
+	return nil
+}
+
+//createStream creates a new committed or pending stream and opens AppendRows connection
+func (bqw *BigQueryWriter) createStream(tableName string, schema *writeStreamSchema) (*writeStream, error) {
+	streamType := storagepb.WriteStream_COMMITTED
+	if bqw.config.StorageWriteAPI.StreamType == PendingWriteStream {
+		streamType = storagepb.WriteStream_PENDING
+	}
+
+	parent := bqw.tablePath(tableName)
+	ctx := metadata.AppendToOutgoingContext(bqw.ctx, "x-goog-request-params", "parent="+url.QueryEscape(parent))
+	created, err := bqw.client.CreateWriteStream(ctx, &storagepb.CreateWriteStreamRequest{
+		Parent:      parent,
+		WriteStream: &storagepb.WriteStream{Type: streamType},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error creating BigQuery write stream for table [%s]: %v", tableName, err)
+	}
+
+	stream := &writeStream{
+		mutex:     &sync.Mutex{},
+		name:      created.GetName(),
+		tableName: tableName,
+		schema:    schema,
+		createdAt: time.Now(),
+	}
+	if err := bqw.openAppendRows(stream); err != nil {
+		return nil, err
+	}
+
+	logging.Debugf("BigQuery %s write stream [%s] has been created", bqw.config.StorageWriteAPI.StreamType, stream.name)
+
+	return stream, nil
+}
+
+//openAppendRows opens bidirectional AppendRows connection for the stream
+func (bqw *BigQueryWriter) openAppendRows(stream *writeStream) error {
+	ctx := metadata.AppendToOutgoingContext(bqw.ctx, "x-goog-request-params", "write_stream="+url.QueryEscape(stream.name))
+	appendRows, err := bqw.client.AppendRows(ctx)
+	if err != nil {
+		return fmt.Errorf("Error opening BigQuery write stream [%s] connection: %v", stream.name, err)
+	}
+
+	stream.appendRows = appendRows
+	stream.schemaIsSent = false
+
+	return nil
+}
+
+//closeStream closes the connection, finalizes and commits (if pending) the stream (the stream mutex must be held)
+func (bqw *BigQueryWriter) closeStream(stream *writeStream) error {
+	stream.closed = true
+	if stream.appendRows != nil {
+		stream.appendRows.CloseSend()
+	}
+
+	if _, err := bqw.finalizeStream(stream); err != nil {
+		return err
+	}
+
+	return bqw.commitStream(stream)
+}
+
+//finalizeStream finalizes the stream and returns number of written rows
+func (bqw *BigQueryWriter) finalizeStream(stream *writeStream) (int64, error) {
+	ctx := metadata.AppendToOutgoingContext(bqw.ctx, "x-goog-request-params", "name="+url.QueryEscape(stream.name))
+	response, err := bqw.client.FinalizeWriteStream(ctx, &storagepb.FinalizeWriteStreamRequest{Name: stream.name})
+	if err != nil {
+		return 0, fmt.Errorf("Error finalizing BigQuery write stream [%s]: %v", stream.name, err)
+	}
+
+	return response.GetRowCount(), nil
+}
+
+//commitStream commits finalized pending stream (committed streams don't require it)
+func (bqw *BigQueryWriter) commitStream(stream *writeStream) error {
+	if bqw.config.StorageWriteAPI.StreamType != PendingWriteStream || stream.nextOffset == 0 {
+		return nil
+	}
+
+	parent := bqw.tablePath(stream.tableName)
+	ctx := metadata.AppendToOutgoingContext(bqw.ctx, "x-goog-request-params", "parent="+url.QueryEscape(parent))
+	response, err := bqw.client.BatchCommitWriteStreams(ctx, &storagepb.BatchCommitWriteStreamsRequest{
+		Parent:       parent,
+		WriteStreams: []string{stream.name},
+	})
+	if err != nil {
+		return fmt.Errorf("Error committing BigQuery write stream [%s]: %v", stream.name, err)
+	}
+
+	if len(response.GetStreamErrors()) > 0 {
+		var messages []string
+		for _, streamErr := range response.GetStreamErrors() {
+			messages = append(messages, streamErr.GetErrorMessage())
+		}
+		return fmt.Errorf("Error committing BigQuery write stream [%s]: %s", stream.name, strings.Join(messages, "; "))
+	}
+
+	logging.Debugf("BigQuery pending write stream [%s] has been committed: %d rows", stream.name, stream.nextOffset)
+
+	return nil
+}
+
+//startCommitter runs goroutine which commits pending streams every commit_interval_sec
+func (bqw *BigQueryWriter) startCommitter() {
+	interval := time.Duration(bqw.config.StorageWriteAPI.CommitIntervalSec) * time.Second
+	safego.RunWithRestart(func() {
+		for {
+			if appstatus.Instance.Idle.Load() {
+				break
+			}
+
+			time.Sleep(interval)
+
+			bqw.mutex.Lock()
+			if bqw.closed {
+				bqw.mutex.Unlock()
+				break
+			}
+			streams := make([]*writeStream, 0, len(bqw.streams))
+			for _, stream := range bqw.streams {
+				streams = append(streams, stream)
+			}
+			bqw.mutex.Unlock()
+
+			for _, stream := range streams {
+				stream.mutex.Lock()
+				if !stream.closed && time.Since(stream.createdAt) >= interval {
+					if err := bqw.closeStream(stream); err != nil {
+						logging.Errorf("Error committing BigQuery write stream [%s]: %v", stream.name, err)
+					}
+				}
+				stream.mutex.Unlock()
+			}
+		}
+	})
+}
+
+func (bqw *BigQueryWriter) tablePath(tableName string) string {
+	return fmt.Sprintf("projects/%s/datasets/%s/tables/%s", bqw.config.Project, bqw.config.Dataset, tableName)
+}
+
+//newWriteStreamSchema builds proto2 message descriptor from the table columns (sorted by name)
+//BigQuery types are mapped according to Storage Write API rules: TIMESTAMP is int64 microseconds,
+//NUMERIC, DATE, JSON, etc are strings, RECORD is a nested message, REPEATED is a repeated field
+func newWriteStreamSchema(table *Table) (*writeStreamSchema, error) {
+	columns := make([]string, 0, len(table.Columns))
+	for name := range table.Columns {
+		columns = append(columns, name)
+	}
+	sort.Strings(columns)
+
+	descriptor := &descriptorpb.DescriptorProto{Name: proto.String(writeStreamMessageName)}
+	signature := make([]string, 0, len(columns))
+	for i, name := range columns {
+		column := table.Columns[name]
+		if column.Nested != nil {
+			descriptor.Field = append(descriptor.Field, writeStreamNestedField(descriptor, "."+writeStreamMessageName, name, int32(i+1), column.Nested))
+			signature = append(signature, name+":"+column.Nested.String())
+			continue
+		}
+
+		columnType := strings.ToUpper(column.Type)
+		descriptor.Field = append(descriptor.Field, &descriptorpb.FieldDescriptorProto{
+			Name:   proto.String(name),
+			Number: proto.Int32(int32(i + 1)),
+			Label:  descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			Type:   writeStreamFieldType(columnType).Enum(),
+		})
+		signature = append(signature, name+":"+columnType)
+	}
+
+	file, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:        proto.String(writeStreamMessageName + ".proto"),
+		Syntax:      proto.String("proto2"),
+		MessageType: []*descriptorpb.DescriptorProto{descriptor},
+	}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Error building BigQuery write stream schema for table [%s]: %v", table.Name, err)
+	}
+
+	return &writeStreamSchema{
+		descriptor:        descriptor,
+		messageDescriptor: file.Messages().Get(0),
+		signature:         strings.Join(signature, ","),
+	}, nil
+}
+
+//writeStreamNestedField returns descriptor of the nested type field: REPEATED field for arrays, nested message for records
+//nested messages are added into the parent message (parentPath is the parent message full name)
+func writeStreamNestedField(parent *descriptorpb.DescriptorProto, parentPath, name string, number int32, nestedType *typing.NestedType) *descriptorpb.FieldDescriptorProto {
+	field := &descriptorpb.FieldDescriptorProto{
+		Name:   proto.String(name),
+		Number: proto.Int32(number),
+		Label:  descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+	}
+	if nestedType.Type == typing.ARRAY {
+		field.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+		nestedType = nestedType.Elem
+	}
+
+	if nestedType.Type != typing.OBJECT {
+		field.Type = writeStreamFieldType(SchemaToBigQueryString[nestedType.Type]).Enum()
+		return field
+	}
+
+	fieldNames := make([]string, 0, len(nestedType.Fields))
+	for fieldName := range nestedType.Fields {
+		fieldNames = append(fieldNames, fieldName)
+	}
+	sort.Strings(fieldNames)
+
+	messageName := name + "_record"
+	messagePath := parentPath + "." + messageName
+	message := &descriptorpb.DescriptorProto{Name: proto.String(messageName)}
+	for i, fieldName := range fieldNames {
+		message.Field = append(message.Field, writeStreamNestedField(message, messagePath, fieldName, int32(i+1), nestedType.Fields[fieldName]))
+	}
+	parent.NestedType = append(parent.NestedType, message)
+
+	field.Type = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum()
+	field.TypeName = proto.String(messagePath)
+	return field
+}
+
+func writeStreamFieldType(columnType string) descriptorpb.FieldDescriptorProto_Type {
+	switch columnType {
+	case "INTEGER", "INT64", "TIMESTAMP":
+		return descriptorpb.FieldDescriptorProto_TYPE_INT64
+	case "FLOAT", "FLOAT64":
+		return descriptorpb.FieldDescriptorProto_TYPE_DOUBLE
+	case "BOOLEAN", "BOOL":
+		return descriptorpb.FieldDescriptorProto_TYPE_BOOL
+	default:
+		return descriptorpb.FieldDescriptorProto_TYPE_STRING
+	}
+}
+
+//marshal serializes the object as proto message, nil values are skipped
+func (wss *writeStreamSchema) marshal(object map[string]interface{}) ([]byte, error) {
+	message := dynamicpb.NewMessage(wss.messageDescriptor)
+	if err := setProtoFields(message, object); err != nil {
+		return nil, err
+	}
+
+	return proto.Marshal(message)
+}
+
+//setProtoFields sets the object values into the message fields (nested objects are nested messages)
+//returns error if a field doesn't exist in the message
+func setProtoFields(message *dynamicpb.Message, object map[string]interface{}) error {
+	fields := message.Descriptor().Fields()
+	for name, value := range object {
+		if value == nil {
+			continue
+		}
+
+		field := fields.ByName(protoreflect.Name(name))
+		if field == nil {
+			return fmt.Errorf("Field [%s] doesn't exist in BigQuery write stream schema", name)
+		}
+
+		if field.IsList() {
+			array, ok := value.([]interface{})
+			if !ok {
+				return fmt.Errorf("Error converting field [%s] value: %v (%T) isn't an array", name, value, value)
+			}
+
+			list := message.Mutable(field).List()
+			for _, element := range array {
+				if element == nil {
+					continue
+				}
+
+				protoValue, err := toProtoFieldValue(field, element)
+				if err != nil {
+					return fmt.Errorf("Error converting field [%s] element: %v", name, err)
+				}
+				list.Append(protoValue)
+			}
+			continue
+		}
+
+		protoValue, err := toProtoFieldValue(field, value)
+		if err != nil {
+			return fmt.Errorf("Error converting field [%s] value: %v", name, err)
+		}
+		message.Set(field, protoValue)
+	}
+
+	return nil
+}
+
+//toProtoFieldValue converts value into proto value of the field (objects are converted into nested messages)
+func toProtoFieldValue(field protoreflect.FieldDescriptor, value interface{}) (protoreflect.Value, error) {
+	if field.Kind() != protoreflect.MessageKind {
+		return toProtoValue(field.Kind(), value)
+	}
+
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		return protoreflect.Value{}, fmt.Errorf("%v (%T) isn't an object", value, value)
+	}
+
+	message := dynamicpb.NewMessage(field.Message())
+	if err := setProtoFields(message, object); err != nil {
+		return protoreflect.Value{}, err
+	}
+
+	return protoreflect.ValueOfMessage(message), nil
+}
+
+//toProtoValue converts value into proto value of the kind
+func toProtoValue(kind protoreflect.Kind, value interface{}) (protoreflect.Value, error) {
+	switch kind {
+	case protoreflect.Int64Kind:
+		switch v := value.(type) {
+		case time.Time:
+			return protoreflect.ValueOfInt64(v.UnixNano() / int64(time.Microsecond)), nil
+		case int:
+			return protoreflect.ValueOfInt64(int64(v)), nil
+		case int32:
+			return protoreflect.ValueOfInt64(int64(v)), nil
+		case int64:
+			return protoreflect.ValueOfInt64(v), nil
+		case float64:
+			return protoreflect.ValueOfInt64(int64(math.Round(v))), nil
+		case bool:
+			if v {
+				return protoreflect.ValueOfInt64(1), nil
+			}
+			return protoreflect.ValueOfInt64(0), nil
+		case string:
+			intValue, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return protoreflect.Value{}, err
+			}
+			return protoreflect.ValueOfInt64(intValue), nil
+		}
+	case protoreflect.DoubleKind:
+		switch v := value.(type) {
+		case float64:
+			return protoreflect.ValueOfFloat64(v), nil
+		case float32:
+			return protoreflect.ValueOfFloat64(float64(v)), nil
+		case int:
+			return protoreflect.ValueOfFloat64(float64(v)), nil
+		case int64:
+			return protoreflect.ValueOfFloat64(float64(v)), nil
+		case string:
+			floatValue, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return protoreflect.Value{}, err
+			}
+			return protoreflect.ValueOfFloat64(floatValue), nil
+		}
+	case protoreflect.BoolKind:
+		switch v := value.(type) {
+		case bool:
+			return protoreflect.ValueOfBool(v), nil
+		case string:
+			boolValue, err := strconv.ParseBool(v)
+			if err != nil {
+				return protoreflect.Value{}, err
+			}
+			return protoreflect.ValueOfBool(boolValue), nil
+		}
+	case protoreflect.StringKind:
+		switch v := value.(type) {
+		case string:
+			return protoreflect.ValueOfString(v), nil
+		case time.Time:
+			return protoreflect.ValueOfString(timestamp.ToISOFormat(v)), nil
+		default:
+			return protoreflect.ValueOfString(fmt.Sprint(nestedToJSON(v))), nil
+		}
+	}
+
+	return protoreflect.Value{}, fmt.Errorf("unsupported value %v (%T) for %s field", value, value, kind)
+}
@@ -0,0 +1,322 @@
+package adapters
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/typing"
+	"github.com/stretchr/testify/require"
+	storagepb "google.golang.org/genproto/googleapis/cloud/bigquery/storage/v1beta2"
+	"google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+//writeClientMock keeps appended rows per stream in memory like BigQuery Storage Write API
+type writeClientMock struct {
+	storagepb.BigQueryWriteClient
+
+	mutex     sync.Mutex
+	requests  []*storagepb.AppendRowsRequest
+	rows      map[string]int64
+	finalized map[string]bool
+	committed []string
+	created   int
+	//failedAppends is a number of the next appends which fail without writing rows
+	failedAppends int
+	//lostResponses is a number of the next appends which responses are lost (rows are written)
+	lostResponses int
+}
+
+func newWriteClientMock() *writeClientMock {
+	return &writeClientMock{rows: map[string]int64{}, finalized: map[string]bool{}}
+}
+
+func (wcm *writeClientMock) CreateWriteStream(ctx context.Context, in *storagepb.CreateWriteStreamRequest, opts ...grpc.CallOption) (*storagepb.WriteStream, error) {
+	wcm.mutex.Lock()
+	defer wcm.mutex.Unlock()
+	wcm.created++
+	return &storagepb.WriteStream{Name: in.Parent + "/streams/" + string(rune('a'+wcm.created-1))}, nil
+}
+
+func (wcm *writeClientMock) AppendRows(ctx context.Context, opts ...grpc.CallOption) (storagepb.BigQueryWrite_AppendRowsClient, error) {
+	return &appendRowsClientMock{client: wcm}, nil
+}
+
+func (wcm *writeClientMock) FinalizeWriteStream(ctx context.Context, in *storagepb.FinalizeWriteStreamRequest, opts ...grpc.CallOption) (*storagepb.FinalizeWriteStreamResponse, error) {
+	wcm.mutex.Lock()
+	defer wcm.mutex.Unlock()
+	wcm.finalized[in.Name] = true
+	return &storagepb.FinalizeWriteStreamResponse{RowCount: wcm.rows[in.Name]}, nil
+}
+
+func (wcm *writeClientMock) BatchCommitWriteStreams(ctx context.Context, in *storagepb.BatchCommitWriteStreamsRequest, opts ...grpc.CallOption) (*storagepb.BatchCommitWriteStreamsResponse, error) {
+	wcm.mutex.Lock()
+	defer wcm.mutex.Unlock()
+	wcm.committed = append(wcm.committed, in.WriteStreams...)
+	return &storagepb.BatchCommitWriteStreamsResponse{}, nil
+}
+
+//append returns ALREADY_EXISTS for written offsets and error for finalized streams
+//failed appends and lost responses return connection error
+func (wcm *writeClientMock) append(request *storagepb.AppendRowsRequest) (*storagepb.AppendRowsResponse, error) {
+	wcm.mutex.Lock()
+	defer wcm.mutex.Unlock()
+	wcm.requests = append(wcm.requests, request)
+
+	if wcm.failedAppends > 0 {
+		wcm.failedAppends--
+		return nil, io.EOF
+	}
+
+	response := &storagepb.AppendRowsResponse{}
+	if wcm.finalized[request.WriteStream] {
+		response.Response = &storagepb.AppendRowsResponse_Error{Error: &status.Status{Code: int32(codes.FailedPrecondition), Message: "stream is finalized"}}
+	} else if request.Offset.GetValue() < wcm.rows[request.WriteStream] {
+		response.Response = &storagepb.AppendRowsResponse_Error{Error: &status.Status{Code: int32(codes.AlreadyExists)}}
+	} else {
+		wcm.rows[request.WriteStream] += int64(len(request.GetProtoRows().GetRows().GetSerializedRows()))
+	}
+
+	if wcm.lostResponses > 0 {
+		wcm.lostResponses--
+		return nil, io.EOF
+	}
+
+	return response, nil
+}
+
+type appendRowsClientMock struct {
+	grpc.ClientStream
+
+	client   *writeClientMock
+	response *storagepb.AppendRowsResponse
+	err      error
+}
+
+func (arcm *appendRowsClientMock) Send(request *storagepb.AppendRowsRequest) error {
+	arcm.response, arcm.err = arcm.client.append(request)
+	return nil
+}
+
+func (arcm *appendRowsClientMock) Recv() (*storagepb.AppendRowsResponse, error) {
+	return arcm.response, arcm.err
+}
+
+func (arcm *appendRowsClientMock) CloseSend() error { return nil }
+
+func newTestBigQueryWriter(client storagepb.BigQueryWriteClient, streamType string) *BigQueryWriter {
+	return &BigQueryWriter{
+		ctx:         context.Background(),
+		config:      &GoogleConfig{Project: "project", Dataset: "dataset", StorageWriteAPI: &StorageWriteAPIConfig{StreamType: streamType, CommitRows: 100}},
+		client:      client,
+		queryLogger: &logging.QueryLogger{},
+		mutex:       &sync.Mutex{},
+		streams:     map[string]*writeStream{},
+	}
+}
+
+func TestWriteStreamSchema(t *testing.T) {
+	table := &Table{Name: "events", Columns: Columns{
+		"id":         typing.SQLColumn{Type: "STRING"},
+		"amount":     typing.SQLColumn{Type: "FLOAT"},
+		"count":      typing.SQLColumn{Type: "INTEGER"},
+		"is_new":     typing.SQLColumn{Type: "BOOLEAN"},
+		"_timestamp": typing.SQLColumn{Type: "TIMESTAMP"},
+		"payload":    typing.SQLColumn{Type: "JSON"},
+	}}
+
+	schema, err := newWriteStreamSchema(table)
+	require.NoError(t, err)
+	require.Equal(t, "_timestamp:TIMESTAMP,amount:FLOAT,count:INTEGER,id:STRING,is_new:BOOLEAN,payload:JSON", schema.signature)
+
+	changed, err := newWriteStreamSchema(&Table{Name: "events", Columns: Columns{"id": typing.SQLColumn{Type: "STRING"}}})
+	require.NoError(t, err)
+	require.NotEqual(t, schema.signature, changed.signature)
+
+	ts := time.Date(2021, 9, 1, 10, 20, 30, 123456000, time.UTC)
+	row, err := schema.marshal(map[string]interface{}{
+		"id":         "abc",
+		"amount":     float64(10),
+		"count":      float64(3),
+		"is_new":     true,
+		"_timestamp": ts,
+		"payload":    map[string]interface{}{"key": "value"},
+		"nullable":   nil,
+	})
+	require.NoError(t, err)
+
+	message := dynamicpb.NewMessage(schema.messageDescriptor)
+	require.NoError(t, proto.Unmarshal(row, message))
+
+	fields := schema.messageDescriptor.Fields()
+	require.Equal(t, "abc", message.Get(fields.ByName("id")).String())
+	require.Equal(t, float64(10), message.Get(fields.ByName("amount")).Float())
+	require.Equal(t, int64(3), message.Get(fields.ByName("count")).Int())
+	require.True(t, message.Get(fields.ByName("is_new")).Bool())
+	require.Equal(t, ts.UnixNano()/int64(time.Microsecond), message.Get(fields.ByName("_timestamp")).Int())
+	require.Equal(t, `{"key":"value"}`, message.Get(fields.ByName("payload")).String())
+
+	_, err = schema.marshal(map[string]interface{}{"unknown": "value"})
+	require.Error(t, err)
+}
+
+func TestWriteStreamNestedSchema(t *testing.T) {
+	user := BigQueryNestedColumn(&typing.NestedType{Type: typing.OBJECT, Fields: map[string]*typing.NestedType{
+		"email":   {Type: typing.STRING},
+		"address": {Type: typing.OBJECT, Fields: map[string]*typing.NestedType{"city": {Type: typing.STRING}}},
+	}})
+	items := BigQueryNestedColumn(&typing.NestedType{Type: typing.ARRAY, Elem: &typing.NestedType{Type: typing.OBJECT, Fields: map[string]*typing.NestedType{
+		"sku":   {Type: typing.STRING},
+		"price": {Type: typing.FLOAT64},
+	}}})
+	tags := BigQueryNestedColumn(&typing.NestedType{Type: typing.ARRAY, Elem: &typing.NestedType{Type: typing.INT64}})
+	table := &Table{Name: "events", Columns: Columns{"id": typing.SQLColumn{Type: "STRING"}, "user": user, "items": items, "tags": tags}}
+
+	schema, err := newWriteStreamSchema(table)
+	require.NoError(t, err)
+	require.Equal(t, "id:STRING,items:ARRAY<STRUCT<price FLOAT64, sku STRING>>,tags:ARRAY<INT64>,user:STRUCT<address STRUCT<city STRING>, email STRING>", schema.signature)
+
+	row, err := schema.marshal(ReformatBigQueryObject(table, map[string]interface{}{
+		"id":    "abc",
+		"user":  map[string]interface{}{"email": "a@b.com", "address": map[string]interface{}{"city": "Berlin"}},
+		"items": []interface{}{map[string]interface{}{"sku": "s1", "price": json.Number("1.5")}, nil, map[string]interface{}{"sku": "s2"}},
+		"tags":  []interface{}{json.Number("1"), json.Number("2")},
+	}))
+	require.NoError(t, err)
+
+	message := dynamicpb.NewMessage(schema.messageDescriptor)
+	require.NoError(t, proto.Unmarshal(row, message))
+
+	fields := schema.messageDescriptor.Fields()
+	userMessage := message.Get(fields.ByName("user")).Message()
+	require.Equal(t, "a@b.com", userMessage.Get(userMessage.Descriptor().Fields().ByName("email")).String())
+	addressMessage := userMessage.Get(userMessage.Descriptor().Fields().ByName("address")).Message()
+	require.Equal(t, "Berlin", addressMessage.Get(addressMessage.Descriptor().Fields().ByName("city")).String())
+
+	itemsList := message.Get(fields.ByName("items")).List()
+	require.Equal(t, 2, itemsList.Len())
+	item := itemsList.Get(0).Message()
+	require.Equal(t, "s1", item.Get(item.Descriptor().Fields().ByName("sku")).String())
+	require.Equal(t, 1.5, item.Get(item.Descriptor().Fields().ByName("price")).Float())
+
+	tagsList := message.Get(fields.ByName("tags")).List()
+	require.Equal(t, 2, tagsList.Len())
+	require.Equal(t, int64(2), tagsList.Get(1).Int())
+
+	_, err = schema.marshal(map[string]interface{}{"user": "not an object"})
+	require.Error(t, err)
+}
+
+func TestBigQueryWriterInsertBatch(t *testing.T) {
+	client := newWriteClientMock()
+	writer := newTestBigQueryWriter(client, PendingWriteStream)
+
+	events := &Table{Name: "events", Columns: Columns{"id": typing.SQLColumn{Type: "STRING"}}}
+	users := &Table{Name: "users", Columns: Columns{"id": typing.SQLColumn{Type: "STRING"}}}
+	errs := writer.InsertBatch([]*EventContext{
+		{Table: events, ProcessedEvent: map[string]interface{}{"id": "1"}},
+		{Table: users, ProcessedEvent: map[string]interface{}{"id": "2"}},
+		{Table: events, ProcessedEvent: map[string]interface{}{"id": "3"}},
+	})
+	require.Equal(t, []error{nil, nil, nil}, errs)
+
+	require.Len(t, client.requests, 2, "one append request per table")
+	require.Len(t, client.requests[0].GetProtoRows().GetRows().GetSerializedRows(), 2)
+	require.Equal(t, int64(0), client.requests[0].Offset.GetValue())
+	require.Len(t, client.requests[1].GetProtoRows().GetRows().GetSerializedRows(), 1)
+
+	errs = writer.InsertBatch([]*EventContext{{Table: events, ProcessedEvent: map[string]interface{}{"id": "4"}}})
+	require.Equal(t, []error{nil}, errs)
+	require.Equal(t, int64(2), client.requests[2].Offset.GetValue(), "offset is advanced by the batch size")
+
+	writer.closeStreams()
+	require.ElementsMatch(t, []string{
+		"projects/project/datasets/dataset/tables/events/streams/a",
+		"projects/project/datasets/dataset/tables/users/streams/b",
+	}, client.committed)
+}
+
+func TestBigQueryWriterAppendRetries(t *testing.T) {
+	tests := []struct {
+		name             string
+		failedAppends    int
+		lostResponses    int
+		expectedErr      error
+		expectedRows     int64
+		expectedRequests int
+		expectedClosed   bool
+	}{
+		{
+			name:             "lost response is deduplicated",
+			lostResponses:    1,
+			expectedRows:     1,
+			expectedRequests: 2,
+		},
+		{
+			name:             "failed append is retried",
+			failedAppends:    1,
+			expectedRows:     1,
+			expectedRequests: 2,
+		},
+		{
+			name:             "not confirmed rows are written",
+			lostResponses:    writeStreamAppendRetries,
+			expectedRows:     1,
+			expectedRequests: writeStreamAppendRetries,
+			expectedClosed:   true,
+		},
+		{
+			name:             "not written rows",
+			failedAppends:    writeStreamAppendRetries,
+			expectedErr:      ErrWriteStreamNotAppended,
+			expectedRequests: writeStreamAppendRetries,
+			expectedClosed:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newWriteClientMock()
+			client.failedAppends = tt.failedAppends
+			client.lostResponses = tt.lostResponses
+			writer := newTestBigQueryWriter(client, PendingWriteStream)
+
+			events := &Table{Name: "events", Columns: Columns{"id": typing.SQLColumn{Type: "STRING"}}}
+			err := writer.Insert(&EventContext{Table: events, ProcessedEvent: map[string]interface{}{"id": "1"}})
+			if tt.expectedErr != nil {
+				require.True(t, errors.Is(err, tt.expectedErr), "unexpected error: %v", err)
+			} else {
+				require.NoError(t, err)
+			}
+
+			stream := writer.streams["events"]
+			require.Equal(t, tt.expectedRows, client.rows[stream.name])
+			require.Len(t, client.requests, tt.expectedRequests)
+			for _, request := range client.requests {
+				require.Equal(t, int64(0), request.Offset.GetValue(), "retries have the same offset")
+			}
+			require.Equal(t, tt.expectedClosed, stream.closed)
+			if tt.expectedClosed && tt.expectedRows > 0 {
+				require.Equal(t, []string{stream.name}, client.committed)
+			}
+
+			//the next rows are appended into the opened or a new stream
+			require.NoError(t, writer.Insert(&EventContext{Table: events, ProcessedEvent: map[string]interface{}{"id": "2"}}))
+			next := writer.streams["events"]
+			if tt.expectedClosed {
+				require.NotEqual(t, stream.name, next.name)
+				require.Equal(t, int64(1), next.nextOffset)
+			} else {
+				require.Equal(t, tt.expectedRows+1, next.nextOffset)
+			}
+		})
+	}
+}
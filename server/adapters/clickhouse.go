@@ -5,23 +5,33 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/jitsucom/jitsu/server/logging"
 	"github.com/jitsucom/jitsu/server/typing"
 	"github.com/mailru/go-clickhouse"
 	"io/ioutil"
+	"regexp"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 )
 
 const (
 	tableSchemaCHQuery        = `SELECT name, type FROM system.columns WHERE database = ? and table = ?`
+	tableEngineCHQuery        = `SELECT engine_full FROM system.tables WHERE database = ? and name = ?`
+	partitionedTablesCHQuery  = `SELECT name, partition_key FROM system.tables WHERE database = ? and partition_key != '' and engine NOT IN ('Distributed', 'View', 'MaterializedView')`
+	partitionsCHQuery         = `SELECT DISTINCT partition_id FROM system.parts WHERE database = ? and table = ? and active`
+	tableNamesCHQuery         = `SELECT name FROM system.tables WHERE database = ? and engine NOT IN ('Distributed', 'View', 'MaterializedView')`
+	dropPartitionCHTemplate   = `ALTER TABLE "%s"."%s" %s DROP PARTITION ID '%s'`
 	createCHDBTemplate        = `CREATE DATABASE IF NOT EXISTS "%s" %s`
 	addColumnCHTemplate       = `ALTER TABLE "%s"."%s" %s ADD COLUMN %s`
 	insertCHTemplate          = `INSERT INTO "%s"."%s" (%s) VALUES %s`
+	insertCHAsyncTemplate     = `INSERT INTO "%s"."%s" (%s) SETTINGS async_insert=1, wait_for_async_insert=1 VALUES %s`
 	deleteQueryChTemplate     = `ALTER TABLE %s.%s DELETE WHERE %s`
+	countQueryCHTemplate      = `SELECT count(*) FROM "%s"."%s" WHERE %s`
 	dropTableCHTemplate       = `DROP TABLE "%s"."%s" %s`
 	onClusterCHClauseTemplate = ` ON CLUSTER "%s" `
 	columnCHNullableTemplate  = ` Nullable(%s) `
@@ -33,11 +43,15 @@ const (
 	truncateDistributedTableCHTemplate = `TRUNCATE TABLE IF EXISTS "%s"."dist_%s" %s`
 
 	defaultPartition  = `PARTITION BY (toYYYYMM(_timestamp))`
+	partitionTemplate = `PARTITION BY (%s)`
 	defaultOrderBy    = `ORDER BY (eventn_ctx_event_id)`
 	defaultPrimaryKey = ``
 )
 
 var (
+	//replacingEngineRegexp matches ReplacingMergeTree([ver]) and ReplicatedReplacingMergeTree('path', 'replica'[, ver]) engines
+	replacingEngineRegexp = regexp.MustCompile(`^(?:Replicated)?ReplacingMergeTree\((.*?)\)`)
+
 	SchemaToClickhouse = map[typing.DataType]string{
 		typing.STRING:    "String",
 		typing.INT64:     "Int64",
@@ -45,6 +59,8 @@ var (
 		typing.TIMESTAMP: "DateTime",
 		typing.BOOL:      "UInt8",
 		typing.UNKNOWN:   "String",
+		typing.OBJECT:    "JSON",
+		typing.ARRAY:     "Array(String)",
 	}
 
 	defaultValues = map[string]interface{}{
@@ -83,6 +99,15 @@ var (
 		"lowcardinality(uint256)":  0,
 		"lowcardinality(string)":   "",
 		"uuid":                     "00000000-0000-0000-0000-000000000000",
+		"json":                     "{}",
+		"object('json')":           "{}",
+		"array(string)":            clickhouse.Array([]string{}),
+		"array(int64)":             clickhouse.Array([]int64{}),
+		"array(float64)":           clickhouse.Array([]float64{}),
+		"array(uint8)":             clickhouse.Array([]uint8{}),
+		"array(datetime)":          clickhouse.Array([]time.Time{}),
+		"array(json)":              clickhouse.Array([]string{}),
+		"array(object('json'))":    clickhouse.Array([]string{}),
 	}
 )
 
@@ -93,6 +118,8 @@ type ClickHouseConfig struct {
 	TLS      map[string]string `mapstructure:"tls" json:"tls,omitempty" yaml:"tls,omitempty"`
 	Cluster  string            `mapstructure:"cluster" json:"cluster,omitempty" yaml:"cluster,omitempty"`
 	Engine   *EngineConfig     `mapstructure:"engine" json:"engine,omitempty" yaml:"engine,omitempty"`
+	//AsyncInsert enables ClickHouse server side batching (async_insert setting) for stream mode inserts
+	AsyncInsert bool `mapstructure:"async_insert" json:"async_insert,omitempty" yaml:"async_insert,omitempty"`
 }
 
 //EngineConfig dto for deserialized clickhouse engine config
@@ -152,18 +179,29 @@ type TableStatementFactory struct {
 	primaryKeyClause string
 
 	engineStatementFormat bool
+
+	//partitioning can be nil
+	partitioning *PartitioningConfig
 }
 
-func NewTableStatementFactory(config *ClickHouseConfig) (*TableStatementFactory, error) {
+//NewTableStatementFactory returns TableStatementFactory
+//partitioning can be nil. If provided it overrides default partition clause and can't be used with engine partition_fields or raw_statement
+func NewTableStatementFactory(config *ClickHouseConfig, partitioning *PartitioningConfig) (*TableStatementFactory, error) {
 	if config == nil {
 		return nil, errors.New("Clickhouse config can't be nil")
 	}
+	if partitioning != nil && config.Engine != nil && (config.Engine.RawStatement != "" || len(config.Engine.PartitionFields) > 0) {
+		return nil, errors.New("data_layout.partitioning can't be used with ClickHouse engine raw_statement or partition_fields")
+	}
 	var onClusterClause string
 	if config.Cluster != "" {
 		onClusterClause = fmt.Sprintf(onClusterCHClauseTemplate, config.Cluster)
 	}
 
 	partitionClause := defaultPartition
+	if partitioning != nil {
+		partitionClause = fmt.Sprintf(partitionTemplate, clickHousePartitionExpression(partitioning))
+	}
 	orderByClause := defaultOrderBy
 	primaryKeyClause := defaultPrimaryKey
 	if config.Engine != nil {
@@ -206,9 +244,19 @@ func NewTableStatementFactory(config *ClickHouseConfig) (*TableStatementFactory,
 		orderByClause:         orderByClause,
 		primaryKeyClause:      primaryKeyClause,
 		engineStatementFormat: engineStatementFormat,
+		partitioning:          partitioning,
 	}, nil
 }
 
+//clickHousePartitionExpression returns toYYYYMMDD(field) or toYYYYMM(field) depends on partitioning granularity
+func clickHousePartitionExpression(partitioning *PartitioningConfig) string {
+	if partitioning.Granularity == PartitionByDay {
+		return fmt.Sprintf("toYYYYMMDD(%s)", partitioning.Field)
+	}
+
+	return fmt.Sprintf("toYYYYMM(%s)", partitioning.Field)
+}
+
 //CreateTableStatement return clickhouse DDL for creating table statement
 func (tsf TableStatementFactory) CreateTableStatement(tableName, columnsClause string) string {
 	engineStatement := tsf.engineStatement
@@ -219,6 +267,12 @@ func (tsf TableStatementFactory) CreateTableStatement(tableName, columnsClause s
 		tsf.partitionClause, tsf.orderByClause, tsf.primaryKeyClause)
 }
 
+//replacingEngine is a table engine description: if the table has ReplacingMergeTree engine and its version column
+type replacingEngine struct {
+	replacing     bool
+	versionColumn string
+}
+
 //ClickHouse is adapter for creating,patching (schema or table), inserting data to clickhouse
 type ClickHouse struct {
 	ctx                   context.Context
@@ -229,12 +283,17 @@ type ClickHouse struct {
 	nullableFields        map[string]bool
 	queryLogger           *logging.QueryLogger
 	sqlTypes              typing.SQLTypes
+	asyncInsert           bool
+
+	enginesMutex *sync.RWMutex
+	//engines is a cache of tables engines: table name - replacingEngine
+	engines map[string]*replacingEngine
 }
 
 //NewClickHouse returns configured ClickHouse adapter instance
 func NewClickHouse(ctx context.Context, connectionString, database, cluster string, tlsConfig map[string]string,
 	tableStatementFactory *TableStatementFactory, nullableFields map[string]bool,
-	queryLogger *logging.QueryLogger, sqlTypes typing.SQLTypes) (*ClickHouse, error) {
+	queryLogger *logging.QueryLogger, sqlTypes typing.SQLTypes, asyncInsert bool) (*ClickHouse, error) {
 	connectionString = strings.TrimSpace(connectionString)
 	//configure tls
 	if strings.Contains(connectionString, "https://") && tlsConfig != nil {
@@ -281,6 +340,9 @@ func NewClickHouse(ctx context.Context, connectionString, database, cluster stri
 		nullableFields:        nullableFields,
 		queryLogger:           queryLogger,
 		sqlTypes:              reformatMappings(sqlTypes, SchemaToClickhouse),
+		asyncInsert:           asyncInsert,
+		enginesMutex:          &sync.RWMutex{},
+		engines:               map[string]*replacingEngine{},
 	}, nil
 }
 
@@ -366,6 +428,93 @@ func (ch *ClickHouse) GetTableSchema(tableName string) (*Table, error) {
 	return table, nil
 }
 
+//MaintainPartitions drops expired partitions of tables created by Jitsu
+//ClickHouse creates partitions on insert, so upcoming partitions aren't created
+//only tables of the database with partition key expression from partitioning configuration are processed
+func (ch *ClickHouse) MaintainPartitions() {
+	partitioning := ch.tableStatementFactory.partitioning
+	if partitioning == nil || partitioning.Retention == 0 {
+		return
+	}
+
+	tableNames, err := ch.getPartitionedTables(clickHousePartitionExpression(partitioning))
+	if err != nil {
+		logging.Errorf("Error getting partitioned tables: %v", err)
+		return
+	}
+
+	now := time.Now()
+	for _, tableName := range tableNames {
+		partitions, err := ch.getPartitions(tableName)
+		if err != nil {
+			logging.Errorf("Error getting [%s] table partitions: %v", tableName, err)
+			continue
+		}
+
+		for _, partitionID := range partitions {
+			if !partitioning.IsExpired(partitionID, now) {
+				continue
+			}
+
+			statement := fmt.Sprintf(dropPartitionCHTemplate, ch.database, tableName, ch.getOnClusterClause(), partitionID)
+			ch.queryLogger.LogDDL(statement)
+			if _, err := ch.dataSource.ExecContext(ch.ctx, statement); err != nil {
+				logging.Errorf("Error dropping [%s] table partition with statement [%s]: %v", tableName, statement, err)
+			}
+		}
+	}
+}
+
+//getPartitionedTables returns names of the database tables with the partition key expression
+func (ch *ClickHouse) getPartitionedTables(partitionKeyExpression string) ([]string, error) {
+	rows, err := ch.dataSource.QueryContext(ch.ctx, partitionedTablesCHQuery, ch.database)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	var tableNames []string
+	for rows.Next() {
+		var tableName, partitionKey string
+		if err := rows.Scan(&tableName, &partitionKey); err != nil {
+			return nil, fmt.Errorf("Error scanning result: %v", err)
+		}
+		if strings.Trim(strings.ReplaceAll(partitionKey, " ", ""), "()") == strings.Trim(partitionKeyExpression, "()") {
+			tableNames = append(tableNames, tableName)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("Last rows.Err: %v", err)
+	}
+
+	return tableNames, nil
+}
+
+//getPartitions returns active partitions ids of the table
+func (ch *ClickHouse) getPartitions(tableName string) ([]string, error) {
+	rows, err := ch.dataSource.QueryContext(ch.ctx, partitionsCHQuery, ch.database, tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	var partitions []string
+	for rows.Next() {
+		var partitionID string
+		if err := rows.Scan(&partitionID); err != nil {
+			return nil, fmt.Errorf("Error scanning result: %v", err)
+		}
+		partitions = append(partitions, partitionID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("Last rows.Err: %v", err)
+	}
+
+	return partitions, nil
+}
+
 //PatchTableSchema add new columns(from provided Table) to existing table
 //drop and create distributed table
 func (ch *ClickHouse) PatchTableSchema(patchSchema *Table) error {
@@ -396,13 +545,14 @@ func (ch *ClickHouse) PatchTableSchema(patchSchema *Table) error {
 }
 
 //Insert provided object in ClickHouse in stream mode
+//uses async_insert setting if configured: ClickHouse server collects rows from many inserts into one part
 func (ch *ClickHouse) Insert(eventContext *EventContext) error {
 	var headerWithQuotes, placeholders []string
 	var values []interface{}
 	for name, value := range eventContext.ProcessedEvent {
 		headerWithQuotes = append(headerWithQuotes, fmt.Sprintf(`"%s"`, name))
 		placeholders = append(placeholders, ch.getPlaceholder(name, eventContext.Table.Columns[name]))
-		values = append(values, value)
+		values = append(values, ch.reformatValue(value, eventContext.Table.Columns[name].Type))
 	}
 
 	wrappedTx, err := ch.OpenTx()
@@ -410,7 +560,12 @@ func (ch *ClickHouse) Insert(eventContext *EventContext) error {
 		return err
 	}
 
-	query := fmt.Sprintf(insertCHTemplate, ch.database, eventContext.Table.Name, strings.Join(headerWithQuotes, ", "), "("+strings.Join(placeholders, ", ")+")")
+	insertTemplate := insertCHTemplate
+	if ch.asyncInsert {
+		insertTemplate = insertCHAsyncTemplate
+	}
+
+	query := fmt.Sprintf(insertTemplate, ch.database, eventContext.Table.Name, strings.Join(headerWithQuotes, ", "), "("+strings.Join(placeholders, ", ")+")")
 	ch.queryLogger.LogQueryWithValues(query, values)
 
 	_, err = wrappedTx.tx.ExecContext(ch.ctx, query, values...)
@@ -422,7 +577,20 @@ func (ch *ClickHouse) Insert(eventContext *EventContext) error {
 	return wrappedTx.DirectCommit()
 }
 
+//BulkUpdate deletes objects with deleteConditions and inserts objects in one prepared statement
+//if the table has ReplacingMergeTree engine, ALTER TABLE ... DELETE mutation isn't used:
+//objects are inserted with the current version (ver column) and old rows are replaced on merges (or with FINAL)
 func (ch *ClickHouse) BulkUpdate(table *Table, objects []map[string]interface{}, deleteConditions *DeleteConditions) error {
+	engine, err := ch.getReplacingEngine(table.Name)
+	if err != nil {
+		return err
+	}
+
+	if engine.replacing {
+		ch.fillVersionColumn(table, engine.versionColumn, objects)
+		return ch.BulkInsert(table, objects)
+	}
+
 	wrappedTx, err := ch.OpenTx()
 	if err != nil {
 		return err
@@ -443,6 +611,64 @@ func (ch *ClickHouse) BulkUpdate(table *Table, objects []map[string]interface{},
 	return wrappedTx.DirectCommit()
 }
 
+//Delete deletes objects with deleteConditions using ALTER TABLE ... DELETE mutation
+func (ch *ClickHouse) Delete(table *Table, deleteConditions *DeleteConditions) error {
+	wrappedTx, err := ch.OpenTx()
+	if err != nil {
+		return err
+	}
+
+	if err := ch.deleteInTransaction(wrappedTx, table, deleteConditions); err != nil {
+		wrappedTx.Rollback()
+		return err
+	}
+
+	return wrappedTx.DirectCommit()
+}
+
+//GetTableNames returns names of all local tables in the database (distributed tables and views are omitted)
+func (ch *ClickHouse) GetTableNames() ([]string, error) {
+	sqlParams := SqlParams{
+		dataSource:  ch.dataSource,
+		queryLogger: ch.queryLogger,
+		ctx:         ch.ctx,
+	}
+	return sqlParams.commonGetTableNames(tableNamesCHQuery, ch.database)
+}
+
+//Select executes SELECT query and passes result rows to consume
+//queries are sent with GET HTTP requests which are executed by ClickHouse in readonly mode
+func (ch *ClickHouse) Select(query string, consume func(row map[string]interface{}) error) error {
+	sqlParams := SqlParams{
+		dataSource:  ch.dataSource,
+		queryLogger: ch.queryLogger,
+		ctx:         ch.ctx,
+	}
+	return sqlParams.commonSelect(query, false, consume)
+}
+
+//DeleteOlderThan counts rows which field value is before the time and deletes them with ALTER TABLE ... DELETE mutation
+//(if dryRun is false). Returns the count because mutations are executed asynchronously
+func (ch *ClickHouse) DeleteOlderThan(table *Table, field string, before time.Time, dryRun bool) (int64, error) {
+	sqlParams := SqlParams{
+		dataSource:  ch.dataSource,
+		queryLogger: ch.queryLogger,
+		ctx:         ch.ctx,
+	}
+	deleteConditions := olderThanConditions(field, before)
+	condition, values := ch.toDeleteQuery(table, deleteConditions)
+	count, err := sqlParams.commonDeleteOlderThan(table.Name, fmt.Sprintf(countQueryCHTemplate, ch.database, table.Name, condition), "", values, true)
+	if err != nil || dryRun || count == 0 {
+		return count, err
+	}
+
+	if err := ch.Delete(table, deleteConditions); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
 func (ch *ClickHouse) deleteInTransaction(wrappedTx *Transaction, table *Table, deleteConditions *DeleteConditions) error {
 	deleteCondition, values := ch.toDeleteQuery(table, deleteConditions)
 	deleteQuery := fmt.Sprintf(deleteQueryChTemplate, ch.database, table.Name, deleteCondition)
@@ -460,6 +686,10 @@ func (ch *ClickHouse) deleteInTransaction(wrappedTx *Transaction, table *Table,
 //BulkInsert insert objects into table in one prepared statement
 func (ch *ClickHouse) BulkInsert(table *Table, objects []map[string]interface{}) error {
 	wrappedTx, err := ch.OpenTx()
+	if err != nil {
+		return err
+	}
+
 	err = ch.insertInTransaction(wrappedTx, table, objects)
 	if err != nil {
 		wrappedTx.Rollback()
@@ -508,6 +738,10 @@ func (ch *ClickHouse) DropTable(table *Table) error {
 		ch.dropDistributedTableInTransaction(wrappedTx, table.Name)
 	}
 
+	ch.enginesMutex.Lock()
+	delete(ch.engines, table.Name)
+	ch.enginesMutex.Unlock()
+
 	return nil
 }
 
@@ -518,68 +752,106 @@ func (ch *ClickHouse) toDeleteQuery(table *Table, conditions *DeleteConditions)
 		queryConditions = append(queryConditions, condition.Field+" "+condition.Clause+" "+ch.getPlaceholder(condition.Field, table.Columns[condition.Field]))
 		values = append(values, condition.Value)
 	}
-	return strings.Join(queryConditions, conditions.JoinCondition), values
+	return strings.Join(queryConditions, " "+conditions.JoinCondition+" "), values
 }
 
-//insertInTransaction creates statement like insert ... values (), (), ()
-//runs executeInsert func
+//insertInTransaction prepares statement insert ... values (?, ?) and executes it for every object
+//ClickHouse driver collects all rows of the prepared statement and sends them in one request on commit
 func (ch *ClickHouse) insertInTransaction(wrappedTx *Transaction, table *Table, objects []map[string]interface{}) error {
-	var placeholdersBuilder strings.Builder
-	var headerWithoutQuotes, headerWithQuotes []string
-	for name := range table.Columns {
+	var headerWithoutQuotes, headerWithQuotes, placeholders []string
+	for name, column := range table.Columns {
 		headerWithoutQuotes = append(headerWithoutQuotes, name)
 		headerWithQuotes = append(headerWithQuotes, fmt.Sprintf(`"%s"`, name))
+		placeholders = append(placeholders, ch.getPlaceholder(name, column))
 	}
-	maxValues := len(objects) * len(table.Columns)
-	valueArgs := make([]interface{}, 0, maxValues)
-	for _, row := range objects {
-		_, err := placeholdersBuilder.WriteString("(")
-		if err != nil {
-			return fmt.Errorf(placeholdersStringBuildErrTemplate, err)
-		}
 
+	statement := fmt.Sprintf(insertCHTemplate, ch.database, table.Name, strings.Join(headerWithQuotes, ", "), "("+strings.Join(placeholders, ", ")+")")
+	ch.queryLogger.LogQuery(fmt.Sprintf("%s (%d rows)", statement, len(objects)))
+
+	//statement mustn't be closed before commit: closed statement isn't sent
+	//it is closed only on error (transaction is rolled back)
+	stmt, err := wrappedTx.tx.PrepareContext(ch.ctx, statement)
+	if err != nil {
+		return fmt.Errorf("Error preparing insert into %s table statement: %s: %v", table.Name, statement, err)
+	}
+
+	for _, row := range objects {
+		valueArgs := make([]interface{}, len(headerWithoutQuotes))
 		for i, column := range headerWithoutQuotes {
-			//append value
 			value, ok := row[column]
 			if ok {
-				valueArgs = append(valueArgs, ch.reformatValue(value))
-			} else {
-				column, _ := table.Columns[column]
-				defaultValue, ok := ch.getDefaultValue(column.Type)
-				if ok {
-					valueArgs = append(valueArgs, defaultValue)
-				} else {
-					valueArgs = append(valueArgs, value)
-				}
-
-			}
-			//placeholder
-			placeholder := ch.getPlaceholder(column, table.Columns[column])
-
-			_, err = placeholdersBuilder.WriteString(placeholder)
-			if err != nil {
-				return fmt.Errorf(placeholdersStringBuildErrTemplate, err)
+				valueArgs[i] = ch.reformatValue(value, table.Columns[column].Type)
+				continue
 			}
 
-			if i < len(headerWithoutQuotes)-1 {
-				_, err = placeholdersBuilder.WriteString(",")
-				if err != nil {
-					return fmt.Errorf(placeholdersStringBuildErrTemplate, err)
-				}
+			defaultValue, ok := ch.getDefaultValue(table.Columns[column].Type)
+			if ok {
+				valueArgs[i] = defaultValue
 			}
 		}
-		_, err = placeholdersBuilder.WriteString("),")
-		if err != nil {
-			return fmt.Errorf(placeholdersStringBuildErrTemplate, err)
+
+		if _, err := stmt.ExecContext(ch.ctx, valueArgs...); err != nil {
+			stmt.Close()
+			//values aren't written into the error: they might contain personal data
+			return fmt.Errorf("Error inserting in %s table statement: %s (%d rows): %v", table.Name, statement, len(objects), err)
 		}
 	}
 
-	err := ch.executeInsert(wrappedTx, table, headerWithQuotes, removeLastComma(placeholdersBuilder.String()), valueArgs)
-	if err != nil {
-		return err
+	return nil
+}
+
+//getReplacingEngine returns cached table engine or selects it from system.tables
+func (ch *ClickHouse) getReplacingEngine(tableName string) (*replacingEngine, error) {
+	ch.enginesMutex.RLock()
+	engine, ok := ch.engines[tableName]
+	ch.enginesMutex.RUnlock()
+	if ok {
+		return engine, nil
 	}
 
-	return nil
+	var engineFull string
+	err := ch.dataSource.QueryRowContext(ch.ctx, tableEngineCHQuery, ch.database, tableName).Scan(&engineFull)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("Error querying table [%s] engine: %v", tableName, err)
+	}
+
+	engine = parseReplacingEngine(engineFull)
+
+	ch.enginesMutex.Lock()
+	ch.engines[tableName] = engine
+	ch.enginesMutex.Unlock()
+
+	return engine, nil
+}
+
+//fillVersionColumn puts the current time into version column of objects which don't have it
+//so the inserted rows replace the old ones with the same sorting key
+func (ch *ClickHouse) fillVersionColumn(table *Table, versionColumn string, objects []map[string]interface{}) {
+	if versionColumn == "" {
+		return
+	}
+
+	column, ok := table.Columns[versionColumn]
+	if !ok {
+		return
+	}
+
+	now := time.Now().UTC()
+	var version interface{}
+	switch columnType := strings.ToLower(column.Type); {
+	case strings.Contains(columnType, "datetime"):
+		version = now
+	case strings.Contains(columnType, "int64"):
+		version = now.UnixNano()
+	default:
+		version = now.Unix()
+	}
+
+	for _, object := range objects {
+		if _, ok := object[versionColumn]; !ok {
+			object[versionColumn] = version
+		}
+	}
 }
 
 //executeInsert execute insert with insertTemplate
@@ -685,20 +957,91 @@ func (ch *ClickHouse) getDefaultValue(sqlType string) (interface{}, bool) {
 	return nil, false
 }
 
+//ClickHouseNestedColumn maps structure of nested objects and arrays into JSON and Array(T) column types
+//arrays of objects are Array(JSON), arrays of arrays and arrays of mixed elements are Array(String) (elements are JSON strings)
+//JSON type requires allow_experimental_object_type setting
+func ClickHouseNestedColumn(nestedType *typing.NestedType) typing.SQLColumn {
+	if nestedType.Type != typing.ARRAY {
+		return typing.SQLColumn{Type: SchemaToClickhouse[nestedType.Type]}
+	}
+
+	if nestedType.Elem == nil || nestedType.Elem.Type == typing.ARRAY {
+		return typing.SQLColumn{Type: SchemaToClickhouse[typing.ARRAY]}
+	}
+
+	return typing.SQLColumn{Type: "Array(" + SchemaToClickhouse[nestedType.Elem.Type] + ")"}
+}
+
+//reformatValue returns value according to the column type:
 //if value is boolean - reformat it [true = 1; false = 0] ClickHouse supports UInt8 instead of boolean
+//if value is array - reformat it into Array(T): elements of Array(String) and Array(JSON) are strings (not string elements are serialized as JSON)
+//if column isn't an array - array is reformatted into JSON string
+//if value is object - reformat it into JSON string
 //otherwise return value as is
-func (ch *ClickHouse) reformatValue(v interface{}) interface{} {
-	//reformat boolean
-	booleanValue, ok := v.(bool)
-	if ok {
-		if booleanValue {
+func (ch *ClickHouse) reformatValue(v interface{}, sqlType string) interface{} {
+	switch value := v.(type) {
+	case bool:
+		if value {
 			return 1
 		}
 
 		return 0
+	case []interface{}:
+		lowerSQLType := strings.ToLower(sqlType)
+		if !strings.HasPrefix(lowerSQLType, "array(") {
+			return nestedToJSON(value)
+		}
+
+		switch elemType := strings.TrimSuffix(strings.TrimPrefix(lowerSQLType, "array("), ")"); elemType {
+		case "string", "lowcardinality(string)", "json", "object('json')":
+			elements := make([]string, 0, len(value))
+			for _, element := range value {
+				if element == nil {
+					continue
+				}
+				if str, ok := element.(string); ok && !strings.Contains(elemType, "json") {
+					elements = append(elements, str)
+				} else {
+					b, _ := json.Marshal(element)
+					elements = append(elements, string(b))
+				}
+			}
+
+			return clickhouse.Array(elements)
+		default:
+			elements := make([]interface{}, 0, len(value))
+			for _, element := range value {
+				if element == nil {
+					continue
+				}
+				elements = append(elements, ch.reformatValue(typing.ReformatValue(element), elemType))
+			}
+
+			return clickhouse.Array(elements)
+		}
+	case map[string]interface{}:
+		return nestedToJSON(value)
+	default:
+		return v
+	}
+}
+
+//parseReplacingEngine returns replacingEngine from system.tables engine_full value
+//version column is the last not quoted engine parameter (Replicated engines have quoted path and replica parameters)
+func parseReplacingEngine(engineFull string) *replacingEngine {
+	matches := replacingEngineRegexp.FindStringSubmatch(strings.TrimSpace(engineFull))
+	if len(matches) != 2 {
+		return &replacingEngine{}
+	}
+
+	engine := &replacingEngine{replacing: true}
+	parameters := strings.Split(matches[1], ",")
+	lastParameter := strings.TrimSpace(parameters[len(parameters)-1])
+	if lastParameter != "" && !strings.HasPrefix(lastParameter, "'") {
+		engine.versionColumn = strings.Trim(lastParameter, "`\"")
 	}
 
-	return v
+	return engine
 }
 
 func extractStatement(fieldConfigs []FieldConfig) string {
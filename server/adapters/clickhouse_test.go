@@ -2,10 +2,12 @@ package adapters
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"github.com/jitsucom/jitsu/server/logging"
 	"github.com/jitsucom/jitsu/server/test"
 	"github.com/jitsucom/jitsu/server/typing"
+	"github.com/mailru/go-clickhouse"
 	uuid "github.com/satori/go.uuid"
 	"github.com/stretchr/testify/require"
 	"gotest.tools/assert"
@@ -158,7 +160,7 @@ func TestTableStatementFactory(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			factory, err := NewTableStatementFactory(tt.inputConfig)
+			factory, err := NewTableStatementFactory(tt.inputConfig, nil)
 			if tt.expectedTableStatement == "" {
 				require.Error(t, err, "Clickhouse config can't be nil")
 				return
@@ -171,6 +173,167 @@ func TestTableStatementFactory(t *testing.T) {
 	}
 }
 
+func TestTableStatementFactoryPartitioning(t *testing.T) {
+	partitioning := &PartitioningConfig{Granularity: PartitionByDay}
+	require.NoError(t, partitioning.Validate())
+
+	factory, err := NewTableStatementFactory(&ClickHouseConfig{Database: "db1"}, partitioning)
+	require.NoError(t, err)
+	require.Equal(t, "CREATE TABLE \"db1\".\"test_table\"  (a String) ENGINE = ReplacingMergeTree(_timestamp) PARTITION BY (toYYYYMMDD(_timestamp)) ORDER BY (eventn_ctx_event_id)",
+		strings.TrimSpace(factory.CreateTableStatement("test_table", "a String")))
+
+	_, err = NewTableStatementFactory(&ClickHouseConfig{Database: "db1", Engine: &EngineConfig{PartitionFields: []FieldConfig{{Field: "a"}}}}, partitioning)
+	require.Error(t, err)
+}
+
+func TestParseReplacingEngine(t *testing.T) {
+	tests := []struct {
+		name       string
+		engineFull string
+		expected   *replacingEngine
+	}{
+		{
+			"Empty engine",
+			"",
+			&replacingEngine{},
+		},
+		{
+			"MergeTree",
+			"MergeTree PARTITION BY toYYYYMM(_timestamp) ORDER BY eventn_ctx_event_id SETTINGS index_granularity = 8192",
+			&replacingEngine{},
+		},
+		{
+			"ReplacingMergeTree with version",
+			"ReplacingMergeTree(_timestamp) PARTITION BY toYYYYMM(_timestamp) ORDER BY eventn_ctx_event_id SETTINGS index_granularity = 8192",
+			&replacingEngine{replacing: true, versionColumn: "_timestamp"},
+		},
+		{
+			"ReplacingMergeTree without version",
+			"ReplacingMergeTree() ORDER BY id",
+			&replacingEngine{replacing: true},
+		},
+		{
+			"ReplicatedReplacingMergeTree with version",
+			"ReplicatedReplacingMergeTree('/clickhouse/tables/{shard}/db1/events', '{replica}', `ver`) ORDER BY id",
+			&replacingEngine{replacing: true, versionColumn: "ver"},
+		},
+		{
+			"ReplicatedReplacingMergeTree without version",
+			"ReplicatedReplacingMergeTree('/clickhouse/tables/{shard}/db1/events', '{replica}') ORDER BY id",
+			&replacingEngine{replacing: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, parseReplacingEngine(tt.engineFull))
+		})
+	}
+}
+
+func TestClickHouseNestedColumn(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    interface{}
+		expected string
+	}{
+		{
+			"object",
+			map[string]interface{}{"id": 1, "tags": []interface{}{"a"}},
+			"JSON",
+		},
+		{
+			"array of integers",
+			[]interface{}{json.Number("1"), json.Number("2")},
+			"Array(Int64)",
+		},
+		{
+			"array of booleans",
+			[]interface{}{true},
+			"Array(UInt8)",
+		},
+		{
+			"array of objects",
+			[]interface{}{map[string]interface{}{"id": 1}},
+			"Array(JSON)",
+		},
+		{
+			"array of arrays",
+			[]interface{}{[]interface{}{1}},
+			"Array(String)",
+		},
+		{
+			"empty array",
+			[]interface{}{},
+			"Array(String)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nestedType, err := typing.NestedTypeFromValue(tt.input)
+			require.NoError(t, err)
+			require.Equal(t, typing.SQLColumn{Type: tt.expected}, ClickHouseNestedColumn(nestedType))
+		})
+	}
+}
+
+func TestClickHouseReformatValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    interface{}
+		sqlType  string
+		expected interface{}
+	}{
+		{
+			"boolean",
+			true,
+			"UInt8",
+			1,
+		},
+		{
+			"object",
+			map[string]interface{}{"id": 1},
+			"JSON",
+			`{"id":1}`,
+		},
+		{
+			"array into Array(String)",
+			[]interface{}{"a", json.Number("1"), map[string]interface{}{"id": 1}},
+			"Array(String)",
+			clickhouse.Array([]string{"a", "1", `{"id":1}`}),
+		},
+		{
+			"array into Array(Int64)",
+			[]interface{}{json.Number("1"), nil, json.Number("2")},
+			"Array(Int64)",
+			clickhouse.Array([]interface{}{int64(1), int64(2)}),
+		},
+		{
+			"array into Array(UInt8)",
+			[]interface{}{true, false},
+			"Array(UInt8)",
+			clickhouse.Array([]interface{}{1, 0}),
+		},
+		{
+			"array into Array(JSON)",
+			[]interface{}{map[string]interface{}{"id": 1}},
+			"Array(JSON)",
+			clickhouse.Array([]string{`{"id":1}`}),
+		},
+		{
+			"array into String",
+			[]interface{}{"a", 1},
+			"String",
+			`["a",1]`,
+		},
+	}
+	ch := &ClickHouse{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, ch.reformatValue(tt.value, tt.sqlType))
+		})
+	}
+}
+
 func TestClickhouseTruncateExistingTable(t *testing.T) {
 	recordsCount := len(timestamps)
 	table := &Table{
@@ -215,12 +378,12 @@ func setupClickHouseDatabase(t *testing.T, table *Table) (*test.ClickHouseContai
 		Dsns:     container.Dsns,
 		Database: container.Database,
 		Cluster:  "",
-	})
+	}, nil)
 	if err != nil {
 		t.Fatalf("failed to initialize table statement factory: %v", err)
 	}
 	adapter, err := NewClickHouse(ctx, container.Dsns[0], container.Database, "", nil, tsf, map[string]bool{},
-		&logging.QueryLogger{}, typing.SQLTypes{})
+		&logging.QueryLogger{}, typing.SQLTypes{}, false)
 	if err != nil {
 		t.Fatalf("Failed to create ClickHouse adapter: %v", err)
 	}
@@ -1,7 +1,9 @@
 package adapters
 
 import (
+	"fmt"
 	"github.com/jitsucom/jitsu/server/events"
+	"strings"
 )
 
 //DeleteCondition is a representation of SQL delete condition
@@ -34,3 +36,47 @@ func DeleteByTimeChunkCondition(timeIntervalValue string) *DeleteConditions {
 		Conditions:    []DeleteCondition{{Field: events.TimeChunkKey, Clause: "=", Value: timeIntervalValue}},
 	}
 }
+
+//DeleteByFieldValuesCondition returns delete condition that removes objects which have one of values in the field
+//or empty condition if values are empty
+func DeleteByFieldValuesCondition(field string, values []string) *DeleteConditions {
+	conditions := &DeleteConditions{JoinCondition: "OR"}
+	for _, value := range values {
+		conditions.Conditions = append(conditions.Conditions, DeleteCondition{Field: field, Clause: "=", Value: value})
+	}
+
+	return conditions
+}
+
+//Match returns true if flat object satisfies conditions. It is used for deleting objects from files (e.g. S3 or logs).
+//Only '=' and '!=' clauses are supported, other clauses never match
+func (dc *DeleteConditions) Match(object map[string]interface{}) bool {
+	if dc.IsEmpty() {
+		return false
+	}
+
+	or := strings.EqualFold(strings.TrimSpace(dc.JoinCondition), "OR")
+	for _, condition := range dc.Conditions {
+		matched := condition.match(object)
+		if or && matched {
+			return true
+		}
+		if !or && !matched {
+			return false
+		}
+	}
+
+	return !or
+}
+
+func (c *DeleteCondition) match(object map[string]interface{}) bool {
+	value, ok := object[c.Field]
+	switch strings.TrimSpace(c.Clause) {
+	case "=":
+		return ok && fmt.Sprint(value) == fmt.Sprint(c.Value)
+	case "!=", "<>":
+		return !ok || fmt.Sprint(value) != fmt.Sprint(c.Value)
+	default:
+		return false
+	}
+}
@@ -0,0 +1,44 @@
+package adapters
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeleteConditionsMatch(t *testing.T) {
+	object := map[string]interface{}{"user_id": "123", "user_anonymous_id": "anon1", "amount": 10}
+	tests := []struct {
+		name       string
+		conditions *DeleteConditions
+		expected   bool
+	}{
+		{"Nil conditions", nil, false},
+		{"Empty conditions", &DeleteConditions{JoinCondition: "OR"}, false},
+		{"OR one matches", &DeleteConditions{JoinCondition: "OR", Conditions: []DeleteCondition{
+			{Field: "user_id", Clause: "=", Value: "456"},
+			{Field: "user_anonymous_id", Clause: "=", Value: "anon1"},
+		}}, true},
+		{"OR nothing matches", &DeleteConditions{JoinCondition: " or ", Conditions: []DeleteCondition{
+			{Field: "user_id", Clause: "=", Value: "456"},
+			{Field: "email", Clause: "=", Value: "123"},
+		}}, false},
+		{"AND all match", &DeleteConditions{JoinCondition: "AND", Conditions: []DeleteCondition{
+			{Field: "user_id", Clause: "=", Value: "123"},
+			{Field: "amount", Clause: "=", Value: "10"},
+		}}, true},
+		{"AND one doesn't match", &DeleteConditions{JoinCondition: "AND", Conditions: []DeleteCondition{
+			{Field: "user_id", Clause: "=", Value: "123"},
+			{Field: "amount", Clause: "=", Value: 11},
+		}}, false},
+		{"Not equal", &DeleteConditions{JoinCondition: "AND", Conditions: []DeleteCondition{{Field: "user_id", Clause: "!=", Value: "456"}}}, true},
+		{"Not equal missing field", &DeleteConditions{JoinCondition: "AND", Conditions: []DeleteCondition{{Field: "email", Clause: "<>", Value: "456"}}}, true},
+		{"Equal missing field", &DeleteConditions{JoinCondition: "AND", Conditions: []DeleteCondition{{Field: "email", Clause: "=", Value: ""}}}, false},
+		{"Unsupported clause", &DeleteConditions{JoinCondition: "OR", Conditions: []DeleteCondition{{Field: "amount", Clause: ">", Value: 1}}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, tt.conditions.Match(object))
+		})
+	}
+}
@@ -6,18 +6,23 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"github.com/jitsucom/jitsu/server/events"
 	"io/ioutil"
 	"net/http"
+	"regexp"
 	"strings"
 	"time"
 
-	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/events"
+	"github.com/jitsucom/jitsu/server/jsonutils"
 	"github.com/jitsucom/jitsu/server/timestamp"
 )
 
 const (
 	eventsURLTemplate = "https://graph.facebook.com/v12.0/%s/events?access_token=%s&locale=en_EN"
+	//fbMaxBatchEvents is a Conversions API limit of events in one request
+	fbMaxBatchEvents = 1000
+	//fbDefaultActionSource is used if action_source isn't configured and isn't set in the event
+	fbDefaultActionSource = "website"
 )
 
 var (
@@ -29,17 +34,68 @@ var (
 		"signup":     "CompleteRegistration",
 	}
 
-	//email (em) has custom handling below
-	fieldsToHash = []string{"ph", "ge", "db", "ln", "fn", "ct", "st", "zp", "country"}
+	//fbActionSources are allowed action_source values
+	//https://developers.facebook.com/docs/marketing-api/conversions-api/parameters/server-event#action-source
+	fbActionSources = map[string]bool{
+		"email": true, "website": true, "app": true, "phone_call": true,
+		"chat": true, "physical_store": true, "system_generated": true, "other": true,
+	}
+
+	//fbServerEventParameters are passed to Conversions API as is if they are present in the event
+	fbServerEventParameters = []string{"opt_out", "data_processing_options", "data_processing_options_country",
+		"data_processing_options_state", "app_data", "referrer_url"}
+
+	//fbCustomDataFields are standard custom_data parameters which are taken from event root or /properties by default
+	//https://developers.facebook.com/docs/marketing-api/conversions-api/parameters/custom-data
+	fbCustomDataFields = []string{"value", "currency", "content_name", "content_category", "content_ids", "contents",
+		"content_type", "order_id", "predicted_ltv", "num_items", "search_string", "status", "delivery_category"}
+
+	//fbUserDataPaths are Jitsu fields which are sent as customer information parameters if user_data doesn't contain them
+	//https://developers.facebook.com/docs/marketing-api/conversions-api/parameters/customer-information-parameters
+	fbUserDataPaths = map[string]jsonutils.JSONPath{
+		"em":                jsonutils.NewJSONPath("/user/email||/eventn_ctx/user/email"),
+		"ph":                jsonutils.NewJSONPath("/user/phone||/eventn_ctx/user/phone"),
+		"fn":                jsonutils.NewJSONPath("/user/first_name||/eventn_ctx/user/first_name"),
+		"ln":                jsonutils.NewJSONPath("/user/last_name||/eventn_ctx/user/last_name"),
+		"external_id":       jsonutils.NewJSONPath("/user/id||/eventn_ctx/user/id"),
+		"ct":                jsonutils.NewJSONPath("/location/city"),
+		"zp":                jsonutils.NewJSONPath("/location/zip"),
+		"country":           jsonutils.NewJSONPath("/location/country"),
+		"client_ip_address": jsonutils.NewJSONPath("/source_ip"),
+		"client_user_agent": jsonutils.NewJSONPath("/user_agent||/eventn_ctx/user_agent"),
+	}
+
+	//fbFbpPath and fbFbcPath are Facebook cookies (_fbp, _fbc) captured by JS SDK (see capture_3rd_party_cookies)
+	fbFbpPath = jsonutils.NewJSONPath("/user_data/fbp||/ids/fbp||/eventn_ctx/ids/fbp")
+	fbFbcPath = jsonutils.NewJSONPath("/user_data/fbc||/ids/fbc||/eventn_ctx/ids/fbc")
+	//fbClickIDPath is fbclid URL parameter captured by JS SDK. It is used for building fbc if _fbc cookie is absent
+	fbClickIDPath   = jsonutils.NewJSONPath("/click_id/fbclid||/eventn_ctx/click_id/fbclid")
+	fbEventIDPath   = jsonutils.NewJSONPath("/event_id||/eventn_ctx_event_id||/eventn_ctx/event_id")
+	fbEventNamePath = jsonutils.NewJSONPath("/event_name||/event_type")
+	fbSourceURLPath = jsonutils.NewJSONPath("/event_source_url||/url||/eventn_ctx/url")
+
+	fbHashedValueRegexp = regexp.MustCompile(`^[a-f0-9]{64}$`)
+	fbNonDigitsRegexp   = regexp.MustCompile(`[^0-9]`)
+
+	//fieldsToHash are customer information parameters which must be normalized and hashed with SHA256
+	//values which are already hashed are sent as is
+	fieldsToHash = []string{"em", "ph", "ge", "db", "ln", "fn", "ct", "st", "zp", "country", "external_id"}
 )
 
 //FacebookConversionAPIConfig dto for deserialized datasource config (e.g. in Facebook destination)
 type FacebookConversionAPIConfig struct {
-	PixelID     string `mapstructure:"pixel_id" json:"pixel_id,omitempty" yaml:"pixel_id,omitempty"`
-	AccessToken string `mapstructure:"access_token" json:"access_token,omitempty" yaml:"access_token,omitempty"`
+	PixelID       string `mapstructure:"pixel_id" json:"pixel_id,omitempty" yaml:"pixel_id,omitempty"`
+	AccessToken   string `mapstructure:"access_token" json:"access_token,omitempty" yaml:"access_token,omitempty"`
+	ActionSource  string `mapstructure:"action_source" json:"action_source,omitempty" yaml:"action_source,omitempty"`
+	TestEventCode string `mapstructure:"test_event_code" json:"test_event_code,omitempty" yaml:"test_event_code,omitempty"`
+	//EventNames is a mapping Jitsu event_type -> Facebook event_name. Overrides default mapping
+	EventNames map[string]string `mapstructure:"event_names" json:"event_names,omitempty" yaml:"event_names,omitempty"`
+	//CustomData is a mapping custom_data parameter -> JSON path in the event. Overrides default mapping
+	CustomData map[string]string `mapstructure:"custom_data" json:"custom_data,omitempty" yaml:"custom_data,omitempty"`
 }
 
 //Validate required fields in FacebookConversionAPIConfig
+//sets default action_source
 func (fmc *FacebookConversionAPIConfig) Validate() error {
 	if fmc == nil {
 		return errors.New("facebook config is required")
@@ -52,6 +108,19 @@ func (fmc *FacebookConversionAPIConfig) Validate() error {
 		return errors.New("access_token is required parameter")
 	}
 
+	if fmc.ActionSource == "" {
+		fmc.ActionSource = fbDefaultActionSource
+	}
+	if !fbActionSources[fmc.ActionSource] {
+		return fmt.Errorf("Unsupported action_source: %s", fmc.ActionSource)
+	}
+
+	for customDataField, path := range fmc.CustomData {
+		if path == "" {
+			return fmt.Errorf("custom_data.%s JSON path is required", customDataField)
+		}
+	}
+
 	return nil
 }
 
@@ -88,7 +157,7 @@ func NewTestFacebookConversion(config *FacebookConversionAPIConfig) *FacebookCon
 
 //NewFacebookConversion returns new instance of adapter
 func NewFacebookConversion(config *FacebookConversionAPIConfig, httpAdapterConfiguration *HTTPAdapterConfiguration) (*FacebookConversionAPI, error) {
-	httpAdapterConfiguration.HTTPReqFactory = &FacebookRequestFactory{config: config}
+	httpAdapterConfiguration.HTTPReqFactory = newFacebookRequestFactory(config)
 
 	httpAdapter, err := NewHTTPAdapter(httpAdapterConfiguration)
 	if err != nil {
@@ -142,6 +211,16 @@ func (fc *FacebookConversionAPI) TestAccess() error {
 	return errors.New("Empty Facebook response body")
 }
 
+//Insert enriches processed event with Jitsu unique ID (as event_id) and Facebook cookies from the raw event
+//they might be removed by mapping rules but they are required for deduplication with the browser pixel and matching
+func (fc *FacebookConversionAPI) Insert(eventContext *EventContext) error {
+	if eventContext.ProcessedEvent != nil {
+		enrichFacebookEvent(eventContext.ProcessedEvent, eventContext.RawEvent, eventContext.EventID)
+	}
+
+	return fc.AbstractHTTP.Insert(eventContext)
+}
+
 //Type returns adapter type
 func (fc *FacebookConversionAPI) Type() string {
 	return "FacebookConversionAPI"
@@ -149,28 +228,43 @@ func (fc *FacebookConversionAPI) Type() string {
 
 //FacebookRequestFactory is a factory for building facebook POST HTTP requests from input events
 type FacebookRequestFactory struct {
-	config *FacebookConversionAPIConfig
+	config     *FacebookConversionAPIConfig
+	eventNames map[string]string
+	customData map[string]jsonutils.JSONPath
 }
 
-//Create returns created http.Request
-//transforms parameters (event_time -> unix timestamp)
-//maps input event_type(event_name) with standard
-//hashes fields according to documentation
-func (frf *FacebookRequestFactory) Create(object map[string]interface{}) (*Request, error) {
-	// ** Parameters transformation **
-	// * action_source
-	_, ok := object["action_source"]
-	if !ok {
-		object["action_source"] = "website"
+//newFacebookRequestFactory returns FacebookRequestFactory with default event names and custom_data mappings
+//overridden with configured ones
+func newFacebookRequestFactory(config *FacebookConversionAPIConfig) *FacebookRequestFactory {
+	eventNames := map[string]string{}
+	for eventType, eventName := range fbEventTypeMapping {
+		eventNames[eventType] = eventName
+	}
+	for eventType, eventName := range config.EventNames {
+		eventNames[eventType] = eventName
 	}
 
-	// * event_time
-	frf.enrichWithEventTime(object)
+	customData := map[string]jsonutils.JSONPath{}
+	for _, field := range fbCustomDataFields {
+		customData[field] = jsonutils.NewJSONPath("/" + field + "||/properties/" + field)
+	}
+	for field, path := range config.CustomData {
+		customData[field] = jsonutils.NewJSONPath(path)
+	}
 
+	return &FacebookRequestFactory{config: config, eventNames: eventNames, customData: customData}
+}
+
+//Create returns created http.Request with Conversions API server event
+//builds server event from Facebook parameters (if they are present in the event, e.g. after mapping) or from Jitsu event fields:
+//maps event_type(event_name) with configured or standard event names
+//transforms parameters (_timestamp -> event_time unix timestamp)
+//fills user_data and custom_data and hashes user_data fields according to documentation
+func (frf *FacebookRequestFactory) Create(object map[string]interface{}) (*Request, error) {
 	// * event_name
-	eventName, ok := object["event_name"]
-	if !ok {
-		return nil, fmt.Errorf("Object doesn't have event_name")
+	eventName, ok := fbEventNamePath.Get(object)
+	if !ok || eventName == nil {
+		return nil, errors.New("Object doesn't have event_name")
 	}
 
 	eventNameStr, ok := eventName.(string)
@@ -178,25 +272,59 @@ func (frf *FacebookRequestFactory) Create(object map[string]interface{}) (*Reque
 		return nil, fmt.Errorf("event_name must be string: %T", eventName)
 	}
 
-	mappedEventName, ok := fbEventTypeMapping[eventNameStr]
-	if ok {
-		object["event_name"] = mappedEventName
+	if mappedEventName, ok := frf.eventNames[eventNameStr]; ok {
+		eventNameStr = mappedEventName
+	}
+
+	eventTime := frf.eventTime(object)
+	serverEvent := map[string]interface{}{
+		"event_name":    eventNameStr,
+		"event_time":    eventTime.Unix(),
+		"action_source": frf.config.ActionSource,
+	}
+
+	// * action_source
+	if actionSource, ok := object["action_source"]; ok && actionSource != "" {
+		serverEvent["action_source"] = actionSource
+	}
+
+	// * event_id is used for deduplication with the browser pixel events (eventID parameter)
+	if eventID := analyticsString(fbEventIDPath, object); eventID != "" {
+		serverEvent["event_id"] = eventID
 	}
 
-	frf.hashFields(object)
+	// * event_source_url
+	if sourceURL := analyticsString(fbSourceURLPath, object); sourceURL != "" {
+		serverEvent["event_source_url"] = sourceURL
+	}
+
+	for _, parameter := range fbServerEventParameters {
+		if value, ok := object[parameter]; ok {
+			serverEvent[parameter] = value
+		}
+	}
+
+	if userData := frf.userData(object, eventTime); len(userData) > 0 {
+		serverEvent["user_data"] = userData
+	}
+
+	if customData := frf.customDataFields(object); len(customData) > 0 {
+		serverEvent["custom_data"] = customData
+	}
 
 	//* test_event_code
-	var testEventCodeStr string
-	testEventCode, testEventCodeExists := object["test_event_code"]
-	if testEventCodeExists {
-		delete(object, "test_event_code")
+	testEventCodeStr := frf.config.TestEventCode
+	if testEventCode, ok := object["test_event_code"]; ok && testEventCode != nil {
 		testEventCodeStr = fmt.Sprint(testEventCode)
 	}
 
 	//creating
 	reqURL := fmt.Sprintf(eventsURLTemplate, frf.config.PixelID, frf.config.AccessToken)
-	reqBody := &FacebookConversionEventsReq{Data: []map[string]interface{}{object}, TestEventCode: testEventCodeStr}
-	bodyPayload, _ := json.Marshal(reqBody)
+	reqBody := &FacebookConversionEventsReq{Data: []map[string]interface{}{serverEvent}, TestEventCode: testEventCodeStr}
+	bodyPayload, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("Error marshalling facebook request: %v", err)
+	}
 
 	return &Request{
 		URL:     reqURL,
@@ -206,60 +334,215 @@ func (frf *FacebookRequestFactory) Create(object map[string]interface{}) (*Reque
 	}, nil
 }
 
-func (frf *FacebookRequestFactory) enrichWithEventTime(object map[string]interface{}) {
-	eventTime := time.Now().UTC()
-	// * event_time
-	t, ok := object[timestamp.Key]
-	if ok {
-		switch t.(type) {
-		case time.Time:
-			eventTime = t.(time.Time)
-		case string:
-			eTime, err := time.Parse(time.RFC3339Nano, t.(string))
-			if err != nil {
-				logging.Errorf("Error parsing %s in facebook event: %v", timestamp.Key, err)
-			} else {
-				eventTime = eTime
-			}
+//eventTime returns _timestamp or event_time (unix timestamp) if it has been set in the event or now
+func (frf *FacebookRequestFactory) eventTime(object map[string]interface{}) time.Time {
+	if _, ok := object[timestamp.Key]; !ok {
+		switch t := object["event_time"].(type) {
+		case int:
+			return time.Unix(int64(t), 0).UTC()
+		case int64:
+			return time.Unix(t, 0).UTC()
+		case float64:
+			return time.Unix(int64(t), 0).UTC()
 		}
 	}
 
-	object["event_time"] = eventTime.Unix()
-
-	delete(object, timestamp.Key)
+	return eventTime(object)
 }
 
-//hashFields hash fields from 'user_data' object according to
+//userData returns user_data from the event enriched with customer information from Jitsu fields and Facebook cookies
+//hashes fields according to
 //https://developers.facebook.com/docs/marketing-api/conversions-api/parameters/customer-information-parameters
-func (frf *FacebookRequestFactory) hashFields(object map[string]interface{}) {
-	iface, ok := object["user_data"]
-	if !ok {
+func (frf *FacebookRequestFactory) userData(object map[string]interface{}, eventTime time.Time) map[string]interface{} {
+	userData := map[string]interface{}{}
+	if eventUserData, ok := object["user_data"].(map[string]interface{}); ok {
+		for name, value := range eventUserData {
+			userData[name] = value
+		}
+	}
+
+	for name, path := range fbUserDataPaths {
+		if _, ok := userData[name]; ok {
+			continue
+		}
+		if value, ok := path.Get(object); ok && value != nil && value != "" {
+			userData[name] = value
+		}
+	}
+
+	if fbp := analyticsString(fbFbpPath, object); fbp != "" {
+		userData["fbp"] = fbp
+	}
+	if fbc := facebookClickID(object, eventTime); fbc != "" {
+		userData["fbc"] = fbc
+	}
+
+	for _, field := range fieldsToHash {
+		if value, ok := userData[field]; ok {
+			userData[field] = hashUserDataValue(field, value)
+		}
+	}
+
+	return userData
+}
+
+//customDataFields returns custom_data from the event enriched with fields from custom_data mapping
+func (frf *FacebookRequestFactory) customDataFields(object map[string]interface{}) map[string]interface{} {
+	customData := map[string]interface{}{}
+	if eventCustomData, ok := object["custom_data"].(map[string]interface{}); ok {
+		for name, value := range eventCustomData {
+			customData[name] = value
+		}
+	}
+
+	for name, path := range frf.customData {
+		if _, ok := customData[name]; ok {
+			continue
+		}
+		if value, ok := path.Get(object); ok && value != nil {
+			customData[name] = value
+		}
+	}
+
+	return customData
+}
+
+//enrichFacebookEvent puts event_id (Jitsu unique ID) and Facebook cookies from the raw event into the object
+//if they are absent
+func enrichFacebookEvent(object, rawEvent map[string]interface{}, eventID string) {
+	if _, ok := fbEventIDPath.Get(object); !ok && eventID != "" {
+		object["event_id"] = eventID
+	}
+
+	if rawEvent == nil {
 		return
 	}
 
-	userData, ok := iface.(map[string]interface{})
-	if !ok {
+	cookies := map[string]string{}
+	if _, ok := fbFbpPath.Get(object); !ok {
+		if fbp := analyticsString(fbFbpPath, rawEvent); fbp != "" {
+			cookies["fbp"] = fbp
+		}
+	}
+	if facebookClickID(object, time.Time{}) == "" {
+		if fbc := facebookClickID(rawEvent, eventTime(rawEvent)); fbc != "" {
+			cookies["fbc"] = fbc
+		}
+	}
+	if len(cookies) == 0 {
 		return
 	}
 
-	for _, field := range fieldsToHash {
-		v, ok := userData[field]
-		if ok {
-			original := []byte(fmt.Sprintf("%v", v))
-			sum := sha256.Sum256(original)
-			userData[field] = fmt.Sprintf("%x", sum)
+	userData, ok := object["user_data"].(map[string]interface{})
+	if !ok {
+		userData = map[string]interface{}{}
+		object["user_data"] = userData
+	}
+	for name, value := range cookies {
+		userData[name] = value
+	}
+}
+
+//facebookClickID returns fbc from _fbc cookie or builds it from fbclid URL parameter
+//https://developers.facebook.com/docs/marketing-api/conversions-api/parameters/fbp-and-fbc
+func facebookClickID(object map[string]interface{}, eventTime time.Time) string {
+	if fbc := analyticsString(fbFbcPath, object); fbc != "" {
+		return fbc
+	}
+
+	fbclid := analyticsString(fbClickIDPath, object)
+	if fbclid == "" {
+		return ""
+	}
+
+	return fmt.Sprintf("fb.1.%d.%s", eventTime.UnixNano()/int64(time.Millisecond), fbclid)
+}
+
+//hashUserDataValue returns normalized and SHA256 hashed value (or values if it is an array)
+//values which are already hashed are returned as is
+func hashUserDataValue(field string, value interface{}) interface{} {
+	if values, ok := value.([]interface{}); ok {
+		hashed := make([]interface{}, 0, len(values))
+		for _, v := range values {
+			hashed = append(hashed, hashUserDataValue(field, v))
 		}
+		return hashed
+	}
+
+	strValue := fmt.Sprint(value)
+	if fbHashedValueRegexp.MatchString(strValue) {
+		return strValue
+	}
+
+	sum := sha256.Sum256([]byte(normalizeUserDataValue(field, strValue)))
+	return fmt.Sprintf("%x", sum)
+}
+
+//normalizeUserDataValue returns value formatted according to Facebook normalization rules
+func normalizeUserDataValue(field, value string) string {
+	value = strings.TrimSpace(value)
+	if field == "external_id" || value == events.MaskedParameterValue {
+		return value
 	}
 
-	//hash email ('em') if only it isn't hashed yet
-	email, ok := userData["em"]
-	if ok {
-		strEmail := fmt.Sprintf("%v", email)
-		if strings.Contains(strEmail, "@") || strEmail == events.MaskedParameterValue {
-			sum := sha256.Sum256([]byte(strEmail))
-			userData["em"] = fmt.Sprintf("%x", sum)
+	value = strings.ToLower(value)
+	switch field {
+	case "ph", "db":
+		return fbNonDigitsRegexp.ReplaceAllString(value, "")
+	case "ge":
+		if len(value) > 1 {
+			return value[:1]
 		}
+	case "ct", "zp":
+		return strings.NewReplacer(" ", "", "-", "").Replace(value)
 	}
+
+	return value
+}
+
+//BatchKey returns test_event_code: requests with different test codes can't be sent in one batch
+func (frf *FacebookRequestFactory) BatchKey(req *Request) string {
+	reqBody := &FacebookConversionEventsReq{}
+	if err := json.Unmarshal(req.Body, reqBody); err != nil {
+		return ""
+	}
+
+	return req.URL + "#" + reqBody.TestEventCode
+}
+
+//CreateBatch returns request with data[] from all requests
+func (frf *FacebookRequestFactory) CreateBatch(requests []*Request) (*Request, error) {
+	batch := &FacebookConversionEventsReq{}
+	for _, r := range requests {
+		reqBody := &FacebookConversionEventsReq{}
+		if err := json.Unmarshal(r.Body, reqBody); err != nil {
+			return nil, fmt.Errorf("Error unmarshalling facebook request: %v", err)
+		}
+		batch.Data = append(batch.Data, reqBody.Data...)
+		batch.TestEventCode = reqBody.TestEventCode
+	}
+
+	bodyPayload, err := json.Marshal(batch)
+	if err != nil {
+		return nil, fmt.Errorf("Error marshalling facebook batch request: %v", err)
+	}
+	return &Request{
+		URL:     requests[0].URL,
+		Method:  http.MethodPost,
+		Body:    bodyPayload,
+		Headers: map[string]string{"Content-Type": "application/json"},
+	}, nil
+}
+
+//MaxBatchEvents returns Conversions API limit
+func (frf *FacebookRequestFactory) MaxBatchEvents() int {
+	return fbMaxBatchEvents
+}
+
+//BatchItemErrors always returns empty errors: Conversions API rejects the whole batch if any event is invalid
+//and doesn't return invalid event index in a structured form
+func (frf *FacebookRequestFactory) BatchItemErrors(statusCode int, responseBody []byte, batchSize int) map[int]error {
+	return map[int]error{}
 }
 
 func (frf *FacebookRequestFactory) Close() {
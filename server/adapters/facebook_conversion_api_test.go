@@ -0,0 +1,154 @@
+package adapters
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/timestamp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFacebookCreate(t *testing.T) {
+	eventTime := time.Date(2021, 12, 31, 10, 0, 0, 0, time.UTC)
+	hashedEmail := sha256Hex("a@b.com")
+	tests := []struct {
+		name                  string
+		config                *FacebookConversionAPIConfig
+		input                 map[string]interface{}
+		expectedEvent         string
+		expectedTestEventCode string
+		expectedErr           string
+	}{
+		{
+			"Jitsu event",
+			&FacebookConversionAPIConfig{},
+			map[string]interface{}{"event_type": "pageview", "eventn_ctx_event_id": "e1", "source_ip": "1.1.1.1", timestamp.Key: eventTime,
+				"eventn_ctx": map[string]interface{}{"url": "https://jitsu.com", "user_agent": "Mozilla",
+					"user": map[string]interface{}{"anonymous_id": "anon1", "id": "u1", "email": " A@B.com "},
+					"ids":  map[string]interface{}{"fbp": "fb.1.1596403881668.1116446470"}},
+				"location": map[string]interface{}{"city": "New York", "country": "US", "zip": "10001"}},
+			`{"event_name":"PageView","event_time":1640944800,"action_source":"website","event_id":"e1","event_source_url":"https://jitsu.com",
+				"user_data":{"em":"` + hashedEmail + `","external_id":"` + sha256Hex("u1") + `","client_ip_address":"1.1.1.1","client_user_agent":"Mozilla",
+				"ct":"` + sha256Hex("newyork") + `","country":"` + sha256Hex("us") + `","zp":"` + sha256Hex("10001") + `","fbp":"fb.1.1596403881668.1116446470"}}`,
+			"",
+			"",
+		},
+		{
+			"Click id from URL parameter",
+			&FacebookConversionAPIConfig{},
+			map[string]interface{}{"event_type": "signup", "event_id": "e2", timestamp.Key: eventTime,
+				"click_id": map[string]interface{}{"fbclid": "IwAR2F4"}, "ids": map[string]interface{}{"fbp": "fb.1.1.2"}},
+			`{"event_name":"CompleteRegistration","event_time":1640944800,"action_source":"website","event_id":"e2",
+				"user_data":{"fbp":"fb.1.1.2","fbc":"fb.1.1640944800000.IwAR2F4"}}`,
+			"",
+			"",
+		},
+		{
+			"Mapped event",
+			&FacebookConversionAPIConfig{},
+			map[string]interface{}{"event_name": "conversion", "event_id": "e3", "event_source_url": "https://jitsu.com/checkout", timestamp.Key: eventTime,
+				"action_source": "app", "opt_out": true, "test_event_code": "TEST1",
+				"user_data":   map[string]interface{}{"em": hashedEmail, "ph": "+1 (555) 123-45", "ge": "Female", "db": "1990-01-31", "fbc": "fb.1.1.3"},
+				"custom_data": map[string]interface{}{"value": 10, "currency": "USD"}},
+			`{"event_name":"Purchase","event_time":1640944800,"action_source":"app","event_id":"e3","event_source_url":"https://jitsu.com/checkout","opt_out":true,
+				"user_data":{"em":"` + hashedEmail + `","ph":"` + sha256Hex("155512345") + `","ge":"` + sha256Hex("f") + `","db":"` + sha256Hex("19900131") + `","fbc":"fb.1.1.3"},
+				"custom_data":{"value":10,"currency":"USD"}}`,
+			"TEST1",
+			"",
+		},
+		{
+			"Configured event names, custom data and test event code",
+			&FacebookConversionAPIConfig{ActionSource: "system_generated", TestEventCode: "TEST2",
+				EventNames: map[string]string{"order_completed": "Purchase", "signup": "Lead"},
+				CustomData: map[string]string{"value": "/order/total", "order_id": "/order/id"}},
+			map[string]interface{}{"event_type": "order_completed", "event_id": "e4", "event_time": 1640944800, "currency": "EUR",
+				"order": map[string]interface{}{"id": "o1", "total": 99.5}},
+			`{"event_name":"Purchase","event_time":1640944800,"action_source":"system_generated","event_id":"e4",
+				"custom_data":{"value":99.5,"order_id":"o1","currency":"EUR"}}`,
+			"TEST2",
+			"",
+		},
+		{
+			"Custom event",
+			&FacebookConversionAPIConfig{EventNames: map[string]string{"signup": "Lead"}},
+			map[string]interface{}{"event_type": "signup", "event_id": "e5", timestamp.Key: eventTime, "properties": map[string]interface{}{"status": "trial"}},
+			`{"event_name":"Lead","event_time":1640944800,"action_source":"website","event_id":"e5","custom_data":{"status":"trial"}}`,
+			"",
+			"",
+		},
+		{
+			"Event without name",
+			&FacebookConversionAPIConfig{},
+			map[string]interface{}{"event_id": "e6"},
+			"",
+			"",
+			"Object doesn't have event_name",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.PixelID = "pixel"
+			tt.config.AccessToken = "token"
+			require.NoError(t, tt.config.Validate())
+
+			r, err := newFacebookRequestFactory(tt.config).Create(tt.input)
+			if tt.expectedErr != "" {
+				require.EqualError(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, "https://graph.facebook.com/v12.0/pixel/events?access_token=token&locale=en_EN", r.URL)
+
+			body := &FacebookConversionEventsReq{}
+			require.NoError(t, json.Unmarshal(r.Body, body))
+			require.Equal(t, tt.expectedTestEventCode, body.TestEventCode)
+			require.Len(t, body.Data, 1)
+			serverEvent, err := json.Marshal(body.Data[0])
+			require.NoError(t, err)
+			require.JSONEq(t, tt.expectedEvent, string(serverEvent))
+		})
+	}
+}
+
+func TestFacebookDeduplication(t *testing.T) {
+	config := &FacebookConversionAPIConfig{PixelID: "pixel", AccessToken: "token"}
+	require.NoError(t, config.Validate())
+
+	//mapping rules have removed Jitsu fields
+	processed := map[string]interface{}{"event_name": "Purchase", "user_data": map[string]interface{}{"em": "a@b.com"}}
+	raw := map[string]interface{}{"event_type": "conversion", "eventn_ctx_event_id": "jitsu-id", timestamp.Key: "2021-12-31T10:00:00.000000Z",
+		"eventn_ctx": map[string]interface{}{"ids": map[string]interface{}{"fbp": "fb.1.1.2"}, "click_id": map[string]interface{}{"fbclid": "IwAR2F4"}}}
+	enrichFacebookEvent(processed, raw, "jitsu-id")
+
+	r, err := newFacebookRequestFactory(config).Create(processed)
+	require.NoError(t, err)
+
+	body := &FacebookConversionEventsReq{}
+	require.NoError(t, json.Unmarshal(r.Body, body))
+	require.Equal(t, "jitsu-id", body.Data[0]["event_id"], "Jitsu unique ID is used as event_id for deduplication with pixel eventID")
+	require.Equal(t, map[string]interface{}{"em": sha256Hex("a@b.com"), "fbp": "fb.1.1.2", "fbc": "fb.1.1640944800000.IwAR2F4"}, body.Data[0]["user_data"])
+
+	//event_id which has been set explicitly isn't overridden
+	processed = map[string]interface{}{"event_name": "Purchase", "event_id": "pixel-id", "user_data": map[string]interface{}{"fbc": "fb.1.1.3"}}
+	enrichFacebookEvent(processed, raw, "jitsu-id")
+	require.Equal(t, map[string]interface{}{"event_name": "Purchase", "event_id": "pixel-id", "user_data": map[string]interface{}{"fbc": "fb.1.1.3", "fbp": "fb.1.1.2"}}, processed)
+}
+
+func TestFacebookConfigValidate(t *testing.T) {
+	config := &FacebookConversionAPIConfig{PixelID: "pixel", AccessToken: "token"}
+	require.NoError(t, config.Validate())
+	require.Equal(t, "website", config.ActionSource)
+
+	config.ActionSource = "browser"
+	require.EqualError(t, config.Validate(), "Unsupported action_source: browser")
+
+	config = &FacebookConversionAPIConfig{PixelID: "pixel", AccessToken: "token", CustomData: map[string]string{"value": ""}}
+	require.EqualError(t, config.Validate(), "custom_data.value JSON path is required")
+}
+
+func sha256Hex(value string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(value)))
+}
@@ -0,0 +1,338 @@
+package adapters
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jitsucom/jitsu/server/jsonutils"
+)
+
+const (
+	ga4CollectURL         = "https://www.google-analytics.com/mp/collect"
+	ga4ValidationURL      = "https://www.google-analytics.com/debug/mp/collect"
+	ga4MaxEventNameLength = 40
+	ga4MaxParamValueLen   = 100
+	//ga4MaxBatchEvents is Measurement Protocol limit of events per request
+	ga4MaxBatchEvents = 25
+)
+
+var (
+	//https://developers.google.com/analytics/devguides/collection/ga4/reference/events
+	ga4EventTypeMapping = map[string]string{
+		"page":             "page_view",
+		"pageview":         "page_view",
+		"app_page":         "page_view",
+		"screen":           "screen_view",
+		"screenview":       "screen_view",
+		"signup":           "sign_up",
+		"sign_up":          "sign_up",
+		"login":            "login",
+		"purchase":         "purchase",
+		"transaction":      "purchase",
+		"order_completed":  "purchase",
+		"refund":           "refund",
+		"add_to_cart":      "add_to_cart",
+		"remove_from_cart": "remove_from_cart",
+		"begin_checkout":   "begin_checkout",
+		"view_item":        "view_item",
+		"search":           "search",
+		"share":            "share",
+	}
+
+	//recommended GA4 event parameters which are copied from the event as is
+	ga4RecommendedParams = []string{"value", "currency", "transaction_id", "tax", "shipping", "coupon", "affiliation",
+		"items", "item_list_id", "item_list_name", "search_term", "method", "content_type", "item_id"}
+
+	ga4PageParams = map[string]jsonutils.JSONPath{
+		"page_location": jsonutils.NewJSONPath("/eventn_ctx/url||/url"),
+		"page_title":    jsonutils.NewJSONPath("/eventn_ctx/page_title||/page_title"),
+		"page_referrer": jsonutils.NewJSONPath("/eventn_ctx/referer||/referer"),
+	}
+
+	ga4ClientIDPath  = jsonutils.NewJSONPath("/client_id||/eventn_ctx/user/anonymous_id||/user/anonymous_id")
+	ga4UserIDPath    = jsonutils.NewJSONPath("/user_id||/eventn_ctx/user/id||/user/id")
+	ga4EventTypePath = jsonutils.NewJSONPath("/event_type")
+
+	ga4NotAllowedSymbols = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+)
+
+//GoogleAnalytics4Config is a GA4 Measurement Protocol configuration
+type GoogleAnalytics4Config struct {
+	MeasurementID string `mapstructure:"measurement_id" json:"measurement_id,omitempty" yaml:"measurement_id,omitempty"`
+	APISecret     string `mapstructure:"api_secret" json:"api_secret,omitempty" yaml:"api_secret,omitempty"`
+}
+
+//Validate returns err if some fields are empty
+func (ga4c *GoogleAnalytics4Config) Validate() error {
+	if ga4c == nil {
+		return errors.New("google_analytics4 config is required")
+	}
+	if ga4c.MeasurementID == "" {
+		return errors.New("measurement_id is required parameter")
+	}
+	if ga4c.APISecret == "" {
+		return errors.New("api_secret is required parameter")
+	}
+
+	return nil
+}
+
+//GoogleAnalytics4Event is a dto for GA4 Measurement Protocol event
+type GoogleAnalytics4Event struct {
+	Name   string                 `json:"name"`
+	Params map[string]interface{} `json:"params,omitempty"`
+}
+
+//GoogleAnalytics4Request is a dto for sending requests to GA4 Measurement Protocol
+//https://developers.google.com/analytics/devguides/collection/protocol/ga4/reference
+type GoogleAnalytics4Request struct {
+	ClientID        string                   `json:"client_id"`
+	UserID          string                   `json:"user_id,omitempty"`
+	TimestampMicros int64                    `json:"timestamp_micros,omitempty"`
+	Events          []*GoogleAnalytics4Event `json:"events"`
+}
+
+//GoogleAnalytics4ValidationResponse is a dto for parsing GA4 validation server response
+type GoogleAnalytics4ValidationResponse struct {
+	ValidationMessages []GoogleAnalytics4ValidationMessage `json:"validationMessages"`
+}
+
+//GoogleAnalytics4ValidationMessage is a dto for parsing GA4 validation message
+type GoogleAnalytics4ValidationMessage struct {
+	FieldPath      string `json:"fieldPath"`
+	Description    string `json:"description"`
+	ValidationCode string `json:"validationCode"`
+}
+
+//GoogleAnalytics4RequestFactory is a HTTPRequestFactory for GA4 Measurement Protocol
+type GoogleAnalytics4RequestFactory struct {
+	config *GoogleAnalytics4Config
+	url    string
+}
+
+//newGoogleAnalytics4RequestFactory returns configured HTTPRequestFactory for GA4 requests to collect or validation URL
+func newGoogleAnalytics4RequestFactory(config *GoogleAnalytics4Config, baseURL string) *GoogleAnalytics4RequestFactory {
+	uv := make(url.Values)
+	uv.Add("measurement_id", config.MeasurementID)
+	uv.Add("api_secret", config.APISecret)
+
+	return &GoogleAnalytics4RequestFactory{config: config, url: baseURL + "?" + uv.Encode()}
+}
+
+//Create returns HTTP POST request with GA4 Measurement Protocol JSON body
+//maps event type to GA4 recommended event and derives client_id/user_id from the event
+func (ga4rf *GoogleAnalytics4RequestFactory) Create(object map[string]interface{}) (*Request, error) {
+	req, err := ga4rf.buildRequest(object)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("Error marshalling google analytics 4 request [%v]: %v", req, err)
+	}
+
+	return &Request{
+		URL:     ga4rf.url,
+		Method:  http.MethodPost,
+		Body:    b,
+		Headers: map[string]string{"Content-Type": "application/json"},
+	}, nil
+}
+
+//buildRequest returns GA4 request with one event built from the object
+func (ga4rf *GoogleAnalytics4RequestFactory) buildRequest(object map[string]interface{}) (*GoogleAnalytics4Request, error) {
+	clientID, ok := ga4ClientIDPath.Get(object)
+	if !ok || fmt.Sprint(clientID) == "" {
+		return nil, errors.New("Object doesn't have client_id: user anonymous_id is required")
+	}
+
+	eventType, ok := ga4EventTypePath.Get(object)
+	if !ok {
+		return nil, errors.New("Object doesn't have event_type")
+	}
+
+	req := &GoogleAnalytics4Request{
+		ClientID:        fmt.Sprint(clientID),
+		TimestampMicros: eventTime(object).UnixNano() / int64(time.Microsecond),
+	}
+	if userID, ok := ga4UserIDPath.Get(object); ok && userID != nil {
+		req.UserID = fmt.Sprint(userID)
+	}
+
+	event := &GoogleAnalytics4Event{Name: ga4EventName(fmt.Sprint(eventType)), Params: map[string]interface{}{}}
+	for param, path := range ga4PageParams {
+		if value, ok := path.Get(object); ok && value != nil {
+			event.Params[param] = ga4ParamValue(value)
+		}
+	}
+	for _, param := range ga4RecommendedParams {
+		if value, ok := object[param]; ok && value != nil {
+			event.Params[param] = ga4ParamValue(value)
+		}
+	}
+	req.Events = []*GoogleAnalytics4Event{event}
+
+	return req, nil
+}
+
+//BatchKey returns key of requests with the same client_id, user_id and timestamp (up to a second)
+//Measurement Protocol has one timestamp per request, so only events of the same second are joined
+func (ga4rf *GoogleAnalytics4RequestFactory) BatchKey(req *Request) string {
+	ga4Request := &GoogleAnalytics4Request{}
+	if err := json.Unmarshal(req.Body, ga4Request); err != nil {
+		return ""
+	}
+
+	return fmt.Sprintf("%s#%s#%s#%d", req.URL, ga4Request.ClientID, ga4Request.UserID, ga4Request.TimestampMicros/int64(time.Second/time.Microsecond))
+}
+
+//CreateBatch returns one request with events from all requests and the timestamp of the first one
+func (ga4rf *GoogleAnalytics4RequestFactory) CreateBatch(requests []*Request) (*Request, error) {
+	var batch *GoogleAnalytics4Request
+	for _, r := range requests {
+		ga4Request := &GoogleAnalytics4Request{}
+		if err := json.Unmarshal(r.Body, ga4Request); err != nil {
+			return nil, fmt.Errorf("Error unmarshalling google analytics 4 request: %v", err)
+		}
+
+		if batch == nil {
+			batch = ga4Request
+		} else {
+			batch.Events = append(batch.Events, ga4Request.Events...)
+		}
+	}
+
+	b, err := json.Marshal(batch)
+	if err != nil {
+		return nil, fmt.Errorf("Error marshalling google analytics 4 batch request: %v", err)
+	}
+
+	return &Request{
+		URL:     requests[0].URL,
+		Method:  http.MethodPost,
+		Body:    b,
+		Headers: requests[0].Headers,
+	}, nil
+}
+
+//MaxBatchEvents returns Measurement Protocol limit of events per request
+func (ga4rf *GoogleAnalytics4RequestFactory) MaxBatchEvents() int {
+	return ga4MaxBatchEvents
+}
+
+//BatchItemErrors returns empty map: Measurement Protocol doesn't return errors of particular events
+func (ga4rf *GoogleAnalytics4RequestFactory) BatchItemErrors(statusCode int, responseBody []byte, batchSize int) map[int]error {
+	return map[int]error{}
+}
+
+func (ga4rf *GoogleAnalytics4RequestFactory) Close() {
+}
+
+//ga4EventName returns GA4 recommended event name or sanitized event type:
+//only letters, numbers and underscores, starts with a letter, 40 symbols max
+func ga4EventName(eventType string) string {
+	if mapped, ok := ga4EventTypeMapping[strings.ToLower(eventType)]; ok {
+		return mapped
+	}
+
+	name := ga4NotAllowedSymbols.ReplaceAllString(eventType, "_")
+	if name == "" || !(name[0] >= 'a' && name[0] <= 'z' || name[0] >= 'A' && name[0] <= 'Z') {
+		name = "event_" + name
+	}
+	if len(name) > ga4MaxEventNameLength {
+		name = name[:ga4MaxEventNameLength]
+	}
+
+	return name
+}
+
+//ga4ParamValue truncates string values up to 100 characters (GA4 limit) keeping multibyte characters whole
+func ga4ParamValue(value interface{}) interface{} {
+	str, ok := value.(string)
+	if ok && utf8.RuneCountInString(str) > ga4MaxParamValueLen {
+		return string([]rune(str)[:ga4MaxParamValueLen])
+	}
+
+	return value
+}
+
+//GoogleAnalytics4 is an adapter for sending events into GA4 via Measurement Protocol
+type GoogleAnalytics4 struct {
+	AbstractHTTP
+
+	config *GoogleAnalytics4Config
+}
+
+//NewGoogleAnalytics4 returns configured GoogleAnalytics4 instance
+func NewGoogleAnalytics4(config *GoogleAnalytics4Config, httpAdapterConfiguration *HTTPAdapterConfiguration) (*GoogleAnalytics4, error) {
+	httpAdapterConfiguration.HTTPReqFactory = newGoogleAnalytics4RequestFactory(config, ga4CollectURL)
+
+	httpAdapter, err := NewHTTPAdapter(httpAdapterConfiguration)
+	if err != nil {
+		return nil, err
+	}
+
+	ga4 := &GoogleAnalytics4{config: config}
+	ga4.httpAdapter = httpAdapter
+	return ga4, nil
+}
+
+//NewTestGoogleAnalytics4 returns test instance of adapter
+func NewTestGoogleAnalytics4(config *GoogleAnalytics4Config) *GoogleAnalytics4 {
+	return &GoogleAnalytics4{config: config}
+}
+
+//TestAccess sends test event to GA4 validation server and returns err if it has validation messages
+//the validation server doesn't check api_secret and measurement_id existence
+func (ga4 *GoogleAnalytics4) TestAccess() error {
+	httpReqFactory := newGoogleAnalytics4RequestFactory(ga4.config, ga4ValidationURL)
+	r, err := httpReqFactory.Create(map[string]interface{}{"event_type": "connection_test", "client_id": "jitsu.connection_test"})
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.DefaultClient.Post(r.URL, r.Headers["Content-Type"], bytes.NewBuffer(r.Body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	responseBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("Error reading google analytics 4 response body: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Error connecting to google analytics 4 [code=%d]: %s", resp.StatusCode, string(responseBody))
+	}
+
+	response := &GoogleAnalytics4ValidationResponse{}
+	if err := json.Unmarshal(responseBody, response); err != nil {
+		return fmt.Errorf("Error unmarshalling google analytics 4 response body: %v", err)
+	}
+
+	if len(response.ValidationMessages) > 0 {
+		var messages []string
+		for _, message := range response.ValidationMessages {
+			messages = append(messages, fmt.Sprintf("%s [%s]: %s", message.FieldPath, message.ValidationCode, message.Description))
+		}
+		return fmt.Errorf("Google analytics 4 validation error: %s", strings.Join(messages, "; "))
+	}
+
+	return nil
+}
+
+//Type returns adapter type
+func (ga4 *GoogleAnalytics4) Type() string {
+	return "GoogleAnalytics4"
+}
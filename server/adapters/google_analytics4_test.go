@@ -0,0 +1,191 @@
+package adapters
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/timestamp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGoogleAnalytics4Create(t *testing.T) {
+	factory := newGoogleAnalytics4RequestFactory(&GoogleAnalytics4Config{MeasurementID: "G-XXXX", APISecret: "se&cret"}, ga4CollectURL)
+
+	tests := []struct {
+		name        string
+		input       map[string]interface{}
+		expected    *GoogleAnalytics4Request
+		expectedErr string
+	}{
+		{
+			"Pageview with page params",
+			map[string]interface{}{
+				"event_type": "pageview",
+				"eventn_ctx": map[string]interface{}{
+					"url":        "https://jitsu.com/docs",
+					"page_title": "Docs",
+					"referer":    "https://google.com",
+					"user":       map[string]interface{}{"anonymous_id": "anon1", "id": "user1", "email": "a@b.com"},
+				},
+				timestamp.Key: "2021-12-31T10:11:12.123456Z",
+			},
+			&GoogleAnalytics4Request{
+				ClientID:        "anon1",
+				UserID:          "user1",
+				TimestampMicros: time.Date(2021, 12, 31, 10, 11, 12, 123456000, time.UTC).UnixNano() / int64(time.Microsecond),
+				Events: []*GoogleAnalytics4Event{{Name: "page_view", Params: map[string]interface{}{
+					"page_location": "https://jitsu.com/docs",
+					"page_title":    "Docs",
+					"page_referrer": "https://google.com",
+				}}},
+			},
+			"",
+		},
+		{
+			"Purchase with recommended params",
+			map[string]interface{}{
+				"event_type":     "order_completed",
+				"user":           map[string]interface{}{"anonymous_id": "anon2"},
+				"value":          10.5,
+				"currency":       "USD",
+				"transaction_id": "T1",
+				"other":          "skipped",
+				timestamp.Key:    time.Date(2021, 12, 31, 0, 0, 0, 0, time.UTC),
+			},
+			&GoogleAnalytics4Request{
+				ClientID:        "anon2",
+				TimestampMicros: time.Date(2021, 12, 31, 0, 0, 0, 0, time.UTC).UnixNano() / int64(time.Microsecond),
+				Events: []*GoogleAnalytics4Event{{Name: "purchase", Params: map[string]interface{}{
+					"value":          10.5,
+					"currency":       "USD",
+					"transaction_id": "T1",
+				}}},
+			},
+			"",
+		},
+		{
+			"Conversion is a custom event",
+			map[string]interface{}{
+				"event_type":  "conversion",
+				"client_id":   "cid",
+				"value":       10.5,
+				timestamp.Key: time.Date(2021, 12, 31, 0, 0, 0, 0, time.UTC),
+			},
+			&GoogleAnalytics4Request{
+				ClientID:        "cid",
+				TimestampMicros: time.Date(2021, 12, 31, 0, 0, 0, 0, time.UTC).UnixNano() / int64(time.Microsecond),
+				Events:          []*GoogleAnalytics4Event{{Name: "conversion", Params: map[string]interface{}{"value": 10.5}}},
+			},
+			"",
+		},
+		{
+			"Custom event name is sanitized",
+			map[string]interface{}{
+				"event_type":  "1 video-played",
+				"client_id":   "cid",
+				timestamp.Key: time.Date(2021, 12, 31, 0, 0, 0, 0, time.UTC),
+			},
+			&GoogleAnalytics4Request{
+				ClientID:        "cid",
+				TimestampMicros: time.Date(2021, 12, 31, 0, 0, 0, 0, time.UTC).UnixNano() / int64(time.Microsecond),
+				Events:          []*GoogleAnalytics4Event{{Name: "event_1_video_played", Params: map[string]interface{}{}}},
+			},
+			"",
+		},
+		{
+			"Without client id",
+			map[string]interface{}{"event_type": "pageview"},
+			nil,
+			"Object doesn't have client_id: user anonymous_id is required",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := factory.Create(tt.input)
+			if tt.expectedErr != "" {
+				require.EqualError(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, "https://www.google-analytics.com/mp/collect?api_secret=se%26cret&measurement_id=G-XXXX", r.URL)
+
+			expected, err := json.Marshal(tt.expected)
+			require.NoError(t, err)
+			require.JSONEq(t, string(expected), string(r.Body))
+		})
+	}
+}
+
+func TestGA4ParamValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    interface{}
+		expected interface{}
+	}{
+		{
+			"Not a string",
+			10.5,
+			10.5,
+		},
+		{
+			"Short string",
+			"value",
+			"value",
+		},
+		{
+			"Long ASCII string",
+			strings.Repeat("a", 150),
+			strings.Repeat("a", 100),
+		},
+		{
+			"Multibyte characters aren't split",
+			strings.Repeat("я", 99) + "€€",
+			strings.Repeat("я", 99) + "€",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, ga4ParamValue(tt.input))
+		})
+	}
+}
+
+func TestGoogleAnalytics4CreateBatch(t *testing.T) {
+	factory := newGoogleAnalytics4RequestFactory(&GoogleAnalytics4Config{MeasurementID: "G-XXXX", APISecret: "secret"}, ga4CollectURL)
+	var batchFactory HTTPBatchRequestFactory = factory
+
+	ts := time.Date(2021, 9, 1, 10, 0, 0, 100000000, time.UTC)
+	create := func(object map[string]interface{}) *Request {
+		r, err := factory.Create(object)
+		require.NoError(t, err)
+		return r
+	}
+	page := create(map[string]interface{}{"event_type": "pageview", "client_id": "c1", timestamp.Key: ts})
+	signup := create(map[string]interface{}{"event_type": "signup", "client_id": "c1", timestamp.Key: ts.Add(500 * time.Millisecond)})
+	otherClient := create(map[string]interface{}{"event_type": "signup", "client_id": "c2", timestamp.Key: ts})
+	otherUser := create(map[string]interface{}{"event_type": "signup", "client_id": "c1", "user_id": "u1", timestamp.Key: ts})
+	nextSecond := create(map[string]interface{}{"event_type": "signup", "client_id": "c1", timestamp.Key: ts.Add(time.Second)})
+
+	require.Equal(t, batchFactory.BatchKey(page), batchFactory.BatchKey(signup))
+	require.NotEqual(t, batchFactory.BatchKey(page), batchFactory.BatchKey(otherClient))
+	require.NotEqual(t, batchFactory.BatchKey(page), batchFactory.BatchKey(otherUser))
+	require.NotEqual(t, batchFactory.BatchKey(page), batchFactory.BatchKey(nextSecond))
+	require.Equal(t, "", batchFactory.BatchKey(&Request{URL: ga4CollectURL, Body: []byte("not json")}))
+	require.Equal(t, 25, batchFactory.MaxBatchEvents())
+
+	batch, err := batchFactory.CreateBatch([]*Request{page, signup})
+	require.NoError(t, err)
+	require.Equal(t, page.URL, batch.URL)
+
+	body := &GoogleAnalytics4Request{}
+	require.NoError(t, json.Unmarshal(batch.Body, body))
+	require.Equal(t, "c1", body.ClientID)
+	require.Equal(t, ts.UnixNano()/int64(time.Microsecond), body.TimestampMicros)
+	require.Len(t, body.Events, 2)
+	require.Equal(t, "page_view", body.Events[0].Name)
+	require.Equal(t, "sign_up", body.Events[1].Name)
+
+	require.Empty(t, batchFactory.BatchItemErrors(200, nil, 2))
+}
@@ -7,10 +7,12 @@ import (
 	"fmt"
 	"github.com/jitsucom/jitsu/server/schema"
 	"strings"
+	"time"
 
 	"cloud.google.com/go/storage"
 	"github.com/jitsucom/jitsu/server/logging"
 	"github.com/jitsucom/jitsu/server/timestamp"
+	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
 )
 
@@ -27,9 +29,49 @@ type GoogleConfig struct {
 	Project string      `mapstructure:"bq_project" json:"bq_project,omitempty" yaml:"bq_project,omitempty"`
 	Dataset string      `mapstructure:"bq_dataset" json:"bq_dataset,omitempty" yaml:"bq_dataset,omitempty"`
 	KeyFile interface{} `mapstructure:"key_file" json:"key_file,omitempty" yaml:"key_file,omitempty"`
+	//StorageWriteAPI is used in stream mode instead of legacy streaming inserts if configured
+	StorageWriteAPI *StorageWriteAPIConfig `mapstructure:"storage_write_api" json:"storage_write_api,omitempty" yaml:"storage_write_api,omitempty"`
 
 	//will be set on validation
 	credentials option.ClientOption
+	streamMode  bool
+}
+
+//StorageWriteAPIConfig dto for BigQuery Storage Write API configuration
+type StorageWriteAPIConfig struct {
+	Enabled bool `mapstructure:"enabled" json:"enabled,omitempty" yaml:"enabled,omitempty"`
+	//StreamType is committed (rows are visible right after append) or pending (rows are visible after commit)
+	StreamType string `mapstructure:"stream_type" json:"stream_type,omitempty" yaml:"stream_type,omitempty"`
+	//CommitRows and CommitIntervalSec are used only with pending streams: stream is committed when one of the limits is reached
+	CommitRows        int `mapstructure:"commit_rows" json:"commit_rows,omitempty" yaml:"commit_rows,omitempty"`
+	CommitIntervalSec int `mapstructure:"commit_interval_sec" json:"commit_interval_sec,omitempty" yaml:"commit_interval_sec,omitempty"`
+	//BatchSize is max number of queued events which are appended with one request per table
+	BatchSize int `mapstructure:"batch_size" json:"batch_size,omitempty" yaml:"batch_size,omitempty"`
+	//Endpoint overrides Storage Write API endpoint (e.g. local emulator host:port without TLS and authorization)
+	Endpoint string `mapstructure:"endpoint" json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+}
+
+//Validate returns err if stream type is unknown and sets default values
+func (swc *StorageWriteAPIConfig) Validate() error {
+	switch swc.StreamType {
+	case "":
+		swc.StreamType = CommittedWriteStream
+	case CommittedWriteStream, PendingWriteStream:
+	default:
+		return fmt.Errorf("Unknown storage_write_api.stream_type: %s. Supported: %s, %s", swc.StreamType, CommittedWriteStream, PendingWriteStream)
+	}
+
+	if swc.CommitRows <= 0 {
+		swc.CommitRows = defaultWriteStreamCommitRows
+	}
+	if swc.CommitIntervalSec <= 0 {
+		swc.CommitIntervalSec = defaultWriteStreamCommitIntervalSec
+	}
+	if swc.BatchSize <= 0 {
+		swc.BatchSize = defaultWriteStreamBatchSize
+	}
+
+	return nil
 }
 
 func (gc *GoogleConfig) Validate(streamMode bool) error {
@@ -40,6 +82,13 @@ func (gc *GoogleConfig) Validate(streamMode bool) error {
 	if !streamMode && gc.Bucket == "" {
 		return errors.New("Google cloud storage bucket(gcs_bucket) is required parameter")
 	}
+	gc.streamMode = streamMode
+
+	if gc.StorageWriteAPI != nil {
+		if err := gc.StorageWriteAPI.Validate(); err != nil {
+			return err
+		}
+	}
 
 	if gc.Dataset != "" {
 		if len(gc.Dataset) > 1024 {
@@ -128,6 +177,38 @@ func (gcs *GoogleCloudStorage) DeleteObject(key string) error {
 	return nil
 }
 
+//DeleteObjectsOlderThan deletes objects under the prefix which were updated before the time
+//if dryRun is true - objects are only listed. Returns names of deleted objects
+func (gcs *GoogleCloudStorage) DeleteObjectsOlderThan(prefix string, before time.Time, dryRun bool) ([]string, error) {
+	var names []string
+	objects := gcs.client.Bucket(gcs.config.Bucket).Objects(gcs.ctx, &storage.Query{Prefix: prefix})
+	for {
+		attrs, err := objects.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Error listing google cloud storage objects in bucket %s with prefix %s: %v", gcs.config.Bucket, prefix, err)
+		}
+
+		if attrs.Updated.Before(before) {
+			names = append(names, attrs.Name)
+		}
+	}
+
+	if dryRun {
+		return names, nil
+	}
+
+	for i, name := range names {
+		if err := gcs.DeleteObject(name); err != nil {
+			return names[:i], err
+		}
+	}
+
+	return names, nil
+}
+
 //ValidateWritePermission tries to create temporary file and remove it.
 //returns nil if file creation was successful.
 func (gcs *GoogleCloudStorage) ValidateWritePermission() error {
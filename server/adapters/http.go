@@ -2,9 +2,12 @@ package adapters
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/metrics"
 	"github.com/jitsucom/jitsu/server/safego"
 	"github.com/panjf2000/ants/v2"
 	"go.uber.org/atomic"
@@ -14,6 +17,9 @@ import (
 	"time"
 )
 
+//observerPauseInterval is a max sleep interval of the queue observer while destination is paused
+const observerPauseInterval = time.Second
+
 //HTTPAdapterConfiguration is a dto for creating HTTPAdapter
 type HTTPAdapterConfiguration struct {
 	DestinationID  string
@@ -24,6 +30,27 @@ type HTTPAdapterConfiguration struct {
 	DebugLogger    *logging.QueryLogger
 	ErrorHandler   func(fallback bool, eventContext *EventContext, err error)
 	SuccessHandler func(eventContext *EventContext)
+	Batch          *HTTPBatchConfig
+	RateLimit      *HTTPRateLimitConfig
+	CircuitBreaker *HTTPCircuitBreakerConfig
+	//TLSConfig is used for client certificate (mTLS). Optional
+	TLSConfig *tls.Config
+	//Authenticator signs or authorizes every HTTP request before sending. Optional
+	Authenticator HTTPRequestAuthenticator
+}
+
+//HTTPAdapterStatus is a dto for HTTP adapter runtime status
+type HTTPAdapterStatus struct {
+	QueueSize      uint64                `json:"queue_size"`
+	CircuitBreaker *CircuitBreakerStatus `json:"circuit_breaker,omitempty"`
+	RateLimit      *RateLimitStatus      `json:"rate_limit,omitempty"`
+}
+
+//httpResponse is a dto for HTTP response code, body and headers
+type httpResponse struct {
+	statusCode int
+	body       []byte
+	header     http.Header
 }
 
 //HTTPConfiguration is a dto for HTTP adapter (client) configuration
@@ -46,6 +73,14 @@ type HTTPAdapter struct {
 	queue          *PersistentQueue
 	debugLogger    *logging.QueryLogger
 	httpReqFactory HTTPRequestFactory
+	//batchFactory isn't nil only if batching is enabled
+	batchFactory HTTPBatchRequestFactory
+	batchConfig  *HTTPBatchConfig
+	//circuitBreaker is nil if it is disabled
+	circuitBreaker *circuitBreaker
+	rateLimiter    *rateLimiter
+	//authenticator is nil if requests don't require authentication
+	authenticator HTTPRequestAuthenticator
 
 	errorHandler   func(fallback bool, eventContext *EventContext, err error)
 	successHandler func(eventContext *EventContext)
@@ -56,17 +91,30 @@ type HTTPAdapter struct {
 	//when reached - requests can't be retried => fallback
 	queueFullnessThreshold uint64
 
+	ctx    context.Context
+	cancel context.CancelFunc
 	closed *atomic.Bool
 }
 
 //NewHTTPAdapter returns configured HTTPAdapter and starts queue observing goroutine
 func NewHTTPAdapter(config *HTTPAdapterConfiguration) (*HTTPAdapter, error) {
+	if err := config.Batch.Validate(); err != nil {
+		return nil, err
+	}
+	if err := config.RateLimit.Validate(); err != nil {
+		return nil, err
+	}
+	if err := config.CircuitBreaker.Validate(); err != nil {
+		return nil, err
+	}
+
 	httpAdapter := &HTTPAdapter{
 		client: &http.Client{
 			Timeout: config.HTTPConfig.GlobalClientTimeout,
 			Transport: &http.Transport{
 				MaxIdleConns:        config.HTTPConfig.ClientMaxIdleConns,
 				MaxIdleConnsPerHost: config.HTTPConfig.ClientMaxIdleConnsPerHost,
+				TLSClientConfig:     config.TLSConfig,
 			},
 		},
 		debugLogger:    config.DebugLogger,
@@ -79,8 +127,22 @@ func NewHTTPAdapter(config *HTTPAdapterConfiguration) (*HTTPAdapter, error) {
 		retryCount:             config.HTTPConfig.RetryCount,
 		retryDelay:             config.HTTPConfig.RetryDelay,
 		queueFullnessThreshold: config.HTTPConfig.QueueFullnessThreshold,
+		circuitBreaker:         newCircuitBreaker(config.DestinationID, config.CircuitBreaker),
+		rateLimiter:            newRateLimiter(config.RateLimit),
+		authenticator:          config.Authenticator,
 		closed:                 atomic.NewBool(false),
 	}
+	httpAdapter.ctx, httpAdapter.cancel = context.WithCancel(context.Background())
+
+	if config.Batch != nil && config.Batch.Enabled {
+		batchFactory, ok := config.HTTPReqFactory.(HTTPBatchRequestFactory)
+		if ok {
+			httpAdapter.batchFactory = batchFactory
+			httpAdapter.batchConfig = config.Batch
+		} else {
+			logging.Warnf("[%s] destination doesn't support HTTP batching. Events will be sent one by one", config.DestinationID)
+		}
+	}
 
 	reqQueue, err := NewPersistentQueue("http_queue.dst="+config.DestinationID, config.Dir)
 	if err != nil {
@@ -101,6 +163,7 @@ func NewHTTPAdapter(config *HTTPAdapterConfiguration) (*HTTPAdapter, error) {
 }
 
 //startObserver run goroutine for polling from the queue and executes Request
+//requests are kept in the queue while destination rate limit is paused or circuit breaker is open
 func (h *HTTPAdapter) startObserver() {
 	safego.RunWithRestart(func() {
 		for {
@@ -108,6 +171,16 @@ func (h *HTTPAdapter) startObserver() {
 				break
 			}
 
+			if pause := h.rateLimiter.PausedFor(); pause > 0 {
+				time.Sleep(minDuration(pause, observerPauseInterval))
+				continue
+			}
+
+			if !h.circuitBreaker.Ready() {
+				time.Sleep(observerPauseInterval)
+				continue
+			}
+
 			if h.workersPool.Free() > 0 {
 				retryableRequest, err := h.queue.DequeueBlock()
 				if err != nil {
@@ -127,15 +200,19 @@ func (h *HTTPAdapter) startObserver() {
 
 					continue
 				}
-				if err := h.workersPool.Invoke(retryableRequest); err != nil {
-					if err != ants.ErrPoolClosed {
-						logging.SystemErrorf("[%s] Error invoking HTTP request task: %v", h.destinationID, err)
-					}
 
-					if err := h.queue.AddRequest(retryableRequest); err != nil {
-						logging.SystemErrorf("[%s] Error enqueueing HTTP request after invoking: %v", h.destinationID, err)
-						h.errorHandler(true, retryableRequest.EventContext, err)
+				if h.batchFactory == nil {
+					h.invoke(retryableRequest, retryableRequest)
+					continue
+				}
+
+				for _, batch := range h.collectBatches(retryableRequest) {
+					//only one probe batch is sent if circuit breaker is half open
+					if !h.circuitBreaker.Ready() {
+						h.putBack(batch.requests...)
+						continue
 					}
+					h.invoke(batch, batch.requests...)
 				}
 			} else {
 				time.Sleep(time.Millisecond * 50)
@@ -144,6 +221,89 @@ func (h *HTTPAdapter) startObserver() {
 	})
 }
 
+//invoke waits for rate limiter and runs task (request or batch) in workers pool
+//puts requests back to the queue if task can't be invoked
+func (h *HTTPAdapter) invoke(task interface{}, retryableRequests ...*RetryableRequest) {
+	if err := h.rateLimiter.Wait(h.ctx); err != nil {
+		h.putBack(retryableRequests...)
+		return
+	}
+
+	h.circuitBreaker.Acquire()
+	if err := h.workersPool.Invoke(task); err != nil {
+		h.circuitBreaker.Release()
+		if err != ants.ErrPoolClosed {
+			logging.SystemErrorf("[%s] Error invoking HTTP request task: %v", h.destinationID, err)
+		}
+
+		h.putBack(retryableRequests...)
+	}
+}
+
+//putBack puts requests back to the queue as is. Requests are sent to fallback if they can't be enqueued
+func (h *HTTPAdapter) putBack(retryableRequests ...*RetryableRequest) {
+	for _, retryableRequest := range retryableRequests {
+		if err := h.queue.AddRequest(retryableRequest); err != nil {
+			logging.SystemErrorf("[%s] Error enqueueing HTTP request after invoking: %v", h.destinationID, err)
+			h.errorHandler(true, retryableRequest.EventContext, err)
+		}
+	}
+}
+
+//postpone puts request back to the queue without retry counting (destination is rate limited or unavailable)
+//request is sent to fallback if queue size is greater than threshold
+func (h *HTTPAdapter) postpone(retryableRequest *RetryableRequest, sendErr error) {
+	if h.queueFullnessThreshold > 0 && h.queue.Size() >= h.queueFullnessThreshold {
+		logging.Errorf("[%s] HTTP request can't be postponed: queue size [%d] has reached the threshold [%d]: %v", h.destinationID, h.queue.Size(), h.queueFullnessThreshold, sendErr)
+		h.errorHandler(true, retryableRequest.EventContext, sendErr)
+		return
+	}
+
+	retryableRequest.DequeuedTime = time.Now().UTC()
+	if err := h.queue.AddRequest(retryableRequest); err != nil {
+		logging.SystemErrorf("[%s] Error enqueueing HTTP request after sending: %v", h.destinationID, err)
+		h.errorHandler(true, retryableRequest.EventContext, sendErr)
+		return
+	}
+
+	h.errorHandler(false, retryableRequest.EventContext, sendErr)
+}
+
+//handleResponse applies rate limit response headers and updates circuit breaker state
+//returns true if the request should be postponed: destination responded with 429 code or circuit breaker is open
+func (h *HTTPAdapter) handleResponse(resp *httpResponse, err error) bool {
+	if resp != nil {
+		if pause := rateLimitPause(resp.header, time.Now()); pause > 0 {
+			h.rateLimiter.Pause(pause)
+		}
+
+		//access token might be revoked before expiration
+		if resp.statusCode == http.StatusUnauthorized && h.authenticator != nil {
+			h.authenticator.Invalidate()
+		}
+
+		if resp.statusCode == http.StatusTooManyRequests {
+			metrics.RateLimitedRequest(h.destinationID)
+			h.rateLimiter.Throttle()
+			if h.rateLimiter.PausedFor() <= 0 {
+				h.rateLimiter.Pause(h.retryDelay)
+			}
+			//destination is available
+			h.circuitBreaker.Success()
+			return true
+		}
+	}
+
+	//network errors and 5xx codes mean that destination is unavailable
+	if err != nil && (resp == nil || resp.statusCode >= http.StatusInternalServerError) {
+		return h.circuitBreaker.Failure(err)
+	}
+
+	h.circuitBreaker.Success()
+	h.rateLimiter.Recover()
+	return false
+}
+
 //SendAsync puts request to the queue
 //returns err if can't put to the queue
 func (h *HTTPAdapter) SendAsync(eventContext *EventContext) error {
@@ -156,12 +316,17 @@ func (h *HTTPAdapter) SendAsync(eventContext *EventContext) error {
 }
 
 func (h *HTTPAdapter) sendRequestWithRetry(i interface{}) {
-	retryableRequest, ok := i.(*RetryableRequest)
-	if !ok {
+	switch task := i.(type) {
+	case *RetryableRequest:
+		h.sendRequest(task)
+	case *httpBatch:
+		h.sendBatch(task)
+	default:
 		logging.SystemErrorf("HTTP webhook request has unknown type: %T", i)
-		return
 	}
+}
 
+func (h *HTTPAdapter) sendRequest(retryableRequest *RetryableRequest) {
 	retries := ""
 	if retryableRequest.Retry > 0 {
 		retries = fmt.Sprintf(" with %d retry", retryableRequest.Retry)
@@ -171,9 +336,14 @@ func (h *HTTPAdapter) sendRequestWithRetry(i interface{}) {
 
 	h.debugLogger.LogQueryWithValues(debugQuery, []interface{}{string(retryableRequest.Request.Body)})
 
-	err := h.doRequest(retryableRequest.Request)
+	resp, err := h.doRequestWithResponse(retryableRequest.Request)
+	postpone := h.handleResponse(resp, err)
 	if err != nil {
-		h.doRetry(retryableRequest, err)
+		if postpone {
+			h.postpone(retryableRequest, err)
+		} else {
+			h.doRetry(retryableRequest, err)
+		}
 	} else {
 		retryableRequest.EventContext.HTTPRequest = retryableRequest.Request
 		h.successHandler(retryableRequest.EventContext)
@@ -209,7 +379,9 @@ func (h *HTTPAdapter) doRetry(retryableRequest *RetryableRequest, sendErr error)
 	h.errorHandler(true, retryableRequest.EventContext, sendErr)
 }
 
-func (h *HTTPAdapter) doRequest(req *Request) error {
+//doRequestWithResponse sends request and returns HTTP response (nil if request hasn't been sent)
+//returns err if HTTP response code isn't 2xx as well
+func (h *HTTPAdapter) doRequestWithResponse(req *Request) (*httpResponse, error) {
 	var httpReq *http.Request
 	var err error
 	if req.Body != nil && len(req.Body) > 0 {
@@ -219,30 +391,37 @@ func (h *HTTPAdapter) doRequest(req *Request) error {
 	}
 
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	for header, value := range req.Headers {
 		httpReq.Header.Add(header, value)
 	}
 
+	if h.authenticator != nil {
+		if err := h.authenticator.Authenticate(httpReq, req.Body); err != nil {
+			return nil, fmt.Errorf("Error authenticating HTTP request: %v", err)
+		}
+	}
+
 	resp, err := h.client.Do(httpReq)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	if resp != nil && resp.Body != nil {
+	var responseBody []byte
+	var readErr error
+	if resp.Body != nil {
 		defer resp.Body.Close()
+		responseBody, readErr = ioutil.ReadAll(resp.Body)
 	}
 
 	//check HTTP response code
 	if resp.StatusCode < 200 || resp.StatusCode > 299 {
-		//read response body
 		responsePayload := "no HTTP response body"
-		if resp != nil && resp.Body != nil {
-			responseBody, err := ioutil.ReadAll(resp.Body)
-			if err != nil {
-				responsePayload = fmt.Sprintf("[%s] Error reading HTTP response body: %v", h.destinationID, err)
+		if resp.Body != nil {
+			if readErr != nil {
+				responsePayload = fmt.Sprintf("[%s] Error reading HTTP response body: %v", h.destinationID, readErr)
 			} else {
 				responsePayload = string(responseBody)
 			}
@@ -250,16 +429,26 @@ func (h *HTTPAdapter) doRequest(req *Request) error {
 
 		headers, _ := json.MarshalIndent(resp.Header, " ", " ")
 
-		return fmt.Errorf("HTTP Response status code: [%d],\n\tResponse body: [%s],\n\tResponse headers: [%s]", resp.StatusCode, responsePayload, string(headers))
+		return &httpResponse{statusCode: resp.StatusCode, body: responseBody, header: resp.Header}, fmt.Errorf("HTTP Response status code: [%d],\n\tResponse body: [%s],\n\tResponse headers: [%s]", resp.StatusCode, responsePayload, string(headers))
 	}
 
-	return nil
+	return &httpResponse{statusCode: resp.StatusCode, body: responseBody, header: resp.Header}, nil
+}
+
+//Status returns queue size, circuit breaker and rate limiter states
+func (h *HTTPAdapter) Status() *HTTPAdapterStatus {
+	return &HTTPAdapterStatus{
+		QueueSize:      h.queue.Size(),
+		CircuitBreaker: h.circuitBreaker.Status(),
+		RateLimit:      h.rateLimiter.Status(),
+	}
 }
 
 //Close closes underlying queue, workers pool and HTTP client
 //returns err if occurred
 func (h *HTTPAdapter) Close() (err error) {
 	h.closed.Store(true)
+	h.cancel()
 	h.httpReqFactory.Close()
 	err = h.queue.Close()
 
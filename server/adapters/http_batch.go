@@ -0,0 +1,189 @@
+package adapters
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/logging"
+)
+
+const (
+	defaultBatchMaxEvents    = 100
+	defaultBatchMaxSizeBytes = 1024 * 1024
+	defaultBatchLingerMs     = 1000
+
+	batchLingerPollInterval = 50 * time.Millisecond
+)
+
+//HTTPBatchConfig is a dto for parsing batching configuration of HTTP destinations
+//requests are grouped until one of the limits is reached: events count, body size or linger time
+type HTTPBatchConfig struct {
+	Enabled      bool `mapstructure:"enabled" json:"enabled,omitempty" yaml:"enabled,omitempty"`
+	MaxEvents    int  `mapstructure:"max_events" json:"max_events,omitempty" yaml:"max_events,omitempty"`
+	MaxSizeBytes int  `mapstructure:"max_size_bytes" json:"max_size_bytes,omitempty" yaml:"max_size_bytes,omitempty"`
+	LingerMs     int  `mapstructure:"linger_ms" json:"linger_ms,omitempty" yaml:"linger_ms,omitempty"`
+}
+
+//Validate returns err if invalid and sets default values
+func (hbc *HTTPBatchConfig) Validate() error {
+	if hbc == nil || !hbc.Enabled {
+		return nil
+	}
+
+	if hbc.MaxEvents < 0 || hbc.MaxSizeBytes < 0 || hbc.LingerMs < 0 {
+		return errors.New("http_batch max_events, max_size_bytes and linger_ms must be positive")
+	}
+	if hbc.MaxEvents == 0 {
+		hbc.MaxEvents = defaultBatchMaxEvents
+	}
+	if hbc.MaxSizeBytes == 0 {
+		hbc.MaxSizeBytes = defaultBatchMaxSizeBytes
+	}
+	if hbc.LingerMs == 0 {
+		hbc.LingerMs = defaultBatchLingerMs
+	}
+
+	return nil
+}
+
+//HTTPBatchRequestFactory is a HTTPRequestFactory which can join requests created by Create() into one batch request
+//(e.g. events array in body). It is used by HTTPAdapter if batching is enabled
+type HTTPBatchRequestFactory interface {
+	HTTPRequestFactory
+
+	//BatchKey returns key of requests which can be joined into one batch (e.g. the same URL)
+	//empty key means that request is always sent alone
+	BatchKey(req *Request) string
+	//CreateBatch returns one request which contains all input requests (all of them have the same BatchKey)
+	CreateBatch(requests []*Request) (*Request, error)
+	//MaxBatchEvents returns max events count in one batch request which is accepted by destination API (0 - unlimited)
+	MaxBatchEvents() int
+	//BatchItemErrors returns errors of particular batch items by index parsed from the HTTP response
+	//these items are sent to fallback, other items are succeeded (2xx code) or retried
+	BatchItemErrors(statusCode int, responseBody []byte, batchSize int) map[int]error
+}
+
+//httpBatch is a group of queued requests which are sent as one HTTP request
+type httpBatch struct {
+	requests []*RetryableRequest
+}
+
+//collectBatches dequeues ready requests (after the first one) until events count, size or linger time limit is reached
+//and returns them grouped into batches by BatchKey. Requests with empty BatchKey are returned as separate batches
+func (h *HTTPAdapter) collectBatches(first *RetryableRequest) []*httpBatch {
+	maxEvents := h.batchConfig.MaxEvents
+	if factoryMax := h.batchFactory.MaxBatchEvents(); factoryMax > 0 && factoryMax < maxEvents {
+		maxEvents = factoryMax
+	}
+
+	collected := []*RetryableRequest{first}
+	size := len(first.Request.Body)
+	deadline := time.Now().Add(time.Duration(h.batchConfig.LingerMs) * time.Millisecond)
+	for len(collected) < maxEvents && size < h.batchConfig.MaxSizeBytes && !h.closed.Load() {
+		retryableRequest, err := h.queue.Dequeue()
+		if err != nil {
+			if err != ErrQueueEmpty {
+				if err != ErrQueueClosed {
+					logging.SystemErrorf("[%s] Error reading HTTP request from the queue: %v", h.destinationID, err)
+				}
+				break
+			}
+
+			remaining := time.Until(deadline)
+			if remaining <= 0 {
+				break
+			}
+			if remaining > batchLingerPollInterval {
+				remaining = batchLingerPollInterval
+			}
+			time.Sleep(remaining)
+			continue
+		}
+
+		//retry timeout hasn't come or request doesn't fit the batch size: put it back and send the batch
+		if time.Now().UTC().Before(retryableRequest.DequeuedTime) || size+len(retryableRequest.Request.Body) > h.batchConfig.MaxSizeBytes {
+			if err := h.queue.AddRequest(retryableRequest); err != nil {
+				logging.SystemErrorf("[%s] Error enqueueing HTTP request after dequeuing: %v", h.destinationID, err)
+				h.errorHandler(true, retryableRequest.EventContext, err)
+			}
+			break
+		}
+
+		collected = append(collected, retryableRequest)
+		size += len(retryableRequest.Request.Body)
+	}
+
+	var batches []*httpBatch
+	batchesByKey := map[string]*httpBatch{}
+	for _, retryableRequest := range collected {
+		key := h.batchFactory.BatchKey(retryableRequest.Request)
+		if key == "" {
+			batches = append(batches, &httpBatch{requests: []*RetryableRequest{retryableRequest}})
+			continue
+		}
+
+		batch, ok := batchesByKey[key]
+		if !ok {
+			batch = &httpBatch{}
+			batchesByKey[key] = batch
+			batches = append(batches, batch)
+		}
+		batch.requests = append(batch.requests, retryableRequest)
+	}
+
+	return batches
+}
+
+//sendBatch sends requests as one batch request
+//all requests are retried (or postponed) if batch request failed. Batch items with errors (parsed from the response) are sent to fallback
+func (h *HTTPAdapter) sendBatch(batch *httpBatch) {
+	if len(batch.requests) == 1 {
+		h.sendRequest(batch.requests[0])
+		return
+	}
+
+	requests := make([]*Request, len(batch.requests))
+	for i, retryableRequest := range batch.requests {
+		requests[i] = retryableRequest.Request
+	}
+
+	batchRequest, err := h.batchFactory.CreateBatch(requests)
+	if err != nil {
+		logging.Errorf("[%s] Error creating HTTP batch request from %d requests: %v. Requests will be sent one by one", h.destinationID, len(requests), err)
+		for _, retryableRequest := range batch.requests {
+			h.sendRequest(retryableRequest)
+		}
+		return
+	}
+
+	debugQuery := fmt.Sprintf("%s %s. Headers: %v batch of %d requests", batchRequest.Method, batchRequest.URL, batchRequest.Headers, len(requests))
+	h.debugLogger.LogQueryWithValues(debugQuery, []interface{}{string(batchRequest.Body)})
+
+	resp, err := h.doRequestWithResponse(batchRequest)
+	postpone := h.handleResponse(resp, err)
+	itemErrors := map[int]error{}
+	if resp != nil && !postpone {
+		itemErrors = h.batchFactory.BatchItemErrors(resp.statusCode, resp.body, len(requests))
+	}
+
+	for i, retryableRequest := range batch.requests {
+		if itemErr, ok := itemErrors[i]; ok {
+			logging.Errorf("[%s] Error sending HTTP request URL: [%s] Method: [%s] Body: [%s] in batch: %v", h.destinationID, retryableRequest.Request.URL, retryableRequest.Request.Method, string(retryableRequest.Request.Body), itemErr)
+			h.errorHandler(true, retryableRequest.EventContext, itemErr)
+			continue
+		}
+
+		if err != nil {
+			if postpone {
+				h.postpone(retryableRequest, err)
+			} else {
+				h.doRetry(retryableRequest, err)
+			}
+			continue
+		}
+
+		retryableRequest.EventContext.HTTPRequest = retryableRequest.Request
+		h.successHandler(retryableRequest.EventContext)
+	}
+}
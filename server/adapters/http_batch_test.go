@@ -0,0 +1,138 @@
+package adapters
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAmplitudeCreateBatch(t *testing.T) {
+	factory, err := newAmplitudeRequestFactory("key")
+	require.NoError(t, err)
+	batchFactory := factory.(HTTPBatchRequestFactory)
+
+	var requests []*Request
+	for _, eventType := range []string{"pageview", "conversion"} {
+		r, err := factory.Create(map[string]interface{}{"event_type": eventType})
+		require.NoError(t, err)
+		requests = append(requests, r)
+	}
+
+	require.Equal(t, batchFactory.BatchKey(requests[0]), batchFactory.BatchKey(requests[1]))
+
+	batch, err := batchFactory.CreateBatch(requests)
+	require.NoError(t, err)
+	require.Equal(t, amplitudeBatchAPIURL, batch.URL)
+	require.JSONEq(t, `{"api_key":"key","events":[{"event_type":"pageview"},{"event_type":"conversion"}]}`, string(batch.Body))
+
+	itemErrors := batchFactory.BatchItemErrors(http.StatusBadRequest,
+		[]byte(`{"code":400,"error":"Request missing required field","events_with_missing_fields":{"user_id":[1,5]}}`), 2)
+	require.Len(t, itemErrors, 1)
+	require.EqualError(t, itemErrors[1], "Amplitude event doesn't have required field [user_id]: Request missing required field")
+
+	require.Empty(t, batchFactory.BatchItemErrors(http.StatusOK, []byte(`{"code":200}`), 2))
+}
+
+func TestFacebookCreateBatch(t *testing.T) {
+	factory := newFacebookRequestFactory(&FacebookConversionAPIConfig{PixelID: "pixel", AccessToken: "token", ActionSource: "website"})
+
+	first, err := factory.Create(map[string]interface{}{"event_name": "pageview", "event_time": 1})
+	require.NoError(t, err)
+	second, err := factory.Create(map[string]interface{}{"event_name": "signup", "event_time": 2})
+	require.NoError(t, err)
+	withTestCode, err := factory.Create(map[string]interface{}{"event_name": "signup", "event_time": 2, "test_event_code": "TEST1"})
+	require.NoError(t, err)
+
+	require.Equal(t, factory.BatchKey(first), factory.BatchKey(second))
+	require.NotEqual(t, factory.BatchKey(first), factory.BatchKey(withTestCode))
+
+	batch, err := factory.CreateBatch([]*Request{first, second})
+	require.NoError(t, err)
+	require.Equal(t, first.URL, batch.URL)
+
+	body := &FacebookConversionEventsReq{}
+	require.NoError(t, json.Unmarshal(batch.Body, body))
+	require.Len(t, body.Data, 2)
+	require.Equal(t, "PageView", body.Data[0]["event_name"])
+	require.Equal(t, "CompleteRegistration", body.Data[1]["event_name"])
+}
+
+func TestWebhookBatchKey(t *testing.T) {
+	factory := &WebhookRequestFactory{}
+	headers := map[string]string{"Content-Type": "application/json"}
+
+	tests := []struct {
+		name     string
+		request  *Request
+		batchKey string
+	}{
+		{"JSON body", &Request{URL: "https://hook", Method: http.MethodPost, Body: []byte(`{"a":1}`), Headers: headers}, "POST https://hook Content-Type=application/json"},
+		{"GET request", &Request{URL: "https://hook", Method: http.MethodGet, Body: []byte(`{"a":1}`), Headers: headers}, ""},
+		{"Not JSON body", &Request{URL: "https://hook", Method: http.MethodPost, Body: []byte(`a=1`), Headers: headers}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.batchKey, factory.BatchKey(tt.request))
+		})
+	}
+}
+
+func TestHTTPAdapterBatching(t *testing.T) {
+	var mutex sync.Mutex
+	var batchSizes []int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		var items []map[string]interface{}
+		require.NoError(t, json.Unmarshal(body, &items))
+
+		mutex.Lock()
+		batchSizes = append(batchSizes, len(items))
+		mutex.Unlock()
+	}))
+	defer server.Close()
+
+	factory, err := NewWebhookRequestFactory("test", "webhook", http.MethodPost, server.URL, "{{ .id }}", map[string]string{})
+	require.NoError(t, err)
+
+	succeeded := make(chan string, 10)
+	adapter, err := NewHTTPAdapter(&HTTPAdapterConfiguration{
+		DestinationID:  "test",
+		Dir:            t.TempDir(),
+		HTTPConfig:     &HTTPConfiguration{GlobalClientTimeout: time.Second, RetryDelay: time.Second, RetryCount: 0},
+		HTTPReqFactory: factory,
+		PoolWorkers:    1,
+		DebugLogger:    &logging.QueryLogger{},
+		ErrorHandler: func(fallback bool, eventContext *EventContext, err error) {
+			t.Errorf("Event %s hasn't been sent: %v", eventContext.EventID, err)
+		},
+		SuccessHandler: func(eventContext *EventContext) {
+			succeeded <- eventContext.EventID
+		},
+		Batch: &HTTPBatchConfig{Enabled: true, MaxEvents: 3, LingerMs: 500},
+	})
+	require.NoError(t, err)
+	defer adapter.Close()
+
+	for _, id := range []string{"1", "2", "3", "4", "5"} {
+		require.NoError(t, adapter.SendAsync(&EventContext{EventID: id, ProcessedEvent: map[string]interface{}{"id": `{"id":` + id + `}`}}))
+	}
+
+	for i := 0; i < 5; i++ {
+		select {
+		case <-succeeded:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timeout waiting for batches")
+		}
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	require.Equal(t, []int{3, 2}, batchSizes)
+}
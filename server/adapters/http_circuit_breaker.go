@@ -0,0 +1,244 @@
+package adapters
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/metrics"
+	"github.com/jitsucom/jitsu/server/timestamp"
+)
+
+const (
+	CircuitBreakerClosed   = "closed"
+	CircuitBreakerHalfOpen = "half_open"
+	CircuitBreakerOpen     = "open"
+
+	defaultCircuitBreakerFailureThreshold = 10
+	defaultCircuitBreakerOpenTimeoutSec   = 30
+	defaultCircuitBreakerMaxOpenSec       = 600
+)
+
+//circuitBreakerMetricValues are values of circuit breaker state metric
+var circuitBreakerMetricValues = map[string]int{
+	CircuitBreakerClosed:   0,
+	CircuitBreakerHalfOpen: 1,
+	CircuitBreakerOpen:     2,
+}
+
+//HTTPCircuitBreakerConfig is a dto for parsing HTTP destination circuit breaker configuration
+//circuit breaker is disabled if it isn't configured or isn't enabled. It is opened after failure_threshold consecutive failed requests (network errors or 5xx codes)
+//and is half opened (one probe request) after open_timeout_sec. Every failed probe doubles the timeout up to max_open_timeout_sec
+type HTTPCircuitBreakerConfig struct {
+	Enabled           bool `mapstructure:"enabled" json:"enabled,omitempty" yaml:"enabled,omitempty"`
+	FailureThreshold  int  `mapstructure:"failure_threshold" json:"failure_threshold,omitempty" yaml:"failure_threshold,omitempty"`
+	OpenTimeoutSec    int  `mapstructure:"open_timeout_sec" json:"open_timeout_sec,omitempty" yaml:"open_timeout_sec,omitempty"`
+	MaxOpenTimeoutSec int  `mapstructure:"max_open_timeout_sec" json:"max_open_timeout_sec,omitempty" yaml:"max_open_timeout_sec,omitempty"`
+}
+
+//Validate returns err if invalid and sets default values
+func (hcbc *HTTPCircuitBreakerConfig) Validate() error {
+	if hcbc == nil || !hcbc.Enabled {
+		return nil
+	}
+
+	if hcbc.FailureThreshold < 0 || hcbc.OpenTimeoutSec < 0 || hcbc.MaxOpenTimeoutSec < 0 {
+		return errors.New("http_circuit_breaker failure_threshold, open_timeout_sec and max_open_timeout_sec must be positive")
+	}
+	if hcbc.FailureThreshold == 0 {
+		hcbc.FailureThreshold = defaultCircuitBreakerFailureThreshold
+	}
+	if hcbc.OpenTimeoutSec == 0 {
+		hcbc.OpenTimeoutSec = defaultCircuitBreakerOpenTimeoutSec
+	}
+	if hcbc.MaxOpenTimeoutSec == 0 {
+		hcbc.MaxOpenTimeoutSec = defaultCircuitBreakerMaxOpenSec
+	}
+	if hcbc.MaxOpenTimeoutSec < hcbc.OpenTimeoutSec {
+		hcbc.MaxOpenTimeoutSec = hcbc.OpenTimeoutSec
+	}
+
+	return nil
+}
+
+//CircuitBreakerStatus is a dto for circuit breaker status
+type CircuitBreakerStatus struct {
+	State               string `json:"state"`
+	ConsecutiveFailures int    `json:"consecutive_failures"`
+	OpenedAt            string `json:"opened_at,omitempty"`
+	OpenUntil           string `json:"open_until,omitempty"`
+	LastError           string `json:"last_error,omitempty"`
+}
+
+//circuitBreaker pauses sending HTTP requests while destination is down
+//nil circuitBreaker (disabled) is always closed
+type circuitBreaker struct {
+	sync.Mutex
+
+	destinationID    string
+	failureThreshold int
+	openTimeout      time.Duration
+	maxOpenTimeout   time.Duration
+
+	state         string
+	failures      int
+	currentOpen   time.Duration
+	openedAt      time.Time
+	openUntil     time.Time
+	probeInFlight bool
+	lastError     string
+}
+
+//newCircuitBreaker returns nil if circuit breaker is disabled
+func newCircuitBreaker(destinationID string, config *HTTPCircuitBreakerConfig) *circuitBreaker {
+	if config == nil || !config.Enabled {
+		return nil
+	}
+
+	cb := &circuitBreaker{
+		destinationID:    destinationID,
+		failureThreshold: config.FailureThreshold,
+		openTimeout:      time.Duration(config.OpenTimeoutSec) * time.Second,
+		maxOpenTimeout:   time.Duration(config.MaxOpenTimeoutSec) * time.Second,
+		state:            CircuitBreakerClosed,
+	}
+	metrics.CircuitBreakerState(destinationID, circuitBreakerMetricValues[cb.state])
+	return cb
+}
+
+//Ready returns true if requests can be sent:
+//circuit breaker is closed or it is half opened and probe request hasn't been sent yet
+func (cb *circuitBreaker) Ready() bool {
+	if cb == nil {
+		return true
+	}
+
+	cb.Lock()
+	defer cb.Unlock()
+
+	switch cb.state {
+	case CircuitBreakerOpen:
+		if time.Now().Before(cb.openUntil) {
+			return false
+		}
+		cb.setState(CircuitBreakerHalfOpen)
+		return true
+	case CircuitBreakerHalfOpen:
+		return !cb.probeInFlight
+	default:
+		return true
+	}
+}
+
+//Acquire marks that request is going to be sent. Only one probe request is sent in half open state
+func (cb *circuitBreaker) Acquire() {
+	if cb == nil {
+		return
+	}
+
+	cb.Lock()
+	defer cb.Unlock()
+
+	if cb.state == CircuitBreakerHalfOpen {
+		cb.probeInFlight = true
+	}
+}
+
+//Release marks that acquired request hasn't been sent
+func (cb *circuitBreaker) Release() {
+	if cb == nil {
+		return
+	}
+
+	cb.Lock()
+	cb.probeInFlight = false
+	cb.Unlock()
+}
+
+//Success closes circuit breaker
+func (cb *circuitBreaker) Success() {
+	if cb == nil {
+		return
+	}
+
+	cb.Lock()
+	defer cb.Unlock()
+
+	cb.failures = 0
+	cb.probeInFlight = false
+	cb.currentOpen = 0
+	if cb.state != CircuitBreakerClosed {
+		logging.Infof("[%s] HTTP destination is available. Circuit breaker is closed", cb.destinationID)
+		cb.setState(CircuitBreakerClosed)
+	}
+}
+
+//Failure counts consecutive failures and opens circuit breaker if threshold is reached or probe request has failed
+//returns true if circuit breaker is open
+func (cb *circuitBreaker) Failure(err error) bool {
+	if cb == nil {
+		return false
+	}
+
+	cb.Lock()
+	defer cb.Unlock()
+
+	cb.failures++
+	cb.lastError = err.Error()
+
+	switch cb.state {
+	case CircuitBreakerOpen:
+		return true
+	case CircuitBreakerHalfOpen:
+		cb.probeInFlight = false
+		cb.currentOpen *= 2
+		if cb.currentOpen > cb.maxOpenTimeout {
+			cb.currentOpen = cb.maxOpenTimeout
+		}
+		cb.open()
+		return true
+	default:
+		if cb.failures < cb.failureThreshold {
+			return false
+		}
+		cb.currentOpen = cb.openTimeout
+		cb.open()
+		return true
+	}
+}
+
+//Status returns circuit breaker status
+func (cb *circuitBreaker) Status() *CircuitBreakerStatus {
+	if cb == nil {
+		return nil
+	}
+
+	cb.Lock()
+	defer cb.Unlock()
+
+	status := &CircuitBreakerStatus{State: cb.state, ConsecutiveFailures: cb.failures, LastError: cb.lastError}
+	if cb.state != CircuitBreakerClosed {
+		status.OpenedAt = timestamp.ToISOFormat(cb.openedAt.UTC())
+		status.OpenUntil = timestamp.ToISOFormat(cb.openUntil.UTC())
+	}
+
+	return status
+}
+
+//open must be called under lock
+func (cb *circuitBreaker) open() {
+	now := time.Now()
+	if cb.state == CircuitBreakerClosed {
+		cb.openedAt = now
+	}
+	cb.openUntil = now.Add(cb.currentOpen)
+	logging.Warnf("[%s] HTTP destination is unavailable after %d failed requests. Circuit breaker is open for %s. Last error: %s", cb.destinationID, cb.failures, cb.currentOpen, cb.lastError)
+	cb.setState(CircuitBreakerOpen)
+}
+
+//setState must be called under lock
+func (cb *circuitBreaker) setState(state string) {
+	cb.state = state
+	metrics.CircuitBreakerState(cb.destinationID, circuitBreakerMetricValues[state])
+}
@@ -11,8 +11,12 @@ import (
 
 const requestsPerPersistedFile = 2000
 
-//ErrQueueClosed is a error in case when queue has been already closed
-var ErrQueueClosed = errors.New("queue is closed")
+var (
+	//ErrQueueClosed is a error in case when queue has been already closed
+	ErrQueueClosed = errors.New("queue is closed")
+	//ErrQueueEmpty is a error in case when non blocking dequeue is called on empty queue
+	ErrQueueEmpty = errors.New("queue is empty")
+)
 
 //QueuedRequest is a dto for serialization in persistent queue
 type QueuedRequest struct {
@@ -82,6 +86,26 @@ func (pq *PersistentQueue) DequeueBlock() (*RetryableRequest, error) {
 		return nil, err
 	}
 
+	return pq.deserialize(iface)
+}
+
+//Dequeue returns enqueued request or ErrQueueEmpty without waiting
+func (pq *PersistentQueue) Dequeue() (*RetryableRequest, error) {
+	iface, err := pq.queue.Dequeue()
+	if err != nil {
+		switch err {
+		case dque.ErrQueueClosed:
+			err = ErrQueueClosed
+		case dque.ErrEmpty:
+			err = ErrQueueEmpty
+		}
+		return nil, err
+	}
+
+	return pq.deserialize(iface)
+}
+
+func (pq *PersistentQueue) deserialize(iface interface{}) (*RetryableRequest, error) {
 	pq.size.Dec()
 
 	wrappedReq, ok := iface.(*QueuedRequest)
@@ -90,7 +114,7 @@ func (pq *PersistentQueue) DequeueBlock() (*RetryableRequest, error) {
 	}
 
 	retryableRequest := &RetryableRequest{}
-	err = json.Unmarshal(wrappedReq.SerializedRetryableRequest, retryableRequest)
+	err := json.Unmarshal(wrappedReq.SerializedRetryableRequest, retryableRequest)
 	if err != nil {
 		return nil, fmt.Errorf("Error deserializing RetryableRequest from the HTTP queue: %v", err)
 	}
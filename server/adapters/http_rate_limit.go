@@ -0,0 +1,228 @@
+package adapters
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/timestamp"
+	"golang.org/x/time/rate"
+)
+
+const (
+	//minRateLimitFactor is a min part of configured rate limit which is used after 429 responses
+	minRateLimitFactor = 0.1
+	//rateLimitRecoveryFactor is a part of configured rate limit which is restored after every succeeded request
+	rateLimitRecoveryFactor = 0.05
+)
+
+//HTTPRateLimitConfig is a dto for parsing per destination rate limit (token bucket) of HTTP requests
+type HTTPRateLimitConfig struct {
+	RequestsPerSecond float64 `mapstructure:"requests_per_second" json:"requests_per_second,omitempty" yaml:"requests_per_second,omitempty"`
+	Burst             int     `mapstructure:"burst" json:"burst,omitempty" yaml:"burst,omitempty"`
+}
+
+//Validate returns err if invalid and sets default burst
+func (hrlc *HTTPRateLimitConfig) Validate() error {
+	if hrlc == nil {
+		return nil
+	}
+
+	if hrlc.RequestsPerSecond < 0 || hrlc.Burst < 0 {
+		return errors.New("http_rate_limit requests_per_second and burst must be positive")
+	}
+	if hrlc.Burst == 0 {
+		hrlc.Burst = int(math.Max(1, math.Ceil(hrlc.RequestsPerSecond)))
+	}
+
+	return nil
+}
+
+//RateLimitStatus is a dto for rate limiter status
+type RateLimitStatus struct {
+	RequestsPerSecond float64 `json:"requests_per_second,omitempty"`
+	PausedUntil       string  `json:"paused_until,omitempty"`
+}
+
+//rateLimiter is an adaptive token bucket: rate is halved after every 429 response and is restored after succeeded requests
+//it is also paused by Retry-After or provider rate limit response headers
+type rateLimiter struct {
+	sync.RWMutex
+
+	limiter     *rate.Limiter
+	configured  rate.Limit
+	pausedUntil time.Time
+}
+
+//newRateLimiter returns rateLimiter without token bucket if config is nil (only pauses are applied)
+func newRateLimiter(config *HTTPRateLimitConfig) *rateLimiter {
+	rl := &rateLimiter{}
+	if config != nil && config.RequestsPerSecond > 0 {
+		rl.configured = rate.Limit(config.RequestsPerSecond)
+		rl.limiter = rate.NewLimiter(rl.configured, config.Burst)
+	}
+
+	return rl
+}
+
+//PausedFor returns duration of rate limit pause (0 if it isn't paused)
+func (rl *rateLimiter) PausedFor() time.Duration {
+	rl.RLock()
+	defer rl.RUnlock()
+
+	return time.Until(rl.pausedUntil)
+}
+
+//Pause prevents sending requests during input duration
+func (rl *rateLimiter) Pause(duration time.Duration) {
+	rl.Lock()
+	defer rl.Unlock()
+
+	if until := time.Now().Add(duration); until.After(rl.pausedUntil) {
+		rl.pausedUntil = until
+	}
+}
+
+//Wait blocks until token bucket allows sending a request
+func (rl *rateLimiter) Wait(ctx context.Context) error {
+	if rl.limiter == nil {
+		return nil
+	}
+
+	return rl.limiter.Wait(ctx)
+}
+
+//Throttle halves current rate (not less than 10% of configured one)
+func (rl *rateLimiter) Throttle() {
+	if rl.limiter == nil {
+		return
+	}
+
+	rl.Lock()
+	defer rl.Unlock()
+
+	limit := rl.limiter.Limit() / 2
+	if min := rl.configured * minRateLimitFactor; limit < min {
+		limit = min
+	}
+	rl.limiter.SetLimit(limit)
+}
+
+//Recover increases current rate up to configured one
+func (rl *rateLimiter) Recover() {
+	if rl.limiter == nil {
+		return
+	}
+
+	rl.Lock()
+	defer rl.Unlock()
+
+	if rl.limiter.Limit() >= rl.configured {
+		return
+	}
+
+	limit := rl.limiter.Limit() + rl.configured*rateLimitRecoveryFactor
+	if limit > rl.configured {
+		limit = rl.configured
+	}
+	rl.limiter.SetLimit(limit)
+}
+
+//Status returns current rate and pause time
+func (rl *rateLimiter) Status() *RateLimitStatus {
+	status := &RateLimitStatus{}
+	if rl.limiter != nil {
+		status.RequestsPerSecond = float64(rl.limiter.Limit())
+	}
+
+	rl.RLock()
+	defer rl.RUnlock()
+	if time.Now().Before(rl.pausedUntil) {
+		status.PausedUntil = timestamp.ToISOFormat(rl.pausedUntil.UTC())
+	}
+
+	return status
+}
+
+//rateLimitPause returns duration until destination API accepts requests again parsed from response headers (0 if it isn't limited):
+// - Retry-After (seconds or HTTP date)
+// - X-RateLimit-Remaining = 0 with X-RateLimit-Reset (seconds or unix time)
+// - HubSpot X-HubSpot-RateLimit-Secondly-Remaining/X-HubSpot-RateLimit-Remaining = 0
+// - Facebook X-Business-Use-Case-Usage estimated_time_to_regain_access (minutes)
+func rateLimitPause(header http.Header, now time.Time) time.Duration {
+	var pause time.Duration
+
+	if retryAfter := header.Get("Retry-After"); retryAfter != "" {
+		if seconds, err := strconv.Atoi(retryAfter); err == nil {
+			pause = maxDuration(pause, time.Duration(seconds)*time.Second)
+		} else if date, err := http.ParseTime(retryAfter); err == nil {
+			pause = maxDuration(pause, date.Sub(now))
+		}
+	}
+
+	if header.Get("X-RateLimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			//big values are unix timestamps
+			if reset > 1_000_000_000 {
+				pause = maxDuration(pause, time.Unix(reset, 0).Sub(now))
+			} else {
+				pause = maxDuration(pause, time.Duration(reset)*time.Second)
+			}
+		}
+	}
+
+	if header.Get("X-HubSpot-RateLimit-Secondly-Remaining") == "0" {
+		pause = maxDuration(pause, time.Second)
+	}
+	if header.Get("X-HubSpot-RateLimit-Remaining") == "0" {
+		if interval, err := strconv.Atoi(header.Get("X-HubSpot-RateLimit-Interval-Milliseconds")); err == nil {
+			pause = maxDuration(pause, time.Duration(interval)*time.Millisecond)
+		}
+	}
+
+	if usage := header.Get("X-Business-Use-Case-Usage"); usage != "" {
+		pause = maxDuration(pause, facebookRegainAccessTime(usage))
+	}
+
+	return pause
+}
+
+//facebookRegainAccessTime returns max estimated_time_to_regain_access from X-Business-Use-Case-Usage header
+//format: {"<business id>": [{"type": "...", "call_count": 100, "estimated_time_to_regain_access": 5}]}
+func facebookRegainAccessTime(usage string) time.Duration {
+	parsed := map[string][]struct {
+		EstimatedTimeToRegainAccess int `json:"estimated_time_to_regain_access"`
+	}{}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(usage)), &parsed); err != nil {
+		return 0
+	}
+
+	var pause time.Duration
+	for _, usages := range parsed {
+		for _, u := range usages {
+			pause = maxDuration(pause, time.Duration(u.EstimatedTimeToRegainAccess)*time.Minute)
+		}
+	}
+
+	return pause
+}
+
+func maxDuration(a, b time.Duration) time.Duration {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func minDuration(a, b time.Duration) time.Duration {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,189 @@
+package adapters
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+)
+
+func TestRateLimitPause(t *testing.T) {
+	now := time.Date(2021, 12, 31, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		header   map[string]string
+		expected time.Duration
+	}{
+		{"No headers", map[string]string{}, 0},
+		{"Retry-After seconds", map[string]string{"Retry-After": "120"}, 2 * time.Minute},
+		{"Retry-After HTTP date", map[string]string{"Retry-After": now.Add(30 * time.Second).Format(http.TimeFormat)}, 30 * time.Second},
+		{"X-RateLimit-Reset seconds", map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": "15"}, 15 * time.Second},
+		{"X-RateLimit-Reset unix time", map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": "1640944860"}, time.Minute},
+		{"X-RateLimit-Remaining isn't 0", map[string]string{"X-RateLimit-Remaining": "10", "X-RateLimit-Reset": "15"}, 0},
+		{"HubSpot secondly limit", map[string]string{"X-HubSpot-RateLimit-Secondly-Remaining": "0"}, time.Second},
+		{"HubSpot interval limit", map[string]string{"X-HubSpot-RateLimit-Remaining": "0", "X-HubSpot-RateLimit-Interval-Milliseconds": "10000"}, 10 * time.Second},
+		{"Facebook business use case usage", map[string]string{"X-Business-Use-Case-Usage": `{"123":[{"type":"ads_management","call_count":100,"estimated_time_to_regain_access":5}]}`}, 5 * time.Minute},
+		{"Max of several headers", map[string]string{"Retry-After": "3", "X-HubSpot-RateLimit-Secondly-Remaining": "0"}, 3 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			for k, v := range tt.header {
+				header.Set(k, v)
+			}
+			require.Equal(t, tt.expected, rateLimitPause(header, now))
+		})
+	}
+}
+
+func TestRateLimiterThrottle(t *testing.T) {
+	config := &HTTPRateLimitConfig{RequestsPerSecond: 10}
+	require.NoError(t, config.Validate())
+	require.Equal(t, 10, config.Burst)
+
+	rl := newRateLimiter(config)
+	for i := 0; i < 10; i++ {
+		rl.Throttle()
+	}
+	require.Equal(t, 1.0, rl.Status().RequestsPerSecond)
+
+	rl.Recover()
+	require.InDelta(t, 1.5, rl.Status().RequestsPerSecond, 0.0001)
+	for i := 0; i < 100; i++ {
+		rl.Recover()
+	}
+	require.Equal(t, 10.0, rl.Status().RequestsPerSecond)
+
+	require.Empty(t, rl.Status().PausedUntil)
+	rl.Pause(time.Minute)
+	require.NotEmpty(t, rl.Status().PausedUntil)
+	require.True(t, rl.PausedFor() > 0)
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	config := &HTTPCircuitBreakerConfig{Enabled: true, FailureThreshold: 2, OpenTimeoutSec: 1, MaxOpenTimeoutSec: 3}
+	require.NoError(t, config.Validate())
+	cb := newCircuitBreaker("test", config)
+	failure := errors.New("connection refused")
+
+	require.False(t, cb.Failure(failure))
+	require.True(t, cb.Ready())
+	require.True(t, cb.Failure(failure))
+	require.Equal(t, CircuitBreakerOpen, cb.Status().State)
+	require.False(t, cb.Ready())
+
+	//half open after timeout: only one probe request
+	cb.openUntil = time.Now()
+	require.True(t, cb.Ready())
+	require.Equal(t, CircuitBreakerHalfOpen, cb.Status().State)
+	cb.Acquire()
+	require.False(t, cb.Ready())
+
+	//failed probe doubles open timeout
+	require.True(t, cb.Failure(failure))
+	require.Equal(t, CircuitBreakerOpen, cb.Status().State)
+	require.Equal(t, 2*time.Second, cb.currentOpen)
+
+	cb.openUntil = time.Now()
+	require.True(t, cb.Ready())
+	cb.Acquire()
+	require.True(t, cb.Failure(failure))
+	require.Equal(t, 3*time.Second, cb.currentOpen, "open timeout is limited by max_open_timeout_sec")
+
+	//succeeded probe closes circuit breaker
+	cb.openUntil = time.Now()
+	require.True(t, cb.Ready())
+	cb.Acquire()
+	cb.Success()
+	status := cb.Status()
+	require.Equal(t, CircuitBreakerClosed, status.State)
+	require.Equal(t, 0, status.ConsecutiveFailures)
+	require.Equal(t, "connection refused", status.LastError)
+
+	for _, disabledConfig := range []*HTTPCircuitBreakerConfig{nil, {}, {FailureThreshold: 2}} {
+		require.NoError(t, disabledConfig.Validate())
+		disabled := newCircuitBreaker("test", disabledConfig)
+		require.Nil(t, disabled, "circuit breaker must be enabled explicitly")
+		require.True(t, disabled.Ready())
+		require.False(t, disabled.Failure(failure))
+		require.Nil(t, disabled.Status())
+	}
+}
+
+func TestHTTPAdapterPostponesRateLimitedRequests(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Inc() == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer server.Close()
+
+	factory, err := NewWebhookRequestFactory("test", "webhook", http.MethodPost, server.URL, "{{ .id }}", map[string]string{})
+	require.NoError(t, err)
+
+	succeeded := make(chan string, 1)
+	adapter, err := NewHTTPAdapter(&HTTPAdapterConfiguration{
+		DestinationID:  "test",
+		Dir:            t.TempDir(),
+		HTTPConfig:     &HTTPConfiguration{GlobalClientTimeout: time.Second, RetryDelay: time.Second, RetryCount: 0},
+		HTTPReqFactory: factory,
+		PoolWorkers:    1,
+		DebugLogger:    &logging.QueryLogger{},
+		ErrorHandler: func(fallback bool, eventContext *EventContext, err error) {
+			require.False(t, fallback, "rate limited request must be postponed without retry counting")
+		},
+		SuccessHandler: func(eventContext *EventContext) {
+			succeeded <- eventContext.EventID
+		},
+		RateLimit:      &HTTPRateLimitConfig{RequestsPerSecond: 10},
+		CircuitBreaker: &HTTPCircuitBreakerConfig{Enabled: true},
+	})
+	require.NoError(t, err)
+	defer adapter.Close()
+
+	require.NoError(t, adapter.SendAsync(&EventContext{EventID: "1", ProcessedEvent: map[string]interface{}{"id": `{"id":1}`}}))
+
+	select {
+	case id := <-succeeded:
+		require.Equal(t, "1", id)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timeout waiting for postponed request")
+	}
+	require.Equal(t, int32(2), calls.Load())
+
+	status := adapter.Status()
+	require.Equal(t, CircuitBreakerClosed, status.CircuitBreaker.State)
+	require.Equal(t, 5.5, status.RateLimit.RequestsPerSecond)
+}
+
+func TestHTTPAdapterPostponeQueueFullnessThreshold(t *testing.T) {
+	queue, err := NewPersistentQueue("http_queue.dst=test", t.TempDir())
+	require.NoError(t, err)
+	defer queue.Close()
+
+	var fallbacks []bool
+	adapter := &HTTPAdapter{
+		queue:                  queue,
+		destinationID:          "test",
+		queueFullnessThreshold: 2,
+		errorHandler: func(fallback bool, eventContext *EventContext, err error) {
+			fallbacks = append(fallbacks, fallback)
+		},
+	}
+
+	sendErr := errors.New("HTTP Response status code: [429]")
+	for i := 0; i < 3; i++ {
+		adapter.postpone(&RetryableRequest{Request: &Request{}, EventContext: &EventContext{}}, sendErr)
+	}
+
+	require.Equal(t, []bool{false, false, true}, fallbacks, "request must be sent to fallback when the queue is full")
+	require.Equal(t, uint64(2), queue.Size())
+}
@@ -1,7 +1,13 @@
 package adapters
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
 	"github.com/jitsucom/jitsu/server/templates"
 )
 
@@ -71,6 +77,50 @@ func (wrf *WebhookRequestFactory) Create(object map[string]interface{}) (req *Re
 	}, nil
 }
 
+//BatchKey returns HTTP method, URL and headers: requests with the same key are sent as one JSON array
+//requests without JSON body (e.g. GET) aren't batched
+func (wrf *WebhookRequestFactory) BatchKey(req *Request) string {
+	if len(req.Body) == 0 || req.Method == http.MethodGet || !json.Valid(req.Body) {
+		return ""
+	}
+
+	headers := make([]string, 0, len(req.Headers))
+	for k, v := range req.Headers {
+		headers = append(headers, k+"="+v)
+	}
+	sort.Strings(headers)
+
+	return req.Method + " " + req.URL + " " + strings.Join(headers, ";")
+}
+
+//CreateBatch returns request with JSON array of requests bodies
+func (wrf *WebhookRequestFactory) CreateBatch(requests []*Request) (*Request, error) {
+	bodies := make([][]byte, len(requests))
+	for i, r := range requests {
+		bodies[i] = r.Body
+	}
+
+	body := append([]byte{'['}, bytes.Join(bodies, []byte{','})...)
+	body = append(body, ']')
+
+	return &Request{
+		URL:     requests[0].URL,
+		Method:  requests[0].Method,
+		Body:    body,
+		Headers: requests[0].Headers,
+	}, nil
+}
+
+//MaxBatchEvents returns 0: webhook batch is limited only by configuration
+func (wrf *WebhookRequestFactory) MaxBatchEvents() int {
+	return 0
+}
+
+//BatchItemErrors returns empty errors: webhook response format is unknown
+func (wrf *WebhookRequestFactory) BatchItemErrors(statusCode int, responseBody []byte, batchSize int) map[int]error {
+	return map[int]error{}
+}
+
 func (wrf *WebhookRequestFactory) Close() {
 	wrf.urlTmpl.Close()
 	wrf.bodyTmpl.Close()
@@ -1,48 +1,75 @@
 package adapters
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/jitsucom/jitsu/server/events"
 	"github.com/jitsucom/jitsu/server/jsonutils"
 	"github.com/jitsucom/jitsu/server/logging"
-	"github.com/jitsucom/jitsu/server/maputils"
 	"github.com/jitsucom/jitsu/server/safego"
+	"github.com/jitsucom/jitsu/server/timestamp"
 	"go.uber.org/atomic"
 	"io/ioutil"
 	"net/http"
 	"net/url"
 	"regexp"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 )
 
 const (
-	hubSpotContactPropertiesAPIURLTemplate   = "https://api.hubapi.com/properties/v1/contacts/properties?hapikey=%s"
-	hubSpotContactWithEmailAPIURLTemplate    = "https://api.hubapi.com/contacts/v1/contact/createOrUpdate/email/%v"
-	hubSpotContactWithoutEmailAPIURLTemplate = "https://api.hubapi.com/contacts/v1/contact"
-	hubSpotEventURL                          = "https://track.hubspot.com/v1/event"
-	JitsuUserAgent                           = "Jitsu.com/1.0"
+	hubSpotDefaultBaseURL               = "https://api.hubapi.com"
+	hubSpotPropertiesPathTemplate       = "/crm/v3/properties/%s"
+	hubSpotPropertiesCreatePathTemplate = "/crm/v3/properties/%s/batch/create"
+	hubSpotUpsertPathTemplate           = "/crm/v3/objects/%s/batch/upsert"
+	hubSpotEventsPath                   = "/events/v3/send/batch"
+	hubSpotContacts                     = "contacts"
+	hubSpotCompanies                    = "companies"
+	hubSpotGroupEvent                   = "group"
+	hubSpotMaxBatchInputs               = 100
+	hubSpotClientTimeout                = 10 * time.Second
+	hubSpotPropertiesReloadInterval     = time.Minute
+	hubSpotCustomEventNameTemplate      = "pe%s_%s"
+	JitsuUserAgent                      = "Jitsu.com/1.0"
 )
 
 var (
 	alphaNumericReplacer = regexp.MustCompile("[^a-zA-Z0-9]+")
 	userPath             = jsonutils.NewJSONPath("/user||/eventn_ctx/user")
 	userEmailPath        = jsonutils.NewJSONPath("/user/email||/eventn_ctx/user/email")
-	revenuePath          = jsonutils.NewJSONPath("/revenue||/conversion/revenue")
+	companyPath          = jsonutils.NewJSONPath("/company||/group")
+	companyDomainPath    = jsonutils.NewJSONPath("/company/domain||/group/domain")
+	hubSpotUTKPath       = jsonutils.NewJSONPath("/hubspotutk||/eventn_ctx/hubspotutk")
+	eventPropertiesPath  = jsonutils.NewJSONPath("/properties")
+
+	//hubSpotPropertyGroups are groups of properties which are created by Jitsu
+	hubSpotPropertyGroups = map[string]string{
+		hubSpotContacts:  "contactinformation",
+		hubSpotCompanies: "companyinformation",
+	}
 )
 
-//HubSpotContactProperty is a dto for serializing contact (user) properties from HubSpot
-type HubSpotContactProperty struct {
-	Name string `json:"name"`
+//HubSpotProperty is a dto for serializing HubSpot object property
+type HubSpotProperty struct {
+	Name      string `json:"name"`
+	Label     string `json:"label,omitempty"`
+	Type      string `json:"type,omitempty"`
+	FieldType string `json:"fieldType,omitempty"`
+	GroupName string `json:"groupName,omitempty"`
+}
+
+//HubSpotPropertiesResponse is a dto for receiving object properties from HubSpot
+type HubSpotPropertiesResponse struct {
+	Results []*HubSpotProperty `json:"results"`
 }
 
-//HubSpotContactPropertyWithValues is a dto for serializing contact (user) properties that are sent to HubSpot
-type HubSpotContactPropertyWithValues struct {
-	Property string      `json:"property"`
-	Value    interface{} `json:"value"`
+//HubSpotPropertiesRequest is a dto for creating object properties in HubSpot
+type HubSpotPropertiesRequest struct {
+	Inputs []*HubSpotProperty `json:"inputs"`
 }
 
 //HubSpotResponse is a dto for receiving response from HubSpot
@@ -52,182 +79,510 @@ type HubSpotResponse struct {
 	Message  string `json:"message"`
 }
 
-//HubSpotContactRequest is a dto for sending contact requests to HubSpot
-type HubSpotContactRequest struct {
-	Properties []HubSpotContactPropertyWithValues `json:"properties"`
+//HubSpotUpsertInput is a dto for contact or company in batch upsert request
+type HubSpotUpsertInput struct {
+	IDProperty string                 `json:"idProperty"`
+	ID         string                 `json:"id"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+//HubSpotUpsertRequest is a dto for sending batch upsert requests to HubSpot
+type HubSpotUpsertRequest struct {
+	Inputs []*HubSpotUpsertInput `json:"inputs"`
+}
+
+//HubSpotEventInput is a dto for custom behavioral event occurrence
+type HubSpotEventInput struct {
+	EventName  string                 `json:"eventName"`
+	Email      string                 `json:"email,omitempty"`
+	UTK        string                 `json:"utk,omitempty"`
+	OccurredAt string                 `json:"occurredAt,omitempty"`
+	Properties map[string]interface{} `json:"properties,omitempty"`
+}
+
+//HubSpotEventsRequest is a dto for sending custom behavioral events batch to HubSpot
+type HubSpotEventsRequest struct {
+	Inputs []*HubSpotEventInput `json:"inputs"`
+}
+
+//HubSpotConfig is a dto for parsing HubSpot configuration
+type HubSpotConfig struct {
+	AccessToken string `mapstructure:"access_token" json:"access_token,omitempty" yaml:"access_token,omitempty"`
+	//CredentialID is an ID of OAuth2 credential (HubSpot public app) which access token is used instead of access_token
+	CredentialID string `mapstructure:"credential_id" json:"credential_id,omitempty" yaml:"credential_id,omitempty"`
+	//Deprecated: HubSpot API keys are sunset. Use private app access_token instead
+	APIKey string `mapstructure:"api_key" json:"api_key,omitempty" yaml:"api_key,omitempty"`
+	HubID  string `mapstructure:"hub_id" json:"hub_id,omitempty" yaml:"hub_id,omitempty"`
+	//Events is a mapping event_type -> fully qualified custom behavioral event name
+	Events                    map[string]string `mapstructure:"events" json:"events,omitempty" yaml:"events,omitempty"`
+	DisablePropertiesCreation bool              `mapstructure:"disable_properties_creation" json:"disable_properties_creation,omitempty" yaml:"disable_properties_creation,omitempty"`
+	//BaseURL is used for sending requests to a local HubSpot stand-in
+	BaseURL string `mapstructure:"base_url" json:"base_url,omitempty" yaml:"base_url,omitempty"`
+}
+
+//Validate returns err if invalid and sets default base URL
+func (hc *HubSpotConfig) Validate() error {
+	if hc == nil {
+		return errors.New("hubspot config is required")
+	}
+	if hc.AccessToken == "" && hc.APIKey == "" && hc.CredentialID == "" {
+		return errors.New("'access_token' or 'credential_id' is required parameter")
+	}
+	if hc.HubID == "" {
+		return errors.New("'hub_id' is required parameter")
+	}
+	if hc.BaseURL == "" {
+		hc.BaseURL = hubSpotDefaultBaseURL
+	}
+	hc.BaseURL = strings.TrimRight(hc.BaseURL, "/")
+
+	return nil
+}
+
+//hubSpotClient sends HubSpot properties API requests
+type hubSpotClient struct {
+	config *HubSpotConfig
+	client *http.Client
+}
+
+//usesAPIKey returns true if deprecated API key is used
+func (hc *HubSpotConfig) usesAPIKey() bool {
+	return hc.AccessToken == "" && hc.CredentialID == ""
+}
+
+//url returns HubSpot API URL with hapikey query parameter if deprecated API key is used
+func (hc *hubSpotClient) url(path string) string {
+	if hc.config.usesAPIKey() {
+		return hc.config.BaseURL + path + "?hapikey=" + url.QueryEscape(hc.config.APIKey)
+	}
+
+	return hc.config.BaseURL + path
+}
+
+//headers returns HubSpot API request headers with authorization token
+func (hc *hubSpotClient) headers() map[string]string {
+	headers := map[string]string{"Content-Type": "application/json", "user-agent": JitsuUserAgent}
+	if hc.config.AccessToken != "" {
+		headers["Authorization"] = "Bearer " + hc.config.AccessToken
+	}
+
+	return headers
+}
+
+//loadProperties requests (HTTP GET) object type properties from HubSpot
+func (hc *hubSpotClient) loadProperties(objectType string) (map[string]bool, error) {
+	responseBody, err := hc.do(http.MethodGet, fmt.Sprintf(hubSpotPropertiesPathTemplate, objectType), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &HubSpotPropertiesResponse{}
+	if err := json.Unmarshal(responseBody, response); err != nil {
+		return nil, fmt.Errorf("error unmarshalling hubspot response body: %v", err)
+	}
+
+	properties := map[string]bool{}
+	for _, property := range response.Results {
+		properties[property.Name] = true
+	}
+
+	return properties, nil
+}
+
+//createProperties creates string properties of the object type in one batch request
+func (hc *hubSpotClient) createProperties(objectType string, names []string) error {
+	request := &HubSpotPropertiesRequest{}
+	for _, name := range names {
+		request.Inputs = append(request.Inputs, &HubSpotProperty{
+			Name:      name,
+			Label:     name,
+			Type:      "string",
+			FieldType: "text",
+			GroupName: hubSpotPropertyGroups[objectType],
+		})
+	}
+
+	b, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	_, err = hc.do(http.MethodPost, fmt.Sprintf(hubSpotPropertiesCreatePathTemplate, objectType), b)
+	return err
+}
+
+//do sends HubSpot API request and returns response body if response code is 2xx
+func (hc *hubSpotClient) do(method, path string, body []byte) ([]byte, error) {
+	req, err := http.NewRequest(method, hc.url(path), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range hc.headers() {
+		req.Header.Add(k, v)
+	}
+	if hc.config.CredentialID != "" {
+		if err := NewCredentialsAuthenticator(hc.config.CredentialID).Authenticate(req, body); err != nil {
+			return nil, err
+		}
+	}
+
+	r, err := hc.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+
+	responseBody, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading hubspot response body: %v", err)
+	}
+
+	if r.StatusCode < 200 || r.StatusCode > 299 {
+		hr := &HubSpotResponse{}
+		if err := json.Unmarshal(responseBody, hr); err != nil {
+			return nil, fmt.Errorf("received HTTP code [%d] from HubSpot: %s", r.StatusCode, string(responseBody))
+		}
+
+		return nil, fmt.Errorf("received HTTP code [%d] from HubSpot: %s [%s]: %s", r.StatusCode, hr.Status, hr.Category, hr.Message)
+	}
+
+	return responseBody, nil
 }
 
 //HubSpotRequestFactory is a factory for building HubSpot HTTP requests from input events
 //reloads properties configuration every minutes in background goroutine
+//creates missing contacts and companies properties if it isn't disabled
 type HubSpotRequestFactory struct {
 	mutex  *sync.RWMutex
-	apiKey string
-	hubID  string
+	config *HubSpotConfig
+	client *hubSpotClient
 
-	userProperties map[string]bool
+	//properties is a map object type -> properties names
+	properties map[string]map[string]bool
+	//creationMutex serializes properties creation
+	creationMutex *sync.Mutex
 
 	closed *atomic.Bool
 }
 
 //newHubSpotRequestFactory returns configured HTTPRequestFactory instance for hubspot requests
-//starts goroutine for getting user properties
-func newHubSpotRequestFactory(apiKey, hubID string) (*HubSpotRequestFactory, error) {
+//starts goroutine for getting contacts and companies properties
+func newHubSpotRequestFactory(config *HubSpotConfig) (*HubSpotRequestFactory, error) {
 	hf := &HubSpotRequestFactory{
-		mutex:  &sync.RWMutex{},
-		apiKey: apiKey,
-		hubID:  hubID,
-		closed: atomic.NewBool(false),
+		mutex:         &sync.RWMutex{},
+		config:        config,
+		client:        &hubSpotClient{config: config, client: &http.Client{Timeout: hubSpotClientTimeout}},
+		properties:    map[string]map[string]bool{hubSpotContacts: {}, hubSpotCompanies: {}},
+		creationMutex: &sync.Mutex{},
+		closed:        atomic.NewBool(false),
 	}
 
-	userProperties, err := loadContactProperties(apiKey)
+	contactProperties, err := hf.client.loadProperties(hubSpotContacts)
 	if err != nil {
 		return nil, fmt.Errorf("Error loading contact properties: %v", err)
 	}
-	hf.userProperties = userProperties
+	hf.properties[hubSpotContacts] = contactProperties
+	hf.reloadProperties(hubSpotCompanies)
+
 	hf.start()
 	return hf, nil
 }
 
-//start runs a goroutines that gets user properties every 1 minute
+//start runs a goroutines that gets contacts and companies properties every 1 minute
 func (hf *HubSpotRequestFactory) start() {
 	safego.RunWithRestart(func() {
 		for {
+			time.Sleep(hubSpotPropertiesReloadInterval)
 			if hf.closed.Load() {
 				break
 			}
 
-			properties, err := loadContactProperties(hf.apiKey)
-			if err != nil {
-				logging.Errorf("Error loading contact properties for [%s] Hub ID: %v", hf.hubID, err)
-			} else {
-				hf.mutex.Lock()
-				hf.userProperties = properties
-				hf.mutex.Unlock()
-			}
-
-			time.Sleep(time.Minute)
+			hf.reloadProperties(hubSpotContacts)
+			hf.reloadProperties(hubSpotCompanies)
 		}
 	})
 }
 
-//Create returns created hubspot request depends on event type
-func (hf *HubSpotRequestFactory) Create(object map[string]interface{}) (*Request, error) {
-	eventType, ok := object[events.EventType]
-	if !ok {
-		eventType = "unknown"
+func (hf *HubSpotRequestFactory) reloadProperties(objectType string) {
+	properties, err := hf.client.loadProperties(objectType)
+	if err != nil {
+		logging.Errorf("Error loading %s properties for [%s] Hub ID: %v", objectType, hf.config.HubID, err)
+		return
 	}
 
-	hf.mutex.RLock()
-	userProperties := maputils.CopySet(hf.userProperties)
-	hf.mutex.RUnlock()
+	hf.mutex.Lock()
+	hf.properties[objectType] = properties
+	hf.mutex.Unlock()
+}
 
-	objectUserProperties := hf.extractUserProperties(object, userProperties)
+//Create returns created hubspot request depends on event type:
+// - user_identify: contacts batch upsert by email
+// - group: companies batch upsert by domain
+// - other: custom behavioral events batch
+func (hf *HubSpotRequestFactory) Create(object map[string]interface{}) (*Request, error) {
+	eventType := "unknown"
+	if et, ok := object[events.EventType]; ok {
+		eventType = fmt.Sprint(et)
+	}
 
-	//contact request
-	if eventType == events.UserIdentify {
-		reqURL := hubSpotContactWithoutEmailAPIURLTemplate
-		if email, ok := userEmailPath.Get(object); ok {
-			reqURL = fmt.Sprintf(hubSpotContactWithEmailAPIURLTemplate, email)
+	var path string
+	var body interface{}
+	switch eventType {
+	case events.UserIdentify:
+		email, ok := userEmailPath.Get(object)
+		if !ok || fmt.Sprint(email) == "" {
+			return nil, errors.New("Object doesn't have user email: /user/email or /eventn_ctx/user/email is required")
 		}
 
-		reqURL += "?hapikey=" + hf.apiKey
+		userObj, _ := userPath.Get(object)
+		path = fmt.Sprintf(hubSpotUpsertPathTemplate, hubSpotContacts)
+		body = &HubSpotUpsertRequest{Inputs: []*HubSpotUpsertInput{{
+			IDProperty: "email",
+			ID:         fmt.Sprint(email),
+			Properties: hf.objectProperties(hubSpotContacts, userObj),
+		}}}
+	case hubSpotGroupEvent:
+		domain, ok := companyDomainPath.Get(object)
+		if !ok || fmt.Sprint(domain) == "" {
+			return nil, errors.New("Object doesn't have company domain: /company/domain or /group/domain is required")
+		}
 
-		body := HubSpotContactRequest{Properties: objectUserProperties}
-		b, err := json.Marshal(body)
+		companyObj, _ := companyPath.Get(object)
+		path = fmt.Sprintf(hubSpotUpsertPathTemplate, hubSpotCompanies)
+		body = &HubSpotUpsertRequest{Inputs: []*HubSpotUpsertInput{{
+			IDProperty: "domain",
+			ID:         fmt.Sprint(domain),
+			Properties: hf.objectProperties(hubSpotCompanies, companyObj),
+		}}}
+	default:
+		event, err := hf.eventInput(eventType, object)
 		if err != nil {
 			return nil, err
 		}
-		return &Request{
-			URL:     reqURL,
-			Method:  http.MethodPost,
-			Body:    b,
-			Headers: map[string]string{"Content-Type": "application/json", "user-agent": JitsuUserAgent},
-		}, nil
-	} else {
-		//event request
-		query := url.Values{}
-		query.Add("_a", hf.hubID)
-		query.Add("_n", fmt.Sprint(eventType))
-		r, ok := revenuePath.Get(object)
-		if ok {
-			query.Add("_m", fmt.Sprint(r))
-		}
-
-		for _, prop := range objectUserProperties {
-			query.Add(prop.Property, fmt.Sprint(prop.Value))
-		}
+		path = hubSpotEventsPath
+		body = &HubSpotEventsRequest{Inputs: []*HubSpotEventInput{event}}
+	}
 
-		return &Request{
-			URL:     hubSpotEventURL + "?" + query.Encode(),
-			Method:  http.MethodGet,
-			Body:    nil,
-			Headers: map[string]string{"Content-Type": "application/json", "user-agent": JitsuUserAgent},
-		}, nil
+	b, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
 	}
-}
 
-//Close closes underlying goroutine
-func (hf *HubSpotRequestFactory) Close() {
-	hf.closed.Store(true)
+	return &Request{
+		URL:     hf.client.url(path),
+		Method:  http.MethodPost,
+		Body:    b,
+		Headers: hf.client.headers(),
+	}, nil
 }
 
-//extractUserProperties returns hubspot user properties from input objects
-func (hf *HubSpotRequestFactory) extractUserProperties(object map[string]interface{}, properties map[string]bool) []HubSpotContactPropertyWithValues {
-	result := []HubSpotContactPropertyWithValues{}
-
-	userObj, ok := userPath.Get(object)
-	if ok {
-		user, ok := userObj.(map[string]interface{})
-		if ok {
-			for userField, value := range user {
-				reformattedUserField := reformatFieldName(userField)
-				if _, ok := properties[reformattedUserField]; ok {
-					//don't pass empty strings
-					if fmt.Sprint(value) != "" {
-						result = append(result, HubSpotContactPropertyWithValues{
-							Property: reformattedUserField,
-							Value:    value,
-						})
-					}
+//eventInput returns custom behavioral event occurrence
+//event name is taken from configured mapping or is built from event_type: pe<hub_id>_<event_type>
+func (hf *HubSpotRequestFactory) eventInput(eventType string, object map[string]interface{}) (*HubSpotEventInput, error) {
+	eventName, ok := hf.config.Events[eventType]
+	if !ok {
+		eventName = fmt.Sprintf(hubSpotCustomEventNameTemplate, hf.config.HubID, reformatFieldName(eventType))
+	}
+
+	event := &HubSpotEventInput{EventName: eventName, Properties: map[string]interface{}{}}
+	if email, ok := userEmailPath.Get(object); ok {
+		event.Email = fmt.Sprint(email)
+	}
+	if utk, ok := hubSpotUTKPath.Get(object); ok {
+		event.UTK = fmt.Sprint(utk)
+	}
+	if event.Email == "" && event.UTK == "" {
+		return nil, errors.New("Object doesn't have user email or hubspotutk: /user/email or /eventn_ctx/user/email is required")
+	}
+
+	switch t := object[timestamp.Key].(type) {
+	case time.Time:
+		event.OccurredAt = timestamp.ToISOFormat(t.UTC())
+	case string:
+		if eventTime, err := time.Parse(time.RFC3339Nano, t); err == nil {
+			event.OccurredAt = timestamp.ToISOFormat(eventTime.UTC())
+		}
+	}
+
+	if properties, ok := eventPropertiesPath.Get(object); ok {
+		if propertiesMap, ok := properties.(map[string]interface{}); ok {
+			for name, value := range propertiesMap {
+				if value != nil && fmt.Sprint(value) != "" {
+					event.Properties[reformatFieldName(name)] = hubSpotValue(value)
 				}
 			}
 		}
 	}
 
+	return event, nil
+}
+
+//objectProperties returns reformatted object fields which are HubSpot object type properties
+//missing properties are created if it isn't disabled
+func (hf *HubSpotRequestFactory) objectProperties(objectType string, obj interface{}) map[string]interface{} {
+	result := map[string]interface{}{}
+	fields, ok := obj.(map[string]interface{})
+	if !ok {
+		return result
+	}
+
+	for field, value := range fields {
+		//don't pass empty strings
+		if value == nil || fmt.Sprint(value) == "" {
+			continue
+		}
+		result[reformatFieldName(field)] = hubSpotValue(value)
+	}
+
+	missing := hf.missingProperties(objectType, result)
+	if len(missing) > 0 && !hf.config.DisablePropertiesCreation {
+		missing = hf.createProperties(objectType, missing)
+	}
+	for _, name := range missing {
+		delete(result, name)
+	}
+
 	return result
 }
 
-//HubSpotConfig is a dto for parsing HubSpot configuration
-type HubSpotConfig struct {
-	APIKey string `mapstructure:"api_key" json:"api_key,omitempty" yaml:"api_key,omitempty"`
-	HubID  string `mapstructure:"hub_id" json:"hub_id,omitempty" yaml:"hub_id,omitempty"`
+//missingProperties returns sorted names of properties which don't exist in HubSpot
+func (hf *HubSpotRequestFactory) missingProperties(objectType string, properties map[string]interface{}) []string {
+	hf.mutex.RLock()
+	defer hf.mutex.RUnlock()
+
+	var missing []string
+	for name := range properties {
+		if !hf.properties[objectType][name] {
+			missing = append(missing, name)
+		}
+	}
+	sort.Strings(missing)
+
+	return missing
 }
 
-//Validate returns err if invalid
-func (hc *HubSpotConfig) Validate() error {
-	if hc == nil {
-		return errors.New("hubspot config is required")
+//createProperties creates missing properties in one batch request
+//returns names of properties which haven't been created
+func (hf *HubSpotRequestFactory) createProperties(objectType string, names []string) []string {
+	hf.creationMutex.Lock()
+	defer hf.creationMutex.Unlock()
+
+	//properties might have been created by concurrent call
+	hf.mutex.RLock()
+	var missing []string
+	for _, name := range names {
+		if !hf.properties[objectType][name] {
+			missing = append(missing, name)
+		}
 	}
-	if hc.APIKey == "" {
-		return errors.New("'api_key' is required parameter")
+	hf.mutex.RUnlock()
+	if len(missing) == 0 {
+		return nil
 	}
-	if hc.HubID == "" {
-		return errors.New("'hub_id' is required parameter")
+
+	if err := hf.client.createProperties(objectType, missing); err != nil {
+		logging.Errorf("Error creating HubSpot %s properties %v for [%s] Hub ID. These properties will be skipped: %v", objectType, missing, hf.config.HubID, err)
+		return missing
+	}
+
+	logging.Infof("HubSpot %s properties %v have been created for [%s] Hub ID", objectType, missing, hf.config.HubID)
+	hf.mutex.Lock()
+	for _, name := range missing {
+		hf.properties[objectType][name] = true
 	}
+	hf.mutex.Unlock()
 
 	return nil
 }
 
+//BatchKey returns request URL: contacts, companies and events are sent in separate batches
+func (hf *HubSpotRequestFactory) BatchKey(req *Request) string {
+	return req.URL
+}
+
+//CreateBatch joins inputs of requests. Upserts of the same contact or company are merged into one input
+//because HubSpot rejects batches with duplicate IDs
+func (hf *HubSpotRequestFactory) CreateBatch(requests []*Request) (*Request, error) {
+	var body interface{}
+	if strings.Contains(requests[0].URL, "/batch/upsert") {
+		batch := &HubSpotUpsertRequest{}
+		inputsByID := map[string]*HubSpotUpsertInput{}
+		for _, r := range requests {
+			upsert := &HubSpotUpsertRequest{}
+			if err := json.Unmarshal(r.Body, upsert); err != nil {
+				return nil, fmt.Errorf("Error unmarshalling HubSpot upsert request: %v", err)
+			}
+
+			for _, input := range upsert.Inputs {
+				existing, ok := inputsByID[input.ID]
+				if !ok {
+					inputsByID[input.ID] = input
+					batch.Inputs = append(batch.Inputs, input)
+					continue
+				}
+				for k, v := range input.Properties {
+					existing.Properties[k] = v
+				}
+			}
+		}
+		body = batch
+	} else {
+		batch := &HubSpotEventsRequest{}
+		for _, r := range requests {
+			eventsRequest := &HubSpotEventsRequest{}
+			if err := json.Unmarshal(r.Body, eventsRequest); err != nil {
+				return nil, fmt.Errorf("Error unmarshalling HubSpot events request: %v", err)
+			}
+			batch.Inputs = append(batch.Inputs, eventsRequest.Inputs...)
+		}
+		body = batch
+	}
+
+	b, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Request{
+		URL:     requests[0].URL,
+		Method:  requests[0].Method,
+		Body:    b,
+		Headers: requests[0].Headers,
+	}, nil
+}
+
+//MaxBatchEvents returns max inputs count of HubSpot batch APIs
+func (hf *HubSpotRequestFactory) MaxBatchEvents() int {
+	return hubSpotMaxBatchInputs
+}
+
+//BatchItemErrors returns empty errors: HubSpot rejects the whole batch if one of inputs is invalid
+func (hf *HubSpotRequestFactory) BatchItemErrors(statusCode int, responseBody []byte, batchSize int) map[int]error {
+	return map[int]error{}
+}
+
+//Close closes underlying goroutine
+func (hf *HubSpotRequestFactory) Close() {
+	hf.closed.Store(true)
+}
+
 //HubSpot is an adapter for sending HTTP requests to HubSpot
 type HubSpot struct {
 	AbstractHTTP
 
-	config  *HubSpotConfig
+	config *HubSpotConfig
 }
 
 //NewHubSpot returns configured HubSpot adapter instance
 func NewHubSpot(config *HubSpotConfig, httpAdapterConfiguration *HTTPAdapterConfiguration) (*HubSpot, error) {
-	httpReqFactory, err := newHubSpotRequestFactory(config.APIKey, config.HubID)
+	if config.usesAPIKey() {
+		logging.Warnf("[%s] HubSpot api_key is deprecated. Please use private app access_token instead", httpAdapterConfiguration.DestinationID)
+	}
+	if config.CredentialID != "" {
+		httpAdapterConfiguration.Authenticator = NewCredentialsAuthenticator(config.CredentialID)
+	}
+
+	httpReqFactory, err := newHubSpotRequestFactory(config)
 	if err != nil {
 		return nil, err
 	}
@@ -249,9 +604,10 @@ func NewTestHubSpot(config *HubSpotConfig) *HubSpot {
 	return &HubSpot{config: config}
 }
 
-//TestAccess sends get user properties request to HubSpot and check if error has occurred
+//TestAccess sends get contact properties request to HubSpot and check if error has occurred
 func (h *HubSpot) TestAccess() error {
-	_, err := loadContactProperties(h.config.APIKey)
+	client := &hubSpotClient{config: h.config, client: &http.Client{Timeout: hubSpotClientTimeout}}
+	_, err := client.loadProperties(hubSpotContacts)
 	return err
 }
 
@@ -260,51 +616,18 @@ func (h *HubSpot) Type() string {
 	return "HubSpot"
 }
 
-//loadContactProperties requests (HTTP GET) contact properties from HubSpot
-func loadContactProperties(apiKey string) (map[string]bool, error) {
-	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf(hubSpotContactPropertiesAPIURLTemplate, apiKey), nil)
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Add("user-agent", JitsuUserAgent)
-	r, err := http.DefaultClient.Do(req)
-	if r != nil && r.Body != nil {
-		defer r.Body.Close()
-
-		responseBody, err := ioutil.ReadAll(r.Body)
-		if err != nil {
-			return nil, fmt.Errorf("error reading hubspot response body: %v", err)
-		}
-
-		if r.StatusCode == 200 {
-			hubspotProperties := []HubSpotContactProperty{}
-			err = json.Unmarshal(responseBody, &hubspotProperties)
-			if err != nil {
-				return nil, fmt.Errorf("error unmarshalling hubspot response body: %v", err)
-			}
-
-			properties := map[string]bool{}
-			for _, hp := range hubspotProperties {
-				properties[hp.Name] = true
-			}
-
-			return properties, nil
-		}
-
-		hr := &HubSpotResponse{}
-		err = json.Unmarshal(responseBody, hr)
+//hubSpotValue returns JSON string for objects and arrays: HubSpot properties values are strings
+func hubSpotValue(value interface{}) interface{} {
+	switch value.(type) {
+	case map[string]interface{}, []interface{}:
+		b, err := json.Marshal(value)
 		if err != nil {
-			return nil, fmt.Errorf("error unmarshalling hubspot response body: %v", err)
+			return fmt.Sprint(value)
 		}
-
-		return nil, fmt.Errorf("received HTTP code [%d] from HubSpot: %s [%s]: %s", r.StatusCode, hr.Status, hr.Category, hr.Message)
-	}
-
-	if err != nil {
-		return nil, err
+		return string(b)
+	default:
+		return value
 	}
-
-	return nil, errors.New("Empty HubSpot response body")
 }
 
 func reformatFieldName(name string) string {
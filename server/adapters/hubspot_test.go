@@ -0,0 +1,202 @@
+package adapters
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/timestamp"
+	"github.com/stretchr/testify/require"
+)
+
+//hubSpotStandIn is a local HubSpot API for tests
+type hubSpotStandIn struct {
+	sync.Mutex
+
+	properties map[string][]string
+	created    map[string][]string
+	//requests is a map path -> request bodies
+	requests map[string][]string
+}
+
+func newHubSpotStandIn(t *testing.T) (*hubSpotStandIn, *httptest.Server) {
+	standIn := &hubSpotStandIn{
+		properties: map[string][]string{hubSpotContacts: {"email", "firstname"}, hubSpotCompanies: {"domain", "name"}},
+		created:    map[string][]string{},
+		requests:   map[string][]string{},
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		body, _ := ioutil.ReadAll(r.Body)
+
+		standIn.Lock()
+		defer standIn.Unlock()
+		for _, objectType := range []string{hubSpotContacts, hubSpotCompanies} {
+			switch r.URL.Path {
+			case "/crm/v3/properties/" + objectType:
+				response := &HubSpotPropertiesResponse{}
+				for _, name := range standIn.properties[objectType] {
+					response.Results = append(response.Results, &HubSpotProperty{Name: name})
+				}
+				json.NewEncoder(w).Encode(response)
+				return
+			case "/crm/v3/properties/" + objectType + "/batch/create":
+				request := &HubSpotPropertiesRequest{}
+				require.NoError(t, json.Unmarshal(body, request))
+				for _, property := range request.Inputs {
+					standIn.properties[objectType] = append(standIn.properties[objectType], property.Name)
+					standIn.created[objectType] = append(standIn.created[objectType], property.Name)
+				}
+				w.WriteHeader(http.StatusCreated)
+				return
+			}
+		}
+
+		standIn.requests[r.URL.Path] = append(standIn.requests[r.URL.Path], string(body))
+	}))
+
+	return standIn, server
+}
+
+func TestHubSpotCreate(t *testing.T) {
+	standIn, server := newHubSpotStandIn(t)
+	defer server.Close()
+
+	config := &HubSpotConfig{AccessToken: "token", HubID: "123", BaseURL: server.URL + "/", Events: map[string]string{"purchase": "pe123_order_completed"}}
+	require.NoError(t, config.Validate())
+	factory, err := newHubSpotRequestFactory(config)
+	require.NoError(t, err)
+	defer factory.Close()
+
+	tests := []struct {
+		name         string
+		input        map[string]interface{}
+		expectedURL  string
+		expectedBody string
+		expectedErr  string
+	}{
+		{
+			"Contact upsert with new property",
+			map[string]interface{}{"event_type": "user_identify", "user": map[string]interface{}{"email": "a@b.com", "firstName": "John", "Plan Name": "pro", "empty": ""}},
+			server.URL + "/crm/v3/objects/contacts/batch/upsert",
+			`{"inputs":[{"idProperty":"email","id":"a@b.com","properties":{"email":"a@b.com","firstname":"John","plan_name":"pro"}}]}`,
+			"",
+		},
+		{
+			"Company upsert",
+			map[string]interface{}{"event_type": "group", "company": map[string]interface{}{"domain": "jitsu.com", "name": "Jitsu"}},
+			server.URL + "/crm/v3/objects/companies/batch/upsert",
+			`{"inputs":[{"idProperty":"domain","id":"jitsu.com","properties":{"domain":"jitsu.com","name":"Jitsu"}}]}`,
+			"",
+		},
+		{
+			"Custom event with default name",
+			map[string]interface{}{"event_type": "Sign Up", "eventn_ctx": map[string]interface{}{"user": map[string]interface{}{"email": "a@b.com"}},
+				"properties": map[string]interface{}{"Plan": "pro"}, timestamp.Key: time.Date(2021, 12, 31, 10, 0, 0, 0, time.UTC)},
+			server.URL + "/events/v3/send/batch",
+			`{"inputs":[{"eventName":"pe123_sign_up","email":"a@b.com","occurredAt":"2021-12-31T10:00:00.000000Z","properties":{"plan":"pro"}}]}`,
+			"",
+		},
+		{
+			"Custom event with configured name",
+			map[string]interface{}{"event_type": "purchase", "hubspotutk": "utk1"},
+			server.URL + "/events/v3/send/batch",
+			`{"inputs":[{"eventName":"pe123_order_completed","utk":"utk1"}]}`,
+			"",
+		},
+		{
+			"Contact without email",
+			map[string]interface{}{"event_type": "user_identify", "user": map[string]interface{}{"firstname": "John"}},
+			"",
+			"",
+			"Object doesn't have user email: /user/email or /eventn_ctx/user/email is required",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := factory.Create(tt.input)
+			if tt.expectedErr != "" {
+				require.EqualError(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedURL, r.URL)
+			require.Equal(t, "Bearer token", r.Headers["Authorization"])
+			require.JSONEq(t, tt.expectedBody, string(r.Body))
+		})
+	}
+
+	require.Equal(t, []string{"plan_name"}, standIn.created[hubSpotContacts], "missing properties are created once")
+}
+
+func TestHubSpotDisabledPropertiesCreation(t *testing.T) {
+	standIn, server := newHubSpotStandIn(t)
+	defer server.Close()
+
+	config := &HubSpotConfig{AccessToken: "token", HubID: "123", BaseURL: server.URL, DisablePropertiesCreation: true}
+	require.NoError(t, config.Validate())
+	factory, err := newHubSpotRequestFactory(config)
+	require.NoError(t, err)
+	defer factory.Close()
+
+	r, err := factory.Create(map[string]interface{}{"event_type": "user_identify", "user": map[string]interface{}{"email": "a@b.com", "plan": "pro"}})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"inputs":[{"idProperty":"email","id":"a@b.com","properties":{"email":"a@b.com"}}]}`, string(r.Body))
+	require.Empty(t, standIn.created)
+}
+
+func TestHubSpotBatching(t *testing.T) {
+	standIn, server := newHubSpotStandIn(t)
+	defer server.Close()
+
+	config := &HubSpotConfig{AccessToken: "token", HubID: "123", BaseURL: server.URL}
+	require.NoError(t, config.Validate())
+
+	succeeded := make(chan string, 10)
+	hubSpot, err := NewHubSpot(config, &HTTPAdapterConfiguration{
+		DestinationID: "test",
+		Dir:           t.TempDir(),
+		HTTPConfig:    &HTTPConfiguration{GlobalClientTimeout: time.Second, RetryDelay: time.Second, RetryCount: 0},
+		PoolWorkers:   1,
+		DebugLogger:   &logging.QueryLogger{},
+		ErrorHandler: func(fallback bool, eventContext *EventContext, err error) {
+			t.Errorf("Event %s hasn't been sent: %v", eventContext.EventID, err)
+		},
+		SuccessHandler: func(eventContext *EventContext) {
+			succeeded <- eventContext.EventID
+		},
+		Batch: &HTTPBatchConfig{Enabled: true, LingerMs: 500},
+	})
+	require.NoError(t, err)
+	defer hubSpot.Close()
+
+	inputs := []map[string]interface{}{
+		{"event_type": "user_identify", "user": map[string]interface{}{"email": "a@b.com", "firstname": "John"}},
+		{"event_type": "pageview", "user": map[string]interface{}{"email": "a@b.com"}},
+		{"event_type": "user_identify", "user": map[string]interface{}{"email": "a@b.com", "lastname": "Doe"}},
+		{"event_type": "user_identify", "user": map[string]interface{}{"email": "c@d.com"}},
+	}
+	for i, input := range inputs {
+		require.NoError(t, hubSpot.Insert(&EventContext{EventID: string(rune('1' + i)), ProcessedEvent: input}))
+	}
+	for range inputs {
+		select {
+		case <-succeeded:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timeout waiting for HubSpot batches")
+		}
+	}
+
+	standIn.Lock()
+	defer standIn.Unlock()
+	require.Equal(t, []string{`{"inputs":[{"idProperty":"email","id":"a@b.com","properties":{"email":"a@b.com","firstname":"John","lastname":"Doe"}},{"idProperty":"email","id":"c@d.com","properties":{"email":"c@d.com"}}]}`},
+		standIn.requests["/crm/v3/objects/contacts/batch/upsert"], "the same contact upserts are merged")
+	require.Len(t, standIn.requests["/events/v3/send/batch"], 1)
+	require.Equal(t, []string{"lastname"}, standIn.created[hubSpotContacts])
+}
@@ -0,0 +1,321 @@
+package adapters
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	mixpanelDefaultBaseURL = "https://api.mixpanel.com"
+	mixpanelImportPath     = "/import"
+	mixpanelEngagePath     = "/engage"
+	mixpanelTrackPath      = "/track"
+	mixpanelPageViewEvent  = "$mp_web_page_view"
+	//mixpanelMaxBatchEvents is an /import and /engage API limit
+	mixpanelMaxBatchEvents = 2000
+)
+
+var (
+	//mixpanelUserProperties are Jitsu user fields which are sent as Mixpanel reserved profile properties
+	mixpanelUserProperties = map[string]string{
+		"email":      "$email",
+		"name":       "$name",
+		"first_name": "$first_name",
+		"firstName":  "$first_name",
+		"last_name":  "$last_name",
+		"lastName":   "$last_name",
+		"phone":      "$phone",
+		"avatar":     "$avatar",
+		"created_at": "$created",
+	}
+)
+
+//MixpanelConfig is a dto for parsing Mixpanel configuration
+type MixpanelConfig struct {
+	ProjectToken string `mapstructure:"project_token" json:"project_token,omitempty" yaml:"project_token,omitempty"`
+	APISecret    string `mapstructure:"api_secret" json:"api_secret,omitempty" yaml:"api_secret,omitempty"`
+	ProjectID    string `mapstructure:"project_id" json:"project_id,omitempty" yaml:"project_id,omitempty"`
+	BaseURL      string `mapstructure:"base_url" json:"base_url,omitempty" yaml:"base_url,omitempty"`
+}
+
+//Validate returns err if invalid and sets default base URL
+func (mc *MixpanelConfig) Validate() error {
+	if mc == nil {
+		return errors.New("mixpanel config is required")
+	}
+	if mc.ProjectToken == "" {
+		return errors.New("'project_token' is required parameter")
+	}
+	if mc.APISecret == "" {
+		return errors.New("'api_secret' is required parameter")
+	}
+	if mc.BaseURL == "" {
+		mc.BaseURL = mixpanelDefaultBaseURL
+	}
+	mc.BaseURL = strings.TrimSuffix(mc.BaseURL, "/")
+
+	return nil
+}
+
+//MixpanelEvent is a dto for sending events to Mixpanel /import and /track APIs
+type MixpanelEvent struct {
+	Event      string                 `json:"event"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+//MixpanelProfileUpdate is a dto for sending user profile properties to Mixpanel /engage API
+type MixpanelProfileUpdate struct {
+	Token      string                 `json:"$token"`
+	DistinctID string                 `json:"$distinct_id"`
+	IP         string                 `json:"$ip,omitempty"`
+	Set        map[string]interface{} `json:"$set"`
+}
+
+//MixpanelImportResponse is a dto for receiving response from Mixpanel /import API
+//failed_records contain indexes of invalid events (on 400 Bad Request in strict mode)
+type MixpanelImportResponse struct {
+	Code               int                     `json:"code"`
+	Error              string                  `json:"error"`
+	NumRecordsImported int                     `json:"num_records_imported"`
+	FailedRecords      []*MixpanelFailedRecord `json:"failed_records,omitempty"`
+}
+
+//MixpanelFailedRecord is a dto for parsing Mixpanel invalid event
+type MixpanelFailedRecord struct {
+	Index    int    `json:"index"`
+	InsertID string `json:"$insert_id"`
+	Field    string `json:"field"`
+	Message  string `json:"message"`
+}
+
+//MixpanelRequestFactory is a factory for building Mixpanel HTTP requests from input events:
+//user_identify events update user profiles via /engage API
+//$identify events link anonymous and identified users via /track API
+//other events are sent via /import API
+type MixpanelRequestFactory struct {
+	config        *MixpanelConfig
+	importURL     string
+	authorization string
+}
+
+//newMixpanelRequestFactory returns configured HTTPRequestFactory instance for Mixpanel requests
+func newMixpanelRequestFactory(config *MixpanelConfig) *MixpanelRequestFactory {
+	uv := url.Values{}
+	uv.Set("strict", "1")
+	if config.ProjectID != "" {
+		uv.Set("project_id", config.ProjectID)
+	}
+
+	return &MixpanelRequestFactory{
+		config:        config,
+		importURL:     config.BaseURL + mixpanelImportPath + "?" + uv.Encode(),
+		authorization: "Basic " + base64.StdEncoding.EncodeToString([]byte(config.APISecret+":")),
+	}
+}
+
+//Create returns Mixpanel request with one event or profile update built from the object
+func (mrf *MixpanelRequestFactory) Create(object map[string]interface{}) (*Request, error) {
+	event, err := newAnalyticsEvent(object)
+	if err != nil {
+		return nil, err
+	}
+
+	switch event.Type {
+	case UserIdentifyEventType:
+		profileUpdate := &MixpanelProfileUpdate{Token: mrf.config.ProjectToken, DistinctID: event.DistinctID, IP: event.IP, Set: map[string]interface{}{}}
+		for name, value := range event.UserProperties {
+			if reserved, ok := mixpanelUserProperties[name]; ok {
+				name = reserved
+			}
+			profileUpdate.Set[name] = value
+		}
+		return mrf.newRequest(mrf.config.BaseURL+mixpanelEngagePath+"?verbose=1", false, []*MixpanelProfileUpdate{profileUpdate})
+	case IdentifyEventType:
+		identifyEvent := &MixpanelEvent{Event: IdentifyEventType, Properties: map[string]interface{}{
+			"token":          mrf.config.ProjectToken,
+			"distinct_id":    event.AnonymousID,
+			"$anon_id":       event.AnonymousID,
+			"$identified_id": event.UserID,
+		}}
+		return mrf.newRequest(mrf.config.BaseURL+mixpanelTrackPath+"?verbose=1", false, []*MixpanelEvent{identifyEvent})
+	}
+
+	properties := event.Properties
+	properties["token"] = mrf.config.ProjectToken
+	properties["time"] = event.Time.UnixNano() / 1e6
+	properties["distinct_id"] = event.DistinctID
+	properties["$insert_id"] = event.InsertID
+	if event.AnonymousID != "" {
+		properties["$device_id"] = event.AnonymousID
+	}
+	if event.UserID != "" {
+		properties["$user_id"] = event.UserID
+	}
+	if event.IP != "" {
+		properties["ip"] = event.IP
+	}
+
+	eventName := event.Type
+	switch strings.ToLower(eventName) {
+	case "pageview", "page", "app_page":
+		eventName = mixpanelPageViewEvent
+	}
+
+	return mrf.newRequest(mrf.importURL, true, []*MixpanelEvent{{Event: eventName, Properties: properties}})
+}
+
+//newRequest returns HTTP POST request with JSON array body
+//authorized requests contain Authorization header with api secret
+func (mrf *MixpanelRequestFactory) newRequest(url string, authorized bool, payload interface{}) (*Request, error) {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("Error marshalling mixpanel request [%v]: %v", payload, err)
+	}
+
+	headers := map[string]string{"Content-Type": "application/json"}
+	if authorized {
+		headers["Authorization"] = mrf.authorization
+	}
+
+	return &Request{
+		URL:     url,
+		Method:  http.MethodPost,
+		Body:    b,
+		Headers: headers,
+	}, nil
+}
+
+//BatchKey returns URL of /import and /engage requests: they accept arrays of events
+//$identify requests are sent one by one
+func (mrf *MixpanelRequestFactory) BatchKey(req *Request) string {
+	if strings.HasPrefix(req.URL, mrf.config.BaseURL+mixpanelTrackPath) {
+		return ""
+	}
+
+	return req.URL
+}
+
+//CreateBatch returns one request with events (or profile updates) from all requests
+func (mrf *MixpanelRequestFactory) CreateBatch(requests []*Request) (*Request, error) {
+	var items []json.RawMessage
+	for _, r := range requests {
+		var reqItems []json.RawMessage
+		if err := json.Unmarshal(r.Body, &reqItems); err != nil {
+			return nil, fmt.Errorf("Error unmarshalling mixpanel request: %v", err)
+		}
+		items = append(items, reqItems...)
+	}
+
+	b, err := json.Marshal(items)
+	if err != nil {
+		return nil, fmt.Errorf("Error marshalling mixpanel batch request: %v", err)
+	}
+	return &Request{
+		URL:     requests[0].URL,
+		Method:  http.MethodPost,
+		Body:    b,
+		Headers: requests[0].Headers,
+	}, nil
+}
+
+//MaxBatchEvents returns Mixpanel /import and /engage API limit
+func (mrf *MixpanelRequestFactory) MaxBatchEvents() int {
+	return mixpanelMaxBatchEvents
+}
+
+//BatchItemErrors returns errors of invalid events from 400 Bad Request response of /import API in strict mode
+//Mixpanel imports all valid events of the batch in this case
+func (mrf *MixpanelRequestFactory) BatchItemErrors(statusCode int, responseBody []byte, batchSize int) map[int]error {
+	itemErrors := map[int]error{}
+	if statusCode != http.StatusBadRequest {
+		return itemErrors
+	}
+
+	response := &MixpanelImportResponse{}
+	if err := json.Unmarshal(responseBody, response); err != nil {
+		return itemErrors
+	}
+
+	for _, record := range response.FailedRecords {
+		if record.Index >= 0 && record.Index < batchSize {
+			itemErrors[record.Index] = fmt.Errorf("Mixpanel event has invalid field [%s]: %s", record.Field, record.Message)
+		}
+	}
+
+	return itemErrors
+}
+
+func (mrf *MixpanelRequestFactory) Close() {
+}
+
+//Mixpanel is an adapter for sending events and user profiles to Mixpanel
+type Mixpanel struct {
+	AbstractHTTP
+
+	config *MixpanelConfig
+}
+
+//NewMixpanel returns configured Mixpanel adapter instance
+func NewMixpanel(config *MixpanelConfig, httpAdapterConfiguration *HTTPAdapterConfiguration) (*Mixpanel, error) {
+	httpAdapterConfiguration.HTTPReqFactory = newMixpanelRequestFactory(config)
+
+	httpAdapter, err := NewHTTPAdapter(httpAdapterConfiguration)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Mixpanel{config: config}
+	m.httpAdapter = httpAdapter
+	return m, nil
+}
+
+//NewTestMixpanel returns test instance of adapter
+func NewTestMixpanel(config *MixpanelConfig) *Mixpanel {
+	return &Mixpanel{config: config}
+}
+
+//TestAccess sends empty events array to Mixpanel /import API and returns err if credentials are invalid
+func (m *Mixpanel) TestAccess() error {
+	httpReqFactory := newMixpanelRequestFactory(m.config)
+	r, err := httpReqFactory.newRequest(httpReqFactory.importURL, true, []*MixpanelEvent{})
+	if err != nil {
+		return err
+	}
+
+	httpReq, err := http.NewRequest(r.Method, r.URL, bytes.NewBuffer(r.Body))
+	if err != nil {
+		return err
+	}
+	for k, v := range r.Headers {
+		httpReq.Header.Set(k, v)
+	}
+
+	resp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	responseBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("Error reading mixpanel response body: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Error connecting to mixpanel [code=%d]: %s", resp.StatusCode, string(responseBody))
+	}
+
+	return nil
+}
+
+//Type returns adapter type
+func (m *Mixpanel) Type() string {
+	return "Mixpanel"
+}
@@ -0,0 +1,116 @@
+package adapters
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/timestamp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMixpanelCreate(t *testing.T) {
+	config := &MixpanelConfig{ProjectToken: "token", APISecret: "secret", ProjectID: "123"}
+	require.NoError(t, config.Validate())
+	factory := newMixpanelRequestFactory(config)
+
+	eventTime := time.Date(2021, 12, 31, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name         string
+		input        map[string]interface{}
+		expectedURL  string
+		expectedAuth string
+		expectedBody string
+		expectedErr  string
+	}{
+		{
+			"Anonymous pageview",
+			map[string]interface{}{"event_type": "pageview", "eventn_ctx_event_id": "e1", "source_ip": "1.1.1.1", timestamp.Key: eventTime,
+				"eventn_ctx": map[string]interface{}{"url": "https://jitsu.com", "user": map[string]interface{}{"anonymous_id": "anon1"}}},
+			"https://api.mixpanel.com/import?project_id=123&strict=1",
+			"Basic c2VjcmV0Og==",
+			`[{"event":"$mp_web_page_view","properties":{"token":"token","time":1640944800000,"distinct_id":"anon1","$device_id":"anon1",
+				"$insert_id":"e1","ip":"1.1.1.1","$current_url":"https://jitsu.com"}}]`,
+			"",
+		},
+		{
+			"Identified custom event",
+			map[string]interface{}{"event_type": "purchase", "eventn_ctx_event_id": "e2", "value": 10, timestamp.Key: "2021-12-31T10:00:00.000000Z",
+				"user": map[string]interface{}{"id": "u1", "anonymous_id": "anon1"}, "properties": map[string]interface{}{"currency": "USD"}},
+			"https://api.mixpanel.com/import?project_id=123&strict=1",
+			"Basic c2VjcmV0Og==",
+			`[{"event":"purchase","properties":{"token":"token","time":1640944800000,"distinct_id":"u1","$device_id":"anon1","$user_id":"u1",
+				"$insert_id":"e2","value":10,"currency":"USD"}}]`,
+			"",
+		},
+		{
+			"User profile",
+			map[string]interface{}{"event_type": "user_identify", "user": map[string]interface{}{"id": "u1", "anonymous_id": "anon1", "email": "a@b.com", "plan": "pro"}},
+			"https://api.mixpanel.com/engage?verbose=1",
+			"",
+			`[{"$token":"token","$distinct_id":"u1","$set":{"$email":"a@b.com","plan":"pro"}}]`,
+			"",
+		},
+		{
+			"Identity linking",
+			map[string]interface{}{"event_type": "$identify", "user": map[string]interface{}{"id": "u1", "anonymous_id": "anon1"}},
+			"https://api.mixpanel.com/track?verbose=1",
+			"",
+			`[{"event":"$identify","properties":{"token":"token","distinct_id":"anon1","$anon_id":"anon1","$identified_id":"u1"}}]`,
+			"",
+		},
+		{
+			"Event without user",
+			map[string]interface{}{"event_type": "pageview"},
+			"",
+			"",
+			"",
+			"Object doesn't have distinct_id: user id, email or anonymous_id is required",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := factory.Create(tt.input)
+			if tt.expectedErr != "" {
+				require.EqualError(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedURL, r.URL)
+			require.Equal(t, tt.expectedAuth, r.Headers["Authorization"])
+			require.JSONEq(t, tt.expectedBody, string(r.Body))
+		})
+	}
+}
+
+func TestMixpanelBatch(t *testing.T) {
+	config := &MixpanelConfig{ProjectToken: "token", APISecret: "secret"}
+	require.NoError(t, config.Validate())
+	factory := newMixpanelRequestFactory(config)
+
+	first, err := factory.Create(map[string]interface{}{"event_type": "a", "eventn_ctx_event_id": "e1", "user": map[string]interface{}{"anonymous_id": "anon1"}})
+	require.NoError(t, err)
+	second, err := factory.Create(map[string]interface{}{"event_type": "b", "eventn_ctx_event_id": "e2", "user": map[string]interface{}{"anonymous_id": "anon1"}})
+	require.NoError(t, err)
+	identify, err := factory.Create(map[string]interface{}{"event_type": "$identify", "user": map[string]interface{}{"id": "u1", "anonymous_id": "anon1"}})
+	require.NoError(t, err)
+
+	require.Equal(t, factory.BatchKey(first), factory.BatchKey(second))
+	require.Empty(t, factory.BatchKey(identify), "identity linking requests are sent one by one")
+
+	batch, err := factory.CreateBatch([]*Request{first, second})
+	require.NoError(t, err)
+	require.Equal(t, first.URL, batch.URL)
+	require.Equal(t, "Basic c2VjcmV0Og==", batch.Headers["Authorization"])
+
+	var items []*MixpanelEvent
+	require.NoError(t, json.Unmarshal(batch.Body, &items))
+	require.Len(t, items, 2)
+	require.Equal(t, "a", items[0].Event)
+	require.Equal(t, "b", items[1].Event)
+
+	itemErrors := factory.BatchItemErrors(400, []byte(`{"code":400,"error":"some data points in the request failed validation","num_records_imported":1,
+		"failed_records":[{"index":1,"$insert_id":"e2","field":"properties.time","message":"'properties.time' is invalid"}]}`), 2)
+	require.Len(t, itemErrors, 1)
+	require.EqualError(t, itemErrors[1], "Mixpanel event has invalid field [properties.time]: 'properties.time' is invalid")
+}
@@ -23,6 +23,13 @@ const (
 									column_name AS name
 								FROM information_schema.columns
 								WHERE table_schema = ? AND table_name = ? AND column_key = 'PRI'`
+	mySQLTableNamesQuery = `SELECT table_name FROM information_schema.tables WHERE table_schema = ? AND table_type = 'BASE TABLE'`
+	mySQLPartitionsQuery = `SELECT
+									partition_name AS name
+								FROM information_schema.partitions
+								WHERE table_schema = ? AND table_name = ? AND partition_name IS NOT NULL
+								ORDER BY partition_ordinal_position`
+	mySQLPartitionedTablesQuery      = `SELECT DISTINCT table_name FROM information_schema.partitions WHERE table_schema = ? AND partition_name = ?`
 	mySQLCreateDBIfNotExistsTemplate = "CREATE DATABASE IF NOT EXISTS `%s`"
 	mySQLCreateTableTemplate         = "CREATE TABLE `%s`.`%s` (%s)"
 	mySQLInsertTemplate              = "INSERT INTO `%s`.`%s` (%s) VALUES %s"
@@ -30,10 +37,18 @@ const (
 	mySQLMergeTemplate               = "INSERT INTO `%s`.`%s` (%s) VALUES %s ON DUPLICATE KEY UPDATE %s"
 	mySQLBulkMergeTemplate           = "INSERT INTO `%s`.`%s` (%s) SELECT * FROM (SELECT %s FROM `%s`.`%s`) AS tmp ON DUPLICATE KEY UPDATE %s"
 	mySQLDeleteQueryTemplate         = "DELETE FROM `%s`.`%s` WHERE %s"
+	mySQLCountQueryTemplate          = "SELECT count(*) FROM `%s`.`%s` WHERE %s"
 	mySQLAddColumnTemplate           = "ALTER TABLE `%s`.`%s` ADD COLUMN %s"
 	mySQLDropPrimaryKeyTemplate      = "ALTER TABLE `%s`.`%s` DROP PRIMARY KEY"
 	mySQLDropTableTemplate           = "DROP TABLE `%s`.`%s`"
 	mySQLTruncateTableTemplate       = "TRUNCATE TABLE `%s`.`%s`"
+	mySQLPartitionByRangeTemplate    = " PARTITION BY RANGE COLUMNS(`%s`) (%s)"
+	mySQLPartitionTemplate           = "PARTITION `%s` VALUES LESS THAN ('%s')"
+	mySQLMaxValuePartitionTemplate   = "PARTITION `%s` VALUES LESS THAN (MAXVALUE)"
+	mySQLReorganizePartitionTemplate = "ALTER TABLE `%s`.`%s` REORGANIZE PARTITION `%s` INTO (%s)"
+	mySQLDropPartitionsTemplate      = "ALTER TABLE `%s`.`%s` DROP PARTITION %s"
+	mySQLMaxValuePartition           = "pmax"
+	mySQLPartitionBoundLayout        = "2006-01-02 15:04:05"
 	mySQLPrimaryKeyMaxLength         = 32
 	mySQLValuesLimit                 = 65535 // this is a limitation of parameters one can pass as query values. If more parameters are passed, error is returned
 )
@@ -46,6 +61,8 @@ var (
 		typing.TIMESTAMP: "DATETIME", // TIMESTAMP type only supports values from 1970 to 2038, DATETIME doesn't have such constrains
 		typing.BOOL:      "BOOLEAN",
 		typing.UNKNOWN:   "TEXT",
+		typing.OBJECT:    "JSON",
+		typing.ARRAY:     "JSON",
 	}
 )
 
@@ -57,10 +74,14 @@ type MySQL struct {
 	queryLogger *logging.QueryLogger
 
 	sqlTypes typing.SQLTypes
+
+	//partitioning can be nil: tables aren't partitioned
+	partitioning *PartitioningConfig
 }
 
 //NewMySQL returns configured MySQL adapter instance
-func NewMySQL(ctx context.Context, config *DataSourceConfig, queryLogger *logging.QueryLogger, sqlTypes typing.SQLTypes) (*MySQL, error) {
+//partitioning can be nil
+func NewMySQL(ctx context.Context, config *DataSourceConfig, partitioning *PartitioningConfig, queryLogger *logging.QueryLogger, sqlTypes typing.SQLTypes) (*MySQL, error) {
 	if _, ok := config.Parameters["tls"]; !ok {
 		// similar to postgres default value of sslmode option
 		config.Parameters["tls"] = "preferred"
@@ -81,7 +102,8 @@ func NewMySQL(ctx context.Context, config *DataSourceConfig, queryLogger *loggin
 	dataSource.SetMaxOpenConns(50)
 	dataSource.SetMaxIdleConns(50)
 
-	return &MySQL{ctx: ctx, config: config, dataSource: dataSource, queryLogger: queryLogger, sqlTypes: reformatMappings(sqlTypes, SchemaToMySQL)}, nil
+	return &MySQL{ctx: ctx, config: config, dataSource: dataSource, queryLogger: queryLogger, sqlTypes: reformatMappings(sqlTypes, SchemaToMySQL),
+		partitioning: partitioning}, nil
 }
 
 //Type returns MySQL type
@@ -112,13 +134,14 @@ func (m *MySQL) CreateDB(dbName string) error {
 }
 
 //CreateTable creates database table with name,columns provided in Table representation
+//if partitioning is configured creates table partitioned by range with current, upcoming and MAXVALUE partitions
 func (m *MySQL) CreateTable(table *Table) error {
 	wrappedTx, err := m.OpenTx()
 	if err != nil {
 		return err
 	}
 
-	err = m.createTableInTransaction(wrappedTx, table)
+	err = m.createTableInTransaction(wrappedTx, table, m.isPartitioned(table))
 	if err != nil {
 		wrappedTx.Rollback()
 		return err
@@ -218,6 +241,21 @@ func (m *MySQL) BulkUpdate(table *Table, objects []map[string]interface{}, delet
 	return wrappedTx.DirectCommit()
 }
 
+//Delete deletes objects with deleteConditions in transaction
+func (m *MySQL) Delete(table *Table, deleteConditions *DeleteConditions) error {
+	wrappedTx, err := m.OpenTx()
+	if err != nil {
+		return err
+	}
+
+	if err := m.deleteInTransaction(wrappedTx, table, deleteConditions); err != nil {
+		wrappedTx.Rollback()
+		return err
+	}
+
+	return wrappedTx.DirectCommit()
+}
+
 //DropTable drops table in transaction
 func (m *MySQL) DropTable(table *Table) error {
 	wrappedTx, err := m.OpenTx()
@@ -253,7 +291,7 @@ func (m *MySQL) toDeleteQuery(conditions *DeleteConditions) (string, []interface
 		queryConditions = append(queryConditions, quotedField+" "+condition.Clause+" ?")
 		values = append(values, condition.Value)
 	}
-	return strings.Join(queryConditions, conditions.JoinCondition), values
+	return strings.Join(queryConditions, " "+conditions.JoinCondition+" "), values
 }
 
 //Truncate deletes all records in tableName table
@@ -267,6 +305,30 @@ func (m *MySQL) Truncate(tableName string) error {
 	return sqlParams.commonTruncate(tableName, statement)
 }
 
+//GetTableNames returns names of all tables in the database
+func (m *MySQL) GetTableNames() ([]string, error) {
+	sqlParams := SqlParams{
+		dataSource:  m.dataSource,
+		queryLogger: m.queryLogger,
+		ctx:         m.ctx,
+	}
+	return sqlParams.commonGetTableNames(mySQLTableNamesQuery, m.config.Db)
+}
+
+//DeleteOlderThan deletes (or counts if dryRun) rows which field value is before the time
+func (m *MySQL) DeleteOlderThan(table *Table, field string, before time.Time, dryRun bool) (int64, error) {
+	sqlParams := SqlParams{
+		dataSource:  m.dataSource,
+		queryLogger: m.queryLogger,
+		ctx:         m.ctx,
+	}
+	condition, values := m.toDeleteQuery(olderThanConditions(field, before))
+	return sqlParams.commonDeleteOlderThan(table.Name,
+		fmt.Sprintf(mySQLCountQueryTemplate, m.config.Db, table.Name, condition),
+		fmt.Sprintf(mySQLDeleteQueryTemplate, m.config.Db, table.Name, condition),
+		values, dryRun)
+}
+
 //Close underlying sql.DB
 func (m *MySQL) Close() error {
 	return m.dataSource.Close()
@@ -453,7 +515,7 @@ func (m *MySQL) bulkMergeInTransaction(wrappedTx *Transaction, table *Table, obj
 		Version:        0,
 	}
 
-	err := m.createTableInTransaction(wrappedTx, tmpTable)
+	err := m.createTableInTransaction(wrappedTx, tmpTable, false)
 	if err != nil {
 		return fmt.Errorf("Error creating temporary table: %v", err)
 	}
@@ -579,7 +641,9 @@ func (m *MySQL) createPrimaryKeyInTransaction(wrappedTx *Transaction, table *Tab
 //create table columns and pk key
 //override input table sql type with configured cast type
 //make fields from Table PkFields - 'not null'
-func (m *MySQL) createTableInTransaction(wrappedTx *Transaction, table *Table) error {
+//partitioned: create table partitioned by range of partitioning field with current, upcoming and MAXVALUE partitions
+//(the first partition also contains all older rows)
+func (m *MySQL) createTableInTransaction(wrappedTx *Transaction, table *Table, partitioned bool) error {
 	var columnsDDL []string
 	pkFields := table.GetPKFieldsMap()
 	for columnName, column := range table.Columns {
@@ -589,6 +653,9 @@ func (m *MySQL) createTableInTransaction(wrappedTx *Transaction, table *Table) e
 	//sorting columns asc
 	sort.Strings(columnsDDL)
 	query := fmt.Sprintf(mySQLCreateTableTemplate, m.config.Db, table.Name, strings.Join(columnsDDL, ", "))
+	if partitioned {
+		query += fmt.Sprintf(mySQLPartitionByRangeTemplate, m.partitioning.Field, m.partitionsDefinition(m.partitioning.UpcomingPartitions(time.Now())))
+	}
 	m.queryLogger.LogDDL(query)
 
 	_, err := wrappedTx.tx.ExecContext(m.ctx, query)
@@ -605,6 +672,112 @@ func (m *MySQL) createTableInTransaction(wrappedTx *Transaction, table *Table) e
 	return nil
 }
 
+//isPartitioned returns true if partitioning is configured and can be applied to the table
+func (m *MySQL) isPartitioned(table *Table) bool {
+	return m.partitioning != nil && m.partitioning.isApplicable(table)
+}
+
+//MaintainPartitions adds upcoming partitions (by reorganizing MAXVALUE partition) and drops expired partitions
+//of partitioned tables created by Jitsu (with MAXVALUE partition)
+//tables are listed from the database, so tables created by other Jitsu nodes or before restart are maintained too
+func (m *MySQL) MaintainPartitions() {
+	if m.partitioning == nil {
+		return
+	}
+
+	sqlParams := SqlParams{
+		dataSource:  m.dataSource,
+		queryLogger: m.queryLogger,
+		ctx:         m.ctx,
+	}
+	tableNames, err := sqlParams.commonGetTableNames(mySQLPartitionedTablesQuery, m.config.Db, mySQLMaxValuePartition)
+	if err != nil {
+		logging.Errorf("Error getting partitioned tables: %v", err)
+		return
+	}
+
+	now := time.Now()
+	for _, tableName := range tableNames {
+		partitions, err := m.getPartitions(tableName)
+		if err != nil {
+			logging.Errorf("Error getting [%s] table partitions: %v", tableName, err)
+			continue
+		}
+		//isn't partitioned by Jitsu
+		if len(partitions) == 0 || partitions[len(partitions)-1] != mySQLMaxValuePartition {
+			continue
+		}
+
+		var statements []string
+		//partitions are ordered: new ones can be added only after the last one
+		var lastPartitionName string
+		if len(partitions) > 1 {
+			lastPartitionName = strings.TrimPrefix(partitions[len(partitions)-2], "p")
+		}
+		var newPartitions []*Partition
+		for _, partition := range m.partitioning.UpcomingPartitions(now) {
+			if partition.Name > lastPartitionName {
+				newPartitions = append(newPartitions, partition)
+			}
+		}
+		if len(newPartitions) > 0 {
+			statements = append(statements, fmt.Sprintf(mySQLReorganizePartitionTemplate, m.config.Db, tableName, mySQLMaxValuePartition, m.partitionsDefinition(newPartitions)))
+		}
+
+		var expired []string
+		for _, partitionName := range partitions[:len(partitions)-1] {
+			if m.partitioning.IsExpired(strings.TrimPrefix(partitionName, "p"), now) {
+				expired = append(expired, m.quote(partitionName))
+			}
+		}
+		if len(expired) > 0 {
+			statements = append(statements, fmt.Sprintf(mySQLDropPartitionsTemplate, m.config.Db, tableName, strings.Join(expired, ", ")))
+		}
+
+		for _, statement := range statements {
+			m.queryLogger.LogDDL(statement)
+			if _, err := m.dataSource.ExecContext(m.ctx, statement); err != nil {
+				logging.Errorf("Error maintaining [%s] table partitions with statement [%s]: %v", tableName, statement, err)
+			}
+		}
+	}
+}
+
+//getPartitions returns ordered partitions names of the table
+func (m *MySQL) getPartitions(tableName string) ([]string, error) {
+	rows, err := m.dataSource.QueryContext(m.ctx, mySQLPartitionsQuery, m.config.Db, tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	var partitions []string
+	for rows.Next() {
+		var partitionName string
+		if err := rows.Scan(&partitionName); err != nil {
+			return nil, fmt.Errorf("Error scanning result: %v", err)
+		}
+		partitions = append(partitions, partitionName)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("Last rows.Err: %v", err)
+	}
+
+	return partitions, nil
+}
+
+//partitionsDefinition returns partitions DDL with MAXVALUE partition at the end
+func (m *MySQL) partitionsDefinition(partitions []*Partition) string {
+	var definitions []string
+	for _, partition := range partitions {
+		definitions = append(definitions, fmt.Sprintf(mySQLPartitionTemplate, "p"+partition.Name, partition.To.Format(mySQLPartitionBoundLayout)))
+	}
+	definitions = append(definitions, fmt.Sprintf(mySQLMaxValuePartitionTemplate, mySQLMaxValuePartition))
+
+	return strings.Join(definitions, ", ")
+}
+
 func (m *MySQL) buildConstraintName(tableName string) string {
 	return m.quote(fmt.Sprintf("%s_%s_pk", m.config.Db, tableName))
 }
@@ -679,7 +852,7 @@ func (m *MySQL) buildInsertPayload(valuesMap map[string]interface{}) ([]string,
 		header[i] = name
 
 		placeholders[i] = "?"
-		values[i] = value
+		values[i] = nestedToJSON(value)
 		i++
 	}
 
@@ -693,5 +866,5 @@ func (m *MySQL) mapColumnValue(columnVal interface{}) interface{} {
 			return time.Date(1, 1, 1, 0, 0, 0, 1, time.UTC)
 		}
 	}
-	return columnVal
+	return nestedToJSON(columnVal)
 }
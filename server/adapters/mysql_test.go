@@ -123,7 +123,7 @@ func setupMySQLDatabase(t *testing.T, table *Table) (*test.MySQLContainer, *MySQ
 		Db:         container.Database,
 		Parameters: map[string]string{"tls": "false"},
 	}
-	adapter, err := NewMySQL(ctx, dsConfig, &logging.QueryLogger{}, typing.SQLTypes{})
+	adapter, err := NewMySQL(ctx, dsConfig, nil, &logging.QueryLogger{}, typing.SQLTypes{})
 	if err != nil {
 		t.Fatalf("Failed to create MySQL adapter: %v", err)
 	}
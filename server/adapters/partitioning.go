@@ -0,0 +1,149 @@
+package adapters
+
+import (
+	"errors"
+	"fmt"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/timestamp"
+	"strings"
+	"time"
+)
+
+const (
+	PartitionByDay   = "day"
+	PartitionByMonth = "month"
+
+	defaultPartitionsPremake    = 3
+	defaultPartitioningSchedule = "0 * * * *"
+)
+
+//PartitionsMaintainer is implemented by SQL adapters which support time partitioning of tables
+type PartitionsMaintainer interface {
+	//MaintainPartitions creates upcoming and drops expired partitions of tables created or written by Jitsu
+	MaintainPartitions()
+}
+
+//PartitioningConfig dto for declarative time partitioning of tables created by Jitsu
+type PartitioningConfig struct {
+	//Granularity is a partition period: day or month
+	Granularity string `mapstructure:"granularity" json:"granularity,omitempty" yaml:"granularity,omitempty"`
+	//Field is a timestamp column which is used as a partition key
+	Field string `mapstructure:"field" json:"field,omitempty" yaml:"field,omitempty"`
+	//Premake is a number of upcoming partitions which are created in advance
+	Premake int `mapstructure:"premake" json:"premake,omitempty" yaml:"premake,omitempty"`
+	//Retention is a number of partitions (the current one included) to keep. Older partitions are dropped. 0 means keep all
+	Retention int `mapstructure:"retention" json:"retention,omitempty" yaml:"retention,omitempty"`
+	//Schedule of partitions maintenance in standard cron format
+	Schedule string `mapstructure:"schedule" json:"schedule,omitempty" yaml:"schedule,omitempty"`
+}
+
+//Partition is a time range [From, To) of one table partition
+type Partition struct {
+	//Name is a partition suffix: 20211231 (day) or 202112 (month)
+	Name string
+	From time.Time
+	To   time.Time
+}
+
+//Validate returns err if invalid and sets default values
+func (pc *PartitioningConfig) Validate() error {
+	if pc == nil {
+		return nil
+	}
+
+	pc.Granularity = strings.ToLower(pc.Granularity)
+	if pc.Granularity != PartitionByDay && pc.Granularity != PartitionByMonth {
+		return fmt.Errorf("Unknown partitioning granularity: [%s]. Supported: %s, %s", pc.Granularity, PartitionByDay, PartitionByMonth)
+	}
+	if pc.Premake < 0 {
+		return errors.New("partitioning.premake can't be negative")
+	}
+	if pc.Retention < 0 {
+		return errors.New("partitioning.retention can't be negative")
+	}
+
+	if pc.Field == "" {
+		pc.Field = timestamp.Key
+	}
+	if pc.Premake == 0 {
+		pc.Premake = defaultPartitionsPremake
+	}
+	if pc.Schedule == "" {
+		pc.Schedule = defaultPartitioningSchedule
+	}
+
+	return nil
+}
+
+//PartitionOf returns the partition which contains t
+func (pc *PartitioningConfig) PartitionOf(t time.Time) *Partition {
+	t = t.UTC()
+	var from, to time.Time
+	if pc.Granularity == PartitionByDay {
+		from = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		to = from.AddDate(0, 0, 1)
+	} else {
+		from = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+		to = from.AddDate(0, 1, 0)
+	}
+
+	return &Partition{Name: from.Format(pc.nameLayout()), From: from, To: to}
+}
+
+//UpcomingPartitions returns the current partition and Premake next ones
+func (pc *PartitioningConfig) UpcomingPartitions(now time.Time) []*Partition {
+	partition := pc.PartitionOf(now)
+	partitions := []*Partition{partition}
+	for i := 0; i < pc.Premake; i++ {
+		partition = pc.PartitionOf(partition.To)
+		partitions = append(partitions, partition)
+	}
+
+	return partitions
+}
+
+//IsExpired returns true if the partition with the name is older than Retention partitions (the current one included)
+//returns false if retention isn't configured or the name isn't a partition name
+func (pc *PartitioningConfig) IsExpired(name string, now time.Time) bool {
+	if pc.Retention == 0 {
+		return false
+	}
+
+	from, err := time.Parse(pc.nameLayout(), name)
+	if err != nil {
+		return false
+	}
+
+	boundary := pc.PartitionOf(now).From
+	if pc.Granularity == PartitionByDay {
+		boundary = boundary.AddDate(0, 0, 1-pc.Retention)
+	} else {
+		boundary = boundary.AddDate(0, 1-pc.Retention, 0)
+	}
+
+	return from.Before(boundary)
+}
+
+//isApplicable returns true if the table can be partitioned: it has partitioning field
+//and its primary key (if any) includes partitioning field (required by Postgres and MySQL)
+func (pc *PartitioningConfig) isApplicable(table *Table) bool {
+	if _, ok := table.Columns[pc.Field]; !ok {
+		logging.Warnf("Table [%s] doesn't have partitioning field [%s] and will be created without partitioning", table.Name, pc.Field)
+		return false
+	}
+
+	if len(table.PKFields) > 0 && !table.PKFields[pc.Field] {
+		logging.Warnf("Table [%s] primary key [%s] doesn't include partitioning field [%s]. The table will be created without partitioning", table.Name, strings.Join(table.GetPKFields(), ", "), pc.Field)
+		return false
+	}
+
+	return true
+}
+
+func (pc *PartitioningConfig) nameLayout() string {
+	if pc.Granularity == PartitionByDay {
+		return "20060102"
+	}
+
+	return "200601"
+}
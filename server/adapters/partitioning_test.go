@@ -0,0 +1,87 @@
+package adapters
+
+import (
+	"github.com/jitsucom/jitsu/server/typing"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestPartitioningConfig(t *testing.T) {
+	now := time.Date(2021, 12, 31, 15, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name             string
+		config           *PartitioningConfig
+		expectedUpcoming []string
+		expired          []string
+		notExpired       []string
+	}{
+		{
+			"daily with retention",
+			&PartitioningConfig{Granularity: "DAY", Premake: 2, Retention: 3},
+			[]string{"20211231", "20220101", "20220102"},
+			[]string{"20211228", "20201231"},
+			[]string{"20211229", "20211231", "20220101", "default", "max"},
+		},
+		{
+			"monthly without retention",
+			&PartitioningConfig{Granularity: "month"},
+			[]string{"202112", "202201", "202202", "202203"},
+			nil,
+			[]string{"202001", "202112"},
+		},
+		{
+			"monthly with retention",
+			&PartitioningConfig{Granularity: "month", Premake: 1, Retention: 12},
+			[]string{"202112", "202201"},
+			[]string{"202012", "201901"},
+			[]string{"202101", "202112"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, tt.config.Validate())
+			require.Equal(t, "_timestamp", tt.config.Field)
+
+			var upcoming []string
+			for _, partition := range tt.config.UpcomingPartitions(now) {
+				upcoming = append(upcoming, partition.Name)
+				require.True(t, partition.From.Before(partition.To))
+			}
+			require.Equal(t, tt.expectedUpcoming, upcoming)
+
+			for _, name := range tt.expired {
+				require.True(t, tt.config.IsExpired(name, now), name)
+			}
+			for _, name := range tt.notExpired {
+				require.False(t, tt.config.IsExpired(name, now), name)
+			}
+		})
+	}
+}
+
+func TestPartitioningConfigValidate(t *testing.T) {
+	require.NoError(t, (*PartitioningConfig)(nil).Validate())
+	require.EqualError(t, (&PartitioningConfig{Granularity: "week"}).Validate(), "Unknown partitioning granularity: [week]. Supported: day, month")
+	require.EqualError(t, (&PartitioningConfig{Granularity: "day", Retention: -1}).Validate(), "partitioning.retention can't be negative")
+}
+
+func TestPartitioningConfigIsApplicable(t *testing.T) {
+	config := &PartitioningConfig{Granularity: PartitionByDay, Field: "_timestamp"}
+	columns := Columns{"_timestamp": typing.SQLColumn{Type: "timestamp"}, "id": typing.SQLColumn{Type: "text"}}
+	tests := []struct {
+		name     string
+		table    *Table
+		expected bool
+	}{
+		{"without primary key", &Table{Name: "events", Columns: columns, PKFields: map[string]bool{}}, true},
+		{"primary key with partitioning field", &Table{Name: "events", Columns: columns, PKFields: map[string]bool{"id": true, "_timestamp": true}}, true},
+		{"primary key without partitioning field", &Table{Name: "events", Columns: columns, PKFields: map[string]bool{"id": true}}, false},
+		{"without partitioning field", &Table{Name: "events", Columns: Columns{"id": typing.SQLColumn{Type: "text"}}, PKFields: map[string]bool{}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, config.isApplicable(tt.table))
+		})
+	}
+}
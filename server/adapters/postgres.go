@@ -19,7 +19,13 @@ import (
 )
 
 const (
-	tableNamesQuery  = `SELECT table_name FROM information_schema.tables WHERE table_schema=$1`
+	tableNamesQuery         = `SELECT table_name FROM information_schema.tables WHERE table_schema=$1`
+	postgresTableNamesQuery = `SELECT pg_class.relname
+						FROM pg_class
+							JOIN pg_namespace ON pg_namespace.oid = pg_class.relnamespace
+						WHERE pg_class.relkind IN ('r'::char, 'p'::char)
+							AND pg_namespace.nspname = $1
+							AND NOT EXISTS (SELECT 1 FROM pg_inherits WHERE pg_inherits.inhrelid = pg_class.oid)`
 	tableSchemaQuery = `SELECT 
  							pg_attribute.attname AS name,
     						pg_catalog.format_type(pg_attribute.atttypid,pg_attribute.atttypmod) AS column_type
@@ -28,7 +34,7 @@ const (
          					LEFT JOIN pg_attrdef pg_attrdef ON pg_attrdef.adrelid = pg_class.oid AND pg_attrdef.adnum = pg_attribute.attnum
          					LEFT JOIN pg_namespace ON pg_namespace.oid = pg_class.relnamespace
          					LEFT JOIN pg_constraint ON pg_constraint.conrelid = pg_class.oid AND pg_attribute.attnum = ANY (pg_constraint.conkey)
-						WHERE pg_class.relkind = 'r'::char
+						WHERE pg_class.relkind IN ('r'::char, 'p'::char)
   							AND  pg_namespace.nspname = $1
   							AND pg_class.relname = $2
   							AND pg_attribute.attnum > 0`
@@ -43,16 +49,31 @@ const (
 								pg_attribute.attrelid = pg_class.oid AND
 								pg_attribute.attnum = any(pg_index.indkey)
 					  	AND indisprimary`
+	partitionsQuery = `SELECT child.relname
+						FROM pg_inherits
+							JOIN pg_class parent ON pg_inherits.inhparent = parent.oid
+							JOIN pg_class child ON pg_inherits.inhrelid = child.oid
+							JOIN pg_namespace ON pg_namespace.oid = parent.relnamespace
+						WHERE pg_namespace.nspname = $1 AND parent.relname = $2`
+	partitionedTablesQuery = `SELECT parent.relname
+						FROM pg_partitioned_table
+							JOIN pg_class parent ON pg_partitioned_table.partrelid = parent.oid
+							JOIN pg_namespace ON pg_namespace.oid = parent.relnamespace
+						WHERE pg_namespace.nspname = $1 AND pg_partitioned_table.partstrat = 'r'`
 	createDbSchemaIfNotExistsTemplate = `CREATE SCHEMA IF NOT EXISTS "%s"`
 	addColumnTemplate                 = `ALTER TABLE "%s"."%s" ADD COLUMN %s`
 	dropPrimaryKeyTemplate            = "ALTER TABLE %s.%s DROP CONSTRAINT %s"
 	alterPrimaryKeyTemplate           = `ALTER TABLE "%s"."%s" ADD CONSTRAINT %s PRIMARY KEY (%s)`
 	createTableTemplate               = `CREATE TABLE "%s"."%s" (%s)`
+	partitionByRangeTemplate          = ` PARTITION BY RANGE ("%s")`
+	createPartitionTemplate           = `CREATE TABLE IF NOT EXISTS "%s"."%s" PARTITION OF "%s"."%s" FOR VALUES FROM ('%s') TO ('%s')`
+	createDefaultPartitionTemplate    = `CREATE TABLE IF NOT EXISTS "%s"."%s" PARTITION OF "%s"."%s" DEFAULT`
 	insertTemplate                    = `INSERT INTO "%s"."%s" (%s) VALUES %s`
 	mergeTemplate                     = `INSERT INTO "%s"."%s"(%s) VALUES %s ON CONFLICT ON CONSTRAINT %s DO UPDATE set %s;`
 	bulkMergeTemplate                 = `INSERT INTO "%s"."%s"(%s) SELECT %s FROM "%s"."%s" ON CONFLICT ON CONSTRAINT %s DO UPDATE SET %s`
 	bulkMergePrefix                   = `excluded`
 	deleteQueryTemplate               = `DELETE FROM "%s"."%s" WHERE %s`
+	countQueryTemplate                = `SELECT count(*) FROM "%s"."%s" WHERE %s`
 
 	dropTableTemplate = `DROP TABLE "%s"."%s"`
 
@@ -62,6 +83,8 @@ const (
 	postgresTruncateTableTemplate      = `TRUNCATE "%s"."%s"`
 	placeholdersStringBuildErrTemplate = `Error building placeholders string: %v`
 	postgresValuesLimit                = 65535 // this is a limitation of parameters one can pass as query values. If more parameters are passed, error is returned
+	postgresCopyRowsThreshold          = 1000  // batches with at least this number of rows are loaded with COPY FROM STDIN instead of INSERT
+	postgresPartitionBoundLayout       = "2006-01-02 15:04:05"
 )
 
 var (
@@ -72,6 +95,8 @@ var (
 		typing.TIMESTAMP: "timestamp",
 		typing.BOOL:      "boolean",
 		typing.UNKNOWN:   "text",
+		typing.OBJECT:    "jsonb",
+		typing.ARRAY:     "jsonb",
 	}
 )
 
@@ -122,6 +147,9 @@ type Postgres struct {
 	queryLogger *logging.QueryLogger
 
 	sqlTypes typing.SQLTypes
+
+	//partitioning can be nil: tables aren't partitioned
+	partitioning *PartitioningConfig
 }
 
 //NewPostgresUnderRedshift returns configured Postgres adapter instance without mapping old types
@@ -149,7 +177,8 @@ func NewPostgresUnderRedshift(ctx context.Context, config *DataSourceConfig, que
 }
 
 //NewPostgres return configured Postgres adapter instance
-func NewPostgres(ctx context.Context, config *DataSourceConfig, queryLogger *logging.QueryLogger, sqlTypes typing.SQLTypes) (*Postgres, error) {
+//partitioning can be nil
+func NewPostgres(ctx context.Context, config *DataSourceConfig, partitioning *PartitioningConfig, queryLogger *logging.QueryLogger, sqlTypes typing.SQLTypes) (*Postgres, error) {
 	connectionString := fmt.Sprintf("host=%s port=%s dbname=%s user=%s password=%s ",
 		config.Host, config.Port.String(), config.Db, config.Username, config.Password)
 	//concat provided connection parameters
@@ -169,7 +198,8 @@ func NewPostgres(ctx context.Context, config *DataSourceConfig, queryLogger *log
 	//set default value
 	dataSource.SetConnMaxLifetime(10 * time.Minute)
 
-	return &Postgres{ctx: ctx, config: config, dataSource: dataSource, queryLogger: queryLogger, sqlTypes: reformatMappings(sqlTypes, SchemaToPostgres)}, nil
+	return &Postgres{ctx: ctx, config: config, dataSource: dataSource, queryLogger: queryLogger, sqlTypes: reformatMappings(sqlTypes, SchemaToPostgres),
+		partitioning: partitioning}, nil
 }
 
 //Type returns Postgres type
@@ -198,13 +228,14 @@ func (p *Postgres) CreateDbSchema(dbSchemaName string) error {
 }
 
 //CreateTable creates database table with name,columns provided in Table representation
+//if partitioning is configured creates partitioned table with default, current and upcoming partitions
 func (p *Postgres) CreateTable(table *Table) error {
 	wrappedTx, err := p.OpenTx()
 	if err != nil {
 		return err
 	}
 
-	err = p.createTableInTransaction(wrappedTx, table)
+	err = p.createTableInTransaction(wrappedTx, table, p.isPartitioned(table))
 	if err != nil {
 		wrappedTx.Rollback()
 		return checkErr(err)
@@ -278,6 +309,40 @@ func (p *Postgres) Truncate(tableName string) error {
 	return sqlParams.commonTruncate(tableName, statement)
 }
 
+//GetTableNames returns names of all tables in the schema. Partitions of partitioned tables are omitted
+func (p *Postgres) GetTableNames() ([]string, error) {
+	sqlParams := SqlParams{
+		dataSource:  p.dataSource,
+		queryLogger: p.queryLogger,
+		ctx:         p.ctx,
+	}
+	return sqlParams.commonGetTableNames(postgresTableNamesQuery, p.config.Schema)
+}
+
+//Select executes SELECT query in a read-only transaction and passes result rows to consume
+func (p *Postgres) Select(query string, consume func(row map[string]interface{}) error) error {
+	sqlParams := SqlParams{
+		dataSource:  p.dataSource,
+		queryLogger: p.queryLogger,
+		ctx:         p.ctx,
+	}
+	return sqlParams.commonSelect(query, true, consume)
+}
+
+//DeleteOlderThan deletes (or counts if dryRun) rows which field value is before the time
+func (p *Postgres) DeleteOlderThan(table *Table, field string, before time.Time, dryRun bool) (int64, error) {
+	sqlParams := SqlParams{
+		dataSource:  p.dataSource,
+		queryLogger: p.queryLogger,
+		ctx:         p.ctx,
+	}
+	condition, values := p.toDeleteQuery(table, olderThanConditions(field, before))
+	return sqlParams.commonDeleteOlderThan(table.Name,
+		fmt.Sprintf(countQueryTemplate, p.config.Schema, table.Name, condition),
+		fmt.Sprintf(deleteQueryTemplate, p.config.Schema, table.Name, condition),
+		values, dryRun)
+}
+
 func (p *Postgres) getTable(tableName string) (*Table, error) {
 	table := &Table{Name: tableName, Columns: map[string]typing.SQLColumn{}, PKFields: map[string]bool{}}
 	rows, err := p.dataSource.QueryContext(p.ctx, tableSchemaQuery, p.config.Schema, tableName)
@@ -309,7 +374,8 @@ func (p *Postgres) getTable(tableName string) (*Table, error) {
 //create table columns and pk key
 //override input table sql type with configured cast type
 //make fields from Table PkFields - 'not null'
-func (p *Postgres) createTableInTransaction(wrappedTx *Transaction, table *Table) error {
+//partitioned: create table partitioned by range of partitioning field with default, current and upcoming partitions
+func (p *Postgres) createTableInTransaction(wrappedTx *Transaction, table *Table, partitioned bool) error {
 	var columnsDDL []string
 	pkFields := table.GetPKFieldsMap()
 	for columnName, column := range table.Columns {
@@ -319,6 +385,9 @@ func (p *Postgres) createTableInTransaction(wrappedTx *Transaction, table *Table
 	//sorting columns asc
 	sort.Strings(columnsDDL)
 	query := fmt.Sprintf(createTableTemplate, p.config.Schema, table.Name, strings.Join(columnsDDL, ", "))
+	if partitioned {
+		query += fmt.Sprintf(partitionByRangeTemplate, p.partitioning.Field)
+	}
 	p.queryLogger.LogDDL(query)
 
 	if _, err := wrappedTx.tx.ExecContext(p.ctx, query); err != nil {
@@ -330,9 +399,116 @@ func (p *Postgres) createTableInTransaction(wrappedTx *Transaction, table *Table
 		return err
 	}
 
+	if partitioned {
+		statements := []string{fmt.Sprintf(createDefaultPartitionTemplate, p.config.Schema, table.Name+"_default", p.config.Schema, table.Name)}
+		for _, partition := range p.partitioning.UpcomingPartitions(time.Now()) {
+			statements = append(statements, p.createPartitionStatement(table.Name, partition))
+		}
+
+		for _, statement := range statements {
+			p.queryLogger.LogDDL(statement)
+			if _, err := wrappedTx.tx.ExecContext(p.ctx, statement); err != nil {
+				return fmt.Errorf("Error creating [%s] table partition with statement [%s]: %v", table.Name, statement, checkErr(err))
+			}
+		}
+	}
+
 	return nil
 }
 
+//isPartitioned returns true if partitioning is configured and can be applied to the table
+func (p *Postgres) isPartitioned(table *Table) bool {
+	return p.partitioning != nil && p.partitioning.isApplicable(table)
+}
+
+//MaintainPartitions creates upcoming and drops expired partitions of partitioned tables created by Jitsu (with default partition)
+//tables are listed from the database schema, so tables created by other Jitsu nodes or before restart are maintained too
+//partition which can't be created (e.g. default partition has rows from its range) is logged and skipped
+func (p *Postgres) MaintainPartitions() {
+	if p.partitioning == nil {
+		return
+	}
+
+	sqlParams := SqlParams{
+		dataSource:  p.dataSource,
+		queryLogger: p.queryLogger,
+		ctx:         p.ctx,
+	}
+	tableNames, err := sqlParams.commonGetTableNames(partitionedTablesQuery, p.config.Schema)
+	if err != nil {
+		logging.Errorf("Error getting partitioned tables: %v", checkErr(err))
+		return
+	}
+
+	now := time.Now()
+	for _, tableName := range tableNames {
+		partitions, err := p.getPartitions(tableName)
+		if err != nil {
+			logging.Errorf("Error getting [%s] table partitions: %v", tableName, err)
+			continue
+		}
+		//isn't partitioned by Jitsu
+		if !partitions[tableName+"_default"] {
+			continue
+		}
+
+		var statements []string
+		for _, partition := range p.partitioning.UpcomingPartitions(now) {
+			if !partitions[partitionTableName(tableName, partition)] {
+				statements = append(statements, p.createPartitionStatement(tableName, partition))
+			}
+		}
+
+		prefix := tableName + "_p"
+		for partitionName := range partitions {
+			if strings.HasPrefix(partitionName, prefix) && p.partitioning.IsExpired(strings.TrimPrefix(partitionName, prefix), now) {
+				statements = append(statements, fmt.Sprintf(dropTableTemplate, p.config.Schema, partitionName))
+			}
+		}
+
+		for _, statement := range statements {
+			p.queryLogger.LogDDL(statement)
+			if _, err := p.dataSource.ExecContext(p.ctx, statement); err != nil {
+				logging.Errorf("Error maintaining [%s] table partitions with statement [%s]: %v", tableName, statement, checkErr(err))
+			}
+		}
+	}
+}
+
+//getPartitions returns partitions table names of the table
+func (p *Postgres) getPartitions(tableName string) (map[string]bool, error) {
+	rows, err := p.dataSource.QueryContext(p.ctx, partitionsQuery, p.config.Schema, tableName)
+	if err != nil {
+		return nil, checkErr(err)
+	}
+
+	defer rows.Close()
+	partitions := map[string]bool{}
+	for rows.Next() {
+		var partitionName string
+		if err := rows.Scan(&partitionName); err != nil {
+			return nil, fmt.Errorf("Error scanning result: %v", err)
+		}
+		partitions[partitionName] = true
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("Last rows.Err: %v", err)
+	}
+
+	return partitions, nil
+}
+
+func (p *Postgres) createPartitionStatement(tableName string, partition *Partition) string {
+	return fmt.Sprintf(createPartitionTemplate, p.config.Schema, partitionTableName(tableName, partition), p.config.Schema, tableName,
+		partition.From.Format(postgresPartitionBoundLayout), partition.To.Format(postgresPartitionBoundLayout))
+}
+
+//partitionTableName returns table name of the partition: tableName_p20211231
+func partitionTableName(tableName string, partition *Partition) string {
+	return tableName + "_p" + partition.Name
+}
+
 //alter table with columns (if not empty)
 //recreate primary key (if not empty) or delete primary key if Table.DeletePkFields is true
 func (p *Postgres) patchTableSchemaInTransaction(wrappedTx *Transaction, patchTable *Table) error {
@@ -446,6 +622,21 @@ func (p *Postgres) BulkUpdate(table *Table, objects []map[string]interface{}, de
 	return wrappedTx.DirectCommit()
 }
 
+//Delete deletes objects with deleteConditions in transaction
+func (p *Postgres) Delete(table *Table, deleteConditions *DeleteConditions) error {
+	wrappedTx, err := p.OpenTx()
+	if err != nil {
+		return err
+	}
+
+	if err := p.deleteInTransaction(wrappedTx, table, deleteConditions); err != nil {
+		wrappedTx.Rollback()
+		return err
+	}
+
+	return wrappedTx.DirectCommit()
+}
+
 //DropTable drops table in transaction
 func (p *Postgres) DropTable(table *Table) error {
 	wrappedTx, err := p.OpenTx()
@@ -461,12 +652,12 @@ func (p *Postgres) DropTable(table *Table) error {
 	return wrappedTx.DirectCommit()
 }
 
-//bulkStoreInTransaction checks PKFields and uses bulkInsert or bulkMerge
+//bulkStoreInTransaction checks PKFields and uses insertInTransaction or bulkMerge
 //in bulkMerge - deduplicate objects
 //if there are any duplicates, do the job 2 times
 func (p *Postgres) bulkStoreInTransaction(wrappedTx *Transaction, table *Table, objects []map[string]interface{}) error {
 	if len(table.PKFields) == 0 {
-		return p.bulkInsertInTransaction(wrappedTx, table, objects, postgresValuesLimit)
+		return p.insertInTransaction(wrappedTx, table, objects)
 	}
 
 	//deduplication for bulkMerge success (it fails if there is any duplicate)
@@ -514,7 +705,7 @@ func (p *Postgres) bulkInsertInTransaction(wrappedTx *Transaction, table *Table,
 
 		for i, column := range headerWithoutQuotes {
 			value, _ := row[column]
-			valueArgs = append(valueArgs, value)
+			valueArgs = append(valueArgs, nestedToJSON(value))
 			castClause := p.getCastClause(column, table.Columns[column])
 
 			_, err = placeholdersBuilder.WriteString("$" + strconv.Itoa(placeholdersCounter) + castClause)
@@ -544,6 +735,72 @@ func (p *Postgres) bulkInsertInTransaction(wrappedTx *Transaction, table *Table,
 	return nil
 }
 
+//insertInTransaction uses COPY FROM STDIN for large batches (it is faster and doesn't have postgresValuesLimit)
+//and multi-row INSERT for small ones or if the table has columns with cast clauses (COPY can't cast values)
+func (p *Postgres) insertInTransaction(wrappedTx *Transaction, table *Table, objects []map[string]interface{}) error {
+	if len(objects) >= postgresCopyRowsThreshold && !p.hasCastClauses(table) {
+		return p.copyInTransaction(wrappedTx, table, objects)
+	}
+
+	return p.bulkInsertInTransaction(wrappedTx, table, objects, postgresValuesLimit)
+}
+
+//hasCastClauses returns true if any table column has ::SQL_TYPE cast clause (see getCastClause)
+func (p *Postgres) hasCastClauses(table *Table) bool {
+	for name, column := range table.Columns {
+		if p.getCastClause(name, column) != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+//copyInTransaction loads objects with COPY FROM STDIN
+//values are parsed by Postgres with column types input functions (without cast clauses)
+func (p *Postgres) copyInTransaction(wrappedTx *Transaction, table *Table, objects []map[string]interface{}) error {
+	headerWithoutQuotes := make([]string, 0, len(table.Columns))
+	for name := range table.Columns {
+		headerWithoutQuotes = append(headerWithoutQuotes, name)
+	}
+
+	statement := pq.CopyInSchema(p.config.Schema, table.Name, headerWithoutQuotes...)
+	p.queryLogger.LogQuery(fmt.Sprintf("%s (%d rows)", statement, len(objects)))
+
+	stmt, err := wrappedTx.tx.PrepareContext(p.ctx, statement)
+	if err != nil {
+		err = checkErr(err)
+		return fmt.Errorf("Error preparing copy statement into %s table: %v", table.Name, err)
+	}
+
+	for _, row := range objects {
+		valueArgs := make([]interface{}, len(headerWithoutQuotes))
+		for i, column := range headerWithoutQuotes {
+			valueArgs[i] = nestedToJSON(row[column])
+		}
+
+		if _, err := stmt.ExecContext(p.ctx, valueArgs...); err != nil {
+			stmt.Close()
+			err = checkErr(err)
+			return fmt.Errorf("Error copying row into %s table: %v", table.Name, err)
+		}
+	}
+
+	//flush buffered rows
+	if _, err := stmt.ExecContext(p.ctx); err != nil {
+		stmt.Close()
+		err = checkErr(err)
+		return fmt.Errorf("Error executing copy into %s table: %v", table.Name, err)
+	}
+
+	if err := stmt.Close(); err != nil {
+		err = checkErr(err)
+		return fmt.Errorf("Error closing copy statement into %s table: %v", table.Name, err)
+	}
+
+	return nil
+}
+
 //bulkMergeInTransaction creates tmp table without duplicates
 //inserts all data into tmp table and using bulkMergeTemplate merges all data to main table
 func (p *Postgres) bulkMergeInTransaction(wrappedTx *Transaction, table *Table, objects []map[string]interface{}) error {
@@ -555,12 +812,12 @@ func (p *Postgres) bulkMergeInTransaction(wrappedTx *Transaction, table *Table,
 		Version:        0,
 	}
 
-	err := p.createTableInTransaction(wrappedTx, tmpTable)
+	err := p.createTableInTransaction(wrappedTx, tmpTable, false)
 	if err != nil {
 		return fmt.Errorf("Error creating temporary table: %v", err)
 	}
 
-	err = p.bulkInsertInTransaction(wrappedTx, tmpTable, objects, postgresValuesLimit)
+	err = p.insertInTransaction(wrappedTx, tmpTable, objects)
 	if err != nil {
 		return fmt.Errorf("Error inserting in temporary table: %v", err)
 	}
@@ -621,7 +878,7 @@ func (p *Postgres) toDeleteQuery(table *Table, conditions *DeleteConditions) (st
 		values = append(values, condition.Value)
 	}
 
-	return strings.Join(queryConditions, conditions.JoinCondition), values
+	return strings.Join(queryConditions, " "+conditions.JoinCondition+" "), values
 }
 
 //executeInsert execute insert with insertTemplate
@@ -737,7 +994,7 @@ func (p *Postgres) buildInsertPayload(table *Table, valuesMap map[string]interfa
 
 		//$1::type, $2::type, $3, etc ($0 - wrong)
 		placeholders[i] = fmt.Sprintf("$%d%s", i+1, p.getCastClause(name, table.Columns[name]))
-		values[i] = value
+		values[i] = nestedToJSON(value)
 		i++
 	}
 
@@ -801,6 +1058,22 @@ func reformatMappings(mappingTypeCasts typing.SQLTypes, dbTypes map[typing.DataT
 	return formattedSqlTypes
 }
 
+//nestedToJSON returns JSON string for nested objects and arrays (they are written into JSON columns)
+//otherwise returns value as is
+func nestedToJSON(value interface{}) interface{} {
+	switch value.(type) {
+	case map[string]interface{}, []interface{}:
+		b, err := json.Marshal(value)
+		if err != nil {
+			logging.SystemErrorf("Error marshaling nested value %v into JSON: %v", value, err)
+			return value
+		}
+		return string(b)
+	default:
+		return value
+	}
+}
+
 func removeLastComma(str string) string {
 	if last := len(str) - 1; last >= 0 && str[last] == ',' {
 		str = str[:last]
@@ -48,6 +48,40 @@ func TestDeduplicateObjects(t *testing.T) {
 	require.Equal(t, 3, len(threeBucketsAgain))
 }
 
+func TestPostgresHasCastClauses(t *testing.T) {
+	tests := []struct {
+		name     string
+		sqlTypes typing.SQLTypes
+		columns  Columns
+		expected bool
+	}{
+		{
+			"No cast clauses",
+			typing.SQLTypes{},
+			Columns{"field1": typing.SQLColumn{Type: "text"}, "field2": typing.SQLColumn{Type: "bigint"}},
+			false,
+		},
+		{
+			"Configured sql type",
+			typing.SQLTypes{"field2": typing.SQLColumn{Type: "numeric(38,18)"}},
+			Columns{"field1": typing.SQLColumn{Type: "text"}, "field2": typing.SQLColumn{Type: "numeric(38,18)"}},
+			true,
+		},
+		{
+			"Overridden column",
+			typing.SQLTypes{},
+			Columns{"field1": typing.SQLColumn{Type: "date", Override: true}},
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pg := &Postgres{sqlTypes: tt.sqlTypes}
+			require.Equal(t, tt.expected, pg.hasCastClauses(&Table{Name: "test", Columns: tt.columns}))
+		})
+	}
+}
+
 func TestBulkInsert(t *testing.T) {
 	table := &Table{
 		Name:    "test_insert",
@@ -108,6 +142,39 @@ func TestBulkMerge(t *testing.T) {
 	assert.Equal(t, rows, 5)
 }
 
+func TestBulkInsertWithCopy(t *testing.T) {
+	table := &Table{
+		Name:    "test_insert_copy",
+		Columns: Columns{"field1": typing.SQLColumn{Type: "text"}, "field2": typing.SQLColumn{Type: "text"}, "field3": typing.SQLColumn{Type: "bigint"}, "user": typing.SQLColumn{Type: "text"}},
+	}
+	container, pg := setupDatabase(t, table)
+	defer container.Close()
+	err := pg.BulkInsert(table, createObjects(postgresCopyRowsThreshold+5))
+	require.NoError(t, err, "Failed to bulk insert objects with copy")
+	rows, err := container.CountRows(table.Name)
+	require.NoError(t, err, "Failed to count objects at "+table.Name)
+	assert.Equal(t, rows, postgresCopyRowsThreshold+5)
+}
+
+func TestBulkMergeWithCopy(t *testing.T) {
+	table := &Table{
+		Name:     "test_merge_copy",
+		Columns:  Columns{"field1": typing.SQLColumn{Type: "text"}, "field2": typing.SQLColumn{Type: "text"}, "field3": typing.SQLColumn{Type: "bigint"}, "user": typing.SQLColumn{Type: "text"}},
+		PKFields: map[string]bool{"field1": true},
+	}
+	container, pg := setupDatabase(t, table)
+	defer container.Close()
+	objects := createObjects(postgresCopyRowsThreshold)
+	err := pg.BulkInsert(table, objects)
+	require.NoError(t, err, "Failed to bulk merge objects with copy")
+	// merge the same objects again: rows must be updated, not duplicated
+	err = pg.BulkInsert(table, objects)
+	require.NoError(t, err, "Failed to bulk merge objects with copy")
+	rows, err := container.CountRows(table.Name)
+	require.NoError(t, err, "Failed to count objects at "+table.Name)
+	assert.Equal(t, rows, postgresCopyRowsThreshold)
+}
+
 func setupDatabase(t *testing.T, table *Table) (*test.PostgresContainer, *Postgres) {
 	ctx := context.Background()
 	container, err := test.NewPostgresContainer(ctx)
@@ -115,7 +182,7 @@ func setupDatabase(t *testing.T, table *Table) (*test.PostgresContainer, *Postgr
 		t.Fatalf("failed to initialize container: %v", err)
 	}
 	dsConfig := &DataSourceConfig{Host: container.Host, Port: json.Number(fmt.Sprint(container.Port)), Username: container.Username, Password: container.Password, Db: container.Database, Schema: container.Schema, Parameters: map[string]string{"sslmode": "disable"}}
-	pg, err := NewPostgres(ctx, dsConfig, &logging.QueryLogger{}, typing.SQLTypes{})
+	pg, err := NewPostgres(ctx, dsConfig, nil, &logging.QueryLogger{}, typing.SQLTypes{})
 	if err != nil {
 		t.Fatalf("Failed to create Postgres adapter: %v", err)
 	}
@@ -0,0 +1,215 @@
+package adapters
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	postHogDefaultBaseURL = "https://app.posthog.com"
+	postHogBatchPath      = "/batch/"
+	postHogDecidePath     = "/decide/?v=3"
+	postHogPageViewEvent  = "$pageview"
+	postHogScreenEvent    = "$screen"
+)
+
+var postHogUUIDRegexp = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+//PostHogConfig is a dto for parsing PostHog configuration
+type PostHogConfig struct {
+	APIKey  string `mapstructure:"api_key" json:"api_key,omitempty" yaml:"api_key,omitempty"`
+	BaseURL string `mapstructure:"base_url" json:"base_url,omitempty" yaml:"base_url,omitempty"`
+}
+
+//Validate returns err if invalid and sets default base URL
+func (phc *PostHogConfig) Validate() error {
+	if phc == nil {
+		return errors.New("posthog config is required")
+	}
+	if phc.APIKey == "" {
+		return errors.New("'api_key' is required parameter")
+	}
+	if phc.BaseURL == "" {
+		phc.BaseURL = postHogDefaultBaseURL
+	}
+	phc.BaseURL = strings.TrimSuffix(phc.BaseURL, "/")
+
+	return nil
+}
+
+//PostHogEvent is a dto for sending events to PostHog capture API
+type PostHogEvent struct {
+	Event      string                 `json:"event"`
+	DistinctID string                 `json:"distinct_id"`
+	Properties map[string]interface{} `json:"properties"`
+	Timestamp  string                 `json:"timestamp"`
+	UUID       string                 `json:"uuid,omitempty"`
+}
+
+//PostHogRequest is a dto for sending requests to PostHog /batch API
+type PostHogRequest struct {
+	APIKey string          `json:"api_key"`
+	Batch  []*PostHogEvent `json:"batch"`
+}
+
+//PostHogRequestFactory is a factory for building PostHog /batch HTTP requests from input events
+//user_identify events are sent as $identify events with user properties in $set
+type PostHogRequestFactory struct {
+	config *PostHogConfig
+}
+
+//newPostHogRequestFactory returns configured HTTPRequestFactory instance for PostHog requests
+func newPostHogRequestFactory(config *PostHogConfig) *PostHogRequestFactory {
+	return &PostHogRequestFactory{config: config}
+}
+
+//Create returns PostHog /batch request with one event built from the object
+func (phrf *PostHogRequestFactory) Create(object map[string]interface{}) (*Request, error) {
+	event, err := newAnalyticsEvent(object)
+	if err != nil {
+		return nil, err
+	}
+
+	postHogEvent := &PostHogEvent{
+		Event:      event.Type,
+		DistinctID: event.DistinctID,
+		Properties: event.Properties,
+		Timestamp:  event.Time.UTC().Format(time.RFC3339Nano),
+	}
+	if postHogUUIDRegexp.MatchString(event.InsertID) {
+		postHogEvent.UUID = event.InsertID
+	}
+	if event.IP != "" {
+		postHogEvent.Properties["$ip"] = event.IP
+	}
+
+	switch strings.ToLower(event.Type) {
+	case "pageview", "page", "app_page":
+		postHogEvent.Event = postHogPageViewEvent
+	case "screen", "screenview":
+		postHogEvent.Event = postHogScreenEvent
+	case UserIdentifyEventType, IdentifyEventType:
+		postHogEvent.Event = IdentifyEventType
+		if len(event.UserProperties) > 0 {
+			postHogEvent.Properties["$set"] = event.UserProperties
+		}
+		//$identify merges anonymous user into identified one
+		if event.AnonymousID != "" && event.AnonymousID != event.DistinctID {
+			postHogEvent.Properties["$anon_distinct_id"] = event.AnonymousID
+		}
+	}
+
+	return phrf.newRequest([]*PostHogEvent{postHogEvent})
+}
+
+//newRequest returns PostHog /batch HTTP POST request
+func (phrf *PostHogRequestFactory) newRequest(batch []*PostHogEvent) (*Request, error) {
+	req := &PostHogRequest{APIKey: phrf.config.APIKey, Batch: batch}
+	b, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("Error marshalling posthog request [%v]: %v", req, err)
+	}
+
+	return &Request{
+		URL:     phrf.config.BaseURL + postHogBatchPath,
+		Method:  http.MethodPost,
+		Body:    b,
+		Headers: map[string]string{"Content-Type": "application/json"},
+	}, nil
+}
+
+//BatchKey returns the same key for all requests: all PostHog requests can be sent in one batch
+func (phrf *PostHogRequestFactory) BatchKey(req *Request) string {
+	return req.URL
+}
+
+//CreateBatch returns /batch request with events from all requests
+func (phrf *PostHogRequestFactory) CreateBatch(requests []*Request) (*Request, error) {
+	var batch []*PostHogEvent
+	for _, r := range requests {
+		req := &PostHogRequest{}
+		if err := json.Unmarshal(r.Body, req); err != nil {
+			return nil, fmt.Errorf("Error unmarshalling posthog request: %v", err)
+		}
+		batch = append(batch, req.Batch...)
+	}
+
+	return phrf.newRequest(batch)
+}
+
+//MaxBatchEvents returns 0: PostHog /batch API limits only body size
+func (phrf *PostHogRequestFactory) MaxBatchEvents() int {
+	return 0
+}
+
+//BatchItemErrors returns empty map: PostHog accepts or rejects the whole batch
+func (phrf *PostHogRequestFactory) BatchItemErrors(statusCode int, responseBody []byte, batchSize int) map[int]error {
+	return map[int]error{}
+}
+
+func (phrf *PostHogRequestFactory) Close() {
+}
+
+//PostHog is an adapter for sending events to PostHog
+type PostHog struct {
+	AbstractHTTP
+
+	config *PostHogConfig
+}
+
+//NewPostHog returns configured PostHog adapter instance
+func NewPostHog(config *PostHogConfig, httpAdapterConfiguration *HTTPAdapterConfiguration) (*PostHog, error) {
+	httpAdapterConfiguration.HTTPReqFactory = newPostHogRequestFactory(config)
+
+	httpAdapter, err := NewHTTPAdapter(httpAdapterConfiguration)
+	if err != nil {
+		return nil, err
+	}
+
+	ph := &PostHog{config: config}
+	ph.httpAdapter = httpAdapter
+	return ph, nil
+}
+
+//NewTestPostHog returns test instance of adapter
+func NewTestPostHog(config *PostHogConfig) *PostHog {
+	return &PostHog{config: config}
+}
+
+//TestAccess sends request to PostHog /decide API and returns err if project API key is invalid
+//capture API isn't used because it accepts events with any API key
+func (ph *PostHog) TestAccess() error {
+	body, err := json.Marshal(map[string]string{"api_key": ph.config.APIKey, "distinct_id": "jitsu.connection_test"})
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.DefaultClient.Post(ph.config.BaseURL+postHogDecidePath, "application/json", bytes.NewBuffer(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	responseBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("Error reading posthog response body: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Error connecting to posthog [code=%d]: %s", resp.StatusCode, string(responseBody))
+	}
+
+	return nil
+}
+
+//Type returns adapter type
+func (ph *PostHog) Type() string {
+	return "PostHog"
+}
@@ -0,0 +1,96 @@
+package adapters
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/timestamp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostHogCreate(t *testing.T) {
+	config := &PostHogConfig{APIKey: "key", BaseURL: "https://posthog.example.com/"}
+	require.NoError(t, config.Validate())
+	factory := newPostHogRequestFactory(config)
+
+	eventTime := time.Date(2021, 12, 31, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name         string
+		input        map[string]interface{}
+		expectedBody string
+		expectedErr  string
+	}{
+		{
+			"Anonymous pageview",
+			map[string]interface{}{"event_type": "pageview", "eventn_ctx_event_id": "0d7e4c4c-4d7b-4d3a-9c2a-7f3f0b1c2d3e", "source_ip": "1.1.1.1", timestamp.Key: eventTime,
+				"eventn_ctx": map[string]interface{}{"url": "https://jitsu.com", "utm": map[string]interface{}{"source": "google"}, "user": map[string]interface{}{"anonymous_id": "anon1"}}},
+			`{"api_key":"key","batch":[{"event":"$pageview","distinct_id":"anon1","timestamp":"2021-12-31T10:00:00Z","uuid":"0d7e4c4c-4d7b-4d3a-9c2a-7f3f0b1c2d3e",
+				"properties":{"$current_url":"https://jitsu.com","utm_source":"google","$ip":"1.1.1.1"}}]}`,
+			"",
+		},
+		{
+			"Identified custom event",
+			map[string]interface{}{"event_type": "purchase", "eventn_ctx_event_id": "e2", "value": 10, timestamp.Key: eventTime,
+				"user": map[string]interface{}{"email": "a@b.com", "anonymous_id": "anon1"}},
+			`{"api_key":"key","batch":[{"event":"purchase","distinct_id":"a@b.com","timestamp":"2021-12-31T10:00:00Z","properties":{"value":10}}]}`,
+			"",
+		},
+		{
+			"User identify",
+			map[string]interface{}{"event_type": "user_identify", timestamp.Key: eventTime,
+				"user": map[string]interface{}{"id": "u1", "anonymous_id": "anon1", "email": "a@b.com"}},
+			`{"api_key":"key","batch":[{"event":"$identify","distinct_id":"u1","timestamp":"2021-12-31T10:00:00Z",
+				"properties":{"$set":{"email":"a@b.com"},"$anon_distinct_id":"anon1"}}]}`,
+			"",
+		},
+		{
+			"Event without type",
+			map[string]interface{}{"user": map[string]interface{}{"id": "u1"}},
+			"",
+			"Object doesn't have event_type",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := factory.Create(tt.input)
+			if tt.expectedErr != "" {
+				require.EqualError(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, "https://posthog.example.com/batch/", r.URL)
+			require.JSONEq(t, tt.expectedBody, string(r.Body))
+		})
+	}
+}
+
+func TestPostHogIdentityLinking(t *testing.T) {
+	config := &PostHogConfig{APIKey: "key"}
+	require.NoError(t, config.Validate())
+	factory := newPostHogRequestFactory(config)
+
+	_, err := IdentifyEvent("anon1", map[string]interface{}{"event_type": "login", "user": map[string]interface{}{"anonymous_id": "anon1"}})
+	require.EqualError(t, err, "Identified event doesn't have user id or email")
+
+	identifyEvent, err := IdentifyEvent("anon1", map[string]interface{}{"event_type": "login", "eventn_ctx": map[string]interface{}{"user": map[string]interface{}{"id": "u1", "anonymous_id": "anon2"}}})
+	require.NoError(t, err)
+
+	first, err := factory.Create(identifyEvent)
+	require.NoError(t, err)
+	second, err := factory.Create(map[string]interface{}{"event_type": "login", timestamp.Key: time.Date(2021, 12, 31, 10, 0, 0, 0, time.UTC), "user": map[string]interface{}{"id": "u1"}})
+	require.NoError(t, err)
+	require.Equal(t, factory.BatchKey(first), factory.BatchKey(second))
+
+	batch, err := factory.CreateBatch([]*Request{first, second})
+	require.NoError(t, err)
+
+	request := &PostHogRequest{}
+	require.NoError(t, json.Unmarshal(batch.Body, request))
+	require.Equal(t, "key", request.APIKey)
+	require.Len(t, request.Batch, 2)
+	require.Equal(t, "$identify", request.Batch[0].Event)
+	require.Equal(t, "u1", request.Batch[0].DistinctID)
+	require.Equal(t, map[string]interface{}{"$anon_distinct_id": "anon1"}, request.Batch[0].Properties, "anonymous id of recognition is linked")
+	require.Equal(t, "login", request.Batch[1].Event)
+}
@@ -0,0 +1,193 @@
+package adapters
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/jsonutils"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/timestamp"
+	"github.com/jitsucom/jitsu/server/uuid"
+)
+
+const (
+	//IdentifyEventType is a type of events which link anonymous ID with identified user in product analytics destinations
+	//such events are created with IdentifyEvent() when users recognition identifies an anonymous user
+	IdentifyEventType = "$identify"
+	//UserIdentifyEventType is a type of Jitsu events which carry user properties
+	UserIdentifyEventType = "user_identify"
+
+	identifyEventSrc = "users_recognition"
+)
+
+var (
+	analyticsUserIDPath      = jsonutils.NewJSONPath("/user/id||/eventn_ctx/user/id||/user/email||/eventn_ctx/user/email")
+	analyticsAnonymousIDPath = jsonutils.NewJSONPath("/user/anonymous_id||/eventn_ctx/user/anonymous_id")
+	analyticsUserPath        = jsonutils.NewJSONPath("/user||/eventn_ctx/user")
+	analyticsEventIDPath     = jsonutils.NewJSONPath("/eventn_ctx_event_id||/eventn_ctx/event_id")
+	analyticsIPPath          = jsonutils.NewJSONPath("/source_ip")
+	analyticsEventTypePath   = jsonutils.NewJSONPath("/event_type")
+
+	//analyticsContextProperties are Jitsu context fields which are sent as well-known product analytics properties
+	analyticsContextProperties = map[string]jsonutils.JSONPath{
+		"$current_url": jsonutils.NewJSONPath("/eventn_ctx/url||/url"),
+		"$referrer":    jsonutils.NewJSONPath("/eventn_ctx/referer||/referer"),
+		"$host":        jsonutils.NewJSONPath("/eventn_ctx/doc_host||/doc_host"),
+		"$pathname":    jsonutils.NewJSONPath("/eventn_ctx/doc_path||/doc_path"),
+		"title":        jsonutils.NewJSONPath("/eventn_ctx/page_title||/page_title"),
+		"$browser":     jsonutils.NewJSONPath("/eventn_ctx/parsed_ua/ua_family||/parsed_ua/ua_family"),
+		"$os":          jsonutils.NewJSONPath("/eventn_ctx/parsed_ua/os_family||/parsed_ua/os_family"),
+		"$device":      jsonutils.NewJSONPath("/eventn_ctx/parsed_ua/device_family||/parsed_ua/device_family"),
+		"utm_source":   jsonutils.NewJSONPath("/eventn_ctx/utm/source||/utm/source"),
+		"utm_medium":   jsonutils.NewJSONPath("/eventn_ctx/utm/medium||/utm/medium"),
+		"utm_campaign": jsonutils.NewJSONPath("/eventn_ctx/utm/campaign||/utm/campaign"),
+		"utm_term":     jsonutils.NewJSONPath("/eventn_ctx/utm/term||/utm/term"),
+		"utm_content":  jsonutils.NewJSONPath("/eventn_ctx/utm/content||/utm/content"),
+	}
+
+	//analyticsReservedFields are Jitsu system and context fields which aren't sent as event properties as is
+	analyticsReservedFields = map[string]bool{
+		"event_type": true, "eventn_ctx": true, "eventn_ctx_event_id": true, "user": true, timestamp.Key: true,
+		"src": true, "api_key": true, "source_ip": true, "properties": true, "parsed_ua": true, "location": true,
+		"utm": true, "click_id": true, "ids": true, "url": true, "referer": true, "page_title": true,
+		"doc_path": true, "doc_host": true, "doc_search": true, "user_agent": true, "utc_time": true,
+		"local_tz_offset": true, "screen_resolution": true, "vp_size": true, "user_language": true, "doc_encoding": true,
+	}
+
+	//analyticsUserIdentifiers are Jitsu user fields which are sent as distinct_id instead of user properties
+	analyticsUserIdentifiers = map[string]bool{"id": true, "anonymous_id": true, "internal_id": true}
+
+	analyticsInsertIDRegexp = regexp.MustCompile(`^[a-zA-Z0-9-]{1,36}$`)
+)
+
+//analyticsEvent is a product analytics event (Mixpanel, PostHog) built from Jitsu event
+type analyticsEvent struct {
+	Type        string
+	DistinctID  string
+	UserID      string
+	AnonymousID string
+	InsertID    string
+	IP          string
+	Time        time.Time
+	//Properties are event custom properties and well-known context properties
+	Properties map[string]interface{}
+	//UserProperties are Jitsu user fields (are filled only in user_identify events)
+	UserProperties map[string]interface{}
+}
+
+//newAnalyticsEvent returns analyticsEvent with distinct_id = user id (or email) or anonymous id if user isn't identified
+func newAnalyticsEvent(object map[string]interface{}) (*analyticsEvent, error) {
+	eventType := analyticsString(analyticsEventTypePath, object)
+	if eventType == "" {
+		return nil, errors.New("Object doesn't have event_type")
+	}
+
+	event := &analyticsEvent{
+		Type:           eventType,
+		UserID:         analyticsString(analyticsUserIDPath, object),
+		AnonymousID:    analyticsString(analyticsAnonymousIDPath, object),
+		InsertID:       analyticsString(analyticsEventIDPath, object),
+		IP:             analyticsString(analyticsIPPath, object),
+		Time:           eventTime(object),
+		Properties:     map[string]interface{}{},
+		UserProperties: map[string]interface{}{},
+	}
+
+	event.DistinctID = event.UserID
+	if event.DistinctID == "" {
+		event.DistinctID = event.AnonymousID
+	}
+	if event.DistinctID == "" {
+		return nil, errors.New("Object doesn't have distinct_id: user id, email or anonymous_id is required")
+	}
+
+	if !analyticsInsertIDRegexp.MatchString(event.InsertID) {
+		event.InsertID = uuid.GetHash(object)
+	}
+
+	for name, path := range analyticsContextProperties {
+		if value, ok := path.Get(object); ok && value != nil && value != "" {
+			event.Properties[name] = value
+		}
+	}
+	for name, value := range customProperties(object) {
+		event.Properties[name] = value
+	}
+
+	if eventType == UserIdentifyEventType {
+		if user, ok := analyticsUserPath.Get(object); ok {
+			if userObject, ok := user.(map[string]interface{}); ok {
+				for name, value := range userObject {
+					if !analyticsUserIdentifiers[name] && value != nil && value != "" {
+						event.UserProperties[name] = value
+					}
+				}
+			}
+		}
+	}
+
+	return event, nil
+}
+
+//customProperties returns event root fields except Jitsu system and context fields merged with /properties fields
+func customProperties(object map[string]interface{}) map[string]interface{} {
+	properties := map[string]interface{}{}
+	for name, value := range object {
+		if !analyticsReservedFields[name] && value != nil {
+			properties[name] = value
+		}
+	}
+	if nested, ok := object["properties"].(map[string]interface{}); ok {
+		for name, value := range nested {
+			properties[name] = value
+		}
+	}
+
+	return properties
+}
+
+//IdentifyEvent returns $identify event which links anonymous ID with the user of identified event
+//returns err if identified event doesn't have user id or email
+func IdentifyEvent(anonymousID string, identifiedEvent map[string]interface{}) (map[string]interface{}, error) {
+	userID := analyticsString(analyticsUserIDPath, identifiedEvent)
+	if userID == "" {
+		return nil, errors.New("Identified event doesn't have user id or email")
+	}
+
+	return map[string]interface{}{
+		"event_type":          IdentifyEventType,
+		"eventn_ctx_event_id": uuid.New(),
+		"src":                 identifyEventSrc,
+		timestamp.Key:         timestamp.NowUTC(),
+		"user":                map[string]interface{}{"id": userID, "anonymous_id": anonymousID},
+	}, nil
+}
+
+//analyticsString returns string value by path or empty string if it is absent
+func analyticsString(path jsonutils.JSONPath, object map[string]interface{}) string {
+	value, ok := path.Get(object)
+	if !ok || value == nil {
+		return ""
+	}
+
+	return fmt.Sprint(value)
+}
+
+//eventTime returns event timestamp or now if it is absent
+func eventTime(object map[string]interface{}) time.Time {
+	switch t := object[timestamp.Key].(type) {
+	case time.Time:
+		return t
+	case string:
+		eventTime, err := time.Parse(time.RFC3339Nano, t)
+		if err != nil {
+			logging.Errorf("Error parsing %s in event: %v", timestamp.Key, err)
+			break
+		}
+		return eventTime
+	}
+
+	return time.Now().UTC()
+}
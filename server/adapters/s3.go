@@ -5,7 +5,11 @@ import (
 	"compress/gzip"
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
@@ -152,6 +156,127 @@ func (a *S3) DeleteObject(key string) error {
 	return nil
 }
 
+//ListObjects returns all object keys from the configured bucket folder
+func (a *S3) ListObjects() ([]string, error) {
+	input := &s3.ListObjectsV2Input{Bucket: aws.String(a.config.Bucket)}
+	if a.config.Folder != "" {
+		input.Prefix = aws.String(a.config.Folder + "/")
+	}
+
+	var keys []string
+	err := a.client.ListObjectsV2Pages(input, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, object := range page.Contents {
+			keys = append(keys, *object.Key)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error listing s3 objects in bucket %s: %v", a.config.Bucket, err)
+	}
+
+	return keys, nil
+}
+
+//DeleteObjectsOlderThan deletes objects under the prefix (in the configured bucket folder) which were modified before the time
+//if dryRun is true - objects are only listed. Returns full keys of deleted objects
+func (a *S3) DeleteObjectsOlderThan(prefix string, before time.Time, dryRun bool) ([]string, error) {
+	if a.config.Folder != "" {
+		prefix = a.config.Folder + "/" + prefix
+	}
+
+	var keys []string
+	input := &s3.ListObjectsV2Input{Bucket: aws.String(a.config.Bucket), Prefix: aws.String(prefix)}
+	err := a.client.ListObjectsV2Pages(input, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, object := range page.Contents {
+			if object.LastModified != nil && object.LastModified.Before(before) {
+				keys = append(keys, *object.Key)
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error listing s3 objects in bucket %s with prefix %s: %v", a.config.Bucket, prefix, err)
+	}
+
+	if dryRun {
+		return keys, nil
+	}
+
+	//DeleteObjects accepts up to 1000 keys per request
+	for start := 0; start < len(keys); start += 1000 {
+		end := start + 1000
+		if end > len(keys) {
+			end = len(keys)
+		}
+
+		var objects []*s3.ObjectIdentifier
+		for _, key := range keys[start:end] {
+			objects = append(objects, &s3.ObjectIdentifier{Key: aws.String(key)})
+		}
+
+		output, err := a.client.DeleteObjects(&s3.DeleteObjectsInput{Bucket: aws.String(a.config.Bucket), Delete: &s3.Delete{Objects: objects, Quiet: aws.Bool(true)}})
+		if err != nil {
+			return keys[:start], fmt.Errorf("Error deleting s3 objects from bucket %s: %v", a.config.Bucket, err)
+		}
+		if len(output.Errors) > 0 {
+			return keys[:start], fmt.Errorf("Error deleting s3 object %s from bucket %s: %s", aws.StringValue(output.Errors[0].Key), a.config.Bucket, aws.StringValue(output.Errors[0].Message))
+		}
+	}
+
+	return keys, nil
+}
+
+//GetObject returns object payload by full key. Decompresses gzip objects
+func (a *S3) GetObject(key string) ([]byte, error) {
+	output, err := a.client.GetObject(&s3.GetObjectInput{Bucket: aws.String(a.config.Bucket), Key: aws.String(key)})
+	if err != nil {
+		return nil, fmt.Errorf("Error getting file %s from s3: %v", key, err)
+	}
+	defer output.Body.Close()
+
+	var reader io.Reader = output.Body
+	if strings.HasSuffix(key, ".gz") {
+		gzipReader, err := gzip.NewReader(output.Body)
+		if err != nil {
+			return nil, fmt.Errorf("Error decompressing file %s from s3: %v", key, err)
+		}
+		defer gzipReader.Close()
+		reader = gzipReader
+	}
+
+	b, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading file %s from s3: %v", key, err)
+	}
+
+	return b, nil
+}
+
+//RewriteObject overwrites object by full key with payload. Compresses payload if the key is a gzip object
+func (a *S3) RewriteObject(key string, fileBytes []byte) error {
+	params := &s3.PutObjectInput{
+		Bucket:      aws.String(a.config.Bucket),
+		Key:         aws.String(key),
+		ContentType: aws.String(http.DetectContentType(fileBytes)),
+	}
+
+	if strings.HasSuffix(key, ".gz") {
+		var err error
+		fileBytes, err = a.compressGZIP(fileBytes)
+		if err != nil {
+			return fmt.Errorf("Error compressing file %v", err)
+		}
+		params.ContentEncoding = aws.String(string(S3CompressionGZIP))
+	}
+
+	params.Body = bytes.NewReader(fileBytes)
+	if _, err := a.client.PutObject(params); err != nil {
+		return fmt.Errorf("Error rewriting file %s in s3: %v", key, err)
+	}
+
+	return nil
+}
+
 func fileNameGZIP(fileName string) string {
 	return fileName + ".gz"
 }
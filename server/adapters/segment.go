@@ -0,0 +1,370 @@
+package adapters
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/jsonutils"
+	"github.com/jitsucom/jitsu/server/uuid"
+)
+
+const (
+	segmentDefaultEndpoint = "https://api.segment.io/v1/batch"
+	segmentLibraryName     = "jitsu"
+
+	segmentTrack    = "track"
+	segmentIdentify = "identify"
+	segmentPage     = "page"
+	segmentScreen   = "screen"
+	segmentGroup    = "group"
+
+	//segmentMaxMessageSize and segmentMaxBatchSize are Segment HTTP Tracking API limits
+	segmentMaxMessageSize = 32 * 1024
+	segmentMaxBatchSize   = 500 * 1024
+)
+
+var (
+	segmentUserIDPath  = jsonutils.NewJSONPath("/user/internal_id||/eventn_ctx/user/internal_id||/user/id||/eventn_ctx/user/id")
+	segmentGroupIDPath = jsonutils.NewJSONPath("/groupId||/group_id||/company/id||/group/id")
+	segmentGroupPath   = jsonutils.NewJSONPath("/company||/group")
+	segmentNamePath    = jsonutils.NewJSONPath("/name")
+	segmentEventPath   = jsonutils.NewJSONPath("/event")
+
+	//segmentContextFields is a mapping Segment context path -> Jitsu event path
+	//it is the reverse of the compatibility.segment.endpoint mapping
+	segmentContextFields = map[string]jsonutils.JSONPath{
+		"/ip":                   jsonutils.NewJSONPath("/source_ip"),
+		"/userAgent":            jsonutils.NewJSONPath("/eventn_ctx/user_agent||/user_agent"),
+		"/locale":               jsonutils.NewJSONPath("/eventn_ctx/user_language||/user_language"),
+		"/location":             jsonutils.NewJSONPath("/eventn_ctx/location||/location"),
+		"/page/url":             jsonutils.NewJSONPath("/eventn_ctx/url||/url"),
+		"/page/title":           jsonutils.NewJSONPath("/eventn_ctx/page_title||/page_title"),
+		"/page/referrer":        jsonutils.NewJSONPath("/eventn_ctx/referer||/referer"),
+		"/page/path":            jsonutils.NewJSONPath("/eventn_ctx/doc_path||/doc_path"),
+		"/page/search":          jsonutils.NewJSONPath("/eventn_ctx/doc_search||/doc_search"),
+		"/campaign/name":        jsonutils.NewJSONPath("/eventn_ctx/utm/campaign||/utm/campaign"),
+		"/campaign/source":      jsonutils.NewJSONPath("/eventn_ctx/utm/source||/utm/source"),
+		"/campaign/medium":      jsonutils.NewJSONPath("/eventn_ctx/utm/medium||/utm/medium"),
+		"/campaign/term":        jsonutils.NewJSONPath("/eventn_ctx/utm/term||/utm/term"),
+		"/campaign/content":     jsonutils.NewJSONPath("/eventn_ctx/utm/content||/utm/content"),
+		"/os/name":              jsonutils.NewJSONPath("/eventn_ctx/parsed_ua/os_family||/parsed_ua/os_family"),
+		"/os/version":           jsonutils.NewJSONPath("/eventn_ctx/parsed_ua/os_version||/parsed_ua/os_version"),
+		"/device/id":            jsonutils.NewJSONPath("/eventn_ctx/parsed_ua/device_id||/parsed_ua/device_id"),
+		"/device/advertisingId": jsonutils.NewJSONPath("/eventn_ctx/parsed_ua/device_advertising_id||/parsed_ua/device_advertising_id"),
+		"/device/manufacturer":  jsonutils.NewJSONPath("/eventn_ctx/parsed_ua/device_brand||/parsed_ua/device_brand"),
+		"/device/model":         jsonutils.NewJSONPath("/eventn_ctx/parsed_ua/device_model||/parsed_ua/device_model"),
+		"/device/name":          jsonutils.NewJSONPath("/eventn_ctx/parsed_ua/device_name||/parsed_ua/device_name"),
+		"/device/type":          jsonutils.NewJSONPath("/eventn_ctx/parsed_ua/device_type||/parsed_ua/device_type"),
+	}
+
+	//segmentPageProperties is a mapping Segment page/screen property -> Jitsu event path
+	segmentPageProperties = map[string]jsonutils.JSONPath{
+		"url":      jsonutils.NewJSONPath("/eventn_ctx/url||/url"),
+		"title":    jsonutils.NewJSONPath("/eventn_ctx/page_title||/page_title"),
+		"referrer": jsonutils.NewJSONPath("/eventn_ctx/referer||/referer"),
+		"path":     jsonutils.NewJSONPath("/eventn_ctx/doc_path||/doc_path"),
+		"search":   jsonutils.NewJSONPath("/eventn_ctx/doc_search||/doc_search"),
+	}
+)
+
+//SegmentConfig is a dto for parsing Segment-compatible destination configuration
+type SegmentConfig struct {
+	WriteKey string `mapstructure:"write_key" json:"write_key,omitempty" yaml:"write_key,omitempty"`
+	Endpoint string `mapstructure:"endpoint" json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+}
+
+//Validate returns err if invalid and sets default endpoint
+func (sc *SegmentConfig) Validate() error {
+	if sc == nil {
+		return errors.New("segment config is required")
+	}
+	if sc.WriteKey == "" {
+		return errors.New("'write_key' is required parameter")
+	}
+	if sc.Endpoint == "" {
+		sc.Endpoint = segmentDefaultEndpoint
+	}
+
+	return nil
+}
+
+//SegmentMessage is a dto for Segment track/identify/page/screen/group call
+type SegmentMessage struct {
+	Type        string                 `json:"type"`
+	MessageID   string                 `json:"messageId"`
+	Timestamp   string                 `json:"timestamp"`
+	UserID      string                 `json:"userId,omitempty"`
+	AnonymousID string                 `json:"anonymousId,omitempty"`
+	Event       string                 `json:"event,omitempty"`
+	Name        string                 `json:"name,omitempty"`
+	GroupID     string                 `json:"groupId,omitempty"`
+	Properties  map[string]interface{} `json:"properties,omitempty"`
+	Traits      map[string]interface{} `json:"traits,omitempty"`
+	Context     map[string]interface{} `json:"context,omitempty"`
+}
+
+//SegmentBatchRequest is a dto for sending requests to Segment-compatible /v1/batch endpoint
+type SegmentBatchRequest struct {
+	Batch []*SegmentMessage `json:"batch"`
+}
+
+//SegmentRequestFactory is a factory for building Segment /v1/batch HTTP requests from input events
+type SegmentRequestFactory struct {
+	config        *SegmentConfig
+	authorization string
+}
+
+//newSegmentRequestFactory returns configured HTTPRequestFactory instance for Segment requests
+func newSegmentRequestFactory(config *SegmentConfig) *SegmentRequestFactory {
+	return &SegmentRequestFactory{
+		config:        config,
+		authorization: "Basic " + base64.StdEncoding.EncodeToString([]byte(config.WriteKey+":")),
+	}
+}
+
+//Create returns /v1/batch request with one Segment message built from the object
+func (srf *SegmentRequestFactory) Create(object map[string]interface{}) (*Request, error) {
+	message, err := srf.buildMessage(object)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := json.Marshal(message)
+	if err != nil {
+		return nil, fmt.Errorf("Error marshalling segment message [%v]: %v", message, err)
+	}
+	if len(b) > segmentMaxMessageSize {
+		return nil, fmt.Errorf("Segment message size %d bytes exceeds %d bytes limit", len(b), segmentMaxMessageSize)
+	}
+
+	return srf.newRequest([]*SegmentMessage{message})
+}
+
+//buildMessage returns Segment message: event type is mapped to the Segment call type
+func (srf *SegmentRequestFactory) buildMessage(object map[string]interface{}) (*SegmentMessage, error) {
+	eventType := analyticsString(analyticsEventTypePath, object)
+	if eventType == "" {
+		return nil, errors.New("Object doesn't have event_type")
+	}
+
+	message := &SegmentMessage{
+		MessageID:   analyticsString(analyticsEventIDPath, object),
+		Timestamp:   eventTime(object).UTC().Format(time.RFC3339Nano),
+		UserID:      analyticsString(segmentUserIDPath, object),
+		AnonymousID: analyticsString(analyticsAnonymousIDPath, object),
+		Context:     segmentContext(object),
+	}
+	if message.UserID == "" && message.AnonymousID == "" {
+		return nil, errors.New("Object doesn't have userId or anonymousId: /user/internal_id, /user/id or /user/anonymous_id is required")
+	}
+	if message.MessageID == "" {
+		message.MessageID = uuid.GetHash(object)
+	}
+
+	traits := segmentTraits(object)
+	properties := customProperties(object)
+	switch strings.ToLower(eventType) {
+	case segmentIdentify, UserIdentifyEventType:
+		message.Type = segmentIdentify
+		message.Traits = traits
+		properties = nil
+	case segmentPage, "pageview", "app_page", segmentScreen, "screenview":
+		message.Type = segmentPage
+		if strings.HasPrefix(strings.ToLower(eventType), segmentScreen) {
+			message.Type = segmentScreen
+		}
+		message.Name = analyticsString(segmentNamePath, object)
+		delete(properties, "name")
+		for name, path := range segmentPageProperties {
+			if value, ok := path.Get(object); ok && value != nil && value != "" {
+				properties[name] = value
+			}
+		}
+	case segmentGroup:
+		message.Type = segmentGroup
+		message.GroupID = analyticsString(segmentGroupIDPath, object)
+		if message.GroupID == "" {
+			return nil, errors.New("Group event doesn't have group id: /groupId, /group_id or /company/id is required")
+		}
+		message.Traits = map[string]interface{}{}
+		if group, ok := segmentGroupPath.Get(object); ok {
+			if groupObject, ok := group.(map[string]interface{}); ok {
+				for name, value := range groupObject {
+					if name != "id" {
+						message.Traits[name] = value
+					}
+				}
+			}
+		}
+		properties = nil
+	default:
+		message.Type = segmentTrack
+		message.Event = eventType
+		//the event has been received via Segment compatibility API
+		if eventType == segmentTrack {
+			message.Event = analyticsString(segmentEventPath, object)
+			delete(properties, "event")
+		}
+	}
+
+	if len(properties) > 0 {
+		message.Properties = properties
+	}
+	if message.Type != segmentIdentify && len(traits) > 0 {
+		message.Context["traits"] = traits
+	}
+
+	return message, nil
+}
+
+//newRequest returns /v1/batch HTTP POST request authorized with write key
+func (srf *SegmentRequestFactory) newRequest(batch []*SegmentMessage) (*Request, error) {
+	req := &SegmentBatchRequest{Batch: batch}
+	b, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("Error marshalling segment request [%v]: %v", req, err)
+	}
+
+	return &Request{
+		URL:     srf.config.Endpoint,
+		Method:  http.MethodPost,
+		Body:    b,
+		Headers: map[string]string{"Content-Type": "application/json", "Authorization": srf.authorization},
+	}, nil
+}
+
+//BatchKey returns the same key for all requests: all Segment messages can be sent in one batch
+func (srf *SegmentRequestFactory) BatchKey(req *Request) string {
+	return req.URL
+}
+
+//CreateBatch returns /v1/batch request with messages from all requests
+func (srf *SegmentRequestFactory) CreateBatch(requests []*Request) (*Request, error) {
+	var batch []*SegmentMessage
+	for _, r := range requests {
+		req := &SegmentBatchRequest{}
+		if err := json.Unmarshal(r.Body, req); err != nil {
+			return nil, fmt.Errorf("Error unmarshalling segment request: %v", err)
+		}
+		batch = append(batch, req.Batch...)
+	}
+
+	return srf.newRequest(batch)
+}
+
+//MaxBatchEvents returns 0: Segment limits only batch size (see NewSegment)
+func (srf *SegmentRequestFactory) MaxBatchEvents() int {
+	return 0
+}
+
+//BatchItemErrors returns empty map: Segment accepts or rejects the whole batch
+func (srf *SegmentRequestFactory) BatchItemErrors(statusCode int, responseBody []byte, batchSize int) map[int]error {
+	return map[int]error{}
+}
+
+func (srf *SegmentRequestFactory) Close() {
+}
+
+//segmentContext returns Segment context object built from Jitsu context fields
+func segmentContext(object map[string]interface{}) map[string]interface{} {
+	context := map[string]interface{}{"library": map[string]interface{}{"name": segmentLibraryName}}
+	for dst, src := range segmentContextFields {
+		if value, ok := src.Get(object); ok && value != nil && value != "" {
+			jsonutils.NewJSONPath(dst).Set(context, value)
+		}
+	}
+
+	return context
+}
+
+//segmentTraits returns Jitsu user fields except identifiers
+func segmentTraits(object map[string]interface{}) map[string]interface{} {
+	traits := map[string]interface{}{}
+	if user, ok := analyticsUserPath.Get(object); ok {
+		if userObject, ok := user.(map[string]interface{}); ok {
+			for name, value := range userObject {
+				if !analyticsUserIdentifiers[name] && value != nil && value != "" {
+					traits[name] = value
+				}
+			}
+		}
+	}
+
+	return traits
+}
+
+//Segment is an adapter for sending events to Segment-compatible HTTP Tracking API (Segment, RudderStack, etc.)
+type Segment struct {
+	AbstractHTTP
+
+	config *SegmentConfig
+}
+
+//NewSegment returns configured Segment adapter instance
+//batch size is limited with Segment limit
+func NewSegment(config *SegmentConfig, httpAdapterConfiguration *HTTPAdapterConfiguration) (*Segment, error) {
+	httpAdapterConfiguration.HTTPReqFactory = newSegmentRequestFactory(config)
+	if batch := httpAdapterConfiguration.Batch; batch != nil && (batch.MaxSizeBytes == 0 || batch.MaxSizeBytes > segmentMaxBatchSize) {
+		batch.MaxSizeBytes = segmentMaxBatchSize
+	}
+
+	httpAdapter, err := NewHTTPAdapter(httpAdapterConfiguration)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Segment{config: config}
+	s.httpAdapter = httpAdapter
+	return s, nil
+}
+
+//NewTestSegment returns test instance of adapter
+func NewTestSegment(config *SegmentConfig) *Segment {
+	return &Segment{config: config}
+}
+
+//TestAccess sends empty batch to the endpoint and returns err if it isn't accepted
+//Note: Segment doesn't validate write key synchronously, but other implementations (e.g. RudderStack) do
+func (s *Segment) TestAccess() error {
+	r, err := newSegmentRequestFactory(s.config).newRequest([]*SegmentMessage{})
+	if err != nil {
+		return err
+	}
+
+	httpReq, err := http.NewRequest(r.Method, r.URL, bytes.NewBuffer(r.Body))
+	if err != nil {
+		return err
+	}
+	for k, v := range r.Headers {
+		httpReq.Header.Set(k, v)
+	}
+
+	resp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	responseBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("Error reading segment response body: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Error connecting to segment endpoint [code=%d]: %s", resp.StatusCode, string(responseBody))
+	}
+
+	return nil
+}
+
+//Type returns adapter type
+func (s *Segment) Type() string {
+	return "Segment"
+}
@@ -0,0 +1,144 @@
+package adapters
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/timestamp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSegmentCreate(t *testing.T) {
+	config := &SegmentConfig{WriteKey: "key"}
+	require.NoError(t, config.Validate())
+	factory := newSegmentRequestFactory(config)
+
+	eventTime := time.Date(2021, 12, 31, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name            string
+		input           map[string]interface{}
+		expectedMessage string
+		expectedErr     string
+	}{
+		{
+			"Track",
+			map[string]interface{}{"event_type": "purchase", "eventn_ctx_event_id": "e1", "value": 10, "source_ip": "1.1.1.1", timestamp.Key: eventTime,
+				"eventn_ctx": map[string]interface{}{"user_agent": "Mozilla", "utm": map[string]interface{}{"campaign": "sale"},
+					"user": map[string]interface{}{"anonymous_id": "anon1", "internal_id": "u1", "email": "a@b.com"}}},
+			`{"type":"track","messageId":"e1","timestamp":"2021-12-31T10:00:00Z","userId":"u1","anonymousId":"anon1","event":"purchase",
+				"properties":{"value":10},"context":{"library":{"name":"jitsu"},"ip":"1.1.1.1","userAgent":"Mozilla","campaign":{"name":"sale"},"traits":{"email":"a@b.com"}}}`,
+			"",
+		},
+		{
+			"Track received via Segment API",
+			map[string]interface{}{"event_type": "track", "event": "Order Completed", "eventn_ctx_event_id": "e2", "total": 5, timestamp.Key: eventTime,
+				"user": map[string]interface{}{"anonymous_id": "anon1"}},
+			`{"type":"track","messageId":"e2","timestamp":"2021-12-31T10:00:00Z","anonymousId":"anon1","event":"Order Completed",
+				"properties":{"total":5},"context":{"library":{"name":"jitsu"}}}`,
+			"",
+		},
+		{
+			"Identify",
+			map[string]interface{}{"event_type": "user_identify", "eventn_ctx_event_id": "e3", "plan": "pro", timestamp.Key: eventTime,
+				"user": map[string]interface{}{"id": "u1", "anonymous_id": "anon1", "email": "a@b.com"}},
+			`{"type":"identify","messageId":"e3","timestamp":"2021-12-31T10:00:00Z","userId":"u1","anonymousId":"anon1",
+				"traits":{"email":"a@b.com"},"context":{"library":{"name":"jitsu"}}}`,
+			"",
+		},
+		{
+			"Page",
+			map[string]interface{}{"event_type": "pageview", "eventn_ctx_event_id": "e4", "name": "Pricing", timestamp.Key: eventTime,
+				"url": "https://jitsu.com/pricing", "page_title": "Pricing", "doc_path": "/pricing", "user": map[string]interface{}{"anonymous_id": "anon1"}},
+			`{"type":"page","messageId":"e4","timestamp":"2021-12-31T10:00:00Z","anonymousId":"anon1","name":"Pricing",
+				"properties":{"url":"https://jitsu.com/pricing","title":"Pricing","path":"/pricing"},
+				"context":{"library":{"name":"jitsu"},"page":{"url":"https://jitsu.com/pricing","title":"Pricing","path":"/pricing"}}}`,
+			"",
+		},
+		{
+			"Group",
+			map[string]interface{}{"event_type": "group", "eventn_ctx_event_id": "e5", timestamp.Key: eventTime,
+				"company": map[string]interface{}{"id": "c1", "name": "Jitsu"}, "user": map[string]interface{}{"id": "u1"}},
+			`{"type":"group","messageId":"e5","timestamp":"2021-12-31T10:00:00Z","userId":"u1","groupId":"c1",
+				"traits":{"name":"Jitsu"},"context":{"library":{"name":"jitsu"}}}`,
+			"",
+		},
+		{
+			"Group without id",
+			map[string]interface{}{"event_type": "group", "user": map[string]interface{}{"id": "u1"}},
+			"",
+			"Group event doesn't have group id: /groupId, /group_id or /company/id is required",
+		},
+		{
+			"Event without user",
+			map[string]interface{}{"event_type": "purchase"},
+			"",
+			"Object doesn't have userId or anonymousId: /user/internal_id, /user/id or /user/anonymous_id is required",
+		},
+		{
+			"Too large message",
+			map[string]interface{}{"event_type": "purchase", timestamp.Key: eventTime, "user": map[string]interface{}{"id": "u1"}, "payload": strings.Repeat("a", segmentMaxMessageSize)},
+			"",
+			"Segment message size 32966 bytes exceeds 32768 bytes limit",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := factory.Create(tt.input)
+			if tt.expectedErr != "" {
+				require.EqualError(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, segmentDefaultEndpoint, r.URL)
+			require.Equal(t, "Basic a2V5Og==", r.Headers["Authorization"])
+
+			req := &SegmentBatchRequest{}
+			require.NoError(t, json.Unmarshal(r.Body, req))
+			require.Len(t, req.Batch, 1)
+			message, err := json.Marshal(req.Batch[0])
+			require.NoError(t, err)
+			require.JSONEq(t, tt.expectedMessage, string(message))
+		})
+	}
+}
+
+func TestSegmentBatch(t *testing.T) {
+	config := &SegmentConfig{WriteKey: "key", Endpoint: "https://rudderstack.example.com/v1/batch"}
+	require.NoError(t, config.Validate())
+
+	batchConfig := &HTTPBatchConfig{Enabled: true}
+	segment, err := NewSegment(config, &HTTPAdapterConfiguration{
+		DestinationID:  "test",
+		Dir:            t.TempDir(),
+		HTTPConfig:     &HTTPConfiguration{GlobalClientTimeout: time.Second, RetryDelay: time.Second},
+		PoolWorkers:    1,
+		DebugLogger:    &logging.QueryLogger{},
+		ErrorHandler:   func(fallback bool, eventContext *EventContext, err error) {},
+		SuccessHandler: func(eventContext *EventContext) {},
+		Batch:          batchConfig,
+	})
+	require.NoError(t, err)
+	defer segment.Close()
+	require.Equal(t, segmentMaxBatchSize, batchConfig.MaxSizeBytes, "batch size is limited with Segment limit")
+
+	factory := newSegmentRequestFactory(config)
+	first, err := factory.Create(map[string]interface{}{"event_type": "a", "user": map[string]interface{}{"anonymous_id": "anon1"}})
+	require.NoError(t, err)
+	second, err := factory.Create(map[string]interface{}{"event_type": "user_identify", "user": map[string]interface{}{"id": "u1"}})
+	require.NoError(t, err)
+	require.Equal(t, factory.BatchKey(first), factory.BatchKey(second))
+
+	batch, err := factory.CreateBatch([]*Request{first, second})
+	require.NoError(t, err)
+	require.Equal(t, "https://rudderstack.example.com/v1/batch", batch.URL)
+	require.Equal(t, "Basic a2V5Og==", batch.Headers["Authorization"])
+
+	req := &SegmentBatchRequest{}
+	require.NoError(t, json.Unmarshal(batch.Body, req))
+	require.Len(t, req.Batch, 2)
+	require.Equal(t, "track", req.Batch[0].Type)
+	require.Equal(t, "identify", req.Batch[1].Type)
+}
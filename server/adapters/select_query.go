@@ -0,0 +1,68 @@
+package adapters
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+)
+
+var (
+	ErrNotSelectQuery      = errors.New("Only SELECT queries are allowed")
+	ErrMultipleStatements  = errors.New("Query must contain a single statement")
+	selectQueryFirstTokens = map[string]bool{"SELECT": true, "WITH": true}
+)
+
+//CheckSelectQuery returns err if the query isn't a single SELECT (or WITH ... SELECT) statement
+//comments and quoted values are skipped. Backslashes aren't treated as escape characters: queries with such values
+//might be rejected but never accepted by mistake
+func CheckSelectQuery(query string) error {
+	code := strings.TrimSpace(stripCommentsAndLiterals(query))
+	code = strings.TrimSpace(strings.TrimSuffix(code, ";"))
+	if strings.Contains(code, ";") {
+		return ErrMultipleStatements
+	}
+
+	firstTokenEnd := strings.IndexFunc(code, func(r rune) bool { return !unicode.IsLetter(r) })
+	if firstTokenEnd == -1 {
+		firstTokenEnd = len(code)
+	}
+	if !selectQueryFirstTokens[strings.ToUpper(code[:firstTokenEnd])] {
+		return ErrNotSelectQuery
+	}
+
+	return nil
+}
+
+//stripCommentsAndLiterals returns query without comments and with empty quoted values ('', "", ``)
+func stripCommentsAndLiterals(query string) string {
+	var result strings.Builder
+	runes := []rune(query)
+	for i := 0; i < len(runes); i++ {
+		switch {
+		case runes[i] == '-' && i+1 < len(runes) && runes[i+1] == '-':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+			result.WriteRune(' ')
+		case runes[i] == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			i += 2
+			for i < len(runes) && !(runes[i] == '*' && i+1 < len(runes) && runes[i+1] == '/') {
+				i++
+			}
+			i++
+			result.WriteRune(' ')
+		case runes[i] == '\'' || runes[i] == '"' || runes[i] == '`':
+			quote := runes[i]
+			i++
+			for i < len(runes) && runes[i] != quote {
+				i++
+			}
+			result.WriteRune(quote)
+			result.WriteRune(quote)
+		default:
+			result.WriteRune(runes[i])
+		}
+	}
+
+	return result.String()
+}
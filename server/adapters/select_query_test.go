@@ -0,0 +1,36 @@
+package adapters
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckSelectQuery(t *testing.T) {
+	tests := []struct {
+		name        string
+		query       string
+		expectedErr error
+	}{
+		{"Select", "SELECT id, email FROM users", nil},
+		{"Lower case select with trailing semicolon", "select * from users;\n", nil},
+		{"With", "WITH paid AS (SELECT * FROM orders) SELECT * FROM paid", nil},
+		{"Leading comments", "-- active users\n/* daily */ SELECT * FROM users", nil},
+		{"Semicolon in value", "SELECT * FROM users WHERE name = 'a;b'", nil},
+		{"Semicolon in comment", "SELECT * FROM users -- a;b", nil},
+		{"Empty", "  ", ErrNotSelectQuery},
+		{"Only comment", "-- SELECT 1", ErrNotSelectQuery},
+		{"Delete", "DELETE FROM users", ErrNotSelectQuery},
+		{"Delete after comment", "/* SELECT */ DELETE FROM users", ErrNotSelectQuery},
+		{"Quoted select", "'SELECT' DROP TABLE users", ErrNotSelectQuery},
+		{"Selected", "SELECTED", ErrNotSelectQuery},
+		{"Multiple statements", "SELECT 1; DROP TABLE users", ErrMultipleStatements},
+		{"Multiple statements after comment", "SELECT 1; -- comment\nDROP TABLE users;", ErrMultipleStatements},
+		{"Backslash isn't escape", `SELECT 'a\'; DROP TABLE users; --'`, ErrMultipleStatements},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expectedErr, CheckSelectQuery(tt.query))
+		})
+	}
+}
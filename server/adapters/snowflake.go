@@ -8,6 +8,7 @@ import (
 	"github.com/jitsucom/jitsu/server/uuid"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/jitsucom/jitsu/server/logging"
 	"github.com/jitsucom/jitsu/server/typing"
@@ -17,10 +18,15 @@ import (
 const (
 	tableExistenceSFQuery   = `SELECT count(*) from INFORMATION_SCHEMA.COLUMNS where TABLE_SCHEMA = ? and TABLE_NAME = ?`
 	descSchemaSFQuery       = `desc table %s.%s`
+	tableNamesSFQuery       = `SELECT TABLE_NAME from INFORMATION_SCHEMA.TABLES where TABLE_SCHEMA = ? and TABLE_TYPE = 'BASE TABLE'`
 	copyStatementFileFormat = ` FILE_FORMAT=(TYPE= 'CSV', FIELD_DELIMITER = '||' SKIP_HEADER = 1 EMPTY_FIELD_AS_NULL = true) `
 	gcpFrom                 = `FROM @%s
    							   %s
                                PATTERN = '%s'`
+	//transformationFrom is used for parsing VARIANT columns (transformations are supported only with stages)
+	transformationFrom = `FROM (SELECT %s FROM @%s)
+   							   %s
+                               PATTERN = '%s'`
 	awsS3From = `FROM 's3://%s/%s'
 					           CREDENTIALS = (aws_key_id='%s' aws_secret_key='%s') 
                                %s`
@@ -30,10 +36,14 @@ const (
 	createSFDbSchemaIfNotExistsTemplate = `CREATE SCHEMA IF NOT EXISTS %s`
 	addSFColumnTemplate                 = `ALTER TABLE %s.%s ADD COLUMN %s`
 	createSFTableTemplate               = `CREATE TABLE %s.%s (%s)`
-	insertSFTemplate                    = `INSERT INTO %s.%s (%s) VALUES %s`
+	insertSFTemplate                    = `INSERT INTO %s.%s (%s) %s`
+	insertFromSelectSFTemplate          = `INSERT INTO %s.%s (%s) SELECT %s FROM %s.%s`
 	deleteSFTemplate                    = `DELETE FROM %s.%s WHERE %s`
+	countSFTemplate                     = `SELECT count(*) FROM %s.%s WHERE %s`
 	dropSFTableTemplate                 = `DROP TABLE %s.%s`
 	truncateSFTableTemplate             = `TRUNCATE TABLE IF EXISTS %s.%s`
+
+	defaultSnowflakeLoadRetries = 3
 )
 
 var (
@@ -44,6 +54,8 @@ var (
 		typing.TIMESTAMP: "timestamp(6)",
 		typing.BOOL:      "boolean",
 		typing.UNKNOWN:   "text",
+		typing.OBJECT:    "variant",
+		typing.ARRAY:     "variant",
 	}
 )
 
@@ -58,6 +70,24 @@ type SnowflakeConfig struct {
 	Warehouse  string             `mapstructure:"warehouse" json:"warehouse,omitempty" yaml:"warehouse,omitempty"`
 	Stage      string             `mapstructure:"stage" json:"stage,omitempty" yaml:"stage,omitempty"`
 	Parameters map[string]*string `mapstructure:"parameters" json:"parameters,omitempty" yaml:"parameters,omitempty"`
+	//InternalStage is used for loading files without external bucket (S3 or GCS)
+	InternalStage *SnowflakeInternalStageConfig `mapstructure:"internal_stage" json:"internal_stage,omitempty" yaml:"internal_stage,omitempty"`
+	//Snowpipe is used for continuous loading of staged files instead of COPY INTO
+	Snowpipe *SnowpipeConfig `mapstructure:"snowpipe" json:"snowpipe,omitempty" yaml:"snowpipe,omitempty"`
+	//LoadRetries is a number of attempts of uploading file into the stage and loading it
+	LoadRetries int `mapstructure:"load_retries" json:"load_retries,omitempty" yaml:"load_retries,omitempty"`
+}
+
+//SnowflakeInternalStageConfig dto for loading files through Snowflake named internal stage (PUT + COPY INTO)
+type SnowflakeInternalStageConfig struct {
+	Enabled bool `mapstructure:"enabled" json:"enabled,omitempty" yaml:"enabled,omitempty"`
+	//Name of the internal stage. It is created if doesn't exist
+	Name string `mapstructure:"name" json:"name,omitempty" yaml:"name,omitempty"`
+}
+
+//IsEnabled returns true if internal stage is configured and enabled
+func (sisc *SnowflakeInternalStageConfig) IsEnabled() bool {
+	return sisc != nil && sisc.Enabled
 }
 
 //Validate required fields in SnowflakeConfig
@@ -82,6 +112,26 @@ func (sc *SnowflakeConfig) Validate() error {
 		sc.Parameters = map[string]*string{}
 	}
 
+	if sc.InternalStage.IsEnabled() {
+		if sc.InternalStage.Name == "" {
+			sc.InternalStage.Name = defaultSnowflakeInternalStage
+		}
+		sc.InternalStage.Name = reformatValue(sc.InternalStage.Name)
+	}
+
+	if sc.Snowpipe.IsEnabled() {
+		if err := sc.Snowpipe.Validate(); err != nil {
+			return err
+		}
+		if sc.Stage == "" && !sc.InternalStage.IsEnabled() {
+			return errors.New("Snowflake stage or enabled internal_stage is required for Snowpipe")
+		}
+	}
+
+	if sc.LoadRetries <= 0 {
+		sc.LoadRetries = defaultSnowflakeLoadRetries
+	}
+
 	sc.Schema = reformatValue(sc.Schema)
 	return nil
 }
@@ -251,11 +301,20 @@ func (s *Snowflake) GetTableSchema(tableName string) (*Table, error) {
 	return table, nil
 }
 
-//Copy transfer data from s3 to Snowflake by passing COPY request to Snowflake
-func (s *Snowflake) Copy(fileName, tableName string, header []string) error {
-	var reformattedHeader []string
-	for _, v := range header {
+//Copy transfer data from s3, gcp or internal stage to Snowflake by passing COPY request to Snowflake
+//Snowflake doesn't load the same file twice (load metadata), so Copy can be safely retried
+//VARIANT columns are parsed from JSON strings with COPY transformation (from stages) or via temporary table (from s3)
+func (s *Snowflake) Copy(fileName string, table *Table, header []string) error {
+	var reformattedHeader, selectColumns []string
+	hasVariant := false
+	for i, v := range header {
 		reformattedHeader = append(reformattedHeader, reformatValue(v))
+		if s.isVariant(v, table.Columns[v]) {
+			selectColumns = append(selectColumns, fmt.Sprintf("PARSE_JSON($%d)", i+1))
+			hasVariant = true
+		} else {
+			selectColumns = append(selectColumns, fmt.Sprintf("$%d", i+1))
+		}
 	}
 
 	wrappedTx, err := s.OpenTx()
@@ -263,16 +322,26 @@ func (s *Snowflake) Copy(fileName, tableName string, header []string) error {
 		return err
 	}
 
-	statement := fmt.Sprintf(`COPY INTO %s.%s (%s) `, s.config.Schema, reformatValue(tableName), strings.Join(reformattedHeader, ","))
-	if s.s3Config != nil {
-		//s3 integration stage
-		if s.s3Config.Folder != "" {
-			fileName = s.s3Config.Folder + "/" + fileName
+	if hasVariant && !s.config.InternalStage.IsEnabled() && s.s3Config != nil {
+		if err := s.copyFromS3WithParsing(wrappedTx, fileName, table, header); err != nil {
+			wrappedTx.Rollback()
+			return err
+		}
+
+		return wrappedTx.DirectCommit()
+	}
+
+	statement := fmt.Sprintf(`COPY INTO %s.%s (%s) `, s.config.Schema, reformatValue(table.Name), strings.Join(reformattedHeader, ","))
+	if s.config.InternalStage.IsEnabled() || s.s3Config == nil {
+		//internal stage or gcp integration stage
+		if hasVariant {
+			statement += fmt.Sprintf(transformationFrom, strings.Join(selectColumns, ","), s.namedStage(), copyStatementFileFormat, fileName)
+		} else {
+			statement += fmt.Sprintf(gcpFrom, s.namedStage(), copyStatementFileFormat, fileName)
 		}
-		statement += fmt.Sprintf(awsS3From, s.s3Config.Bucket, fileName, s.s3Config.AccessKeyID, s.s3Config.SecretKey, copyStatementFileFormat)
 	} else {
-		//gcp integration stage
-		statement += fmt.Sprintf(gcpFrom, s.config.Stage, copyStatementFileFormat, fileName)
+		//s3 integration stage
+		statement += s.s3From(fileName)
 	}
 
 	_, err = wrappedTx.tx.ExecContext(s.ctx, statement)
@@ -284,6 +353,54 @@ func (s *Snowflake) Copy(fileName, tableName string, header []string) error {
 	return wrappedTx.DirectCommit()
 }
 
+//copyFromS3WithParsing loads s3 file into a temporary table with text columns and inserts rows into the table
+//with parsed VARIANT columns (COPY transformations aren't supported with external locations)
+func (s *Snowflake) copyFromS3WithParsing(wrappedTx *Transaction, fileName string, table *Table, header []string) error {
+	tmpTable := &Table{
+		Name:     fmt.Sprintf("jitsu_tmp_%s", uuid.NewLettersNumbers()[:5]),
+		Columns:  Columns{},
+		PKFields: map[string]bool{},
+	}
+	var reformattedHeader, selectColumns []string
+	for _, name := range header {
+		tmpTable.Columns[name] = typing.SQLColumn{Type: SchemaToSnowflake[typing.STRING]}
+		reformattedHeader = append(reformattedHeader, reformatValue(name))
+		//other columns are converted implicitly
+		if s.isVariant(name, table.Columns[name]) {
+			selectColumns = append(selectColumns, "PARSE_JSON("+reformatValue(name)+")")
+		} else {
+			selectColumns = append(selectColumns, reformatValue(name))
+		}
+	}
+
+	if err := s.createTableInTransaction(wrappedTx, tmpTable); err != nil {
+		return fmt.Errorf("Error creating temporary table: %v", err)
+	}
+
+	statement := fmt.Sprintf(`COPY INTO %s.%s (%s) `, s.config.Schema, tmpTable.Name, strings.Join(reformattedHeader, ",")) + s.s3From(fileName)
+	if _, err := wrappedTx.tx.ExecContext(s.ctx, statement); err != nil {
+		return err
+	}
+
+	insertStatement := fmt.Sprintf(insertFromSelectSFTemplate, s.config.Schema, reformatValue(table.Name), strings.Join(reformattedHeader, ","),
+		strings.Join(selectColumns, ","), s.config.Schema, tmpTable.Name)
+	s.queryLogger.LogQuery(insertStatement)
+	if _, err := wrappedTx.tx.ExecContext(s.ctx, insertStatement); err != nil {
+		return fmt.Errorf("Error inserting rows from temporary table: %v", err)
+	}
+
+	return s.dropTableInTransaction(wrappedTx, tmpTable)
+}
+
+//s3From returns FROM clause with s3 file location and credentials
+func (s *Snowflake) s3From(fileName string) string {
+	if s.s3Config.Folder != "" {
+		fileName = s.s3Config.Folder + "/" + fileName
+	}
+
+	return fmt.Sprintf(awsS3From, s.s3Config.Bucket, fileName, s.s3Config.AccessKeyID, s.s3Config.SecretKey, copyStatementFileFormat)
+}
+
 // Insert inserts provided object into Snowflake
 func (s *Snowflake) Insert(eventContext *EventContext) error {
 	wrappedTx, err := s.OpenTx()
@@ -301,20 +418,19 @@ func (s *Snowflake) Insert(eventContext *EventContext) error {
 
 //insertInTransaction inserts provided object into Snowflake in transaction
 func (s *Snowflake) insertInTransaction(wrappedTx *Transaction, eventContext *EventContext) error {
-	var columnNames, placeholders []string
+	var unformattedColumnNames, columnNames, placeholders []string
 	var values []interface{}
 	for name, value := range eventContext.ProcessedEvent {
+		unformattedColumnNames = append(unformattedColumnNames, name)
 		columnNames = append(columnNames, reformatValue(name))
-
-		castClause := s.getCastClause(name, eventContext.Table.Columns[name])
-		placeholders = append(placeholders, "?"+castClause)
-		values = append(values, value)
+		placeholders = append(placeholders, s.placeholder(name, eventContext.Table.Columns[name]))
+		values = append(values, nestedToJSON(value))
 	}
 
 	header := strings.Join(columnNames, ", ")
-	placeholderStr := strings.Join(placeholders, ", ")
+	rows := s.insertRowsClause(eventContext.Table, unformattedColumnNames, []string{strings.Join(placeholders, ", ")})
 
-	query := fmt.Sprintf(insertSFTemplate, s.config.Schema, reformatValue(eventContext.Table.Name), header, "("+placeholderStr+")")
+	query := fmt.Sprintf(insertSFTemplate, s.config.Schema, reformatValue(eventContext.Table.Name), header, rows)
 	s.queryLogger.LogQueryWithValues(query, values)
 
 	_, err := wrappedTx.tx.ExecContext(s.ctx, query, values...)
@@ -372,6 +488,21 @@ func (s *Snowflake) BulkUpdate(table *Table, objects []map[string]interface{}, d
 	return wrappedTx.DirectCommit()
 }
 
+//Delete deletes objects with deleteConditions in transaction
+func (s *Snowflake) Delete(table *Table, deleteConditions *DeleteConditions) error {
+	wrappedTx, err := s.OpenTx()
+	if err != nil {
+		return err
+	}
+
+	if err := s.deleteInTransaction(wrappedTx, table, deleteConditions); err != nil {
+		wrappedTx.Rollback()
+		return err
+	}
+
+	return wrappedTx.DirectCommit()
+}
+
 //DropTable drops table in transaction
 func (s *Snowflake) DropTable(table *Table) error {
 	wrappedTx, err := s.OpenTx()
@@ -398,6 +529,41 @@ func (s *Snowflake) Truncate(tableName string) error {
 	return sqlParams.commonTruncate(tableName, statement)
 }
 
+//GetTableNames returns names of all tables in the schema (in upper case if they aren't quoted)
+func (s *Snowflake) GetTableNames() ([]string, error) {
+	sqlParams := SqlParams{
+		dataSource:  s.dataSource,
+		queryLogger: s.queryLogger,
+		ctx:         s.ctx,
+	}
+	return sqlParams.commonGetTableNames(tableNamesSFQuery, reformatToParam(s.config.Schema))
+}
+
+//Select executes SELECT query and passes result rows to consume
+//Snowflake driver doesn't support read-only transactions and executes only single statements
+func (s *Snowflake) Select(query string, consume func(row map[string]interface{}) error) error {
+	sqlParams := SqlParams{
+		dataSource:  s.dataSource,
+		queryLogger: s.queryLogger,
+		ctx:         s.ctx,
+	}
+	return sqlParams.commonSelect(query, false, consume)
+}
+
+//DeleteOlderThan deletes (or counts if dryRun) rows which field value is before the time
+func (s *Snowflake) DeleteOlderThan(table *Table, field string, before time.Time, dryRun bool) (int64, error) {
+	sqlParams := SqlParams{
+		dataSource:  s.dataSource,
+		queryLogger: s.queryLogger,
+		ctx:         s.ctx,
+	}
+	condition, values := s.toDeleteQuery(olderThanConditions(field, before))
+	return sqlParams.commonDeleteOlderThan(table.Name,
+		fmt.Sprintf(countSFTemplate, s.config.Schema, reformatValue(table.Name), condition),
+		fmt.Sprintf(deleteSFTemplate, s.config.Schema, reformatValue(table.Name), condition),
+		values, dryRun)
+}
+
 //createTableInTransaction creates database table with name,columns provided in Table representation
 func (s *Snowflake) createTableInTransaction(wrappedTx *Transaction, table *Table) error {
 	var columnsDDL []string
@@ -419,9 +585,8 @@ func (s *Snowflake) createTableInTransaction(wrappedTx *Transaction, table *Tabl
 	return nil
 }
 
-//bulkInsertInTransaction inserts events in batches (insert into values (),(),())
+//bulkInsertInTransaction inserts events in batches (insert into values (),(),() or insert into select union all select)
 func (s *Snowflake) bulkInsertInTransaction(wrappedTx *Transaction, table *Table, objects []map[string]interface{}) error {
-	var placeholdersBuilder strings.Builder
 	var unformattedColumnNames []string
 	for name := range table.Columns {
 		unformattedColumnNames = append(unformattedColumnNames, name)
@@ -431,47 +596,30 @@ func (s *Snowflake) bulkInsertInTransaction(wrappedTx *Transaction, table *Table
 		maxValues = postgresValuesLimit
 	}
 	valueArgs := make([]interface{}, 0, maxValues)
+	var rowsPlaceholders []string
 	for _, row := range objects {
 		// if number of values exceeds limit, we have to execute insert query on processed rows
 		if len(valueArgs)+len(unformattedColumnNames) > postgresValuesLimit {
-			err := s.executeInsert(wrappedTx, table, unformattedColumnNames, removeLastComma(placeholdersBuilder.String()), valueArgs)
+			err := s.executeInsert(wrappedTx, table, unformattedColumnNames, rowsPlaceholders, valueArgs)
 			if err != nil {
 				return fmt.Errorf("Error executing insert: %v", err)
 			}
 
-			placeholdersBuilder.Reset()
+			rowsPlaceholders = nil
 			valueArgs = make([]interface{}, 0, maxValues)
 		}
-		_, err := placeholdersBuilder.WriteString("(")
-		if err != nil {
-			return fmt.Errorf(placeholdersStringBuildErrTemplate, err)
-		}
 
+		placeholders := make([]string, len(unformattedColumnNames))
 		for i, column := range unformattedColumnNames {
 			value, _ := row[column]
-			valueArgs = append(valueArgs, value)
-			castClause := s.getCastClause(column, table.Columns[column])
-
-			_, err = placeholdersBuilder.WriteString("?" + castClause)
-			if err != nil {
-				return fmt.Errorf(placeholdersStringBuildErrTemplate, err)
-			}
-
-			if i < len(unformattedColumnNames)-1 {
-				_, err = placeholdersBuilder.WriteString(",")
-				if err != nil {
-					return fmt.Errorf(placeholdersStringBuildErrTemplate, err)
-				}
-			}
-		}
-		_, err = placeholdersBuilder.WriteString("),")
-		if err != nil {
-			return fmt.Errorf(placeholdersStringBuildErrTemplate, err)
+			valueArgs = append(valueArgs, nestedToJSON(value))
+			placeholders[i] = s.placeholder(column, table.Columns[column])
 		}
+		rowsPlaceholders = append(rowsPlaceholders, strings.Join(placeholders, ","))
 	}
 
 	if len(valueArgs) > 0 {
-		err := s.executeInsert(wrappedTx, table, unformattedColumnNames, removeLastComma(placeholdersBuilder.String()), valueArgs)
+		err := s.executeInsert(wrappedTx, table, unformattedColumnNames, rowsPlaceholders, valueArgs)
 		if err != nil {
 			return fmt.Errorf("Error executing last insert in bulk: %v", err)
 		}
@@ -546,13 +694,13 @@ func (s *Snowflake) dropTableInTransaction(wrappedTx *Transaction, table *Table)
 }
 
 //executeInsert execute insert with insertTemplate
-func (s *Snowflake) executeInsert(wrappedTx *Transaction, table *Table, headerWithoutQuotes []string, placeholders string, valueArgs []interface{}) error {
+func (s *Snowflake) executeInsert(wrappedTx *Transaction, table *Table, headerWithoutQuotes []string, rowsPlaceholders []string, valueArgs []interface{}) error {
 	var quotedHeader []string
 	for _, columnName := range headerWithoutQuotes {
 		quotedHeader = append(quotedHeader, reformatValue(columnName))
 	}
 
-	statement := fmt.Sprintf(insertSFTemplate, s.config.Schema, table.Name, strings.Join(quotedHeader, ", "), placeholders)
+	statement := fmt.Sprintf(insertSFTemplate, s.config.Schema, table.Name, strings.Join(quotedHeader, ", "), s.insertRowsClause(table, headerWithoutQuotes, rowsPlaceholders))
 
 	s.queryLogger.LogQueryWithValues(statement, valueArgs)
 
@@ -587,7 +735,16 @@ func (s *Snowflake) toDeleteQuery(conditions *DeleteConditions) (string, []inter
 		values = append(values, condition.Value)
 	}
 
-	return strings.Join(queryConditions, conditions.JoinCondition), values
+	return strings.Join(queryConditions, " "+conditions.JoinCondition+" "), values
+}
+
+//namedStage returns internal stage name with schema or configured external stage name
+func (s *Snowflake) namedStage() string {
+	if s.config.InternalStage.IsEnabled() {
+		return s.config.Schema + "." + s.config.InternalStage.Name
+	}
+
+	return s.config.Stage
 }
 
 //Close underlying sql.DB
@@ -610,6 +767,36 @@ func (s *Snowflake) getCastClause(name string, column typing.SQLColumn) string {
 	return ""
 }
 
+//placeholder returns ? with cast clause or PARSE_JSON(?) for VARIANT columns (values are JSON strings)
+func (s *Snowflake) placeholder(name string, column typing.SQLColumn) string {
+	if s.isVariant(name, column) {
+		return "PARSE_JSON(?)"
+	}
+
+	return "?" + s.getCastClause(name, column)
+}
+
+//isVariant returns true if the column (or overridden column) type is VARIANT
+func (s *Snowflake) isVariant(name string, column typing.SQLColumn) bool {
+	if overriddenSQLType, ok := s.sqlTypes[name]; ok {
+		column = overriddenSQLType
+	}
+
+	return strings.EqualFold(column.DDLType(), SchemaToSnowflake[typing.OBJECT])
+}
+
+//insertRowsClause returns VALUES (...),(...) clause or SELECT ... UNION ALL SELECT ... clause if there are VARIANT columns
+//because Snowflake doesn't support PARSE_JSON in VALUES clause
+func (s *Snowflake) insertRowsClause(table *Table, columns []string, rowsPlaceholders []string) string {
+	for _, column := range columns {
+		if s.isVariant(column, table.Columns[column]) {
+			return "SELECT " + strings.Join(rowsPlaceholders, " UNION ALL SELECT ")
+		}
+	}
+
+	return "VALUES (" + strings.Join(rowsPlaceholders, "),(") + ")"
+}
+
 //columnDDL returns column DDL (column name, mapped sql type)
 func (s *Snowflake) columnDDL(name string, column typing.SQLColumn) string {
 	sqlColumnTypeDDL := column.DDLType()
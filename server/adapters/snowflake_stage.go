@@ -0,0 +1,162 @@
+package adapters
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/jitsucom/jitsu/server/logging"
+)
+
+const (
+	createSFStageTemplate = `CREATE STAGE IF NOT EXISTS %s`
+	putSFStageTemplate    = `PUT 'file://%s' @%s/%s AUTO_COMPRESS = FALSE OVERWRITE = TRUE`
+	removeSFStageTemplate = `REMOVE @%s/%s`
+
+	defaultSnowflakeInternalStage = "jitsu_stage"
+
+	//PUT result statuses
+	sfPutUploaded = "UPLOADED"
+	sfPutSkipped  = "SKIPPED"
+)
+
+//ErrSnowflakePutNotUploaded is returned when PUT statement hasn't uploaded the file (e.g. when Snowflake driver doesn't support PUT)
+var ErrSnowflakePutNotUploaded = errors.New("file hasn't been uploaded into Snowflake internal stage")
+
+//SnowflakeInternalStage is a Stage implementation for Snowflake named internal stage
+//files are uploaded with PUT and deleted with REMOVE statements
+type SnowflakeInternalStage struct {
+	ctx         context.Context
+	dataSource  *sql.DB
+	stageName   string
+	queryLogger *logging.QueryLogger
+}
+
+//NewSnowflakeInternalStage creates internal stage if it doesn't exist and returns configured SnowflakeInternalStage
+//uses Snowflake adapter connection
+func NewSnowflakeInternalStage(s *Snowflake) (*SnowflakeInternalStage, error) {
+	if !s.config.InternalStage.IsEnabled() {
+		return nil, errors.New("Snowflake internal_stage isn't enabled")
+	}
+
+	stage := &SnowflakeInternalStage{
+		ctx:         s.ctx,
+		dataSource:  s.dataSource,
+		stageName:   s.namedStage(),
+		queryLogger: s.queryLogger,
+	}
+
+	query := fmt.Sprintf(createSFStageTemplate, stage.stageName)
+	stage.queryLogger.LogDDL(query)
+	if _, err := stage.dataSource.ExecContext(stage.ctx, query); err != nil {
+		return nil, fmt.Errorf("Error creating Snowflake internal stage [%s]: %v", stage.stageName, err)
+	}
+
+	return stage, nil
+}
+
+//UploadBytes writes bytes into a temporary file and uploads it with PUT statement
+//fileName may contain directories: they are used as the stage path
+func (sis *SnowflakeInternalStage) UploadBytes(fileName string, fileBytes []byte) error {
+	//PUT uses local file name as a target file name
+	dir, err := ioutil.TempDir("", "jitsu_snowflake")
+	if err != nil {
+		return fmt.Errorf("Error creating temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	localFile := filepath.Join(dir, path.Base(fileName))
+	if err := ioutil.WriteFile(localFile, fileBytes, 0644); err != nil {
+		return fmt.Errorf("Error writing temporary file: %v", err)
+	}
+
+	query := fmt.Sprintf(putSFStageTemplate, filepath.ToSlash(localFile), sis.stageName, stageDir(fileName))
+	sis.queryLogger.LogQuery(query)
+
+	rows, err := sis.dataSource.QueryContext(sis.ctx, query)
+	if err != nil {
+		return fmt.Errorf("Error uploading file [%s] into Snowflake internal stage: %v", fileName, err)
+	}
+	defer rows.Close()
+
+	uploaded, err := isPutUploaded(rows)
+	if err != nil {
+		return fmt.Errorf("Error reading PUT result of file [%s]: %v", fileName, err)
+	}
+	if !uploaded {
+		return ErrSnowflakePutNotUploaded
+	}
+
+	return nil
+}
+
+//DeleteObject removes the file from the stage
+func (sis *SnowflakeInternalStage) DeleteObject(key string) error {
+	query := fmt.Sprintf(removeSFStageTemplate, sis.stageName, key)
+	sis.queryLogger.LogQuery(query)
+
+	if _, err := sis.dataSource.ExecContext(sis.ctx, query); err != nil {
+		return fmt.Errorf("Error removing file [%s] from Snowflake internal stage: %v", key, err)
+	}
+
+	return nil
+}
+
+//Close does nothing because the connection is owned by Snowflake adapter
+func (sis *SnowflakeInternalStage) Close() error {
+	return nil
+}
+
+//isPutUploaded returns true if PUT result contains 'status' column with UPLOADED or SKIPPED (the same file already exists) value
+func isPutUploaded(rows *sql.Rows) (bool, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return false, err
+	}
+
+	statusIndex := -1
+	for i, column := range columns {
+		if strings.ToLower(column) == "status" {
+			statusIndex = i
+		}
+	}
+	if statusIndex == -1 {
+		return false, nil
+	}
+
+	uploaded := false
+	for rows.Next() {
+		line := make([]interface{}, len(columns))
+		linePointers := make([]interface{}, len(columns))
+		for i := range columns {
+			linePointers[i] = &line[i]
+		}
+		if err := rows.Scan(linePointers...); err != nil {
+			return false, err
+		}
+
+		status := strings.ToUpper(fmt.Sprint(line[statusIndex]))
+		if status != sfPutUploaded && status != sfPutSkipped {
+			return false, nil
+		}
+		uploaded = true
+	}
+
+	return uploaded, rows.Err()
+}
+
+//stageDir returns directory part of the file name or empty string
+func stageDir(fileName string) string {
+	dir := path.Dir(fileName)
+	if dir == "." {
+		return ""
+	}
+
+	return dir
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/stretchr/testify/require"
 	"math/rand"
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -210,3 +211,47 @@ func createObjectsWithFields(fields []string, num int) []map[string]interface{}
 	}
 	return objects
 }
+
+func TestSnowflakeInsertRowsClause(t *testing.T) {
+	s := &Snowflake{sqlTypes: typing.SQLTypes{"overridden": typing.SQLColumn{Type: "variant", ColumnType: "variant"}}}
+	tests := []struct {
+		name     string
+		columns  Columns
+		expected string
+	}{
+		{
+			"Plain columns",
+			Columns{"id": typing.SQLColumn{Type: "bigint"}, "name": typing.SQLColumn{Type: "text"}},
+			"VALUES (?,?),(?,?)",
+		},
+		{
+			"Variant column",
+			Columns{"id": typing.SQLColumn{Type: "bigint"}, "name": typing.SQLColumn{Type: "VARIANT"}},
+			"SELECT ?,PARSE_JSON(?) UNION ALL SELECT ?,PARSE_JSON(?)",
+		},
+		{
+			"Overridden variant column",
+			Columns{"id": typing.SQLColumn{Type: "bigint"}, "overridden": typing.SQLColumn{Type: "text"}},
+			"SELECT ?,PARSE_JSON(?) UNION ALL SELECT ?,PARSE_JSON(?)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table := &Table{Name: "events", Columns: tt.columns}
+			columns := []string{"id"}
+			for name := range tt.columns {
+				if name != "id" {
+					columns = append(columns, name)
+				}
+			}
+
+			var placeholders []string
+			for _, column := range columns {
+				placeholders = append(placeholders, s.placeholder(column, table.Columns[column]))
+			}
+			row := strings.Join(placeholders, ",")
+
+			require.Equal(t, tt.expected, s.insertRowsClause(table, columns, []string{row, row}))
+		})
+	}
+}
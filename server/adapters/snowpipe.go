@@ -0,0 +1,472 @@
+package adapters
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/jitsucom/jitsu/server/appstatus"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/meta"
+	"github.com/jitsucom/jitsu/server/safego"
+	"github.com/jitsucom/jitsu/server/uuid"
+)
+
+const (
+	snowpipeInsertFilesURLTemplate  = "https://%s.snowflakecomputing.com/v1/data/pipes/%s/insertFiles?requestId=%s"
+	snowpipeInsertReportURLTemplate = "https://%s.snowflakecomputing.com/v1/data/pipes/%s/insertReport?requestId=%s"
+	createSFPipeTemplate            = `CREATE PIPE IF NOT EXISTS %s.%s AS COPY INTO %s.%s FROM @%s FILE_FORMAT = (TYPE = 'JSON') MATCH_BY_COLUMN_NAME = CASE_INSENSITIVE`
+
+	snowpipeTokenLifetime = time.Hour
+	//token is reissued before expiration
+	snowpipeTokenRefresh = 50 * time.Minute
+
+	defaultSnowpipeReportIntervalSec = 60
+	defaultSnowpipeRetryAfterSec     = 600
+
+	//insertReport statuses
+	snowpipeLoaded          = "LOADED"
+	snowpipeLoadFailed      = "LOAD_FAILED"
+	snowpipePartiallyLoaded = "PARTIALLY_LOADED"
+)
+
+//SnowpipeConfig dto for Snowpipe REST API configuration
+//Snowpipe REST API requires key pair authentication of the Snowflake user
+type SnowpipeConfig struct {
+	Enabled bool `mapstructure:"enabled" json:"enabled,omitempty" yaml:"enabled,omitempty"`
+	//PrivateKey is PEM encoded (PKCS#1 or unencrypted PKCS#8) RSA private key
+	PrivateKey string `mapstructure:"private_key" json:"private_key,omitempty" yaml:"private_key,omitempty"`
+	//ReportIntervalSec is an interval of checking load results of submitted files
+	ReportIntervalSec int `mapstructure:"report_interval_sec" json:"report_interval_sec,omitempty" yaml:"report_interval_sec,omitempty"`
+	//RetryAfterSec is a period after that a file without load result is submitted again
+	RetryAfterSec int `mapstructure:"retry_after_sec" json:"retry_after_sec,omitempty" yaml:"retry_after_sec,omitempty"`
+}
+
+//IsEnabled returns true if Snowpipe is configured and enabled
+func (sc *SnowpipeConfig) IsEnabled() bool {
+	return sc != nil && sc.Enabled
+}
+
+//Validate returns err if private key isn't provided and sets default values
+func (sc *SnowpipeConfig) Validate() error {
+	if sc.PrivateKey == "" {
+		return errors.New("Snowpipe private_key is required parameter")
+	}
+	if sc.ReportIntervalSec <= 0 {
+		sc.ReportIntervalSec = defaultSnowpipeReportIntervalSec
+	}
+	if sc.RetryAfterSec <= 0 {
+		sc.RetryAfterSec = defaultSnowpipeRetryAfterSec
+	}
+
+	return nil
+}
+
+//SnowpipeInsertReport is a dto for insertReport response
+type SnowpipeInsertReport struct {
+	NextBeginMark string                `json:"nextBeginMark"`
+	Files         []*SnowpipeFileReport `json:"files"`
+}
+
+//SnowpipeFileReport is a dto for a file load result
+type SnowpipeFileReport struct {
+	Path       string `json:"path"`
+	Status     string `json:"status"`
+	FirstError string `json:"firstError"`
+}
+
+//Snowpipe submits staged files into pipes with Snowpipe REST API and tracks load results:
+//loaded files are deleted from the stage, files without load result are submitted again (Snowpipe doesn't load the same file twice),
+//failed files and files without load result after load_retries submits are kept in the stage for manual reloading (system error is sent).
+//Submitted files are persisted in meta storage and tracking is resumed after restart
+type Snowpipe struct {
+	destinationID string
+	config        *SnowflakeConfig
+	stage         Stage
+	metaStorage   meta.Storage
+	client        *http.Client
+	privateKey    *rsa.PrivateKey
+	//account and user in JWT format
+	qualifiedUser string
+	fingerprint   string
+
+	mutex       *sync.Mutex
+	token       string
+	tokenIssued time.Time
+	//pending is a pipe - file path - submitted file
+	pending map[string]map[string]*meta.SnowpipeFile
+	//beginMarks is a pipe - insertReport begin mark
+	beginMarks map[string]string
+	closed     bool
+}
+
+//NewSnowpipe returns configured Snowpipe, restores pending files from meta storage and runs goroutine for checking load results
+func NewSnowpipe(destinationID string, config *SnowflakeConfig, stage Stage, metaStorage meta.Storage) (*Snowpipe, error) {
+	privateKey, err := parseRSAPrivateKey(config.Snowpipe.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing Snowpipe private_key: %v", err)
+	}
+
+	fingerprint, err := publicKeyFingerprint(privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	//account locator without region and cloud
+	account := strings.ToUpper(strings.Split(config.Account, ".")[0])
+	sp := &Snowpipe{
+		destinationID: destinationID,
+		config:        config,
+		stage:         stage,
+		metaStorage:   metaStorage,
+		client:        &http.Client{Timeout: time.Minute},
+		privateKey:    privateKey,
+		qualifiedUser: account + "." + strings.ToUpper(config.Username),
+		fingerprint:   fingerprint,
+		mutex:         &sync.Mutex{},
+		pending:       map[string]map[string]*meta.SnowpipeFile{},
+		beginMarks:    map[string]string{},
+	}
+
+	files, err := metaStorage.GetSnowpipeFiles(destinationID)
+	if err != nil {
+		return nil, fmt.Errorf("Error getting Snowpipe pending files from meta storage: %v", err)
+	}
+	for _, file := range files {
+		sp.addPending(file)
+	}
+	if len(files) > 0 {
+		logging.Infof("[%s] Snowpipe load results of %d files will be checked", destinationID, len(files))
+	}
+
+	sp.start()
+
+	return sp, nil
+}
+
+//Submit sends the file path (relative to the stage) to the pipe and tracks its load result
+//the file is saved into meta storage before submitting so its load result is checked even after restart
+func (sp *Snowpipe) Submit(pipe, filePath string) error {
+	file := &meta.SnowpipeFile{Pipe: pipe, Path: filePath, SubmittedAt: time.Now().UTC(), Attempts: 1}
+	if err := sp.metaStorage.SaveSnowpipeFile(sp.destinationID, file); err != nil {
+		return fmt.Errorf("Error saving Snowpipe file into meta storage: %v", err)
+	}
+
+	if err := sp.insertFiles(pipe, []string{filePath}); err != nil {
+		if deleteErr := sp.metaStorage.DeleteSnowpipeFile(sp.destinationID, file); deleteErr != nil {
+			logging.Errorf("[%s] Snowpipe file [%s] wasn't deleted from meta storage: %v", sp.destinationID, filePath, deleteErr)
+		}
+		return err
+	}
+
+	sp.mutex.Lock()
+	sp.addPending(file)
+	sp.mutex.Unlock()
+
+	return nil
+}
+
+//addPending adds the file into pending files of its pipe (must be called under the mutex or before start)
+func (sp *Snowpipe) addPending(file *meta.SnowpipeFile) {
+	files, ok := sp.pending[file.Pipe]
+	if !ok {
+		files = map[string]*meta.SnowpipeFile{}
+		sp.pending[file.Pipe] = files
+	}
+	files[file.Path] = file
+}
+
+//Close stops checking load results
+//pending files are kept in meta storage and checked after restart
+func (sp *Snowpipe) Close() error {
+	sp.mutex.Lock()
+	defer sp.mutex.Unlock()
+
+	sp.closed = true
+	pendingCount := 0
+	for _, files := range sp.pending {
+		pendingCount += len(files)
+	}
+	if pendingCount > 0 {
+		logging.Infof("[%s] Snowpipe load results of %d files haven't been received. They will be checked after restart", sp.destinationID, pendingCount)
+	}
+
+	return nil
+}
+
+//start runs goroutine which checks load results every report_interval_sec
+func (sp *Snowpipe) start() {
+	safego.RunWithRestart(func() {
+		for {
+			if appstatus.Instance.Idle.Load() {
+				break
+			}
+
+			time.Sleep(time.Duration(sp.config.Snowpipe.ReportIntervalSec) * time.Second)
+
+			sp.mutex.Lock()
+			closed := sp.closed
+			pipes := make([]string, 0, len(sp.pending))
+			for pipe, files := range sp.pending {
+				if len(files) > 0 {
+					pipes = append(pipes, pipe)
+				}
+			}
+			sp.mutex.Unlock()
+
+			if closed {
+				break
+			}
+
+			for _, pipe := range pipes {
+				if err := sp.checkPipe(pipe); err != nil {
+					logging.Errorf("Error checking Snowpipe [%s] load results: %v", pipe, err)
+				}
+			}
+		}
+	})
+}
+
+//checkPipe requests insertReport, deletes loaded files from the stage and submits again files without load result
+//failed files and files without load result after load_retries submits are kept in the stage and aren't tracked anymore
+func (sp *Snowpipe) checkPipe(pipe string) error {
+	sp.mutex.Lock()
+	beginMark := sp.beginMarks[pipe]
+	sp.mutex.Unlock()
+
+	report, err := sp.insertReport(pipe, beginMark)
+	if err != nil {
+		return err
+	}
+
+	sp.mutex.Lock()
+	sp.beginMarks[pipe] = report.NextBeginMark
+	files := sp.pending[pipe]
+	var loaded, finished, retry []*meta.SnowpipeFile
+	for _, fileReport := range report.Files {
+		file, ok := files[fileReport.Path]
+		if !ok {
+			continue
+		}
+
+		switch fileReport.Status {
+		case snowpipeLoaded:
+			loaded = append(loaded, file)
+			delete(files, fileReport.Path)
+		case snowpipeLoadFailed, snowpipePartiallyLoaded:
+			logging.SystemErrorf("[%s] Snowpipe [%s] file [%s] %s: %s. File is kept in the stage for manual reloading", sp.destinationID, pipe, fileReport.Path, fileReport.Status, fileReport.FirstError)
+			finished = append(finished, file)
+			delete(files, fileReport.Path)
+		}
+	}
+
+	retryAfter := time.Duration(sp.config.Snowpipe.RetryAfterSec) * time.Second
+	for filePath, file := range files {
+		if time.Since(file.SubmittedAt) < retryAfter {
+			continue
+		}
+		if file.Attempts >= sp.config.LoadRetries {
+			logging.SystemErrorf("[%s] Snowpipe [%s] load result of file [%s] hasn't been received after %d attempts. File is kept in the stage for manual reloading", sp.destinationID, pipe, filePath, file.Attempts)
+			finished = append(finished, file)
+			delete(files, filePath)
+			continue
+		}
+
+		file.Attempts++
+		file.SubmittedAt = time.Now().UTC()
+		retry = append(retry, file)
+	}
+	sp.mutex.Unlock()
+
+	for _, file := range loaded {
+		if err := sp.stage.DeleteObject(file.Path); err != nil {
+			logging.Errorf("[%s] Snowpipe [%s] loaded file [%s] wasn't deleted from stage: %v", sp.destinationID, pipe, file.Path, err)
+		}
+	}
+
+	for _, file := range append(loaded, finished...) {
+		if err := sp.metaStorage.DeleteSnowpipeFile(sp.destinationID, file); err != nil {
+			logging.Errorf("[%s] Snowpipe [%s] file [%s] wasn't deleted from meta storage: %v", sp.destinationID, pipe, file.Path, err)
+		}
+	}
+
+	if len(retry) > 0 {
+		logging.Warnf("[%s] Snowpipe [%s] load results of %d files haven't been received. Files will be submitted again", sp.destinationID, pipe, len(retry))
+		filePaths := make([]string, 0, len(retry))
+		for _, file := range retry {
+			if err := sp.metaStorage.SaveSnowpipeFile(sp.destinationID, file); err != nil {
+				logging.Errorf("[%s] Snowpipe [%s] file [%s] wasn't updated in meta storage: %v", sp.destinationID, pipe, file.Path, err)
+			}
+			filePaths = append(filePaths, file.Path)
+		}
+		return sp.insertFiles(pipe, filePaths)
+	}
+
+	return nil
+}
+
+//insertFiles sends insertFiles request
+func (sp *Snowpipe) insertFiles(pipe string, filePaths []string) error {
+	type file struct {
+		Path string `json:"path"`
+	}
+	files := make([]file, 0, len(filePaths))
+	for _, filePath := range filePaths {
+		files = append(files, file{Path: filePath})
+	}
+	body, err := json.Marshal(map[string]interface{}{"files": files})
+	if err != nil {
+		return err
+	}
+
+	_, err = sp.doRequest(http.MethodPost, fmt.Sprintf(snowpipeInsertFilesURLTemplate, sp.config.Account, sp.pipePath(pipe), uuid.New()), body)
+	if err != nil {
+		return fmt.Errorf("Error submitting files into Snowpipe [%s]: %v", pipe, err)
+	}
+
+	return nil
+}
+
+//insertReport sends insertReport request
+func (sp *Snowpipe) insertReport(pipe, beginMark string) (*SnowpipeInsertReport, error) {
+	reportURL := fmt.Sprintf(snowpipeInsertReportURLTemplate, sp.config.Account, sp.pipePath(pipe), uuid.New())
+	if beginMark != "" {
+		reportURL += "&beginMark=" + url.QueryEscape(beginMark)
+	}
+
+	body, err := sp.doRequest(http.MethodGet, reportURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &SnowpipeInsertReport{}
+	if err := json.Unmarshal(body, report); err != nil {
+		return nil, fmt.Errorf("Error parsing insertReport response [%s]: %v", string(body), err)
+	}
+
+	return report, nil
+}
+
+func (sp *Snowpipe) doRequest(method, requestURL string, body []byte) ([]byte, error) {
+	token, err := sp.getToken()
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(method, requestURL, bytes.NewBuffer(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := sp.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	responseBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP code = %d, response: %s", resp.StatusCode, string(responseBody))
+	}
+
+	return responseBody, nil
+}
+
+//getToken returns cached or new JWT signed with the private key
+func (sp *Snowpipe) getToken() (string, error) {
+	sp.mutex.Lock()
+	defer sp.mutex.Unlock()
+
+	if sp.token != "" && time.Since(sp.tokenIssued) < snowpipeTokenRefresh {
+		return sp.token, nil
+	}
+
+	now := time.Now()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.StandardClaims{
+		Issuer:    sp.qualifiedUser + "." + sp.fingerprint,
+		Subject:   sp.qualifiedUser,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(snowpipeTokenLifetime).Unix(),
+	}).SignedString(sp.privateKey)
+	if err != nil {
+		return "", fmt.Errorf("Error signing Snowpipe JWT: %v", err)
+	}
+
+	sp.token = token
+	sp.tokenIssued = now
+
+	return token, nil
+}
+
+//pipePath returns fully qualified pipe name: db.schema.pipe
+func (sp *Snowpipe) pipePath(pipe string) string {
+	return reformatToParam(sp.config.Db) + "." + reformatToParam(sp.config.Schema) + "." + reformatToParam(pipe)
+}
+
+//CreatePipe creates pipe for loading JSON files from the stage into the table if it doesn't exist
+//columns are matched by names, so the pipe doesn't depend on the table schema changes
+func (s *Snowflake) CreatePipe(pipe, tableName string) error {
+	query := fmt.Sprintf(createSFPipeTemplate, s.config.Schema, reformatValue(pipe), s.config.Schema, reformatValue(tableName), s.namedStage())
+	s.queryLogger.LogDDL(query)
+
+	if _, err := s.dataSource.ExecContext(s.ctx, query); err != nil {
+		return fmt.Errorf("Error creating pipe [%s] for table [%s]: %v", pipe, tableName, err)
+	}
+
+	return nil
+}
+
+//parseRSAPrivateKey parses PEM encoded PKCS#1 or PKCS#8 RSA private key
+func parseRSAPrivateKey(privateKey string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(privateKey))
+	if block == nil {
+		return nil, errors.New("PEM block wasn't found")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key isn't RSA key")
+	}
+
+	return rsaKey, nil
+}
+
+//publicKeyFingerprint returns SHA256:base64(sha256(DER public key)) as Snowflake expects in JWT issuer
+func publicKeyFingerprint(privateKey *rsa.PrivateKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	if err != nil {
+		return "", fmt.Errorf("Error marshaling public key: %v", err)
+	}
+
+	hash := sha256.Sum256(der)
+	return "SHA256:" + base64.StdEncoding.EncodeToString(hash[:]), nil
+}
@@ -0,0 +1,239 @@
+package adapters
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/jitsucom/jitsu/server/meta"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnowflakeConfigValidateLoading(t *testing.T) {
+	tests := []struct {
+		name          string
+		input         *SnowflakeConfig
+		expectedStage string
+		expectedErr   string
+	}{
+		{
+			"internal stage with default name",
+			&SnowflakeConfig{InternalStage: &SnowflakeInternalStageConfig{Enabled: true}},
+			"public.jitsu_stage",
+			"",
+		},
+		{
+			"internal stage with custom name",
+			&SnowflakeConfig{InternalStage: &SnowflakeInternalStageConfig{Enabled: true, Name: "my_stage"}},
+			"public.my_stage",
+			"",
+		},
+		{
+			"snowpipe without private key",
+			&SnowflakeConfig{Stage: "gcs_stage", Snowpipe: &SnowpipeConfig{Enabled: true}},
+			"",
+			"Snowpipe private_key is required parameter",
+		},
+		{
+			"snowpipe without stage",
+			&SnowflakeConfig{Snowpipe: &SnowpipeConfig{Enabled: true, PrivateKey: "key"}},
+			"",
+			"Snowflake stage or enabled internal_stage is required for Snowpipe",
+		},
+		{
+			"snowpipe with external stage",
+			&SnowflakeConfig{Stage: "gcs_stage", Snowpipe: &SnowpipeConfig{Enabled: true, PrivateKey: "key"}},
+			"gcs_stage",
+			"",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.input.Account = "account"
+			tt.input.Db = "db"
+			tt.input.Username = "user"
+			tt.input.Warehouse = "warehouse"
+			tt.input.Schema = "public"
+
+			err := tt.input.Validate()
+			if tt.expectedErr != "" {
+				require.EqualError(t, err, tt.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, defaultSnowflakeLoadRetries, tt.input.LoadRetries)
+			require.Equal(t, tt.expectedStage, (&Snowflake{config: tt.input}).namedStage())
+		})
+	}
+}
+
+func TestSnowpipeToken(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	privateKey := string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8}))
+
+	config := &SnowflakeConfig{Account: "xy12345.us-east-2.aws", Username: "jitsu", Snowpipe: &SnowpipeConfig{Enabled: true, PrivateKey: privateKey}}
+	require.NoError(t, config.Snowpipe.Validate())
+
+	sp, err := NewSnowpipe("test", config, nil, &meta.Dummy{})
+	require.NoError(t, err)
+	defer sp.Close()
+
+	token, err := sp.getToken()
+	require.NoError(t, err)
+
+	claims := &jwt.StandardClaims{}
+	_, err = jwt.ParseWithClaims(token, claims, func(*jwt.Token) (interface{}, error) {
+		return &key.PublicKey, nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, "XY12345.JITSU", claims.Subject)
+	require.True(t, strings.HasPrefix(claims.Issuer, "XY12345.JITSU.SHA256:"))
+
+	cached, err := sp.getToken()
+	require.NoError(t, err)
+	require.Equal(t, token, cached)
+
+	_, err = parseRSAPrivateKey("not a key")
+	require.Error(t, err)
+}
+
+//snowpipeFilesStorageMock is an in-memory meta.Storage which supports only Snowpipe files methods
+type snowpipeFilesStorageMock struct {
+	meta.Storage
+	mutex sync.Mutex
+	files map[string]meta.SnowpipeFile
+}
+
+func (sfsm *snowpipeFilesStorageMock) SaveSnowpipeFile(destinationID string, file *meta.SnowpipeFile) error {
+	sfsm.mutex.Lock()
+	defer sfsm.mutex.Unlock()
+	sfsm.files[file.Key()] = *file
+	return nil
+}
+
+func (sfsm *snowpipeFilesStorageMock) GetSnowpipeFiles(destinationID string) ([]*meta.SnowpipeFile, error) {
+	sfsm.mutex.Lock()
+	defer sfsm.mutex.Unlock()
+	files := make([]*meta.SnowpipeFile, 0, len(sfsm.files))
+	for _, file := range sfsm.files {
+		file := file
+		files = append(files, &file)
+	}
+	return files, nil
+}
+
+func (sfsm *snowpipeFilesStorageMock) DeleteSnowpipeFile(destinationID string, file *meta.SnowpipeFile) error {
+	sfsm.mutex.Lock()
+	defer sfsm.mutex.Unlock()
+	delete(sfsm.files, file.Key())
+	return nil
+}
+
+func (sfsm *snowpipeFilesStorageMock) paths() []string {
+	sfsm.mutex.Lock()
+	defer sfsm.mutex.Unlock()
+	paths := []string{}
+	for _, file := range sfsm.files {
+		paths = append(paths, file.Path)
+	}
+	return paths
+}
+
+type stageMock struct {
+	deleted []string
+}
+
+func (sm *stageMock) UploadBytes(fileName string, fileBytes []byte) error { return nil }
+func (sm *stageMock) DeleteObject(key string) error {
+	sm.deleted = append(sm.deleted, key)
+	return nil
+}
+func (sm *stageMock) Close() error { return nil }
+
+//snowpipeTransportMock responds to insertReport requests with the report and to insertFiles requests with ok or error
+type snowpipeTransportMock struct {
+	report      string
+	insertErr   error
+	insertFiles []string
+}
+
+func (stm *snowpipeTransportMock) RoundTrip(req *http.Request) (*http.Response, error) {
+	body := "{}"
+	if strings.Contains(req.URL.Path, "/insertReport") {
+		body = stm.report
+	} else {
+		if stm.insertErr != nil {
+			return nil, stm.insertErr
+		}
+		b, _ := ioutil.ReadAll(req.Body)
+		stm.insertFiles = append(stm.insertFiles, string(b))
+	}
+	return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader(body)), Header: http.Header{}}, nil
+}
+
+func TestSnowpipePendingFiles(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	privateKey := string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8}))
+
+	config := &SnowflakeConfig{Account: "xy12345", Username: "jitsu", Db: "db", Schema: "public", LoadRetries: 2,
+		Snowpipe: &SnowpipeConfig{Enabled: true, PrivateKey: privateKey, ReportIntervalSec: 3600, RetryAfterSec: 600}}
+	require.NoError(t, config.Snowpipe.Validate())
+
+	old := time.Now().Add(-time.Hour)
+	storage := &snowpipeFilesStorageMock{files: map[string]meta.SnowpipeFile{}}
+	for _, file := range []*meta.SnowpipeFile{
+		{Pipe: "pipe", Path: "events/loaded.json", SubmittedAt: time.Now(), Attempts: 1},
+		{Pipe: "pipe", Path: "events/failed.json", SubmittedAt: time.Now(), Attempts: 1},
+		{Pipe: "pipe", Path: "events/waiting.json", SubmittedAt: time.Now(), Attempts: 1},
+		{Pipe: "pipe", Path: "events/retry.json", SubmittedAt: old, Attempts: 1},
+		{Pipe: "pipe", Path: "events/exhausted.json", SubmittedAt: old, Attempts: 2},
+	} {
+		require.NoError(t, storage.SaveSnowpipeFile("test", file))
+	}
+
+	stage := &stageMock{}
+	//pending files are restored from meta storage
+	sp, err := NewSnowpipe("test", config, stage, storage)
+	require.NoError(t, err)
+	defer sp.Close()
+	require.Len(t, sp.pending["pipe"], 5)
+
+	transport := &snowpipeTransportMock{report: `{"nextBeginMark": "1", "files": [
+		{"path": "events/loaded.json", "status": "LOADED"},
+		{"path": "events/failed.json", "status": "LOAD_FAILED", "firstError": "error"}]}`}
+	sp.client = &http.Client{Transport: transport}
+
+	require.NoError(t, sp.checkPipe("pipe"))
+	//only loaded file is deleted from the stage: failed and exhausted ones are kept for manual reloading
+	require.Equal(t, []string{"events/loaded.json"}, stage.deleted)
+	require.ElementsMatch(t, []string{"events/waiting.json", "events/retry.json"}, storage.paths())
+	require.Equal(t, []string{`{"files":[{"path":"events/retry.json"}]}`}, transport.insertFiles)
+	require.Equal(t, 2, storage.files["pipe/events/retry.json"].Attempts)
+
+	//submitted file is persisted
+	require.NoError(t, sp.Submit("pipe", "events/new.json"))
+	require.Contains(t, storage.paths(), "events/new.json")
+
+	//file which hasn't been submitted isn't tracked
+	transport.insertErr = errors.New("connection refused")
+	require.Error(t, sp.Submit("pipe", "events/rejected.json"))
+	require.NotContains(t, storage.paths(), "events/rejected.json")
+	require.NotContains(t, sp.pending["pipe"], "events/rejected.json")
+}
@@ -0,0 +1,528 @@
+package adapters
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/typing"
+	"github.com/jitsucom/jitsu/server/uuid"
+	_ "github.com/mattn/go-sqlite3"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	sqliteTableSchemaQuery      = `SELECT name, type, pk FROM pragma_table_info(?)`
+	sqliteTableNamesQuery       = `SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%'`
+	sqliteCreateTableTemplate   = `CREATE TABLE "%s" (%s)`
+	sqliteInsertTemplate        = `INSERT INTO "%s" (%s) VALUES (%s)`
+	sqliteUpsertTemplate        = `INSERT INTO "%s" (%s) VALUES (%s) ON CONFLICT (%s) DO UPDATE SET %s`
+	sqliteCopyTableTemplate     = `INSERT OR REPLACE INTO "%s" (%s) SELECT %s FROM "%s"`
+	sqliteRenameTableTemplate   = `ALTER TABLE "%s" RENAME TO "%s"`
+	sqliteDeleteQueryTemplate   = `DELETE FROM "%s" WHERE %s`
+	sqliteCountQueryTemplate    = `SELECT count(*) FROM "%s" WHERE %s`
+	sqliteAddColumnTemplate     = `ALTER TABLE "%s" ADD COLUMN %s`
+	sqliteDropTableTemplate     = `DROP TABLE "%s"`
+	sqliteTruncateTableTemplate = `DELETE FROM "%s"`
+	sqliteDefaultBusyTimeoutMs  = 5000
+)
+
+var (
+	SchemaToSQLite = map[typing.DataType]string{
+		typing.STRING:    "TEXT",
+		typing.INT64:     "INTEGER",
+		typing.FLOAT64:   "REAL",
+		typing.TIMESTAMP: "TIMESTAMP",
+		typing.BOOL:      "BOOLEAN",
+		typing.UNKNOWN:   "TEXT",
+	}
+)
+
+//SQLiteConfig dto for embedded SQLite destination
+type SQLiteConfig struct {
+	//Path is a database file path or :memory:
+	Path string `mapstructure:"path" json:"path,omitempty" yaml:"path,omitempty"`
+}
+
+//Validate returns err if invalid
+func (sc *SQLiteConfig) Validate() error {
+	if sc == nil {
+		return errors.New("SQLite config is required")
+	}
+
+	if sc.Path == "" {
+		return errors.New("path is required parameter")
+	}
+
+	return nil
+}
+
+//SQLite is adapter for creating, patching (schema or table), inserting data to embedded SQLite database file
+//doesn't require any server: it is useful for local analytics and as a test destination
+type SQLite struct {
+	ctx         context.Context
+	config      *SQLiteConfig
+	dataSource  *sql.DB
+	queryLogger *logging.QueryLogger
+
+	sqlTypes typing.SQLTypes
+}
+
+//NewSQLite returns configured SQLite adapter instance
+//creates database file (and its directory) if doesn't exist
+func NewSQLite(ctx context.Context, config *SQLiteConfig, queryLogger *logging.QueryLogger, sqlTypes typing.SQLTypes) (*SQLite, error) {
+	if config.Path != ":memory:" {
+		if dir := filepath.Dir(config.Path); dir != "" {
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return nil, fmt.Errorf("Error creating SQLite database directory [%s]: %v", dir, err)
+			}
+		}
+	}
+
+	dataSource, err := sql.Open("sqlite3", fmt.Sprintf("file:%s?_busy_timeout=%d&_journal_mode=WAL", config.Path, sqliteDefaultBusyTimeoutMs))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := dataSource.Ping(); err != nil {
+		dataSource.Close()
+		return nil, err
+	}
+
+	//SQLite supports only one writer at a time
+	//one connection also keeps :memory: database alive
+	dataSource.SetMaxOpenConns(1)
+	dataSource.SetMaxIdleConns(1)
+
+	return &SQLite{ctx: ctx, config: config, dataSource: dataSource, queryLogger: queryLogger, sqlTypes: reformatMappings(sqlTypes, SchemaToSQLite)}, nil
+}
+
+//Type returns SQLite type
+func (SQLite) Type() string {
+	return "SQLite"
+}
+
+//OpenTx opens underline sql transaction and return wrapped instance
+func (s *SQLite) OpenTx() (*Transaction, error) {
+	tx, err := s.dataSource.BeginTx(s.ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Transaction{tx: tx, dbType: s.Type()}, nil
+}
+
+//CreateTable creates database table with name,columns provided in Table representation
+func (s *SQLite) CreateTable(table *Table) error {
+	wrappedTx, err := s.OpenTx()
+	if err != nil {
+		return err
+	}
+
+	if err := s.createTableInTransaction(wrappedTx, table); err != nil {
+		wrappedTx.Rollback()
+		return err
+	}
+
+	return wrappedTx.DirectCommit()
+}
+
+//PatchTableSchema adds new columns(from provided Table) to existing table
+//SQLite doesn't support primary key constraint altering: the table is rebuilt if primary keys are changed
+func (s *SQLite) PatchTableSchema(patchTable *Table) error {
+	wrappedTx, err := s.OpenTx()
+	if err != nil {
+		return err
+	}
+
+	if err := s.patchTableSchemaInTransaction(wrappedTx, patchTable); err != nil {
+		wrappedTx.Rollback()
+		return err
+	}
+
+	return wrappedTx.DirectCommit()
+}
+
+//GetTableSchema returns table (name,columns with name and types and primary keys) representation wrapped in Table struct
+func (s *SQLite) GetTableSchema(tableName string) (*Table, error) {
+	rows, err := s.dataSource.QueryContext(s.ctx, sqliteTableSchemaQuery, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("Error querying table [%s] schema: %v", tableName, err)
+	}
+
+	return s.scanTable(tableName, rows)
+}
+
+//Insert provided object in SQLite
+//uses upsert (merge on conflict) if primary_keys are configured
+func (s *SQLite) Insert(eventContext *EventContext) error {
+	header := make([]string, 0, len(eventContext.ProcessedEvent))
+	for name := range eventContext.ProcessedEvent {
+		header = append(header, name)
+	}
+	sort.Strings(header)
+
+	statement := s.insertStatement(eventContext.Table, header)
+	values := make([]interface{}, len(header))
+	for i, name := range header {
+		values[i] = nestedToJSON(eventContext.ProcessedEvent[name])
+	}
+
+	s.queryLogger.LogQueryWithValues(statement, values)
+	if _, err := s.dataSource.ExecContext(s.ctx, statement, values...); err != nil {
+		return fmt.Errorf("Error inserting in %s table with statement: %s values: %v: %v", eventContext.Table.Name, statement, values, err)
+	}
+
+	return nil
+}
+
+//BulkInsert runs bulkStoreInTransaction
+func (s *SQLite) BulkInsert(table *Table, objects []map[string]interface{}) error {
+	wrappedTx, err := s.OpenTx()
+	if err != nil {
+		return err
+	}
+
+	if err := s.bulkStoreInTransaction(wrappedTx, table, objects); err != nil {
+		wrappedTx.Rollback()
+		return err
+	}
+
+	return wrappedTx.DirectCommit()
+}
+
+//BulkUpdate deletes with deleteConditions and runs bulkStoreInTransaction
+func (s *SQLite) BulkUpdate(table *Table, objects []map[string]interface{}, deleteConditions *DeleteConditions) error {
+	wrappedTx, err := s.OpenTx()
+	if err != nil {
+		return err
+	}
+
+	if !deleteConditions.IsEmpty() {
+		if err := s.deleteInTransaction(wrappedTx, table, deleteConditions); err != nil {
+			wrappedTx.Rollback()
+			return err
+		}
+	}
+
+	if err := s.bulkStoreInTransaction(wrappedTx, table, objects); err != nil {
+		wrappedTx.Rollback()
+		return err
+	}
+
+	return wrappedTx.DirectCommit()
+}
+
+//Delete deletes objects with deleteConditions in transaction
+func (s *SQLite) Delete(table *Table, deleteConditions *DeleteConditions) error {
+	wrappedTx, err := s.OpenTx()
+	if err != nil {
+		return err
+	}
+
+	if err := s.deleteInTransaction(wrappedTx, table, deleteConditions); err != nil {
+		wrappedTx.Rollback()
+		return err
+	}
+
+	return wrappedTx.DirectCommit()
+}
+
+//DropTable drops table in transaction
+func (s *SQLite) DropTable(table *Table) error {
+	wrappedTx, err := s.OpenTx()
+	if err != nil {
+		return err
+	}
+
+	if err := s.dropTableInTransaction(wrappedTx, table.Name); err != nil {
+		wrappedTx.Rollback()
+		return err
+	}
+
+	return wrappedTx.DirectCommit()
+}
+
+//Truncate deletes all records in tableName table
+//SQLite doesn't have TRUNCATE statement: DELETE without WHERE clause is optimized in the same way
+func (s *SQLite) Truncate(tableName string) error {
+	sqlParams := SqlParams{
+		dataSource:  s.dataSource,
+		queryLogger: s.queryLogger,
+		ctx:         s.ctx,
+	}
+	statement := fmt.Sprintf(sqliteTruncateTableTemplate, tableName)
+	return sqlParams.commonTruncate(tableName, statement)
+}
+
+//GetTableNames returns names of all tables in the database file
+func (s *SQLite) GetTableNames() ([]string, error) {
+	sqlParams := SqlParams{
+		dataSource:  s.dataSource,
+		queryLogger: s.queryLogger,
+		ctx:         s.ctx,
+	}
+	return sqlParams.commonGetTableNames(sqliteTableNamesQuery)
+}
+
+//DeleteOlderThan deletes (or counts if dryRun) rows which field value is before the time
+func (s *SQLite) DeleteOlderThan(table *Table, field string, before time.Time, dryRun bool) (int64, error) {
+	sqlParams := SqlParams{
+		dataSource:  s.dataSource,
+		queryLogger: s.queryLogger,
+		ctx:         s.ctx,
+	}
+	condition, values := s.toDeleteQuery(olderThanConditions(field, before))
+	return sqlParams.commonDeleteOlderThan(table.Name,
+		fmt.Sprintf(sqliteCountQueryTemplate, table.Name, condition),
+		fmt.Sprintf(sqliteDeleteQueryTemplate, table.Name, condition),
+		values, dryRun)
+}
+
+//Close underlying sql.DB
+func (s *SQLite) Close() error {
+	return s.dataSource.Close()
+}
+
+//scanTable returns Table from pragma_table_info rows
+func (s *SQLite) scanTable(tableName string, rows *sql.Rows) (*Table, error) {
+	table := &Table{Name: tableName, Columns: map[string]typing.SQLColumn{}, PKFields: map[string]bool{}}
+
+	defer rows.Close()
+	for rows.Next() {
+		var columnName, columnType string
+		var pk int
+		if err := rows.Scan(&columnName, &columnType, &pk); err != nil {
+			return nil, fmt.Errorf("Error scanning result: %v", err)
+		}
+
+		table.Columns[columnName] = typing.SQLColumn{Type: columnType}
+		//pk is a 1-based index of the column in the primary key
+		if pk > 0 {
+			table.PKFields[columnName] = true
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("Last rows.Err: %v", err)
+	}
+
+	return table, nil
+}
+
+//bulkStoreInTransaction inserts objects one by one with prepared statement
+//objects with the same primary keys values are merged: the last one wins
+func (s *SQLite) bulkStoreInTransaction(wrappedTx *Transaction, table *Table, objects []map[string]interface{}) error {
+	header := make([]string, 0, len(table.Columns))
+	for name := range table.Columns {
+		header = append(header, name)
+	}
+	sort.Strings(header)
+
+	statement := s.insertStatement(table, header)
+	s.queryLogger.LogQuery(statement)
+
+	stmt, err := wrappedTx.tx.PrepareContext(s.ctx, statement)
+	if err != nil {
+		return fmt.Errorf("Error preparing statement [%s]: %v", statement, err)
+	}
+	defer stmt.Close()
+
+	values := make([]interface{}, len(header))
+	for _, object := range objects {
+		for i, name := range header {
+			values[i] = nestedToJSON(object[name])
+		}
+
+		if _, err := stmt.ExecContext(s.ctx, values...); err != nil {
+			return fmt.Errorf("Error inserting in %s table with statement: %s values: %v: %v", table.Name, statement, values, err)
+		}
+	}
+
+	return nil
+}
+
+//insertStatement returns insert statement with header columns
+//or upsert statement if table has primary keys
+func (s *SQLite) insertStatement(table *Table, header []string) string {
+	quotedHeader := make([]string, len(header))
+	placeholders := make([]string, len(header))
+	for i, name := range header {
+		quotedHeader[i] = s.quote(name)
+		placeholders[i] = "?"
+	}
+
+	if len(table.PKFields) == 0 {
+		return fmt.Sprintf(sqliteInsertTemplate, table.Name, strings.Join(quotedHeader, ", "), strings.Join(placeholders, ", "))
+	}
+
+	var quotedPKFields []string
+	for _, pkField := range table.GetPKFields() {
+		quotedPKFields = append(quotedPKFields, s.quote(pkField))
+	}
+	sort.Strings(quotedPKFields)
+
+	var updateColumns []string
+	for _, name := range quotedHeader {
+		updateColumns = append(updateColumns, fmt.Sprintf("%s=excluded.%s", name, name))
+	}
+
+	return fmt.Sprintf(sqliteUpsertTemplate, table.Name, strings.Join(quotedHeader, ", "), strings.Join(placeholders, ", "),
+		strings.Join(quotedPKFields, ", "), strings.Join(updateColumns, ", "))
+}
+
+func (s *SQLite) deleteInTransaction(wrappedTx *Transaction, table *Table, deleteConditions *DeleteConditions) error {
+	deleteCondition, values := s.toDeleteQuery(deleteConditions)
+	query := fmt.Sprintf(sqliteDeleteQueryTemplate, table.Name, deleteCondition)
+	s.queryLogger.LogQueryWithValues(query, values)
+
+	if _, err := wrappedTx.tx.ExecContext(s.ctx, query, values...); err != nil {
+		return fmt.Errorf("Error deleting using query: %s, error: %v", query, err)
+	}
+
+	return nil
+}
+
+func (s *SQLite) toDeleteQuery(conditions *DeleteConditions) (string, []interface{}) {
+	var queryConditions []string
+	var values []interface{}
+	for _, condition := range conditions.Conditions {
+		queryConditions = append(queryConditions, s.quote(condition.Field)+" "+condition.Clause+" ?")
+		values = append(values, condition.Value)
+	}
+	return strings.Join(queryConditions, " "+conditions.JoinCondition+" "), values
+}
+
+func (s *SQLite) dropTableInTransaction(wrappedTx *Transaction, tableName string) error {
+	query := fmt.Sprintf(sqliteDropTableTemplate, tableName)
+	s.queryLogger.LogDDL(query)
+
+	if _, err := wrappedTx.tx.ExecContext(s.ctx, query); err != nil {
+		return fmt.Errorf("Error dropping [%s] table: %v", tableName, err)
+	}
+
+	return nil
+}
+
+//create table columns and primary key constraint
+//override input table sql type with configured cast type
+//make fields from Table PkFields - 'not null'
+func (s *SQLite) createTableInTransaction(wrappedTx *Transaction, table *Table) error {
+	var columnsDDL []string
+	pkFields := table.GetPKFieldsMap()
+	for columnName, column := range table.Columns {
+		columnsDDL = append(columnsDDL, s.columnDDL(columnName, column, pkFields))
+	}
+
+	//sorting columns asc
+	sort.Strings(columnsDDL)
+
+	if len(pkFields) > 0 {
+		var quotedPKFields []string
+		for _, pkField := range table.GetPKFields() {
+			quotedPKFields = append(quotedPKFields, s.quote(pkField))
+		}
+		sort.Strings(quotedPKFields)
+		columnsDDL = append(columnsDDL, fmt.Sprintf("PRIMARY KEY (%s)", strings.Join(quotedPKFields, ", ")))
+	}
+
+	query := fmt.Sprintf(sqliteCreateTableTemplate, table.Name, strings.Join(columnsDDL, ", "))
+	s.queryLogger.LogDDL(query)
+
+	if _, err := wrappedTx.tx.ExecContext(s.ctx, query); err != nil {
+		return fmt.Errorf("Error creating [%s] table with statement [%s]: %v", table.Name, query, err)
+	}
+
+	return nil
+}
+
+//alter table with columns (if not empty)
+//rebuild table with new primary keys (if not empty) or without primary keys if Table.DeletePkFields is true
+func (s *SQLite) patchTableSchemaInTransaction(wrappedTx *Transaction, patchTable *Table) error {
+	//patch columns
+	//SQLite can't add NOT NULL column without default value: primary keys constraints are applied on the table rebuilding
+	for columnName, column := range patchTable.Columns {
+		columnDDL := s.columnDDL(columnName, column, map[string]bool{})
+		query := fmt.Sprintf(sqliteAddColumnTemplate, patchTable.Name, columnDDL)
+		s.queryLogger.LogDDL(query)
+
+		if _, err := wrappedTx.tx.ExecContext(s.ctx, query); err != nil {
+			return fmt.Errorf("Error patching %s table with [%s] DDL: %v", patchTable.Name, columnDDL, err)
+		}
+	}
+
+	if patchTable.DeletePkFields || len(patchTable.PKFields) > 0 {
+		return s.rebuildTableInTransaction(wrappedTx, patchTable.Name, patchTable.PKFields)
+	}
+
+	return nil
+}
+
+//rebuildTableInTransaction creates a new table with all columns and pkFields primary keys, copies data,
+//drops the old table and renames the new one
+func (s *SQLite) rebuildTableInTransaction(wrappedTx *Transaction, tableName string, pkFields map[string]bool) error {
+	rows, err := wrappedTx.tx.QueryContext(s.ctx, sqliteTableSchemaQuery, tableName)
+	if err != nil {
+		return fmt.Errorf("Error querying table [%s] schema: %v", tableName, err)
+	}
+	current, err := s.scanTable(tableName, rows)
+	if err != nil {
+		return err
+	}
+
+	tmpTable := &Table{
+		Name:     fmt.Sprintf("jitsu_tmp_%s", uuid.NewLettersNumbers()[:5]),
+		Columns:  current.Columns,
+		PKFields: pkFields,
+	}
+	if err := s.createTableInTransaction(wrappedTx, tmpTable); err != nil {
+		return err
+	}
+
+	var quotedHeader []string
+	for name := range current.Columns {
+		quotedHeader = append(quotedHeader, s.quote(name))
+	}
+	sort.Strings(quotedHeader)
+	header := strings.Join(quotedHeader, ", ")
+
+	statements := []string{
+		fmt.Sprintf(sqliteCopyTableTemplate, tmpTable.Name, header, header, tableName),
+		fmt.Sprintf(sqliteDropTableTemplate, tableName),
+		fmt.Sprintf(sqliteRenameTableTemplate, tmpTable.Name, tableName),
+	}
+	for _, statement := range statements {
+		s.queryLogger.LogDDL(statement)
+		if _, err := wrappedTx.tx.ExecContext(s.ctx, statement); err != nil {
+			return fmt.Errorf("Error rebuilding [%s] table with primary keys [%s] with statement [%s]: %v", tableName, strings.Join(tmpTable.GetPKFields(), ","), statement, err)
+		}
+	}
+
+	return nil
+}
+
+//columnDDL returns column DDL (quoted column name, mapped sql type and 'not null' if pk field)
+func (s *SQLite) columnDDL(name string, column typing.SQLColumn, pkFields map[string]bool) string {
+	var notNullClause string
+	sqlType := column.DDLType()
+
+	if overriddenSQLType, ok := s.sqlTypes[name]; ok {
+		sqlType = overriddenSQLType.ColumnType
+	}
+
+	//not null
+	if _, ok := pkFields[name]; ok {
+		notNullClause = " NOT NULL"
+	}
+
+	return fmt.Sprintf("%s %s%s", s.quote(name), sqlType, notNullClause)
+}
+
+func (s *SQLite) quote(str string) string {
+	return fmt.Sprintf(`"%s"`, str)
+}
@@ -0,0 +1,134 @@
+package adapters
+
+import (
+	"context"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/typing"
+	"github.com/stretchr/testify/require"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSQLiteBulkInsertAndMerge(t *testing.T) {
+	sqlite := newTestSQLite(t)
+
+	table := &Table{
+		Name:     "events",
+		Columns:  Columns{"id": typing.SQLColumn{Type: "TEXT"}, "value": typing.SQLColumn{Type: "INTEGER"}, "_timestamp": typing.SQLColumn{Type: "TIMESTAMP"}},
+		PKFields: map[string]bool{"id": true},
+	}
+	require.NoError(t, sqlite.CreateTable(table))
+
+	dbTable, err := sqlite.GetTableSchema(table.Name)
+	require.NoError(t, err)
+	require.Equal(t, map[string]bool{"id": true}, dbTable.PKFields)
+	require.Equal(t, typing.SQLColumn{Type: "INTEGER"}, dbTable.Columns["value"])
+
+	now := time.Now().UTC()
+	objects := []map[string]interface{}{
+		{"id": "1", "value": 1, "_timestamp": now},
+		{"id": "2", "value": 2, "_timestamp": now},
+		{"id": "1", "value": 3, "_timestamp": now},
+	}
+	require.NoError(t, sqlite.BulkInsert(dbTable, objects))
+
+	require.NoError(t, sqlite.Insert(&EventContext{Table: dbTable, ProcessedEvent: map[string]interface{}{"id": "2", "value": 4}}))
+
+	require.Equal(t, map[string]int{"1": 3, "2": 4}, selectSQLiteValues(t, sqlite, table.Name))
+}
+
+func TestSQLitePatchTableSchema(t *testing.T) {
+	sqlite := newTestSQLite(t)
+
+	table := &Table{Name: "events", Columns: Columns{"id": typing.SQLColumn{Type: "TEXT"}, "value": typing.SQLColumn{Type: "INTEGER"}}}
+	require.NoError(t, sqlite.CreateTable(table))
+	require.NoError(t, sqlite.BulkInsert(table, []map[string]interface{}{{"id": "1", "value": 1}, {"id": "2", "value": 2}}))
+
+	//new column and primary key: the table is rebuilt with data
+	require.NoError(t, sqlite.PatchTableSchema(&Table{
+		Name:     table.Name,
+		Columns:  Columns{"name": typing.SQLColumn{Type: "TEXT"}},
+		PKFields: map[string]bool{"id": true},
+	}))
+
+	dbTable, err := sqlite.GetTableSchema(table.Name)
+	require.NoError(t, err)
+	require.Len(t, dbTable.Columns, 3)
+	require.Equal(t, map[string]bool{"id": true}, dbTable.PKFields)
+	require.Equal(t, map[string]int{"1": 1, "2": 2}, selectSQLiteValues(t, sqlite, table.Name))
+
+	//delete primary key
+	require.NoError(t, sqlite.PatchTableSchema(&Table{Name: table.Name, DeletePkFields: true}))
+
+	dbTable, err = sqlite.GetTableSchema(table.Name)
+	require.NoError(t, err)
+	require.Empty(t, dbTable.PKFields)
+}
+
+func TestSQLiteTruncate(t *testing.T) {
+	sqlite := newTestSQLite(t)
+
+	table := &Table{Name: "events", Columns: Columns{"id": typing.SQLColumn{Type: "TEXT"}, "value": typing.SQLColumn{Type: "INTEGER"}}}
+	require.NoError(t, sqlite.CreateTable(table))
+	require.NoError(t, sqlite.BulkInsert(table, []map[string]interface{}{{"id": "1", "value": 1}}))
+
+	require.NoError(t, sqlite.Truncate(table.Name))
+	require.Empty(t, selectSQLiteValues(t, sqlite, table.Name))
+
+	require.Equal(t, ErrTableNotExist, sqlite.Truncate("nonexistent"))
+}
+
+func TestSQLiteDeleteOlderThan(t *testing.T) {
+	sqlite := newTestSQLite(t)
+
+	table := &Table{Name: "events", Columns: Columns{"id": typing.SQLColumn{Type: "TEXT"}, "value": typing.SQLColumn{Type: "INTEGER"}, "_timestamp": typing.SQLColumn{Type: "TIMESTAMP"}}}
+	require.NoError(t, sqlite.CreateTable(table))
+
+	now := time.Now().UTC()
+	require.NoError(t, sqlite.BulkInsert(table, []map[string]interface{}{
+		{"id": "1", "value": 1, "_timestamp": now.AddDate(0, 0, -100)},
+		{"id": "2", "value": 2, "_timestamp": now.AddDate(0, 0, -91)},
+		{"id": "3", "value": 3, "_timestamp": now.AddDate(0, 0, -1)},
+	}))
+
+	tableNames, err := sqlite.GetTableNames()
+	require.NoError(t, err)
+	require.Equal(t, []string{"events"}, tableNames)
+
+	before := now.AddDate(0, 0, -90)
+	count, err := sqlite.DeleteOlderThan(table, "_timestamp", before, true)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), count)
+	require.Len(t, selectSQLiteValues(t, sqlite, table.Name), 3)
+
+	deleted, err := sqlite.DeleteOlderThan(table, "_timestamp", before, false)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), deleted)
+	require.Equal(t, map[string]int{"3": 3}, selectSQLiteValues(t, sqlite, table.Name))
+}
+
+func newTestSQLite(t *testing.T) *SQLite {
+	sqlite, err := NewSQLite(context.Background(), &SQLiteConfig{Path: filepath.Join(t.TempDir(), "test.db")}, &logging.QueryLogger{}, typing.SQLTypes{})
+	require.NoError(t, err)
+	t.Cleanup(func() { sqlite.Close() })
+
+	return sqlite
+}
+
+func selectSQLiteValues(t *testing.T, sqlite *SQLite, tableName string) map[string]int {
+	rows, err := sqlite.dataSource.Query(`SELECT id, value FROM "` + tableName + `"`)
+	require.NoError(t, err)
+	defer rows.Close()
+
+	values := map[string]int{}
+	for rows.Next() {
+		var id string
+		var value int
+		require.NoError(t, rows.Scan(&id, &value))
+		values[id] = value
+	}
+	require.NoError(t, rows.Err())
+
+	return values
+}
@@ -0,0 +1,215 @@
+package adapters
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/typing"
+	"github.com/jitsucom/jitsu/server/uuid"
+)
+
+const (
+	synapseCopyTemplate = `COPY INTO %s.%s (%s)
+							FROM '%s'
+							WITH (
+								FILE_TYPE = 'CSV',
+								CREDENTIAL = (IDENTITY = 'Shared Access Signature', SECRET = '%s'),
+								FIELDQUOTE = '"',
+								FIELDTERMINATOR = ',',
+								ROWTERMINATOR = '0x0A',
+								FIRSTROW = 2
+							)`
+	//synapseTimestampLayout is DATETIME2 format with max precision
+	synapseTimestampLayout = "2006-01-02 15:04:05.0000000"
+)
+
+//Synapse is adapter for creating, patching (schema or table), inserting and copying data from Azure Blob Storage to Azure Synapse dedicated SQL pool
+//it is MSSQL adapter with Synapse restrictions: primary keys are NONCLUSTERED NOT ENFORCED, multi rows inserts are INSERT .. SELECT UNION ALL
+type Synapse struct {
+	*MSSQL
+
+	stageConfig *AzureBlobConfig
+}
+
+//NewSynapse returns configured Synapse adapter instance
+//stageConfig can be nil in stream mode
+func NewSynapse(ctx context.Context, config *DataSourceConfig, stageConfig *AzureBlobConfig, queryLogger *logging.QueryLogger, sqlTypes typing.SQLTypes) (*Synapse, error) {
+	//Azure requires encrypted connections
+	if _, ok := config.Parameters["encrypt"]; !ok {
+		config.Parameters["encrypt"] = "true"
+	}
+
+	m, err := newMSSQL(ctx, config, queryLogger, sqlTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	m.dbType = "Synapse"
+	m.primaryKeyTemplate = synapseAlterPrimaryKeyTemplate
+	return &Synapse{MSSQL: m, stageConfig: stageConfig}, nil
+}
+
+//Copy transfers data from Azure Blob Storage CSV file (with header) into the table by passing COPY request to Synapse
+func (s *Synapse) Copy(fileName, tableName string, header []string) error {
+	wrappedTx, err := s.OpenTx()
+	if err != nil {
+		return err
+	}
+
+	if err := s.copyInTransaction(wrappedTx, fileName, tableName, header); err != nil {
+		wrappedTx.Rollback()
+		return err
+	}
+
+	return wrappedTx.DirectCommit()
+}
+
+//CopyMerge transfers data from Azure Blob Storage CSV file into a staging table with the same structure as the table
+//and merges it into the table by primary keys. All statements are executed in one transaction:
+//the table isn't changed if any of them fails. Objects in the file must be deduplicated by primary keys
+func (s *Synapse) CopyMerge(fileName string, table *Table, header []string) error {
+	wrappedTx, err := s.OpenTx()
+	if err != nil {
+		return err
+	}
+
+	//only columns from the file are merged: other columns of existing rows are kept
+	mergeTable := &Table{Name: table.Name, Columns: Columns{}, PKFields: table.PKFields}
+	for _, column := range header {
+		mergeTable.Columns[column] = table.Columns[column]
+	}
+
+	stagingTable := &Table{
+		Name:     fmt.Sprintf("jitsu_tmp_%s", uuid.NewLettersNumbers()[:5]),
+		Columns:  mergeTable.Columns,
+		PKFields: map[string]bool{},
+	}
+	if err := s.createTableInTransaction(wrappedTx, stagingTable); err != nil {
+		wrappedTx.Rollback()
+		return fmt.Errorf("Error creating staging table: %v", err)
+	}
+
+	if err := s.copyInTransaction(wrappedTx, fileName, stagingTable.Name, header); err != nil {
+		wrappedTx.Rollback()
+		return fmt.Errorf("Error copying into staging table [%s]: %v", stagingTable.Name, err)
+	}
+
+	if err := s.mergeFromTableInTransaction(wrappedTx, mergeTable, stagingTable); err != nil {
+		wrappedTx.Rollback()
+		return err
+	}
+
+	if err := s.dropTableInTransaction(wrappedTx, stagingTable); err != nil {
+		wrappedTx.Rollback()
+		return err
+	}
+
+	return wrappedTx.DirectCommit()
+}
+
+//copyInTransaction runs COPY from Azure Blob Storage file into the table
+func (s *Synapse) copyInTransaction(wrappedTx *Transaction, fileName, tableName string, header []string) error {
+	if s.stageConfig == nil {
+		return fmt.Errorf("%s stage isn't configured", s.Type())
+	}
+
+	//don't log SAS token
+	s.queryLogger.LogQuery(s.buildCopyStatement(fileName, tableName, header, "***"))
+
+	statement := s.buildCopyStatement(fileName, tableName, header, s.stageConfig.SASToken)
+	if _, err := wrappedTx.tx.ExecContext(s.ctx, statement); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+//buildCopyStatement returns COPY statement from the stage file into the table with columns in header order
+func (s *Synapse) buildCopyStatement(fileName, tableName string, header []string, secret string) string {
+	var quotedHeader []string
+	for _, column := range header {
+		quotedHeader = append(quotedHeader, s.quote(column))
+	}
+
+	return fmt.Sprintf(synapseCopyTemplate, s.quote(s.config.Schema), s.quote(tableName), strings.Join(quotedHeader, ", "),
+		escapeSQLLiteral(s.stageConfig.ObjectURL(fileName)), escapeSQLLiteral(secret))
+}
+
+//MarshalSynapseCSV returns RFC 4180 CSV with header line which is loaded by COPY statement:
+//null values are empty unquoted fields, empty strings are quoted ("") so COPY doesn't load them as null,
+//timestamps are in DATETIME2 format, booleans are 1/0, nested objects are JSON
+func MarshalSynapseCSV(header []string, objects []map[string]interface{}) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	writeSynapseCSVRecord(buf, header, make([]bool, len(header)))
+
+	record := make([]string, len(header))
+	nulls := make([]bool, len(header))
+	for _, object := range objects {
+		for i, column := range header {
+			value, err := synapseCSVValue(object[column])
+			if err != nil {
+				return nil, fmt.Errorf("Error marshaling [%s] value: %v", column, err)
+			}
+			record[i] = value
+			nulls[i] = object[column] == nil
+		}
+		writeSynapseCSVRecord(buf, record, nulls)
+	}
+
+	return buf.Bytes(), nil
+}
+
+//writeSynapseCSVRecord writes CSV line: null fields are written as is (empty),
+//other fields are quoted if they are empty or contain quotes, separators, line breaks or leading/trailing spaces
+func writeSynapseCSVRecord(buf *bytes.Buffer, record []string, nulls []bool) {
+	for i, field := range record {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		if nulls[i] || !synapseCSVNeedsQuotes(field) {
+			buf.WriteString(field)
+			continue
+		}
+
+		buf.WriteByte('"')
+		buf.WriteString(strings.ReplaceAll(field, `"`, `""`))
+		buf.WriteByte('"')
+	}
+	buf.WriteByte('\n')
+}
+
+func synapseCSVNeedsQuotes(field string) bool {
+	return field == "" || strings.ContainsAny(field, "\",\r\n") || strings.TrimSpace(field) != field
+}
+
+func synapseCSVValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case time.Time:
+		return v.UTC().Format(synapseTimestampLayout), nil
+	case bool:
+		if v {
+			return "1", nil
+		}
+		return "0", nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case map[string]interface{}, []interface{}:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		return string(b), nil
+	default:
+		return fmt.Sprint(v), nil
+	}
+}
@@ -59,6 +59,7 @@ func (t *Table) GetPKFieldsMap() map[string]bool {
 // 1) another one is empty
 // 2) all fields from another schema exist in current schema
 // NOTE: Diff method doesn't take types into account
+// (except native nested columns: new fields of nested records are added into existing columns)
 func (t Table) Diff(another *Table) *Table {
 	diff := &Table{Name: t.Name, Columns: map[string]typing.SQLColumn{}, PKFields: map[string]bool{}}
 
@@ -67,9 +68,11 @@ func (t Table) Diff(another *Table) *Table {
 	}
 
 	for name, column := range another.Columns {
-		_, ok := t.Columns[name]
+		current, ok := t.Columns[name]
 		if !ok {
 			diff.Columns[name] = column
+		} else if extended, changed := typing.ExtendNestedType(current.Nested, column.Nested); changed {
+			diff.Columns[name] = typing.SQLColumn{Type: extended.String(), Nested: extended}
 		}
 	}
 
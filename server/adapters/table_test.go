@@ -85,6 +85,18 @@ func TestDiff(t *testing.T) {
 			&Table{Name: "some", Columns: Columns{"col1": typing.SQLColumn{Type: "text"}, "col4": typing.SQLColumn{Type: "text"}, "col5": typing.SQLColumn{Type: "text"}}, PKFields: map[string]bool{"col1": true, "col4": true}},
 			&Table{Name: "some", Columns: Columns{"col5": typing.SQLColumn{Type: "text"}}, PKFields: map[string]bool{"col1": true, "col4": true}, DeletePkFields: true},
 		},
+		{
+			"Diff with new nested fields",
+			&Table{Name: "some", Columns: Columns{"col1": typing.SQLColumn{Type: "STRUCT<a INT64>", Nested: &typing.NestedType{Type: typing.OBJECT, Fields: map[string]*typing.NestedType{"a": {Type: typing.INT64}}}}}},
+			&Table{Name: "some", Columns: Columns{"col1": typing.SQLColumn{Type: "STRUCT<a STRING, b BOOL>", Nested: &typing.NestedType{Type: typing.OBJECT, Fields: map[string]*typing.NestedType{"a": {Type: typing.STRING}, "b": {Type: typing.BOOL}}}}}},
+			&Table{Name: "some", Columns: Columns{"col1": typing.SQLColumn{Type: "STRUCT<a INT64, b BOOL>", Nested: &typing.NestedType{Type: typing.OBJECT, Fields: map[string]*typing.NestedType{"a": {Type: typing.INT64}, "b": {Type: typing.BOOL}}}}}, PKFields: map[string]bool{}},
+		},
+		{
+			"Diff with same nested fields",
+			&Table{Name: "some", Columns: Columns{"col1": typing.SQLColumn{Type: "ARRAY<STRUCT<a INT64>>", Nested: &typing.NestedType{Type: typing.ARRAY, Elem: &typing.NestedType{Type: typing.OBJECT, Fields: map[string]*typing.NestedType{"a": {Type: typing.INT64}}}}}}},
+			&Table{Name: "some", Columns: Columns{"col1": typing.SQLColumn{Type: "ARRAY<STRUCT<a INT64>>", Nested: &typing.NestedType{Type: typing.ARRAY, Elem: &typing.NestedType{Type: typing.OBJECT, Fields: map[string]*typing.NestedType{"a": {Type: typing.INT64}}}}}}},
+			&Table{Name: "some", Columns: Columns{}, PKFields: map[string]bool{}},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -3,6 +3,7 @@ package adapters
 import (
 	"errors"
 	"github.com/jitsucom/jitsu/server/typing"
+	"net/http"
 )
 
 var (
@@ -19,10 +20,13 @@ var (
 
 //WebHookConfig is a dto for parsing Webhook configuration
 type WebHookConfig struct {
-	URL     string            `mapstructure:"url" json:"url,omitempty" yaml:"url,omitempty"`
-	Method  string            `mapstructure:"method" json:"method,omitempty" yaml:"method,omitempty"`
-	Body    string            `mapstructure:"body" json:"body,omitempty" yaml:"body,omitempty"`
-	Headers map[string]string `mapstructure:"headers" json:"headers,omitempty" yaml:"headers,omitempty"`
+	URL       string                  `mapstructure:"url" json:"url,omitempty" yaml:"url,omitempty"`
+	Method    string                  `mapstructure:"method" json:"method,omitempty" yaml:"method,omitempty"`
+	Body      string                  `mapstructure:"body" json:"body,omitempty" yaml:"body,omitempty"`
+	Headers   map[string]string       `mapstructure:"headers" json:"headers,omitempty" yaml:"headers,omitempty"`
+	Signature *WebHookSignatureConfig `mapstructure:"signature" json:"signature,omitempty" yaml:"signature,omitempty"`
+	OAuth2    *WebHookOAuth2Config    `mapstructure:"oauth2" json:"oauth2,omitempty" yaml:"oauth2,omitempty"`
+	TLS       *WebHookTLSConfig       `mapstructure:"tls" json:"tls,omitempty" yaml:"tls,omitempty"`
 }
 
 //Validate returns err if invalid
@@ -33,13 +37,21 @@ func (whc *WebHookConfig) Validate() error {
 	if whc.URL == "" {
 		return errors.New("'url' is required parameter")
 	}
+	if err := whc.Signature.Validate(); err != nil {
+		return err
+	}
+	if err := whc.OAuth2.Validate(); err != nil {
+		return err
+	}
 
-	return nil
+	return whc.TLS.Validate()
 }
 
 //WebHook is an adapter for sending HTTP requests with configurable HTTP parameters (URL, body, headers)
 type WebHook struct {
 	AbstractHTTP
+
+	config *WebHookConfig
 }
 
 //NewWebHook returns configured WebHook adapter instance
@@ -50,19 +62,54 @@ func NewWebHook(config *WebHookConfig, httpAdapterConfiguration *HTTPAdapterConf
 		return nil, err
 	}
 
+	tlsConfig, authenticator, err := webHookAuthentication(config, httpAdapterConfiguration.HTTPConfig.GlobalClientTimeout)
+	if err != nil {
+		return nil, err
+	}
+
 	httpAdapterConfiguration.HTTPReqFactory = httpReqFactory
+	httpAdapterConfiguration.TLSConfig = tlsConfig
+	httpAdapterConfiguration.Authenticator = authenticator
 
 	httpAdapter, err := NewHTTPAdapter(httpAdapterConfiguration)
 	if err != nil {
 		return nil, err
 	}
 
-	wh := &WebHook{}
+	wh := &WebHook{config: config}
 	wh.httpAdapter = httpAdapter
 
 	return wh, nil
 }
 
+//NewTestWebHook returns test instance of adapter
+func NewTestWebHook(config *WebHookConfig) *WebHook {
+	return &WebHook{config: config}
+}
+
+//TestAccess checks configured authentication without sending events:
+// - parses tls client certificate and establishes TLS connection with webhook host (if it isn't a template)
+// - requests OAuth2 access token
+func (wh *WebHook) TestAccess() error {
+	tlsConfig, authenticator, err := webHookAuthentication(wh.config, webHookTestTimeout)
+	if err != nil {
+		return err
+	}
+
+	if tlsConfig != nil {
+		if err := testTLSHandshake(wh.config.URL, tlsConfig); err != nil {
+			return err
+		}
+	}
+
+	if authenticator != nil {
+		req, _ := http.NewRequest(http.MethodPost, wh.config.URL, nil)
+		return authenticator.Authenticate(req, nil)
+	}
+
+	return nil
+}
+
 //Type returns adapter type
 func (wh *WebHook) Type() string {
 	return "WebHook"
@@ -0,0 +1,359 @@
+package adapters
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/credentials"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+const (
+	defaultSignatureHeader          = "X-Jitsu-Signature"
+	defaultSignatureTimestampHeader = "X-Jitsu-Timestamp"
+	signaturePrefix                 = "sha256="
+
+	webHookTestTimeout = 10 * time.Second
+)
+
+//WebHookSignatureConfig is a dto for parsing webhook HMAC-SHA256 signature configuration
+//signature = hex(HMAC-SHA256(secret, "<unix timestamp seconds>.<request body>"))
+//receivers should reject requests with stale timestamp header for preventing replay attacks
+type WebHookSignatureConfig struct {
+	Secret          string `mapstructure:"secret" json:"secret,omitempty" yaml:"secret,omitempty"`
+	Header          string `mapstructure:"header" json:"header,omitempty" yaml:"header,omitempty"`
+	TimestampHeader string `mapstructure:"timestamp_header" json:"timestamp_header,omitempty" yaml:"timestamp_header,omitempty"`
+}
+
+//Validate returns err if invalid and sets default headers
+func (wsc *WebHookSignatureConfig) Validate() error {
+	if wsc == nil {
+		return nil
+	}
+
+	if wsc.Secret == "" {
+		return errors.New("'signature.secret' is required parameter")
+	}
+	if wsc.Header == "" {
+		wsc.Header = defaultSignatureHeader
+	}
+	if wsc.TimestampHeader == "" {
+		wsc.TimestampHeader = defaultSignatureTimestampHeader
+	}
+
+	return nil
+}
+
+//WebHookOAuth2Config is a dto for parsing OAuth2 client credentials flow configuration
+//access token is requested from token_url, cached until expiration and sent in Authorization: Bearer header
+//if credential_id is configured, access token of the stored credential is used instead (see credentials package)
+type WebHookOAuth2Config struct {
+	CredentialID string            `mapstructure:"credential_id" json:"credential_id,omitempty" yaml:"credential_id,omitempty"`
+	TokenURL     string            `mapstructure:"token_url" json:"token_url,omitempty" yaml:"token_url,omitempty"`
+	ClientID     string            `mapstructure:"client_id" json:"client_id,omitempty" yaml:"client_id,omitempty"`
+	ClientSecret string            `mapstructure:"client_secret" json:"client_secret,omitempty" yaml:"client_secret,omitempty"`
+	Scopes       []string          `mapstructure:"scopes" json:"scopes,omitempty" yaml:"scopes,omitempty"`
+	Params       map[string]string `mapstructure:"params" json:"params,omitempty" yaml:"params,omitempty"`
+}
+
+//Validate returns err if invalid
+func (woc *WebHookOAuth2Config) Validate() error {
+	if woc == nil || woc.CredentialID != "" {
+		return nil
+	}
+
+	if woc.TokenURL == "" {
+		return errors.New("'oauth2.token_url' is required parameter")
+	}
+	if woc.ClientID == "" {
+		return errors.New("'oauth2.client_id' is required parameter")
+	}
+	if woc.ClientSecret == "" {
+		return errors.New("'oauth2.client_secret' is required parameter")
+	}
+
+	return nil
+}
+
+//WebHookTLSConfig is a dto for parsing webhook mTLS configuration
+//every value might be a file path as well as PEM string content
+type WebHookTLSConfig struct {
+	ClientCert string `mapstructure:"client_cert" json:"client_cert,omitempty" yaml:"client_cert,omitempty"`
+	ClientKey  string `mapstructure:"client_key" json:"client_key,omitempty" yaml:"client_key,omitempty"`
+	ServerCA   string `mapstructure:"server_ca" json:"server_ca,omitempty" yaml:"server_ca,omitempty"`
+}
+
+//Validate returns err if invalid
+func (wtc *WebHookTLSConfig) Validate() error {
+	if wtc == nil {
+		return nil
+	}
+
+	if wtc.ClientCert == "" {
+		return errors.New("'tls.client_cert' is required parameter")
+	}
+	if wtc.ClientKey == "" {
+		return errors.New("'tls.client_key' is required parameter")
+	}
+
+	return nil
+}
+
+//TLS returns tls.Config with client certificate and optional server CA
+func (wtc *WebHookTLSConfig) TLS() (*tls.Config, error) {
+	if wtc == nil {
+		return nil, nil
+	}
+
+	certPEM, err := readPEM(wtc.ClientCert)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading tls.client_cert: %v", err)
+	}
+	keyPEM, err := readPEM(wtc.ClientKey)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading tls.client_key: %v", err)
+	}
+	certificate, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing tls.client_cert and tls.client_key: %v", err)
+	}
+
+	tlsConfig := &tls.Config{Certificates: []tls.Certificate{certificate}, MinVersion: tls.VersionTLS12}
+	if wtc.ServerCA != "" {
+		caPEM, err := readPEM(wtc.ServerCA)
+		if err != nil {
+			return nil, fmt.Errorf("Error reading tls.server_ca: %v", err)
+		}
+		caCertPool := x509.NewCertPool()
+		if !caCertPool.AppendCertsFromPEM(caPEM) {
+			return nil, errors.New("Error parsing tls.server_ca: PEM certificates weren't found")
+		}
+		tlsConfig.RootCAs = caCertPool
+	}
+
+	return tlsConfig, nil
+}
+
+//HTTPRequestAuthenticator authenticates HTTP requests right before sending (e.g. signs body or sets access token)
+type HTTPRequestAuthenticator interface {
+	Authenticate(httpReq *http.Request, body []byte) error
+	//Invalidate is called when destination responds with 401 code (e.g. for dropping cached access token)
+	Invalidate()
+}
+
+//multiAuthenticator applies all underlying authenticators
+type multiAuthenticator []HTTPRequestAuthenticator
+
+//Authenticate applies all authenticators one by one
+func (ma multiAuthenticator) Authenticate(httpReq *http.Request, body []byte) error {
+	for _, authenticator := range ma {
+		if err := authenticator.Authenticate(httpReq, body); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//Invalidate invalidates all authenticators
+func (ma multiAuthenticator) Invalidate() {
+	for _, authenticator := range ma {
+		authenticator.Invalidate()
+	}
+}
+
+//HMACSigner sets HMAC-SHA256 signature and timestamp headers
+type HMACSigner struct {
+	config *WebHookSignatureConfig
+	now    func() time.Time
+}
+
+//NewHMACSigner returns configured HMACSigner
+func NewHMACSigner(config *WebHookSignatureConfig) *HMACSigner {
+	return &HMACSigner{config: config, now: time.Now}
+}
+
+//Authenticate sets signature of current timestamp and body
+func (hs *HMACSigner) Authenticate(httpReq *http.Request, body []byte) error {
+	ts := strconv.FormatInt(hs.now().Unix(), 10)
+	httpReq.Header.Set(hs.config.TimestampHeader, ts)
+	httpReq.Header.Set(hs.config.Header, signaturePrefix+Sign(hs.config.Secret, ts, body))
+	return nil
+}
+
+//Invalidate does nothing: signature doesn't have state
+func (hs *HMACSigner) Invalidate() {
+}
+
+//Sign returns hex HMAC-SHA256 of "<timestamp>.<body>" with the secret
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte{'.'})
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+//OAuth2Authenticator sets Authorization: Bearer header with access token obtained via OAuth2 client credentials flow
+//token is cached and refreshed when it expires or destination responds with 401 code
+type OAuth2Authenticator struct {
+	sync.Mutex
+
+	config      *clientcredentials.Config
+	ctx         context.Context
+	tokenSource oauth2.TokenSource
+}
+
+//NewOAuth2Authenticator returns configured OAuth2Authenticator
+//token requests are sent with input HTTP client (e.g. with mTLS)
+func NewOAuth2Authenticator(config *WebHookOAuth2Config, client *http.Client) *OAuth2Authenticator {
+	endpointParams := url.Values{}
+	for k, v := range config.Params {
+		endpointParams.Set(k, v)
+	}
+
+	credentialsConfig := &clientcredentials.Config{
+		ClientID:       config.ClientID,
+		ClientSecret:   config.ClientSecret,
+		TokenURL:       config.TokenURL,
+		Scopes:         config.Scopes,
+		EndpointParams: endpointParams,
+	}
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, client)
+
+	return &OAuth2Authenticator{config: credentialsConfig, ctx: ctx, tokenSource: credentialsConfig.TokenSource(ctx)}
+}
+
+//Authenticate sets access token (requests a new one if cached token is expired)
+func (oa *OAuth2Authenticator) Authenticate(httpReq *http.Request, body []byte) error {
+	token, err := oa.Token()
+	if err != nil {
+		return err
+	}
+
+	token.SetAuthHeader(httpReq)
+	return nil
+}
+
+//Token returns cached or a new access token
+func (oa *OAuth2Authenticator) Token() (*oauth2.Token, error) {
+	oa.Lock()
+	tokenSource := oa.tokenSource
+	oa.Unlock()
+
+	token, err := tokenSource.Token()
+	if err != nil {
+		return nil, fmt.Errorf("Error getting OAuth2 access token from [%s]: %v", oa.config.TokenURL, err)
+	}
+
+	return token, nil
+}
+
+//Invalidate drops cached access token
+func (oa *OAuth2Authenticator) Invalidate() {
+	oa.Lock()
+	oa.tokenSource = oa.config.TokenSource(oa.ctx)
+	oa.Unlock()
+}
+
+//CredentialsAuthenticator sets Authorization: Bearer header with access token of the stored OAuth2 credential
+//token is refreshed and shared between cluster nodes by credentials service
+type CredentialsAuthenticator struct {
+	credentialID string
+}
+
+//NewCredentialsAuthenticator returns configured CredentialsAuthenticator
+func NewCredentialsAuthenticator(credentialID string) *CredentialsAuthenticator {
+	return &CredentialsAuthenticator{credentialID: credentialID}
+}
+
+//Authenticate sets current access token of the credential
+func (ca *CredentialsAuthenticator) Authenticate(httpReq *http.Request, body []byte) error {
+	token, err := credentials.Token(ca.credentialID)
+	if err != nil {
+		return err
+	}
+
+	token.SetAuthHeader(httpReq)
+	return nil
+}
+
+//Invalidate marks current access token as rejected
+func (ca *CredentialsAuthenticator) Invalidate() {
+	credentials.Invalidate(ca.credentialID)
+}
+
+//webHookAuthentication returns TLS config and authenticator (nil if they aren't configured)
+func webHookAuthentication(config *WebHookConfig, timeout time.Duration) (*tls.Config, HTTPRequestAuthenticator, error) {
+	tlsConfig, err := config.TLS.TLS()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var authenticators multiAuthenticator
+	if config.OAuth2 != nil && config.OAuth2.CredentialID != "" {
+		authenticators = append(authenticators, NewCredentialsAuthenticator(config.OAuth2.CredentialID))
+	} else if config.OAuth2 != nil {
+		tokenClient := &http.Client{Timeout: timeout, Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+		authenticators = append(authenticators, NewOAuth2Authenticator(config.OAuth2, tokenClient))
+	}
+	if config.Signature != nil {
+		authenticators = append(authenticators, NewHMACSigner(config.Signature))
+	}
+
+	if len(authenticators) == 0 {
+		return tlsConfig, nil, nil
+	}
+
+	return tlsConfig, authenticators, nil
+}
+
+//testTLSHandshake establishes TLS connection with webhook host with client certificate
+//check is skipped if the host is templated (e.g. JavaScript function)
+func testTLSHandshake(rawURL string, tlsConfig *tls.Config) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" || strings.ContainsAny(u.Host, "{}$`") {
+		return nil
+	}
+	if u.Scheme == "http" {
+		return errors.New("webhook URL must be https:// for using tls client certificate")
+	}
+
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), "443")
+	}
+
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: webHookTestTimeout}, "tcp", host, tlsConfig)
+	if err != nil {
+		return fmt.Errorf("Error establishing TLS connection with [%s]: %v", host, err)
+	}
+
+	return conn.Close()
+}
+
+//readPEM returns file content if payload is an absolute file path
+//otherwise returns payload as is
+func readPEM(payload string) ([]byte, error) {
+	if path.IsAbs(payload) {
+		return ioutil.ReadFile(payload)
+	}
+
+	return []byte(strings.TrimSpace(payload)), nil
+}
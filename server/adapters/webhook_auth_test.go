@@ -0,0 +1,203 @@
+package adapters
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/credentials"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/meta"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+)
+
+func TestHMACSigner(t *testing.T) {
+	config := &WebHookSignatureConfig{Secret: "secret"}
+	require.NoError(t, config.Validate())
+
+	signer := NewHMACSigner(config)
+	signer.now = func() time.Time { return time.Unix(1640944800, 0) }
+
+	req, err := http.NewRequest(http.MethodPost, "https://hook", nil)
+	require.NoError(t, err)
+	require.NoError(t, signer.Authenticate(req, []byte(`{"a":1}`)))
+
+	require.Equal(t, "1640944800", req.Header.Get("X-Jitsu-Timestamp"))
+	require.Equal(t, "sha256=02c7a813538f4df53641df773dbdf58beded74f5f726a4d062a02d619f8558e9", req.Header.Get("X-Jitsu-Signature"))
+	require.NotEqual(t, Sign("secret", "1640944801", []byte(`{"a":1}`)), Sign("secret", "1640944800", []byte(`{"a":1}`)), "signature depends on timestamp")
+}
+
+func TestOAuth2Authenticator(t *testing.T) {
+	var tokens atomic.Int32
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		require.Equal(t, "client_credentials", r.Form.Get("grant_type"))
+		require.Equal(t, "events:write", r.Form.Get("scope"))
+		require.Equal(t, "https://api", r.Form.Get("audience"))
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "token" + strconv.Itoa(int(tokens.Inc())),
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+		})
+	}))
+	defer tokenServer.Close()
+
+	authenticator := NewOAuth2Authenticator(&WebHookOAuth2Config{
+		TokenURL:     tokenServer.URL,
+		ClientID:     "id",
+		ClientSecret: "secret",
+		Scopes:       []string{"events:write"},
+		Params:       map[string]string{"audience": "https://api"},
+	}, http.DefaultClient)
+
+	for i := 0; i < 3; i++ {
+		req, _ := http.NewRequest(http.MethodPost, "https://hook", nil)
+		require.NoError(t, authenticator.Authenticate(req, nil))
+		require.Equal(t, "Bearer token1", req.Header.Get("Authorization"), "token is cached")
+	}
+
+	authenticator.Invalidate()
+	req, _ := http.NewRequest(http.MethodPost, "https://hook", nil)
+	require.NoError(t, authenticator.Authenticate(req, nil))
+	require.Equal(t, "Bearer token2", req.Header.Get("Authorization"))
+}
+
+func TestCredentialsAuthenticator(t *testing.T) {
+	var tokens atomic.Int32
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		require.Equal(t, "refresh_token", r.Form.Get("grant_type"))
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token":  "token" + strconv.Itoa(int(tokens.Inc())),
+			"token_type":    "Bearer",
+			"expires_in":    3600,
+			"refresh_token": "refresh",
+		})
+	}))
+	defer tokenServer.Close()
+
+	service, err := credentials.Init(context.Background(), nil, &meta.Dummy{}, func(system, collection string) (func(), error) {
+		return func() {}, nil
+	})
+	require.NoError(t, err)
+	defer service.Close()
+	require.NoError(t, service.Save(&credentials.Config{ID: "app", TokenURL: tokenServer.URL, ClientID: "id", RefreshToken: "refresh"}))
+
+	config := &WebHookConfig{URL: "https://hook", OAuth2: &WebHookOAuth2Config{CredentialID: "app"}}
+	require.NoError(t, config.OAuth2.Validate())
+	_, authenticator, err := webHookAuthentication(config, time.Second)
+	require.NoError(t, err)
+	require.NotNil(t, authenticator)
+
+	req, _ := http.NewRequest(http.MethodPost, "https://hook", nil)
+	require.NoError(t, authenticator.Authenticate(req, nil))
+	require.Equal(t, "Bearer token1", req.Header.Get("Authorization"))
+
+	authenticator.Invalidate()
+	req, _ = http.NewRequest(http.MethodPost, "https://hook", nil)
+	require.NoError(t, authenticator.Authenticate(req, nil))
+	require.Equal(t, "Bearer token2", req.Header.Get("Authorization"), "rejected token is refreshed in the credentials store")
+}
+
+func TestWebHookMutualTLS(t *testing.T) {
+	serverCert, serverCertPEM, _ := generateTestCertificate(t)
+	_, clientCertPEM, clientKeyPEM := generateTestCertificate(t)
+
+	clientCAs := x509.NewCertPool()
+	require.True(t, clientCAs.AppendCertsFromPEM(clientCertPEM))
+
+	received := make(chan http.Header, 1)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		ts := r.Header.Get("X-Jitsu-Timestamp")
+		require.Equal(t, "sha256="+Sign("secret", ts, body), r.Header.Get("X-Jitsu-Signature"))
+		received <- r.Header
+	}))
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{serverCert}, ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	server.StartTLS()
+	defer server.Close()
+
+	config := &WebHookConfig{
+		URL:       server.URL,
+		Method:    http.MethodPost,
+		Body:      "{{ .id }}",
+		Signature: &WebHookSignatureConfig{Secret: "secret"},
+		TLS:       &WebHookTLSConfig{ClientCert: string(clientCertPEM), ClientKey: string(clientKeyPEM), ServerCA: string(serverCertPEM)},
+	}
+	require.NoError(t, config.Validate())
+	require.NoError(t, NewTestWebHook(config).TestAccess())
+
+	invalidKey := &WebHookConfig{URL: server.URL, TLS: &WebHookTLSConfig{ClientCert: string(serverCertPEM), ClientKey: "wrong"}}
+	require.Error(t, NewTestWebHook(invalidKey).TestAccess())
+
+	webHook, err := NewWebHook(config, &HTTPAdapterConfiguration{
+		DestinationID: "test",
+		Dir:           t.TempDir(),
+		HTTPConfig:    &HTTPConfiguration{GlobalClientTimeout: time.Second, RetryDelay: time.Second, RetryCount: 0},
+		PoolWorkers:   1,
+		DebugLogger:   &logging.QueryLogger{},
+		ErrorHandler: func(fallback bool, eventContext *EventContext, err error) {
+			t.Errorf("Event hasn't been sent: %v", err)
+		},
+		SuccessHandler: func(eventContext *EventContext) {},
+	})
+	require.NoError(t, err)
+	defer webHook.Close()
+
+	require.NoError(t, webHook.httpAdapter.SendAsync(&EventContext{EventID: "1", ProcessedEvent: map[string]interface{}{"id": `{"id":1}`}}))
+	select {
+	case header := <-received:
+		require.NotEmpty(t, header.Get("X-Jitsu-Timestamp"))
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timeout waiting for webhook request")
+	}
+}
+
+//generateTestCertificate returns self-signed localhost certificate and its PEM encoded certificate and key
+func generateTestCertificate(t *testing.T) (tls.Certificate, []byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: "localhost"},
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	certificate, err := tls.X509KeyPair(certPEM, keyPEM)
+	require.NoError(t, err)
+
+	return certificate, certPEM, keyPEM
+}
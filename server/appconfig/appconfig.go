@@ -89,6 +89,10 @@ func setDefaultParams(containerized bool) {
 	viper.SetDefault("users_recognition.enabled", false)
 	viper.SetDefault("users_recognition.anonymous_id_node", "/eventn_ctx/user/anonymous_id||/user/anonymous_id")
 	viper.SetDefault("users_recognition.identification_nodes", []string{"/eventn_ctx/user/internal_id||/user/internal_id"})
+	//user data deletion
+	viper.SetDefault("gdpr.anonymous_id_node", "/eventn_ctx/user/anonymous_id||/user/anonymous_id")
+	viper.SetDefault("gdpr.identification_nodes", []string{"/eventn_ctx/user/internal_id||/user/internal_id", "/eventn_ctx/user/email||/user/email"})
+	viper.SetDefault("gitops.reload_sec", 10)
 	viper.SetDefault("singer-bridge.python", "python3")
 	viper.SetDefault("singer-bridge.install_taps", true)
 	viper.SetDefault("singer-bridge.update_taps", false)
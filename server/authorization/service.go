@@ -42,11 +42,18 @@ func NewService() (*Service, error) {
 		return nil, errors.New("server.api_keys_reload_sec can't be empty")
 	}
 
-	//if api_keys is used => strict tokens
-	if viper.IsSet(viperApiKeysKey) || viper.IsSet(deprecatedViperServerApiKeysKey) {
+	//if api_keys is used or api keys are loaded from gitops configuration directory => strict tokens
+	if viper.IsSet(viperApiKeysKey) || viper.IsSet(deprecatedViperServerApiKeysKey) || viper.GetString("gitops.path") != "" {
 		viper.SetDefault("server.strict_auth_tokens", true)
 	}
 
+	//api keys are loaded from gitops configuration directory only (gitops.Provider calls UpdateTokens)
+	//api_keys source isn't read and watched: otherwise its reloading would overwrite gitops api keys
+	if viper.GetString("gitops.path") != "" {
+		service.tokensHolder = reformat(nil)
+		return service, nil
+	}
+
 	viperKey := viperApiKeysKey
 	if viper.IsSet(deprecatedViperServerApiKeysKey) {
 		viperKey = deprecatedViperServerApiKeysKey
@@ -72,9 +79,9 @@ func NewService() (*Service, error) {
 		if len(auth) == 1 {
 			authSource := auth[0]
 			if strings.HasPrefix(authSource, "http://") || strings.HasPrefix(authSource, "https://") {
-				resources.Watch(serviceName, authSource, resources.LoadFromHTTP, service.updateTokens, time.Duration(reloadSec)*time.Second)
+				resources.Watch(serviceName, authSource, resources.LoadFromHTTP, service.UpdateTokens, time.Duration(reloadSec)*time.Second)
 			} else if strings.HasPrefix(authSource, "file://") || strings.HasPrefix(authSource, "/") {
-				resources.Watch(serviceName, strings.Replace(authSource, "file://", "", 1), resources.LoadFromFile, service.updateTokens, time.Duration(reloadSec)*time.Second)
+				resources.Watch(serviceName, strings.Replace(authSource, "file://", "", 1), resources.LoadFromFile, service.UpdateTokens, time.Duration(reloadSec)*time.Second)
 			} else if strings.HasPrefix(authSource, "{") && strings.HasSuffix(authSource, "}") {
 				tokensHolder, err := parseFromBytes([]byte(authSource))
 				if err != nil {
@@ -167,8 +174,8 @@ func (s *Service) GetTokenID(tokenFilter string) string {
 	return ""
 }
 
-//parse and set tokensHolder with lock
-func (s *Service) updateTokens(payload []byte) {
+//UpdateTokens parses payload and sets tokensHolder with lock
+func (s *Service) UpdateTokens(payload []byte) {
 	tokenHolder, err := parseFromBytes(payload)
 	if err != nil {
 		logging.Errorf("Error updating authorization tokens: %v", err)
@@ -0,0 +1,22 @@
+package coordination
+
+import (
+	"github.com/jitsucom/jitsu/server/credentials"
+	"github.com/jitsucom/jitsu/server/logging"
+)
+
+//CredentialsLock returns credentials.LockFunc which locks OAuth2 credentials refreshing cluster-wide
+func CredentialsLock(service Service) credentials.LockFunc {
+	return func(system, collection string) (func(), error) {
+		lock, err := service.Lock(system, collection)
+		if err != nil {
+			return nil, err
+		}
+
+		return func() {
+			if err := service.Unlock(lock); err != nil {
+				logging.SystemErrorf("Error unlocking [%s] [%s]: %v", system, collection, err)
+			}
+		}, nil
+	}
+}
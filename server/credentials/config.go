@@ -0,0 +1,111 @@
+package credentials
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/jitsucom/jitsu/server/meta"
+	"github.com/jitsucom/jitsu/server/timestamp"
+)
+
+const (
+	//RefreshTokenGrantType is used for credentials which are issued on behalf of a user (authorization code flow)
+	RefreshTokenGrantType = "refresh_token"
+	//ClientCredentialsGrantType is used for machine-to-machine credentials
+	ClientCredentialsGrantType = "client_credentials"
+)
+
+//providerTokenURLs are token endpoints of well-known OAuth2 providers
+var providerTokenURLs = map[string]string{
+	"google":  "https://oauth2.googleapis.com/token",
+	"hubspot": "https://api.hubapi.com/oauth/v1/token",
+}
+
+//Config is a dto for parsing OAuth2 credential configuration (server 'credentials' section or API request)
+type Config struct {
+	ID string `mapstructure:"id" json:"id,omitempty" yaml:"id,omitempty"`
+	//Provider is a shortcut for token_url of well-known providers: google, hubspot
+	Provider     string   `mapstructure:"provider" json:"provider,omitempty" yaml:"provider,omitempty"`
+	GrantType    string   `mapstructure:"grant_type" json:"grant_type,omitempty" yaml:"grant_type,omitempty"`
+	TokenURL     string   `mapstructure:"token_url" json:"token_url,omitempty" yaml:"token_url,omitempty"`
+	ClientID     string   `mapstructure:"client_id" json:"client_id,omitempty" yaml:"client_id,omitempty"`
+	ClientSecret string   `mapstructure:"client_secret" json:"client_secret,omitempty" yaml:"client_secret,omitempty"`
+	RefreshToken string   `mapstructure:"refresh_token" json:"refresh_token,omitempty" yaml:"refresh_token,omitempty"`
+	Scopes       []string `mapstructure:"scopes" json:"scopes,omitempty" yaml:"scopes,omitempty"`
+	//Params are additional token request parameters (client_credentials grant type only)
+	Params map[string]string `mapstructure:"params" json:"params,omitempty" yaml:"params,omitempty"`
+}
+
+//Validate returns err if invalid
+//sets token_url by provider and grant type by refresh_token presence
+func (c *Config) Validate() error {
+	if c == nil {
+		return errors.New("credential config is required")
+	}
+	if c.ID == "" {
+		return errors.New("'id' is required parameter")
+	}
+
+	if c.TokenURL == "" && c.Provider != "" {
+		tokenURL, ok := providerTokenURLs[c.Provider]
+		if !ok {
+			return fmt.Errorf("Unknown provider: %s. Please configure 'token_url'", c.Provider)
+		}
+		c.TokenURL = tokenURL
+	}
+	if c.TokenURL == "" {
+		return errors.New("'token_url' or 'provider' is required parameter")
+	}
+	if c.ClientID == "" {
+		return errors.New("'client_id' is required parameter")
+	}
+
+	if c.GrantType == "" {
+		c.GrantType = ClientCredentialsGrantType
+		if c.RefreshToken != "" {
+			c.GrantType = RefreshTokenGrantType
+		}
+	}
+
+	switch c.GrantType {
+	case RefreshTokenGrantType:
+		if c.RefreshToken == "" {
+			return errors.New("'refresh_token' is required parameter")
+		}
+	case ClientCredentialsGrantType:
+		if c.ClientSecret == "" {
+			return errors.New("'client_secret' is required parameter")
+		}
+	default:
+		return fmt.Errorf("Unsupported grant_type: %s. Supported: [%s, %s]", c.GrantType, RefreshTokenGrantType, ClientCredentialsGrantType)
+	}
+
+	return nil
+}
+
+//hash returns hash of the whole configuration
+func (c *Config) hash() string {
+	scopes := append([]string{}, c.Scopes...)
+	sort.Strings(scopes)
+	b, _ := json.Marshal([]interface{}{c.GrantType, c.TokenURL, c.ClientID, c.ClientSecret, c.RefreshToken, scopes, c.Params})
+	return fmt.Sprintf("%x", sha256.Sum256(b))
+}
+
+//credential returns meta storage entity without tokens
+func (c *Config) credential() *meta.OAuth2Credential {
+	return &meta.OAuth2Credential{
+		ID:           c.ID,
+		GrantType:    c.GrantType,
+		TokenURL:     c.TokenURL,
+		ClientID:     c.ClientID,
+		ClientSecret: c.ClientSecret,
+		Scopes:       c.Scopes,
+		Params:       c.Params,
+		RefreshToken: c.RefreshToken,
+		ConfigHash:   c.hash(),
+		UpdatedAt:    timestamp.NowUTC(),
+	}
+}
@@ -0,0 +1,402 @@
+package credentials
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/meta"
+	"github.com/jitsucom/jitsu/server/safego"
+	"github.com/jitsucom/jitsu/server/timestamp"
+	"github.com/spf13/viper"
+	"go.uber.org/atomic"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+const (
+	//LockSystem is a coordination service system name for locking credentials refreshing
+	LockSystem = "oauth2_credentials"
+
+	defaultRefreshBefore = 5 * time.Minute
+	refreshCheckInterval = 30 * time.Second
+	tokenRequestTimeout  = 30 * time.Second
+)
+
+var (
+	//ErrNotInitialized is returned if credentials are requested before Init()
+	ErrNotInitialized = errors.New("Credentials service isn't initialized")
+
+	instance *Service
+)
+
+//LockFunc acquires cluster-wide lock of the resource (coordination.Service.Lock) and returns unlock func
+type LockFunc func(system, collection string) (unlock func(), err error)
+
+//Service stores OAuth2 credentials in meta storage and provides current access tokens by credential ID
+//access tokens are refreshed before expiration under cluster-wide lock: only one node requests a new token
+//and saves it (with the rotated refresh token) into meta storage, other nodes read it from there
+type Service struct {
+	ctx           context.Context
+	metaStorage   meta.Storage
+	lock          LockFunc
+	client        *http.Client
+	refreshBefore time.Duration
+	now           func() time.Time
+
+	mutex *sync.RWMutex
+	//credentials is a local cache (and the only storage if meta storage isn't configured)
+	credentials map[string]*meta.OAuth2Credential
+	//rejected are access tokens which were rejected by destinations (see Invalidate)
+	rejected map[string]string
+	//refreshMutex serializes token requests on the node
+	refreshMutex *sync.Mutex
+
+	closed *atomic.Bool
+}
+
+//Init creates Service from 'credentials' configuration section, starts background refreshing goroutine
+//and makes it available via package functions (Token, TokenSource, Invalidate)
+func Init(ctx context.Context, viperConfig *viper.Viper, metaStorage meta.Storage, lock LockFunc) (*Service, error) {
+	configs := map[string]*Config{}
+	if viperConfig != nil {
+		if err := viperConfig.Unmarshal(&configs); err != nil {
+			return nil, fmt.Errorf("Error parsing credentials configuration: %v", err)
+		}
+	}
+
+	if len(configs) > 0 && metaStorage.Type() == meta.DummyType {
+		logging.Warnf("OAuth2 credentials are stored in memory: rotated refresh tokens will be lost after restart. Please configure meta.storage")
+	}
+
+	service := NewService(ctx, metaStorage, lock)
+	for id, config := range configs {
+		config.ID = id
+		if err := service.Save(config); err != nil {
+			return nil, fmt.Errorf("Error saving credential [%s]: %v", id, err)
+		}
+	}
+
+	service.startRefreshing()
+	instance = service
+	return service, nil
+}
+
+//NewService returns configured Service without background refreshing
+func NewService(ctx context.Context, metaStorage meta.Storage, lock LockFunc) *Service {
+	return &Service{
+		ctx:           ctx,
+		metaStorage:   metaStorage,
+		lock:          lock,
+		client:        &http.Client{Timeout: tokenRequestTimeout},
+		refreshBefore: defaultRefreshBefore,
+		now:           time.Now,
+		mutex:         &sync.RWMutex{},
+		credentials:   map[string]*meta.OAuth2Credential{},
+		rejected:      map[string]string{},
+		refreshMutex:  &sync.Mutex{},
+		closed:        atomic.NewBool(false),
+	}
+}
+
+//Token returns current access token of the credential from the global Service
+func Token(credentialID string) (*oauth2.Token, error) {
+	if instance == nil {
+		return nil, ErrNotInitialized
+	}
+
+	return instance.Token(credentialID)
+}
+
+//TokenSource returns oauth2.TokenSource of the credential from the global Service
+//it is used with HTTP clients and Google client libraries (option.WithTokenSource)
+func TokenSource(credentialID string) oauth2.TokenSource {
+	return &tokenSource{credentialID: credentialID}
+}
+
+//Invalidate marks current access token of the credential as rejected in the global Service
+func Invalidate(credentialID string) {
+	if instance != nil {
+		instance.Invalidate(credentialID)
+	}
+}
+
+//Save validates configuration and saves credential into meta storage
+//credential tokens are kept if the configuration hasn't been changed (e.g. on server restart)
+func (s *Service) Save(config *Config) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+
+	credential := config.credential()
+	unlock, err := s.lock(LockSystem, config.ID)
+	if err != nil {
+		return fmt.Errorf("Error locking credential: %v", err)
+	}
+	defer unlock()
+
+	stored, err := s.load(config.ID)
+	if err == nil && stored.ConfigHash == credential.ConfigHash {
+		s.cache(stored)
+		return nil
+	}
+	if err != nil && err != meta.ErrCredentialNotFound {
+		return err
+	}
+
+	return s.save(credential)
+}
+
+//Delete removes credential from meta storage and local cache
+func (s *Service) Delete(credentialID string) error {
+	if err := s.metaStorage.DeleteCredential(credentialID); err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	delete(s.credentials, credentialID)
+	delete(s.rejected, credentialID)
+	s.mutex.Unlock()
+	return nil
+}
+
+//GetAll returns all credentials sorted by ID
+func (s *Service) GetAll() ([]*meta.OAuth2Credential, error) {
+	stored, err := s.metaStorage.GetAllCredentials()
+	if err != nil {
+		return nil, err
+	}
+
+	credentials := map[string]*meta.OAuth2Credential{}
+	s.mutex.RLock()
+	for id, credential := range s.credentials {
+		credentials[id] = credential
+	}
+	s.mutex.RUnlock()
+	for i := range stored {
+		credentials[stored[i].ID] = &stored[i]
+	}
+
+	result := make([]*meta.OAuth2Credential, 0, len(credentials))
+	for _, credential := range credentials {
+		result = append(result, credential)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result, nil
+}
+
+//Token returns cached access token or a new one if it is absent, expires soon or has been rejected
+func (s *Service) Token(credentialID string) (*oauth2.Token, error) {
+	s.mutex.RLock()
+	credential, ok := s.credentials[credentialID]
+	rejected := s.rejected[credentialID]
+	s.mutex.RUnlock()
+
+	if ok && s.isValid(credential, rejected) {
+		return toToken(credential), nil
+	}
+
+	return s.refresh(credentialID)
+}
+
+//Invalidate marks current access token as rejected: the next Token() call requests a new one
+func (s *Service) Invalidate(credentialID string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if credential, ok := s.credentials[credentialID]; ok && credential.AccessToken != "" {
+		s.rejected[credentialID] = credential.AccessToken
+	}
+}
+
+//Close stops background refreshing
+func (s *Service) Close() error {
+	s.closed.Store(true)
+	return nil
+}
+
+//refresh requests a new access token under cluster-wide lock
+//if another node has already refreshed the token, it is taken from meta storage
+func (s *Service) refresh(credentialID string) (*oauth2.Token, error) {
+	s.refreshMutex.Lock()
+	defer s.refreshMutex.Unlock()
+
+	unlock, err := s.lock(LockSystem, credentialID)
+	if err != nil {
+		return nil, fmt.Errorf("Error locking credential [%s] for refreshing: %v", credentialID, err)
+	}
+	defer unlock()
+
+	credential, err := s.load(credentialID)
+	if err != nil {
+		return nil, fmt.Errorf("Error loading credential [%s]: %v", credentialID, err)
+	}
+
+	s.mutex.RLock()
+	rejected := s.rejected[credentialID]
+	s.mutex.RUnlock()
+	if s.isValid(credential, rejected) {
+		s.cache(credential)
+		return toToken(credential), nil
+	}
+
+	token, err := s.requestToken(credential)
+	if err != nil {
+		credential.LastError = err.Error()
+		credential.UpdatedAt = timestamp.NowUTC()
+		if saveErr := s.save(credential); saveErr != nil {
+			logging.Errorf("Error saving credential [%s] error: %v", credentialID, saveErr)
+		}
+		return nil, fmt.Errorf("Error refreshing OAuth2 credential [%s]: %v", credentialID, err)
+	}
+
+	credential.AccessToken = token.AccessToken
+	credential.TokenType = token.TokenType
+	credential.Expiry = token.Expiry
+	//authorization servers may rotate refresh tokens: the previous one becomes invalid
+	if token.RefreshToken != "" {
+		credential.RefreshToken = token.RefreshToken
+	}
+	credential.LastError = ""
+	credential.UpdatedAt = timestamp.NowUTC()
+
+	if err := s.save(credential); err != nil {
+		return nil, fmt.Errorf("Error saving refreshed credential [%s]: %v", credentialID, err)
+	}
+
+	return toToken(credential), nil
+}
+
+//requestToken requests a new access token from token_url according to grant type
+func (s *Service) requestToken(credential *meta.OAuth2Credential) (*oauth2.Token, error) {
+	ctx := context.WithValue(s.ctx, oauth2.HTTPClient, s.client)
+	if credential.GrantType == ClientCredentialsGrantType {
+		endpointParams := url.Values{}
+		for k, v := range credential.Params {
+			endpointParams.Set(k, v)
+		}
+
+		config := &clientcredentials.Config{
+			ClientID:       credential.ClientID,
+			ClientSecret:   credential.ClientSecret,
+			TokenURL:       credential.TokenURL,
+			Scopes:         credential.Scopes,
+			EndpointParams: endpointParams,
+		}
+		return config.Token(ctx)
+	}
+
+	config := &oauth2.Config{
+		ClientID:     credential.ClientID,
+		ClientSecret: credential.ClientSecret,
+		Endpoint:     oauth2.Endpoint{TokenURL: credential.TokenURL},
+		Scopes:       credential.Scopes,
+	}
+	return config.TokenSource(ctx, &oauth2.Token{RefreshToken: credential.RefreshToken}).Token()
+}
+
+//startRefreshing runs goroutine which refreshes access tokens before expiration every 30 seconds
+func (s *Service) startRefreshing() {
+	safego.RunWithRestart(func() {
+		for {
+			if s.closed.Load() {
+				break
+			}
+
+			s.refreshExpiring()
+
+			time.Sleep(refreshCheckInterval)
+		}
+	})
+}
+
+//refreshExpiring refreshes all access tokens which expire soon
+//credentials which haven't been used yet are refreshed on the first Token() call
+func (s *Service) refreshExpiring() {
+	credentials, err := s.GetAll()
+	if err != nil {
+		logging.Errorf("Error getting OAuth2 credentials: %v", err)
+		return
+	}
+
+	for _, credential := range credentials {
+		if credential.AccessToken == "" || credential.Expiry.IsZero() || s.isValid(credential, "") {
+			continue
+		}
+
+		if _, err := s.refresh(credential.ID); err != nil {
+			logging.Error(err)
+		}
+	}
+}
+
+//isValid returns true if access token exists, isn't rejected and doesn't expire soon
+func (s *Service) isValid(credential *meta.OAuth2Credential, rejected string) bool {
+	if credential.AccessToken == "" || credential.AccessToken == rejected {
+		return false
+	}
+
+	return credential.Expiry.IsZero() || s.now().Add(s.refreshBefore).Before(credential.Expiry)
+}
+
+//load returns credential from meta storage or from the local cache if meta storage isn't configured
+func (s *Service) load(credentialID string) (*meta.OAuth2Credential, error) {
+	credential, err := s.metaStorage.GetCredential(credentialID)
+	if err == nil {
+		return credential, nil
+	}
+	if err != meta.ErrCredentialNotFound {
+		return nil, err
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	cached, ok := s.credentials[credentialID]
+	if !ok {
+		return nil, meta.ErrCredentialNotFound
+	}
+
+	copied := *cached
+	return &copied, nil
+}
+
+//save saves credential into meta storage and into the local cache
+func (s *Service) save(credential *meta.OAuth2Credential) error {
+	if err := s.metaStorage.SaveCredential(credential); err != nil {
+		return err
+	}
+
+	s.cache(credential)
+	return nil
+}
+
+//cache puts credential into the local cache
+func (s *Service) cache(credential *meta.OAuth2Credential) {
+	s.mutex.Lock()
+	s.credentials[credential.ID] = credential
+	if s.rejected[credential.ID] != credential.AccessToken {
+		delete(s.rejected, credential.ID)
+	}
+	s.mutex.Unlock()
+}
+
+//toToken returns oauth2.Token without refresh token
+func toToken(credential *meta.OAuth2Credential) *oauth2.Token {
+	return &oauth2.Token{AccessToken: credential.AccessToken, TokenType: credential.TokenType, Expiry: credential.Expiry}
+}
+
+//tokenSource is an oauth2.TokenSource of the credential from the global Service
+type tokenSource struct {
+	credentialID string
+}
+
+//Token returns current access token
+func (ts *tokenSource) Token() (*oauth2.Token, error) {
+	return Token(ts.credentialID)
+}
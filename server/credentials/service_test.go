@@ -0,0 +1,198 @@
+package credentials
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/meta"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+)
+
+//metaStorageMock keeps credentials in memory like shared Redis meta storage
+type metaStorageMock struct {
+	meta.Dummy
+
+	mutex       sync.Mutex
+	credentials map[string]string
+}
+
+func (msm *metaStorageMock) SaveCredential(credential *meta.OAuth2Credential) error {
+	msm.mutex.Lock()
+	defer msm.mutex.Unlock()
+	msm.credentials[credential.ID] = credential.Marshal()
+	return nil
+}
+
+func (msm *metaStorageMock) GetCredential(credentialID string) (*meta.OAuth2Credential, error) {
+	msm.mutex.Lock()
+	defer msm.mutex.Unlock()
+	payload, ok := msm.credentials[credentialID]
+	if !ok {
+		return nil, meta.ErrCredentialNotFound
+	}
+	credential := &meta.OAuth2Credential{}
+	return credential, json.Unmarshal([]byte(payload), credential)
+}
+
+func (msm *metaStorageMock) Type() string {
+	return meta.RedisType
+}
+
+//tokenServer returns access tokens "access_N" and rotates refresh tokens "refresh_N"
+//rejects outdated refresh tokens
+type tokenServer struct {
+	*httptest.Server
+
+	requests     *atomic.Int64
+	refreshToken *atomic.String
+}
+
+func newTokenServer(t *testing.T) *tokenServer {
+	ts := &tokenServer{requests: atomic.NewInt64(0), refreshToken: atomic.NewString("refresh_0")}
+	ts.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		w.Header().Set("Content-Type", "application/json")
+		if r.Form.Get("grant_type") == RefreshTokenGrantType && r.Form.Get("refresh_token") != ts.refreshToken.Load() {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+
+		n := ts.requests.Inc()
+		ts.refreshToken.Store(fmt.Sprintf("refresh_%d", n))
+		fmt.Fprintf(w, `{"access_token":"access_%d","token_type":"Bearer","expires_in":3600,"refresh_token":"refresh_%d"}`, n, n)
+	}))
+	return ts
+}
+
+func inMemoryLock() LockFunc {
+	locks := &sync.Map{}
+	return func(system, collection string) (func(), error) {
+		mutex, _ := locks.LoadOrStore(system+"_"+collection, &sync.Mutex{})
+		mutex.(*sync.Mutex).Lock()
+		return mutex.(*sync.Mutex).Unlock, nil
+	}
+}
+
+func TestTokenRefreshing(t *testing.T) {
+	server := newTokenServer(t)
+	defer server.Close()
+
+	metaStorage := &metaStorageMock{credentials: map[string]string{}}
+	lock := inMemoryLock()
+	node1 := NewService(context.Background(), metaStorage, lock)
+	node2 := NewService(context.Background(), metaStorage, lock)
+
+	config := &Config{ID: "google", TokenURL: server.URL, ClientID: "client", ClientSecret: "secret", RefreshToken: "refresh_0"}
+	require.NoError(t, node1.Save(config))
+	require.Equal(t, RefreshTokenGrantType, config.GrantType)
+
+	token, err := node1.Token("google")
+	require.NoError(t, err)
+	require.Equal(t, "access_1", token.AccessToken)
+	stored, err := metaStorage.GetCredential("google")
+	require.NoError(t, err)
+	require.Equal(t, "refresh_1", stored.RefreshToken, "rotated refresh token is saved")
+
+	token, err = node2.Token("google")
+	require.NoError(t, err)
+	require.Equal(t, "access_1", token.AccessToken, "token is shared between nodes")
+	require.Equal(t, int64(1), server.requests.Load())
+
+	//token expires in 4 minutes
+	node2.now = func() time.Time { return time.Now().Add(56 * time.Minute) }
+	token, err = node2.Token("google")
+	require.NoError(t, err)
+	require.Equal(t, "access_2", token.AccessToken)
+
+	//node1 has outdated refresh token in the local cache (token server rejects it)
+	//but it takes the actual one from meta storage
+	node1.now = func() time.Time { return time.Now().Add(time.Hour) }
+	token, err = node1.Token("google")
+	require.NoError(t, err)
+	require.Equal(t, "access_3", token.AccessToken)
+	require.Equal(t, int64(3), server.requests.Load())
+
+	//server restart with the same configuration keeps rotated tokens
+	require.NoError(t, NewService(context.Background(), metaStorage, lock).Save(&Config{ID: "google", TokenURL: server.URL, ClientID: "client", ClientSecret: "secret", RefreshToken: "refresh_0"}))
+	stored, err = metaStorage.GetCredential("google")
+	require.NoError(t, err)
+	require.Equal(t, "refresh_3", stored.RefreshToken)
+	require.Equal(t, "access_3", stored.AccessToken)
+}
+
+func TestTokenInvalidation(t *testing.T) {
+	server := newTokenServer(t)
+	defer server.Close()
+
+	service := NewService(context.Background(), &meta.Dummy{}, inMemoryLock())
+	require.NoError(t, service.Save(&Config{ID: "webhook", TokenURL: server.URL, ClientID: "client", ClientSecret: "secret"}))
+
+	token, err := service.Token("webhook")
+	require.NoError(t, err)
+	require.Equal(t, "access_1", token.AccessToken)
+
+	service.Invalidate("webhook")
+	token, err = service.Token("webhook")
+	require.NoError(t, err)
+	require.Equal(t, "access_2", token.AccessToken, "rejected token is refreshed")
+
+	token, err = service.Token("webhook")
+	require.NoError(t, err)
+	require.Equal(t, "access_2", token.AccessToken)
+}
+
+func TestTokenError(t *testing.T) {
+	server := newTokenServer(t)
+	defer server.Close()
+
+	metaStorage := &metaStorageMock{credentials: map[string]string{}}
+	service := NewService(context.Background(), metaStorage, inMemoryLock())
+	require.NoError(t, service.Save(&Config{ID: "hubspot", TokenURL: server.URL, ClientID: "client", RefreshToken: "revoked"}))
+
+	_, err := service.Token("hubspot")
+	require.Error(t, err)
+	stored, err := metaStorage.GetCredential("hubspot")
+	require.NoError(t, err)
+	require.Contains(t, stored.LastError, "invalid_grant")
+
+	_, err = service.Token("unknown")
+	require.EqualError(t, err, "Error loading credential [unknown]: Credential wasn't found")
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name              string
+		config            *Config
+		expectedTokenURL  string
+		expectedGrantType string
+		expectedErr       string
+	}{
+		{"Provider", &Config{ID: "c", Provider: "google", ClientID: "id", RefreshToken: "r"}, "https://oauth2.googleapis.com/token", RefreshTokenGrantType, ""},
+		{"Client credentials", &Config{ID: "c", TokenURL: "https://auth", ClientID: "id", ClientSecret: "s"}, "https://auth", ClientCredentialsGrantType, ""},
+		{"Unknown provider", &Config{ID: "c", Provider: "unknown", ClientID: "id"}, "", "", "Unknown provider: unknown. Please configure 'token_url'"},
+		{"Without token url", &Config{ID: "c", ClientID: "id"}, "", "", "'token_url' or 'provider' is required parameter"},
+		{"Without refresh token", &Config{ID: "c", Provider: "hubspot", ClientID: "id", GrantType: RefreshTokenGrantType}, "", "", "'refresh_token' is required parameter"},
+		{"Without client secret", &Config{ID: "c", Provider: "hubspot", ClientID: "id"}, "", "", "'client_secret' is required parameter"},
+		{"Unsupported grant type", &Config{ID: "c", Provider: "hubspot", ClientID: "id", GrantType: "password"}, "", "", "Unsupported grant_type: password. Supported: [refresh_token, client_credentials]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.expectedErr != "" {
+				require.EqualError(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedTokenURL, tt.config.TokenURL)
+			require.Equal(t, tt.expectedGrantType, tt.config.GrantType)
+		})
+	}
+}
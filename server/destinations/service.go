@@ -7,6 +7,7 @@ import (
 	"github.com/jitsucom/jitsu/server/adapters"
 	"github.com/jitsucom/jitsu/server/appconfig"
 	"github.com/jitsucom/jitsu/server/events"
+	"github.com/jitsucom/jitsu/server/gitops"
 	"github.com/jitsucom/jitsu/server/logging"
 	"github.com/jitsucom/jitsu/server/resources"
 	"github.com/jitsucom/jitsu/server/storages"
@@ -104,11 +105,13 @@ func NewService(destinations *viper.Viper, destinationsSource string, storageFac
 
 	} else if destinationsSource != "" {
 		if strings.HasPrefix(destinationsSource, "http://") || strings.HasPrefix(destinationsSource, "https://") {
-			appconfig.Instance.AuthorizationService.DestinationsForceReload = resources.Watch(serviceName, destinationsSource, resources.LoadFromHTTP, service.updateDestinations, time.Duration(reloadSec)*time.Second)
+			appconfig.Instance.AuthorizationService.DestinationsForceReload = resources.Watch(serviceName, destinationsSource, resources.LoadFromHTTP, service.UpdateDestinations, time.Duration(reloadSec)*time.Second)
 		} else if strings.Contains(destinationsSource, "file://") || strings.HasPrefix(destinationsSource, "/") {
-			appconfig.Instance.AuthorizationService.DestinationsForceReload = resources.Watch(serviceName, strings.Replace(destinationsSource, "file://", "", 1), resources.LoadFromFile, service.updateDestinations, time.Duration(reloadSec)*time.Second)
+			appconfig.Instance.AuthorizationService.DestinationsForceReload = resources.Watch(serviceName, strings.Replace(destinationsSource, "file://", "", 1), resources.LoadFromFile, service.UpdateDestinations, time.Duration(reloadSec)*time.Second)
 		} else if strings.HasPrefix(destinationsSource, "{") && strings.HasSuffix(destinationsSource, "}") {
-			service.updateDestinations([]byte(destinationsSource))
+			service.UpdateDestinations([]byte(destinationsSource))
+		} else if destinationsSource == gitops.SourceName {
+			logging.Info("Destinations will be loaded from gitops configuration directory")
 		} else {
 			return nil, errors.New("Unknown destination source: " + destinationsSource)
 		}
@@ -140,6 +143,39 @@ func (s *Service) GetDestinationByID(id string) (storages.StorageProxy, bool) {
 	return unit.storage, true
 }
 
+//Query executes SELECT query in the destination and passes result rows to consume one by one
+//returns err if destination doesn't exist, isn't initialized or doesn't support queries
+func (s *Service) Query(destinationID, query string, consume func(row map[string]interface{}) error) error {
+	storageProxy, ok := s.GetDestinationByID(destinationID)
+	if !ok {
+		return fmt.Errorf("Destination [%s] doesn't exist", destinationID)
+	}
+
+	storage, ok := storageProxy.Get()
+	if !ok {
+		return fmt.Errorf("Destination [%s] isn't initialized", destinationID)
+	}
+
+	if err := storage.Query(query, consume); err != nil {
+		return fmt.Errorf("[%s] %s query error: %v", destinationID, storage.Type(), err)
+	}
+
+	return nil
+}
+
+//GetAllDestinationIDs returns IDs of all configured destinations
+func (s *Service) GetAllDestinationIDs() []string {
+	s.RLock()
+	defer s.RUnlock()
+
+	ids := make([]string, 0, len(s.unitsByID))
+	for id := range s.unitsByID {
+		ids = append(ids, id)
+	}
+
+	return ids
+}
+
 func (s *Service) GetDestinations(tokenID string) (storages []storages.StorageProxy) {
 	s.RLock()
 	defer s.RUnlock()
@@ -186,7 +222,8 @@ func (s *Service) GetEventsConsumerByDestinationID(destinationID string) (events
 
 }
 
-func (s *Service) updateDestinations(payload []byte) {
+//UpdateDestinations parses payload and reinitializes changed destinations
+func (s *Service) UpdateDestinations(payload []byte) {
 	dc, err := parseFromBytes(payload)
 	if err != nil {
 		logging.Error(marshallingErrorMsg, err)
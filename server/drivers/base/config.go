@@ -20,6 +20,14 @@ type SourceConfig struct {
 	Schedule     string        `mapstructure:"schedule" json:"schedule,omitempty" yaml:"schedule,omitempty"`
 
 	Config map[string]interface{} `mapstructure:"config" json:"config,omitempty" yaml:"config,omitempty"`
+
+	//Querier executes SQL queries in destinations. It is set by sources.Service (without serialization)
+	Querier Querier `mapstructure:"-" json:"-" yaml:"-"`
+}
+
+//Querier executes SELECT query in a destination and passes result rows to consume one by one
+type Querier interface {
+	Query(destinationID, query string, consume func(row map[string]interface{}) error) error
 }
 
 //Collection is a dto for report unit serialization
@@ -5,8 +5,10 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/jitsucom/jitsu/server/credentials"
 	"github.com/jitsucom/jitsu/server/logging"
 	"github.com/jitsucom/jitsu/server/schema"
+	"google.golang.org/api/option"
 	"io"
 	"io/ioutil"
 	"strings"
@@ -21,6 +23,7 @@ const (
 	GooglePlayType      = "google_play"
 	GoogleAdsType       = "google_ads"
 	RedisType           = "redis"
+	SQLQueryType        = "sql_query"
 
 	SingerType          = "singer"
 	AirbyteType         = "airbyte"
@@ -45,6 +48,8 @@ type GoogleAuthConfig struct {
 	RefreshToken      string      `mapstructure:"refresh_token" json:"refresh_token,omitempty" yaml:"refresh_token,omitempty"`
 	ServiceAccountKey interface{} `mapstructure:"service_account_key" json:"service_account_key,omitempty" yaml:"service_account_key,omitempty"`
 	Subject           string      `mapstructure:"subject" json:"subject,omitempty" yaml:"subject,omitempty"`
+	//CredentialID is an ID of OAuth2 credential from the server credentials store. It is used instead of other fields
+	CredentialID string `mapstructure:"credential_id" json:"credential_id,omitempty" yaml:"credential_id,omitempty"`
 }
 
 func (gac *GoogleAuthConfig) Marshal() ([]byte, error) {
@@ -72,6 +77,10 @@ func (gac *GoogleAuthConfig) Marshal() ([]byte, error) {
 //Validate checks service account JSON or OAuth fields
 //returns err if both authorization parameters are empty
 func (gac *GoogleAuthConfig) Validate() error {
+	if gac.CredentialID != "" {
+		return nil
+	}
+
 	if gac.Type == GoogleOAuthAuthorizationType {
 		//validate OAuth field
 		if gac.ClientID == "" {
@@ -94,6 +103,20 @@ func (gac *GoogleAuthConfig) Validate() error {
 	return nil
 }
 
+//ClientOption returns Google API client option with token source from the credentials store if credential_id is configured
+//or with credentials JSON otherwise
+func (gac *GoogleAuthConfig) ClientOption() (option.ClientOption, error) {
+	if gac.CredentialID != "" {
+		return option.WithTokenSource(credentials.TokenSource(gac.CredentialID)), nil
+	}
+
+	credentialsJSON, err := gac.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	return option.WithCredentialsJSON(credentialsJSON), nil
+}
+
 //GoogleAuthorizedUserJSON is a Google dto for authorization
 type GoogleAuthorizedUserJSON struct {
 	ClientID     string `mapstructure:"client_id" json:"client_id,omitempty" yaml:"client_id,omitempty"`
@@ -140,6 +163,23 @@ type Driver interface {
 	GetDriversInfo() *DriversInfo
 }
 
+//DiffDriver is implemented by drivers which objects are compared with the previous sync:
+//only new and changed objects are stored (e.g. SQL query results which are sent to HTTP destinations)
+type DiffDriver interface {
+	Driver
+
+	//GetObjectKey returns object identifier which is used for matching with the object from the previous sync
+	GetObjectKey(object map[string]interface{}) string
+}
+
+//StreamingDriver is implemented by drivers which pass objects page by page instead of loading all of them into memory
+type StreamingDriver interface {
+	Driver
+
+	//StreamObjectsFor passes objects of the interval to consume page by page
+	StreamObjectsFor(interval *TimeInterval, consume func(objects []map[string]interface{}) error) error
+}
+
 //CLIDriver interface must be implemented by every CLI source type (Singer or Airbyte)
 type CLIDriver interface {
 	Driver
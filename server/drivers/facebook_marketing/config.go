@@ -21,14 +21,16 @@ type FacebookReportConfig struct {
 type FacebookMarketingConfig struct {
 	AccountID   string `mapstructure:"account_id" json:"account_id,omitempty" yaml:"account_id,omitempty"`
 	AccessToken string `mapstructure:"access_token" json:"access_token,omitempty" yaml:"access_token,omitempty"`
+	//CredentialID is an ID of OAuth2 credential (Facebook app) which access token is used instead of access_token
+	CredentialID string `mapstructure:"credential_id" json:"credential_id,omitempty" yaml:"credential_id,omitempty"`
 }
 
 func (fmc *FacebookMarketingConfig) Validate() error {
 	if fmc.AccountID == "" {
 		return errors.New("account_id is required")
 	}
-	if fmc.AccessToken == "" {
-		return errors.New("access_token is required")
+	if fmc.AccessToken == "" && fmc.CredentialID == "" {
+		return errors.New("access_token or credential_id is required")
 	}
 	return nil
 }
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	fb "github.com/huandu/facebook/v2"
+	"github.com/jitsucom/jitsu/server/credentials"
 	"github.com/jitsucom/jitsu/server/drivers/base"
 	"github.com/jitsucom/jitsu/server/jsonutils"
 	"github.com/jitsucom/jitsu/server/logging"
@@ -43,6 +44,8 @@ const (
 
 	fbMarketingAPIVersion      = "v12.0"
 	defaultFacebookReportLevel = "ad"
+	//fbInvalidAccessTokenCode is Graph API OAuthException code of expired or revoked access token
+	fbInvalidAccessTokenCode = 190
 )
 
 func init() {
@@ -164,9 +167,8 @@ func (fm *FacebookMarketing) buildTimeInterval(interval *base.TimeInterval) stri
 
 func (fm *FacebookMarketing) loadReportWithRetry(url string, fields []string, interval *base.TimeInterval, pageLimit int, failFast bool) ([]map[string]interface{}, error) {
 	requestParameters := fb.Params{
-		"level":        fm.reportConfig.Level,
-		"fields":       strings.Join(fields, ","),
-		"access_token": fm.config.AccessToken,
+		"level":  fm.reportConfig.Level,
+		"fields": strings.Join(fields, ","),
 	}
 
 	if interval != nil {
@@ -181,6 +183,13 @@ func (fm *FacebookMarketing) loadReportWithRetry(url string, fields []string, in
 	var response fb.Result
 	var err error
 	for attempt < fbMaxAttempts {
+		var accessToken string
+		accessToken, err = fm.accessToken()
+		if err != nil {
+			return nil, err
+		}
+		requestParameters["access_token"] = accessToken
+
 		response, err = fb.Get(url, requestParameters)
 		if err == nil {
 			fm.logUsage(response.UsageInfo())
@@ -207,6 +216,11 @@ func (fm *FacebookMarketing) loadReportWithRetry(url string, fields []string, in
 			return data, nil
 		}
 
+		if fbErr, ok := err.(*fb.Error); ok && fbErr.Code == fbInvalidAccessTokenCode && fm.config.CredentialID != "" {
+			//access token has been expired or revoked: the next request will use a new one
+			credentials.Invalidate(fm.config.CredentialID)
+		}
+
 		if failFast {
 			return nil, err
 		}
@@ -231,6 +245,18 @@ func (fm *FacebookMarketing) loadReportWithRetry(url string, fields []string, in
 }
 
 //parseData read all data (if paging) and return result
+//accessToken returns access token from the credentials store if credential_id is configured or access_token otherwise
+func (fm *FacebookMarketing) accessToken() (string, error) {
+	if fm.config.CredentialID != "" {
+		token, err := credentials.Token(fm.config.CredentialID)
+		if err != nil {
+			return "", err
+		}
+		return token.AccessToken, nil
+	}
+	return fm.config.AccessToken, nil
+}
+
 func (fm *FacebookMarketing) parseData(response fb.Result) ([]map[string]interface{}, error) {
 	session := &fb.Session{
 		Version: "v9.0",
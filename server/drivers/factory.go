@@ -14,6 +14,7 @@ import (
 	_ "github.com/jitsucom/jitsu/server/drivers/google_play"
 	_ "github.com/jitsucom/jitsu/server/drivers/redis"
 	_ "github.com/jitsucom/jitsu/server/drivers/singer"
+	_ "github.com/jitsucom/jitsu/server/drivers/sql_query"
 	"github.com/jitsucom/jitsu/server/jsonutils"
 	"github.com/jitsucom/jitsu/server/logging"
 	"github.com/jitsucom/jitsu/server/scheduling"
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"github.com/iancoleman/strcase"
 	"github.com/jitsucom/jitsu/server/appconfig"
+	"github.com/jitsucom/jitsu/server/credentials"
 	"github.com/jitsucom/jitsu/server/drivers/base"
 	"github.com/jitsucom/jitsu/server/httputils"
 	"github.com/jitsucom/jitsu/server/jsonutils"
@@ -291,6 +292,13 @@ func query(config *GoogleAdsConfig, httpClient *http.Client, query string) ([]ma
 }
 
 func acquireAccessToken(config *GoogleAdsConfig, httpClient *http.Client) (string, error) {
+	if config.AuthConfig.CredentialID != "" {
+		token, err := credentials.Token(config.AuthConfig.CredentialID)
+		if err != nil {
+			return "", err
+		}
+		return token.AccessToken, nil
+	}
 	if config.AuthConfig.Type != base.GoogleOAuthAuthorizationType && config.AuthConfig.Subject == "" {
 		return "", fmt.Errorf("'subject' is required. Subject – a Google Ads user with permissions on the Google Ads account you want to access. Google Ads does not support using service accounts without impersonation.")
 	}
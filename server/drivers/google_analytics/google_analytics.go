@@ -9,7 +9,6 @@ import (
 	"github.com/jitsucom/jitsu/server/logging"
 	"github.com/jitsucom/jitsu/server/typing"
 	ga "google.golang.org/api/analyticsreporting/v4"
-	"google.golang.org/api/option"
 	"strings"
 	"time"
 )
@@ -83,11 +82,11 @@ func NewGoogleAnalytics(ctx context.Context, sourceConfig *base.SourceConfig, co
 		return nil, errors.New("metrics and dimensions must not be empty")
 	}
 
-	credentialsJSON, err := config.AuthConfig.Marshal()
+	credentialsOption, err := config.AuthConfig.ClientOption()
 	if err != nil {
 		return nil, err
 	}
-	service, err := ga.NewService(ctx, option.WithCredentialsJSON(credentialsJSON))
+	service, err := ga.NewService(ctx, credentialsOption)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create GA service: %v", err)
 	}
@@ -112,11 +111,11 @@ func TestGoogleAnalytics(sourceConfig *base.SourceConfig) error {
 		return err
 	}
 
-	credentialsJSON, err := config.AuthConfig.Marshal()
+	credentialsOption, err := config.AuthConfig.ClientOption()
 	if err != nil {
 		return err
 	}
-	service, err := ga.NewService(context.Background(), option.WithCredentialsJSON(credentialsJSON))
+	service, err := ga.NewService(context.Background(), credentialsOption)
 	if err != nil {
 		return fmt.Errorf("failed to create GA service: %v", err)
 	}
@@ -68,11 +68,11 @@ func NewGooglePlay(ctx context.Context, sourceConfig *base.SourceConfig, collect
 		return nil, err
 	}
 
-	credentialsJSON, err := config.AccountKey.Marshal()
+	credentialsOption, err := config.AccountKey.ClientOption()
 	if err != nil {
 		return nil, err
 	}
-	client, err := storage.NewClient(ctx, option.WithCredentialsJSON(credentialsJSON),
+	client, err := storage.NewClient(ctx, credentialsOption,
 		option.WithScopes("https://www.googleapis.com/auth/devstorage.read_only"))
 	if err != nil {
 		return nil, fmt.Errorf("GooglePlay error creating google cloud storage client: %v", err)
@@ -98,13 +98,13 @@ func TestGooglePlay(sourceConfig *base.SourceConfig) error {
 		return err
 	}
 
-	credentialsJSON, err := config.AccountKey.Marshal()
+	credentialsOption, err := config.AccountKey.ClientOption()
 	if err != nil {
 		return err
 	}
 
 	client, err := storage.NewClient(context.Background(),
-		option.WithCredentialsJSON(credentialsJSON),
+		credentialsOption,
 		option.WithScopes("https://www.googleapis.com/auth/devstorage.read_only"))
 	if err != nil {
 		return fmt.Errorf("GooglePlay error creating google cloud storage client: %v", err)
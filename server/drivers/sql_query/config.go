@@ -0,0 +1,60 @@
+package sql_query
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jitsucom/jitsu/server/adapters"
+)
+
+//SQLQueryConfig is a SQL query source configuration dto for serialization
+type SQLQueryConfig struct {
+	//DestinationID is an ID of SQL destination (Postgres, Redshift, ClickHouse, Snowflake, BigQuery) where queries are executed
+	DestinationID string `mapstructure:"destination_id" json:"destination_id,omitempty" yaml:"destination_id,omitempty"`
+}
+
+//Validate returns err if configuration is invalid
+func (sqc *SQLQueryConfig) Validate() error {
+	if sqc == nil {
+		return errors.New("SQL query config is required")
+	}
+	if sqc.DestinationID == "" {
+		return errors.New("'destination_id' is required")
+	}
+	return nil
+}
+
+//SQLQueryParameters is a SQL query collection configuration dto for serialization
+type SQLQueryParameters struct {
+	Query string `mapstructure:"query" json:"query,omitempty" yaml:"query,omitempty"`
+	//PrimaryKey columns identify rows between syncs. If empty, a changed row is sent as a new one
+	PrimaryKey []string `mapstructure:"primary_key" json:"primary_key,omitempty" yaml:"primary_key,omitempty"`
+	//EventType is an event_type of sent rows. Default value is the collection name
+	EventType string `mapstructure:"event_type" json:"event_type,omitempty" yaml:"event_type,omitempty"`
+	//ObjectPath is a JSON path in the event where row columns are put (e.g. /user). Default value is the event root
+	ObjectPath string `mapstructure:"object_path" json:"object_path,omitempty" yaml:"object_path,omitempty"`
+	//PageSize is a number of rows which are stored into destinations at once. Default value is 10000
+	PageSize int `mapstructure:"page_size" json:"page_size,omitempty" yaml:"page_size,omitempty"`
+}
+
+//Validate returns err if configuration is invalid
+func (sqp *SQLQueryParameters) Validate() error {
+	if sqp == nil {
+		return errors.New("'parameters' configuration section is required")
+	}
+	if sqp.Query == "" {
+		return errors.New("'query' is required")
+	}
+	if err := adapters.CheckSelectQuery(sqp.Query); err != nil {
+		return fmt.Errorf("'query' is invalid: %v", err)
+	}
+	if sqp.PageSize < 0 {
+		return errors.New("'page_size' must be positive")
+	}
+	for _, column := range sqp.PrimaryKey {
+		if column == "" {
+			return errors.New("'primary_key' columns can't be empty")
+		}
+	}
+	return nil
+}
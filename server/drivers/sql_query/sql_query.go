@@ -0,0 +1,190 @@
+package sql_query
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/drivers/base"
+	"github.com/jitsucom/jitsu/server/events"
+	"github.com/jitsucom/jitsu/server/jsonutils"
+	"github.com/jitsucom/jitsu/server/uuid"
+)
+
+const (
+	testConnectionQuery = "SELECT 1"
+	defaultPageSize     = 10000
+)
+
+//ErrQueriesAreNotAvailable is returned if source config doesn't have base.Querier
+var ErrQueriesAreNotAvailable = errors.New("SQL queries in destinations aren't available")
+
+//SQLQuery is a driver for syncing SQL query results from SQL destinations (warehouses) to other destinations (reverse ETL)
+//rows are compared with the previous sync: only new and changed rows are sent to HTTP destinations
+type SQLQuery struct {
+	base.IntervalDriver
+
+	collection    *base.Collection
+	querier       base.Querier
+	destinationID string
+	parameters    *SQLQueryParameters
+	objectPath    jsonutils.JSONPath
+}
+
+func init() {
+	base.RegisterDriver(base.SQLQueryType, NewSQLQuery)
+	base.RegisterTestConnectionFunc(base.SQLQueryType, TestSQLQuery)
+}
+
+//NewSQLQuery returns configured SQL query driver instance
+func NewSQLQuery(_ context.Context, sourceConfig *base.SourceConfig, collection *base.Collection) (base.Driver, error) {
+	if sourceConfig.Querier == nil {
+		return nil, ErrQueriesAreNotAvailable
+	}
+
+	config := &SQLQueryConfig{}
+	if err := jsonutils.UnmarshalConfig(sourceConfig.Config, config); err != nil {
+		return nil, err
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	parameters := &SQLQueryParameters{}
+	if err := jsonutils.UnmarshalConfig(collection.Parameters, parameters); err != nil {
+		return nil, err
+	}
+	if err := parameters.Validate(); err != nil {
+		return nil, err
+	}
+	if parameters.EventType == "" {
+		parameters.EventType = collection.Name
+	}
+	if parameters.PageSize == 0 {
+		parameters.PageSize = defaultPageSize
+	}
+
+	return &SQLQuery{
+		IntervalDriver: base.IntervalDriver{SourceType: sourceConfig.Type},
+		collection:     collection,
+		querier:        sourceConfig.Querier,
+		destinationID:  config.DestinationID,
+		parameters:     parameters,
+		objectPath:     jsonutils.NewJSONPath(parameters.ObjectPath),
+	}, nil
+}
+
+//TestSQLQuery tests that SQL destination is available for queries without creating Driver instance
+func TestSQLQuery(sourceConfig *base.SourceConfig) error {
+	config := &SQLQueryConfig{}
+	if err := jsonutils.UnmarshalConfig(sourceConfig.Config, config); err != nil {
+		return err
+	}
+	if err := config.Validate(); err != nil {
+		return err
+	}
+
+	if sourceConfig.Querier == nil {
+		return ErrQueriesAreNotAvailable
+	}
+
+	return sourceConfig.Querier.Query(config.DestinationID, testConnectionQuery, func(row map[string]interface{}) error { return nil })
+}
+
+//GetRefreshWindow returns 0 because the query result is always synced as a whole
+func (sq *SQLQuery) GetRefreshWindow() (time.Duration, error) {
+	return 0, nil
+}
+
+//GetAllAvailableIntervals returns ALL constant
+func (sq *SQLQuery) GetAllAvailableIntervals() ([]*base.TimeInterval, error) {
+	return []*base.TimeInterval{base.NewTimeInterval(base.ALL, time.Time{})}, nil
+}
+
+//GetObjectsFor executes the query and returns all rows as events with configured event_type
+//StreamObjectsFor is used in synchronization for not loading all rows into memory
+func (sq *SQLQuery) GetObjectsFor(interval *base.TimeInterval) ([]map[string]interface{}, error) {
+	var objects []map[string]interface{}
+	if err := sq.StreamObjectsFor(interval, func(page []map[string]interface{}) error {
+		objects = append(objects, page...)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return objects, nil
+}
+
+//StreamObjectsFor executes the query and passes rows as events with configured event_type to consume
+//in pages of the configured page_size
+func (sq *SQLQuery) StreamObjectsFor(interval *base.TimeInterval, consume func(objects []map[string]interface{}) error) error {
+	page := make([]map[string]interface{}, 0, sq.parameters.PageSize)
+	if err := sq.querier.Query(sq.destinationID, sq.parameters.Query, func(row map[string]interface{}) error {
+		object := row
+		if !sq.objectPath.IsEmpty() {
+			object = map[string]interface{}{}
+			if err := sq.objectPath.Set(object, row); err != nil {
+				return fmt.Errorf("Error setting row into %s: %v", sq.objectPath.String(), err)
+			}
+		}
+		object[events.EventType] = sq.parameters.EventType
+		page = append(page, object)
+
+		if len(page) < sq.parameters.PageSize {
+			return nil
+		}
+
+		err := consume(page)
+		page = make([]map[string]interface{}, 0, sq.parameters.PageSize)
+		return err
+	}); err != nil {
+		return err
+	}
+
+	if len(page) == 0 {
+		return nil
+	}
+
+	return consume(page)
+}
+
+//GetObjectKey returns joined primary key column values or hash of the whole object if primary key isn't configured
+func (sq *SQLQuery) GetObjectKey(object map[string]interface{}) string {
+	if len(sq.parameters.PrimaryKey) == 0 {
+		return uuid.GetHash(object)
+	}
+
+	row := object
+	if !sq.objectPath.IsEmpty() {
+		value, _ := sq.objectPath.Get(object)
+		row, _ = value.(map[string]interface{})
+	}
+
+	values := make([]string, len(sq.parameters.PrimaryKey))
+	for i, column := range sq.parameters.PrimaryKey {
+		values[i] = fmt.Sprint(row[column])
+	}
+	return strings.Join(values, "|")
+}
+
+//Type returns SQL query type
+func (sq *SQLQuery) Type() string {
+	return base.SQLQueryType
+}
+
+//GetCollectionTable returns collection table
+func (sq *SQLQuery) GetCollectionTable() string {
+	return sq.collection.GetTableName()
+}
+
+//GetCollectionMetaKey returns collection meta key (key is used in meta storage)
+func (sq *SQLQuery) GetCollectionMetaKey() string {
+	return sq.collection.Name + "_" + sq.GetCollectionTable()
+}
+
+//Close does nothing because queries are executed with destination connections
+func (sq *SQLQuery) Close() error {
+	return nil
+}
@@ -0,0 +1,119 @@
+package sql_query
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/drivers/base"
+	"github.com/stretchr/testify/require"
+)
+
+//querierMock returns rows for queries executed in the destination
+type querierMock struct {
+	destinationID string
+	query         string
+	rows          []map[string]interface{}
+}
+
+func (qm *querierMock) Query(destinationID, query string, consume func(row map[string]interface{}) error) error {
+	if destinationID != qm.destinationID || query != qm.query {
+		return fmt.Errorf("unexpected query [%s] in [%s] destination", query, destinationID)
+	}
+
+	//every query returns new row objects
+	for _, row := range qm.rows {
+		rowCopy := map[string]interface{}{}
+		for k, v := range row {
+			rowCopy[k] = v
+		}
+		if err := consume(rowCopy); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestGetObjectsFor(t *testing.T) {
+	querier := &querierMock{
+		destinationID: "warehouse",
+		query:         "SELECT email, plan FROM users",
+		rows:          []map[string]interface{}{{"email": "a@b.com", "plan": "pro"}, {"email": "c@d.com", "plan": "free"}},
+	}
+
+	tests := []struct {
+		name            string
+		parameters      map[string]interface{}
+		expectedObjects []map[string]interface{}
+		expectedKeys    []string
+		expectedPages   int
+	}{
+		{
+			"Default event type",
+			map[string]interface{}{"query": "SELECT email, plan FROM users", "primary_key": []string{"email"}},
+			[]map[string]interface{}{{"email": "a@b.com", "plan": "pro", "event_type": "users"}, {"email": "c@d.com", "plan": "free", "event_type": "users"}},
+			[]string{"a@b.com", "c@d.com"},
+			1,
+		},
+		{
+			"Object path",
+			map[string]interface{}{"query": "SELECT email, plan FROM users", "primary_key": []string{"email", "plan"}, "event_type": "user_identify", "object_path": "/user"},
+			[]map[string]interface{}{{"user": map[string]interface{}{"email": "a@b.com", "plan": "pro"}, "event_type": "user_identify"},
+				{"user": map[string]interface{}{"email": "c@d.com", "plan": "free"}, "event_type": "user_identify"}},
+			[]string{"a@b.com|pro", "c@d.com|free"},
+			1,
+		},
+		{
+			"Pages",
+			map[string]interface{}{"query": "SELECT email, plan FROM users", "primary_key": []string{"email"}, "page_size": 1},
+			[]map[string]interface{}{{"email": "a@b.com", "plan": "pro", "event_type": "users"}, {"email": "c@d.com", "plan": "free", "event_type": "users"}},
+			[]string{"a@b.com", "c@d.com"},
+			2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			driver, err := NewSQLQuery(context.Background(), &base.SourceConfig{SourceID: "reverse_etl", Type: base.SQLQueryType, Config: map[string]interface{}{"destination_id": "warehouse"}, Querier: querier},
+				&base.Collection{SourceID: "reverse_etl", Name: "users", Parameters: tt.parameters})
+			require.NoError(t, err)
+
+			intervals, err := driver.GetAllAvailableIntervals()
+			require.NoError(t, err)
+			objects, err := driver.GetObjectsFor(intervals[0])
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedObjects, objects)
+
+			for i, object := range objects {
+				require.Equal(t, tt.expectedKeys[i], driver.(base.DiffDriver).GetObjectKey(object))
+			}
+
+			var pages [][]map[string]interface{}
+			require.NoError(t, driver.(base.StreamingDriver).StreamObjectsFor(intervals[0], func(objects []map[string]interface{}) error {
+				pages = append(pages, objects)
+				return nil
+			}))
+			require.Len(t, pages, tt.expectedPages)
+		})
+	}
+}
+
+func TestNewSQLQueryErrors(t *testing.T) {
+	querier := &querierMock{destinationID: "warehouse", query: testConnectionQuery}
+
+	_, err := NewSQLQuery(context.Background(), &base.SourceConfig{Config: map[string]interface{}{"destination_id": "warehouse"}},
+		&base.Collection{Name: "users", Parameters: map[string]interface{}{"query": "SELECT email FROM users"}})
+	require.Equal(t, ErrQueriesAreNotAvailable, err)
+
+	_, err = NewSQLQuery(context.Background(), &base.SourceConfig{Config: map[string]interface{}{}, Querier: querier}, &base.Collection{Name: "users"})
+	require.EqualError(t, err, "'destination_id' is required")
+
+	_, err = NewSQLQuery(context.Background(), &base.SourceConfig{Config: map[string]interface{}{"destination_id": "warehouse"}, Querier: querier}, &base.Collection{Name: "users"})
+	require.EqualError(t, err, "'query' is required")
+
+	_, err = NewSQLQuery(context.Background(), &base.SourceConfig{Config: map[string]interface{}{"destination_id": "warehouse"}, Querier: querier},
+		&base.Collection{Name: "users", Parameters: map[string]interface{}{"query": "DELETE FROM users"}})
+	require.EqualError(t, err, "'query' is invalid: Only SELECT queries are allowed")
+
+	require.Equal(t, ErrQueriesAreNotAvailable, TestSQLQuery(&base.SourceConfig{Config: map[string]interface{}{"destination_id": "warehouse"}}))
+	require.NoError(t, TestSQLQuery(&base.SourceConfig{Config: map[string]interface{}{"destination_id": "warehouse"}, Querier: querier}))
+}
@@ -8,6 +8,8 @@ import (
 const (
 	//SrcKey is a system field
 	SrcKey = "src"
+	//SourceSrc is a SrcKey value of objects which are synchronized from sources
+	SourceSrc = "source"
 	//TimeChunkKey is a system field
 	TimeChunkKey      = "_time_interval"
 	timeIntervalStart = "_interval_start"
@@ -13,7 +13,10 @@ import (
 
 const eventsPerPersistedFile = 2000
 
-var ErrQueueClosed = errors.New("queue is closed")
+var (
+	ErrQueueClosed = errors.New("queue is closed")
+	ErrQueueEmpty  = errors.New("queue is empty")
+)
 
 type QueuedEvent struct {
 	FactBytes    []byte
@@ -72,6 +75,27 @@ func (pq *PersistentQueue) DequeueBlock() (Event, time.Time, string, error) {
 		return nil, time.Time{}, "", err
 	}
 
+	return pq.unwrap(iface)
+}
+
+//Dequeue returns the next event without waiting. Returns ErrQueueEmpty if there are no events
+func (pq *PersistentQueue) Dequeue() (Event, time.Time, string, error) {
+	iface, err := pq.queue.Dequeue()
+	if err != nil {
+		switch err {
+		case dque.ErrEmpty:
+			err = ErrQueueEmpty
+		case dque.ErrQueueClosed:
+			err = ErrQueueClosed
+		}
+		return nil, time.Time{}, "", err
+	}
+
+	return pq.unwrap(iface)
+}
+
+//unwrap returns event, dequeued time and token ID of dequeued object
+func (pq *PersistentQueue) unwrap(iface interface{}) (Event, time.Time, string, error) {
 	metrics.DequeuedEvent(pq.identifier)
 
 	wrappedFact, ok := iface.(*QueuedEvent)
@@ -0,0 +1,44 @@
+package events
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPersistentQueueDequeue(t *testing.T) {
+	dir, err := ioutil.TempDir("", "persistent_queue")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	queue, err := NewPersistentQueue("test", "queue.dst=test", dir)
+	require.NoError(t, err)
+
+	_, _, _, err = queue.Dequeue()
+	require.Equal(t, ErrQueueEmpty, err)
+
+	retryTime := time.Now().Add(time.Minute).Round(time.Second)
+	queue.Consume(Event{"id": "1"}, "token1")
+	queue.ConsumeTimed(Event{"id": "2"}, retryTime, "token2")
+
+	fact, _, tokenID, err := queue.Dequeue()
+	require.NoError(t, err)
+	require.Equal(t, "1", fact["id"])
+	require.Equal(t, "token1", tokenID)
+
+	fact, dequeuedTime, tokenID, err := queue.Dequeue()
+	require.NoError(t, err)
+	require.Equal(t, "2", fact["id"])
+	require.Equal(t, "token2", tokenID)
+	require.True(t, retryTime.Equal(dequeuedTime))
+
+	_, _, _, err = queue.Dequeue()
+	require.Equal(t, ErrQueueEmpty, err)
+
+	require.NoError(t, queue.Close())
+	_, _, _, err = queue.Dequeue()
+	require.Equal(t, ErrQueueClosed, err)
+}
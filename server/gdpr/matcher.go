@@ -0,0 +1,90 @@
+package gdpr
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/jitsucom/jitsu/server/jsonutils"
+)
+
+//fallbackEventField is a field of fallback log records with the original event
+const fallbackEventField = "event"
+
+//userMatcher matches events of a user: identifiers are looked up only in identification JSON paths
+//and anonymous ids only in anonymous id JSON paths
+type userMatcher struct {
+	identificationPaths []jsonutils.JSONPath
+	anonymousIDPaths    []jsonutils.JSONPath
+
+	identifiers  map[string]bool
+	anonymousIDs map[string]bool
+}
+
+//newUserMatcher returns userMatcher with configured JSON paths
+func (s *Service) newUserMatcher(identifiers, anonymousIDs []string) *userMatcher {
+	matcher := &userMatcher{
+		identificationPaths: append([]jsonutils.JSONPath{}, s.identificationPaths...),
+		anonymousIDPaths:    append([]jsonutils.JSONPath{}, s.anonymousIDPaths...),
+		identifiers:         map[string]bool{},
+		anonymousIDs:        map[string]bool{},
+	}
+	for _, identifier := range identifiers {
+		matcher.identifiers[identifier] = true
+	}
+	for _, anonymousID := range anonymousIDs {
+		matcher.anonymousIDs[anonymousID] = true
+	}
+
+	return matcher
+}
+
+//Match returns true if the event contains one of the user values in the corresponding JSON paths
+func (um *userMatcher) Match(event map[string]interface{}) bool {
+	return matchPaths(event, um.identificationPaths, um.identifiers) || matchPaths(event, um.anonymousIDPaths, um.anonymousIDs)
+}
+
+//MatchJSON returns true if JSON event or fallback record ({"event": {...}, "error": "..."}) matches
+func (um *userMatcher) MatchJSON(payload []byte) bool {
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+	event := map[string]interface{}{}
+	if err := decoder.Decode(&event); err != nil {
+		return false
+	}
+
+	if um.Match(event) {
+		return true
+	}
+
+	if original, ok := event[fallbackEventField].(map[string]interface{}); ok {
+		return um.Match(original)
+	}
+
+	return false
+}
+
+func matchPaths(event map[string]interface{}, paths []jsonutils.JSONPath, values map[string]bool) bool {
+	if len(values) == 0 {
+		return false
+	}
+
+	for _, path := range paths {
+		value, ok := path.Get(event)
+		if !ok {
+			continue
+		}
+
+		switch v := value.(type) {
+		case string:
+			if values[v] {
+				return true
+			}
+		case json.Number:
+			if values[v.String()] {
+				return true
+			}
+		}
+	}
+
+	return false
+}
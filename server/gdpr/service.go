@@ -0,0 +1,365 @@
+package gdpr
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/destinations"
+	"github.com/jitsucom/jitsu/server/jsonutils"
+	"github.com/jitsucom/jitsu/server/logfiles"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/meta"
+	"github.com/jitsucom/jitsu/server/safego"
+	"github.com/jitsucom/jitsu/server/storages"
+	"github.com/jitsucom/jitsu/server/timestamp"
+	"github.com/jitsucom/jitsu/server/uuid"
+)
+
+//Job and step statuses
+const (
+	RunningStatus = "RUNNING"
+	DoneStatus    = "DONE"
+	FailedStatus  = "FAILED"
+	SkippedStatus = "SKIPPED"
+)
+
+var (
+	//ErrMetaStorageRequired is returned when meta storage isn't configured (jobs can't be tracked)
+	ErrMetaStorageRequired = errors.New("meta.storage configuration is required for user data deletion")
+	//ErrEmptyRequest is returned when request doesn't contain any identifiers
+	ErrEmptyRequest = errors.New("identifiers or anonymous_ids are required")
+)
+
+//DeletionRequest is a dto for user data deletion (right to be forgotten) request
+type DeletionRequest struct {
+	//Identifiers are identification values of a user (e.g. internal user id or email)
+	Identifiers []string `json:"identifiers,omitempty"`
+	//AnonymousIDs are anonymous ids (cookies) of a user. Anonymous ids linked with Identifiers are resolved automatically
+	AnonymousIDs []string `json:"anonymous_ids,omitempty"`
+	//DestinationIDs limits deletion to these destinations (all destinations by default)
+	DestinationIDs []string `json:"destination_ids,omitempty"`
+	//Tables limits deletion to these tables (all tables known by destinations by default)
+	Tables []string `json:"tables,omitempty"`
+}
+
+//Service deletes user data from destinations, meta storage and log files (fallback and archive)
+//every request is tracked as a meta.DeletionJob with per destination status
+type Service struct {
+	metaStorage        meta.Storage
+	destinationService *destinations.Service
+
+	fallbackDir string
+	archiveDir  string
+
+	anonymousIDFields      []string
+	identificationIDFields []string
+	anonymousIDPaths       []jsonutils.JSONPath
+	identificationPaths    []jsonutils.JSONPath
+
+	//logs files are rewritten by one job at a time
+	logsMutex sync.Mutex
+}
+
+//NewService returns configured Service
+//anonymousIDNode and identificationNodes are JSON paths (with || alternatives) of user identifiers in events
+func NewService(logEventPath string, metaStorage meta.Storage, destinationService *destinations.Service, anonymousIDNode string, identificationNodes []string) *Service {
+	return &Service{
+		metaStorage:            metaStorage,
+		destinationService:     destinationService,
+		fallbackDir:            path.Join(logEventPath, logging.FailedDir),
+		archiveDir:             path.Join(logEventPath, logging.ArchiveDir),
+		anonymousIDFields:      toFieldNames(anonymousIDNode),
+		identificationIDFields: toFieldNames(identificationNodes...),
+		anonymousIDPaths:       toJSONPaths(anonymousIDNode),
+		identificationPaths:    toJSONPaths(identificationNodes...),
+	}
+}
+
+//CreateJob validates request, saves a new deletion job and runs it in background
+func (s *Service) CreateJob(req *DeletionRequest) (*meta.DeletionJob, error) {
+	if s.metaStorage.Type() == meta.DummyType {
+		return nil, ErrMetaStorageRequired
+	}
+
+	req.Identifiers = nonEmpty(req.Identifiers)
+	req.AnonymousIDs = nonEmpty(req.AnonymousIDs)
+	if len(req.Identifiers) == 0 && len(req.AnonymousIDs) == 0 {
+		return nil, ErrEmptyRequest
+	}
+
+	destinationIDs := req.DestinationIDs
+	if len(destinationIDs) == 0 {
+		destinationIDs = s.destinationService.GetAllDestinationIDs()
+	}
+
+	job := &meta.DeletionJob{
+		ID:           uuid.New(),
+		Status:       RunningStatus,
+		CreatedAt:    timestamp.NowUTC(),
+		Identifiers:  req.Identifiers,
+		AnonymousIDs: req.AnonymousIDs,
+		Destinations: map[string]*meta.DeletionJobResult{},
+		MetaStorage:  &meta.DeletionJobResult{Status: RunningStatus},
+		Logs:         &meta.DeletionJobResult{Status: RunningStatus},
+	}
+	for _, destinationID := range destinationIDs {
+		job.Destinations[destinationID] = &meta.DeletionJobResult{Status: RunningStatus}
+	}
+
+	if err := s.metaStorage.SaveDeletionJob(job); err != nil {
+		return nil, fmt.Errorf("Error saving deletion job: %v", err)
+	}
+
+	//job is updated in background: run with a copy
+	runningJob := job.Copy()
+	safego.Run(func() {
+		s.run(runningJob, req.Tables)
+	})
+
+	return job, nil
+}
+
+//GetJob returns deletion job by ID
+func (s *Service) GetJob(jobID string) (*meta.DeletionJob, error) {
+	return s.metaStorage.GetDeletionJob(jobID)
+}
+
+//GetAllJobs returns all deletion jobs
+func (s *Service) GetAllJobs() ([]meta.DeletionJob, error) {
+	return s.metaStorage.GetAllDeletionJobs()
+}
+
+//run deletes data from every destination, then from meta storage and log files
+//job status is saved after every step
+func (s *Service) run(job *meta.DeletionJob, tables []string) {
+	logging.Infof("[gdpr] Running user data deletion job [%s]", job.ID)
+
+	//matches user events with anonymous ids from all destinations in log files
+	logsMatcher := s.newUserMatcher(job.Identifiers, job.AnonymousIDs)
+	matchersByDestination := map[string]*userMatcher{}
+	for destinationID, result := range job.Destinations {
+		anonymousIDs := s.resolveAnonymousIDs(destinationID, job.Identifiers, job.AnonymousIDs)
+		matcher := s.newUserMatcher(job.Identifiers, anonymousIDs)
+
+		storage, err := s.getStorage(destinationID)
+		if err != nil {
+			setResult(result, err)
+		} else {
+			if recognition := storage.GetUsersRecognition(); recognition.IsEnabled() {
+				matcher.anonymousIDPaths = append(matcher.anonymousIDPaths, recognition.AnonymousIDJSONPath)
+				logsMatcher.anonymousIDPaths = append(logsMatcher.anonymousIDPaths, recognition.AnonymousIDJSONPath)
+			}
+			s.deleteFromDestination(storage, job.Identifiers, anonymousIDs, tables, result)
+		}
+		s.saveJob(job)
+
+		matchersByDestination[destinationID] = matcher
+		for _, anonymousID := range anonymousIDs {
+			logsMatcher.anonymousIDs[anonymousID] = true
+		}
+	}
+
+	setResult(job.MetaStorage, s.deleteFromMetaStorage(job.Identifiers, matchersByDestination))
+	s.saveJob(job)
+
+	setResult(job.Logs, s.deleteFromLogs(logsMatcher))
+
+	job.Status = DoneStatus
+	for _, result := range job.Destinations {
+		if result.Status == FailedStatus {
+			job.Status = FailedStatus
+		}
+	}
+	if job.MetaStorage.Status == FailedStatus || job.Logs.Status == FailedStatus {
+		job.Status = FailedStatus
+	}
+	job.FinishedAt = timestamp.NowUTC()
+	s.saveJob(job)
+
+	logging.Infof("[gdpr] User data deletion job [%s] has been finished with status: %s", job.ID, job.Status)
+}
+
+//resolveAnonymousIDs returns input anonymous ids + anonymous ids which were linked with identifiers by users recognition
+func (s *Service) resolveAnonymousIDs(destinationID string, identifiers, anonymousIDs []string) []string {
+	resolved := map[string]bool{}
+	for _, anonymousID := range anonymousIDs {
+		resolved[anonymousID] = true
+	}
+
+	for _, identifier := range identifiers {
+		linked, err := s.metaStorage.GetLinkedAnonymousIDs(destinationID, identifier)
+		if err != nil {
+			logging.SystemErrorf("[%s] Error getting anonymous ids linked with identifier: %v", destinationID, err)
+			continue
+		}
+		for _, anonymousID := range linked {
+			resolved[anonymousID] = true
+		}
+	}
+
+	result := make([]string, 0, len(resolved))
+	for anonymousID := range resolved {
+		result = append(result, anonymousID)
+	}
+
+	return result
+}
+
+//getStorage returns initialized destination storage
+func (s *Service) getStorage(destinationID string) (storages.Storage, error) {
+	storageProxy, ok := s.destinationService.GetDestinationByID(destinationID)
+	if !ok {
+		return nil, fmt.Errorf("Destination [%s] wasn't found", destinationID)
+	}
+
+	storage, ok := storageProxy.Get()
+	if !ok {
+		return nil, fmt.Errorf("Destination [%s] hasn't been initialized yet", destinationID)
+	}
+
+	return storage, nil
+}
+
+func (s *Service) deleteFromDestination(storage storages.Storage, identifiers, anonymousIDs, tables []string, result *meta.DeletionJobResult) {
+	anonymousIDFields := s.anonymousIDFields
+	if recognition := storage.GetUsersRecognition(); recognition.IsEnabled() {
+		anonymousIDFields = appendUnique(anonymousIDFields, recognition.AnonymousIDJSONPath.FieldName())
+	}
+
+	deleteConditions := &adapters.DeleteConditions{JoinCondition: "OR"}
+	for _, field := range s.identificationIDFields {
+		deleteConditions.Conditions = append(deleteConditions.Conditions, adapters.DeleteByFieldValuesCondition(field, identifiers).Conditions...)
+	}
+	for _, field := range anonymousIDFields {
+		deleteConditions.Conditions = append(deleteConditions.Conditions, adapters.DeleteByFieldValuesCondition(field, anonymousIDs).Conditions...)
+	}
+
+	err := storage.DeleteUserData(tables, deleteConditions)
+	if err == storages.ErrDeletionIsNotSupported {
+		result.Status = SkippedStatus
+		result.Error = err.Error()
+		return
+	}
+
+	if err != nil {
+		logging.Errorf("[%s] Error deleting user data: %v", storage.ID(), err)
+	}
+	setResult(result, err)
+}
+
+func (s *Service) deleteFromMetaStorage(identifiers []string, matchersByDestination map[string]*userMatcher) (multiErr error) {
+	for destinationID, matcher := range matchersByDestination {
+		for anonymousID := range matcher.anonymousIDs {
+			if err := s.metaStorage.DeleteAnonymousEvents(destinationID, anonymousID); err != nil {
+				multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error deleting anonymous events: %v", destinationID, err))
+			}
+		}
+
+		for _, identifier := range identifiers {
+			if err := s.metaStorage.DeleteLinkedAnonymousIDs(destinationID, identifier); err != nil {
+				multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error deleting linked anonymous ids: %v", destinationID, err))
+			}
+		}
+
+		deleted, err := s.metaStorage.DeleteEvents(destinationID, func(event *meta.Event) bool {
+			return matcher.MatchJSON([]byte(event.Original))
+		})
+		if err != nil {
+			multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error deleting cached events: %v", destinationID, err))
+		} else if deleted > 0 {
+			logging.Infof("[%s] Deleted %d cached events", destinationID, deleted)
+		}
+	}
+
+	return multiErr
+}
+
+func (s *Service) deleteFromLogs(matcher *userMatcher) (multiErr error) {
+	s.logsMutex.Lock()
+	defer s.logsMutex.Unlock()
+
+	for _, dir := range []string{s.fallbackDir, s.archiveDir} {
+		erased, err := logfiles.EraseLines(dir, matcher.MatchJSON)
+		if err != nil {
+			multiErr = multierror.Append(multiErr, err)
+		}
+		if erased > 0 {
+			logging.Infof("[gdpr] Erased %d lines from log files in %s", erased, dir)
+		}
+	}
+
+	return multiErr
+}
+
+func (s *Service) saveJob(job *meta.DeletionJob) {
+	if err := s.metaStorage.SaveDeletionJob(job); err != nil {
+		logging.SystemErrorf("Error saving deletion job [%s]: %v", job.ID, err)
+	}
+}
+
+func setResult(result *meta.DeletionJobResult, err error) {
+	if err != nil {
+		result.Status = FailedStatus
+		result.Error = err.Error()
+		return
+	}
+
+	result.Status = DoneStatus
+	result.Error = ""
+}
+
+//toFieldNames returns flat field names of all JSON paths (including || alternatives)
+func toFieldNames(nodes ...string) []string {
+	var fields []string
+	for _, node := range nodes {
+		for _, path := range strings.Split(node, "||") {
+			if strings.TrimSpace(path) == "" {
+				continue
+			}
+			fields = appendUnique(fields, jsonutils.NewSingleJSONPath(path).FieldName())
+		}
+	}
+
+	return fields
+}
+
+//toJSONPaths returns single JSON paths of all nodes (including || alternatives)
+func toJSONPaths(nodes ...string) []jsonutils.JSONPath {
+	var paths []jsonutils.JSONPath
+	for _, node := range nodes {
+		for _, path := range strings.Split(node, "||") {
+			if strings.TrimSpace(path) == "" {
+				continue
+			}
+			paths = append(paths, jsonutils.NewSingleJSONPath(strings.TrimSpace(path)))
+		}
+	}
+
+	return paths
+}
+
+func appendUnique(fields []string, field string) []string {
+	for _, f := range fields {
+		if f == field {
+			return fields
+		}
+	}
+
+	return append(fields, field)
+}
+
+func nonEmpty(values []string) []string {
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		if strings.TrimSpace(value) != "" {
+			result = append(result, value)
+		}
+	}
+
+	return result
+}
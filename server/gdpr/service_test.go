@@ -0,0 +1,233 @@
+package gdpr
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/destinations"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/meta"
+	"github.com/jitsucom/jitsu/server/storages"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testAnonymousIDNode = "/eventn_ctx/user/anonymous_id||/user/anonymous_id"
+	testIdentifierNode  = "/eventn_ctx/user/email||/user/email"
+)
+
+//metaStorageMock keeps deletion jobs and cached events in memory
+type metaStorageMock struct {
+	meta.Dummy
+
+	mutex         sync.Mutex
+	jobs          map[string]string
+	linked        map[string][]string
+	cachedEvents  map[string][]*meta.Event
+	deletedEvents map[string][]string
+}
+
+func (msm *metaStorageMock) Type() string { return meta.RedisType }
+
+func (msm *metaStorageMock) SaveDeletionJob(job *meta.DeletionJob) error {
+	msm.mutex.Lock()
+	defer msm.mutex.Unlock()
+	msm.jobs[job.ID] = job.Marshal()
+	return nil
+}
+
+func (msm *metaStorageMock) job(jobID string) string {
+	msm.mutex.Lock()
+	defer msm.mutex.Unlock()
+	return msm.jobs[jobID]
+}
+
+func (msm *metaStorageMock) GetLinkedAnonymousIDs(destinationID, identifier string) ([]string, error) {
+	return msm.linked[identifier], nil
+}
+
+func (msm *metaStorageMock) DeleteEvents(destinationID string, match func(event *meta.Event) bool) (int, error) {
+	msm.mutex.Lock()
+	defer msm.mutex.Unlock()
+	deleted := 0
+	for _, event := range msm.cachedEvents[destinationID] {
+		if match(event) {
+			msm.deletedEvents[destinationID] = append(msm.deletedEvents[destinationID], event.Original)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+//storageMock records delete conditions
+type storageMock struct {
+	storages.Storage
+
+	deleteConditions *adapters.DeleteConditions
+}
+
+func (sm *storageMock) ID() string { return "postgres" }
+
+func (sm *storageMock) DeleteUserData(tableNames []string, deleteConditions *adapters.DeleteConditions) error {
+	sm.deleteConditions = deleteConditions
+	return nil
+}
+
+func (sm *storageMock) GetUsersRecognition() *storages.UserRecognitionConfiguration {
+	return &storages.UserRecognitionConfiguration{}
+}
+
+type storageProxyMock struct {
+	storages.StorageProxy
+
+	storage storages.Storage
+}
+
+func (spm *storageProxyMock) Get() (storages.Storage, bool) { return spm.storage, true }
+
+func TestDeletionJob(t *testing.T) {
+	logEventPath, err := ioutil.TempDir("", "gdpr")
+	require.NoError(t, err)
+	defer os.RemoveAll(logEventPath)
+
+	fallbackDir := path.Join(logEventPath, logging.FailedDir)
+	archiveDir := path.Join(logEventPath, logging.ArchiveDir)
+	require.NoError(t, os.MkdirAll(fallbackDir, 0755))
+	require.NoError(t, os.MkdirAll(archiveDir, 0755))
+
+	fallbackFile := path.Join(fallbackDir, "failed.dst=postgres-2021-06-01T10-00-00.000.log")
+	writeFile(t, fallbackFile, false,
+		`{"event":{"user":{"email":"john@example.com"}},"error":"err"}`,
+		`{"event":{"user":{"email":"jane@example.com"}},"error":"err"}`)
+	archiveFile := path.Join(archiveDir, "incoming.tok=abc-2021-06-01T10-00-00.000.log.gz")
+	writeFile(t, archiveFile, true,
+		`{"eventn_ctx":{"user":{"anonymous_id":"anon1"}}}`,
+		`{"eventn_ctx":{"user":{"anonymous_id":"anon2"}}}`,
+		`{"user":{"email":"jane@example.com"},"referrer":"john@example.com"}`,
+		`{"user":{"anonymous_id":"anon3"}}`)
+
+	metaStorage := &metaStorageMock{
+		jobs:   map[string]string{},
+		linked: map[string][]string{"john@example.com": {"anon2"}},
+		cachedEvents: map[string][]*meta.Event{"postgres": {
+			{Original: `{"user":{"email":"john@example.com"}}`},
+			{Original: `{"user":{"email":"jane@example.com","note":"john@example.com"}}`},
+			{Original: `{"eventn_ctx":{"user":{"anonymous_id":"anon2"}}}`},
+		}},
+		deletedEvents: map[string][]string{},
+	}
+	storage := &storageMock{}
+	destinationService := destinations.NewTestService(map[string]*destinations.Unit{"postgres": destinations.NewTestUnit(&storageProxyMock{storage: storage})},
+		destinations.TokenizedConsumers{}, destinations.TokenizedStorages{}, destinations.TokenizedIDs{}, nil)
+
+	service := NewService(logEventPath, metaStorage, destinationService, testAnonymousIDNode, []string{testIdentifierNode})
+	job, err := service.CreateJob(&DeletionRequest{Identifiers: []string{"john@example.com"}, AnonymousIDs: []string{"anon1", " "}})
+	require.NoError(t, err)
+	require.Equal(t, RunningStatus, job.Status)
+
+	require.Eventually(t, func() bool {
+		return strings.Contains(metaStorage.job(job.ID), `"finished_at"`)
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Contains(t, metaStorage.job(job.ID), `"status":"DONE"`)
+	require.Equal(t, RunningStatus, job.Status, "returned job isn't changed by the background run")
+
+	require.Equal(t, "OR", storage.deleteConditions.JoinCondition)
+	require.ElementsMatch(t, []adapters.DeleteCondition{
+		{Field: "eventn_ctx_user_email", Clause: "=", Value: "john@example.com"},
+		{Field: "user_email", Clause: "=", Value: "john@example.com"},
+		{Field: "eventn_ctx_user_anonymous_id", Clause: "=", Value: "anon1"},
+		{Field: "eventn_ctx_user_anonymous_id", Clause: "=", Value: "anon2"},
+		{Field: "user_anonymous_id", Clause: "=", Value: "anon1"},
+		{Field: "user_anonymous_id", Clause: "=", Value: "anon2"},
+	}, storage.deleteConditions.Conditions)
+
+	require.Equal(t, []string{`{"user":{"email":"john@example.com"}}`, `{"eventn_ctx":{"user":{"anonymous_id":"anon2"}}}`},
+		metaStorage.deletedEvents["postgres"], "events with the identifier in other fields are kept")
+
+	require.Equal(t, []string{`{"event":{"user":{"email":"jane@example.com"}},"error":"err"}`}, readFile(t, fallbackFile, false))
+	require.Equal(t, []string{
+		`{"user":{"email":"jane@example.com"},"referrer":"john@example.com"}`,
+		`{"user":{"anonymous_id":"anon3"}}`,
+	}, readFile(t, archiveFile, true))
+}
+
+func TestCreateJobErrors(t *testing.T) {
+	service := NewService("/tmp", &meta.Dummy{}, nil, testAnonymousIDNode, []string{testIdentifierNode})
+	_, err := service.CreateJob(&DeletionRequest{Identifiers: []string{"id"}})
+	require.Equal(t, ErrMetaStorageRequired, err)
+
+	service = NewService("/tmp", &metaStorageMock{}, nil, testAnonymousIDNode, []string{testIdentifierNode})
+	_, err = service.CreateJob(&DeletionRequest{Identifiers: []string{""}, AnonymousIDs: []string{" "}})
+	require.Equal(t, ErrEmptyRequest, err)
+}
+
+func TestUserMatcher(t *testing.T) {
+	service := NewService("/tmp", &meta.Dummy{}, nil, testAnonymousIDNode, []string{testIdentifierNode, "/user/internal_id"})
+	matcher := service.newUserMatcher([]string{"john@example.com", "123"}, []string{"anon1"})
+
+	tests := []struct {
+		name     string
+		input    string
+		expected bool
+	}{
+		{"Identifier in identification path", `{"user":{"email":"john@example.com"}}`, true},
+		{"Identifier in alternative identification path", `{"eventn_ctx":{"user":{"email":"john@example.com"}}}`, true},
+		{"Numeric identifier", `{"user":{"internal_id":123}}`, true},
+		{"Anonymous id in anonymous id path", `{"user":{"anonymous_id":"anon1"}}`, true},
+		{"Fallback record", `{"event":{"user":{"anonymous_id":"anon1"}},"error":"error"}`, true},
+		{"Identifier in other field", `{"user":{"email":"jane@example.com"},"page_title":"john@example.com"}`, false},
+		{"Identifier substring", `{"user":{"email":"john@example.com.au"}}`, false},
+		{"Anonymous id in identification path", `{"user":{"email":"anon1"}}`, false},
+		{"Identifier in anonymous id path", `{"user":{"anonymous_id":"john@example.com"}}`, false},
+		{"Invalid JSON", `{"user":`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, matcher.MatchJSON([]byte(tt.input)))
+		})
+	}
+}
+
+func writeFile(t *testing.T, filePath string, gzipped bool, lines ...string) {
+	file, err := os.Create(filePath)
+	require.NoError(t, err)
+	defer file.Close()
+
+	payload := []byte(strings.Join(lines, "\n") + "\n")
+	if !gzipped {
+		_, err = file.Write(payload)
+		require.NoError(t, err)
+		return
+	}
+
+	gzw := gzip.NewWriter(file)
+	_, err = gzw.Write(payload)
+	require.NoError(t, err)
+	require.NoError(t, gzw.Close())
+}
+
+func readFile(t *testing.T, filePath string, gzipped bool) []string {
+	file, err := os.Open(filePath)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var b []byte
+	if gzipped {
+		gzr, err := gzip.NewReader(file)
+		require.NoError(t, err)
+		b, err = ioutil.ReadAll(gzr)
+		require.NoError(t, err)
+	} else {
+		b, err = ioutil.ReadAll(file)
+		require.NoError(t, err)
+	}
+
+	return strings.Split(strings.TrimSpace(string(b)), "\n")
+}
@@ -0,0 +1,253 @@
+package gitops
+
+import (
+	"crypto/md5"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/appstatus"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/safego"
+	"github.com/jitsucom/jitsu/server/timestamp"
+	"github.com/spf13/viper"
+)
+
+//SourceName is used as destinations and sources configuration source when they are loaded by Provider
+const SourceName = "gitops"
+
+//Config is a dto for gitops configuration section
+type Config struct {
+	Path      string `mapstructure:"path"`
+	GitPull   bool   `mapstructure:"git_pull"`
+	ReloadSec int    `mapstructure:"reload_sec"`
+}
+
+//Status is a dto for applied and rejected configuration revisions
+type Status struct {
+	Enabled          bool               `json:"enabled"`
+	Path             string             `json:"path,omitempty"`
+	Revision         string             `json:"revision,omitempty"`
+	AppliedAt        string             `json:"applied_at,omitempty"`
+	RejectedRevision string             `json:"rejected_revision,omitempty"`
+	RejectedAt       string             `json:"rejected_at,omitempty"`
+	Errors           []*ValidationError `json:"errors,omitempty"`
+}
+
+//Provider watches a directory (or git checkout) with per-entity YAML/JSON files:
+//  api_keys/<id>.yaml, destinations/<id>.yaml, sources/<id>.yaml
+//validates all entities and applies them together only if the whole configuration is valid
+type Provider struct {
+	sync.RWMutex
+
+	config  *Config
+	enabled bool
+
+	apiKeysConsumer      func([]byte)
+	destinationsConsumer func([]byte)
+	sourcesConsumer      func([]byte)
+
+	status *Status
+	//lastRevision is the last read revision (applied or rejected)
+	lastRevision string
+}
+
+//NewProvider returns configured Provider
+//returns disabled instance if gitops.path isn't configured
+func NewProvider(viperConfig *viper.Viper) (*Provider, error) {
+	if viperConfig == nil || viperConfig.GetString("path") == "" {
+		return &Provider{status: &Status{}}, nil
+	}
+
+	config := &Config{}
+	if err := viperConfig.Unmarshal(config); err != nil {
+		return nil, fmt.Errorf("Error parsing gitops configuration: %v", err)
+	}
+
+	info, err := os.Stat(config.Path)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading gitops.path [%s]: %v", config.Path, err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("gitops.path [%s] must be a directory", config.Path)
+	}
+
+	if config.ReloadSec <= 0 {
+		return nil, errors.New("gitops.reload_sec must be positive")
+	}
+
+	return &Provider{
+		config:  config,
+		enabled: true,
+		status:  &Status{Enabled: true, Path: config.Path},
+	}, nil
+}
+
+//IsEnabled returns true if gitops.path is configured
+func (p *Provider) IsEnabled() bool {
+	return p.enabled
+}
+
+//Start applies the current configuration and runs goroutine for watching changes every gitops.reload_sec
+//consumers are applied in order: api keys, destinations, sources
+func (p *Provider) Start(apiKeysConsumer, destinationsConsumer, sourcesConsumer func([]byte)) {
+	if !p.enabled {
+		return
+	}
+
+	p.apiKeysConsumer = apiKeysConsumer
+	p.destinationsConsumer = destinationsConsumer
+	p.sourcesConsumer = sourcesConsumer
+
+	logging.Infof("🔄 Configuration will be loaded from [%s] directory every %d seconds", p.config.Path, p.config.ReloadSec)
+	p.reload()
+
+	safego.RunWithRestart(func() {
+		for {
+			if appstatus.Instance.Idle.Load() {
+				break
+			}
+
+			time.Sleep(time.Duration(p.config.ReloadSec) * time.Second)
+
+			p.reload()
+		}
+	})
+}
+
+//Status returns applied configuration revision and the last rejection report
+func (p *Provider) Status() Status {
+	p.RLock()
+	defer p.RUnlock()
+
+	if p.status == nil {
+		return Status{}
+	}
+
+	return *p.status
+}
+
+//reload pulls git changes (if configured), reads and validates configuration
+//applies it if the revision was changed and all entities are valid
+func (p *Provider) reload() {
+	if p.config.GitPull {
+		if output, err := p.git("pull", "--ff-only"); err != nil {
+			logging.Errorf("Error pulling gitops configuration in [%s]: %v: %s", p.config.Path, err, output)
+		}
+	}
+
+	revision, err := p.revision()
+	if err != nil {
+		logging.Errorf("Error getting gitops configuration revision: %v", err)
+		return
+	}
+
+	if revision == p.lastRevision {
+		return
+	}
+	p.lastRevision = revision
+
+	snapshot, validationErrors := readSnapshot(p.config.Path)
+	if len(validationErrors) > 0 {
+		p.reject(revision, validationErrors)
+		return
+	}
+
+	p.apiKeysConsumer(snapshot.APIKeysPayload())
+	p.destinationsConsumer(snapshot.DestinationsPayload())
+	p.sourcesConsumer(snapshot.SourcesPayload())
+
+	p.Lock()
+	p.status.Revision = revision
+	p.status.AppliedAt = timestamp.NowUTC()
+	p.status.RejectedRevision = ""
+	p.status.RejectedAt = ""
+	p.status.Errors = nil
+	p.Unlock()
+
+	logging.Infof("✅ Configuration revision [%s] has been applied: %d API keys, %d destinations, %d sources",
+		revision, len(snapshot.APIKeys), len(snapshot.Destinations), len(snapshot.Sources))
+}
+
+//reject keeps the current applied configuration and saves the validation report
+func (p *Provider) reject(revision string, validationErrors []*ValidationError) {
+	report := make([]string, 0, len(validationErrors))
+	for _, validationError := range validationErrors {
+		report = append(report, fmt.Sprintf("\t%s: %s", validationError.File, validationError.Error))
+	}
+	logging.Errorf("❌ Configuration revision [%s] has been rejected (applied revision: [%s]):\n%s",
+		revision, p.Status().Revision, strings.Join(report, "\n"))
+
+	p.Lock()
+	p.status.RejectedRevision = revision
+	p.status.RejectedAt = timestamp.NowUTC()
+	p.status.Errors = validationErrors
+	p.Unlock()
+}
+
+//revision returns git HEAD commit hash with 'dirty' suffix if there are uncommitted changes
+//or content hash of all configuration files if the directory isn't a git checkout
+func (p *Provider) revision() (string, error) {
+	contentHash, err := p.contentHash()
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := os.Stat(filepath.Join(p.config.Path, ".git")); err != nil {
+		return contentHash, nil
+	}
+
+	head, err := p.git("rev-parse", "HEAD")
+	if err != nil {
+		logging.Warnf("Error getting git revision of [%s]: %v. Content hash will be used", p.config.Path, err)
+		return contentHash, nil
+	}
+
+	status, err := p.git("status", "--porcelain")
+	if err == nil && status != "" {
+		return head + "-dirty-" + contentHash[:8], nil
+	}
+
+	return head, nil
+}
+
+//contentHash returns md5 hash of all entity files names and contents
+func (p *Provider) contentHash() (string, error) {
+	var files []string
+	for _, dir := range []string{apiKeysDir, destinationsDir, sourcesDir} {
+		matches, err := filepath.Glob(filepath.Join(p.config.Path, dir, "*"))
+		if err != nil {
+			return "", err
+		}
+		files = append(files, matches...)
+	}
+	sort.Strings(files)
+
+	hash := md5.New()
+	for _, file := range files {
+		if configType(file) == "" {
+			continue
+		}
+
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return "", err
+		}
+		hash.Write([]byte(file))
+		hash.Write(b)
+	}
+
+	return fmt.Sprintf("%x", hash.Sum(nil)), nil
+}
+
+func (p *Provider) git(args ...string) (string, error) {
+	output, err := exec.Command("git", append([]string{"-C", p.config.Path}, args...)...).CombinedOutput()
+	return strings.TrimSpace(string(output)), err
+}
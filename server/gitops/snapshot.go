@@ -0,0 +1,335 @@
+package gitops
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jitsucom/jitsu/server/authorization"
+	driversbase "github.com/jitsucom/jitsu/server/drivers/base"
+	"github.com/jitsucom/jitsu/server/storages"
+	"github.com/spf13/viper"
+)
+
+const (
+	apiKeysDir      = "api_keys"
+	destinationsDir = "destinations"
+	sourcesDir      = "sources"
+)
+
+//ValidationError is a dto for a single invalid configuration file
+type ValidationError struct {
+	File  string `json:"file"`
+	Error string `json:"error"`
+}
+
+//Snapshot is a validated set of all entities from the configuration directory
+type Snapshot struct {
+	APIKeys      []authorization.Token
+	Destinations map[string]storages.DestinationConfig
+	Sources      map[string]driversbase.SourceConfig
+}
+
+//validator is implemented by destination type specific configurations
+type validator interface {
+	Validate() error
+}
+
+//readSnapshot reads all entity files from the directory and validates them
+//returns Snapshot only if all files are valid, otherwise returns validation errors for every invalid file
+func readSnapshot(dir string) (*Snapshot, []*ValidationError) {
+	snapshot := &Snapshot{
+		Destinations: map[string]storages.DestinationConfig{},
+		Sources:      map[string]driversbase.SourceConfig{},
+	}
+	var errs []*ValidationError
+
+	//api keys
+	keysFiles := map[string]string{}
+	for _, file := range listEntityFiles(filepath.Join(dir, apiKeysDir), &errs) {
+		token := authorization.Token{}
+		id, err := readEntity(file, &token)
+		if err == nil {
+			if token.ID == "" {
+				token.ID = id
+			}
+			err = validateAPIKey(token, keysFiles)
+		}
+		if err != nil {
+			errs = append(errs, newValidationError(dir, file, err))
+			continue
+		}
+
+		snapshot.APIKeys = append(snapshot.APIKeys, token)
+	}
+
+	//destinations
+	for _, file := range listEntityFiles(filepath.Join(dir, destinationsDir), &errs) {
+		destination := storages.DestinationConfig{}
+		id, err := readEntity(file, &destination)
+		if err == nil {
+			err = validateDestination(id, destination, keysFiles)
+		}
+		if err != nil {
+			errs = append(errs, newValidationError(dir, file, err))
+			continue
+		}
+
+		snapshot.Destinations[id] = destination
+	}
+
+	//sources
+	for _, file := range listEntityFiles(filepath.Join(dir, sourcesDir), &errs) {
+		source := driversbase.SourceConfig{}
+		id, err := readEntity(file, &source)
+		if err == nil {
+			err = validateSource(source, snapshot.Destinations)
+		}
+		if err != nil {
+			errs = append(errs, newValidationError(dir, file, err))
+			continue
+		}
+
+		snapshot.Sources[id] = source
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	return snapshot, nil
+}
+
+//APIKeysPayload returns api keys in authorization service payload format
+func (s *Snapshot) APIKeysPayload() []byte {
+	b, _ := json.Marshal(authorization.TokensPayload{Tokens: s.APIKeys})
+	return b
+}
+
+//DestinationsPayload returns destinations in destinations service payload format
+func (s *Snapshot) DestinationsPayload() []byte {
+	b, _ := json.Marshal(map[string]interface{}{destinationsDir: s.Destinations})
+	return b
+}
+
+//SourcesPayload returns sources in sources service payload format
+func (s *Snapshot) SourcesPayload() []byte {
+	b, _ := json.Marshal(map[string]interface{}{sourcesDir: s.Sources})
+	return b
+}
+
+//listEntityFiles returns sorted paths of all YAML/JSON files in the directory
+//missing directory means there are no entities of this kind
+func listEntityFiles(dir string, errs *[]*ValidationError) []string {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			*errs = append(*errs, &ValidationError{File: filepath.Base(dir), Error: err.Error()})
+		}
+		return nil
+	}
+
+	var files []string
+	for _, info := range infos {
+		if info.IsDir() || strings.HasPrefix(info.Name(), ".") {
+			continue
+		}
+		if configType(info.Name()) == "" {
+			continue
+		}
+
+		files = append(files, filepath.Join(dir, info.Name()))
+	}
+	sort.Strings(files)
+
+	return files
+}
+
+//readEntity parses the file into entity with mapstructure tags (the same as in the main config)
+//returns entity ID (the file name without extension)
+func readEntity(file string, entity interface{}) (string, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+
+	v := viper.New()
+	v.SetConfigType(configType(file))
+	if err := v.ReadConfig(bytes.NewBuffer(b)); err != nil {
+		return "", fmt.Errorf("Error parsing file: %v", err)
+	}
+
+	if err := v.Unmarshal(entity); err != nil {
+		return "", fmt.Errorf("Error parsing file: %v", err)
+	}
+
+	name := filepath.Base(file)
+	return strings.TrimSuffix(name, filepath.Ext(name)), nil
+}
+
+//validateAPIKey checks required fields and that token ID and secrets are unique
+//keysFiles is used for uniqueness check and is filled with token ID and secrets
+func validateAPIKey(token authorization.Token, keysFiles map[string]string) error {
+	if token.ClientSecret == "" && token.ServerSecret == "" {
+		return fmt.Errorf("API key [%s] must have client_secret or server_secret", token.ID)
+	}
+
+	for _, value := range []string{token.ID, token.ClientSecret, token.ServerSecret} {
+		if value == "" {
+			continue
+		}
+		if _, ok := keysFiles[value]; ok {
+			return fmt.Errorf("API key [%s] id or secret [%s] is already used by API key [%s]", token.ID, value, keysFiles[value])
+		}
+	}
+
+	for _, value := range []string{token.ID, token.ClientSecret, token.ServerSecret} {
+		if value != "" {
+			keysFiles[value] = token.ID
+		}
+	}
+
+	return nil
+}
+
+//validateDestination checks type, mode, type specific configuration and only_tokens references
+func validateDestination(id string, destination storages.DestinationConfig, keys map[string]string) error {
+	destinationType := destination.Type
+	if destinationType == "" {
+		destinationType = id
+	}
+	if _, ok := storages.StorageTypes[destinationType]; !ok {
+		return fmt.Errorf("Unknown destination type: %s", destinationType)
+	}
+
+	if destination.Mode != "" && destination.Mode != storages.BatchMode && destination.Mode != storages.StreamMode {
+		return fmt.Errorf("Unknown destination mode: %s. Supported: %s, %s", destination.Mode, storages.BatchMode, storages.StreamMode)
+	}
+
+	for _, tokenID := range destination.OnlyTokens {
+		if _, ok := keys[tokenID]; !ok {
+			return fmt.Errorf("only_tokens: API key [%s] doesn't exist", tokenID)
+		}
+	}
+
+	if destination.UsersRecognition != nil {
+		if err := destination.UsersRecognition.Validate(); err != nil {
+			return fmt.Errorf("users_recognition: %v", err)
+		}
+	}
+
+	//type specific configurations (only provided ones)
+	configs := []validator{}
+	if destination.DataSource != nil {
+		configs = append(configs, destination.DataSource)
+	}
+	if destination.S3 != nil {
+		configs = append(configs, destination.S3)
+	}
+	if destination.GoogleAnalytics != nil {
+		configs = append(configs, destination.GoogleAnalytics)
+	}
+	if destination.GoogleAnalytics4 != nil {
+		configs = append(configs, destination.GoogleAnalytics4)
+	}
+	if destination.ClickHouse != nil {
+		configs = append(configs, destination.ClickHouse)
+	}
+	if destination.Snowflake != nil {
+		configs = append(configs, destination.Snowflake)
+	}
+	if destination.Redshift != nil {
+		configs = append(configs, destination.Redshift)
+	}
+	if destination.SQLite != nil {
+		configs = append(configs, destination.SQLite)
+	}
+	if destination.Facebook != nil {
+		configs = append(configs, destination.Facebook)
+	}
+	if destination.WebHook != nil {
+		configs = append(configs, destination.WebHook)
+	}
+	if destination.Amplitude != nil {
+		configs = append(configs, destination.Amplitude)
+	}
+	if destination.HubSpot != nil {
+		configs = append(configs, destination.HubSpot)
+	}
+	if destination.Mixpanel != nil {
+		configs = append(configs, destination.Mixpanel)
+	}
+	if destination.PostHog != nil {
+		configs = append(configs, destination.PostHog)
+	}
+	if destination.Segment != nil {
+		configs = append(configs, destination.Segment)
+	}
+	if destination.DbtCloud != nil {
+		configs = append(configs, destination.DbtCloud)
+	}
+	if destination.AzureBlob != nil {
+		configs = append(configs, destination.AzureBlob)
+	}
+
+	for _, config := range configs {
+		if err := config.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if destination.Google != nil {
+		if err := destination.Google.Validate(destination.Mode == storages.StreamMode); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//validateSource checks type and destinations references
+func validateSource(source driversbase.SourceConfig, destinations map[string]storages.DestinationConfig) error {
+	if source.Type == "" {
+		return fmt.Errorf("type is required field")
+	}
+	if _, ok := driversbase.DriverConstructors[source.Type]; !ok {
+		return fmt.Errorf("Unknown source type: %s", source.Type)
+	}
+
+	if len(source.Destinations) == 0 {
+		return fmt.Errorf("destinations are required")
+	}
+
+	for _, destinationID := range append(source.Destinations, source.PostHandleDestinations...) {
+		if _, ok := destinations[destinationID]; !ok {
+			return fmt.Errorf("destination [%s] doesn't exist", destinationID)
+		}
+	}
+
+	return nil
+}
+
+func newValidationError(dir, file string, err error) *ValidationError {
+	relative, relErr := filepath.Rel(dir, file)
+	if relErr != nil {
+		relative = file
+	}
+
+	return &ValidationError{File: relative, Error: err.Error()}
+}
+
+func configType(file string) string {
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".yaml", ".yml":
+		return "yaml"
+	case ".json":
+		return "json"
+	default:
+		return ""
+	}
+}
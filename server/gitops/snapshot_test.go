@@ -0,0 +1,100 @@
+package gitops
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadSnapshot(t *testing.T) {
+	tests := []struct {
+		name           string
+		files          map[string]string
+		expectedErrors []*ValidationError
+		expectedKeys   int
+		expectedDests  []string
+	}{
+		{
+			"Empty directory",
+			map[string]string{},
+			nil,
+			0,
+			[]string{},
+		},
+		{
+			"Valid configuration",
+			map[string]string{
+				"api_keys/key1.yaml":          "client_secret: c1\nserver_secret: s1\n",
+				"api_keys/key2.json":          `{"id": "custom", "client_secret": "c2"}`,
+				"destinations/pg.yaml":        "type: postgres\nonly_tokens: [key1, c2]\ndatasource:\n  host: localhost\n  db: db\n  username: user\n",
+				"destinations/README.md":      "ignored",
+				"destinations/archive.yml":    "type: s3\nmode: batch\ns3:\n  access_key_id: id\n  secret_access_key: key\n  bucket: bucket\n  region: us-east-1\n",
+				"destinations/.hidden.yaml":   "type: unknown",
+				"sources/nested/ignored.yaml": "type: unknown",
+			},
+			nil,
+			2,
+			[]string{"archive", "pg"},
+		},
+		{
+			"Invalid configuration",
+			map[string]string{
+				"api_keys/key1.yaml":     "client_secret: c1\n",
+				"api_keys/key2.yaml":     "client_secret: c1\n",
+				"api_keys/key3.yaml":     "origins: [abc]\n",
+				"destinations/pg.yaml":   "type: postgres\nonly_tokens: [key5]\n",
+				"destinations/bad.yaml":  "type: unknown\n",
+				"destinations/mode.yaml": "type: postgres\nmode: realtime\n",
+				"destinations/ga4.yaml":  "type: google_analytics4\ngoogle_analytics4:\n  measurement_id: G-1\n",
+				"destinations/syn.yaml":  "type: synapse\nazure_blob:\n  account_name: account\n",
+				"destinations/bq.yaml":   "type: bigquery\ngoogle:\n  bq_dataset: dataset\n",
+				"sources/src.yaml":       "destinations: [pg]\n",
+			},
+			[]*ValidationError{
+				{File: "api_keys/key2.yaml", Error: "API key [key2] id or secret [c1] is already used by API key [key1]"},
+				{File: "api_keys/key3.yaml", Error: "API key [key3] must have client_secret or server_secret"},
+				{File: "destinations/bad.yaml", Error: "Unknown destination type: unknown"},
+				{File: "destinations/bq.yaml", Error: "Google cloud storage bucket(gcs_bucket) is required parameter"},
+				{File: "destinations/ga4.yaml", Error: "api_secret is required parameter"},
+				{File: "destinations/mode.yaml", Error: "Unknown destination mode: realtime. Supported: batch, stream"},
+				{File: "destinations/pg.yaml", Error: "only_tokens: API key [key5] doesn't exist"},
+				{File: "destinations/syn.yaml", Error: "Azure Blob container is required parameter"},
+				{File: "sources/src.yaml", Error: "type is required field"},
+			},
+			0,
+			nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "gitops")
+			require.NoError(t, err)
+			defer os.RemoveAll(dir)
+
+			for name, content := range tt.files {
+				path := filepath.Join(dir, name)
+				require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+				require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+			}
+
+			snapshot, validationErrors := readSnapshot(dir)
+			if len(tt.expectedErrors) > 0 {
+				require.Nil(t, snapshot)
+				require.Equal(t, tt.expectedErrors, validationErrors)
+				return
+			}
+
+			require.Empty(t, validationErrors)
+			require.Len(t, snapshot.APIKeys, tt.expectedKeys)
+
+			destinations := []string{}
+			for id := range snapshot.Destinations {
+				destinations = append(destinations, id)
+			}
+			require.ElementsMatch(t, tt.expectedDests, destinations)
+		})
+	}
+}
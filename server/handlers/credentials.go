@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/jitsucom/jitsu/server/credentials"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/meta"
+	"github.com/jitsucom/jitsu/server/middleware"
+	"net/http"
+	"time"
+)
+
+//CredentialResponse is a dto for OAuth2 credential without secrets
+type CredentialResponse struct {
+	ID        string    `json:"id"`
+	GrantType string    `json:"grant_type"`
+	TokenURL  string    `json:"token_url"`
+	ClientID  string    `json:"client_id"`
+	Scopes    []string  `json:"scopes,omitempty"`
+	Expiry    time.Time `json:"expiry,omitempty"`
+	UpdatedAt string    `json:"updated_at,omitempty"`
+	LastError string    `json:"last_error,omitempty"`
+}
+
+//CredentialsResponse is a response dto for getting all OAuth2 credentials
+type CredentialsResponse struct {
+	Credentials []*CredentialResponse `json:"credentials"`
+}
+
+//CredentialsHandler handles OAuth2 credentials store requests
+type CredentialsHandler struct {
+	credentialsService *credentials.Service
+}
+
+//NewCredentialsHandler returns configured CredentialsHandler
+func NewCredentialsHandler(credentialsService *credentials.Service) *CredentialsHandler {
+	return &CredentialsHandler{credentialsService: credentialsService}
+}
+
+//SaveHandler creates or updates OAuth2 credential
+func (ch *CredentialsHandler) SaveHandler(c *gin.Context) {
+	config := &credentials.Config{}
+	if err := c.BindJSON(config); err != nil {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse("Failed to parse body", err))
+		return
+	}
+
+	if err := ch.credentialsService.Save(config); err != nil {
+		logging.Error(err)
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse("Error saving credential", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, middleware.OKResponse())
+}
+
+//GetAllHandler returns all OAuth2 credentials without secrets and tokens
+func (ch *CredentialsHandler) GetAllHandler(c *gin.Context) {
+	stored, err := ch.credentialsService.GetAll()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse("Error getting credentials", err))
+		return
+	}
+
+	response := CredentialsResponse{Credentials: make([]*CredentialResponse, 0, len(stored))}
+	for _, credential := range stored {
+		response.Credentials = append(response.Credentials, &CredentialResponse{
+			ID:        credential.ID,
+			GrantType: credential.GrantType,
+			TokenURL:  credential.TokenURL,
+			ClientID:  credential.ClientID,
+			Scopes:    credential.Scopes,
+			Expiry:    credential.Expiry,
+			UpdatedAt: credential.UpdatedAt,
+			LastError: credential.LastError,
+		})
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+//DeleteHandler deletes OAuth2 credential by ID
+func (ch *CredentialsHandler) DeleteHandler(c *gin.Context) {
+	credentialID := c.Param("credentialID")
+	if credentialID == "" {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse("'credential_id' is required path parameter", nil))
+		return
+	}
+
+	if err := ch.credentialsService.Delete(credentialID); err != nil {
+		if err == meta.ErrCredentialNotFound {
+			c.JSON(http.StatusNotFound, middleware.ErrResponse(err.Error(), nil))
+			return
+		}
+
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse("Error deleting credential", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, middleware.OKResponse())
+}
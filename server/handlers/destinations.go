@@ -107,6 +107,13 @@ func testDestinationConnection(config *storages.DestinationConfig) error {
 		}
 
 		return nil
+	case storages.GoogleAnalytics4Type:
+		if err := config.GoogleAnalytics4.Validate(); err != nil {
+			return err
+		}
+
+		ga4Adapter := adapters.NewTestGoogleAnalytics4(config.GoogleAnalytics4)
+		return ga4Adapter.TestAccess()
 	case storages.FacebookType:
 		if err := config.Facebook.Validate(); err != nil {
 			return err
@@ -120,7 +127,8 @@ func testDestinationConnection(config *storages.DestinationConfig) error {
 			return err
 		}
 
-		return nil
+		webHookAdapter := adapters.NewTestWebHook(config.WebHook)
+		return webHookAdapter.TestAccess()
 	case storages.AmplitudeType:
 		if err := config.Amplitude.Validate(); err != nil {
 			return err
@@ -135,6 +143,27 @@ func testDestinationConnection(config *storages.DestinationConfig) error {
 
 		hubspotAdapter := adapters.NewTestHubSpot(config.HubSpot)
 		return hubspotAdapter.TestAccess()
+	case storages.MixpanelType:
+		if err := config.Mixpanel.Validate(); err != nil {
+			return err
+		}
+
+		mixpanelAdapter := adapters.NewTestMixpanel(config.Mixpanel)
+		return mixpanelAdapter.TestAccess()
+	case storages.PostHogType:
+		if err := config.PostHog.Validate(); err != nil {
+			return err
+		}
+
+		postHogAdapter := adapters.NewTestPostHog(config.PostHog)
+		return postHogAdapter.TestAccess()
+	case storages.SegmentType:
+		if err := config.Segment.Validate(); err != nil {
+			return err
+		}
+
+		segmentAdapter := adapters.NewTestSegment(config.Segment)
+		return segmentAdapter.TestAccess()
 	case storages.DbtCloudType:
 		if err := config.DbtCloud.Validate(); err != nil {
 			return err
@@ -147,6 +176,18 @@ func testDestinationConnection(config *storages.DestinationConfig) error {
 			timestamp.Key: typing.SQLColumn{Type: "DATETIME"},
 		}
 		return testMySQL(config, eventContext)
+	case storages.SQLiteType:
+		eventContext.Table.Columns = adapters.Columns{
+			uniqueIDField: typing.SQLColumn{Type: "TEXT"},
+			timestamp.Key: typing.SQLColumn{Type: "TIMESTAMP"},
+		}
+		return testSQLite(config, eventContext)
+	case storages.MSSQLType, storages.SynapseType:
+		eventContext.Table.Columns = adapters.Columns{
+			uniqueIDField: typing.SQLColumn{Type: "NVARCHAR(MAX)"},
+			timestamp.Key: typing.SQLColumn{Type: "DATETIME2"},
+		}
+		return testMSSQL(config, eventContext)
 	case storages.S3Type:
 		s3Adapter, err := adapters.NewS3(config.S3)
 		if err != nil {
@@ -188,7 +229,7 @@ func testPostgres(config *storages.DestinationConfig, eventContext *adapters.Eve
 		}
 	}()
 
-	postgres, err := adapters.NewPostgres(context.Background(), config.DataSource, &logging.QueryLogger{}, typing.SQLTypes{})
+	postgres, err := adapters.NewPostgres(context.Background(), config.DataSource, nil, &logging.QueryLogger{}, typing.SQLTypes{})
 	if err != nil {
 		return err
 	}
@@ -227,7 +268,7 @@ func testClickHouse(config *storages.DestinationConfig, eventContext *adapters.E
 		return err
 	}
 
-	tableStatementFactory, err := adapters.NewTableStatementFactory(config.ClickHouse)
+	tableStatementFactory, err := adapters.NewTableStatementFactory(config.ClickHouse, nil)
 	if err != nil {
 		return err
 	}
@@ -262,7 +303,7 @@ func testClickHouse(config *storages.DestinationConfig, eventContext *adapters.E
 
 		ch, err := adapters.NewClickHouse(context.Background(), dsnURL.String(),
 			config.ClickHouse.Database, config.ClickHouse.Cluster, config.ClickHouse.TLS, tableStatementFactory,
-			map[string]bool{}, &logging.QueryLogger{}, typing.SQLTypes{})
+			map[string]bool{}, &logging.QueryLogger{}, typing.SQLTypes{}, false)
 		if err != nil {
 			return err
 		}
@@ -324,7 +365,7 @@ func testRedshift(config *storages.DestinationConfig, eventContext *adapters.Eve
 		}
 	}()
 
-	redshift, err := adapters.NewAwsRedshift(context.Background(), config.DataSource, config.S3, &logging.QueryLogger{}, typing.SQLTypes{})
+	redshift, err := adapters.NewAwsRedshift(context.Background(), config.DataSource, config.S3, config.Redshift, &logging.QueryLogger{}, typing.SQLTypes{})
 	if err != nil {
 		return err
 	}
@@ -445,7 +486,7 @@ func testBigQuery(config *storages.DestinationConfig, eventContext *adapters.Eve
 
 //testClickHouse depends on the destination mode:
 // stream: connects to Snowflake, creates table, writes 1 test record, deletes table
-// batch: connects to Snowflake, S3 or Google Cloud Storage, creates table, writes 1 test file with 1 test record, copies it to Snowflake, deletes table
+// batch: connects to Snowflake, S3, Google Cloud Storage or internal stage, creates table, writes 1 test file with 1 test record, copies it to Snowflake, deletes table
 //returns err if has occurred
 func testSnowflake(config *storages.DestinationConfig, eventContext *adapters.EventContext) error {
 	if err := config.Snowflake.Validate(); err != nil {
@@ -484,7 +525,12 @@ func testSnowflake(config *storages.DestinationConfig, eventContext *adapters.Ev
 
 	if config.Mode == storages.BatchMode {
 		var stageAdapter adapters.Stage
-		if config.Google != nil {
+		if config.Snowflake.InternalStage.IsEnabled() {
+			stageAdapter, err = adapters.NewSnowflakeInternalStage(snowflake)
+			if err != nil {
+				return err
+			}
+		} else if config.Google != nil {
 			//with google stage
 			if err := config.Google.Validate(config.Mode == storages.StreamMode); err != nil {
 				return err
@@ -514,8 +560,9 @@ func testSnowflake(config *storages.DestinationConfig, eventContext *adapters.Ev
 		if err := stageAdapter.UploadBytes(eventContext.Table.Name, b); err != nil {
 			return err
 		}
+		defer stageAdapter.DeleteObject(eventContext.Table.Name)
 
-		if err = snowflake.Copy(eventContext.Table.Name, eventContext.Table.Name, header); err != nil {
+		if err = snowflake.Copy(eventContext.Table.Name, eventContext.Table, header); err != nil {
 			return err
 		}
 	} else {
@@ -540,7 +587,7 @@ func testMySQL(config *storages.DestinationConfig, eventContext *adapters.EventC
 
 	config.DataSource.Parameters["timeout"] = "6s"
 
-	mysql, err := storages.CreateMySQLAdapter(context.Background(), *config.DataSource, &logging.QueryLogger{}, typing.SQLTypes{})
+	mysql, err := storages.CreateMySQLAdapter(context.Background(), *config.DataSource, nil, &logging.QueryLogger{}, typing.SQLTypes{})
 	if err != nil {
 		return err
 	}
@@ -564,3 +611,119 @@ func testMySQL(config *storages.DestinationConfig, eventContext *adapters.EventC
 
 	return nil
 }
+
+//testSQLite opens (or creates) database file, creates table, write 1 test record, deletes table
+//returns err if has occurred
+func testSQLite(config *storages.DestinationConfig, eventContext *adapters.EventContext) error {
+	if err := config.SQLite.Validate(); err != nil {
+		return err
+	}
+
+	sqlite, err := adapters.NewSQLite(context.Background(), config.SQLite, &logging.QueryLogger{}, typing.SQLTypes{})
+	if err != nil {
+		return err
+	}
+
+	if err = sqlite.CreateTable(eventContext.Table); err != nil {
+		sqlite.Close()
+		return err
+	}
+
+	defer func() {
+		if err := sqlite.DropTable(eventContext.Table); err != nil {
+			logging.Errorf("Error dropping table in test connection: %v", err)
+		}
+
+		sqlite.Close()
+	}()
+
+	if err = sqlite.Insert(eventContext); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+//testMSSQL depends on the destination type and mode:
+// mssql or synapse in stream mode: connects to the database, creates table, writes 1 test record, deletes table
+// synapse in batch mode: connects to the database and Azure Blob Storage, creates table, writes 1 test file with 1 test record, copies it to Synapse, deletes table
+//returns err if has occurred
+func testMSSQL(config *storages.DestinationConfig, eventContext *adapters.EventContext) error {
+	if err := config.DataSource.Validate(); err != nil {
+		return err
+	}
+
+	if config.DataSource.Port.String() == "" {
+		config.DataSource.Port = "1433"
+	}
+	if config.DataSource.Schema == "" {
+		config.DataSource.Schema = "dbo"
+	}
+
+	config.DataSource.Parameters["dial timeout"] = "6"
+
+	synapseBatch := config.Type == storages.SynapseType && config.Mode == storages.BatchMode
+	var stageAdapter *adapters.AzureBlob
+	if synapseBatch {
+		var err error
+		stageAdapter, err = adapters.NewAzureBlob(config.AzureBlob)
+		if err != nil {
+			return err
+		}
+	}
+
+	var mssql *adapters.MSSQL
+	var synapse *adapters.Synapse
+	var err error
+	if config.Type == storages.SynapseType {
+		synapse, err = adapters.NewSynapse(context.Background(), config.DataSource, config.AzureBlob, &logging.QueryLogger{}, typing.SQLTypes{})
+		if synapse != nil {
+			mssql = synapse.MSSQL
+		}
+	} else {
+		mssql, err = adapters.NewMSSQL(context.Background(), config.DataSource, &logging.QueryLogger{}, typing.SQLTypes{})
+	}
+	if err != nil {
+		return err
+	}
+
+	//create db schema if doesn't exist
+	if err = mssql.CreateDbSchema(config.DataSource.Schema); err != nil {
+		mssql.Close()
+		return err
+	}
+
+	if err = mssql.CreateTable(eventContext.Table); err != nil {
+		mssql.Close()
+		return err
+	}
+
+	defer func() {
+		if err := mssql.DropTable(eventContext.Table); err != nil {
+			logging.Errorf("Error dropping table in test connection: %v", err)
+		}
+
+		mssql.Close()
+	}()
+
+	if !synapseBatch {
+		return mssql.Insert(eventContext)
+	}
+
+	var header []string
+	for column := range eventContext.Table.Columns {
+		header = append(header, column)
+	}
+	b, err := adapters.MarshalSynapseCSV(header, []map[string]interface{}{eventContext.ProcessedEvent})
+	if err != nil {
+		return err
+	}
+
+	fileName := eventContext.Table.Name + ".csv"
+	if err := stageAdapter.UploadBytes(fileName, b); err != nil {
+		return err
+	}
+	defer stageAdapter.DeleteObject(fileName)
+
+	return synapse.Copy(fileName, eventContext.Table.Name, header)
+}
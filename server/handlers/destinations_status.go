@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/jitsucom/jitsu/server/destinations"
+	"github.com/jitsucom/jitsu/server/middleware"
+	"github.com/jitsucom/jitsu/server/storages"
+	"net/http"
+	"sort"
+)
+
+//DestinationsStatusResponse is a response dto for destinations status request
+type DestinationsStatusResponse struct {
+	Destinations []*storages.DestinationStatus `json:"destinations"`
+}
+
+//DestinationsStatusHandler handles destinations runtime status requests
+type DestinationsStatusHandler struct {
+	destinationService *destinations.Service
+}
+
+//NewDestinationsStatusHandler returns configured DestinationsStatusHandler
+func NewDestinationsStatusHandler(destinationService *destinations.Service) *DestinationsStatusHandler {
+	return &DestinationsStatusHandler{destinationService: destinationService}
+}
+
+//Handler returns status (initialization, HTTP queue size, circuit breaker and rate limit) of the destination
+//with destination_id query parameter or of all destinations
+func (dsh *DestinationsStatusHandler) Handler(c *gin.Context) {
+	destinationIDs := dsh.destinationService.GetAllDestinationIDs()
+	if destinationID := c.Query("destination_id"); destinationID != "" {
+		if _, ok := dsh.destinationService.GetDestinationByID(destinationID); !ok {
+			c.JSON(http.StatusBadRequest, middleware.ErrResponse(fmt.Sprintf("Destination with id=[%s] does not exist", destinationID), nil))
+			return
+		}
+		destinationIDs = []string{destinationID}
+	}
+	sort.Strings(destinationIDs)
+
+	statuses := make([]*storages.DestinationStatus, 0, len(destinationIDs))
+	for _, id := range destinationIDs {
+		if storageProxy, ok := dsh.destinationService.GetDestinationByID(id); ok {
+			statuses = append(statuses, storageProxy.Status())
+		}
+	}
+
+	c.JSON(http.StatusOK, DestinationsStatusResponse{Destinations: statuses})
+}
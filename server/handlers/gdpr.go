@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/jitsucom/jitsu/server/gdpr"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/meta"
+	"github.com/jitsucom/jitsu/server/middleware"
+	"net/http"
+)
+
+//DeletionJobsResponse is a response dto for getting all user data deletion jobs
+type DeletionJobsResponse struct {
+	Jobs []meta.DeletionJob `json:"jobs"`
+}
+
+//DeletionHandler handles user data deletion (GDPR) requests
+type DeletionHandler struct {
+	gdprService *gdpr.Service
+}
+
+//NewDeletionHandler returns configured DeletionHandler
+func NewDeletionHandler(gdprService *gdpr.Service) *DeletionHandler {
+	return &DeletionHandler{gdprService: gdprService}
+}
+
+//CreateHandler creates user data deletion job and returns it
+func (dh *DeletionHandler) CreateHandler(c *gin.Context) {
+	req := &gdpr.DeletionRequest{}
+	if err := c.BindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse("Failed to parse body", err))
+		return
+	}
+
+	job, err := dh.gdprService.CreateJob(req)
+	if err != nil {
+		if err == gdpr.ErrEmptyRequest {
+			c.JSON(http.StatusBadRequest, middleware.ErrResponse(err.Error(), nil))
+			return
+		}
+
+		logging.Error(err)
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse("Error creating user data deletion job", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+//GetAllHandler returns all user data deletion jobs
+func (dh *DeletionHandler) GetAllHandler(c *gin.Context) {
+	jobs, err := dh.gdprService.GetAllJobs()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse("Error getting user data deletion jobs", err))
+		return
+	}
+
+	if jobs == nil {
+		jobs = []meta.DeletionJob{}
+	}
+
+	c.JSON(http.StatusOK, DeletionJobsResponse{Jobs: jobs})
+}
+
+//GetByIDHandler returns user data deletion job by ID
+func (dh *DeletionHandler) GetByIDHandler(c *gin.Context) {
+	jobID := c.Param("jobID")
+	if jobID == "" {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse("'job_id' is required path parameter", nil))
+		return
+	}
+
+	job, err := dh.gdprService.GetJob(jobID)
+	if err != nil {
+		if err == meta.ErrDeletionJobNotFound {
+			c.JSON(http.StatusNotFound, middleware.ErrResponse(err.Error(), nil))
+			return
+		}
+
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse("Error getting user data deletion job", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/jitsucom/jitsu/server/gitops"
+	"net/http"
+)
+
+//GitOpsHandler handles gitops configuration status requests
+type GitOpsHandler struct {
+	provider *gitops.Provider
+}
+
+//NewGitOpsHandler returns configured GitOpsHandler instance
+func NewGitOpsHandler(provider *gitops.Provider) *GitOpsHandler {
+	return &GitOpsHandler{provider: provider}
+}
+
+//StatusHandler returns configuration revision which is applied on the current node
+//and the last rejected revision with validation errors
+func (gh *GitOpsHandler) StatusHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gh.provider.Status())
+}
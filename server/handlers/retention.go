@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/jitsucom/jitsu/server/destinations"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/middleware"
+	"github.com/jitsucom/jitsu/server/storages"
+	"net/http"
+)
+
+//RetentionPreviewResponse is a response dto for retention dry run
+type RetentionPreviewResponse struct {
+	DestinationID string                      `json:"destination_id"`
+	Results       []*storages.RetentionResult `json:"results"`
+}
+
+//RetentionHandler handles destinations data retention requests
+type RetentionHandler struct {
+	destinationService *destinations.Service
+}
+
+//NewRetentionHandler returns configured RetentionHandler
+func NewRetentionHandler(destinationService *destinations.Service) *RetentionHandler {
+	return &RetentionHandler{destinationService: destinationService}
+}
+
+//PreviewHandler applies destination retention rules in dry run mode and returns what would be deleted
+func (rh *RetentionHandler) PreviewHandler(c *gin.Context) {
+	destinationID := c.Query("destination_id")
+	if destinationID == "" {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse("[destination_id] is required query parameter", nil))
+		return
+	}
+
+	storageProxy, ok := rh.destinationService.GetDestinationByID(destinationID)
+	if !ok {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse(fmt.Sprintf("Destination with id=[%s] does not exist", destinationID), nil))
+		return
+	}
+
+	results, err := storageProxy.ApplyRetention(true)
+	if err != nil {
+		if err == storages.ErrRetentionIsNotConfigured {
+			c.JSON(http.StatusBadRequest, middleware.ErrResponse(err.Error(), nil))
+			return
+		}
+
+		//results of successfully processed tables are returned as well
+		logging.Errorf("[%s] Error previewing retention: %v", destinationID, err)
+		if len(results) == 0 {
+			c.JSON(http.StatusBadRequest, middleware.ErrResponse("Error previewing retention", err))
+			return
+		}
+	}
+
+	if results == nil {
+		results = []*storages.RetentionResult{}
+	}
+
+	c.JSON(http.StatusOK, RetentionPreviewResponse{DestinationID: destinationID, Results: results})
+}
@@ -150,6 +150,10 @@ func (sh *SourcesHandler) TestSourcesHandler(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, middleware.ErrResponse("Failed to parse body", err))
 		return
 	}
+	//sql_query sources execute queries in SQL destinations
+	if sh.destinationsService != nil {
+		sourceConfig.Querier = sh.destinationsService
+	}
 	err := testSourceConnection(sourceConfig)
 	if err != nil {
 		if err == runner.ErrNotReady {
@@ -45,7 +45,7 @@ func TestMySQLPrimaryKeyRemoval(t *testing.T) {
 		Parameters: map[string]string{"tls": "false"},
 	}
 
-	mySQL, err := adapters.NewMySQL(ctx, dsConfig, logging.NewQueryLogger("test", nil, nil), typing.SQLTypes{})
+	mySQL, err := adapters.NewMySQL(ctx, dsConfig, nil, logging.NewQueryLogger("test", nil, nil), typing.SQLTypes{})
 	require.NoError(t, err)
 	require.NotNil(t, mySQL)
 
@@ -0,0 +1,104 @@
+package logfiles
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/jitsucom/jitsu/server/logging"
+)
+
+//EraseLines rewrites all log files (plain or gzipped) in the dir (recursively) without lines which match the func
+//active files of rolling writers are rotated before and skipped (they are written concurrently).
+//status files are skipped. Returns count of erased lines
+func EraseLines(dir string, match func(line []byte) bool) (int, error) {
+	logging.RotateFiles(dir)
+
+	var multiErr error
+	erased := 0
+	err := filepath.Walk(dir, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+
+		if info.IsDir() || strings.HasSuffix(filePath, statusFileExtension) || logging.IsActiveFile(filePath) {
+			return nil
+		}
+
+		fileErased, err := eraseFileLines(filePath, info.Mode(), match)
+		if err != nil {
+			multiErr = multierror.Append(multiErr, err)
+		}
+		erased += fileErased
+		return nil
+	})
+	if err != nil {
+		multiErr = multierror.Append(multiErr, fmt.Errorf("Error walking dir [%s]: %v", dir, err))
+	}
+
+	return erased, multiErr
+}
+
+func eraseFileLines(filePath string, mode os.FileMode, match func(line []byte) bool) (int, error) {
+	b, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return 0, fmt.Errorf("Error reading file [%s]: %v", filePath, err)
+	}
+
+	gzipped := strings.HasSuffix(filePath, ".gz")
+	if gzipped {
+		reader, err := gzip.NewReader(bytes.NewBuffer(b))
+		if err != nil {
+			return 0, fmt.Errorf("Error decompressing file [%s]: %v", filePath, err)
+		}
+		b, err = ioutil.ReadAll(reader)
+		if err != nil {
+			return 0, fmt.Errorf("Error decompressing file [%s]: %v", filePath, err)
+		}
+	}
+
+	output := bytes.Buffer{}
+	erased := 0
+	for _, line := range bytes.Split(b, []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		if match(line) {
+			erased++
+			continue
+		}
+		output.Write(line)
+		output.WriteString("\n")
+	}
+
+	if erased == 0 {
+		return 0, nil
+	}
+
+	payload := output.Bytes()
+	if gzipped {
+		compressed := bytes.Buffer{}
+		gzw := gzip.NewWriter(&compressed)
+		if _, err := gzw.Write(payload); err != nil {
+			return 0, fmt.Errorf("Error compressing file [%s]: %v", filePath, err)
+		}
+		if err := gzw.Close(); err != nil {
+			return 0, fmt.Errorf("Error compressing file [%s]: %v", filePath, err)
+		}
+		payload = compressed.Bytes()
+	}
+
+	if err := ioutil.WriteFile(filePath, payload, mode); err != nil {
+		return 0, fmt.Errorf("Error rewriting file [%s]: %v", filePath, err)
+	}
+
+	return erased, nil
+}
@@ -0,0 +1,80 @@
+package logfiles
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEraseLines(t *testing.T) {
+	dir, err := ioutil.TempDir("", "eraser")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	plainFile := path.Join(dir, "failed.dst=pg-2021-06-01T10-00-00.000.log")
+	require.NoError(t, ioutil.WriteFile(plainFile, []byte("erase 1\nkeep 1\n\nerase 2\n"), 0644))
+
+	gzippedFile := path.Join(dir, "failed.dst=pg-2021-06-02T10-00-00.000.log.gz")
+	compressed := bytes.Buffer{}
+	gzw := gzip.NewWriter(&compressed)
+	_, err = gzw.Write([]byte("keep 2\nerase 3\n"))
+	require.NoError(t, err)
+	require.NoError(t, gzw.Close())
+	require.NoError(t, ioutil.WriteFile(gzippedFile, compressed.Bytes(), 0644))
+
+	statusFile := path.Join(dir, "failed.dst=pg-2021-06-01T10-00-00.000.log"+statusFileExtension)
+	require.NoError(t, ioutil.WriteFile(statusFile, []byte("erase status\n"), 0644))
+
+	//active file is rotated before erasing and new records are written into a new active file
+	writer := logging.NewRollingWriter(&logging.Config{FileName: "failed.dst=active", FileDir: dir})
+	defer writer.Close()
+	_, err = writer.Write([]byte("erase 4\nkeep 3\n"))
+	require.NoError(t, err)
+
+	erased, err := EraseLines(dir, func(line []byte) bool {
+		return bytes.HasPrefix(line, []byte("erase"))
+	})
+	require.NoError(t, err)
+	require.Equal(t, 4, erased)
+
+	b, err := ioutil.ReadFile(plainFile)
+	require.NoError(t, err)
+	require.Equal(t, "keep 1\n", string(b))
+
+	gzr, err := gzip.NewReader(bytes.NewReader(readFile(t, gzippedFile)))
+	require.NoError(t, err)
+	b, err = ioutil.ReadAll(gzr)
+	require.NoError(t, err)
+	require.Equal(t, "keep 2\n", string(b))
+
+	require.Equal(t, "erase status\n", string(readFile(t, statusFile)), "status files are skipped")
+
+	rotated, err := filepath.Glob(path.Join(dir, "failed.dst=active-*.log"))
+	require.NoError(t, err)
+	require.Len(t, rotated, 1)
+	require.Equal(t, "keep 3\n", string(readFile(t, rotated[0])))
+
+	_, err = writer.Write([]byte("new\n"))
+	require.NoError(t, err)
+	require.Equal(t, "new\n", string(readFile(t, path.Join(dir, "failed.dst=active.log"))))
+}
+
+func TestEraseLinesNotExistingDir(t *testing.T) {
+	erased, err := EraseLines(path.Join(os.TempDir(), "not_existing_"+strings.Repeat("dir", 3)), func(line []byte) bool { return true })
+	require.NoError(t, err)
+	require.Equal(t, 0, erased)
+}
+
+func readFile(t *testing.T, filePath string) []byte {
+	b, err := ioutil.ReadFile(filePath)
+	require.NoError(t, err)
+	return b
+}
@@ -2,8 +2,10 @@ package logging
 
 import (
 	"io"
+	"os"
 	"path/filepath"
 	"regexp"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -20,6 +22,9 @@ const (
 //TokenIDExtractRegexp is a regex for reading already rotated and closed log files
 var TokenIDExtractRegexp = regexp.MustCompile("incoming.tok=(.*)-\\d\\d\\d\\d-\\d\\d-\\d\\dT")
 
+//openedWriters are rolling writers per active file path
+var openedWriters sync.Map
+
 //RollingWriterProxy for lumberjack.Logger
 //Rotate() only if file isn't empty
 type RollingWriterProxy struct {
@@ -49,6 +54,7 @@ func NewRollingWriter(config *Config) io.WriteCloser {
 	}
 
 	rwp := &RollingWriterProxy{lWriter: lWriter, records: 0, rotateOnClose: config.RotateOnClose}
+	openedWriters.Store(fileNamePath, rwp)
 
 	if config.RotationMin == 0 {
 		config.RotationMin = twentyFourHoursInMinutes
@@ -66,6 +72,37 @@ func NewRollingWriter(config *Config) io.WriteCloser {
 	return rwp
 }
 
+//RotateFiles rotates not empty active files of all opened rolling writers in the dir
+//rotated files aren't written anymore and can be rewritten (e.g. on user data deletion)
+func RotateFiles(dir string) {
+	dir = filepath.Clean(dir)
+	openedWriters.Range(func(key, value interface{}) bool {
+		if filepath.Dir(key.(string)) == dir {
+			value.(*RollingWriterProxy).rotateIfNotEmpty()
+		}
+		return true
+	})
+}
+
+//IsActiveFile returns true if the file is opened by a rolling writer
+func IsActiveFile(filePath string) bool {
+	_, ok := openedWriters.Load(filepath.Clean(filePath))
+	return ok
+}
+
+//rotateIfNotEmpty rotates the file even if it hasn't been written by this writer (e.g. it was written before restart)
+func (rwp *RollingWriterProxy) rotateIfNotEmpty() {
+	info, err := os.Stat(rwp.lWriter.Filename)
+	if err != nil || info.Size() == 0 {
+		return
+	}
+
+	atomic.StoreUint64(&rwp.records, 0)
+	if err := rwp.lWriter.Rotate(); err != nil {
+		log.Errorf("Error rotating log file [%s]: %v", rwp.lWriter.Filename, err)
+	}
+}
+
 func (rwp *RollingWriterProxy) rotate() {
 	if atomic.SwapUint64(&rwp.records, 0) > 0 {
 		if err := rwp.lWriter.Rotate(); err != nil {
@@ -84,5 +121,9 @@ func (rwp *RollingWriterProxy) Close() error {
 		rwp.rotate()
 	}
 
+	if writer, ok := openedWriters.Load(rwp.lWriter.Filename); ok && writer == rwp {
+		openedWriters.Delete(rwp.lWriter.Filename)
+	}
+
 	return rwp.lWriter.Close()
 }
@@ -9,7 +9,9 @@ import (
 	"github.com/jitsucom/jitsu/server/airbyte"
 	"github.com/jitsucom/jitsu/server/cmd"
 	"github.com/jitsucom/jitsu/server/events"
+	"github.com/jitsucom/jitsu/server/gdpr"
 	"github.com/jitsucom/jitsu/server/geo"
+	"github.com/jitsucom/jitsu/server/gitops"
 	"github.com/jitsucom/jitsu/server/multiplexing"
 	"github.com/jitsucom/jitsu/server/schema"
 	"github.com/jitsucom/jitsu/server/system"
@@ -33,6 +35,7 @@ import (
 	"github.com/jitsucom/jitsu/server/config"
 	"github.com/jitsucom/jitsu/server/coordination"
 	"github.com/jitsucom/jitsu/server/counters"
+	"github.com/jitsucom/jitsu/server/credentials"
 	"github.com/jitsucom/jitsu/server/destinations"
 	"github.com/jitsucom/jitsu/server/enrichment"
 	"github.com/jitsucom/jitsu/server/fallback"
@@ -47,6 +50,7 @@ import (
 	"github.com/jitsucom/jitsu/server/scheduling"
 	"github.com/jitsucom/jitsu/server/singer"
 	"github.com/jitsucom/jitsu/server/sources"
+	"github.com/jitsucom/jitsu/server/ssl"
 	"github.com/jitsucom/jitsu/server/storages"
 	"github.com/jitsucom/jitsu/server/synchronization"
 	"github.com/jitsucom/jitsu/server/telemetry"
@@ -271,6 +275,13 @@ func main() {
 		}
 	}()
 
+	// ** OAuth2 credentials **
+	credentialsService, err := credentials.Init(ctx, viper.Sub("credentials"), metaStorage, coordination.CredentialsLock(coordinationService))
+	if err != nil {
+		logging.Fatalf("Error initializing OAuth2 credentials: %v", err)
+	}
+	appconfig.Instance.ScheduleClosing(credentialsService)
+
 	// ** Destinations **
 	//events counters
 	counters.InitEvents(metaStorage)
@@ -294,10 +305,30 @@ func main() {
 
 	maxColumns := viper.GetInt("server.max_columns")
 	logging.Infof("📝 Limit server.max_columns is %d", maxColumns)
-	destinationsFactory := storages.NewFactory(ctx, logEventPath, geoService, coordinationService, eventsCache, loggerFactory, globalRecognitionConfiguration, metaStorage, maxColumns)
+	//Create source&collection sync and destinations maintenance scheduler
+	cronScheduler := scheduling.NewCronScheduler()
+	appconfig.Instance.ScheduleClosing(cronScheduler)
+
+	destinationsFactory := storages.NewFactory(ctx, logEventPath, geoService, coordinationService, eventsCache, loggerFactory, globalRecognitionConfiguration, metaStorage, maxColumns, cronScheduler)
+
+	//gitops configuration directory (api keys, destinations and sources)
+	gitopsProvider, err := gitops.NewProvider(viper.Sub("gitops"))
+	if err != nil {
+		logging.Fatal(err)
+	}
+
+	destinationsViper, destinationsSource := viper.Sub(destinationsKey), viper.GetString(destinationsKey)
+	sourcesViper, sourcesSource := viper.Sub(sourcesKey), viper.GetString(sourcesKey)
+	if gitopsProvider.IsEnabled() {
+		if destinationsViper != nil || sourcesViper != nil || viper.IsSet("api_keys") {
+			logging.Warnf("api_keys, destinations and sources configuration sections are ignored because gitops.path is configured")
+		}
+		destinationsViper, destinationsSource = nil, gitops.SourceName
+		sourcesViper, sourcesSource = nil, gitops.SourceName
+	}
 
 	//Create event destinations
-	destinationsService, err := destinations.NewService(viper.Sub(destinationsKey), viper.GetString(destinationsKey), destinationsFactory, loggerFactory, viper.GetBool("server.strict_auth_tokens"))
+	destinationsService, err := destinations.NewService(destinationsViper, destinationsSource, destinationsFactory, loggerFactory, viper.GetBool("server.strict_auth_tokens"))
 	if err != nil {
 		logging.Fatal(err)
 	}
@@ -311,24 +342,24 @@ func main() {
 
 	// ** Sources **
 
-	//Create source&collection sync scheduler
-	cronScheduler := scheduling.NewCronScheduler()
-	appconfig.Instance.ScheduleClosing(cronScheduler)
-
 	//Create sources
-	sourceService, err := sources.NewService(ctx, viper.Sub(sourcesKey), viper.GetString(sourcesKey), destinationsService, metaStorage, cronScheduler)
+	sourceService, err := sources.NewService(ctx, sourcesViper, sourcesSource, destinationsService, metaStorage, cronScheduler)
 	if err != nil {
 		logging.Fatal("Error creating sources service:", err)
 	}
 	appconfig.Instance.ScheduleClosing(sourceService)
 
+	gitopsProvider.Start(appconfig.Instance.AuthorizationService.UpdateTokens, destinationsService.UpdateDestinations, sourceService.UpdateSources)
+
 	storeTasksLogsForLastRuns := viper.GetInt("server.sync_tasks.store_logs.last_runs")
 	//Create sync task service
 	taskService := synchronization.NewTaskService(sourceService, destinationsService, metaStorage, coordinationService, storeTasksLogsForLastRuns)
 
-	//Start cron scheduler
+	//Start cron scheduler (sources are synced only if task service is configured)
 	if taskService.IsConfigured() {
 		cronScheduler.Start(taskService.ScheduleSyncFunc)
+	} else {
+		cronScheduler.Start(nil)
 	}
 
 	//sources sync tasks pool size
@@ -367,6 +398,10 @@ func main() {
 		logging.Fatal("Error creating fallback service:", err)
 	}
 
+	//user data deletion (right to be forgotten)
+	gdprService := gdpr.NewService(logEventPath, metaStorage, destinationsService,
+		viper.GetString("gdpr.anonymous_id_node"), viper.GetStringSlice("gdpr.identification_nodes"))
+
 	//** Segment API
 	//field mapper
 	mappings, err := schema.ConvertOldMappings(schema.Default, viper.GetStringSlice("compatibility.segment.endpoint"))
@@ -409,20 +444,60 @@ func main() {
 	walService := wal.NewService(logEventPath, loggerFactory.CreateWriteAheadLogger(), multiplexingService, processorHolder)
 	appconfig.Instance.ScheduleWriteAheadLogClosing(walService)
 
+	//automatic SSL certificates for custom domains
+	certificateService, err := ssl.NewCertificateService(viper.Sub("server.ssl.autocert"), metaStorage)
+	if err != nil {
+		logging.Fatal("Error creating SSL certificate service:", err)
+	}
+
 	router := routers.SetupRouter(adminToken, metaStorage, destinationsService, sourceService, taskService, fallbackService,
 		coordinationService, eventsCache, systemService, segmentRequestFieldsMapper, segmentCompatRequestFieldsMapper, processorHolder,
-		multiplexingService, walService, geoService)
+		multiplexingService, walService, geoService, gdprService, certificateService, gitopsProvider, credentialsService)
 
 	telemetry.ServerStart()
 	notifications.ServerStart()
 	logging.Info("🚀 Started server: " + appconfig.Instance.Authority)
+	handler := middleware.Cors(router, appconfig.Instance.AuthorizationService.GetClientOrigins)
 	server := &http.Server{
 		Addr:              appconfig.Instance.Authority,
-		Handler:           middleware.Cors(router, appconfig.Instance.AuthorizationService.GetClientOrigins),
+		Handler:           handler,
 		ReadTimeout:       time.Second * 60,
 		ReadHeaderTimeout: time.Second * 60,
 		IdleTimeout:       time.Second * 65,
 	}
+
+	if certificateService.IsEnabled() {
+		safego.Run(func() {
+			logging.Fatal(server.ListenAndServe())
+		})
+
+		//ACME HTTP-01 challenges are sent to port 80: serve them separately if server.port is different
+		if certificateService.ChallengeAuthority() != appconfig.Instance.Authority {
+			logging.Info("🔒 Started ACME challenges server: " + certificateService.ChallengeAuthority())
+			challengeServer := &http.Server{
+				Addr:              certificateService.ChallengeAuthority(),
+				Handler:           certificateService.ChallengeHandler(),
+				ReadTimeout:       time.Second * 60,
+				ReadHeaderTimeout: time.Second * 60,
+				IdleTimeout:       time.Second * 65,
+			}
+			safego.Run(func() {
+				logging.Fatal(challengeServer.ListenAndServe())
+			})
+		}
+
+		logging.Info("🔒 Started HTTPS server: " + certificateService.Authority())
+		tlsServer := &http.Server{
+			Addr:              certificateService.Authority(),
+			Handler:           handler,
+			TLSConfig:         certificateService.TLSConfig(),
+			ReadTimeout:       time.Second * 60,
+			ReadHeaderTimeout: time.Second * 60,
+			IdleTimeout:       time.Second * 65,
+		}
+		logging.Fatal(tlsServer.ListenAndServeTLS("", ""))
+	}
+
 	logging.Fatal(server.ListenAndServe())
 }
 
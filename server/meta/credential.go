@@ -0,0 +1,33 @@
+package meta
+
+import (
+	"encoding/json"
+	"time"
+)
+
+//OAuth2Credential is a Redis entity of OAuth2 client configuration and its current tokens
+//refresh token is rotated (overwritten) if authorization server returns a new one
+type OAuth2Credential struct {
+	ID           string            `json:"id,omitempty"`
+	GrantType    string            `json:"grant_type,omitempty"`
+	TokenURL     string            `json:"token_url,omitempty"`
+	ClientID     string            `json:"client_id,omitempty"`
+	ClientSecret string            `json:"client_secret,omitempty"`
+	Scopes       []string          `json:"scopes,omitempty"`
+	Params       map[string]string `json:"params,omitempty"`
+	RefreshToken string            `json:"refresh_token,omitempty"`
+	AccessToken  string            `json:"access_token,omitempty"`
+	TokenType    string            `json:"token_type,omitempty"`
+	Expiry       time.Time         `json:"expiry,omitempty"`
+	//ConfigHash is a hash of the configuration which the credential has been created from
+	//it is used for detecting configuration changes (e.g. new refresh token after re-authorization)
+	ConfigHash string `json:"config_hash,omitempty"`
+	UpdatedAt  string `json:"updated_at,omitempty"`
+	LastError  string `json:"last_error,omitempty"`
+}
+
+//Marshal returns serialized JSON object string
+func (oc *OAuth2Credential) Marshal() string {
+	b, _ := json.Marshal(oc)
+	return string(b)
+}
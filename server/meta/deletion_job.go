@@ -0,0 +1,50 @@
+package meta
+
+import "encoding/json"
+
+//DeletionJob is a Redis entity of user data deletion (GDPR right to be forgotten) request
+type DeletionJob struct {
+	ID           string                        `json:"id,omitempty"`
+	Status       string                        `json:"status,omitempty"`
+	CreatedAt    string                        `json:"created_at,omitempty"`
+	FinishedAt   string                        `json:"finished_at,omitempty"`
+	Identifiers  []string                      `json:"identifiers,omitempty"`
+	AnonymousIDs []string                      `json:"anonymous_ids,omitempty"`
+	Destinations map[string]*DeletionJobResult `json:"destinations,omitempty"`
+	MetaStorage  *DeletionJobResult            `json:"meta_storage,omitempty"`
+	Logs         *DeletionJobResult            `json:"logs,omitempty"`
+}
+
+//DeletionJobResult is a result of a deletion step (per destination, meta storage or log files)
+type DeletionJobResult struct {
+	Status string `json:"status,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+//Marshal returns serialized JSON object string
+func (dj *DeletionJob) Marshal() string {
+	b, _ := json.Marshal(dj)
+	return string(b)
+}
+
+//Copy returns a deep copy of the job
+func (dj *DeletionJob) Copy() *DeletionJob {
+	c := *dj
+	c.Identifiers = append([]string{}, dj.Identifiers...)
+	c.AnonymousIDs = append([]string{}, dj.AnonymousIDs...)
+	c.Destinations = make(map[string]*DeletionJobResult, len(dj.Destinations))
+	for destinationID, result := range dj.Destinations {
+		resultCopy := *result
+		c.Destinations[destinationID] = &resultCopy
+	}
+	if dj.MetaStorage != nil {
+		metaStorageCopy := *dj.MetaStorage
+		c.MetaStorage = &metaStorageCopy
+	}
+	if dj.Logs != nil {
+		logsCopy := *dj.Logs
+		c.Logs = &logsCopy
+	}
+
+	return &c
+}
@@ -8,6 +8,18 @@ type Dummy struct {
 func (d *Dummy) GetSignature(sourceID, collection, interval string) (string, error)   { return "", nil }
 func (d *Dummy) SaveSignature(sourceID, collection, interval, signature string) error { return nil }
 func (d *Dummy) DeleteSignature(sourceID, collection string) error                    { return nil }
+func (d *Dummy) GetObjectSignatures(sourceID, collection, destinationID string) (map[string]string, error) {
+	return map[string]string{}, nil
+}
+func (d *Dummy) DeleteObjectSignatures(sourceID, collection, destinationID string, objectKeys []string) error {
+	return nil
+}
+func (d *Dummy) SavePendingObjects(destinationID string, pendingObjects map[string]*PendingObject) error {
+	return nil
+}
+func (d *Dummy) AcknowledgePendingObject(destinationID, eventID string, delivered bool) error {
+	return nil
+}
 
 func (d *Dummy) SuccessEvents(id, namespace, eventType string, now time.Time, value int) error {
 	return nil
@@ -45,6 +57,21 @@ func (d *Dummy) GetAnonymousEvents(destinationID, anonymousID string) (map[strin
 	return map[string]string{}, nil
 }
 func (d *Dummy) DeleteAnonymousEvent(destinationID, anonymousID, eventID string) error { return nil }
+func (d *Dummy) DeleteAnonymousEvents(destinationID, anonymousID string) error         { return nil }
+func (d *Dummy) LinkAnonymousID(destinationID, identifier, anonymousID string) error   { return nil }
+func (d *Dummy) GetLinkedAnonymousIDs(destinationID, identifier string) ([]string, error) {
+	return []string{}, nil
+}
+func (d *Dummy) DeleteLinkedAnonymousIDs(destinationID, identifier string) error { return nil }
+
+func (d *Dummy) DeleteEvents(destinationID string, match func(event *Event) bool) (int, error) {
+	return 0, nil
+}
+func (d *Dummy) SaveDeletionJob(job *DeletionJob) error { return nil }
+func (d *Dummy) GetDeletionJob(jobID string) (*DeletionJob, error) {
+	return nil, ErrDeletionJobNotFound
+}
+func (d *Dummy) GetAllDeletionJobs() ([]DeletionJob, error) { return []DeletionJob{}, nil }
 
 func (d *Dummy) CreateTask(sourceID, collection string, task *Task, createdAt time.Time) error {
 	return nil
@@ -80,6 +107,28 @@ func (d *Dummy) GetTaskLogs(taskID string, from, to time.Time) ([]TaskLogRecord,
 func (d *Dummy) PushTask(task *Task) error { return nil }
 func (d *Dummy) PollTask() (*Task, error)  { return nil, nil }
 
+func (d *Dummy) GetCertificate(key string) ([]byte, error)     { return nil, ErrCertificateNotFound }
+func (d *Dummy) SaveCertificate(key string, data []byte) error { return nil }
+func (d *Dummy) DeleteCertificate(key string) error            { return nil }
+
+func (d *Dummy) SaveCredential(credential *OAuth2Credential) error { return nil }
+func (d *Dummy) GetCredential(credentialID string) (*OAuth2Credential, error) {
+	return nil, ErrCredentialNotFound
+}
+func (d *Dummy) GetAllCredentials() ([]OAuth2Credential, error) { return []OAuth2Credential{}, nil }
+func (d *Dummy) DeleteCredential(credentialID string) error     { return nil }
+
+func (d *Dummy) SaveSnowpipeFile(destinationID string, file *SnowpipeFile) error { return nil }
+func (d *Dummy) GetSnowpipeFiles(destinationID string) ([]*SnowpipeFile, error) {
+	return []*SnowpipeFile{}, nil
+}
+func (d *Dummy) DeleteSnowpipeFile(destinationID string, file *SnowpipeFile) error { return nil }
+
+func (d *Dummy) SaveDestinationTable(destinationID, tableName string) error { return nil }
+func (d *Dummy) GetDestinationTables(destinationID string) ([]string, error) {
+	return []string{}, nil
+}
+
 func (d *Dummy) GetOrCreateClusterID(generatedClusterID string) string { return generatedClusterID }
 
 func (d *Dummy) Type() string {
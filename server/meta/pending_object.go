@@ -0,0 +1,18 @@
+package meta
+
+import "encoding/json"
+
+//PendingObject is a synchronized source object which has been sent to HTTP destination but hasn't been delivered yet
+//the object signature is saved only after delivery
+type PendingObject struct {
+	SourceID   string `json:"source_id"`
+	Collection string `json:"collection"`
+	Key        string `json:"key"`
+	Signature  string `json:"signature"`
+}
+
+//Marshal returns serialized JSON object string
+func (po *PendingObject) Marshal() string {
+	b, _ := json.Marshal(po)
+	return string(b)
+}
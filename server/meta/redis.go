@@ -34,6 +34,21 @@ const (
 	syncTasksPrefix  = "sync_tasks#"
 	taskHeartBeatKey = "sync_tasks_heartbeat"
 
+	deletionJobsKey = "gdpr_deletion_jobs"
+
+	sslCertificatesKey = "ssl_certificates"
+
+	oauth2CredentialsKey = "oauth2_credentials"
+
+	snowpipeFilesKeyPrefix = "snowpipe_files:destination#"
+
+	destinationTablesKeyPrefix = "destination_tables:destination#"
+
+	//objectSignaturesChunkSize is max number of hash fields in one HDEL command
+	objectSignaturesChunkSize = 10000
+	//pendingObjectSecondsTTL is a lifetime of objects which haven't been delivered (7 days)
+	pendingObjectSecondsTTL = 7 * 24 * 60 * 60
+
 	responseTimestampLayout = "2006-01-02T15:04:05+0000"
 
 	PushEventType = "push"
@@ -48,7 +63,10 @@ const (
 )
 
 var (
-	ErrTaskNotFound = errors.New("Sync task wasn't found")
+	ErrTaskNotFound        = errors.New("Sync task wasn't found")
+	ErrDeletionJobNotFound = errors.New("Deletion job wasn't found")
+	ErrCertificateNotFound = errors.New("Certificate wasn't found")
+	ErrCredentialNotFound  = errors.New("Credential wasn't found")
 )
 
 type Redis struct {
@@ -60,6 +78,9 @@ type Redis struct {
 //
 //** Sources state**
 //source#sourceID:collection#collectionID:chunks [sourceID, collectionID] - hashtable with signatures
+//source#sourceID:collection#collectionID:destination#destinationID:objects [object_key] {signature} - hashtable with signatures of objects delivered to HTTP destination (SQL query results)
+//source#sourceID:collection#collectionID:destinations [destinationID1, destinationID2] - set of destination ids with signatures of objects
+//destination#destinationID:pending_object#eventID {source_id, collection, key, signature} - object which has been sent to HTTP destination but hasn't been delivered yet (with TTL)
 //
 //** Events counters **
 // * per destination *
@@ -86,6 +107,22 @@ type Redis struct {
 //
 //** Retroactive user recognition **
 //anonymous_events:destination_id#${destination_id}:anonymous_id#${cookies_anonymous_id} [event_id] {event JSON} - hashtable with all anonymous events
+//anonymous_links:destination_id#${destination_id}:identifier#${identification_value} [anonymous_id1, anonymous_id2] - set of anonymous ids which were identified with the value
+//
+//** User data deletion (GDPR) **
+//gdpr_deletion_jobs [job_id] {job JSON} - hashtable with all deletion jobs
+//
+//** SSL certificates **
+//ssl_certificates [key] {data} - hashtable with autocert cache data (account key, domain certificates, http-01 challenge tokens)
+//
+//** OAuth2 credentials **
+//oauth2_credentials [credential_id] {credential JSON} - hashtable with OAuth2 client configurations and current tokens
+//
+//** Snowpipe **
+//snowpipe_files:destination#destinationID [pipe/path] {file JSON} - hashtable with submitted files which load results haven't been received yet
+//
+//** Destination tables **
+//destination_tables:destination#destinationID [tableName1, tableName2] - set with names of tables which the destination writes to
 //
 //** Sources Synchronization **
 // - task_id = $source_$collection_$UUID
@@ -148,12 +185,139 @@ func (r *Redis) SaveSignature(sourceID, collection, interval, signature string)
 	return nil
 }
 
-//DeleteSignature deletes source collection signature from Redis
+//DeleteSignature deletes source collection signature and signatures of objects delivered to all destinations from Redis
 func (r *Redis) DeleteSignature(sourceID, collection string) error {
 	key := "source#" + sourceID + ":collection#" + collection + ":chunks"
+	destinationsKey := getObjectDestinationsKey(sourceID, collection)
 	connection := r.pool.Get()
 	defer connection.Close()
-	_, err := connection.Do("DEL", key)
+
+	destinationIDs, err := redis.Strings(connection.Do("SMEMBERS", destinationsKey))
+	noticeError(err)
+	if err != nil && err != redis.ErrNil {
+		return err
+	}
+
+	keys := redis.Args{key, destinationsKey}
+	for _, destinationID := range destinationIDs {
+		keys = keys.Add(getObjectSignaturesKey(sourceID, collection, destinationID))
+	}
+	_, err = connection.Do("DEL", keys...)
+	noticeError(err)
+	if err != nil && err != redis.ErrNil {
+		return err
+	}
+
+	return nil
+}
+
+//GetObjectSignatures returns signatures of objects which have been delivered to the destination (object key -> signature)
+func (r *Redis) GetObjectSignatures(sourceID, collection, destinationID string) (map[string]string, error) {
+	key := getObjectSignaturesKey(sourceID, collection, destinationID)
+	connection := r.pool.Get()
+	defer connection.Close()
+	signatures, err := redis.StringMap(connection.Do("HGETALL", key))
+	noticeError(err)
+	if err != nil && err != redis.ErrNil {
+		return nil, err
+	}
+
+	return signatures, nil
+}
+
+//DeleteObjectSignatures deletes signatures of objects which aren't in the source anymore
+func (r *Redis) DeleteObjectSignatures(sourceID, collection, destinationID string, objectKeys []string) error {
+	if len(objectKeys) == 0 {
+		return nil
+	}
+
+	key := getObjectSignaturesKey(sourceID, collection, destinationID)
+	connection := r.pool.Get()
+	defer connection.Close()
+
+	for start := 0; start < len(objectKeys); start += objectSignaturesChunkSize {
+		end := start + objectSignaturesChunkSize
+		if end > len(objectKeys) {
+			end = len(objectKeys)
+		}
+
+		_, err := connection.Do("HDEL", redis.Args{key}.AddFlat(objectKeys[start:end])...)
+		noticeError(err)
+		if err != nil && err != redis.ErrNil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//SavePendingObjects saves objects which have been sent to the destination (event ID -> object) in one transaction
+//signatures of the objects are saved on delivery (see AcknowledgePendingObject)
+func (r *Redis) SavePendingObjects(destinationID string, pendingObjects map[string]*PendingObject) error {
+	if len(pendingObjects) == 0 {
+		return nil
+	}
+
+	connection := r.pool.Get()
+	defer connection.Close()
+
+	if err := connection.Send("MULTI"); err != nil {
+		return err
+	}
+	for eventID, pendingObject := range pendingObjects {
+		if err := connection.Send("SET", getPendingObjectKey(destinationID, eventID), pendingObject.Marshal(), "EX", pendingObjectSecondsTTL); err != nil {
+			return err
+		}
+	}
+
+	_, err := connection.Do("EXEC")
+	noticeError(err)
+	if err != nil && err != redis.ErrNil {
+		return err
+	}
+
+	return nil
+}
+
+//AcknowledgePendingObject deletes pending object by event ID and saves its signature if the object has been delivered
+//undelivered objects don't have signatures and will be sent again in the next sync
+func (r *Redis) AcknowledgePendingObject(destinationID, eventID string, delivered bool) error {
+	pendingObjectKey := getPendingObjectKey(destinationID, eventID)
+	connection := r.pool.Get()
+	defer connection.Close()
+
+	payload, err := redis.String(connection.Do("GET", pendingObjectKey))
+	noticeError(err)
+	if err != nil {
+		if err == redis.ErrNil {
+			return nil
+		}
+
+		return err
+	}
+
+	_, err = connection.Do("DEL", pendingObjectKey)
+	noticeError(err)
+	if err != nil && err != redis.ErrNil {
+		return err
+	}
+
+	if !delivered {
+		return nil
+	}
+
+	pendingObject := &PendingObject{}
+	if err := json.Unmarshal([]byte(payload), pendingObject); err != nil {
+		return fmt.Errorf("Error deserializing pending object [%s]: %v", pendingObjectKey, err)
+	}
+
+	_, err = connection.Do("HSET", getObjectSignaturesKey(pendingObject.SourceID, pendingObject.Collection, destinationID), pendingObject.Key, pendingObject.Signature)
+	noticeError(err)
+	if err != nil && err != redis.ErrNil {
+		return err
+	}
+
+	_, err = connection.Do("SADD", getObjectDestinationsKey(pendingObject.SourceID, pendingObject.Collection), destinationID)
 	noticeError(err)
 	if err != nil && err != redis.ErrNil {
 		return err
@@ -396,6 +560,370 @@ func (r *Redis) DeleteAnonymousEvent(destinationID, anonymousID, eventID string)
 	return nil
 }
 
+//DeleteAnonymousEvents deletes all anonymous events by destination ID and anonymous ID key
+func (r *Redis) DeleteAnonymousEvents(destinationID, anonymousID string) error {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	anonymousEventKey := "anonymous_events:destination_id#" + destinationID + ":anonymous_id#" + anonymousID
+	_, err := conn.Do("DEL", anonymousEventKey)
+	noticeError(err)
+	if err != nil && err != redis.ErrNil {
+		return err
+	}
+
+	return nil
+}
+
+//LinkAnonymousID saves anonymous ID into the set of anonymous IDs which were identified with identifier value
+func (r *Redis) LinkAnonymousID(destinationID, identifier, anonymousID string) error {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	_, err := conn.Do("SADD", getAnonymousLinksKey(destinationID, identifier), anonymousID)
+	noticeError(err)
+	if err != nil && err != redis.ErrNil {
+		return err
+	}
+
+	return nil
+}
+
+//GetLinkedAnonymousIDs returns all anonymous IDs which were identified with identifier value
+func (r *Redis) GetLinkedAnonymousIDs(destinationID, identifier string) ([]string, error) {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	anonymousIDs, err := redis.Strings(conn.Do("SMEMBERS", getAnonymousLinksKey(destinationID, identifier)))
+	noticeError(err)
+	if err != nil && err != redis.ErrNil {
+		return nil, err
+	}
+
+	return anonymousIDs, nil
+}
+
+//DeleteLinkedAnonymousIDs deletes the set of anonymous IDs which were identified with identifier value
+func (r *Redis) DeleteLinkedAnonymousIDs(destinationID, identifier string) error {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	_, err := conn.Do("DEL", getAnonymousLinksKey(destinationID, identifier))
+	noticeError(err)
+	if err != nil && err != redis.ErrNil {
+		return err
+	}
+
+	return nil
+}
+
+//DeleteEvents deletes all cached events of the destination which match the func
+//returns count of deleted events
+func (r *Redis) DeleteEvents(destinationID string, match func(event *Event) bool) (int, error) {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	lastEventsIndexKey := "last_events_index:destination#" + destinationID
+	eventIDs, err := redis.Strings(conn.Do("ZRANGE", lastEventsIndexKey, 0, -1))
+	noticeError(err)
+	if err != nil && err != redis.ErrNil {
+		return 0, err
+	}
+
+	deleted := 0
+	for _, eventID := range eventIDs {
+		lastEventsKey := "last_events:destination#" + destinationID + ":id#" + eventID
+		eventValues, err := redis.Values(conn.Do("HGETALL", lastEventsKey))
+		noticeError(err)
+		if err != nil && err != redis.ErrNil {
+			return deleted, err
+		}
+
+		event := &Event{}
+		if err := redis.ScanStruct(eventValues, event); err != nil {
+			return deleted, fmt.Errorf("Error deserializing event struct key [%s]: %v", lastEventsKey, err)
+		}
+
+		if !match(event) {
+			continue
+		}
+
+		if _, err := conn.Do("DEL", lastEventsKey); err != nil {
+			noticeError(err)
+			return deleted, err
+		}
+		if _, err := conn.Do("ZREM", lastEventsIndexKey, eventID); err != nil {
+			noticeError(err)
+			return deleted, err
+		}
+		deleted++
+	}
+
+	return deleted, nil
+}
+
+//SaveDeletionJob creates or updates deletion job
+func (r *Redis) SaveDeletionJob(job *DeletionJob) error {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	_, err := conn.Do("HSET", deletionJobsKey, job.ID, job.Marshal())
+	noticeError(err)
+	if err != nil && err != redis.ErrNil {
+		return err
+	}
+
+	return nil
+}
+
+//GetDeletionJob returns deletion job by ID or ErrDeletionJobNotFound
+func (r *Redis) GetDeletionJob(jobID string) (*DeletionJob, error) {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	payload, err := redis.String(conn.Do("HGET", deletionJobsKey, jobID))
+	noticeError(err)
+	if err != nil {
+		if err == redis.ErrNil {
+			return nil, ErrDeletionJobNotFound
+		}
+
+		return nil, err
+	}
+
+	job := &DeletionJob{}
+	if err := json.Unmarshal([]byte(payload), job); err != nil {
+		return nil, fmt.Errorf("Error deserializing deletion job [%s]: %v", jobID, err)
+	}
+
+	return job, nil
+}
+
+//GetAllDeletionJobs returns all deletion jobs
+func (r *Redis) GetAllDeletionJobs() ([]DeletionJob, error) {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	jobsMap, err := redis.StringMap(conn.Do("HGETALL", deletionJobsKey))
+	noticeError(err)
+	if err != nil && err != redis.ErrNil {
+		return nil, err
+	}
+
+	jobs := make([]DeletionJob, 0, len(jobsMap))
+	for jobID, payload := range jobsMap {
+		job := DeletionJob{}
+		if err := json.Unmarshal([]byte(payload), &job); err != nil {
+			return nil, fmt.Errorf("Error deserializing deletion job [%s]: %v", jobID, err)
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs, nil
+}
+
+//GetCertificate returns certificate data by key or ErrCertificateNotFound
+func (r *Redis) GetCertificate(key string) ([]byte, error) {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	data, err := redis.Bytes(conn.Do("HGET", sslCertificatesKey, key))
+	noticeError(err)
+	if err != nil {
+		if err == redis.ErrNil {
+			return nil, ErrCertificateNotFound
+		}
+
+		return nil, err
+	}
+
+	return data, nil
+}
+
+//SaveCertificate saves certificate data by key
+func (r *Redis) SaveCertificate(key string, data []byte) error {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	_, err := conn.Do("HSET", sslCertificatesKey, key, data)
+	noticeError(err)
+	if err != nil && err != redis.ErrNil {
+		return err
+	}
+
+	return nil
+}
+
+//DeleteCertificate deletes certificate data by key
+func (r *Redis) DeleteCertificate(key string) error {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	_, err := conn.Do("HDEL", sslCertificatesKey, key)
+	noticeError(err)
+	if err != nil && err != redis.ErrNil {
+		return err
+	}
+
+	return nil
+}
+
+//SaveCredential saves OAuth2 credential by its ID
+func (r *Redis) SaveCredential(credential *OAuth2Credential) error {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	_, err := conn.Do("HSET", oauth2CredentialsKey, credential.ID, credential.Marshal())
+	noticeError(err)
+	if err != nil && err != redis.ErrNil {
+		return err
+	}
+
+	return nil
+}
+
+//GetCredential returns OAuth2 credential by ID or ErrCredentialNotFound
+func (r *Redis) GetCredential(credentialID string) (*OAuth2Credential, error) {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	payload, err := redis.String(conn.Do("HGET", oauth2CredentialsKey, credentialID))
+	noticeError(err)
+	if err != nil {
+		if err == redis.ErrNil {
+			return nil, ErrCredentialNotFound
+		}
+
+		return nil, err
+	}
+
+	credential := &OAuth2Credential{}
+	if err := json.Unmarshal([]byte(payload), credential); err != nil {
+		return nil, fmt.Errorf("Error deserializing credential [%s]: %v", credentialID, err)
+	}
+
+	return credential, nil
+}
+
+//GetAllCredentials returns all OAuth2 credentials
+func (r *Redis) GetAllCredentials() ([]OAuth2Credential, error) {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	credentialsMap, err := redis.StringMap(conn.Do("HGETALL", oauth2CredentialsKey))
+	noticeError(err)
+	if err != nil && err != redis.ErrNil {
+		return nil, err
+	}
+
+	credentials := make([]OAuth2Credential, 0, len(credentialsMap))
+	for credentialID, payload := range credentialsMap {
+		credential := OAuth2Credential{}
+		if err := json.Unmarshal([]byte(payload), &credential); err != nil {
+			return nil, fmt.Errorf("Error deserializing credential [%s]: %v", credentialID, err)
+		}
+		credentials = append(credentials, credential)
+	}
+
+	return credentials, nil
+}
+
+//DeleteCredential deletes OAuth2 credential by ID
+func (r *Redis) DeleteCredential(credentialID string) error {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	deleted, err := redis.Int(conn.Do("HDEL", oauth2CredentialsKey, credentialID))
+	noticeError(err)
+	if err != nil && err != redis.ErrNil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrCredentialNotFound
+	}
+
+	return nil
+}
+
+//SaveSnowpipeFile saves (or updates) pending Snowpipe file of the destination
+func (r *Redis) SaveSnowpipeFile(destinationID string, file *SnowpipeFile) error {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	_, err := conn.Do("HSET", snowpipeFilesKeyPrefix+destinationID, file.Key(), file.Marshal())
+	noticeError(err)
+	if err != nil && err != redis.ErrNil {
+		return err
+	}
+
+	return nil
+}
+
+//GetSnowpipeFiles returns all pending Snowpipe files of the destination
+func (r *Redis) GetSnowpipeFiles(destinationID string) ([]*SnowpipeFile, error) {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	filesMap, err := redis.StringMap(conn.Do("HGETALL", snowpipeFilesKeyPrefix+destinationID))
+	noticeError(err)
+	if err != nil && err != redis.ErrNil {
+		return nil, err
+	}
+
+	files := make([]*SnowpipeFile, 0, len(filesMap))
+	for key, payload := range filesMap {
+		file := &SnowpipeFile{}
+		if err := json.Unmarshal([]byte(payload), file); err != nil {
+			return nil, fmt.Errorf("Error deserializing Snowpipe file [%s]: %v", key, err)
+		}
+		files = append(files, file)
+	}
+
+	return files, nil
+}
+
+//DeleteSnowpipeFile deletes pending Snowpipe file of the destination
+func (r *Redis) DeleteSnowpipeFile(destinationID string, file *SnowpipeFile) error {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	_, err := conn.Do("HDEL", snowpipeFilesKeyPrefix+destinationID, file.Key())
+	noticeError(err)
+	if err != nil && err != redis.ErrNil {
+		return err
+	}
+
+	return nil
+}
+
+//SaveDestinationTable adds table name into the set of tables which the destination writes to
+func (r *Redis) SaveDestinationTable(destinationID, tableName string) error {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	_, err := conn.Do("SADD", destinationTablesKeyPrefix+destinationID, tableName)
+	noticeError(err)
+	if err != nil && err != redis.ErrNil {
+		return err
+	}
+
+	return nil
+}
+
+//GetDestinationTables returns names of tables which the destination writes to
+func (r *Redis) GetDestinationTables(destinationID string) ([]string, error) {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	tableNames, err := redis.Strings(conn.Do("SMEMBERS", destinationTablesKeyPrefix+destinationID))
+	noticeError(err)
+	if err != nil && err != redis.ErrNil {
+		return nil, err
+	}
+
+	return tableNames, nil
+}
+
 //CreateTask saves task into Redis and add Task ID in index
 func (r *Redis) CreateTask(sourceID, collection string, task *Task, createdAt time.Time) error {
 	err := r.upsertTask(task)
@@ -1230,4 +1758,20 @@ func extractOriginalEventId(eventId string) string {
 		return parts[0]
 	}
 	return eventId
-}
\ No newline at end of file
+}
+
+func getAnonymousLinksKey(destinationID, identifier string) string {
+	return "anonymous_links:destination_id#" + destinationID + ":identifier#" + identifier
+}
+
+func getObjectSignaturesKey(sourceID, collection, destinationID string) string {
+	return "source#" + sourceID + ":collection#" + collection + ":destination#" + destinationID + ":objects"
+}
+
+func getObjectDestinationsKey(sourceID, collection string) string {
+	return "source#" + sourceID + ":collection#" + collection + ":destinations"
+}
+
+func getPendingObjectKey(destinationID, eventID string) string {
+	return "destination#" + destinationID + ":pending_object#" + eventID
+}
@@ -0,0 +1,25 @@
+package meta
+
+import (
+	"encoding/json"
+	"time"
+)
+
+//SnowpipeFile is a Redis entity of a staged file which has been submitted into Snowpipe but its load result hasn't been received yet
+type SnowpipeFile struct {
+	Pipe        string    `json:"pipe,omitempty"`
+	Path        string    `json:"path,omitempty"`
+	SubmittedAt time.Time `json:"submitted_at,omitempty"`
+	Attempts    int       `json:"attempts,omitempty"`
+}
+
+//Key returns hash field of the file: pipe and path relative to the stage
+func (sf *SnowpipeFile) Key() string {
+	return sf.Pipe + "/" + sf.Path
+}
+
+//Marshal returns serialized JSON object string
+func (sf *SnowpipeFile) Marshal() string {
+	b, _ := json.Marshal(sf)
+	return string(b)
+}
@@ -19,6 +19,12 @@ type Storage interface {
 	GetSignature(sourceID, collection, interval string) (string, error)
 	SaveSignature(sourceID, collection, interval, signature string) error
 	DeleteSignature(sourceID, collection string) error
+	//signatures of objects which have been delivered to HTTP destinations (object key -> signature)
+	GetObjectSignatures(sourceID, collection, destinationID string) (map[string]string, error)
+	DeleteObjectSignatures(sourceID, collection, destinationID string, objectKeys []string) error
+	//objects which have been sent to HTTP destinations and are waiting for delivery (by event ID)
+	SavePendingObjects(destinationID string, pendingObjects map[string]*PendingObject) error
+	AcknowledgePendingObject(destinationID, eventID string, delivered bool) error
 
 	//** Counters **
 	//events counters
@@ -46,6 +52,36 @@ type Storage interface {
 	SaveAnonymousEvent(destinationID, anonymousID, eventID, payload string) error
 	GetAnonymousEvents(destinationID, anonymousID string) (map[string]string, error)
 	DeleteAnonymousEvent(destinationID, anonymousID, eventID string) error
+	DeleteAnonymousEvents(destinationID, anonymousID string) error
+	LinkAnonymousID(destinationID, identifier, anonymousID string) error
+	GetLinkedAnonymousIDs(destinationID, identifier string) ([]string, error)
+	DeleteLinkedAnonymousIDs(destinationID, identifier string) error
+
+	// ** User data deletion (GDPR) **
+	DeleteEvents(destinationID string, match func(event *Event) bool) (int, error)
+	SaveDeletionJob(job *DeletionJob) error
+	GetDeletionJob(jobID string) (*DeletionJob, error)
+	GetAllDeletionJobs() ([]DeletionJob, error)
+
+	// ** SSL certificates (autocert cache) **
+	GetCertificate(key string) ([]byte, error)
+	SaveCertificate(key string, data []byte) error
+	DeleteCertificate(key string) error
+
+	// ** OAuth2 credentials **
+	SaveCredential(credential *OAuth2Credential) error
+	GetCredential(credentialID string) (*OAuth2Credential, error)
+	GetAllCredentials() ([]OAuth2Credential, error)
+	DeleteCredential(credentialID string) error
+
+	// ** Snowpipe pending files **
+	SaveSnowpipeFile(destinationID string, file *SnowpipeFile) error
+	GetSnowpipeFiles(destinationID string) ([]*SnowpipeFile, error)
+	DeleteSnowpipeFile(destinationID string, file *SnowpipeFile) error
+
+	// ** Destination tables **
+	SaveDestinationTable(destinationID, tableName string) error
+	GetDestinationTables(destinationID string) ([]string, error)
 
 	// ** Sync Tasks **
 	CreateTask(sourceID, collection string, task *Task, createdAt time.Time) error
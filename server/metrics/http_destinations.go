@@ -0,0 +1,42 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var httpDestinationLabels = []string{"project_id", "destination_id"}
+
+var (
+	circuitBreakerState *prometheus.GaugeVec
+	rateLimitedRequests *prometheus.CounterVec
+)
+
+func initHTTPDestinations() {
+	circuitBreakerState = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "eventnative",
+		Subsystem: "destinations",
+		Name:      "circuit_breaker_state",
+	}, httpDestinationLabels)
+	rateLimitedRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "eventnative",
+		Subsystem: "destinations",
+		Name:      "rate_limited_requests",
+	}, httpDestinationLabels)
+}
+
+//CircuitBreakerState sets HTTP destination circuit breaker state: 0 - closed, 1 - half open, 2 - open
+func CircuitBreakerState(destinationName string, value int) {
+	if Enabled {
+		projectID, destinationID := extractLabels(destinationName)
+		circuitBreakerState.WithLabelValues(projectID, destinationID).Set(float64(value))
+	}
+}
+
+//RateLimitedRequest increments counter of HTTP requests which have been rejected by destination with 429 code
+func RateLimitedRequest(destinationName string) {
+	if Enabled {
+		projectID, destinationID := extractLabels(destinationName)
+		rateLimitedRequests.WithLabelValues(projectID, destinationID).Inc()
+	}
+}
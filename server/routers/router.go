@@ -1,7 +1,9 @@
 package routers
 
 import (
+	"github.com/jitsucom/jitsu/server/gdpr"
 	"github.com/jitsucom/jitsu/server/geo"
+	"github.com/jitsucom/jitsu/server/gitops"
 	"github.com/jitsucom/jitsu/server/multiplexing"
 	"github.com/jitsucom/jitsu/server/wal"
 	"net/http"
@@ -11,6 +13,7 @@ import (
 	"github.com/jitsucom/jitsu/server/appconfig"
 	"github.com/jitsucom/jitsu/server/caching"
 	"github.com/jitsucom/jitsu/server/cluster"
+	"github.com/jitsucom/jitsu/server/credentials"
 	"github.com/jitsucom/jitsu/server/destinations"
 	"github.com/jitsucom/jitsu/server/events"
 	"github.com/jitsucom/jitsu/server/fallback"
@@ -19,6 +22,7 @@ import (
 	"github.com/jitsucom/jitsu/server/metrics"
 	"github.com/jitsucom/jitsu/server/middleware"
 	"github.com/jitsucom/jitsu/server/sources"
+	"github.com/jitsucom/jitsu/server/ssl"
 	"github.com/jitsucom/jitsu/server/synchronization"
 	"github.com/jitsucom/jitsu/server/system"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -28,7 +32,8 @@ import (
 func SetupRouter(adminToken string, metaStorage meta.Storage, destinations *destinations.Service, sourcesService *sources.Service, taskService *synchronization.TaskService,
 	fallbackService *fallback.Service, clusterManager cluster.Manager, eventsCache *caching.EventsCache, systemService *system.Service,
 	segmentEndpointFieldMapper, segmentCompatEndpointFieldMapper events.Mapper, processorHolder *events.ProcessorHolder,
-	multiplexingService *multiplexing.Service, walService *wal.Service, geoService *geo.Service) *gin.Engine {
+	multiplexingService *multiplexing.Service, walService *wal.Service, geoService *geo.Service, gdprService *gdpr.Service,
+	certificateService *ssl.CertificateService, gitopsProvider *gitops.Provider, credentialsService *credentials.Service) *gin.Engine {
 	gin.SetMode(gin.ReleaseMode)
 
 	router := gin.New() //gin.Default()
@@ -38,6 +43,11 @@ func SetupRouter(adminToken string, metaStorage meta.Storage, destinations *dest
 		c.String(http.StatusOK, "pong")
 	})
 
+	//ACME HTTP-01 challenge for automatic SSL certificates
+	if certificateService.IsEnabled() {
+		router.GET(ssl.ChallengePath+":token", gin.WrapH(certificateService.ChallengeHandler()))
+	}
+
 	publicURL := viper.GetString("server.public_url")
 	configuratorURL := viper.GetString("server.configurator_url")
 
@@ -67,6 +77,11 @@ func SetupRouter(adminToken string, metaStorage meta.Storage, destinations *dest
 
 	geoDataResolverHandler := handlers.NewGeoDataResolverHandler(geoService)
 
+	deletionHandler := handlers.NewDeletionHandler(gdprService)
+	retentionHandler := handlers.NewRetentionHandler(destinations)
+	destinationsStatusHandler := handlers.NewDestinationsStatusHandler(destinations)
+	credentialsHandler := handlers.NewCredentialsHandler(credentialsService)
+
 	adminTokenMiddleware := middleware.AdminToken{Token: adminToken}
 	apiV1 := router.Group("/api/v1")
 	{
@@ -93,6 +108,8 @@ func SetupRouter(adminToken string, metaStorage meta.Storage, destinations *dest
 		apiV1.GET("/geo_data_resolvers/editions", adminTokenMiddleware.AdminAuth(geoDataResolverHandler.EditionsHandler))
 		apiV1.POST("/geo_data_resolvers/test", adminTokenMiddleware.AdminAuth(geoDataResolverHandler.TestHandler))
 		apiV1.POST("/destinations/test", adminTokenMiddleware.AdminAuth(handlers.DestinationsHandler))
+		apiV1.GET("/destinations/retention", adminTokenMiddleware.AdminAuth(retentionHandler.PreviewHandler))
+		apiV1.GET("/destinations/status", adminTokenMiddleware.AdminAuth(destinationsStatusHandler.Handler))
 		apiV1.POST("/templates/evaluate", adminTokenMiddleware.AdminAuth(handlers.EventTemplateHandler))
 
 		sourcesRoute := apiV1.Group("/sources")
@@ -118,6 +135,22 @@ func SetupRouter(adminToken string, metaStorage meta.Storage, destinations *dest
 		apiV1.GET("/fallback", adminTokenMiddleware.AdminAuth(fallbackHandler.GetHandler))
 		apiV1.POST("/replay", adminTokenMiddleware.AdminAuth(fallbackHandler.ReplayHandler))
 
+		apiV1.GET("/gitops/revision", adminTokenMiddleware.AdminAuth(handlers.NewGitOpsHandler(gitopsProvider).StatusHandler))
+
+		gdprRoute := apiV1.Group("/gdpr")
+		{
+			gdprRoute.POST("/deletions", adminTokenMiddleware.AdminAuth(deletionHandler.CreateHandler))
+			gdprRoute.GET("/deletions", adminTokenMiddleware.AdminAuth(deletionHandler.GetAllHandler))
+			gdprRoute.GET("/deletions/:jobID", adminTokenMiddleware.AdminAuth(deletionHandler.GetByIDHandler))
+		}
+
+		credentialsRoute := apiV1.Group("/credentials")
+		{
+			credentialsRoute.GET("", adminTokenMiddleware.AdminAuth(credentialsHandler.GetAllHandler))
+			credentialsRoute.POST("", adminTokenMiddleware.AdminAuth(credentialsHandler.SaveHandler))
+			credentialsRoute.DELETE("/:credentialID", adminTokenMiddleware.AdminAuth(credentialsHandler.DeleteHandler))
+		}
+
 		apiV1.GET("/airbyte/:dockerImageName/spec", adminTokenMiddleware.AdminAuth(airbyteHandler.SpecHandler))
 		apiV1.GET("/airbyte/:dockerImageName/versions", adminTokenMiddleware.AdminAuth(airbyteHandler.VersionsHandler))
 		apiV1.POST("/airbyte/:dockerImageName/catalog", adminTokenMiddleware.AdminAuth(airbyteHandler.CatalogHandler))
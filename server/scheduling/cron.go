@@ -7,12 +7,12 @@ import (
 	"sync"
 )
 
-//CronScheduler is used for scheduling TaskService.Sync() call
+//CronScheduler is used for scheduling TaskService.Sync() call and other periodic jobs (e.g. destinations maintenance)
 type CronScheduler struct {
 	mutex *sync.RWMutex
 
 	cronInstance *cron.Cron
-	//sourceID_collectionID: EntryID
+	//sourceID_collectionID or job key: EntryID
 	scheduledEntries map[string]cron.EntryID
 
 	executeFunc func(source, collection string, retryCount int)
@@ -33,6 +33,7 @@ func NewCronScheduler() *CronScheduler {
 }
 
 //Start initialize executeFunc and start cron scheduler job
+//executeFunc can be nil: source_collection pairs won't be synced but other jobs will be run
 func (s *CronScheduler) Start(executeFunc func(source, collection string, retryCount int)) {
 	s.executeFunc = executeFunc
 	s.cronInstance.Start()
@@ -53,7 +54,29 @@ func (s *CronScheduler) Schedule(source, collection, scheduleTiming string) erro
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	entryID, err := s.cronInstance.AddFunc(scheduleTiming, func() { s.executeFunc(source, collection, 0) })
+	entryID, err := s.cronInstance.AddFunc(scheduleTiming, func() {
+		if s.executeFunc != nil {
+			s.executeFunc(source, collection, 0)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	s.scheduledEntries[key] = entryID
+	return nil
+}
+
+//ScheduleFunc adds job with unique key to cron scheduler with scheduleTiming (standard cron format e.g. 0 3 * * *)
+func (s *CronScheduler) ScheduleFunc(key, scheduleTiming string, job func()) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exist := s.scheduledEntries[key]; exist {
+		return fmt.Errorf("Job [%s] is already scheduled", key)
+	}
+
+	entryID, err := s.cronInstance.AddFunc(scheduleTiming, job)
 	if err != nil {
 		return err
 	}
@@ -64,7 +87,11 @@ func (s *CronScheduler) Schedule(source, collection, scheduleTiming string) erro
 
 //Remove delete source_collection pair from cron scheduler
 func (s *CronScheduler) Remove(source, collection string) error {
-	key := fmt.Sprintf("%s_%s", source, collection)
+	return s.RemoveFunc(fmt.Sprintf("%s_%s", source, collection))
+}
+
+//RemoveFunc deletes job with the key from cron scheduler
+func (s *CronScheduler) RemoveFunc(key string) error {
 	s.mutex.RLock()
 	entry, exist := s.scheduledEntries[key]
 	s.mutex.RUnlock()
@@ -42,6 +42,7 @@ func (f Fields) Clone() Fields {
 
 		clone[fieldName] = Field{
 			dataType:       fieldPayload.dataType,
+			nestedType:     fieldPayload.nestedType,
 			typeOccurrence: clonedTypeOccurence,
 		}
 	}
@@ -56,6 +57,7 @@ func (f Fields) OverrideTypes(other Fields) {
 			//override type occurrences
 			currentField.typeOccurrence = otherField.typeOccurrence
 			currentField.dataType = otherField.dataType
+			currentField.nestedType = otherField.nestedType
 			f[otherName] = currentField
 		}
 	}
@@ -97,7 +99,9 @@ func NewSQLTypeSuggestion(sqlType typing.SQLColumn, sqlTypePerDestination map[st
 type Field struct {
 	dataType          *typing.DataType
 	sqlTypeSuggestion *SQLTypeSuggestion
-	typeOccurrence    map[typing.DataType]bool
+	//nestedType is a structure of nested objects and arrays values
+	nestedType     *typing.NestedType
+	typeOccurrence map[typing.DataType]bool
 }
 
 //NewField returns Field instance
@@ -117,6 +121,20 @@ func NewFieldWithSQLType(t typing.DataType, sqlTypeSuggestion *SQLTypeSuggestion
 	}
 }
 
+//NewNestedField returns Field instance of nested object or array with the values structure
+func NewNestedField(t typing.DataType, nestedType *typing.NestedType) Field {
+	return Field{
+		dataType:       &t,
+		nestedType:     nestedType,
+		typeOccurrence: map[typing.DataType]bool{t: true},
+	}
+}
+
+//GetNestedType returns structure of nested objects and arrays values or nil
+func (f Field) GetNestedType() *typing.NestedType {
+	return f.nestedType
+}
+
 //GetSuggestedSQLType returns suggested SQL type if configured
 //is used in case when source overrides destination type
 func (f Field) GetSuggestedSQLType(destinationType string) (typing.SQLColumn, bool) {
@@ -161,9 +179,11 @@ func (f Field) GetType() typing.DataType {
 	return common
 }
 
-//Merge adds new type occurrences
+//Merge adds new type occurrences and merges nested values structures
 //wipes field.type if new type was added
 func (f *Field) Merge(anotherField *Field) {
+	f.nestedType = typing.MergeNestedTypes(f.nestedType, anotherField.nestedType)
+
 	//add new type occurrences
 	//wipe field.type if new type was added
 	for t := range anotherField.typeOccurrence {
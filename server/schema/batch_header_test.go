@@ -44,6 +44,12 @@ func TestFieldMerge(t *testing.T) {
 				"col5": NewField(typing.TIMESTAMP),
 			},
 		},
+		{
+			"Nested merged ok",
+			Fields{"col1": NewNestedField(typing.OBJECT, &typing.NestedType{Type: typing.OBJECT, Fields: map[string]*typing.NestedType{"a": {Type: typing.INT64}}})},
+			Fields{"col1": NewNestedField(typing.OBJECT, &typing.NestedType{Type: typing.OBJECT, Fields: map[string]*typing.NestedType{"b": {Type: typing.STRING}}})},
+			Fields{"col1": NewNestedField(typing.OBJECT, &typing.NestedType{Type: typing.OBJECT, Fields: map[string]*typing.NestedType{"a": {Type: typing.INT64}, "b": {Type: typing.STRING}}})},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
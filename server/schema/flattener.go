@@ -13,6 +13,8 @@ type Flattener interface {
 
 type FlattenerImpl struct {
 	omitNilValues bool
+	//preserveNested keeps nested objects and arrays as is (only top level keys are reformatted)
+	preserveNested bool
 }
 
 func NewFlattener() Flattener {
@@ -21,6 +23,15 @@ func NewFlattener() Flattener {
 	}
 }
 
+//NewPreserveNestedFlattener returns Flattener which doesn't flatten nested objects and arrays
+//it is used for destinations with native nested types (JSON, arrays)
+func NewPreserveNestedFlattener() Flattener {
+	return &FlattenerImpl{
+		omitNilValues:  true,
+		preserveNested: true,
+	}
+}
+
 //FlattenObject flatten object e.g. from {"key1":{"key2":123}} to {"key1_key2":123}
 //from {"$key1":1} to {"_key1":1}
 //from {"(key1)":1} to {"_key1_":1}
@@ -47,6 +58,10 @@ func (f *FlattenerImpl) flatten(key string, value interface{}, destination map[s
 			destination[key] = value
 			return nil
 		}
+		if f.preserveNested {
+			destination[key] = value
+			return nil
+		}
 		b, err := json.Marshal(value)
 		if err != nil {
 			return fmt.Errorf("Error marshaling array with key %s: %v", key, err)
@@ -54,6 +69,12 @@ func (f *FlattenerImpl) flatten(key string, value interface{}, destination map[s
 		destination[key] = string(b)
 	case reflect.Map:
 		unboxed := value.(map[string]interface{})
+		if f.preserveNested && key != "" {
+			if len(unboxed) > 0 || !f.omitNilValues {
+				destination[key] = unboxed
+			}
+			return nil
+		}
 		for k, v := range unboxed {
 			newKey := k
 			if key != "" {
@@ -68,3 +68,50 @@ func TestFlattenObject(t *testing.T) {
 		})
 	}
 }
+
+func TestPreserveNestedFlattenObject(t *testing.T) {
+	tests := []struct {
+		name         string
+		inputJSON    map[string]interface{}
+		expectedJSON map[string]interface{}
+	}{
+		{
+			"Empty input json",
+			map[string]interface{}{},
+			map[string]interface{}{},
+		},
+		{
+			"Nested input json",
+			map[string]interface{}{
+				"key1": "value1",
+				"key2": nil,
+				"key3": []interface{}{1, "2"},
+				"key4": map[string]interface{}{},
+				"Key$5": map[string]interface{}{
+					"sub_key1": "event",
+					"sub$key2": map[string]interface{}{
+						"sub_sub_key1": []interface{}{"1,", "2."}},
+				},
+				"__sql_type_key6": []interface{}{"jsonb"},
+			},
+			map[string]interface{}{
+				"key1": "value1",
+				"key3": []interface{}{1, "2"},
+				"key_5": map[string]interface{}{
+					"sub_key1": "event",
+					"sub$key2": map[string]interface{}{
+						"sub_sub_key1": []interface{}{"1,", "2."}},
+				},
+				"__sql_type_key6": []interface{}{"jsonb"},
+			},
+		},
+	}
+	flattener := NewPreserveNestedFlattener()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actualFlattenJSON, err := flattener.FlattenObject(tt.inputJSON)
+			require.NoError(t, err)
+			test.ObjectsEqual(t, tt.expectedJSON, actualFlattenJSON, "Wrong flattened json")
+		})
+	}
+}
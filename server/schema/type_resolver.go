@@ -89,6 +89,13 @@ func (tr *TypeResolverImpl) Resolve(object map[string]interface{}) (Fields, erro
 		}
 		if sqlType, ok := mappedTypes[k]; ok {
 			fields[k] = NewFieldWithSQLType(resultColumnType, NewSQLTypeSuggestion(sqlType, nil))
+		} else if resultColumnType == typing.OBJECT || resultColumnType == typing.ARRAY {
+			//nested objects and arrays (if destination preserves them) keep the values structure
+			nestedType, err := typing.NestedTypeFromValue(v)
+			if err != nil {
+				return nil, fmt.Errorf("Error getting nested type of field [%s]: %v", k, err)
+			}
+			fields[k] = NewNestedField(resultColumnType, nestedType)
 		} else {
 			fields[k] = NewField(resultColumnType)
 		}
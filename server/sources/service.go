@@ -8,6 +8,7 @@ import (
 	"github.com/jitsucom/jitsu/server/destinations"
 	"github.com/jitsucom/jitsu/server/drivers"
 	driversbase "github.com/jitsucom/jitsu/server/drivers/base"
+	"github.com/jitsucom/jitsu/server/gitops"
 	"github.com/jitsucom/jitsu/server/logging"
 	"github.com/jitsucom/jitsu/server/meta"
 	"github.com/jitsucom/jitsu/server/resources"
@@ -69,6 +70,10 @@ func NewService(ctx context.Context, sources *viper.Viper, sourcesURL string, de
 	}
 
 	if metaStorage.Type() == meta.DummyType {
+		if sourcesURL == gitops.SourceName {
+			logging.Warnf("❌ Sources from gitops configuration directory require 'meta.storage' configuration")
+			return service, nil
+		}
 		return nil, errors.New("Meta storage is required")
 	}
 
@@ -88,11 +93,13 @@ func NewService(ctx context.Context, sources *viper.Viper, sourcesURL string, de
 		}
 	} else {
 		if strings.HasPrefix(sourcesURL, "http://") || strings.HasPrefix(sourcesURL, "https://") {
-			resources.Watch(serviceName, sourcesURL, resources.LoadFromHTTP, service.updateSources, time.Duration(reloadSec)*time.Second)
+			resources.Watch(serviceName, sourcesURL, resources.LoadFromHTTP, service.UpdateSources, time.Duration(reloadSec)*time.Second)
 		} else if strings.Contains(sourcesURL, "file://") || strings.HasPrefix(sourcesURL, "/") {
-			resources.Watch(serviceName, strings.Replace(sourcesURL, "file://", "", 1), resources.LoadFromFile, service.updateSources, time.Duration(reloadSec)*time.Second)
+			resources.Watch(serviceName, strings.Replace(sourcesURL, "file://", "", 1), resources.LoadFromFile, service.UpdateSources, time.Duration(reloadSec)*time.Second)
 		} else if strings.HasPrefix(sourcesURL, "{") && strings.HasSuffix(sourcesURL, "}") {
-			service.updateSources([]byte(sourcesURL))
+			service.UpdateSources([]byte(sourcesURL))
+		} else if sourcesURL == gitops.SourceName {
+			logging.Info("Sources will be loaded from gitops configuration directory")
 		} else {
 			return nil, errors.New("Unknown sources configuration: " + sourcesURL)
 		}
@@ -101,13 +108,21 @@ func NewService(ctx context.Context, sources *viper.Viper, sourcesURL string, de
 	return service, nil
 }
 
-func (s *Service) updateSources(payload []byte) {
+//UpdateSources parses payload and reinitializes changed sources
+func (s *Service) UpdateSources(payload []byte) {
 	dc, err := parseFromBytes(payload)
 	if err != nil {
 		logging.Error(marshallingErrorMsg, err)
 		return
 	}
 
+	if !s.configured {
+		if len(dc) > 0 {
+			logging.Errorf("Error initializing sources: 'meta.storage' configuration is required")
+		}
+		return
+	}
+
 	s.init(dc)
 
 	if len(s.sources) == 0 {
@@ -163,6 +178,11 @@ func (s *Service) init(sc map[string]driversbase.SourceConfig) {
 			s.Unlock()
 		}
 
+		//sql_query sources execute queries in SQL destinations
+		if s.destinationsService != nil {
+			sourceConfig.Querier = s.destinationsService
+		}
+
 		driverPerCollection, err := drivers.Create(s.ctx, name, &sourceConfig, s.cronScheduler)
 		if err != nil {
 			logging.Errorf("[%s] Error initializing source of type %s: %v", name, sourceConfig.Type, err)
@@ -0,0 +1,43 @@
+package ssl
+
+import (
+	"context"
+
+	"github.com/jitsucom/jitsu/server/meta"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+//MetaStorageCache is an autocert.Cache implementation which stores certificates in meta storage
+//so that every cluster node shares the same certificates and HTTP-01 challenge tokens
+type MetaStorageCache struct {
+	metaStorage meta.Storage
+}
+
+//NewMetaStorageCache returns configured MetaStorageCache
+func NewMetaStorageCache(metaStorage meta.Storage) *MetaStorageCache {
+	return &MetaStorageCache{metaStorage: metaStorage}
+}
+
+//Get returns certificate data by key or autocert.ErrCacheMiss
+func (msc *MetaStorageCache) Get(ctx context.Context, key string) ([]byte, error) {
+	data, err := msc.metaStorage.GetCertificate(key)
+	if err != nil {
+		if err == meta.ErrCertificateNotFound {
+			return nil, autocert.ErrCacheMiss
+		}
+
+		return nil, err
+	}
+
+	return data, nil
+}
+
+//Put saves certificate data by key
+func (msc *MetaStorageCache) Put(ctx context.Context, key string, data []byte) error {
+	return msc.metaStorage.SaveCertificate(key, data)
+}
+
+//Delete removes certificate data by key
+func (msc *MetaStorageCache) Delete(ctx context.Context, key string) error {
+	return msc.metaStorage.DeleteCertificate(key)
+}
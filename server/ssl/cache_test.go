@@ -0,0 +1,59 @@
+package ssl
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/meta"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+//certificatesStorageMock is an in-memory meta.Storage which supports only certificates methods
+type certificatesStorageMock struct {
+	meta.Storage
+	certificates map[string][]byte
+}
+
+func newCertificatesStorageMock() *certificatesStorageMock {
+	return &certificatesStorageMock{certificates: map[string][]byte{}}
+}
+
+func (csm *certificatesStorageMock) GetCertificate(key string) ([]byte, error) {
+	data, ok := csm.certificates[key]
+	if !ok {
+		return nil, meta.ErrCertificateNotFound
+	}
+	return data, nil
+}
+
+func (csm *certificatesStorageMock) SaveCertificate(key string, data []byte) error {
+	csm.certificates[key] = data
+	return nil
+}
+
+func (csm *certificatesStorageMock) DeleteCertificate(key string) error {
+	delete(csm.certificates, key)
+	return nil
+}
+
+func (csm *certificatesStorageMock) Type() string {
+	return meta.RedisType
+}
+
+func TestMetaStorageCache(t *testing.T) {
+	ctx := context.Background()
+	cache := NewMetaStorageCache(newCertificatesStorageMock())
+
+	_, err := cache.Get(ctx, "example.com")
+	require.Equal(t, autocert.ErrCacheMiss, err)
+
+	require.NoError(t, cache.Put(ctx, "example.com", []byte("certificate")))
+	data, err := cache.Get(ctx, "example.com")
+	require.NoError(t, err)
+	require.Equal(t, []byte("certificate"), data)
+
+	require.NoError(t, cache.Delete(ctx, "example.com"))
+	_, err = cache.Get(ctx, "example.com")
+	require.Equal(t, autocert.ErrCacheMiss, err)
+}
@@ -0,0 +1,207 @@
+package ssl
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/meta"
+	"github.com/jitsucom/jitsu/server/resources"
+	"github.com/spf13/cast"
+	"github.com/spf13/viper"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+const (
+	//ChallengePath is a path prefix of ACME HTTP-01 challenge requests
+	ChallengePath = "/.well-known/acme-challenge/"
+
+	serviceName = "custom_domains"
+)
+
+//Config is a dto for server.ssl.autocert configuration section
+//Domains is a Configurator custom domains URL or a static list of domains
+type Config struct {
+	Enabled          bool        `mapstructure:"enabled"`
+	Domains          interface{} `mapstructure:"domains"`
+	DomainsReloadSec int         `mapstructure:"domains_reload_sec"`
+	Email            string      `mapstructure:"email"`
+	Port             int         `mapstructure:"port"`
+	ChallengePort    int         `mapstructure:"challenge_port"`
+	DirectoryURL     string      `mapstructure:"directory_url"`
+	CacheDir         string      `mapstructure:"cache_dir"`
+}
+
+//DomainsPayload is a dto for Configurator custom domains response
+type DomainsPayload struct {
+	Domains []string `json:"domains"`
+}
+
+//CertificateService issues and renews TLS certificates for custom domains with ACME (Let's Encrypt)
+//custom domains are reloaded from Configurator (or configured statically)
+//certificates are stored in meta storage (shared between cluster nodes) or in a local directory if meta storage isn't configured
+type CertificateService struct {
+	mutex   *sync.RWMutex
+	domains map[string]bool
+
+	manager            *autocert.Manager
+	authority          string
+	challengeAuthority string
+}
+
+//NewCertificateService returns configured CertificateService
+//returns disabled instance if server.ssl.autocert isn't configured
+func NewCertificateService(viperConfig *viper.Viper, metaStorage meta.Storage) (*CertificateService, error) {
+	if viperConfig == nil {
+		return &CertificateService{}, nil
+	}
+
+	config := &Config{}
+	if err := viperConfig.Unmarshal(config); err != nil {
+		return nil, fmt.Errorf("Error parsing server.ssl.autocert configuration: %v", err)
+	}
+
+	if !config.Enabled {
+		return &CertificateService{}, nil
+	}
+
+	domainsURL, isURL := config.Domains.(string)
+	isURL = isURL && (strings.HasPrefix(domainsURL, "http://") || strings.HasPrefix(domainsURL, "https://"))
+	var staticDomains []string
+	if !isURL {
+		staticDomains = normalizeDomains(cast.ToStringSlice(config.Domains))
+		if len(staticDomains) == 0 {
+			return nil, errors.New("server.ssl.autocert.domains is required parameter")
+		}
+	}
+
+	var cache autocert.Cache
+	if metaStorage.Type() != meta.DummyType {
+		cache = NewMetaStorageCache(metaStorage)
+	} else if config.CacheDir != "" {
+		logging.Warnf("Certificates will be stored in [%s] directory. Please configure meta.storage for sharing certificates between cluster nodes", config.CacheDir)
+		cache = autocert.DirCache(config.CacheDir)
+	} else {
+		return nil, errors.New("SSL certificates require 'meta.storage' configuration or server.ssl.autocert.cache_dir parameter")
+	}
+
+	if config.Port <= 0 {
+		config.Port = 443
+	}
+	if config.ChallengePort <= 0 {
+		config.ChallengePort = 80
+	}
+
+	if config.DomainsReloadSec <= 0 {
+		config.DomainsReloadSec = 1
+	}
+
+	service := &CertificateService{
+		mutex:              &sync.RWMutex{},
+		domains:            map[string]bool{},
+		authority:          fmt.Sprintf("0.0.0.0:%d", config.Port),
+		challengeAuthority: fmt.Sprintf("0.0.0.0:%d", config.ChallengePort),
+	}
+	service.manager = &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		Cache:      cache,
+		HostPolicy: service.hostPolicy,
+		Email:      config.Email,
+	}
+	if config.DirectoryURL != "" {
+		service.manager.Client = &acme.Client{DirectoryURL: config.DirectoryURL}
+	}
+
+	if isURL {
+		logging.Info("🔒 SSL certificates will be issued automatically for Configurator custom domains")
+		resources.Watch(serviceName, domainsURL, resources.LoadFromHTTP, service.updateDomains, time.Duration(config.DomainsReloadSec)*time.Second)
+	} else {
+		logging.Infof("🔒 SSL certificates will be issued automatically for domains: %s", strings.Join(staticDomains, ", "))
+		service.setDomains(staticDomains)
+	}
+
+	return service, nil
+}
+
+//updateDomains parses Configurator response and replaces custom domains
+func (cs *CertificateService) updateDomains(payload []byte) {
+	domainsPayload := &DomainsPayload{}
+	if err := json.Unmarshal(payload, domainsPayload); err != nil {
+		logging.Errorf("Error parsing custom domains [%s]: %v", string(payload), err)
+		return
+	}
+
+	domains := normalizeDomains(domainsPayload.Domains)
+	cs.setDomains(domains)
+	logging.Infof("🔒 Custom domains for SSL certificates have been updated: %d domains", len(domains))
+}
+
+func (cs *CertificateService) setDomains(domains []string) {
+	domainsSet := make(map[string]bool, len(domains))
+	for _, domain := range domains {
+		domainsSet[domain] = true
+	}
+
+	cs.mutex.Lock()
+	cs.domains = domainsSet
+	cs.mutex.Unlock()
+}
+
+//hostPolicy is an autocert.HostPolicy which allows only current custom domains
+func (cs *CertificateService) hostPolicy(_ context.Context, host string) error {
+	cs.mutex.RLock()
+	defer cs.mutex.RUnlock()
+
+	if !cs.domains[strings.ToLower(host)] {
+		return fmt.Errorf("acme/autocert: host %q isn't a custom domain", host)
+	}
+
+	return nil
+}
+
+//IsEnabled returns true if automatic certificates are configured
+func (cs *CertificateService) IsEnabled() bool {
+	return cs != nil && cs.manager != nil
+}
+
+//Authority returns HTTPS listen address
+func (cs *CertificateService) Authority() string {
+	return cs.authority
+}
+
+//ChallengeAuthority returns plain HTTP listen address for ACME HTTP-01 challenges
+func (cs *CertificateService) ChallengeAuthority() string {
+	return cs.challengeAuthority
+}
+
+//TLSConfig returns tls.Config which obtains certificates on the fly
+func (cs *CertificateService) TLSConfig() *tls.Config {
+	return cs.manager.TLSConfig()
+}
+
+//ChallengeHandler returns http.Handler which responds to ACME HTTP-01 challenges
+//challenge tokens are read from the cache, so any cluster node can respond
+func (cs *CertificateService) ChallengeHandler() http.Handler {
+	return cs.manager.HTTPHandler(http.NotFoundHandler())
+}
+
+//normalizeDomains returns lower cased domains without empty values
+func normalizeDomains(domains []string) []string {
+	normalized := make([]string, 0, len(domains))
+	for _, domain := range domains {
+		domain = strings.ToLower(strings.TrimSpace(domain))
+		if domain != "" {
+			normalized = append(normalized, domain)
+		}
+	}
+
+	return normalized
+}
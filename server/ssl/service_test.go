@@ -0,0 +1,142 @@
+package ssl
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/meta"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCertificateService(t *testing.T) {
+	tests := []struct {
+		name                       string
+		config                     map[string]interface{}
+		metaStorage                meta.Storage
+		expectedEnabled            bool
+		expectedAuthority          string
+		expectedChallengeAuthority string
+		expectedErr                string
+	}{
+		{
+			"Not configured",
+			nil,
+			newCertificatesStorageMock(),
+			false,
+			"",
+			"",
+			"",
+		},
+		{
+			"Disabled",
+			map[string]interface{}{"enabled": false, "domains": []string{"example.com"}},
+			newCertificatesStorageMock(),
+			false,
+			"",
+			"",
+			"",
+		},
+		{
+			"Without domains",
+			map[string]interface{}{"enabled": true, "domains": []string{" "}},
+			newCertificatesStorageMock(),
+			false,
+			"",
+			"",
+			"server.ssl.autocert.domains is required parameter",
+		},
+		{
+			"Without meta storage and cache dir",
+			map[string]interface{}{"enabled": true, "domains": []string{"example.com"}},
+			&meta.Dummy{},
+			false,
+			"",
+			"",
+			"SSL certificates require 'meta.storage' configuration or server.ssl.autocert.cache_dir parameter",
+		},
+		{
+			"Default ports",
+			map[string]interface{}{"enabled": true, "domains": []string{"example.com"}},
+			newCertificatesStorageMock(),
+			true,
+			"0.0.0.0:443",
+			"0.0.0.0:80",
+			"",
+		},
+		{
+			"Configured ports",
+			map[string]interface{}{"enabled": true, "domains": []string{"example.com"}, "port": 8443, "challenge_port": 8080},
+			newCertificatesStorageMock(),
+			true,
+			"0.0.0.0:8443",
+			"0.0.0.0:8080",
+			"",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var viperConfig *viper.Viper
+			if tt.config != nil {
+				viperConfig = viper.New()
+				require.NoError(t, viperConfig.MergeConfigMap(tt.config))
+			}
+
+			service, err := NewCertificateService(viperConfig, tt.metaStorage)
+			if tt.expectedErr != "" {
+				require.EqualError(t, err, tt.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedEnabled, service.IsEnabled())
+			require.Equal(t, tt.expectedAuthority, service.Authority())
+			require.Equal(t, tt.expectedChallengeAuthority, service.ChallengeAuthority())
+		})
+	}
+}
+
+func TestCertificateServiceHostPolicy(t *testing.T) {
+	configurator := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"domains": ["Track.Example.com", " ", "data.example.org"]}`))
+	}))
+	defer configurator.Close()
+
+	tests := []struct {
+		name     string
+		domains  interface{}
+		allowed  []string
+		rejected []string
+	}{
+		{
+			"Static domains",
+			[]string{"Example.com "},
+			[]string{"example.com", "EXAMPLE.com"},
+			[]string{"track.example.com"},
+		},
+		{
+			"Configurator custom domains",
+			configurator.URL + "/api/v1/custom_domains?token=token",
+			[]string{"track.example.com", "data.example.org"},
+			[]string{"example.com", ""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viperConfig := viper.New()
+			require.NoError(t, viperConfig.MergeConfigMap(map[string]interface{}{"enabled": true, "domains": tt.domains}))
+
+			service, err := NewCertificateService(viperConfig, newCertificatesStorageMock())
+			require.NoError(t, err)
+
+			for _, host := range tt.allowed {
+				require.NoError(t, service.hostPolicy(context.Background(), host), host)
+			}
+			for _, host := range tt.rejected {
+				require.Error(t, service.hostPolicy(context.Background(), host), host)
+			}
+		})
+	}
+}
@@ -1,9 +1,11 @@
 package storages
 
 import (
+	"errors"
 	"fmt"
 	"github.com/jitsucom/jitsu/server/identifiers"
 	"math/rand"
+	"time"
 
 	"github.com/hashicorp/go-multierror"
 	"github.com/jitsucom/jitsu/server/adapters"
@@ -16,6 +18,13 @@ import (
 	"github.com/jitsucom/jitsu/server/telemetry"
 )
 
+var (
+	//ErrDeletionIsNotSupported is returned by destinations which can't delete already stored data (e.g. HTTP based destinations)
+	ErrDeletionIsNotSupported = errors.New("Destination doesn't support data deletion")
+	//ErrQueryIsNotSupported is returned by destinations which can't execute SQL queries (e.g. HTTP based destinations)
+	ErrQueryIsNotSupported = errors.New("Destination doesn't support SQL queries")
+)
+
 //Abstract is an Abstract destination storage
 //contains common destination funcs
 //aka abstract class
@@ -176,6 +185,114 @@ func (a *Abstract) Clean(tableName string) error {
 	return nil
 }
 
+//DeleteUserData deletes objects which match deleteConditions from tableNames tables in every SQL adapter
+//if tableNames are empty - deletes from all tables in the database (schema, dataset)
+//conditions with columns which don't exist in a table are omitted
+func (a *Abstract) DeleteUserData(tableNames []string, deleteConditions *adapters.DeleteConditions) (multiErr error) {
+	if len(a.sqlAdapters) == 0 {
+		return ErrDeletionIsNotSupported
+	}
+
+	for _, sqlAdapter := range a.sqlAdapters {
+		names := tableNames
+		if len(names) == 0 {
+			var err error
+			names, err = sqlAdapter.GetTableNames()
+			if err != nil {
+				multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error getting table names: %v", a.ID(), err))
+				continue
+			}
+		}
+
+		for _, tableName := range names {
+			table, err := sqlAdapter.GetTableSchema(tableName)
+			if err != nil {
+				multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error getting table %s schema: %v", a.ID(), tableName, err))
+				continue
+			}
+
+			if !table.Exists() {
+				continue
+			}
+
+			tableConditions := &adapters.DeleteConditions{JoinCondition: deleteConditions.JoinCondition}
+			for _, condition := range deleteConditions.Conditions {
+				if _, ok := table.Columns[condition.Field]; ok {
+					tableConditions.Conditions = append(tableConditions.Conditions, condition)
+				}
+			}
+			if tableConditions.IsEmpty() {
+				continue
+			}
+
+			if err := sqlAdapter.Delete(table, tableConditions); err != nil {
+				multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error deleting data from table %s: %v", a.ID(), tableName, err))
+			}
+		}
+	}
+
+	return multiErr
+}
+
+//ApplyRetention deletes rows older than rules days from tables which match rules patterns in every SQL adapter
+//if dryRun is true - rows are only counted. Tables without rule field are skipped
+func (a *Abstract) ApplyRetention(rules []*RetentionRule, dryRun bool) (results []*RetentionResult, multiErr error) {
+	if len(a.sqlAdapters) == 0 {
+		return nil, ErrDeletionIsNotSupported
+	}
+
+	now := time.Now().UTC()
+	for _, sqlAdapter := range a.sqlAdapters {
+		tableNames, err := sqlAdapter.GetTableNames()
+		if err != nil {
+			multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error getting table names: %v", a.ID(), err))
+			continue
+		}
+
+		for _, rule := range rules {
+			for _, tableName := range tableNames {
+				if !rule.matchTable(tableName) {
+					continue
+				}
+
+				table, err := sqlAdapter.GetTableSchema(tableName)
+				if err != nil {
+					multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error getting table %s schema: %v", a.ID(), tableName, err))
+					continue
+				}
+
+				if _, ok := table.Columns[rule.Field]; !ok {
+					continue
+				}
+
+				result := &RetentionResult{Table: tableName, Before: rule.boundary(now), DryRun: dryRun}
+				result.Deleted, err = sqlAdapter.DeleteOlderThan(table, rule.Field, result.Before, dryRun)
+				if err != nil {
+					result.Error = err.Error()
+					multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error applying retention to table %s: %v", a.ID(), tableName, err))
+				}
+				results = append(results, result)
+			}
+		}
+	}
+
+	return results, multiErr
+}
+
+//Query executes SELECT query with the first SQL adapter and passes result rows to consume one by one
+func (a *Abstract) Query(query string, consume func(row map[string]interface{}) error) error {
+	if len(a.sqlAdapters) == 0 {
+		return ErrQueryIsNotSupported
+	}
+
+	querier, ok := a.sqlAdapters[0].(adapters.Querier)
+	if !ok {
+		return ErrQueryIsNotSupported
+	}
+
+	return querier.Select(query, consume)
+}
+
 func (a *Abstract) close() (multiErr error) {
 	if a.fallbackLogger != nil {
 		if err := a.fallbackLogger.Close(); err != nil {
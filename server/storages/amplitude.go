@@ -36,6 +36,9 @@ func NewAmplitude(config *Config) (Storage, error) {
 		DebugLogger:    requestDebugLogger,
 		ErrorHandler:   a.ErrorEvent,
 		SuccessHandler: a.SuccessEvent,
+		Batch:          config.destination.HTTPBatch,
+		RateLimit:      config.destination.HTTPRateLimit,
+		CircuitBreaker: config.destination.HTTPCircuitBreaker,
 	})
 	if err != nil {
 		return nil, err
@@ -52,6 +55,7 @@ func NewAmplitude(config *Config) (Storage, error) {
 	a.processor = config.processor
 	a.fallbackLogger = config.loggerFactory.CreateFailedLogger(config.destinationID)
 	a.eventsCache = config.eventsCache
+	a.metaStorage = config.metaStorage
 	a.archiveLogger = config.loggerFactory.CreateStreamingArchiveLogger(config.destinationID)
 	a.uniqueIDField = config.uniqueIDField
 	a.staged = config.destination.Staged
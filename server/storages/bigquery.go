@@ -1,6 +1,7 @@
 package storages
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"time"
@@ -16,7 +17,7 @@ var disabledRecognitionConfiguration = &UserRecognitionConfiguration{enabled: fa
 
 //BigQuery stores files to google BigQuery in two modes:
 //batch: via google cloud storage in batch mode (1 file = 1 operation)
-//stream: via events queue in stream mode (1 object = 1 operation)
+//stream: via events queue in stream mode (1 object = 1 operation or batch of objects with Storage Write API)
 type BigQuery struct {
 	Abstract
 
@@ -160,8 +161,20 @@ func (bq *BigQuery) storeTable(fdata *schema.ProcessedFile, table *adapters.Tabl
 
 	//batch mode
 	if bq.gcsAdapter != nil {
-		b := fdata.GetPayloadBytes(schema.JSONMarshallerInstance)
-		if err := bq.gcsAdapter.UploadBytes(fdata.FileName, b); err != nil {
+		//RECORD and REPEATED columns values are reformatted according to the table structure
+		buf := bytes.Buffer{}
+		for _, object := range fdata.GetPayload() {
+			objectBytes, err := schema.JSONMarshallerInstance.Marshal(nil, adapters.ReformatBigQueryObject(dbTable, object))
+			if err != nil {
+				return fmt.Errorf("Error marshaling object: %v", err)
+			}
+			if buf.Len() > 0 {
+				buf.WriteString("\n")
+			}
+			buf.Write(objectBytes)
+		}
+
+		if err := bq.gcsAdapter.UploadBytes(fdata.FileName, buf.Bytes()); err != nil {
 			return err
 		}
 
@@ -177,7 +190,95 @@ func (bq *BigQuery) storeTable(fdata *schema.ProcessedFile, table *adapters.Tabl
 	}
 
 	//stream mode
-	return bq.bqAdapter.BulkInsert(table, fdata.GetPayload())
+	return bq.bqAdapter.BulkInsert(dbTable, fdata.GetPayload())
+}
+
+//StreamingBatchSize returns max number of events which are inserted with one InsertBatch call (Storage Write API)
+func (bq *BigQuery) StreamingBatchSize() int {
+	return bq.bqAdapter.WriteBatchSize()
+}
+
+//InsertBatch ensures tables and inserts events in stream mode with one request per table
+//events which have failed are retried once with the renewed tables schema
+//writes metrics/counters/events cache and archives inserted events. Returns errors per event (in the same order)
+func (bq *BigQuery) InsertBatch(eventContexts []*adapters.EventContext) []error {
+	_, tableHelper := bq.getAdapters()
+
+	errs := make([]error, len(eventContexts))
+	dbSchemasFromObjects := make([]*adapters.Table, len(eventContexts))
+	var indexes []int
+	for i, eventContext := range eventContexts {
+		dbSchemasFromObjects[i] = eventContext.Table
+		dbTable, err := tableHelper.EnsureTableWithCaching(bq.ID(), eventContext.Table)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+
+		eventContext.Table = dbTable
+		indexes = append(indexes, i)
+	}
+
+	failed := bq.insertBatch(eventContexts, indexes, errs)
+
+	//renew current db schema and retry
+	var retryIndexes []int
+	refreshed := map[string]bool{}
+	for _, i := range failed {
+		dbSchemaFromObject := dbSchemasFromObjects[i]
+		if !refreshed[dbSchemaFromObject.Name] {
+			if _, err := tableHelper.RefreshTableSchema(bq.ID(), dbSchemaFromObject); err != nil {
+				errs[i] = err
+				continue
+			}
+			refreshed[dbSchemaFromObject.Name] = true
+		}
+
+		dbTable, err := tableHelper.EnsureTableWithCaching(bq.ID(), dbSchemaFromObject)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+
+		eventContexts[i].Table = dbTable
+		retryIndexes = append(retryIndexes, i)
+	}
+
+	bq.insertBatch(eventContexts, retryIndexes, errs)
+
+	for i, eventContext := range eventContexts {
+		//metrics/counters/cache/fallback
+		bq.AccountResult(eventContext, errs[i])
+
+		//archive
+		if errs[i] == nil {
+			bq.archiveLogger.Consume(eventContext.RawEvent, eventContext.TokenID)
+		}
+	}
+
+	return errs
+}
+
+//insertBatch inserts events with the indexes, puts results into errs and returns indexes of failed events
+func (bq *BigQuery) insertBatch(eventContexts []*adapters.EventContext, indexes []int, errs []error) []int {
+	if len(indexes) == 0 {
+		return nil
+	}
+
+	batch := make([]*adapters.EventContext, len(indexes))
+	for j, i := range indexes {
+		batch[j] = eventContexts[i]
+	}
+
+	var failed []int
+	for j, err := range bq.bqAdapter.InsertBatch(batch) {
+		errs[indexes[j]] = err
+		if err != nil {
+			failed = append(failed, indexes[j])
+		}
+	}
+
+	return failed
 }
 
 //Update isn't supported
@@ -225,6 +326,28 @@ func (bq *BigQuery) Clean(tableName string) error {
 	return cleanImpl(bq, tableName)
 }
 
+//ApplyRetention deletes rows from tables which match rules patterns and objects with rules prefixes from google cloud storage
+//if dryRun is true - rows are only counted and objects are only listed
+func (bq *BigQuery) ApplyRetention(rules []*RetentionRule, dryRun bool) ([]*RetentionResult, error) {
+	results, multiErr := bq.Abstract.ApplyRetention(rules, dryRun)
+
+	for _, rule := range rules {
+		if rule.Prefix != "" && bq.gcsAdapter == nil {
+			return results, multierror.Append(multiErr, fmt.Errorf("[%s] retention prefix rules require google cloud storage which is used only in %s mode", bq.ID(), BatchMode))
+		}
+	}
+
+	if bq.gcsAdapter != nil {
+		objectsResults, err := applyObjectsRetention(bq.gcsAdapter, rules, dryRun)
+		results = append(results, objectsResults...)
+		if err != nil {
+			multiErr = multierror.Append(multiErr, err)
+		}
+	}
+
+	return results, multiErr
+}
+
 //GetUsersRecognition returns disabled users recognition configuration
 func (bq *BigQuery) GetUsersRecognition() *UserRecognitionConfiguration {
 	return disabledRecognitionConfiguration
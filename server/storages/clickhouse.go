@@ -19,6 +19,7 @@ type ClickHouse struct {
 	chTableHelpers                []*TableHelper
 	streamingWorker               *StreamingWorker
 	usersRecognitionConfiguration *UserRecognitionConfiguration
+	partitionsMaintenance         *clusterJob
 }
 
 func init() {
@@ -32,7 +33,7 @@ func NewClickHouse(config *Config) (Storage, error) {
 		return nil, err
 	}
 
-	tableStatementFactory, err := adapters.NewTableStatementFactory(chConfig)
+	tableStatementFactory, err := adapters.NewTableStatementFactory(chConfig, config.partitioning)
 	if err != nil {
 		return nil, err
 	}
@@ -53,7 +54,7 @@ func NewClickHouse(config *Config) (Storage, error) {
 	var chTableHelpers []*TableHelper
 	for _, dsn := range chConfig.Dsns {
 		adapter, err := adapters.NewClickHouse(config.ctx, dsn, chConfig.Database, chConfig.Cluster, chConfig.TLS,
-			tableStatementFactory, nullableFields, queryLogger, config.sqlTypes)
+			tableStatementFactory, nullableFields, queryLogger, config.sqlTypes, chConfig.AsyncInsert)
 		if err != nil {
 			//close all previous created adapters
 			for _, toClose := range chAdapters {
@@ -99,6 +100,16 @@ func NewClickHouse(config *Config) (Storage, error) {
 	ch.streamingWorker = newStreamingWorker(config.eventQueue, config.processor, ch, chTableHelpers...)
 	ch.streamingWorker.start()
 
+	var maintainers []adapters.PartitionsMaintainer
+	for _, adapter := range chAdapters {
+		maintainers = append(maintainers, adapter)
+	}
+	ch.partitionsMaintenance, err = newPartitionsMaintenance(config, maintainers...)
+	if err != nil {
+		ch.Close()
+		return nil, err
+	}
+
 	return ch, nil
 }
 
@@ -195,6 +206,10 @@ func (ch *ClickHouse) GetUsersRecognition() *UserRecognitionConfiguration {
 
 //Close closes ClickHouse adapters, fallback logger and streaming worker
 func (ch *ClickHouse) Close() (multiErr error) {
+	if err := ch.partitionsMaintenance.Close(); err != nil {
+		multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error removing partitions maintenance job: %v", ch.ID(), err))
+	}
+
 	for i, adapter := range ch.adapters {
 		if err := adapter.Close(); err != nil {
 			multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing clickhouse datasource[%d]: %v", ch.ID(), i, err))
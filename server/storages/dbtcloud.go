@@ -47,6 +47,9 @@ func NewDbtCloud(config *Config) (Storage, error) {
 		DebugLogger:    requestDebugLogger,
 		ErrorHandler:   dbt.ErrorEvent,
 		SuccessHandler: dbt.SuccessEvent,
+		Batch:          config.destination.HTTPBatch,
+		RateLimit:      config.destination.HTTPRateLimit,
+		CircuitBreaker: config.destination.HTTPCircuitBreaker,
 	})
 	if err != nil {
 		return nil, err
@@ -62,6 +65,7 @@ func NewDbtCloud(config *Config) (Storage, error) {
 	dbt.processor = config.processor
 	dbt.fallbackLogger = config.loggerFactory.CreateFailedLogger(config.destinationID)
 	dbt.eventsCache = config.eventsCache
+	dbt.metaStorage = config.metaStorage
 	dbt.archiveLogger = config.loggerFactory.CreateStreamingArchiveLogger(config.destinationID)
 	dbt.uniqueIDField = config.uniqueIDField
 	dbt.staged = config.destination.Staged
@@ -38,6 +38,9 @@ func NewFacebook(config *Config) (Storage, error) {
 		DebugLogger:    requestDebugLogger,
 		ErrorHandler:   fb.ErrorEvent,
 		SuccessHandler: fb.SuccessEvent,
+		Batch:          config.destination.HTTPBatch,
+		RateLimit:      config.destination.HTTPRateLimit,
+		CircuitBreaker: config.destination.HTTPCircuitBreaker,
 	})
 	if err != nil {
 		return nil, err
@@ -53,6 +56,7 @@ func NewFacebook(config *Config) (Storage, error) {
 	fb.processor = config.processor
 	fb.fallbackLogger = config.loggerFactory.CreateFailedLogger(config.destinationID)
 	fb.eventsCache = config.eventsCache
+	fb.metaStorage = config.metaStorage
 	fb.archiveLogger = config.loggerFactory.CreateStreamingArchiveLogger(config.destinationID)
 	fb.uniqueIDField = config.uniqueIDField
 	fb.staged = config.destination.Staged
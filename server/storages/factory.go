@@ -16,6 +16,7 @@ import (
 	"github.com/jitsucom/jitsu/server/events"
 	"github.com/jitsucom/jitsu/server/identifiers"
 	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/scheduling"
 	"github.com/jitsucom/jitsu/server/schema"
 	"github.com/jitsucom/jitsu/server/typing"
 )
@@ -42,35 +43,64 @@ var (
 		PostgresType:   59,
 		SnowflakeType:  251,
 		ClickHouseType: 251,
+		MSSQLType:      128,
+		SynapseType:    128,
+	}
+
+	//preserveNestedDestinationTypes are destinations with native nested column types (JSON, arrays)
+	preserveNestedDestinationTypes = map[string]bool{
+		PostgresType:   true,
+		MySQLType:      true,
+		BigQueryType:   true,
+		ClickHouseType: true,
+		SnowflakeType:  true,
+	}
+
+	//nestedTypesMappings are destinations which map structure of nested values into native column types
+	//(e.g. BigQuery RECORD/REPEATED). Other destinations use columnTypesMapping (e.g. JSON)
+	nestedTypesMappings = map[string]func(nestedType *typing.NestedType) typing.SQLColumn{
+		BigQueryType:   adapters.BigQueryNestedColumn,
+		ClickHouseType: adapters.ClickHouseNestedColumn,
 	}
 )
 
 //DestinationConfig is a destination configuration for serialization
 type DestinationConfig struct {
-	OnlyTokens             []string                 `mapstructure:"only_tokens" json:"only_tokens,omitempty" yaml:"only_tokens,omitempty"`
-	Type                   string                   `mapstructure:"type" json:"type,omitempty" yaml:"type,omitempty"`
-	Mode                   string                   `mapstructure:"mode" json:"mode,omitempty" yaml:"mode,omitempty"`
-	DataLayout             *DataLayout              `mapstructure:"data_layout" json:"data_layout,omitempty" yaml:"data_layout,omitempty"`
-	UsersRecognition       *UsersRecognition        `mapstructure:"users_recognition" json:"users_recognition,omitempty" yaml:"users_recognition,omitempty"`
-	Enrichment             []*enrichment.RuleConfig `mapstructure:"enrichment" json:"enrichment,omitempty" yaml:"enrichment,omitempty"`
-	Log                    *logging.SQLDebugConfig  `mapstructure:"log" json:"log,omitempty" yaml:"log,omitempty"`
-	BreakOnError           bool                     `mapstructure:"break_on_error" json:"break_on_error,omitempty" yaml:"break_on_error,omitempty"`
-	Staged                 bool                     `mapstructure:"staged" json:"staged,omitempty" yaml:"staged,omitempty"`
-	CachingConfiguration   *CachingConfiguration    `mapstructure:"caching" json:"caching,omitempty" yaml:"caching,omitempty"`
-	PostHandleDestinations []string                 `mapstructure:"post_handle_destinations" json:"post_handle_destinations,omitempty" yaml:"post_handle_destinations,omitempty"`
-	GeoDataResolverID      string                   `mapstructure:"geo_data_resolver_id" json:"geo_data_resolver_id,omitempty" yaml:"geo_data_resolver_id,omitempty"`
-
-	DataSource      *adapters.DataSourceConfig            `mapstructure:"datasource" json:"datasource,omitempty" yaml:"datasource,omitempty"`
-	S3              *adapters.S3Config                    `mapstructure:"s3" json:"s3,omitempty" yaml:"s3,omitempty"`
-	Google          *adapters.GoogleConfig                `mapstructure:"google" json:"google,omitempty" yaml:"google,omitempty"`
-	GoogleAnalytics *adapters.GoogleAnalyticsConfig       `mapstructure:"google_analytics" json:"google_analytics,omitempty" yaml:"google_analytics,omitempty"`
-	ClickHouse      *adapters.ClickHouseConfig            `mapstructure:"clickhouse" json:"clickhouse,omitempty" yaml:"clickhouse,omitempty"`
-	Snowflake       *adapters.SnowflakeConfig             `mapstructure:"snowflake" json:"snowflake,omitempty" yaml:"snowflake,omitempty"`
-	Facebook        *adapters.FacebookConversionAPIConfig `mapstructure:"facebook" json:"facebook,omitempty" yaml:"facebook,omitempty"`
-	WebHook         *adapters.WebHookConfig               `mapstructure:"webhook" json:"webhook,omitempty" yaml:"webhook,omitempty"`
-	Amplitude       *adapters.AmplitudeConfig             `mapstructure:"amplitude" json:"amplitude,omitempty" yaml:"amplitude,omitempty"`
-	HubSpot         *adapters.HubSpotConfig               `mapstructure:"hubspot" json:"hubspot,omitempty" yaml:"hubspot,omitempty"`
-	DbtCloud        *adapters.DbtCloudConfig              `mapstructure:"dbtcloud" json:"dbtcloud,omitempty" yaml:"dbtcloud,omitempty"`
+	OnlyTokens             []string                           `mapstructure:"only_tokens" json:"only_tokens,omitempty" yaml:"only_tokens,omitempty"`
+	Type                   string                             `mapstructure:"type" json:"type,omitempty" yaml:"type,omitempty"`
+	Mode                   string                             `mapstructure:"mode" json:"mode,omitempty" yaml:"mode,omitempty"`
+	DataLayout             *DataLayout                        `mapstructure:"data_layout" json:"data_layout,omitempty" yaml:"data_layout,omitempty"`
+	UsersRecognition       *UsersRecognition                  `mapstructure:"users_recognition" json:"users_recognition,omitempty" yaml:"users_recognition,omitempty"`
+	Enrichment             []*enrichment.RuleConfig           `mapstructure:"enrichment" json:"enrichment,omitempty" yaml:"enrichment,omitempty"`
+	Log                    *logging.SQLDebugConfig            `mapstructure:"log" json:"log,omitempty" yaml:"log,omitempty"`
+	BreakOnError           bool                               `mapstructure:"break_on_error" json:"break_on_error,omitempty" yaml:"break_on_error,omitempty"`
+	Staged                 bool                               `mapstructure:"staged" json:"staged,omitempty" yaml:"staged,omitempty"`
+	CachingConfiguration   *CachingConfiguration              `mapstructure:"caching" json:"caching,omitempty" yaml:"caching,omitempty"`
+	PostHandleDestinations []string                           `mapstructure:"post_handle_destinations" json:"post_handle_destinations,omitempty" yaml:"post_handle_destinations,omitempty"`
+	GeoDataResolverID      string                             `mapstructure:"geo_data_resolver_id" json:"geo_data_resolver_id,omitempty" yaml:"geo_data_resolver_id,omitempty"`
+	Retention              *RetentionConfig                   `mapstructure:"retention" json:"retention,omitempty" yaml:"retention,omitempty"`
+	HTTPBatch              *adapters.HTTPBatchConfig          `mapstructure:"http_batch" json:"http_batch,omitempty" yaml:"http_batch,omitempty"`
+	HTTPRateLimit          *adapters.HTTPRateLimitConfig      `mapstructure:"http_rate_limit" json:"http_rate_limit,omitempty" yaml:"http_rate_limit,omitempty"`
+	HTTPCircuitBreaker     *adapters.HTTPCircuitBreakerConfig `mapstructure:"http_circuit_breaker" json:"http_circuit_breaker,omitempty" yaml:"http_circuit_breaker,omitempty"`
+
+	DataSource       *adapters.DataSourceConfig            `mapstructure:"datasource" json:"datasource,omitempty" yaml:"datasource,omitempty"`
+	S3               *adapters.S3Config                    `mapstructure:"s3" json:"s3,omitempty" yaml:"s3,omitempty"`
+	Google           *adapters.GoogleConfig                `mapstructure:"google" json:"google,omitempty" yaml:"google,omitempty"`
+	GoogleAnalytics  *adapters.GoogleAnalyticsConfig       `mapstructure:"google_analytics" json:"google_analytics,omitempty" yaml:"google_analytics,omitempty"`
+	GoogleAnalytics4 *adapters.GoogleAnalytics4Config      `mapstructure:"google_analytics4" json:"google_analytics4,omitempty" yaml:"google_analytics4,omitempty"`
+	ClickHouse       *adapters.ClickHouseConfig            `mapstructure:"clickhouse" json:"clickhouse,omitempty" yaml:"clickhouse,omitempty"`
+	Snowflake        *adapters.SnowflakeConfig             `mapstructure:"snowflake" json:"snowflake,omitempty" yaml:"snowflake,omitempty"`
+	Facebook         *adapters.FacebookConversionAPIConfig `mapstructure:"facebook" json:"facebook,omitempty" yaml:"facebook,omitempty"`
+	WebHook          *adapters.WebHookConfig               `mapstructure:"webhook" json:"webhook,omitempty" yaml:"webhook,omitempty"`
+	Amplitude        *adapters.AmplitudeConfig             `mapstructure:"amplitude" json:"amplitude,omitempty" yaml:"amplitude,omitempty"`
+	HubSpot          *adapters.HubSpotConfig               `mapstructure:"hubspot" json:"hubspot,omitempty" yaml:"hubspot,omitempty"`
+	Mixpanel         *adapters.MixpanelConfig              `mapstructure:"mixpanel" json:"mixpanel,omitempty" yaml:"mixpanel,omitempty"`
+	PostHog          *adapters.PostHogConfig               `mapstructure:"posthog" json:"posthog,omitempty" yaml:"posthog,omitempty"`
+	Segment          *adapters.SegmentConfig               `mapstructure:"segment" json:"segment,omitempty" yaml:"segment,omitempty"`
+	DbtCloud         *adapters.DbtCloudConfig              `mapstructure:"dbtcloud" json:"dbtcloud,omitempty" yaml:"dbtcloud,omitempty"`
+	Redshift         *adapters.RedshiftConfig              `mapstructure:"redshift" json:"redshift,omitempty" yaml:"redshift,omitempty"`
+	SQLite           *adapters.SQLiteConfig                `mapstructure:"sqlite" json:"sqlite,omitempty" yaml:"sqlite,omitempty"`
+	AzureBlob        *adapters.AzureBlobConfig             `mapstructure:"azure_blob" json:"azure_blob,omitempty" yaml:"azure_blob,omitempty"`
 }
 
 //DataLayout is used for configure mappings/table names and other data layout parameters
@@ -88,6 +118,9 @@ type DataLayout struct {
 	TableNameTemplate string          `mapstructure:"table_name_template" json:"table_name_template,omitempty" yaml:"table_name_template,omitempty"`
 	PrimaryKeyFields  []string        `mapstructure:"primary_key_fields" json:"primary_key_fields,omitempty" yaml:"primary_key_fields,omitempty"`
 	UniqueIDField     string          `mapstructure:"unique_id_field" json:"unique_id_field,omitempty" yaml:"unique_id_field,omitempty"`
+	PreserveNested    bool            `mapstructure:"preserve_nested" json:"preserve_nested,omitempty" yaml:"preserve_nested,omitempty"`
+
+	Partitioning *adapters.PartitioningConfig `mapstructure:"partitioning" json:"partitioning,omitempty" yaml:"partitioning,omitempty"`
 }
 
 //UsersRecognition is a model for Users recognition module configuration
@@ -138,12 +171,16 @@ type Config struct {
 	monitorKeeper          MonitorKeeper
 	eventQueue             *events.PersistentQueue
 	eventsCache            *caching.EventsCache
+	metaStorage            meta.Storage
 	loggerFactory          *logging.Factory
 	pkFields               map[string]bool
 	sqlTypes               typing.SQLTypes
 	uniqueIDField          *identifiers.UniqueID
 	mappingsStyle          string
 	logEventPath           string
+	cronScheduler          *scheduling.CronScheduler
+	partitioning           *adapters.PartitioningConfig
+	retention              *RetentionConfig
 	PostHandleDestinations []string
 }
 
@@ -174,11 +211,13 @@ type FactoryImpl struct {
 	globalConfiguration *UsersRecognition
 	metaStorage         meta.Storage
 	maxColumns          int
+	cronScheduler       *scheduling.CronScheduler
 }
 
 //NewFactory returns configured Factory
+//cronScheduler is used for destinations maintenance jobs and can be nil
 func NewFactory(ctx context.Context, logEventPath string, geoService *geo.Service, monitorKeeper MonitorKeeper, eventsCache *caching.EventsCache,
-	globalLoggerFactory *logging.Factory, globalConfiguration *UsersRecognition, metaStorage meta.Storage, maxColumns int) Factory {
+	globalLoggerFactory *logging.Factory, globalConfiguration *UsersRecognition, metaStorage meta.Storage, maxColumns int, cronScheduler *scheduling.CronScheduler) Factory {
 	return &FactoryImpl{
 		ctx:                 ctx,
 		logEventPath:        logEventPath,
@@ -189,6 +228,7 @@ func NewFactory(ctx context.Context, logEventPath string, geoService *geo.Servic
 		globalConfiguration: globalConfiguration,
 		metaStorage:         metaStorage,
 		maxColumns:          maxColumns,
+		cronScheduler:       cronScheduler,
 	}
 }
 
@@ -218,6 +258,8 @@ func (f *FactoryImpl) Create(destinationID string, destination DestinationConfig
 	uniqueIDField := appconfig.Instance.GlobalUniqueIDField
 	transform := ""
 	transformEnabled := false
+	preserveNested := false
+	var partitioning *adapters.PartitioningConfig
 	if destination.DataLayout != nil {
 		transformEnabled = destination.DataLayout.TransformEnabled
 		if transformEnabled {
@@ -244,6 +286,31 @@ func (f *FactoryImpl) Create(destinationID string, destination DestinationConfig
 		if destination.DataLayout.UniqueIDField != "" {
 			uniqueIDField = identifiers.NewUniqueID(destination.DataLayout.UniqueIDField)
 		}
+
+		if destination.DataLayout.PreserveNested {
+			if preserveNestedDestinationTypes[destination.Type] {
+				preserveNested = true
+				logging.Infof("[%s] preserves nested objects and arrays in native column types", destinationID)
+			} else {
+				logging.Warnf("[%s] data_layout.preserve_nested isn't supported by %s destination. Nested objects will be flattened", destinationID, destination.Type)
+			}
+		}
+
+		if destination.DataLayout.Partitioning != nil {
+			if !partitioningDestinationTypes[destination.Type] {
+				return nil, nil, fmt.Errorf("data_layout.partitioning isn't supported by %s destination", destination.Type)
+			}
+			if err := destination.DataLayout.Partitioning.Validate(); err != nil {
+				return nil, nil, err
+			}
+			partitioning = destination.DataLayout.Partitioning
+			//primary keys of partitioned tables must include partition key column
+			if len(pkFields) > 0 && destination.Type != ClickHouseType && !pkFields[partitioning.Field] {
+				return nil, nil, fmt.Errorf("data_layout.primary_key_fields must include partitioning field [%s]", partitioning.Field)
+			}
+
+			logging.Infof("[%s] tables are partitioned by %s on [%s] field", destinationID, partitioning.Granularity, partitioning.Field)
+		}
 	}
 
 	if tableName == "" {
@@ -265,6 +332,17 @@ func (f *FactoryImpl) Create(destinationID string, destination DestinationConfig
 		return nil, nil, fmt.Errorf("Unknown destination mode: %s. Available mode: [%s, %s]", destination.Mode, BatchMode, StreamMode)
 	}
 
+	if destination.Retention != nil {
+		if !retentionDestinationTypes[destination.Type] {
+			return nil, nil, fmt.Errorf("retention isn't supported by %s destination", destination.Type)
+		}
+		if err := destination.Retention.Validate(destination.Type); err != nil {
+			return nil, nil, err
+		}
+
+		logging.Infof("[%s] has %d retention rules with schedule [%s] dry run: %t", destinationID, len(destination.Retention.Rules), destination.Retention.Schedule, destination.Retention.DryRun)
+	}
+
 	if len(destination.Enrichment) == 0 {
 		logging.Warnf("[%s] doesn't have enrichment rules", destinationID)
 	} else {
@@ -316,6 +394,9 @@ func (f *FactoryImpl) Create(destinationID string, destination DestinationConfig
 	if needDummy(&destination) {
 		flattener = schema.NewDummyFlattener()
 		typeResolver = schema.NewDummyTypeResolver()
+	} else if preserveNested {
+		flattener = schema.NewPreserveNestedFlattener()
+		typeResolver = schema.NewTypeResolver()
 	} else {
 		flattener = schema.NewFlattener()
 		typeResolver = schema.NewTypeResolver()
@@ -377,12 +458,16 @@ func (f *FactoryImpl) Create(destinationID string, destination DestinationConfig
 		monitorKeeper:          f.monitorKeeper,
 		eventQueue:             eventQueue,
 		eventsCache:            f.eventsCache,
+		metaStorage:            f.metaStorage,
 		loggerFactory:          destinationLoggerFactory,
 		pkFields:               pkFields,
 		sqlTypes:               sqlTypes,
 		uniqueIDField:          uniqueIDField,
 		mappingsStyle:          mappingsStyle,
 		logEventPath:           f.logEventPath,
+		cronScheduler:          f.cronScheduler,
+		partitioning:           partitioning,
+		retention:              destination.Retention,
 		PostHandleDestinations: destination.PostHandleDestinations,
 	}
 
@@ -396,7 +481,8 @@ func needDummy(destCfg *DestinationConfig) bool {
 		return destCfg.S3.Format == adapters.S3FormatJSON
 	}
 	return destCfg.Type == FacebookType || destCfg.Type == DbtCloudType || destCfg.Type == WebHookType ||
-		destCfg.Type == AmplitudeType || destCfg.Type == HubSpotType
+		destCfg.Type == AmplitudeType || destCfg.Type == HubSpotType || destCfg.Type == GoogleAnalytics4Type ||
+		destCfg.Type == MixpanelType || destCfg.Type == PostHogType || destCfg.Type == SegmentType
 }
 
 //initializeRetroactiveUsersRecognition initializes recognition configuration (overrides global one with destination layer)
@@ -518,6 +604,8 @@ func isSQLType(destinationType string) bool {
 		destinationType == PostgresType ||
 		destinationType == ClickHouseType ||
 		destinationType == SnowflakeType ||
+		destinationType == MSSQLType ||
+		destinationType == SynapseType ||
 		//S3 can be SQL (S3 as intermediate layer)
 		destinationType == S3Type
 }
@@ -38,6 +38,9 @@ func NewGoogleAnalytics(config *Config) (Storage, error) {
 		DebugLogger:    requestDebugLogger,
 		ErrorHandler:   ga.ErrorEvent,
 		SuccessHandler: ga.SuccessEvent,
+		Batch:          config.destination.HTTPBatch,
+		RateLimit:      config.destination.HTTPRateLimit,
+		CircuitBreaker: config.destination.HTTPCircuitBreaker,
 	})
 	if err != nil {
 		return nil, err
@@ -53,6 +56,7 @@ func NewGoogleAnalytics(config *Config) (Storage, error) {
 	ga.processor = config.processor
 	ga.fallbackLogger = config.loggerFactory.CreateFailedLogger(config.destinationID)
 	ga.eventsCache = config.eventsCache
+	ga.metaStorage = config.metaStorage
 	ga.archiveLogger = config.loggerFactory.CreateStreamingArchiveLogger(config.destinationID)
 	ga.uniqueIDField = config.uniqueIDField
 	ga.staged = config.destination.Staged
@@ -0,0 +1,75 @@
+package storages
+
+import (
+	"fmt"
+
+	"github.com/jitsucom/jitsu/server/adapters"
+)
+
+//GoogleAnalytics4 stores events to Google Analytics 4 in stream mode
+type GoogleAnalytics4 struct {
+	HTTPStorage
+}
+
+func init() {
+	RegisterStorage(StorageType{typeName: GoogleAnalytics4Type, createFunc: NewGoogleAnalytics4})
+}
+
+//NewGoogleAnalytics4 returns GoogleAnalytics4 instance
+//start streaming worker goroutine
+func NewGoogleAnalytics4(config *Config) (Storage, error) {
+	if !config.streamMode {
+		return nil, fmt.Errorf("Google Analytics 4 destination doesn't support %s mode", BatchMode)
+	}
+
+	ga4Config := config.destination.GoogleAnalytics4
+	if err := ga4Config.Validate(); err != nil {
+		return nil, err
+	}
+
+	ga4 := &GoogleAnalytics4{}
+
+	requestDebugLogger := config.loggerFactory.CreateSQLQueryLogger(config.destinationID)
+	ga4Adapter, err := adapters.NewGoogleAnalytics4(ga4Config, &adapters.HTTPAdapterConfiguration{
+		DestinationID:  config.destinationID,
+		Dir:            config.logEventPath,
+		HTTPConfig:     DefaultHTTPConfiguration,
+		PoolWorkers:    defaultWorkersPoolSize,
+		DebugLogger:    requestDebugLogger,
+		ErrorHandler:   ga4.ErrorEvent,
+		SuccessHandler: ga4.SuccessEvent,
+		Batch:          config.destination.HTTPBatch,
+		RateLimit:      config.destination.HTTPRateLimit,
+		CircuitBreaker: config.destination.HTTPCircuitBreaker,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tableHelper := NewTableHelper(ga4Adapter, config.monitorKeeper, config.pkFields, adapters.DefaultSchemaTypeMappings, 0, GoogleAnalytics4Type)
+
+	ga4.adapter = ga4Adapter
+	ga4.tableHelper = tableHelper
+
+	//Abstract (SQLAdapters and tableHelpers are omitted)
+	ga4.destinationID = config.destinationID
+	ga4.processor = config.processor
+	ga4.fallbackLogger = config.loggerFactory.CreateFailedLogger(config.destinationID)
+	ga4.eventsCache = config.eventsCache
+	ga4.metaStorage = config.metaStorage
+	ga4.archiveLogger = config.loggerFactory.CreateStreamingArchiveLogger(config.destinationID)
+	ga4.uniqueIDField = config.uniqueIDField
+	ga4.staged = config.destination.Staged
+	ga4.cachingConfiguration = config.destination.CachingConfiguration
+
+	//streaming worker (queue reading)
+	ga4.streamingWorker = newStreamingWorker(config.eventQueue, config.processor, ga4, tableHelper)
+	ga4.streamingWorker.start()
+
+	return ga4, nil
+}
+
+//Type returns Google Analytics 4 type
+func (ga4 *GoogleAnalytics4) Type() string {
+	return GoogleAnalytics4Type
+}
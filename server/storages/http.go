@@ -6,6 +6,8 @@ import (
 	"github.com/jitsucom/jitsu/server/adapters"
 	"github.com/jitsucom/jitsu/server/events"
 	"github.com/jitsucom/jitsu/server/identifiers"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/meta"
 	"github.com/jitsucom/jitsu/server/schema"
 )
 
@@ -18,7 +20,51 @@ type HTTPStorage struct {
 	tableHelper     *TableHelper
 	streamingWorker *StreamingWorker
 
-	adapter adapters.Adapter
+	adapter     adapters.Adapter
+	metaStorage meta.Storage
+}
+
+//HTTPStatus returns underlying HTTP adapter status or nil if adapter doesn't support it
+func (h *HTTPStorage) HTTPStatus() *adapters.HTTPAdapterStatus {
+	if statusAdapter, ok := h.adapter.(interface {
+		Status() *adapters.HTTPAdapterStatus
+	}); ok {
+		return statusAdapter.Status()
+	}
+
+	return nil
+}
+
+//SuccessEvent writes success event into counters and saves signature of delivered source object
+func (h *HTTPStorage) SuccessEvent(eventCtx *adapters.EventContext) {
+	h.Abstract.SuccessEvent(eventCtx)
+	h.acknowledgeSourceObject(eventCtx, true)
+}
+
+//ErrorEvent writes error event into counters and releases source object if the event won't be retried
+func (h *HTTPStorage) ErrorEvent(fallback bool, eventCtx *adapters.EventContext, err error) {
+	h.Abstract.ErrorEvent(fallback, eventCtx, err)
+	if fallback {
+		h.acknowledgeSourceObject(eventCtx, false)
+	}
+}
+
+//SkipEvent writes skip event into counters and releases source object
+func (h *HTTPStorage) SkipEvent(eventCtx *adapters.EventContext, err error) {
+	h.Abstract.SkipEvent(eventCtx, err)
+	h.acknowledgeSourceObject(eventCtx, false)
+}
+
+//acknowledgeSourceObject saves signature of delivered object which has been synchronized from source
+//undelivered objects are sent again in the next sync
+func (h *HTTPStorage) acknowledgeSourceObject(eventCtx *adapters.EventContext, delivered bool) {
+	if h.metaStorage == nil || h.metaStorage.Type() == meta.DummyType || eventCtx.Src != events.SourceSrc || eventCtx.EventID == "" {
+		return
+	}
+
+	if err := h.metaStorage.AcknowledgePendingObject(h.destinationID, eventCtx.EventID, delivered); err != nil {
+		logging.SystemErrorf("[%s] Error acknowledging source object [%s]: %v", h.destinationID, eventCtx.EventID, err)
+	}
 }
 
 //Insert sends event into adapters.Adapter
@@ -45,6 +91,23 @@ func (h *HTTPStorage) Update(object map[string]interface{}) error {
 	return fmt.Errorf("%s doesn't support Store() func", h.Type())
 }
 
+//linkIdentity sends adapters.IdentifyEvent of anonymous ID and the user of identified event into the adapter queue
+func (h *HTTPStorage) linkIdentity(anonymousID string, identifiedEvent events.Event) error {
+	identifyEvent, err := adapters.IdentifyEvent(anonymousID, identifiedEvent)
+	if err != nil {
+		return err
+	}
+
+	return h.Insert(&adapters.EventContext{
+		CacheDisabled:  true,
+		DestinationID:  h.destinationID,
+		EventID:        h.uniqueIDField.Extract(identifyEvent),
+		Src:            events.ExtractSrc(identifyEvent),
+		RawEvent:       identifyEvent,
+		ProcessedEvent: identifyEvent,
+	})
+}
+
 //GetUsersRecognition returns disabled users recognition configuration
 func (h *HTTPStorage) GetUsersRecognition() *UserRecognitionConfiguration {
 	return disabledRecognitionConfiguration
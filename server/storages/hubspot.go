@@ -36,6 +36,9 @@ func NewHubSpot(config *Config) (Storage, error) {
 		DebugLogger:    requestDebugLogger,
 		ErrorHandler:   h.ErrorEvent,
 		SuccessHandler: h.SuccessEvent,
+		Batch:          config.destination.HTTPBatch,
+		RateLimit:      config.destination.HTTPRateLimit,
+		CircuitBreaker: config.destination.HTTPCircuitBreaker,
 	})
 	if err != nil {
 		return nil, err
@@ -51,6 +54,7 @@ func NewHubSpot(config *Config) (Storage, error) {
 	h.processor = config.processor
 	h.fallbackLogger = config.loggerFactory.CreateFailedLogger(config.destinationID)
 	h.eventsCache = config.eventsCache
+	h.metaStorage = config.metaStorage
 	h.archiveLogger = config.loggerFactory.CreateStreamingArchiveLogger(config.destinationID)
 	h.uniqueIDField = config.uniqueIDField
 	h.staged = config.destination.Staged
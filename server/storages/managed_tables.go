@@ -0,0 +1,81 @@
+package storages
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/meta"
+)
+
+//managedTables keeps names of tables which the destination writes to
+//names are kept in memory and in meta storage (tables which are written by other nodes of the cluster)
+type managedTables struct {
+	sync.Mutex
+
+	destinationID string
+	metaStorage   meta.Storage
+	tables        map[string]bool
+}
+
+func newManagedTables(destinationID string, metaStorage meta.Storage) *managedTables {
+	return &managedTables{
+		destinationID: destinationID,
+		metaStorage:   metaStorage,
+		tables:        map[string]bool{},
+	}
+}
+
+//add saves the table name. It is saved into meta storage only once per instance
+//nil managedTables (tables aren't tracked) does nothing
+func (mt *managedTables) add(tableName string) {
+	if mt == nil {
+		return
+	}
+
+	mt.Lock()
+	defer mt.Unlock()
+
+	if mt.tables[tableName] {
+		return
+	}
+
+	if mt.metaStorage != nil {
+		if err := mt.metaStorage.SaveDestinationTable(mt.destinationID, tableName); err != nil {
+			//will be saved on the next write
+			logging.SystemErrorf("[%s] Error saving table [%s] into meta storage: %v", mt.destinationID, tableName, err)
+			return
+		}
+	}
+
+	mt.tables[tableName] = true
+}
+
+//names returns sorted names of tables which have been written by all nodes
+func (mt *managedTables) names() ([]string, error) {
+	mt.Lock()
+	tables := make(map[string]bool, len(mt.tables))
+	for tableName := range mt.tables {
+		tables[tableName] = true
+	}
+	mt.Unlock()
+
+	if mt.metaStorage != nil {
+		savedTables, err := mt.metaStorage.GetDestinationTables(mt.destinationID)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, tableName := range savedTables {
+			tables[tableName] = true
+		}
+	}
+
+	names := make([]string, 0, len(tables))
+	for tableName := range tables {
+		names = append(names, tableName)
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
@@ -0,0 +1,73 @@
+package storages
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/meta"
+	"github.com/stretchr/testify/require"
+)
+
+//destinationTablesStorageMock is an in-memory meta.Storage which supports only destination tables methods
+type destinationTablesStorageMock struct {
+	meta.Storage
+	mutex   sync.Mutex
+	tables  map[string]map[string]bool
+	saves   int
+	saveErr error
+}
+
+func (dtsm *destinationTablesStorageMock) SaveDestinationTable(destinationID, tableName string) error {
+	dtsm.mutex.Lock()
+	defer dtsm.mutex.Unlock()
+	dtsm.saves++
+	if dtsm.saveErr != nil {
+		return dtsm.saveErr
+	}
+	if _, ok := dtsm.tables[destinationID]; !ok {
+		dtsm.tables[destinationID] = map[string]bool{}
+	}
+	dtsm.tables[destinationID][tableName] = true
+	return nil
+}
+
+func (dtsm *destinationTablesStorageMock) GetDestinationTables(destinationID string) ([]string, error) {
+	dtsm.mutex.Lock()
+	defer dtsm.mutex.Unlock()
+	var tableNames []string
+	for tableName := range dtsm.tables[destinationID] {
+		tableNames = append(tableNames, tableName)
+	}
+	return tableNames, nil
+}
+
+func TestManagedTables(t *testing.T) {
+	metaStorage := &destinationTablesStorageMock{tables: map[string]map[string]bool{"redshift": {"pages": true}, "other": {"orders": true}}}
+	mt := newManagedTables("redshift", metaStorage)
+
+	mt.add("events")
+	mt.add("events")
+	mt.add("identifies")
+	require.Equal(t, 2, metaStorage.saves, "table must be saved into meta storage once")
+
+	names, err := mt.names()
+	require.NoError(t, err)
+	require.Equal(t, []string{"events", "identifies", "pages"}, names, "tables written by other nodes must be included")
+
+	//table which hasn't been saved is saved on the next write
+	metaStorage.saveErr = errors.New("connection refused")
+	mt.add("users")
+	metaStorage.saveErr = nil
+	mt.add("users")
+	require.Equal(t, 4, metaStorage.saves)
+
+	withoutMeta := newManagedTables("redshift", nil)
+	withoutMeta.add("events")
+	names, err = withoutMeta.names()
+	require.NoError(t, err)
+	require.Equal(t, []string{"events"}, names)
+
+	var disabled *managedTables
+	disabled.add("events")
+}
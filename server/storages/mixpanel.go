@@ -0,0 +1,89 @@
+package storages
+
+import (
+	"fmt"
+
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/events"
+)
+
+//Mixpanel is a destination that can send data into Mixpanel
+//anonymous and identified users are merged on Mixpanel side (see IdentityLinker)
+type Mixpanel struct {
+	HTTPStorage
+
+	usersRecognition *UserRecognitionConfiguration
+}
+
+func init() {
+	RegisterStorage(StorageType{typeName: MixpanelType, createFunc: NewMixpanel})
+}
+
+//NewMixpanel returns configured Mixpanel destination
+func NewMixpanel(config *Config) (Storage, error) {
+	if !config.streamMode {
+		return nil, fmt.Errorf("Mixpanel destination doesn't support %s mode", BatchMode)
+	}
+
+	mixpanelConfig := config.destination.Mixpanel
+	if err := mixpanelConfig.Validate(); err != nil {
+		return nil, err
+	}
+
+	m := &Mixpanel{usersRecognition: config.usersRecognition}
+
+	requestDebugLogger := config.loggerFactory.CreateSQLQueryLogger(config.destinationID)
+	mAdapter, err := adapters.NewMixpanel(mixpanelConfig, &adapters.HTTPAdapterConfiguration{
+		DestinationID:  config.destinationID,
+		Dir:            config.logEventPath,
+		HTTPConfig:     DefaultHTTPConfiguration,
+		PoolWorkers:    defaultWorkersPoolSize,
+		DebugLogger:    requestDebugLogger,
+		ErrorHandler:   m.ErrorEvent,
+		SuccessHandler: m.SuccessEvent,
+		Batch:          config.destination.HTTPBatch,
+		RateLimit:      config.destination.HTTPRateLimit,
+		CircuitBreaker: config.destination.HTTPCircuitBreaker,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tableHelper := NewTableHelper(mAdapter, config.monitorKeeper, config.pkFields, adapters.DefaultSchemaTypeMappings, 0, MixpanelType)
+
+	//HTTPStorage
+	m.tableHelper = tableHelper
+	m.adapter = mAdapter
+
+	//Abstract (SQLAdapters and tableHelpers are omitted)
+	m.destinationID = config.destinationID
+	m.processor = config.processor
+	m.fallbackLogger = config.loggerFactory.CreateFailedLogger(config.destinationID)
+	m.eventsCache = config.eventsCache
+	m.metaStorage = config.metaStorage
+	m.archiveLogger = config.loggerFactory.CreateStreamingArchiveLogger(config.destinationID)
+	m.uniqueIDField = config.uniqueIDField
+	m.staged = config.destination.Staged
+	m.cachingConfiguration = config.destination.CachingConfiguration
+
+	//streaming worker (queue reading)
+	m.streamingWorker = newStreamingWorker(config.eventQueue, config.processor, m, tableHelper)
+	m.streamingWorker.start()
+
+	return m, nil
+}
+
+//GetUsersRecognition returns users recognition configuration
+func (m *Mixpanel) GetUsersRecognition() *UserRecognitionConfiguration {
+	return m.usersRecognition
+}
+
+//LinkIdentity sends $identify event which merges anonymous user into identified one
+func (m *Mixpanel) LinkIdentity(anonymousID string, identifiedEvent events.Event) error {
+	return m.linkIdentity(anonymousID, identifiedEvent)
+}
+
+//Type returns Mixpanel type
+func (m *Mixpanel) Type() string {
+	return MixpanelType
+}
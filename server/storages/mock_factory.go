@@ -35,6 +35,12 @@ func (tpm *testProxyMock) GetPostHandleDestinations() []string { return nil }
 //GetGeoResolverID is a mock func
 func (tpm *testProxyMock) GetGeoResolverID() string { return "" }
 
+//ApplyRetention is a mock func
+func (tpm *testProxyMock) ApplyRetention(dryRun bool) ([]*RetentionResult, error) { return nil, nil }
+
+//Status is a mock func
+func (tpm *testProxyMock) Status() *DestinationStatus { return &DestinationStatus{} }
+
 //MockFactory is a Mock destinations storages factory
 type MockFactory struct{}
 
@@ -2,10 +2,18 @@ package storages
 
 import (
 	"context"
+	"fmt"
 	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/scheduling"
+	"github.com/jitsucom/jitsu/server/uuid"
 	"io"
+	"time"
 )
 
+//clusterJobLockMinDuration is a minimum time of holding a cluster job lock:
+//cron jobs are scheduled with minute precision and are started on all nodes at (almost) the same time
+var clusterJobLockMinDuration = time.Minute
+
 type ResourceLock interface {
 	Unlock(ctx context.Context) error
 }
@@ -32,6 +40,75 @@ type MonitorKeeper interface {
 	IncrementVersion(system string, collection string) (int64, error)
 }
 
+//runClusterJob runs the destination scheduled job only on one node of the cluster:
+//the job is skipped if the lock is acquired by another node
+//monitorKeeper can be nil: the job is run without locking
+func runClusterJob(monitorKeeper MonitorKeeper, destinationID, jobName string, job func()) {
+	if monitorKeeper == nil {
+		job()
+		return
+	}
+
+	lock, err := monitorKeeper.TryLock(destinationID, jobName)
+	if err != nil {
+		logging.Infof("[%s] %s is skipped: it is running on another node: %v", destinationID, jobName, err)
+		return
+	}
+
+	start := time.Now()
+	defer func() {
+		//other nodes must not acquire the lock for the same cron run
+		if elapsed := time.Since(start); elapsed < clusterJobLockMinDuration {
+			time.Sleep(clusterJobLockMinDuration - elapsed)
+		}
+
+		if err := monitorKeeper.Unlock(lock); err != nil {
+			logging.SystemErrorf("[%s] Error unlocking %s: %v", destinationID, jobName, err)
+		}
+	}()
+
+	job()
+}
+
+//clusterJob is a destination job which is run on schedule only on one node of the cluster
+type clusterJob struct {
+	cronScheduler *scheduling.CronScheduler
+	jobKey        string
+}
+
+//scheduleClusterJob schedules the job according to cron schedule. The job is run with runClusterJob
+//returns nil if cron scheduler isn't available
+func scheduleClusterJob(config *Config, jobName, schedule string, job func()) (*clusterJob, error) {
+	if config.cronScheduler == nil {
+		logging.Warnf("[%s] %s is configured but scheduler isn't available. It won't be run", config.destinationID, jobName)
+		return nil, nil
+	}
+
+	//unique key: a new instance is created before the old one is closed on configuration reload
+	cj := &clusterJob{
+		cronScheduler: config.cronScheduler,
+		jobKey:        jobName + "_" + config.destinationID + "_" + uuid.New(),
+	}
+	destinationID := config.destinationID
+	monitorKeeper := config.monitorKeeper
+	if err := cj.cronScheduler.ScheduleFunc(cj.jobKey, schedule, func() {
+		runClusterJob(monitorKeeper, destinationID, jobName, job)
+	}); err != nil {
+		return nil, fmt.Errorf("Error scheduling %s with schedule [%s]: %v", jobName, schedule, err)
+	}
+
+	return cj, nil
+}
+
+//Close removes scheduled job
+func (cj *clusterJob) Close() error {
+	if cj == nil {
+		return nil
+	}
+
+	return cj.cronScheduler.RemoveFunc(cj.jobKey)
+}
+
 //RetryableLock hold lock, resource closer
 //For unlocking with retryCount attempts
 type RetryableLock struct {
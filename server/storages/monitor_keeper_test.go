@@ -0,0 +1,106 @@
+package storages
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/scheduling"
+	"github.com/stretchr/testify/require"
+)
+
+//monitorKeeperMock keeps locks in memory like a cluster coordination service
+type monitorKeeperMock struct {
+	MonitorKeeper
+
+	mutex  sync.Mutex
+	locked map[string]bool
+}
+
+type lockMock struct {
+	identifier string
+}
+
+func (lm *lockMock) Unlock()            {}
+func (lm *lockMock) Identifier() string { return lm.identifier }
+
+func (mkm *monitorKeeperMock) TryLock(system string, collection string) (Lock, error) {
+	mkm.mutex.Lock()
+	defer mkm.mutex.Unlock()
+
+	identifier := system + "_" + collection
+	if mkm.locked[identifier] {
+		return nil, errors.New("Resource has been already locked")
+	}
+	mkm.locked[identifier] = true
+	return &lockMock{identifier: identifier}, nil
+}
+
+func (mkm *monitorKeeperMock) Unlock(lock Lock) error {
+	mkm.mutex.Lock()
+	defer mkm.mutex.Unlock()
+
+	delete(mkm.locked, lock.Identifier())
+	return nil
+}
+
+func TestRunClusterJob(t *testing.T) {
+	defaultLockDuration := clusterJobLockMinDuration
+	clusterJobLockMinDuration = 100 * time.Millisecond
+	defer func() { clusterJobLockMinDuration = defaultLockDuration }()
+
+	monitorKeeper := &monitorKeeperMock{locked: map[string]bool{}}
+	var mutex sync.Mutex
+	runs := 0
+	job := func() {
+		mutex.Lock()
+		runs++
+		mutex.Unlock()
+	}
+
+	//the same cron run on several nodes
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			runClusterJob(monitorKeeper, "destination", "retention", job)
+		}()
+	}
+	wg.Wait()
+	require.Equal(t, 1, runs, "job is run only on one node")
+	require.Empty(t, monitorKeeper.locked, "lock is released after the job")
+
+	runClusterJob(monitorKeeper, "destination", "retention", job)
+	require.Equal(t, 2, runs, "the next cron run isn't skipped")
+
+	runClusterJob(nil, "destination", "retention", job)
+	require.Equal(t, 3, runs, "job is run without monitor keeper")
+}
+
+func TestScheduleClusterJob(t *testing.T) {
+	job := func() {}
+
+	withoutScheduler, err := scheduleClusterJob(&Config{destinationID: "destination"}, retentionJobName, "0 3 * * *", job)
+	require.NoError(t, err)
+	require.Nil(t, withoutScheduler)
+	require.NoError(t, withoutScheduler.Close())
+
+	cronScheduler := scheduling.NewCronScheduler()
+	defer cronScheduler.Close()
+	config := &Config{destinationID: "destination", cronScheduler: cronScheduler}
+
+	_, err = scheduleClusterJob(config, retentionJobName, "every day", job)
+	require.Error(t, err)
+
+	//a new instance is created before the old one is closed on configuration reload
+	oldJob, err := scheduleClusterJob(config, retentionJobName, "0 3 * * *", job)
+	require.NoError(t, err)
+	newJob, err := scheduleClusterJob(config, retentionJobName, "0 3 * * *", job)
+	require.NoError(t, err)
+	require.NotEqual(t, oldJob.jobKey, newJob.jobKey)
+
+	require.NoError(t, oldJob.Close())
+	require.NoError(t, newJob.Close())
+}
@@ -0,0 +1,206 @@
+package storages
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/events"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/schema"
+)
+
+const defaultMSSQLSchema = "dbo"
+
+//MSSQL stores files to Microsoft SQL Server in two modes:
+//batch: (1 file = 1 bulk copy)
+//stream: (1 object = 1 statement)
+type MSSQL struct {
+	Abstract
+
+	adapter                       *adapters.MSSQL
+	streamingWorker               *StreamingWorker
+	usersRecognitionConfiguration *UserRecognitionConfiguration
+}
+
+func init() {
+	RegisterStorage(StorageType{typeName: MSSQLType, createFunc: NewMSSQL})
+}
+
+//NewMSSQL returns configured MSSQL Destination
+func NewMSSQL(config *Config) (Storage, error) {
+	mConfig := config.destination.DataSource
+	if err := mConfig.Validate(); err != nil {
+		return nil, err
+	}
+	//enrich with default parameters
+	enrichMSSQLDataSource(config.destinationID, mConfig)
+
+	queryLogger := config.loggerFactory.CreateSQLQueryLogger(config.destinationID)
+	adapter, err := adapters.NewMSSQL(config.ctx, mConfig, queryLogger, config.sqlTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	//create db schema if doesn't exist
+	if err := adapter.CreateDbSchema(mConfig.Schema); err != nil {
+		adapter.Close()
+		return nil, err
+	}
+
+	tableHelper := NewTableHelper(adapter, config.monitorKeeper, config.pkFields, adapters.SchemaToMSSQL, config.maxColumns, MSSQLType)
+
+	m := &MSSQL{
+		adapter:                       adapter,
+		usersRecognitionConfiguration: config.usersRecognition,
+	}
+
+	//Abstract
+	m.destinationID = config.destinationID
+	m.processor = config.processor
+	m.fallbackLogger = config.loggerFactory.CreateFailedLogger(config.destinationID)
+	m.eventsCache = config.eventsCache
+	m.tableHelpers = []*TableHelper{tableHelper}
+	m.sqlAdapters = []adapters.SQLAdapter{adapter}
+	m.archiveLogger = config.loggerFactory.CreateStreamingArchiveLogger(config.destinationID)
+	m.uniqueIDField = config.uniqueIDField
+	m.staged = config.destination.Staged
+	m.cachingConfiguration = config.destination.CachingConfiguration
+
+	//streaming worker (queue reading)
+	m.streamingWorker = newStreamingWorker(config.eventQueue, config.processor, m, tableHelper)
+	m.streamingWorker.start()
+
+	return m, nil
+}
+
+//enrichMSSQLDataSource sets default port and schema (it is used by Synapse as well)
+func enrichMSSQLDataSource(destinationID string, mConfig *adapters.DataSourceConfig) {
+	if mConfig.Port.String() == "" {
+		mConfig.Port = "1433"
+		logging.Warnf("[%s] port wasn't provided. Will be used default one: %s", destinationID, mConfig.Port.String())
+	}
+	if mConfig.Schema == "" {
+		mConfig.Schema = defaultMSSQLSchema
+		logging.Warnf("[%s] schema wasn't provided. Will be used default one: %s", destinationID, mConfig.Schema)
+	}
+}
+
+func (m *MSSQL) DryRun(payload events.Event) ([][]adapters.TableField, error) {
+	_, tableHelper := m.getAdapters()
+	return dryRun(payload, m.processor, tableHelper)
+}
+
+//Store process events and stores with storeTable() func
+//returns store result per table, failed events (group of events which are failed to process) and err
+func (m *MSSQL) Store(fileName string, objects []map[string]interface{}, alreadyUploadedTables map[string]bool) (map[string]*StoreResult, *events.FailedEvents, *events.SkippedEvents, error) {
+	_, tableHelper := m.getAdapters()
+	flatData, failedEvents, skippedEvents, err := m.processor.ProcessEvents(fileName, objects, alreadyUploadedTables)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	//update cache with failed events
+	for _, failedEvent := range failedEvents.Events {
+		m.eventsCache.Error(m.IsCachingDisabled(), m.ID(), failedEvent.EventID, failedEvent.Error)
+	}
+	//update cache and counter with skipped events
+	for _, skipEvent := range skippedEvents.Events {
+		m.eventsCache.Skip(m.IsCachingDisabled(), m.ID(), skipEvent.EventID, skipEvent.Error)
+	}
+
+	storeFailedEvents := true
+	tableResults := map[string]*StoreResult{}
+	for _, fdata := range flatData {
+		table := tableHelper.MapTableSchema(fdata.BatchHeader)
+		err := m.storeTable(fdata, table)
+		tableResults[table.Name] = &StoreResult{Err: err, RowsCount: fdata.GetPayloadLen(), EventsSrc: fdata.GetEventsPerSrc()}
+		if err != nil {
+			storeFailedEvents = false
+		}
+
+		//events cache
+		for _, object := range fdata.GetPayload() {
+			if err != nil {
+				m.eventsCache.Error(m.IsCachingDisabled(), m.ID(), m.uniqueIDField.Extract(object), err.Error())
+			} else {
+				m.eventsCache.Succeed(&adapters.EventContext{
+					CacheDisabled:  m.IsCachingDisabled(),
+					DestinationID:  m.ID(),
+					EventID:        m.uniqueIDField.Extract(object),
+					ProcessedEvent: object,
+					Table:          table,
+				})
+			}
+		}
+	}
+
+	//store failed events to fallback only if other events have been inserted ok
+	if storeFailedEvents {
+		return tableResults, failedEvents, skippedEvents, nil
+	}
+
+	return tableResults, nil, skippedEvents, nil
+}
+
+//check table schema
+//and store data into one table
+func (m *MSSQL) storeTable(fdata *schema.ProcessedFile, table *adapters.Table) error {
+	_, tableHelper := m.getAdapters()
+	dbSchema, err := tableHelper.EnsureTableWithoutCaching(m.ID(), table)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	if err := m.adapter.BulkInsert(dbSchema, fdata.GetPayload()); err != nil {
+		return err
+	}
+	logging.Debugf("[%s] Inserted [%d] rows in [%.2f] seconds", m.ID(), len(fdata.GetPayload()), time.Now().Sub(start).Seconds())
+
+	return nil
+}
+
+//SyncStore is used in storing chunk of pulled data to MSSQL with processing
+func (m *MSSQL) SyncStore(overriddenDataSchema *schema.BatchHeader, objects []map[string]interface{}, timeIntervalValue string, cacheTable bool) error {
+	return syncStoreImpl(m, overriddenDataSchema, objects, timeIntervalValue, cacheTable)
+}
+
+func (m *MSSQL) Clean(tableName string) error {
+	return cleanImpl(m, tableName)
+}
+
+//Update uses SyncStore under the hood
+func (m *MSSQL) Update(object map[string]interface{}) error {
+	return m.SyncStore(nil, []map[string]interface{}{object}, "", true)
+}
+
+//GetUsersRecognition returns users recognition configuration
+func (m *MSSQL) GetUsersRecognition() *UserRecognitionConfiguration {
+	return m.usersRecognitionConfiguration
+}
+
+//Type returns MSSQL type
+func (m *MSSQL) Type() string {
+	return MSSQLType
+}
+
+//Close closes MSSQL adapter, fallback logger and streaming worker
+func (m *MSSQL) Close() (multiErr error) {
+	if err := m.adapter.Close(); err != nil {
+		multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing mssql datasource: %v", m.ID(), err))
+	}
+
+	if m.streamingWorker != nil {
+		if err := m.streamingWorker.Close(); err != nil {
+			multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing streaming worker: %v", m.ID(), err))
+		}
+	}
+
+	if err := m.close(); err != nil {
+		multiErr = multierror.Append(multiErr, err)
+	}
+
+	return
+}
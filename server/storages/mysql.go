@@ -23,6 +23,7 @@ type MySQL struct {
 	adapter                       *adapters.MySQL
 	streamingWorker               *StreamingWorker
 	usersRecognitionConfiguration *UserRecognitionConfiguration
+	partitionsMaintenance         *clusterJob
 }
 
 func init() {
@@ -47,7 +48,7 @@ func NewMySQL(config *Config) (Storage, error) {
 	}
 
 	queryLogger := config.loggerFactory.CreateSQLQueryLogger(config.destinationID)
-	adapter, err := CreateMySQLAdapter(config.ctx, *mConfig, queryLogger, config.sqlTypes)
+	adapter, err := CreateMySQLAdapter(config.ctx, *mConfig, config.partitioning, queryLogger, config.sqlTypes)
 	if err != nil {
 		return nil, err
 	}
@@ -75,20 +76,27 @@ func NewMySQL(config *Config) (Storage, error) {
 	m.streamingWorker = newStreamingWorker(config.eventQueue, config.processor, m, tableHelper)
 	m.streamingWorker.start()
 
+	m.partitionsMaintenance, err = newPartitionsMaintenance(config, adapter)
+	if err != nil {
+		m.Close()
+		return nil, err
+	}
+
 	return m, nil
 }
 
 //CreateMySQLAdapter creates mysql adapter with database
 //if database doesn't exist - mysql returns error. In this case connect without database and create it
-func CreateMySQLAdapter(ctx context.Context, config adapters.DataSourceConfig, queryLogger *logging.QueryLogger, sqlTypes typing.SQLTypes) (*adapters.MySQL, error) {
-	mySQLAdapter, err := adapters.NewMySQL(ctx, &config, queryLogger, sqlTypes)
+//partitioning can be nil
+func CreateMySQLAdapter(ctx context.Context, config adapters.DataSourceConfig, partitioning *adapters.PartitioningConfig, queryLogger *logging.QueryLogger, sqlTypes typing.SQLTypes) (*adapters.MySQL, error) {
+	mySQLAdapter, err := adapters.NewMySQL(ctx, &config, partitioning, queryLogger, sqlTypes)
 	if err != nil {
 		if mErr, ok := err.(*mysql.MySQLError); ok {
 			//db doesn't exist
 			if mErr.Number == 1049 {
 				mySQLDB := config.Db
 				config.Db = ""
-				mySQLAdapter, err := adapters.NewMySQL(ctx, &config, queryLogger, sqlTypes)
+				mySQLAdapter, err := adapters.NewMySQL(ctx, &config, partitioning, queryLogger, sqlTypes)
 				if err != nil {
 					return nil, err
 				}
@@ -100,7 +108,7 @@ func CreateMySQLAdapter(ctx context.Context, config adapters.DataSourceConfig, q
 				}
 				mySQLAdapter.Close()
 
-				mySQLAdapter, err = adapters.NewMySQL(ctx, &config, queryLogger, sqlTypes)
+				mySQLAdapter, err = adapters.NewMySQL(ctx, &config, partitioning, queryLogger, sqlTypes)
 				if err != nil {
 					return nil, err
 				}
@@ -214,6 +222,10 @@ func (m *MySQL) Type() string {
 
 //Close closes MySQL adapter, fallback logger and streaming worker
 func (m *MySQL) Close() (multiErr error) {
+	if err := m.partitionsMaintenance.Close(); err != nil {
+		multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error removing partitions maintenance job: %v", m.ID(), err))
+	}
+
 	if err := m.adapter.Close(); err != nil {
 		multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing postgres datasource: %v", m.ID(), err))
 	}
@@ -0,0 +1,33 @@
+package storages
+
+import (
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/logging"
+)
+
+//partitionsMaintenanceJob is a cluster lock collection of partitions maintenance
+const partitionsMaintenanceJob = "partitions_maintenance"
+
+//partitioningDestinationTypes are destinations with time partitioning support
+var partitioningDestinationTypes = map[string]bool{
+	PostgresType:   true,
+	MySQLType:      true,
+	ClickHouseType: true,
+}
+
+//newPartitionsMaintenance schedules maintainers MaintainPartitions() calls according to partitioning schedule
+//maintenance is run only on one node of the cluster
+//returns nil if partitioning isn't configured or cron scheduler isn't available
+func newPartitionsMaintenance(config *Config, maintainers ...adapters.PartitionsMaintainer) (*clusterJob, error) {
+	if config.partitioning == nil {
+		return nil, nil
+	}
+
+	destinationID := config.destinationID
+	return scheduleClusterJob(config, partitionsMaintenanceJob, config.partitioning.Schedule, func() {
+		logging.Infof("[%s] Running partitions maintenance..", destinationID)
+		for _, maintainer := range maintainers {
+			maintainer.MaintainPartitions()
+		}
+	})
+}
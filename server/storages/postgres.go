@@ -21,6 +21,7 @@ type Postgres struct {
 	adapter                       *adapters.Postgres
 	streamingWorker               *StreamingWorker
 	usersRecognitionConfiguration *UserRecognitionConfiguration
+	partitionsMaintenance         *clusterJob
 }
 
 func init() {
@@ -53,7 +54,7 @@ func NewPostgres(config *Config) (Storage, error) {
 	}
 
 	queryLogger := config.loggerFactory.CreateSQLQueryLogger(config.destinationID)
-	adapter, err := adapters.NewPostgres(config.ctx, pgConfig, queryLogger, config.sqlTypes)
+	adapter, err := adapters.NewPostgres(config.ctx, pgConfig, config.partitioning, queryLogger, config.sqlTypes)
 	if err != nil {
 		return nil, err
 	}
@@ -88,6 +89,12 @@ func NewPostgres(config *Config) (Storage, error) {
 	p.streamingWorker = newStreamingWorker(config.eventQueue, config.processor, p, tableHelper)
 	p.streamingWorker.start()
 
+	p.partitionsMaintenance, err = newPartitionsMaintenance(config, adapter)
+	if err != nil {
+		p.Close()
+		return nil, err
+	}
+
 	return p, nil
 }
 
@@ -187,6 +194,10 @@ func (p *Postgres) Type() string {
 
 //Close closes Postgres adapter, fallback logger and streaming worker
 func (p *Postgres) Close() (multiErr error) {
+	if err := p.partitionsMaintenance.Close(); err != nil {
+		multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error removing partitions maintenance job: %v", p.ID(), err))
+	}
+
 	if err := p.adapter.Close(); err != nil {
 		multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing postgres datasource: %v", p.ID(), err))
 	}
@@ -0,0 +1,89 @@
+package storages
+
+import (
+	"fmt"
+
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/events"
+)
+
+//PostHog is a destination that can send data into PostHog
+//anonymous and identified users are merged on PostHog side (see IdentityLinker)
+type PostHog struct {
+	HTTPStorage
+
+	usersRecognition *UserRecognitionConfiguration
+}
+
+func init() {
+	RegisterStorage(StorageType{typeName: PostHogType, createFunc: NewPostHog})
+}
+
+//NewPostHog returns configured PostHog destination
+func NewPostHog(config *Config) (Storage, error) {
+	if !config.streamMode {
+		return nil, fmt.Errorf("PostHog destination doesn't support %s mode", BatchMode)
+	}
+
+	posthogConfig := config.destination.PostHog
+	if err := posthogConfig.Validate(); err != nil {
+		return nil, err
+	}
+
+	ph := &PostHog{usersRecognition: config.usersRecognition}
+
+	requestDebugLogger := config.loggerFactory.CreateSQLQueryLogger(config.destinationID)
+	phAdapter, err := adapters.NewPostHog(posthogConfig, &adapters.HTTPAdapterConfiguration{
+		DestinationID:  config.destinationID,
+		Dir:            config.logEventPath,
+		HTTPConfig:     DefaultHTTPConfiguration,
+		PoolWorkers:    defaultWorkersPoolSize,
+		DebugLogger:    requestDebugLogger,
+		ErrorHandler:   ph.ErrorEvent,
+		SuccessHandler: ph.SuccessEvent,
+		Batch:          config.destination.HTTPBatch,
+		RateLimit:      config.destination.HTTPRateLimit,
+		CircuitBreaker: config.destination.HTTPCircuitBreaker,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tableHelper := NewTableHelper(phAdapter, config.monitorKeeper, config.pkFields, adapters.DefaultSchemaTypeMappings, 0, PostHogType)
+
+	//HTTPStorage
+	ph.tableHelper = tableHelper
+	ph.adapter = phAdapter
+
+	//Abstract (SQLAdapters and tableHelpers are omitted)
+	ph.destinationID = config.destinationID
+	ph.processor = config.processor
+	ph.fallbackLogger = config.loggerFactory.CreateFailedLogger(config.destinationID)
+	ph.eventsCache = config.eventsCache
+	ph.metaStorage = config.metaStorage
+	ph.archiveLogger = config.loggerFactory.CreateStreamingArchiveLogger(config.destinationID)
+	ph.uniqueIDField = config.uniqueIDField
+	ph.staged = config.destination.Staged
+	ph.cachingConfiguration = config.destination.CachingConfiguration
+
+	//streaming worker (queue reading)
+	ph.streamingWorker = newStreamingWorker(config.eventQueue, config.processor, ph, tableHelper)
+	ph.streamingWorker.start()
+
+	return ph, nil
+}
+
+//GetUsersRecognition returns users recognition configuration
+func (ph *PostHog) GetUsersRecognition() *UserRecognitionConfiguration {
+	return ph.usersRecognition
+}
+
+//LinkIdentity sends $identify event which merges anonymous user into identified one
+func (ph *PostHog) LinkIdentity(anonymousID string, identifiedEvent events.Event) error {
+	return ph.linkIdentity(anonymousID, identifiedEvent)
+}
+
+//Type returns PostHog type
+func (ph *PostHog) Type() string {
+	return PostHogType
+}
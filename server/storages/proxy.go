@@ -1,6 +1,9 @@
 package storages
 
 import (
+	"fmt"
+	"github.com/hashicorp/go-multierror"
+	"github.com/jitsucom/jitsu/server/adapters"
 	"github.com/jitsucom/jitsu/server/identifiers"
 	"github.com/jitsucom/jitsu/server/logging"
 	"github.com/jitsucom/jitsu/server/safego"
@@ -15,10 +18,11 @@ type RetryableProxy struct {
 	sync.RWMutex
 	factoryMethod func(*Config) (Storage, error)
 
-	config  *Config
-	storage Storage
-	ready   *atomic.Bool
-	closed  *atomic.Bool
+	config    *Config
+	storage   Storage
+	retention *clusterJob
+	ready     *atomic.Bool
+	closed    *atomic.Bool
 }
 
 //newProxy return New RetryableProxy and starts goroutine
@@ -48,8 +52,14 @@ func (rsp *RetryableProxy) start() {
 				continue
 			}
 
+			retention, err := newRetentionJob(rsp.config, storage)
+			if err != nil {
+				logging.Errorf("[%s] Error initializing retention: %v", rsp.config.destinationID, err)
+			}
+
 			rsp.Lock()
 			rsp.storage = storage
+			rsp.retention = retention
 			rsp.ready.Store(true)
 			rsp.Unlock()
 
@@ -98,11 +108,48 @@ func (rsp *RetryableProxy) GetGeoResolverID() string {
 	return rsp.config.destination.GeoDataResolverID
 }
 
-//Close stops underlying goroutine and close the storage
-func (rsp *RetryableProxy) Close() error {
+//ApplyRetention applies configured retention rules to the underlying storage
+//if dryRun is true - returns what would be deleted
+func (rsp *RetryableProxy) ApplyRetention(dryRun bool) ([]*RetentionResult, error) {
+	if rsp.config.retention == nil {
+		return nil, ErrRetentionIsNotConfigured
+	}
+
+	storage, ok := rsp.Get()
+	if !ok {
+		return nil, fmt.Errorf("Destination [%s] hasn't been initialized yet", rsp.config.destinationID)
+	}
+
+	return storage.ApplyRetention(rsp.config.retention.Rules, dryRun)
+}
+
+//Status returns destination initialization status and HTTP adapter status (queue size, circuit breaker and rate limit)
+func (rsp *RetryableProxy) Status() *DestinationStatus {
+	storage, ok := rsp.Get()
+	status := &DestinationStatus{ID: rsp.config.destinationID, Type: rsp.config.destination.Type, Initialized: ok}
+	if httpStorage, isHTTP := storage.(interface {
+		HTTPStatus() *adapters.HTTPAdapterStatus
+	}); ok && isHTTP {
+		status.HTTP = httpStorage.HTTPStatus()
+	}
+
+	return status
+}
+
+//Close stops underlying goroutine, removes retention job and close the storage
+func (rsp *RetryableProxy) Close() (multiErr error) {
 	rsp.closed.Store(true)
+
+	rsp.RLock()
+	defer rsp.RUnlock()
+	if err := rsp.retention.Close(); err != nil {
+		multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error removing retention job: %v", rsp.config.destinationID, err))
+	}
 	if rsp.storage != nil {
-		return rsp.storage.Close()
+		if err := rsp.storage.Close(); err != nil {
+			multiErr = multierror.Append(multiErr, err)
+		}
 	}
-	return nil
+
+	return
 }
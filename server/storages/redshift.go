@@ -1,8 +1,10 @@
 package storages
 
 import (
+	"bytes"
 	"fmt"
 	"github.com/jitsucom/jitsu/server/appconfig"
+	"strings"
 	"time"
 
 	"github.com/hashicorp/go-multierror"
@@ -12,6 +14,9 @@ import (
 	"github.com/jitsucom/jitsu/server/schema"
 )
 
+//redshiftMaintenanceJob is a cluster lock collection of scheduled VACUUM and ANALYZE
+const redshiftMaintenanceJob = "redshift_maintenance"
+
 //AwsRedshift stores files to aws RedShift in two modes:
 //batch: via aws s3 in batch mode (1 file = 1 statement)
 //stream: via events queue in stream mode (1 object = 1 statement)
@@ -22,6 +27,9 @@ type AwsRedshift struct {
 	redshiftAdapter               *adapters.AwsRedshift
 	streamingWorker               *StreamingWorker
 	usersRecognitionConfiguration *UserRecognitionConfiguration
+	managedTables                 *managedTables
+
+	maintenance *clusterJob
 }
 
 func init() {
@@ -48,6 +56,10 @@ func NewAwsRedshift(config *Config) (Storage, error) {
 		redshiftConfig.Parameters["connect_timeout"] = "600"
 	}
 
+	if err := config.destination.Redshift.Validate(); err != nil {
+		return nil, err
+	}
+
 	dir := adapters.SSLDir(appconfig.Instance.ConfigPath, config.destinationID)
 	if err := adapters.ProcessSSL(dir, redshiftConfig); err != nil {
 		return nil, err
@@ -63,7 +75,7 @@ func NewAwsRedshift(config *Config) (Storage, error) {
 	}
 
 	queryLogger := config.loggerFactory.CreateSQLQueryLogger(config.destinationID)
-	redshiftAdapter, err := adapters.NewAwsRedshift(config.ctx, redshiftConfig, config.destination.S3, queryLogger, config.sqlTypes)
+	redshiftAdapter, err := adapters.NewAwsRedshift(config.ctx, redshiftConfig, config.destination.S3, config.destination.Redshift, queryLogger, config.sqlTypes)
 	if err != nil {
 		return nil, err
 	}
@@ -76,11 +88,15 @@ func NewAwsRedshift(config *Config) (Storage, error) {
 	}
 
 	tableHelper := NewTableHelper(redshiftAdapter, config.monitorKeeper, config.pkFields, adapters.SchemaToRedshift, config.maxColumns, RedshiftType)
+	//only tables which the destination writes to are maintained
+	managedTables := newManagedTables(config.destinationID, config.metaStorage)
+	tableHelper.managedTables = managedTables
 
 	ar := &AwsRedshift{
 		s3Adapter:                     s3Adapter,
 		redshiftAdapter:               redshiftAdapter,
 		usersRecognitionConfiguration: config.usersRecognition,
+		managedTables:                 managedTables,
 	}
 
 	//Abstract
@@ -99,9 +115,32 @@ func NewAwsRedshift(config *Config) (Storage, error) {
 	ar.streamingWorker = newStreamingWorker(config.eventQueue, config.processor, ar, tableHelper)
 	ar.streamingWorker.start()
 
+	//VACUUM and ANALYZE of the destination tables
+	if config.destination.Redshift != nil && config.destination.Redshift.Maintenance != nil {
+		maintenance, err := scheduleClusterJob(config, redshiftMaintenanceJob, config.destination.Redshift.Maintenance.Schedule, ar.maintain)
+		if err != nil {
+			ar.Close()
+			return nil, err
+		}
+		ar.maintenance = maintenance
+	}
+
 	return ar, nil
 }
 
+//maintain runs VACUUM and ANALYZE of tables which the destination writes to
+func (ar *AwsRedshift) maintain() {
+	tableNames, err := ar.managedTables.names()
+	if err != nil {
+		logging.Errorf("[%s] Error getting tables for Redshift maintenance: %v", ar.ID(), err)
+		return
+	}
+
+	start := time.Now()
+	ar.redshiftAdapter.Maintain(tableNames)
+	logging.Infof("[%s] Redshift maintenance has been finished in [%.2f] seconds", ar.ID(), time.Since(start).Seconds())
+}
+
 //Store process events and stores with storeTable() func
 //returns store result per table, failed events (group of events which are failed to process) and err
 func (ar *AwsRedshift) Store(fileName string, objects []map[string]interface{}, alreadyUploadedTables map[string]bool) (map[string]*StoreResult, *events.FailedEvents, *events.SkippedEvents, error) {
@@ -156,6 +195,7 @@ func (ar *AwsRedshift) Store(fileName string, objects []map[string]interface{},
 
 //check table schema
 //and store data into one table via s3
+//tables with primary keys: deduplicated objects are copied into a staging table and merged into the table
 func (ar *AwsRedshift) storeTable(fdata *schema.ProcessedFile, table *adapters.Table) error {
 	_, tableHelper := ar.getAdapters()
 	dbTable, err := tableHelper.EnsureTableWithoutCaching(ar.ID(), table)
@@ -163,12 +203,24 @@ func (ar *AwsRedshift) storeTable(fdata *schema.ProcessedFile, table *adapters.T
 		return err
 	}
 
-	b := fdata.GetPayloadBytes(schema.JSONMarshallerInstance)
+	merge := len(dbTable.PKFields) > 0
+
+	var b []byte
+	if merge {
+		b = marshalLastByPrimaryKeys(dbTable.GetPKFields(), fdata.GetPayload())
+	} else {
+		b = fdata.GetPayloadBytes(schema.JSONMarshallerInstance)
+	}
 	if err := ar.s3Adapter.UploadBytes(fdata.FileName, b); err != nil {
 		return err
 	}
 
-	if err := ar.redshiftAdapter.Copy(fdata.FileName, dbTable.Name); err != nil {
+	if merge {
+		err = ar.redshiftAdapter.CopyMerge(fdata.FileName, dbTable)
+	} else {
+		err = ar.redshiftAdapter.Copy(fdata.FileName, dbTable.Name)
+	}
+	if err != nil {
 		return fmt.Errorf("Error copying file [%s] from s3 to redshift: %v", fdata.FileName, err)
 	}
 
@@ -179,6 +231,49 @@ func (ar *AwsRedshift) storeTable(fdata *schema.ProcessedFile, table *adapters.T
 	return nil
 }
 
+//marshalLastByPrimaryKeys returns JSON lines of objects deduplicated by primary keys values (the last object wins)
+func marshalLastByPrimaryKeys(pkFields []string, objects []map[string]interface{}) []byte {
+	deduplicated := lastByPrimaryKeys(pkFields, objects)
+
+	buf := bytes.Buffer{}
+	for i, object := range deduplicated {
+		b, err := schema.JSONMarshallerInstance.Marshal(nil, object)
+		if err != nil {
+			logging.SystemErrorf("Error marshaling object %v: %v", object, err)
+			continue
+		}
+		if i > 0 {
+			buf.WriteByte('\n')
+		}
+		buf.Write(b)
+	}
+
+	return buf.Bytes()
+}
+
+//lastByPrimaryKeys returns objects deduplicated by primary keys values (the last object wins)
+//objects order is kept
+func lastByPrimaryKeys(pkFields []string, objects []map[string]interface{}) []map[string]interface{} {
+	keyIndex := map[string]int{}
+	var deduplicated []map[string]interface{}
+	for _, object := range objects {
+		var key strings.Builder
+		for _, pkField := range pkFields {
+			key.WriteString(fmt.Sprint(object[pkField]))
+			key.WriteString("|")
+		}
+
+		if i, ok := keyIndex[key.String()]; ok {
+			deduplicated[i] = object
+			continue
+		}
+		keyIndex[key.String()] = len(deduplicated)
+		deduplicated = append(deduplicated, object)
+	}
+
+	return deduplicated
+}
+
 // SyncStore is used in storing chunk of pulled data to AwsRedshift with processing
 func (ar *AwsRedshift) SyncStore(overriddenDataSchema *schema.BatchHeader, objects []map[string]interface{}, timeIntervalValue string, cacheTable bool) error {
 	return syncStoreImpl(ar, overriddenDataSchema, objects, timeIntervalValue, cacheTable)
@@ -224,8 +319,12 @@ func (ar *AwsRedshift) Type() string {
 	return RedshiftType
 }
 
-//Close closes AwsRedshift adapter, fallback logger and streaming worker
+//Close removes maintenance job, closes AwsRedshift adapter, fallback logger and streaming worker
 func (ar *AwsRedshift) Close() (multiErr error) {
+	if err := ar.maintenance.Close(); err != nil {
+		multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error removing redshift maintenance job: %v", ar.ID(), err))
+	}
+
 	if err := ar.redshiftAdapter.Close(); err != nil {
 		multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing redshift datasource: %v", ar.ID(), err))
 	}
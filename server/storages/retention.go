@@ -0,0 +1,204 @@
+package storages
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/timestamp"
+)
+
+const (
+	defaultRetentionSchedule = "0 3 * * *"
+	//retentionJobName is a cluster lock collection of scheduled retention
+	retentionJobName = "retention"
+)
+
+var (
+	//ErrRetentionIsNotConfigured is returned on retention preview of a destination without retention configuration
+	ErrRetentionIsNotConfigured = errors.New("Destination doesn't have retention configuration")
+
+	//retentionDestinationTypes are destinations with data retention support
+	retentionDestinationTypes = map[string]bool{
+		PostgresType:   true,
+		MySQLType:      true,
+		ClickHouseType: true,
+		RedshiftType:   true,
+		SnowflakeType:  true,
+		BigQueryType:   true,
+		SQLiteType:     true,
+		MSSQLType:      true,
+		SynapseType:    true,
+		S3Type:         true,
+	}
+
+	//objectsRetentionDestinationTypes are destinations with S3 or GCS objects retention support
+	objectsRetentionDestinationTypes = map[string]bool{
+		S3Type:       true,
+		BigQueryType: true,
+	}
+)
+
+//RetentionConfig dto for data retention policies of a destination
+type RetentionConfig struct {
+	//Rules are applied one by one on every run
+	Rules []*RetentionRule `mapstructure:"rules" json:"rules,omitempty" yaml:"rules,omitempty"`
+	//Schedule of retention runs in standard cron format
+	Schedule string `mapstructure:"schedule" json:"schedule,omitempty" yaml:"schedule,omitempty"`
+	//DryRun if true - scheduled runs only log what would be deleted
+	DryRun bool `mapstructure:"dry_run" json:"dry_run,omitempty" yaml:"dry_run,omitempty"`
+}
+
+//RetentionRule is a policy for tables which names match Tables pattern or for S3/GCS objects with Prefix
+type RetentionRule struct {
+	//Tables is a table name pattern in path.Match syntax (e.g. events_*). Matching is case insensitive
+	Tables string `mapstructure:"tables" json:"tables,omitempty" yaml:"tables,omitempty"`
+	//Prefix is an objects key prefix: in the configured folder of S3 destination or in gcs_bucket of BigQuery destination
+	Prefix string `mapstructure:"prefix" json:"prefix,omitempty" yaml:"prefix,omitempty"`
+	//Field is a timestamp column which value is compared with retention boundary
+	Field string `mapstructure:"field" json:"field,omitempty" yaml:"field,omitempty"`
+	//Days is a number of days to keep data
+	Days int `mapstructure:"days" json:"days,omitempty" yaml:"days,omitempty"`
+}
+
+//RetentionResult is a result of applying a retention rule to one table or objects prefix
+type RetentionResult struct {
+	Table  string    `json:"table,omitempty"`
+	Prefix string    `json:"prefix,omitempty"`
+	Before time.Time `json:"before"`
+	//Deleted is a number of deleted (or to be deleted in dry run) rows or objects
+	Deleted int64    `json:"deleted"`
+	Objects []string `json:"objects,omitempty"`
+	DryRun  bool     `json:"dry_run"`
+	Error   string   `json:"error,omitempty"`
+}
+
+//objectsStorage is S3 or Google Cloud Storage adapter
+type objectsStorage interface {
+	DeleteObjectsOlderThan(prefix string, before time.Time, dryRun bool) ([]string, error)
+}
+
+//Validate returns err if invalid and sets default values
+func (rc *RetentionConfig) Validate(destinationType string) error {
+	if len(rc.Rules) == 0 {
+		return errors.New("retention.rules are required")
+	}
+
+	for i, rule := range rc.Rules {
+		if rule == nil {
+			return fmt.Errorf("retention.rules[%d] is empty", i)
+		}
+		if (rule.Tables == "") == (rule.Prefix == "") {
+			return fmt.Errorf("retention.rules[%d]: one of tables or prefix is required", i)
+		}
+		if rule.Days <= 0 {
+			return fmt.Errorf("retention.rules[%d]: days must be positive", i)
+		}
+		if rule.Tables != "" {
+			if destinationType == S3Type {
+				return fmt.Errorf("retention.rules[%d]: %s destination supports only prefix rules", i, destinationType)
+			}
+			if _, err := path.Match(rule.Tables, ""); err != nil {
+				return fmt.Errorf("retention.rules[%d]: malformed tables pattern [%s]: %v", i, rule.Tables, err)
+			}
+		}
+		if rule.Prefix != "" && !objectsRetentionDestinationTypes[destinationType] {
+			return fmt.Errorf("retention.rules[%d]: prefix rules aren't supported by %s destination", i, destinationType)
+		}
+		if rule.Field == "" {
+			rule.Field = timestamp.Key
+		}
+	}
+
+	if rc.Schedule == "" {
+		rc.Schedule = defaultRetentionSchedule
+	}
+
+	return nil
+}
+
+//boundary returns the time before which data is deleted
+func (rr *RetentionRule) boundary(now time.Time) time.Time {
+	return now.AddDate(0, 0, -rr.Days)
+}
+
+//matchTable returns true if the rule is a tables rule and tableName matches the pattern
+func (rr *RetentionRule) matchTable(tableName string) bool {
+	if rr.Tables == "" {
+		return false
+	}
+
+	matched, _ := path.Match(strings.ToLower(rr.Tables), strings.ToLower(tableName))
+	return matched
+}
+
+//applyObjectsRetention applies prefix rules to objects storage
+func applyObjectsRetention(storage objectsStorage, rules []*RetentionRule, dryRun bool) (results []*RetentionResult, multiErr error) {
+	now := time.Now().UTC()
+	for _, rule := range rules {
+		if rule.Prefix == "" {
+			continue
+		}
+
+		result := &RetentionResult{Prefix: rule.Prefix, Before: rule.boundary(now), DryRun: dryRun}
+		objects, err := storage.DeleteObjectsOlderThan(rule.Prefix, result.Before, dryRun)
+		if err != nil {
+			result.Error = err.Error()
+			multiErr = multierror.Append(multiErr, err)
+		}
+		result.Objects = objects
+		result.Deleted = int64(len(objects))
+		results = append(results, result)
+	}
+
+	return results, multiErr
+}
+
+//logRetentionResults writes what has been deleted (or would be deleted in dry run) into the application log
+func logRetentionResults(destinationID string, results []*RetentionResult) {
+	for _, result := range results {
+		target := "table " + result.Table
+		unit := "rows"
+		if result.Prefix != "" {
+			target = "prefix " + result.Prefix
+			unit = "objects"
+		}
+
+		action := "Deleted"
+		if result.DryRun {
+			action = "[dry run] Would be deleted"
+		}
+
+		if result.Error != "" {
+			logging.Errorf("[%s] Retention error in %s: %s", destinationID, target, result.Error)
+		}
+		logging.Infof("[%s] Retention: %s %d %s older than %s from %s", destinationID, action, result.Deleted, unit, timestamp.ToISOFormat(result.Before), target)
+		for _, object := range result.Objects {
+			logging.Debugf("[%s] Retention: %s object %s", destinationID, action, object)
+		}
+	}
+}
+
+//newRetentionJob schedules storage ApplyRetention() calls according to retention schedule
+//retention is run only on one node of the cluster
+//returns nil if retention isn't configured or cron scheduler isn't available
+func newRetentionJob(config *Config, storage Storage) (*clusterJob, error) {
+	if config.retention == nil {
+		return nil, nil
+	}
+
+	destinationID := config.destinationID
+	retention := config.retention
+	return scheduleClusterJob(config, retentionJobName, retention.Schedule, func() {
+		logging.Infof("[%s] Running retention..", destinationID)
+		results, err := storage.ApplyRetention(retention.Rules, retention.DryRun)
+		logRetentionResults(destinationID, results)
+		if err != nil {
+			logging.Errorf("[%s] Retention finished with errors: %v", destinationID, err)
+		}
+	})
+}
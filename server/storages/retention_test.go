@@ -0,0 +1,79 @@
+package storages
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestRetentionConfigValidate(t *testing.T) {
+	tests := []struct {
+		name            string
+		destinationType string
+		config          *RetentionConfig
+		expectedErr     string
+	}{
+		{
+			"empty rules",
+			PostgresType,
+			&RetentionConfig{},
+			"retention.rules are required",
+		},
+		{
+			"tables and prefix",
+			BigQueryType,
+			&RetentionConfig{Rules: []*RetentionRule{{Tables: "events", Prefix: "events", Days: 1}}},
+			"retention.rules[0]: one of tables or prefix is required",
+		},
+		{
+			"non positive days",
+			PostgresType,
+			&RetentionConfig{Rules: []*RetentionRule{{Tables: "events"}}},
+			"retention.rules[0]: days must be positive",
+		},
+		{
+			"tables rule in s3",
+			S3Type,
+			&RetentionConfig{Rules: []*RetentionRule{{Tables: "events", Days: 1}}},
+			"retention.rules[0]: s3 destination supports only prefix rules",
+		},
+		{
+			"prefix rule in postgres",
+			PostgresType,
+			&RetentionConfig{Rules: []*RetentionRule{{Tables: "events", Days: 1}, {Prefix: "events", Days: 1}}},
+			"retention.rules[1]: prefix rules aren't supported by postgres destination",
+		},
+		{
+			"malformed pattern",
+			PostgresType,
+			&RetentionConfig{Rules: []*RetentionRule{{Tables: "events[", Days: 1}}},
+			"retention.rules[0]: malformed tables pattern [events[]: syntax error in pattern",
+		},
+		{
+			"valid",
+			BigQueryType,
+			&RetentionConfig{Rules: []*RetentionRule{{Tables: "events_*", Days: 90}, {Prefix: "raw/", Days: 30}}},
+			"",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate(tt.destinationType)
+			if tt.expectedErr != "" {
+				require.EqualError(t, err, tt.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, defaultRetentionSchedule, tt.config.Schedule)
+			require.Equal(t, "_timestamp", tt.config.Rules[0].Field)
+		})
+	}
+}
+
+func TestRetentionRuleMatchTable(t *testing.T) {
+	rule := &RetentionRule{Tables: "events_*"}
+	require.True(t, rule.matchTable("events_pageview"))
+	require.True(t, rule.matchTable("EVENTS_PAGEVIEW"))
+	require.False(t, rule.matchTable("events"))
+	require.False(t, (&RetentionRule{Prefix: "events"}).matchTable("events"))
+}
@@ -1,10 +1,15 @@
 package storages
 
 import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/hashicorp/go-multierror"
 	"github.com/jitsucom/jitsu/server/timestamp"
+	"path"
+	"strings"
 	"time"
 
 	"github.com/jitsucom/jitsu/server/adapters"
@@ -176,6 +181,159 @@ func (s3 *S3) Update(object map[string]interface{}) error {
 	return errors.New("S3 doesn't support updates")
 }
 
+//DeleteUserData rewrites all files in the bucket folder without objects which match deleteConditions
+//if tableNames aren't empty - only files of these tables are processed
+func (s3 *S3) DeleteUserData(tableNames []string, deleteConditions *adapters.DeleteConditions) (multiErr error) {
+	keys, err := s3.s3Adapter.ListObjects()
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		if !s3.isTableFile(key, tableNames) {
+			continue
+		}
+
+		b, err := s3.s3Adapter.GetObject(key)
+		if err != nil {
+			multiErr = multierror.Append(multiErr, err)
+			continue
+		}
+
+		var filtered []byte
+		var removed int
+		if s3.s3Adapter.Format() == adapters.S3FormatCSV {
+			filtered, removed, err = filterCSVPayload(b, deleteConditions)
+		} else {
+			filtered, removed, err = filterJSONPayload(b, deleteConditions)
+		}
+		if err != nil {
+			multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error filtering file %s: %v", s3.ID(), key, err))
+			continue
+		}
+
+		if removed == 0 {
+			continue
+		}
+
+		if err := s3.s3Adapter.RewriteObject(key, filtered); err != nil {
+			multiErr = multierror.Append(multiErr, err)
+			continue
+		}
+
+		logging.Infof("[%s] Deleted %d objects from file %s", s3.ID(), removed, key)
+	}
+
+	return multiErr
+}
+
+//ApplyRetention deletes objects older than rules days with rules prefixes in the bucket folder
+//if dryRun is true - objects are only listed
+func (s3 *S3) ApplyRetention(rules []*RetentionRule, dryRun bool) ([]*RetentionResult, error) {
+	return applyObjectsRetention(s3.s3Adapter, rules, dryRun)
+}
+
+func (s3 *S3) isTableFile(key string, tableNames []string) bool {
+	if len(tableNames) == 0 {
+		return true
+	}
+
+	fileName := path.Base(key)
+	for _, tableName := range tableNames {
+		if strings.HasPrefix(fileName, tableName+"-start-") {
+			return true
+		}
+	}
+
+	return false
+}
+
+//filterJSONPayload returns JSON lines payload without objects which match deleteConditions and count of removed objects
+func filterJSONPayload(payload []byte, deleteConditions *adapters.DeleteConditions) ([]byte, int, error) {
+	flattener := schema.NewFlattener()
+	buf := bytes.Buffer{}
+	removed := 0
+	for _, line := range bytes.Split(payload, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		object := map[string]interface{}{}
+		if err := json.Unmarshal(line, &object); err != nil {
+			return nil, 0, err
+		}
+
+		flatObject, err := flattener.FlattenObject(object)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		if deleteConditions.Match(flatObject) {
+			removed++
+			continue
+		}
+
+		buf.Write(line)
+		buf.WriteString("\n")
+	}
+
+	return buf.Bytes(), removed, nil
+}
+
+//filterCSVPayload returns CSV payload (with header) without rows which match deleteConditions and count of removed rows
+//keeps original lines as is because values are written without escaping (see schema.CSVMarshaller)
+func filterCSVPayload(payload []byte, deleteConditions *adapters.DeleteConditions) ([]byte, int, error) {
+	lines := bytes.Split(payload, []byte("\n"))
+	if len(lines) == 0 {
+		return payload, 0, nil
+	}
+
+	header, err := parseCSVLine(lines[0])
+	if err != nil {
+		return nil, 0, err
+	}
+
+	buf := bytes.Buffer{}
+	buf.Write(lines[0])
+	buf.WriteString("\n")
+
+	removed := 0
+	for _, line := range lines[1:] {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		values, err := parseCSVLine(line)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		object := map[string]interface{}{}
+		for i, value := range values {
+			if i < len(header) {
+				object[header[i]] = value
+			}
+		}
+
+		if deleteConditions.Match(object) {
+			removed++
+			continue
+		}
+
+		buf.Write(line)
+		buf.WriteString("\n")
+	}
+
+	return buf.Bytes(), removed, nil
+}
+
+func parseCSVLine(line []byte) ([]string, error) {
+	reader := csv.NewReader(bytes.NewReader(line))
+	reader.LazyQuotes = true
+	reader.FieldsPerRecord = -1
+	return reader.Read()
+}
+
 //GetUsersRecognition returns disabled users recognition configuration
 func (s3 *S3) GetUsersRecognition() *UserRecognitionConfiguration {
 	return disabledRecognitionConfiguration
@@ -1,6 +1,7 @@
 package storages
 
 import (
+	"github.com/jitsucom/jitsu/server/adapters"
 	"github.com/jitsucom/jitsu/server/timestamp"
 	"github.com/stretchr/testify/require"
 	"testing"
@@ -59,3 +60,39 @@ func TestFindStartEndTimestamp(t *testing.T) {
 		})
 	}
 }
+
+func TestFilterJSONPayload(t *testing.T) {
+	payload := []byte(`{"user":{"id":"123"},"event_type":"pageview"}
+{"user":{"id":"456"},"event_type":"pageview"}
+
+{"user":{"id":"123","anonymous_id":"anon1"},"event_type":"click"}
+{"user":{"anonymous_id":"anon1"},"page":"123"}
+`)
+	conditions := &adapters.DeleteConditions{JoinCondition: "OR", Conditions: []adapters.DeleteCondition{
+		{Field: "user_id", Clause: "=", Value: "123"},
+		{Field: "user_anonymous_id", Clause: "=", Value: "anon1"},
+	}}
+
+	filtered, removed, err := filterJSONPayload(payload, conditions)
+	require.NoError(t, err)
+	require.Equal(t, 3, removed)
+	require.Equal(t, `{"user":{"id":"456"},"event_type":"pageview"}`+"\n", string(filtered))
+
+	_, _, err = filterJSONPayload([]byte(`{"user":`), conditions)
+	require.Error(t, err)
+}
+
+func TestFilterCSVPayload(t *testing.T) {
+	payload := []byte(`user_id,user_anonymous_id,page
+123,anon2,/
+456,anon1,/about
+789,anon3,"123"
+`)
+	conditions := adapters.DeleteByFieldValuesCondition("user_id", []string{"123"})
+	conditions.Conditions = append(conditions.Conditions, adapters.DeleteByFieldValuesCondition("user_anonymous_id", []string{"anon1"}).Conditions...)
+
+	filtered, removed, err := filterCSVPayload(payload, conditions)
+	require.NoError(t, err)
+	require.Equal(t, 2, removed)
+	require.Equal(t, "user_id,user_anonymous_id,page\n"+`789,anon3,"123"`+"\n", string(filtered))
+}
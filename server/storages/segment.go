@@ -0,0 +1,75 @@
+package storages
+
+import (
+	"fmt"
+
+	"github.com/jitsucom/jitsu/server/adapters"
+)
+
+//Segment sends events to Segment-compatible HTTP Tracking API in stream mode
+type Segment struct {
+	HTTPStorage
+}
+
+func init() {
+	RegisterStorage(StorageType{typeName: SegmentType, createFunc: NewSegment})
+}
+
+//NewSegment returns Segment instance
+//start streaming worker goroutine
+func NewSegment(config *Config) (Storage, error) {
+	if !config.streamMode {
+		return nil, fmt.Errorf("Segment destination doesn't support %s mode", BatchMode)
+	}
+
+	segmentConfig := config.destination.Segment
+	if err := segmentConfig.Validate(); err != nil {
+		return nil, err
+	}
+
+	s := &Segment{}
+
+	requestDebugLogger := config.loggerFactory.CreateSQLQueryLogger(config.destinationID)
+	sAdapter, err := adapters.NewSegment(segmentConfig, &adapters.HTTPAdapterConfiguration{
+		DestinationID:  config.destinationID,
+		Dir:            config.logEventPath,
+		HTTPConfig:     DefaultHTTPConfiguration,
+		PoolWorkers:    defaultWorkersPoolSize,
+		DebugLogger:    requestDebugLogger,
+		ErrorHandler:   s.ErrorEvent,
+		SuccessHandler: s.SuccessEvent,
+		Batch:          config.destination.HTTPBatch,
+		RateLimit:      config.destination.HTTPRateLimit,
+		CircuitBreaker: config.destination.HTTPCircuitBreaker,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tableHelper := NewTableHelper(sAdapter, config.monitorKeeper, config.pkFields, adapters.DefaultSchemaTypeMappings, 0, SegmentType)
+
+	s.adapter = sAdapter
+	s.tableHelper = tableHelper
+
+	//Abstract (SQLAdapters and tableHelpers are omitted)
+	s.destinationID = config.destinationID
+	s.processor = config.processor
+	s.fallbackLogger = config.loggerFactory.CreateFailedLogger(config.destinationID)
+	s.eventsCache = config.eventsCache
+	s.metaStorage = config.metaStorage
+	s.archiveLogger = config.loggerFactory.CreateStreamingArchiveLogger(config.destinationID)
+	s.uniqueIDField = config.uniqueIDField
+	s.staged = config.destination.Staged
+	s.cachingConfiguration = config.destination.CachingConfiguration
+
+	//streaming worker (queue reading)
+	s.streamingWorker = newStreamingWorker(config.eventQueue, config.processor, s, tableHelper)
+	s.streamingWorker.start()
+
+	return s, nil
+}
+
+//Type returns Segment type
+func (s *Segment) Type() string {
+	return SegmentType
+}
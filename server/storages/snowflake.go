@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/jitsucom/jitsu/server/typing"
 
@@ -16,13 +18,17 @@ import (
 )
 
 //Snowflake stores files to Snowflake in two modes:
-//batch: via aws s3 (or gcp) in batch mode (1 file = 1 transaction)
+//batch: via aws s3, gcp or Snowflake internal stage in batch mode (1 file = 1 transaction or 1 Snowpipe submit)
 //stream: via events queue in stream mode (1 object = 1 transaction)
 type Snowflake struct {
 	Abstract
 
 	stageAdapter                  adapters.Stage
 	snowflakeAdapter              *adapters.Snowflake
+	snowpipe                      *adapters.Snowpipe
+	loadRetries                   int
+	pipesMutex                    *sync.Mutex
+	pipes                         map[string]bool
 	streamingWorker               *StreamingWorker
 	usersRecognitionConfiguration *UserRecognitionConfiguration
 }
@@ -59,7 +65,7 @@ func NewSnowflake(config *Config) (Storage, error) {
 	}
 
 	var stageAdapter adapters.Stage
-	if !config.streamMode {
+	if !config.streamMode && !snowflakeConfig.InternalStage.IsEnabled() {
 		var err error
 		if config.destination.S3 != nil {
 			stageAdapter, err = adapters.NewS3(config.destination.S3)
@@ -83,11 +89,34 @@ func NewSnowflake(config *Config) (Storage, error) {
 		return nil, err
 	}
 
+	//internal stage uses Snowflake connection
+	if !config.streamMode && snowflakeConfig.InternalStage.IsEnabled() {
+		stageAdapter, err = adapters.NewSnowflakeInternalStage(snowflakeAdapter)
+		if err != nil {
+			snowflakeAdapter.Close()
+			return nil, err
+		}
+	}
+
+	var snowpipe *adapters.Snowpipe
+	if !config.streamMode && snowflakeConfig.Snowpipe.IsEnabled() {
+		snowpipe, err = adapters.NewSnowpipe(config.destinationID, snowflakeConfig, stageAdapter, config.metaStorage)
+		if err != nil {
+			stageAdapter.Close()
+			snowflakeAdapter.Close()
+			return nil, err
+		}
+	}
+
 	tableHelper := NewTableHelper(snowflakeAdapter, config.monitorKeeper, config.pkFields, adapters.SchemaToSnowflake, config.maxColumns, SnowflakeType)
 
 	snowflake := &Snowflake{
 		stageAdapter:                  stageAdapter,
 		snowflakeAdapter:              snowflakeAdapter,
+		snowpipe:                      snowpipe,
+		loadRetries:                   snowflakeConfig.LoadRetries,
+		pipesMutex:                    &sync.Mutex{},
+		pipes:                         map[string]bool{},
 		usersRecognitionConfiguration: config.usersRecognition,
 	}
 
@@ -198,7 +227,9 @@ func (s *Snowflake) Store(fileName string, objects []map[string]interface{}, alr
 }
 
 //check table schema
-//and store data into one table via stage (google cloud storage or s3)
+//and store data into one table via stage (google cloud storage, s3 or internal stage)
+//uploading and loading are retried (Snowflake doesn't load the same file twice)
+//the staged file is deleted after loading or after the last failed attempt (the whole file will be stored again by the caller)
 func (s *Snowflake) storeTable(fdata *schema.ProcessedFile, table *adapters.Table) error {
 	_, tableHelper := s.getAdapters()
 	dbTable, err := tableHelper.EnsureTableWithoutCaching(s.ID(), table)
@@ -206,20 +237,92 @@ func (s *Snowflake) storeTable(fdata *schema.ProcessedFile, table *adapters.Tabl
 		return err
 	}
 
+	if s.snowpipe != nil {
+		return s.submitToSnowpipe(fdata, dbTable)
+	}
+
 	b, header := fdata.GetPayloadBytesWithHeader(schema.VerticalBarSeparatedMarshallerInstance)
-	if err := s.stageAdapter.UploadBytes(fdata.FileName, b); err != nil {
+	err = s.retry(func() error {
+		if err := s.stageAdapter.UploadBytes(fdata.FileName, b); err != nil {
+			return err
+		}
+
+		if err := s.snowflakeAdapter.Copy(fdata.FileName, dbTable, header); err != nil {
+			return fmt.Errorf("Error copying file [%s] from stage to snowflake: %v", fdata.FileName, err)
+		}
+
+		return nil
+	})
+
+	if deleteErr := s.stageAdapter.DeleteObject(fdata.FileName); deleteErr != nil {
+		logging.SystemErrorf("[%s] file %s wasn't deleted from stage: %v", s.ID(), fdata.FileName, deleteErr)
+	}
+
+	return err
+}
+
+//submitToSnowpipe uploads JSON file into the stage table directory and submits it into the table pipe
+//the file is deleted from the stage after loading (see adapters.Snowpipe) or if it hasn't been submitted after the last attempt
+//(the whole file will be stored again by the caller)
+func (s *Snowflake) submitToSnowpipe(fdata *schema.ProcessedFile, dbTable *adapters.Table) error {
+	pipe, err := s.ensurePipe(dbTable.Name)
+	if err != nil {
 		return err
 	}
 
-	if err := s.snowflakeAdapter.Copy(fdata.FileName, dbTable.Name, header); err != nil {
-		return fmt.Errorf("Error copying file [%s] from stage to snowflake: %v", fdata.FileName, err)
+	filePath := dbTable.Name + "/" + fdata.FileName + ".json"
+	b := fdata.GetPayloadBytes(schema.JSONMarshallerInstance)
+
+	err = s.retry(func() error {
+		if err := s.stageAdapter.UploadBytes(filePath, b); err != nil {
+			return err
+		}
+
+		return s.snowpipe.Submit(pipe, filePath)
+	})
+
+	if err != nil {
+		if deleteErr := s.stageAdapter.DeleteObject(filePath); deleteErr != nil {
+			logging.SystemErrorf("[%s] file %s wasn't deleted from stage: %v", s.ID(), filePath, deleteErr)
+		}
+	}
+
+	return err
+}
+
+//ensurePipe creates pipe for the table if it hasn't been created yet and returns pipe name
+func (s *Snowflake) ensurePipe(tableName string) (string, error) {
+	pipe := "jitsu_" + tableName + "_pipe"
+
+	s.pipesMutex.Lock()
+	defer s.pipesMutex.Unlock()
+
+	if s.pipes[pipe] {
+		return pipe, nil
 	}
 
-	if err := s.stageAdapter.DeleteObject(fdata.FileName); err != nil {
-		logging.SystemErrorf("[%s] file %s wasn't deleted from stage: %v", s.ID(), fdata.FileName, err)
+	if err := s.snowflakeAdapter.CreatePipe(pipe, tableName); err != nil {
+		return "", err
 	}
+	s.pipes[pipe] = true
 
-	return nil
+	return pipe, nil
+}
+
+//retry runs f up to load_retries times with increasing pause between attempts
+func (s *Snowflake) retry(f func() error) (err error) {
+	for attempt := 1; attempt <= s.loadRetries; attempt++ {
+		if err = f(); err == nil {
+			return nil
+		}
+
+		if attempt < s.loadRetries {
+			logging.Warnf("[%s] Snowflake loading attempt %d/%d failed: %v", s.ID(), attempt, s.loadRetries, err)
+			time.Sleep(time.Duration(attempt) * time.Second)
+		}
+	}
+
+	return err
 }
 
 //GetUsersRecognition returns users recognition configuration
@@ -246,8 +349,14 @@ func (s *Snowflake) Type() string {
 	return SnowflakeType
 }
 
-//Close closes Snowflake adapter, stage adapter, fallback logger and streaming worker
+//Close closes Snowflake adapter, stage adapter, Snowpipe, fallback logger and streaming worker
 func (s *Snowflake) Close() (multiErr error) {
+	if s.snowpipe != nil {
+		if err := s.snowpipe.Close(); err != nil {
+			multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing snowpipe: %v", s.ID(), err))
+		}
+	}
+
 	if err := s.snowflakeAdapter.Close(); err != nil {
 		multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing snowflake datasource: %v", s.ID(), err))
 	}
@@ -0,0 +1,184 @@
+package storages
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/events"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/schema"
+)
+
+//SQLite stores files to embedded SQLite database file in two modes:
+//batch: (1 file = 1 transaction)
+//stream: (1 object = 1 statement)
+type SQLite struct {
+	Abstract
+
+	adapter                       *adapters.SQLite
+	streamingWorker               *StreamingWorker
+	usersRecognitionConfiguration *UserRecognitionConfiguration
+}
+
+func init() {
+	RegisterStorage(StorageType{typeName: SQLiteType, createFunc: NewSQLite})
+}
+
+//NewSQLite returns configured SQLite Destination
+func NewSQLite(config *Config) (Storage, error) {
+	sqliteConfig := config.destination.SQLite
+	if err := sqliteConfig.Validate(); err != nil {
+		return nil, err
+	}
+
+	queryLogger := config.loggerFactory.CreateSQLQueryLogger(config.destinationID)
+	adapter, err := adapters.NewSQLite(config.ctx, sqliteConfig, queryLogger, config.sqlTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	tableHelper := NewTableHelper(adapter, config.monitorKeeper, config.pkFields, adapters.SchemaToSQLite, config.maxColumns, SQLiteType)
+
+	s := &SQLite{
+		adapter:                       adapter,
+		usersRecognitionConfiguration: config.usersRecognition,
+	}
+
+	//Abstract
+	s.destinationID = config.destinationID
+	s.processor = config.processor
+	s.fallbackLogger = config.loggerFactory.CreateFailedLogger(config.destinationID)
+	s.eventsCache = config.eventsCache
+	s.tableHelpers = []*TableHelper{tableHelper}
+	s.sqlAdapters = []adapters.SQLAdapter{adapter}
+	s.archiveLogger = config.loggerFactory.CreateStreamingArchiveLogger(config.destinationID)
+	s.uniqueIDField = config.uniqueIDField
+	s.staged = config.destination.Staged
+	s.cachingConfiguration = config.destination.CachingConfiguration
+
+	//streaming worker (queue reading)
+	s.streamingWorker = newStreamingWorker(config.eventQueue, config.processor, s, tableHelper)
+	s.streamingWorker.start()
+
+	return s, nil
+}
+
+func (s *SQLite) DryRun(payload events.Event) ([][]adapters.TableField, error) {
+	_, tableHelper := s.getAdapters()
+	return dryRun(payload, s.processor, tableHelper)
+}
+
+//Store process events and stores with storeTable() func
+//returns store result per table, failed events (group of events which are failed to process) and err
+func (s *SQLite) Store(fileName string, objects []map[string]interface{}, alreadyUploadedTables map[string]bool) (map[string]*StoreResult, *events.FailedEvents, *events.SkippedEvents, error) {
+	_, tableHelper := s.getAdapters()
+	flatData, failedEvents, skippedEvents, err := s.processor.ProcessEvents(fileName, objects, alreadyUploadedTables)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	//update cache with failed events
+	for _, failedEvent := range failedEvents.Events {
+		s.eventsCache.Error(s.IsCachingDisabled(), s.ID(), failedEvent.EventID, failedEvent.Error)
+	}
+	//update cache and counter with skipped events
+	for _, skipEvent := range skippedEvents.Events {
+		s.eventsCache.Skip(s.IsCachingDisabled(), s.ID(), skipEvent.EventID, skipEvent.Error)
+	}
+
+	storeFailedEvents := true
+	tableResults := map[string]*StoreResult{}
+	for _, fdata := range flatData {
+		table := tableHelper.MapTableSchema(fdata.BatchHeader)
+		err := s.storeTable(fdata, table)
+		tableResults[table.Name] = &StoreResult{Err: err, RowsCount: fdata.GetPayloadLen(), EventsSrc: fdata.GetEventsPerSrc()}
+		if err != nil {
+			storeFailedEvents = false
+		}
+
+		//events cache
+		for _, object := range fdata.GetPayload() {
+			if err != nil {
+				s.eventsCache.Error(s.IsCachingDisabled(), s.ID(), s.uniqueIDField.Extract(object), err.Error())
+			} else {
+				s.eventsCache.Succeed(&adapters.EventContext{
+					CacheDisabled:  s.IsCachingDisabled(),
+					DestinationID:  s.ID(),
+					EventID:        s.uniqueIDField.Extract(object),
+					ProcessedEvent: object,
+					Table:          table,
+				})
+			}
+		}
+	}
+
+	//store failed events to fallback only if other events have been inserted ok
+	if storeFailedEvents {
+		return tableResults, failedEvents, skippedEvents, nil
+	}
+
+	return tableResults, nil, skippedEvents, nil
+}
+
+//check table schema
+//and store data into one table
+func (s *SQLite) storeTable(fdata *schema.ProcessedFile, table *adapters.Table) error {
+	_, tableHelper := s.getAdapters()
+	dbSchema, err := tableHelper.EnsureTableWithoutCaching(s.ID(), table)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	if err := s.adapter.BulkInsert(dbSchema, fdata.GetPayload()); err != nil {
+		return err
+	}
+	logging.Debugf("[%s] Inserted [%d] rows in [%.2f] seconds", s.ID(), len(fdata.GetPayload()), time.Now().Sub(start).Seconds())
+
+	return nil
+}
+
+//SyncStore is used in storing chunk of pulled data to SQLite with processing
+func (s *SQLite) SyncStore(overriddenDataSchema *schema.BatchHeader, objects []map[string]interface{}, timeIntervalValue string, cacheTable bool) error {
+	return syncStoreImpl(s, overriddenDataSchema, objects, timeIntervalValue, cacheTable)
+}
+
+func (s *SQLite) Clean(tableName string) error {
+	return cleanImpl(s, tableName)
+}
+
+//Update uses SyncStore under the hood
+func (s *SQLite) Update(object map[string]interface{}) error {
+	return s.SyncStore(nil, []map[string]interface{}{object}, "", true)
+}
+
+//GetUsersRecognition returns users recognition configuration
+func (s *SQLite) GetUsersRecognition() *UserRecognitionConfiguration {
+	return s.usersRecognitionConfiguration
+}
+
+//Type returns SQLite type
+func (s *SQLite) Type() string {
+	return SQLiteType
+}
+
+//Close closes SQLite adapter, fallback logger and streaming worker
+func (s *SQLite) Close() (multiErr error) {
+	if s.streamingWorker != nil {
+		if err := s.streamingWorker.Close(); err != nil {
+			multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing streaming worker: %v", s.ID(), err))
+		}
+	}
+
+	if err := s.adapter.Close(); err != nil {
+		multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing sqlite datasource: %v", s.ID(), err))
+	}
+
+	if err := s.close(); err != nil {
+		multiErr = multierror.Append(multiErr, err)
+	}
+
+	return
+}
@@ -26,6 +26,16 @@ type StreamingStorage interface {
 	SkipEvent(eventCtx *adapters.EventContext, err error)
 }
 
+//BatchStreamingStorage supports InsertBatch operation. StreamingWorker dequeues up to StreamingBatchSize events
+//without waiting and inserts them with one call
+type BatchStreamingStorage interface {
+	StreamingStorage
+	//StreamingBatchSize returns max number of dequeued events per InsertBatch call. Batches aren't used if it is less than 2
+	StreamingBatchSize() int
+	//InsertBatch inserts events and writes metrics/counters/events cache. Returns errors per event (in the same order)
+	InsertBatch(eventContexts []*adapters.EventContext) []error
+}
+
 //StreamingWorker reads events from queue and using events.StreamingStorage writes them
 type StreamingWorker struct {
 	eventQueue       *events.PersistentQueue
@@ -76,54 +86,14 @@ func (sw *StreamingWorker) start() {
 				continue
 			}
 
-			//is used in writing counters/metrics/events cache
-			eventContext := &adapters.EventContext{
-				CacheDisabled: sw.streamingStorage.IsCachingDisabled(),
-				DestinationID: sw.streamingStorage.ID(),
-				EventID:       sw.streamingStorage.GetUniqueIDField().Extract(fact),
-				TokenID:       tokenID,
-				Src:           events.ExtractSrc(fact),
-				RawEvent:      fact,
-			}
-
-			envelops, err := sw.processor.ProcessEvent(fact)
-			if err != nil {
-				if err == schema.ErrSkipObject {
-					if !appconfig.Instance.DisableSkipEventsWarn {
-						logging.Warnf("[%s] Event [%s]: %v", sw.streamingStorage.ID(), sw.streamingStorage.GetUniqueIDField().Extract(fact), err)
-					}
-
-					sw.streamingStorage.SkipEvent(eventContext, err)
-				} else {
-					logging.Errorf("[%s] Unable to process object %s: %v", sw.streamingStorage.ID(), fact.Serialize(), err)
-					sw.streamingStorage.ErrorEvent(true, eventContext, err)
-				}
-
+			if batchStorage, ok := sw.streamingStorage.(BatchStreamingStorage); ok && batchStorage.StreamingBatchSize() > 1 {
+				sw.insertBatch(batchStorage, fact, tokenID)
 				continue
 			}
-			for _, envelop := range envelops {
-				batchHeader := envelop.Header
-				flattenObject := envelop.Event
-				//don't process empty object
-				if !batchHeader.Exists() {
-					continue
-				}
-
-				table := sw.getTableHelper().MapTableSchema(batchHeader)
-				eventContext := &adapters.EventContext{
-					CacheDisabled: sw.streamingStorage.IsCachingDisabled(),
-					DestinationID: sw.streamingStorage.ID(),
-					EventID: utils.NvlString(sw.streamingStorage.GetUniqueIDField().Extract(flattenObject),
-						sw.streamingStorage.GetUniqueIDField().Extract(fact)),
-					TokenID:        tokenID,
-					Src:            events.ExtractSrc(fact),
-					RawEvent:       fact,
-					ProcessedEvent: flattenObject,
-					Table:          table,
-				}
 
+			for _, eventContext := range sw.processEvent(fact, tokenID) {
 				if err := sw.streamingStorage.Insert(eventContext); err != nil {
-					logging.Errorf("[%s] Error inserting object %s to table [%s]: %v", sw.streamingStorage.ID(), flattenObject.Serialize(), table.Name, err)
+					logging.Errorf("[%s] Error inserting object %s to table [%s]: %v", sw.streamingStorage.ID(), eventContext.ProcessedEvent.Serialize(), eventContext.Table.Name, err)
 					if IsConnectionError(err) {
 						//retry
 						sw.eventQueue.ConsumeTimed(fact, time.Now().Add(20*time.Second), tokenID)
@@ -136,6 +106,116 @@ func (sw *StreamingWorker) start() {
 	})
 }
 
+//insertBatch dequeues (without waiting) up to batch size events including the first one and inserts them with one call
+//events with connection errors are put back into the queue for retry
+func (sw *StreamingWorker) insertBatch(batchStorage BatchStreamingStorage, fact events.Event, tokenID string) {
+	facts := []events.Event{fact}
+	tokenIDs := []string{tokenID}
+	for len(facts) < batchStorage.StreamingBatchSize() {
+		fact, dequeuedTime, tokenID, err := sw.eventQueue.Dequeue()
+		if err != nil {
+			if err != events.ErrQueueEmpty && !(err == events.ErrQueueClosed && sw.closed.Load()) {
+				logging.SystemErrorf("[%s] Error reading event from queue: %v", sw.streamingStorage.ID(), err)
+			}
+			break
+		}
+
+		//dequeued event was from retry call and retry timeout hasn't come
+		if time.Now().Before(dequeuedTime) {
+			sw.eventQueue.ConsumeTimed(fact, dequeuedTime, tokenID)
+			break
+		}
+
+		facts = append(facts, fact)
+		tokenIDs = append(tokenIDs, tokenID)
+	}
+
+	var eventContexts []*adapters.EventContext
+	//factIndexes is an index of the fact per event context
+	var factIndexes []int
+	for i, fact := range facts {
+		for _, eventContext := range sw.processEvent(fact, tokenIDs[i]) {
+			eventContexts = append(eventContexts, eventContext)
+			factIndexes = append(factIndexes, i)
+		}
+	}
+
+	if len(eventContexts) == 0 {
+		return
+	}
+
+	retry := make([]bool, len(facts))
+	for i, err := range batchStorage.InsertBatch(eventContexts) {
+		if err != nil {
+			logging.Errorf("[%s] Error inserting object %s to table [%s]: %v", sw.streamingStorage.ID(), eventContexts[i].ProcessedEvent.Serialize(), eventContexts[i].Table.Name, err)
+			if IsConnectionError(err) {
+				retry[factIndexes[i]] = true
+			}
+		}
+	}
+
+	for i, fact := range facts {
+		if retry[i] {
+			sw.eventQueue.ConsumeTimed(fact, time.Now().Add(20*time.Second), tokenIDs[i])
+		}
+	}
+}
+
+//processEvent returns event contexts of the event envelopes
+//skipped events and events which are failed to process are written into metrics/counters/events cache
+func (sw *StreamingWorker) processEvent(fact events.Event, tokenID string) []*adapters.EventContext {
+	envelops, err := sw.processor.ProcessEvent(fact)
+	if err != nil {
+		//is used in writing counters/metrics/events cache
+		eventContext := &adapters.EventContext{
+			CacheDisabled: sw.streamingStorage.IsCachingDisabled(),
+			DestinationID: sw.streamingStorage.ID(),
+			EventID:       sw.streamingStorage.GetUniqueIDField().Extract(fact),
+			TokenID:       tokenID,
+			Src:           events.ExtractSrc(fact),
+			RawEvent:      fact,
+		}
+
+		if err == schema.ErrSkipObject {
+			if !appconfig.Instance.DisableSkipEventsWarn {
+				logging.Warnf("[%s] Event [%s]: %v", sw.streamingStorage.ID(), sw.streamingStorage.GetUniqueIDField().Extract(fact), err)
+			}
+
+			sw.streamingStorage.SkipEvent(eventContext, err)
+		} else {
+			logging.Errorf("[%s] Unable to process object %s: %v", sw.streamingStorage.ID(), fact.Serialize(), err)
+			sw.streamingStorage.ErrorEvent(true, eventContext, err)
+		}
+
+		return nil
+	}
+
+	var eventContexts []*adapters.EventContext
+	for _, envelop := range envelops {
+		batchHeader := envelop.Header
+		flattenObject := envelop.Event
+		//don't process empty object
+		if !batchHeader.Exists() {
+			continue
+		}
+
+		table := sw.getTableHelper().MapTableSchema(batchHeader)
+		eventContexts = append(eventContexts, &adapters.EventContext{
+			CacheDisabled: sw.streamingStorage.IsCachingDisabled(),
+			DestinationID: sw.streamingStorage.ID(),
+			EventID: utils.NvlString(sw.streamingStorage.GetUniqueIDField().Extract(flattenObject),
+				sw.streamingStorage.GetUniqueIDField().Extract(fact)),
+			TokenID:        tokenID,
+			Src:            events.ExtractSrc(fact),
+			RawEvent:       fact,
+			ProcessedEvent: flattenObject,
+			Table:          table,
+		})
+	}
+
+	return eventContexts
+}
+
 func (sw *StreamingWorker) Close() error {
 	sw.closed.Store(true)
 
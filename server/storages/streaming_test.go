@@ -0,0 +1,102 @@
+package storages
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/enrichment"
+	"github.com/jitsucom/jitsu/server/events"
+	"github.com/jitsucom/jitsu/server/identifiers"
+	"github.com/jitsucom/jitsu/server/schema"
+	"github.com/stretchr/testify/require"
+)
+
+//batchStreamingStorageMock keeps inserted batches and returns errors per event ID
+type batchStreamingStorageMock struct {
+	StreamingStorage
+
+	batchSize int
+	errs      map[string]error
+	batches   [][]string
+}
+
+func (bssm *batchStreamingStorageMock) ID() string {
+	return "test"
+}
+
+func (bssm *batchStreamingStorageMock) IsCachingDisabled() bool {
+	return true
+}
+
+func (bssm *batchStreamingStorageMock) GetUniqueIDField() *identifiers.UniqueID {
+	return identifiers.NewUniqueID("/eventn_ctx/event_id")
+}
+
+func (bssm *batchStreamingStorageMock) StreamingBatchSize() int {
+	return bssm.batchSize
+}
+
+func (bssm *batchStreamingStorageMock) InsertBatch(eventContexts []*adapters.EventContext) []error {
+	var ids []string
+	errs := make([]error, len(eventContexts))
+	for i, eventContext := range eventContexts {
+		ids = append(ids, eventContext.EventID)
+		errs[i] = bssm.errs[eventContext.EventID]
+	}
+	bssm.batches = append(bssm.batches, ids)
+
+	return errs
+}
+
+func TestStreamingWorkerInsertBatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "streaming_worker")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	queue, err := events.NewPersistentQueue("test", "queue.dst=test", dir)
+	require.NoError(t, err)
+	defer queue.Close()
+
+	processor, err := schema.NewProcessor("test", BigQueryType, "events", "", schema.DummyMapper{}, []enrichment.Rule{}, schema.NewFlattener(),
+		schema.NewTypeResolver(), false, identifiers.NewUniqueID("/eventn_ctx/event_id"), 0)
+	require.NoError(t, err)
+
+	storage := &batchStreamingStorageMock{
+		batchSize: 3,
+		errs: map[string]error{
+			"2": fmt.Errorf("%w [stream]: EOF", adapters.ErrWriteStreamNotAppended),
+			"3": errors.New("invalid row"),
+		},
+	}
+	tableHelper := NewTableHelper(nil, nil, map[string]bool{}, adapters.SchemaToBigQueryString, 0, BigQueryType)
+	worker := newStreamingWorker(queue, processor, storage, tableHelper)
+
+	for i := 1; i <= 5; i++ {
+		queue.Consume(events.Event{"eventn_ctx": map[string]interface{}{"event_id": fmt.Sprint(i)}}, "token")
+	}
+
+	//the batch is limited by the batch size, not appended events are put back into the queue
+	fact, _, tokenID, err := queue.Dequeue()
+	require.NoError(t, err)
+	worker.insertBatch(storage, fact, tokenID)
+	require.Equal(t, [][]string{{"1", "2", "3"}}, storage.batches)
+
+	//the batch is stopped by the retried event
+	fact, _, tokenID, err = queue.Dequeue()
+	require.NoError(t, err)
+	worker.insertBatch(storage, fact, tokenID)
+	require.Equal(t, [][]string{{"1", "2", "3"}, {"4", "5"}}, storage.batches)
+
+	fact, dequeuedTime, _, err := queue.Dequeue()
+	require.NoError(t, err)
+	require.Equal(t, "2", storage.GetUniqueIDField().Extract(fact))
+	require.True(t, dequeuedTime.After(time.Now()), "event is retried later")
+
+	_, _, _, err = queue.Dequeue()
+	require.Equal(t, events.ErrQueueEmpty, err)
+}
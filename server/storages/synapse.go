@@ -0,0 +1,233 @@
+package storages
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/events"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/schema"
+)
+
+//Synapse stores files to Azure Synapse dedicated SQL pool in two modes:
+//batch: via Azure Blob Storage container (1 file = 1 COPY statement)
+//stream: via events queue in stream mode (1 object = 1 statement)
+type Synapse struct {
+	Abstract
+
+	stageAdapter                  *adapters.AzureBlob
+	synapseAdapter                *adapters.Synapse
+	streamingWorker               *StreamingWorker
+	usersRecognitionConfiguration *UserRecognitionConfiguration
+}
+
+func init() {
+	RegisterStorage(StorageType{typeName: SynapseType, createFunc: NewSynapse})
+}
+
+//NewSynapse returns Synapse and start goroutine for Synapse batch storage or for stream consumer depend on destination mode
+func NewSynapse(config *Config) (Storage, error) {
+	sConfig := config.destination.DataSource
+	if err := sConfig.Validate(); err != nil {
+		return nil, err
+	}
+	//enrich with default parameters
+	enrichMSSQLDataSource(config.destinationID, sConfig)
+
+	var stageAdapter *adapters.AzureBlob
+	if !config.streamMode {
+		var err error
+		stageAdapter, err = adapters.NewAzureBlob(config.destination.AzureBlob)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	queryLogger := config.loggerFactory.CreateSQLQueryLogger(config.destinationID)
+	synapseAdapter, err := adapters.NewSynapse(config.ctx, sConfig, config.destination.AzureBlob, queryLogger, config.sqlTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	//create db schema if doesn't exist
+	if err := synapseAdapter.CreateDbSchema(sConfig.Schema); err != nil {
+		synapseAdapter.Close()
+		return nil, err
+	}
+
+	tableHelper := NewTableHelper(synapseAdapter, config.monitorKeeper, config.pkFields, adapters.SchemaToMSSQL, config.maxColumns, SynapseType)
+
+	s := &Synapse{
+		stageAdapter:                  stageAdapter,
+		synapseAdapter:                synapseAdapter,
+		usersRecognitionConfiguration: config.usersRecognition,
+	}
+
+	//Abstract
+	s.destinationID = config.destinationID
+	s.processor = config.processor
+	s.fallbackLogger = config.loggerFactory.CreateFailedLogger(config.destinationID)
+	s.eventsCache = config.eventsCache
+	s.tableHelpers = []*TableHelper{tableHelper}
+	s.sqlAdapters = []adapters.SQLAdapter{synapseAdapter}
+	s.archiveLogger = config.loggerFactory.CreateStreamingArchiveLogger(config.destinationID)
+	s.uniqueIDField = config.uniqueIDField
+	s.staged = config.destination.Staged
+	s.cachingConfiguration = config.destination.CachingConfiguration
+
+	//streaming worker (queue reading)
+	s.streamingWorker = newStreamingWorker(config.eventQueue, config.processor, s, tableHelper)
+	s.streamingWorker.start()
+
+	return s, nil
+}
+
+func (s *Synapse) DryRun(payload events.Event) ([][]adapters.TableField, error) {
+	_, tableHelper := s.getAdapters()
+	return dryRun(payload, s.processor, tableHelper)
+}
+
+//Store process events and stores with storeTable() func
+//returns store result per table, failed events (group of events which are failed to process) and err
+func (s *Synapse) Store(fileName string, objects []map[string]interface{}, alreadyUploadedTables map[string]bool) (map[string]*StoreResult, *events.FailedEvents, *events.SkippedEvents, error) {
+	_, tableHelper := s.getAdapters()
+	flatData, failedEvents, skippedEvents, err := s.processor.ProcessEvents(fileName, objects, alreadyUploadedTables)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	//update cache with failed events
+	for _, failedEvent := range failedEvents.Events {
+		s.eventsCache.Error(s.IsCachingDisabled(), s.ID(), failedEvent.EventID, failedEvent.Error)
+	}
+	//update cache and counter with skipped events
+	for _, skipEvent := range skippedEvents.Events {
+		s.eventsCache.Skip(s.IsCachingDisabled(), s.ID(), skipEvent.EventID, skipEvent.Error)
+	}
+
+	storeFailedEvents := true
+	tableResults := map[string]*StoreResult{}
+	for _, fdata := range flatData {
+		table := tableHelper.MapTableSchema(fdata.BatchHeader)
+		err := s.storeTable(fdata, table)
+		tableResults[table.Name] = &StoreResult{Err: err, RowsCount: fdata.GetPayloadLen(), EventsSrc: fdata.GetEventsPerSrc()}
+		if err != nil {
+			storeFailedEvents = false
+		}
+
+		//events cache
+		for _, object := range fdata.GetPayload() {
+			if err != nil {
+				s.eventsCache.Error(s.IsCachingDisabled(), s.ID(), s.uniqueIDField.Extract(object), err.Error())
+			} else {
+				s.eventsCache.Succeed(&adapters.EventContext{
+					CacheDisabled:  s.IsCachingDisabled(),
+					DestinationID:  s.ID(),
+					EventID:        s.uniqueIDField.Extract(object),
+					ProcessedEvent: object,
+					Table:          table,
+				})
+			}
+		}
+	}
+
+	//store failed events to fallback only if other events have been inserted ok
+	if storeFailedEvents {
+		return tableResults, failedEvents, skippedEvents, nil
+	}
+
+	return tableResults, nil, skippedEvents, nil
+}
+
+//check table schema
+//and store data into one table via Azure Blob Storage
+//tables with primary keys: deduplicated objects are copied into a staging table and merged into the table
+func (s *Synapse) storeTable(fdata *schema.ProcessedFile, table *adapters.Table) error {
+	_, tableHelper := s.getAdapters()
+	dbTable, err := tableHelper.EnsureTableWithoutCaching(s.ID(), table)
+	if err != nil {
+		return err
+	}
+
+	merge := len(dbTable.PKFields) > 0
+
+	objects := fdata.GetPayload()
+	if merge {
+		objects = lastByPrimaryKeys(dbTable.GetPKFields(), objects)
+	}
+	header := fdata.BatchHeader.Fields.Header()
+	sort.Strings(header)
+	b, err := adapters.MarshalSynapseCSV(header, objects)
+	if err != nil {
+		return err
+	}
+
+	fileName := fdata.FileName + ".csv"
+	if err := s.stageAdapter.UploadBytes(fileName, b); err != nil {
+		return err
+	}
+
+	start := time.Now()
+	if merge {
+		err = s.synapseAdapter.CopyMerge(fileName, dbTable, header)
+	} else {
+		err = s.synapseAdapter.Copy(fileName, dbTable.Name, header)
+	}
+	if err != nil {
+		err = fmt.Errorf("Error copying file [%s] from Azure Blob Storage to Synapse: %v", fileName, err)
+	} else {
+		logging.Debugf("[%s] Copied [%d] rows in [%.2f] seconds", s.ID(), len(objects), time.Since(start).Seconds())
+	}
+
+	if deleteErr := s.stageAdapter.DeleteObject(fileName); deleteErr != nil {
+		logging.SystemErrorf("[%s] file %s wasn't deleted from Azure Blob Storage: %v", s.ID(), fileName, deleteErr)
+	}
+
+	return err
+}
+
+//SyncStore is used in storing chunk of pulled data to Synapse with processing
+func (s *Synapse) SyncStore(overriddenDataSchema *schema.BatchHeader, objects []map[string]interface{}, timeIntervalValue string, cacheTable bool) error {
+	return syncStoreImpl(s, overriddenDataSchema, objects, timeIntervalValue, cacheTable)
+}
+
+func (s *Synapse) Clean(tableName string) error {
+	return cleanImpl(s, tableName)
+}
+
+//Update uses SyncStore under the hood
+func (s *Synapse) Update(object map[string]interface{}) error {
+	return s.SyncStore(nil, []map[string]interface{}{object}, "", true)
+}
+
+//GetUsersRecognition returns users recognition configuration
+func (s *Synapse) GetUsersRecognition() *UserRecognitionConfiguration {
+	return s.usersRecognitionConfiguration
+}
+
+//Type returns Synapse type
+func (s *Synapse) Type() string {
+	return SynapseType
+}
+
+//Close closes Synapse adapter, fallback logger and streaming worker
+func (s *Synapse) Close() (multiErr error) {
+	if err := s.synapseAdapter.Close(); err != nil {
+		multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing synapse datasource: %v", s.ID(), err))
+	}
+
+	if s.streamingWorker != nil {
+		if err := s.streamingWorker.Close(); err != nil {
+			multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing streaming worker: %v", s.ID(), err))
+		}
+	}
+
+	if err := s.close(); err != nil {
+		multiErr = multierror.Append(multiErr, err)
+	}
+
+	return
+}
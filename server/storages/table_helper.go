@@ -20,6 +20,8 @@ type TableHelper struct {
 	sqlAdapter    adapters.SQLAdapter
 	monitorKeeper MonitorKeeper
 	tables        map[string]*adapters.Table
+	//managedTables isn't nil if names of tables which the destination writes to are tracked
+	managedTables *managedTables
 
 	pkFields           map[string]bool
 	columnTypesMapping map[typing.DataType]string
@@ -64,12 +66,21 @@ func (th *TableHelper) MapTableSchema(batchHeader *schema.BatchHeader) *adapters
 			continue
 		}
 
+		//map structure of nested value -> native nested SQL type (if field doesn't have plain values)
+		fieldType := field.GetType()
+		if nestedType := field.GetNestedType(); nestedType != nil && (fieldType == typing.OBJECT || fieldType == typing.ARRAY) {
+			if nestedTypeMapping, ok := nestedTypesMappings[th.destinationType]; ok {
+				table.Columns[fieldName] = nestedTypeMapping(nestedType)
+				continue
+			}
+		}
+
 		//map Jitsu type -> SQL type
-		sqlType, ok := th.columnTypesMapping[field.GetType()]
+		sqlType, ok := th.columnTypesMapping[fieldType]
 		if ok {
 			table.Columns[fieldName] = typing.SQLColumn{Type: sqlType}
 		} else {
-			logging.SystemErrorf("Unknown column type mapping for %s mapping: %v", field.GetType(), th.columnTypesMapping)
+			logging.SystemErrorf("Unknown column type mapping for %s mapping: %v", fieldType, th.columnTypesMapping)
 		}
 	}
 
@@ -105,6 +116,8 @@ func (th *TableHelper) EnsureTable(destinationID string, dataSchema *adapters.Ta
 		return nil, err
 	}
 
+	th.managedTables.add(dbSchema.Name)
+
 	//if diff doesn't exist - do nothing
 	diff := dbSchema.Diff(dataSchema)
 	if !diff.Exists() {
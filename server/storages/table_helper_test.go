@@ -60,6 +60,47 @@ func TestMapTableSchema(t *testing.T) {
 	}
 }
 
+func TestMapTableSchemaNested(t *testing.T) {
+	nestedType := &typing.NestedType{Type: typing.ARRAY, Elem: &typing.NestedType{Type: typing.INT64}}
+	mixedField := schema.NewNestedField(typing.OBJECT, &typing.NestedType{Type: typing.OBJECT, Fields: map[string]*typing.NestedType{}})
+	plainField := schema.NewField(typing.INT64)
+	mixedField.Merge(&plainField)
+	batchHeader := &schema.BatchHeader{TableName: "test_table", Fields: schema.Fields{
+		"field1": schema.NewNestedField(typing.ARRAY, nestedType),
+		"field2": schema.NewField(typing.STRING),
+		"field3": mixedField,
+	}}
+	columnTypesMapping := map[typing.DataType]string{typing.STRING: "text", typing.ARRAY: "json"}
+
+	tests := []struct {
+		name            string
+		destinationType string
+		expected        adapters.Columns
+	}{
+		{
+			"BigQuery REPEATED column",
+			BigQueryType,
+			adapters.Columns{"field1": adapters.BigQueryNestedColumn(nestedType), "field2": typing.SQLColumn{Type: "text"}, "field3": typing.SQLColumn{Type: "text"}},
+		},
+		{
+			"ClickHouse Array column",
+			ClickHouseType,
+			adapters.Columns{"field1": typing.SQLColumn{Type: "Array(Int64)"}, "field2": typing.SQLColumn{Type: "text"}, "field3": typing.SQLColumn{Type: "text"}},
+		},
+		{
+			"Postgres JSON column",
+			PostgresType,
+			adapters.Columns{"field1": typing.SQLColumn{Type: "json"}, "field2": typing.SQLColumn{Type: "text"}, "field3": typing.SQLColumn{Type: "text"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tableHelper := NewTableHelper(nil, nil, map[string]bool{}, columnTypesMapping, 0, tt.destinationType)
+			require.Equal(t, tt.expected, tableHelper.MapTableSchema(batchHeader).Columns)
+		})
+	}
+}
+
 func TestProcessTransformWithTypesOverride(t *testing.T) {
 	viper.Set("server.log.path", "")
 
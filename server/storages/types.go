@@ -11,19 +11,26 @@ import (
 )
 
 const (
-	RedshiftType        = "redshift"
-	BigQueryType        = "bigquery"
-	PostgresType        = "postgres"
-	MySQLType           = "mysql"
-	ClickHouseType      = "clickhouse"
-	S3Type              = "s3"
-	SnowflakeType       = "snowflake"
-	GoogleAnalyticsType = "google_analytics"
-	FacebookType        = "facebook"
-	WebHookType         = "webhook"
-	AmplitudeType       = "amplitude"
-	HubSpotType         = "hubspot"
-	DbtCloudType        = "dbtcloud"
+	RedshiftType         = "redshift"
+	BigQueryType         = "bigquery"
+	PostgresType         = "postgres"
+	MySQLType            = "mysql"
+	ClickHouseType       = "clickhouse"
+	S3Type               = "s3"
+	SnowflakeType        = "snowflake"
+	GoogleAnalyticsType  = "google_analytics"
+	FacebookType         = "facebook"
+	WebHookType          = "webhook"
+	AmplitudeType        = "amplitude"
+	HubSpotType          = "hubspot"
+	DbtCloudType         = "dbtcloud"
+	SQLiteType           = "sqlite"
+	MSSQLType            = "mssql"
+	SynapseType          = "synapse"
+	GoogleAnalytics4Type = "google_analytics4"
+	MixpanelType         = "mixpanel"
+	PostHogType          = "posthog"
+	SegmentType          = "segment"
 )
 
 //Storage is a destination representation
@@ -43,6 +50,15 @@ type Storage interface {
 	IsStaging() bool
 	IsCachingDisabled() bool
 	Clean(tableName string) error
+	DeleteUserData(tableNames []string, deleteConditions *adapters.DeleteConditions) error
+	ApplyRetention(rules []*RetentionRule, dryRun bool) ([]*RetentionResult, error)
+	Query(query string, consume func(row map[string]interface{}) error) error
+}
+
+//IdentityLinker is implemented by destinations which merge anonymous and identified users on their side (e.g. Mixpanel, PostHog)
+//users recognition calls LinkIdentity instead of rewriting stored anonymous events
+type IdentityLinker interface {
+	LinkIdentity(anonymousID string, identifiedEvent events.Event) error
 }
 
 //StorageProxy is a storage proxy
@@ -53,10 +69,21 @@ type StorageProxy interface {
 	GetPostHandleDestinations() []string
 	GetGeoResolverID() string
 	IsCachingDisabled() bool
+	ApplyRetention(dryRun bool) ([]*RetentionResult, error)
+	Status() *DestinationStatus
 	ID() string
 	Type() string
 }
 
+//DestinationStatus is a dto for destination runtime status
+//HTTP is filled only for initialized HTTP destinations
+type DestinationStatus struct {
+	ID          string                      `json:"id"`
+	Type        string                      `json:"type"`
+	Initialized bool                        `json:"initialized"`
+	HTTP        *adapters.HTTPAdapterStatus `json:"http,omitempty"`
+}
+
 //StoreResult is used as a Batch storing result
 type StoreResult struct {
 	Err       error
@@ -34,7 +34,8 @@ func dryRun(payload events.Event, processor *schema.Processor, tableHelper *Tabl
 }
 
 func IsConnectionError(err error) bool {
-	return strings.Contains(err.Error(), "connection refused") ||
+	return errors.Is(err, adapters.ErrWriteStreamNotAppended) ||
+		strings.Contains(err.Error(), "connection refused") ||
 		strings.Contains(err.Error(), "EOF") ||
 		strings.Contains(err.Error(), "write: broken pipe") ||
 		strings.Contains(err.Error(), "context deadline exceeded") ||
@@ -60,6 +60,9 @@ func NewWebHook(config *Config) (Storage, error) {
 		DebugLogger:    requestDebugLogger,
 		ErrorHandler:   wh.ErrorEvent,
 		SuccessHandler: wh.SuccessEvent,
+		Batch:          config.destination.HTTPBatch,
+		RateLimit:      config.destination.HTTPRateLimit,
+		CircuitBreaker: config.destination.HTTPCircuitBreaker,
 	})
 	if err != nil {
 		return nil, err
@@ -75,6 +78,7 @@ func NewWebHook(config *Config) (Storage, error) {
 	wh.processor = config.processor
 	wh.fallbackLogger = config.loggerFactory.CreateFailedLogger(config.destinationID)
 	wh.eventsCache = config.eventsCache
+	wh.metaStorage = config.metaStorage
 	wh.archiveLogger = config.loggerFactory.CreateStreamingArchiveLogger(config.destinationID)
 	wh.uniqueIDField = config.uniqueIDField
 	wh.staged = config.destination.Staged
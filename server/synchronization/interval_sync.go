@@ -0,0 +1,238 @@
+package synchronization
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/counters"
+	driversbase "github.com/jitsucom/jitsu/server/drivers/base"
+	"github.com/jitsucom/jitsu/server/events"
+	"github.com/jitsucom/jitsu/server/meta"
+	"github.com/jitsucom/jitsu/server/metrics"
+	"github.com/jitsucom/jitsu/server/schema"
+	"github.com/jitsucom/jitsu/server/storages"
+	"github.com/jitsucom/jitsu/server/telemetry"
+	"github.com/jitsucom/jitsu/server/timestamp"
+	"github.com/jitsucom/jitsu/server/uuid"
+)
+
+//httpStorage is implemented by HTTP destinations
+type httpStorage interface {
+	HTTPStatus() *adapters.HTTPAdapterStatus
+}
+
+//intervalSync stores source objects of one time interval into destinations page by page
+//the first page replaces previously synced objects of the interval in SQL destinations, next pages are appended.
+//HTTP destinations receive only new and changed objects and only from diff drivers
+type intervalSync struct {
+	te                  *TaskExecutor
+	task                *meta.Task
+	taskLogger          *TaskLogger
+	driver              driversbase.Driver
+	destinationStorages []storages.Storage
+	interval            *driversbase.TimeInterval
+	tableName           string
+
+	//diffs per HTTP destination ID (only for diff drivers)
+	diffs map[string]*objectsDiff
+	pages int
+	//storeErr is the last store error (it is returned instead of wrapped driver errors)
+	storeErr error
+}
+
+func (te *TaskExecutor) newIntervalSync(task *meta.Task, taskLogger *TaskLogger, driver driversbase.Driver, destinationStorages []storages.Storage,
+	interval *driversbase.TimeInterval, tableName string) (*intervalSync, error) {
+	diffs := map[string]*objectsDiff{}
+	for _, storage := range destinationStorages {
+		if !sendsEvents(driver, storage) {
+			continue
+		}
+
+		previousSignatures, err := te.metaStorage.GetObjectSignatures(task.Source, driver.GetCollectionMetaKey(), storage.ID())
+		if err != nil {
+			return nil, fmt.Errorf("Error getting [%s] destination objects signatures: %v", storage.ID(), err)
+		}
+		diffs[storage.ID()] = newObjectsDiff(previousSignatures)
+	}
+
+	return &intervalSync{
+		te:                  te,
+		task:                task,
+		taskLogger:          taskLogger,
+		driver:              driver,
+		destinationStorages: destinationStorages,
+		interval:            interval,
+		tableName:           tableName,
+		diffs:               diffs,
+	}, nil
+}
+
+//store enriches the page of objects and stores it into all destinations
+func (is *intervalSync) store(objects []map[string]interface{}) error {
+	is.storeErr = is.storePage(objects)
+	return is.storeErr
+}
+
+func (is *intervalSync) storePage(objects []map[string]interface{}) error {
+	//objects are signed before enrichment because enriched values are different in every sync
+	var signatures []objectSignature
+	if diffDriver, ok := is.driver.(driversbase.DiffDriver); ok && len(is.diffs) > 0 {
+		signatures = signObjects(diffDriver, objects)
+	}
+
+	//Note: we assume that destinations connected to 1 source can't have different unique ID configuration
+	uniqueIDField := is.destinationStorages[0].GetUniqueIDField()
+	for _, object := range objects {
+		//enrich with values
+		object[events.SrcKey] = srcSource
+		object[timestamp.Key] = timestamp.NowUTC()
+		if err := uniqueIDField.Set(object, uuid.GetHash(object)); err != nil {
+			b, _ := json.Marshal(object)
+			return fmt.Errorf("Error setting unique ID field into %s: %v", string(b), err)
+		}
+		events.EnrichWithCollection(object, is.task.Collection)
+		events.EnrichWithTimeInterval(object, is.interval.String(), is.interval.LowerEndpoint(), is.interval.UpperEndpoint())
+	}
+
+	timeIntervalValue := ""
+	if is.pages == 0 {
+		timeIntervalValue = is.interval.String()
+	}
+	is.pages++
+
+	for _, storage := range is.destinationStorages {
+		rowsCount := len(objects)
+		var err error
+		if diff, ok := is.diffs[storage.ID()]; ok {
+			var objectsToSend []map[string]interface{}
+			objectsToSend, err = is.prepareChangedObjects(storage, diff, objects, signatures)
+			if err != nil {
+				return fmt.Errorf("Error preparing source objects for [%s] destination: %v", storage.ID(), err)
+			}
+
+			rowsCount = len(objectsToSend)
+			err = is.te.sendEvents(is.task.Source, storage.ID(), objectsToSend)
+		} else {
+			err = storage.SyncStore(&schema.BatchHeader{TableName: is.tableName}, objects, timeIntervalValue, false)
+		}
+		if err != nil {
+			metrics.ErrorSourceEvents(is.task.Source, storage.ID(), rowsCount)
+			metrics.ErrorObjects(is.task.Source, rowsCount)
+			telemetry.Error(is.task.Source, storage.ID(), srcSource, is.driver.GetDriversInfo().SourceType, rowsCount)
+			counters.ErrorPullDestinationEvents(storage.ID(), rowsCount)
+			counters.ErrorPullSourceEvents(is.task.Source, rowsCount)
+			return fmt.Errorf("Error storing %d source objects in [%s] destination: %v", rowsCount, storage.ID(), err)
+		}
+
+		metrics.SuccessSourceEvents(is.task.Source, storage.ID(), rowsCount)
+		metrics.SuccessObjects(is.task.Source, rowsCount)
+		telemetry.Event(is.task.Source, storage.ID(), srcSource, is.driver.GetDriversInfo().SourceType, rowsCount)
+		counters.SuccessPullDestinationEvents(storage.ID(), rowsCount)
+	}
+
+	counters.SuccessPullSourceEvents(is.task.Source, len(objects))
+	return nil
+}
+
+//finish deletes signatures of objects which have been removed from the source since the previous delivery
+//must be called after all pages have been stored
+func (is *intervalSync) finish() error {
+	for destinationID, diff := range is.diffs {
+		if err := is.te.metaStorage.DeleteObjectSignatures(is.task.Source, is.driver.GetCollectionMetaKey(), destinationID, diff.removedKeys()); err != nil {
+			return fmt.Errorf("Error deleting [%s] destination signatures of removed objects: %v", destinationID, err)
+		}
+
+		is.taskLogger.INFO("[%s] New and changed objects: [%d] of [%d]", destinationID, diff.changed, len(diff.currentKeys))
+	}
+
+	return nil
+}
+
+//prepareChangedObjects returns objects which are new or have been changed since the previous delivery into the destination
+//and saves them as pending objects: their signatures are saved only after delivery (see storages.HTTPStorage)
+//so undelivered objects are sent again in the next sync
+func (is *intervalSync) prepareChangedObjects(storage storages.Storage, diff *objectsDiff, objects []map[string]interface{},
+	signatures []objectSignature) ([]map[string]interface{}, error) {
+	changedIndexes := diff.changedIndexes(signatures)
+
+	uniqueIDField := storage.GetUniqueIDField()
+	changedObjects := make([]map[string]interface{}, 0, len(changedIndexes))
+	pendingObjects := make(map[string]*meta.PendingObject, len(changedIndexes))
+	for _, i := range changedIndexes {
+		changedObjects = append(changedObjects, objects[i])
+		pendingObjects[uniqueIDField.Extract(objects[i])] = &meta.PendingObject{
+			SourceID:   is.task.Source,
+			Collection: is.driver.GetCollectionMetaKey(),
+			Key:        signatures[i].key,
+			Signature:  signatures[i].signature,
+		}
+	}
+
+	if err := is.te.metaStorage.SavePendingObjects(storage.ID(), pendingObjects); err != nil {
+		return nil, fmt.Errorf("Error saving pending objects: %v", err)
+	}
+
+	return changedObjects, nil
+}
+
+//sendsEvents returns true if source objects are sent to the destination as events
+//only diff drivers objects are sent to HTTP destinations: other sources would resend all objects on every sync
+func sendsEvents(driver driversbase.Driver, storage storages.Storage) bool {
+	_, isHTTPStorage := storage.(httpStorage)
+	_, isDiffDriver := driver.(driversbase.DiffDriver)
+	return isHTTPStorage && isDiffDriver
+}
+
+//objectSignature is a diff driver object key and a signature of the object content
+type objectSignature struct {
+	key       string
+	signature string
+}
+
+//signObjects returns keys and signatures of objects in the same order
+func signObjects(driver driversbase.DiffDriver, objects []map[string]interface{}) []objectSignature {
+	signatures := make([]objectSignature, 0, len(objects))
+	for _, object := range objects {
+		signatures = append(signatures, objectSignature{key: driver.GetObjectKey(object), signature: uuid.GetHash(object)})
+	}
+
+	return signatures
+}
+
+//objectsDiff is a comparison of synced objects with objects which have been delivered into a destination before
+//objects are compared page by page: only keys of synced objects are kept in memory
+type objectsDiff struct {
+	previousSignatures map[string]string
+	currentKeys        map[string]bool
+	changed            int
+}
+
+func newObjectsDiff(previousSignatures map[string]string) *objectsDiff {
+	return &objectsDiff{previousSignatures: previousSignatures, currentKeys: map[string]bool{}}
+}
+
+//changedIndexes returns indexes of objects which are new or have been changed since the previous delivery
+func (od *objectsDiff) changedIndexes(signatures []objectSignature) []int {
+	var changedIndexes []int
+	for i, objectSignature := range signatures {
+		od.currentKeys[objectSignature.key] = true
+		if od.previousSignatures[objectSignature.key] != objectSignature.signature {
+			changedIndexes = append(changedIndexes, i)
+		}
+	}
+
+	od.changed += len(changedIndexes)
+	return changedIndexes
+}
+
+//removedKeys returns keys of previously delivered objects which haven't been synced (aren't in the source anymore)
+func (od *objectsDiff) removedKeys() []string {
+	var removedKeys []string
+	for key := range od.previousSignatures {
+		if !od.currentKeys[key] {
+			removedKeys = append(removedKeys, key)
+		}
+	}
+
+	return removedKeys
+}
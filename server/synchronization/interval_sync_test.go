@@ -0,0 +1,119 @@
+package synchronization
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/adapters"
+	driversbase "github.com/jitsucom/jitsu/server/drivers/base"
+	"github.com/jitsucom/jitsu/server/storages"
+	"github.com/jitsucom/jitsu/server/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+type diffDriverMock struct {
+	driversbase.Driver
+}
+
+func (ddm *diffDriverMock) GetObjectKey(object map[string]interface{}) string {
+	return fmt.Sprint(object["id"])
+}
+
+type driverMock struct {
+	driversbase.Driver
+}
+
+type storageMock struct {
+	storages.Storage
+}
+
+type httpStorageMock struct {
+	storages.Storage
+}
+
+func (hsm *httpStorageMock) HTTPStatus() *adapters.HTTPAdapterStatus {
+	return &adapters.HTTPAdapterStatus{}
+}
+
+func TestSendsEvents(t *testing.T) {
+	tests := []struct {
+		name     string
+		driver   driversbase.Driver
+		storage  storages.Storage
+		expected bool
+	}{
+		{"Diff driver to HTTP destination", &diffDriverMock{}, &httpStorageMock{}, true},
+		{"Diff driver to SQL destination", &diffDriverMock{}, &storageMock{}, false},
+		{"Driver to HTTP destination", &driverMock{}, &httpStorageMock{}, false},
+		{"Driver to SQL destination", &driverMock{}, &storageMock{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, sendsEvents(tt.driver, tt.storage))
+		})
+	}
+}
+
+func TestObjectsDiff(t *testing.T) {
+	unchanged := map[string]interface{}{"id": 1, "plan": "free"}
+	changed := map[string]interface{}{"id": 2, "plan": "pro"}
+	created := map[string]interface{}{"id": 3, "plan": "free"}
+	signatures := signObjects(&diffDriverMock{}, []map[string]interface{}{unchanged, changed, created})
+	require.Equal(t, []objectSignature{
+		{key: "1", signature: uuid.GetHash(unchanged)},
+		{key: "2", signature: uuid.GetHash(changed)},
+		{key: "3", signature: uuid.GetHash(created)},
+	}, signatures)
+
+	tests := []struct {
+		name               string
+		previousSignatures map[string]string
+		expectedIndexes    [][]int
+		expectedRemoved    []string
+	}{
+		{
+			"New destination",
+			map[string]string{},
+			[][]int{{0, 1}, {0}},
+			nil,
+		},
+		{
+			"Changed, created and removed objects",
+			map[string]string{
+				"1": uuid.GetHash(map[string]interface{}{"id": 1, "plan": "free"}),
+				"2": uuid.GetHash(map[string]interface{}{"id": 2, "plan": "free"}),
+				"4": uuid.GetHash(map[string]interface{}{"id": 4, "plan": "deleted"}),
+			},
+			[][]int{{1}, {0}},
+			[]string{"4"},
+		},
+		{
+			"Undelivered object",
+			map[string]string{
+				"1": uuid.GetHash(unchanged),
+				"3": uuid.GetHash(created),
+			},
+			[][]int{{1}, nil},
+			nil,
+		},
+		{
+			"All delivered",
+			map[string]string{
+				"1": uuid.GetHash(unchanged),
+				"2": uuid.GetHash(changed),
+				"3": uuid.GetHash(created),
+			},
+			[][]int{nil, nil},
+			nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			//objects are compared in two pages
+			diff := newObjectsDiff(tt.previousSignatures)
+			require.Equal(t, tt.expectedIndexes[0], diff.changedIndexes(signatures[:2]))
+			require.Equal(t, tt.expectedIndexes[1], diff.changedIndexes(signatures[2:]))
+			require.Equal(t, tt.expectedRemoved, diff.removedKeys())
+		})
+	}
+}
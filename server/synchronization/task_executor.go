@@ -3,7 +3,6 @@ package synchronization
 import (
 	"encoding/json"
 	"fmt"
-	"github.com/jitsucom/jitsu/server/counters"
 	"github.com/jitsucom/jitsu/server/destinations"
 	driversbase "github.com/jitsucom/jitsu/server/drivers/base"
 	"github.com/jitsucom/jitsu/server/events"
@@ -14,16 +13,14 @@ import (
 	"github.com/jitsucom/jitsu/server/schema"
 	"github.com/jitsucom/jitsu/server/sources"
 	"github.com/jitsucom/jitsu/server/storages"
-	"github.com/jitsucom/jitsu/server/telemetry"
 	"github.com/jitsucom/jitsu/server/timestamp"
-	"github.com/jitsucom/jitsu/server/uuid"
 	"github.com/panjf2000/ants/v2"
 	"go.uber.org/atomic"
 	"strings"
 	"time"
 )
 
-const srcSource = "source"
+const srcSource = events.SourceSrc
 
 type TaskExecutor struct {
 	workersPool        *ants.PoolWithFunc
@@ -397,43 +394,31 @@ func (te *TaskExecutor) sync(task *meta.Task, taskLogger *TaskLogger, driver dri
 
 		taskLogger.INFO("Running [%s] synchronization", intervalToSync.String())
 
-		objects, err := driver.GetObjectsFor(intervalToSync)
+		intervalSync, err := te.newIntervalSync(task, taskLogger, driver, destinationStorages, intervalToSync, reformattedTableName)
 		if err != nil {
-			return fmt.Errorf("Error [%s] synchronization: %v", intervalToSync.String(), err)
+			return err
 		}
 
-		//Note: we assume that destinations connected to 1 source can't have different unique ID configuration
-		uniqueIDField := destinationStorages[0].GetUniqueIDField()
-		for _, object := range objects {
-			//enrich with values
-			object[events.SrcKey] = srcSource
-			object[timestamp.Key] = timestamp.NowUTC()
-			if err := uniqueIDField.Set(object, uuid.GetHash(object)); err != nil {
-				b, _ := json.Marshal(object)
-				return fmt.Errorf("Error setting unique ID field into %s: %v", string(b), err)
+		//streaming drivers pass objects page by page without loading all of them into memory
+		if streamingDriver, ok := driver.(driversbase.StreamingDriver); ok {
+			err = streamingDriver.StreamObjectsFor(intervalToSync, intervalSync.store)
+		} else {
+			var objects []map[string]interface{}
+			objects, err = driver.GetObjectsFor(intervalToSync)
+			if err == nil {
+				err = intervalSync.store(objects)
 			}
-			events.EnrichWithCollection(object, task.Collection)
-			events.EnrichWithTimeInterval(object, intervalToSync.String(), intervalToSync.LowerEndpoint(), intervalToSync.UpperEndpoint())
 		}
-		rowsCount := len(objects)
-		for _, storage := range destinationStorages {
-			err := storage.SyncStore(&schema.BatchHeader{TableName: reformattedTableName}, objects, intervalToSync.String(), false)
-			if err != nil {
-				metrics.ErrorSourceEvents(task.Source, storage.ID(), rowsCount)
-				metrics.ErrorObjects(task.Source, rowsCount)
-				telemetry.Error(task.Source, storage.ID(), srcSource, driver.GetDriversInfo().SourceType, rowsCount)
-				counters.ErrorPullDestinationEvents(storage.ID(), rowsCount)
-				counters.ErrorPullSourceEvents(task.Source, rowsCount)
-				return fmt.Errorf("Error storing %d source objects in [%s] destination: %v", rowsCount, storage.ID(), err)
-			}
-
-			metrics.SuccessSourceEvents(task.Source, storage.ID(), rowsCount)
-			metrics.SuccessObjects(task.Source, rowsCount)
-			telemetry.Event(task.Source, storage.ID(), srcSource, driver.GetDriversInfo().SourceType, rowsCount)
-			counters.SuccessPullDestinationEvents(storage.ID(), rowsCount)
+		if intervalSync.storeErr != nil {
+			return intervalSync.storeErr
+		}
+		if err != nil {
+			return fmt.Errorf("Error [%s] synchronization: %v", intervalToSync.String(), err)
 		}
 
-		counters.SuccessPullSourceEvents(task.Source, rowsCount)
+		if err := intervalSync.finish(); err != nil {
+			return err
+		}
 
 		if err := te.metaStorage.SaveSignature(task.Source, collectionMetaKey, intervalToSync.String(), intervalToSync.CalculateSignatureFrom(now, refreshWindow)); err != nil {
 			logging.SystemErrorf("Unable to save source: [%s] collection: [%s] meta key: [%s] signature: %v", task.Source, task.Collection, collectionMetaKey, err)
@@ -445,6 +430,21 @@ func (te *TaskExecutor) sync(task *meta.Task, taskLogger *TaskLogger, driver dri
 	return nil
 }
 
+//sendEvents puts objects into the HTTP destination events queue
+//HTTP destinations don't support SyncStore: objects are processed and sent as events
+func (te *TaskExecutor) sendEvents(sourceID, destinationID string, objects []map[string]interface{}) error {
+	eventsConsumer, ok := te.destinationService.GetEventsConsumerByDestinationID(destinationID)
+	if !ok {
+		return fmt.Errorf("Events queue of [%s] destination wasn't found", destinationID)
+	}
+
+	for _, object := range objects {
+		eventsConsumer.Consume(object, sourceID)
+	}
+
+	return nil
+}
+
 //syncCLI syncs singer/airbyte source
 func (te *TaskExecutor) syncCLI(task *meta.Task, taskLogger *TaskLogger, cliDriver driversbase.CLIDriver,
 	destinationStorages []storages.Storage, taskCloser *TaskCloser) error {
@@ -11,11 +11,14 @@ import (
 	"github.com/jitsucom/jitsu/server/appconfig"
 	"github.com/jitsucom/jitsu/server/caching"
 	"github.com/jitsucom/jitsu/server/coordination"
+	"github.com/jitsucom/jitsu/server/credentials"
 	"github.com/jitsucom/jitsu/server/destinations"
 	"github.com/jitsucom/jitsu/server/enrichment"
 	"github.com/jitsucom/jitsu/server/events"
 	"github.com/jitsucom/jitsu/server/fallback"
+	"github.com/jitsucom/jitsu/server/gdpr"
 	"github.com/jitsucom/jitsu/server/geo"
+	"github.com/jitsucom/jitsu/server/gitops"
 	"github.com/jitsucom/jitsu/server/logging"
 	"github.com/jitsucom/jitsu/server/meta"
 	"github.com/jitsucom/jitsu/server/middleware"
@@ -23,6 +26,7 @@ import (
 	"github.com/jitsucom/jitsu/server/routers"
 	"github.com/jitsucom/jitsu/server/schema"
 	"github.com/jitsucom/jitsu/server/sources"
+	"github.com/jitsucom/jitsu/server/ssl"
 	"github.com/jitsucom/jitsu/server/storages"
 	"github.com/jitsucom/jitsu/server/synchronization"
 	"github.com/jitsucom/jitsu/server/system"
@@ -180,7 +184,7 @@ func (sb *suiteBuilder) WithDestinationService(t *testing.T, destinationConfig s
 	monitor := coordination.NewInMemoryService([]string{})
 	tempDir := os.TempDir()
 	loggerFactory := logging.NewFactory(tempDir, 5, false, nil, nil)
-	destinationsFactory := storages.NewFactory(context.Background(), tempDir, sb.geoService, monitor, sb.eventsCache, loggerFactory, sb.globalUsersRecognitionConfig, sb.metaStorage, 0)
+	destinationsFactory := storages.NewFactory(context.Background(), tempDir, sb.geoService, monitor, sb.eventsCache, loggerFactory, sb.globalUsersRecognitionConfig, sb.metaStorage, 0, nil)
 	destinationService, err := destinations.NewService(nil, destinationConfig, destinationsFactory, loggerFactory, false)
 	require.NoError(t, err)
 	appconfig.Instance.ScheduleClosing(destinationService)
@@ -216,9 +220,12 @@ func (sb *suiteBuilder) Build(t *testing.T) Suit {
 	walService := wal.NewService("/tmp", &logging.AsyncLogger{}, multiplexingService, processorHolder)
 	appconfig.Instance.ScheduleWriteAheadLogClosing(walService)
 
+	coordinationService := coordination.NewInMemoryService([]string{})
 	router := routers.SetupRouter("", sb.metaStorage, sb.destinationService, sources.NewTestService(), synchronization.NewTestTaskService(),
-		fallback.NewTestService(), coordination.NewInMemoryService([]string{}), sb.eventsCache, sb.systemService,
-		sb.segmentRequestFieldsMapper, sb.segmentCompatRequestFieldsMapper, processorHolder, multiplexingService, walService, sb.geoService)
+		fallback.NewTestService(), coordinationService, sb.eventsCache, sb.systemService,
+		sb.segmentRequestFieldsMapper, sb.segmentCompatRequestFieldsMapper, processorHolder, multiplexingService, walService, sb.geoService,
+		gdpr.NewService("/tmp", sb.metaStorage, sb.destinationService, "", nil), &ssl.CertificateService{}, &gitops.Provider{},
+		credentials.NewService(context.Background(), sb.metaStorage, coordination.CredentialsLock(coordinationService)))
 
 	server := &http.Server{
 		Addr:              sb.httpAuthority,
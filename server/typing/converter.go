@@ -1,6 +1,7 @@
 package typing
 
 import (
+	"encoding/json"
 	"fmt"
 	"strconv"
 	"strings"
@@ -18,6 +19,7 @@ import (
 //    |
 //  BOOL(1)
 //
+//OBJECT(6) and ARRAY(7) aren't in the tree: they can be cast only to STRING (JSON)
 var (
 	typecastTree = &typeNode{
 		t: STRING,
@@ -45,6 +47,8 @@ var (
 		rule{from: INT64, to: STRING}:     numberToString,
 		rule{from: FLOAT64, to: STRING}:   numberToString,
 		rule{from: TIMESTAMP, to: STRING}: timestampToString,
+		rule{from: OBJECT, to: STRING}:    nestedToString,
+		rule{from: ARRAY, to: STRING}:     nestedToString,
 
 		rule{from: BOOL, to: INT64}: boolToNumber,
 
@@ -111,6 +115,15 @@ func Convert(toType DataType, v interface{}) (interface{}, error) {
 
 //GetCommonAncestorType returns lowest common ancestor type
 func GetCommonAncestorType(t1, t2 DataType) DataType {
+	if t1 == t2 {
+		return t1
+	}
+
+	//nested types can be cast only to STRING
+	if t1 == OBJECT || t1 == ARRAY || t2 == OBJECT || t2 == ARRAY {
+		return STRING
+	}
+
 	return lowestCommonAncestor(typecastTree, t1, t2)
 }
 
@@ -135,6 +148,16 @@ func lowestCommonAncestor(root *typeNode, t1, t2 DataType) DataType {
 	return UNKNOWN
 }
 
+//assume that input v can't be nil
+func nestedToString(v interface{}) (interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("Error marshaling %v into JSON string: %v", v, err)
+	}
+
+	return string(b), nil
+}
+
 //assume that input v can't be nil
 func numberToString(v interface{}) (interface{}, error) {
 	switch v.(type) {
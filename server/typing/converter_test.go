@@ -64,6 +64,20 @@ func TestConvert(t *testing.T) {
 			float64(123),
 			"",
 		},
+		{
+			"object -> string",
+			map[string]interface{}{"key": []interface{}{1, "2"}},
+			STRING,
+			`{"key":[1,"2"]}`,
+			"",
+		},
+		{
+			"array -> string",
+			[]interface{}{map[string]interface{}{"key": true}},
+			STRING,
+			`[{"key":true}]`,
+			"",
+		},
 		/* Future
 		{
 				"string -> int ok",
@@ -262,6 +276,36 @@ func TestGetCommonAncestorType(t *testing.T) {
 			TIMESTAMP,
 			STRING,
 		},
+		{
+			"object+object=object",
+			OBJECT,
+			OBJECT,
+			OBJECT,
+		},
+		{
+			"array+array=array",
+			ARRAY,
+			ARRAY,
+			ARRAY,
+		},
+		{
+			"object+array=string",
+			OBJECT,
+			ARRAY,
+			STRING,
+		},
+		{
+			"int64+object=string",
+			INT64,
+			OBJECT,
+			STRING,
+		},
+		{
+			"timestamp+array=string",
+			TIMESTAMP,
+			ARRAY,
+			STRING,
+		},
 	}
 
 	for _, tt := range tests {
@@ -28,6 +28,10 @@ const (
 	STRING
 	//TIMESTAMP type for string values that match timestamp pattern
 	TIMESTAMP
+	//OBJECT type for nested objects (is used only if destination preserves nested values)
+	OBJECT
+	//ARRAY type for arrays (is used only if destination preserves nested values)
+	ARRAY
 )
 
 var (
@@ -37,6 +41,8 @@ var (
 		"double":    FLOAT64,
 		"timestamp": TIMESTAMP,
 		"boolean":   BOOL,
+		"object":    OBJECT,
+		"array":     ARRAY,
 	}
 	typeToInputString = map[DataType]string{
 		STRING:    "string",
@@ -44,6 +50,8 @@ var (
 		FLOAT64:   "double",
 		TIMESTAMP: "timestamp",
 		BOOL:      "boolean",
+		OBJECT:    "object",
+		ARRAY:     "array",
 	}
 )
 
@@ -61,6 +69,10 @@ func (dt DataType) String() string {
 		return "TIMESTAMP"
 	case BOOL:
 		return "BOOL"
+	case OBJECT:
+		return "OBJECT"
+	case ARRAY:
+		return "ARRAY"
 	case UNKNOWN:
 		return "UNKNOWN"
 	}
@@ -148,6 +160,10 @@ func TypeFromValue(v interface{}) (DataType, error) {
 		return TIMESTAMP, nil
 	case bool:
 		return BOOL, nil
+	case map[string]interface{}:
+		return OBJECT, nil
+	case []interface{}:
+		return ARRAY, nil
 	default:
 		return UNKNOWN, fmt.Errorf("Unknown DataType for value: %v type: %t", v, v)
 	}
@@ -16,6 +16,8 @@ func TestDataTypeValues(t *testing.T) {
 	require.Equal(t, DataType(3), FLOAT64)
 	require.Equal(t, DataType(4), STRING)
 	require.Equal(t, DataType(5), TIMESTAMP)
+	require.Equal(t, DataType(6), OBJECT)
+	require.Equal(t, DataType(7), ARRAY)
 }
 
 func TestTypeFromString(t *testing.T) {
@@ -215,6 +217,18 @@ func TestTypeFromValue(t *testing.T) {
 			BOOL,
 			"",
 		},
+		{
+			"object ok",
+			map[string]interface{}{"key": "value"},
+			OBJECT,
+			"",
+		},
+		{
+			"array ok",
+			[]interface{}{1, "2"},
+			ARRAY,
+			"",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -0,0 +1,143 @@
+package typing
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+//NestedType is a structure of nested objects and arrays (is used only if destination preserves nested values)
+//Fields are types of OBJECT fields, Elem is a type of ARRAY elements (nil if array is empty). Other types are scalar
+type NestedType struct {
+	Type   DataType
+	Fields map[string]*NestedType
+	Elem   *NestedType
+}
+
+//NestedTypeFromValue returns structure of the value. Nil values are skipped: nil is returned for nil value
+//nested timestamps are kept as strings
+func NestedTypeFromValue(v interface{}) (*NestedType, error) {
+	switch value := v.(type) {
+	case nil:
+		return nil, nil
+	case map[string]interface{}:
+		nestedType := &NestedType{Type: OBJECT, Fields: map[string]*NestedType{}}
+		for name, fieldValue := range value {
+			fieldType, err := NestedTypeFromValue(fieldValue)
+			if err != nil {
+				return nil, fmt.Errorf("field [%s]: %v", name, err)
+			}
+			if fieldType != nil {
+				nestedType.Fields[name] = fieldType
+			}
+		}
+		return nestedType, nil
+	case []interface{}:
+		nestedType := &NestedType{Type: ARRAY}
+		for _, element := range value {
+			elementType, err := NestedTypeFromValue(element)
+			if err != nil {
+				return nil, err
+			}
+			nestedType.Elem = MergeNestedTypes(nestedType.Elem, elementType)
+		}
+		return nestedType, nil
+	default:
+		dataType, err := TypeFromValue(ReformatValue(value))
+		if err != nil {
+			return nil, err
+		}
+		return &NestedType{Type: dataType}, nil
+	}
+}
+
+//MergeNestedTypes returns common structure of two types: fields of objects are united, array elements are merged
+//different types are merged into common ancestor type (e.g. object and array into STRING)
+//input types aren't changed
+func MergeNestedTypes(t1, t2 *NestedType) *NestedType {
+	if t1 == nil {
+		return t2
+	}
+	if t2 == nil {
+		return t1
+	}
+
+	if t1.Type != t2.Type {
+		return &NestedType{Type: GetCommonAncestorType(t1.Type, t2.Type)}
+	}
+
+	switch t1.Type {
+	case OBJECT:
+		merged := &NestedType{Type: OBJECT, Fields: make(map[string]*NestedType, len(t1.Fields))}
+		for name, fieldType := range t1.Fields {
+			merged.Fields[name] = fieldType
+		}
+		for name, fieldType := range t2.Fields {
+			merged.Fields[name] = MergeNestedTypes(merged.Fields[name], fieldType)
+		}
+		return merged
+	case ARRAY:
+		return &NestedType{Type: ARRAY, Elem: MergeNestedTypes(t1.Elem, t2.Elem)}
+	default:
+		return t1
+	}
+}
+
+//ExtendNestedType returns current type with fields of another type which don't exist in current objects
+//and true if there are such fields. Types of existing fields aren't changed. Input types aren't changed
+func ExtendNestedType(current, another *NestedType) (*NestedType, bool) {
+	if current == nil || another == nil || current.Type != another.Type {
+		return current, false
+	}
+
+	switch current.Type {
+	case OBJECT:
+		extended := &NestedType{Type: OBJECT, Fields: make(map[string]*NestedType, len(current.Fields))}
+		changed := false
+		for name, fieldType := range current.Fields {
+			extendedField, fieldChanged := ExtendNestedType(fieldType, another.Fields[name])
+			extended.Fields[name] = extendedField
+			changed = changed || fieldChanged
+		}
+		for name, fieldType := range another.Fields {
+			if _, ok := current.Fields[name]; !ok {
+				extended.Fields[name] = fieldType
+				changed = true
+			}
+		}
+		return extended, changed
+	case ARRAY:
+		if current.Elem == nil {
+			return current, false
+		}
+		elem, changed := ExtendNestedType(current.Elem, another.Elem)
+		return &NestedType{Type: ARRAY, Elem: elem}, changed
+	default:
+		return current, false
+	}
+}
+
+//String returns the type in SQL syntax with sorted fields: STRUCT<field1 INT64, field2 ARRAY<STRING>>
+func (nt *NestedType) String() string {
+	switch nt.Type {
+	case OBJECT:
+		names := make([]string, 0, len(nt.Fields))
+		for name := range nt.Fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		fields := make([]string, len(names))
+		for i, name := range names {
+			fields[i] = name + " " + nt.Fields[name].String()
+		}
+		return "STRUCT<" + strings.Join(fields, ", ") + ">"
+	case ARRAY:
+		if nt.Elem == nil {
+			return "ARRAY<" + UNKNOWN.String() + ">"
+		}
+		return "ARRAY<" + nt.Elem.String() + ">"
+	default:
+		return nt.Type.String()
+	}
+}
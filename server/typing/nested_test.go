@@ -0,0 +1,146 @@
+package typing
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNestedTypeFromValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    interface{}
+		expected string
+	}{
+		{
+			"scalar",
+			json.Number("10"),
+			"INT64",
+		},
+		{
+			"object",
+			map[string]interface{}{"id": "1", "amount": json.Number("1.5"), "empty": nil, "tags": []interface{}{"a", "b"}},
+			"STRUCT<amount FLOAT64, id STRING, tags ARRAY<STRING>>",
+		},
+		{
+			"array of objects is merged",
+			[]interface{}{map[string]interface{}{"id": 1}, nil, map[string]interface{}{"name": "a", "id": 2.5}},
+			"ARRAY<STRUCT<id FLOAT64, name STRING>>",
+		},
+		{
+			"empty array",
+			[]interface{}{},
+			"ARRAY<UNKNOWN>",
+		},
+		{
+			"mixed array",
+			[]interface{}{map[string]interface{}{"id": 1}, []interface{}{1}},
+			"ARRAY<STRING>",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := NestedTypeFromValue(tt.input)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, actual.String())
+		})
+	}
+}
+
+func TestMergeNestedTypes(t *testing.T) {
+	tests := []struct {
+		name     string
+		t1       interface{}
+		t2       interface{}
+		expected string
+	}{
+		{
+			"nil",
+			nil,
+			map[string]interface{}{"id": 1},
+			"STRUCT<id INT64>",
+		},
+		{
+			"objects fields are united",
+			map[string]interface{}{"id": 1, "user": map[string]interface{}{"email": "a"}},
+			map[string]interface{}{"id": "1", "user": map[string]interface{}{"name": "b"}},
+			"STRUCT<id STRING, user STRUCT<email STRING, name STRING>>",
+		},
+		{
+			"empty array element type",
+			[]interface{}{},
+			[]interface{}{true},
+			"ARRAY<BOOL>",
+		},
+		{
+			"object and array",
+			map[string]interface{}{"id": 1},
+			[]interface{}{1},
+			"STRING",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t1, err := NestedTypeFromValue(tt.t1)
+			require.NoError(t, err)
+			t2, err := NestedTypeFromValue(tt.t2)
+			require.NoError(t, err)
+			t1String := ""
+			if t1 != nil {
+				t1String = t1.String()
+			}
+
+			require.Equal(t, tt.expected, MergeNestedTypes(t1, t2).String())
+			if t1 != nil {
+				require.Equal(t, t1String, t1.String(), "input type mustn't be changed")
+			}
+		})
+	}
+}
+
+func TestExtendNestedType(t *testing.T) {
+	tests := []struct {
+		name            string
+		current         interface{}
+		another         interface{}
+		expected        string
+		expectedChanged bool
+	}{
+		{
+			"new fields",
+			map[string]interface{}{"id": 1, "items": []interface{}{map[string]interface{}{"sku": "a"}}},
+			map[string]interface{}{"id": "1", "name": "a", "items": []interface{}{map[string]interface{}{"price": 1.5}}},
+			"STRUCT<id INT64, items ARRAY<STRUCT<price FLOAT64, sku STRING>>, name STRING>",
+			true,
+		},
+		{
+			"existing fields types aren't changed",
+			map[string]interface{}{"id": 1, "user": map[string]interface{}{"email": "a"}},
+			map[string]interface{}{"id": "1", "user": []interface{}{1}},
+			"STRUCT<id INT64, user STRUCT<email STRING>>",
+			false,
+		},
+		{
+			"different types",
+			map[string]interface{}{"id": 1},
+			[]interface{}{1},
+			"STRUCT<id INT64>",
+			false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current, err := NestedTypeFromValue(tt.current)
+			require.NoError(t, err)
+			another, err := NestedTypeFromValue(tt.another)
+			require.NoError(t, err)
+			currentString := current.String()
+
+			extended, changed := ExtendNestedType(current, another)
+			require.Equal(t, tt.expectedChanged, changed)
+			require.Equal(t, tt.expected, extended.String())
+			require.Equal(t, currentString, current.String(), "input type mustn't be changed")
+		})
+	}
+}
@@ -8,6 +8,8 @@ type SQLColumn struct {
 	Type       string
 	ColumnType string
 	Override   bool
+	//Nested is a structure of native nested column type (e.g. BigQuery RECORD). It is nil for other columns
+	Nested *NestedType
 }
 
 func (c SQLColumn) DDLType() string {
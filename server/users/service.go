@@ -55,6 +55,7 @@ func (ei *EventIdentifiers) IsAllIdentificationValuesFilled() bool {
 //RecognitionService has a thread pool under the hood
 //saves anonymous events in meta storage
 //rewrites recognized events
+//or links anonymous and identified users in destinations which merge them on their side (storages.IdentityLinker)
 type RecognitionService struct {
 	metaStorage        meta.Storage
 	destinationService *destinations.Service
@@ -135,6 +136,14 @@ func (rs *RecognitionService) start() {
 			}
 
 			for destinationID, identifiers := range rp.DestinationsIdentifiers {
+				if linker, ok := rs.getIdentityLinker(destinationID); ok {
+					// Destination merges users on its side: anonymous events aren't stored and rewritten
+					if identifiers.IsAllIdentificationValuesFilled() {
+						rs.linkIdentity(destinationID, linker, identifiers, rp.EventBytes)
+					}
+					continue
+				}
+
 				if identifiers.IsAllIdentificationValuesFilled() {
 					// Run pipeline only if all identification values were recognized,
 					// it is needed to update all other anonymous events
@@ -142,6 +151,7 @@ func (rs *RecognitionService) start() {
 					if err != nil {
 						logging.SystemErrorf("[%s] Error running recognizing pipeline: %v", destinationID, err)
 					}
+					rs.linkAnonymousID(destinationID, identifiers)
 				} else {
 					// If some identification value is missing - event is still anonymous
 					err = rs.metaStorage.SaveAnonymousEvent(destinationID, identifiers.AnonymousID, identifiers.EventID, string(rp.EventBytes))
@@ -277,6 +287,87 @@ func (rs *RecognitionService) runPipeline(destinationID string, identifiers Even
 	return nil
 }
 
+//getIdentityLinker returns storages.IdentityLinker if the destination merges anonymous and identified users on its side
+func (rs *RecognitionService) getIdentityLinker(destinationID string) (storages.IdentityLinker, bool) {
+	storageProxy, ok := rs.destinationService.GetDestinationByID(destinationID)
+	if !ok {
+		return nil, false
+	}
+
+	storage, ok := storageProxy.Get()
+	if !ok {
+		return nil, false
+	}
+
+	linker, ok := storage.(storages.IdentityLinker)
+	return linker, ok
+}
+
+//linkIdentity links anonymous ID with the identified user in the destination
+//anonymous IDs which have been already linked with all identification values are skipped
+func (rs *RecognitionService) linkIdentity(destinationID string, linker storages.IdentityLinker, identifiers EventIdentifiers, eventBytes []byte) {
+	if identifiers.AnonymousID == "" || rs.isLinked(destinationID, identifiers) {
+		return
+	}
+
+	event := events.Event{}
+	if err := json.Unmarshal(eventBytes, &event); err != nil {
+		logging.SystemErrorf("[%s] Error unmarshalling identified event with anonymous id %s: %v", destinationID, identifiers.AnonymousID, err)
+		return
+	}
+
+	if err := linker.LinkIdentity(identifiers.AnonymousID, event); err != nil {
+		logging.Errorf("[%s] Error linking anonymous id %s with identified user: %v", destinationID, identifiers.AnonymousID, err)
+		return
+	}
+
+	rs.linkAnonymousID(destinationID, identifiers)
+}
+
+//isLinked returns true if anonymous ID has been already linked with every identification value
+func (rs *RecognitionService) isLinked(destinationID string, identifiers EventIdentifiers) bool {
+	if len(identifiers.IdentificationValues) == 0 {
+		return false
+	}
+
+	for _, value := range identifiers.IdentificationValues {
+		identifier := fmt.Sprint(value)
+		anonymousIDs, err := rs.metaStorage.GetLinkedAnonymousIDs(destinationID, identifier)
+		if err != nil {
+			logging.SystemErrorf("[%s] Error getting linked anonymous ids with identifier %s: %v", destinationID, identifier, err)
+			return false
+		}
+
+		linked := false
+		for _, anonymousID := range anonymousIDs {
+			if anonymousID == identifiers.AnonymousID {
+				linked = true
+				break
+			}
+		}
+		if !linked {
+			return false
+		}
+	}
+
+	return true
+}
+
+//linkAnonymousID saves anonymous ID with every identification value into meta storage
+//it is used for resolving all anonymous IDs of a user (e.g. for user data deletion)
+func (rs *RecognitionService) linkAnonymousID(destinationID string, identifiers EventIdentifiers) {
+	if identifiers.AnonymousID == "" {
+		return
+	}
+
+	for _, value := range identifiers.IdentificationValues {
+		identifier := fmt.Sprint(value)
+		if err := rs.metaStorage.LinkAnonymousID(destinationID, identifier, identifiers.AnonymousID); err != nil {
+			logging.SystemErrorf("[%s] Error linking anonymous id %s with identifier %s: %v", destinationID, identifiers.AnonymousID, identifier, err)
+		}
+	}
+}
+
 //Close sets closed flag = true (stop goroutines)
 //closes the queue
 func (rs *RecognitionService) Close() error {